@@ -0,0 +1,87 @@
+// benchcheck 对比两次 go test -bench -benchmem 的输出，超出性能预算时以非零状态退出
+//
+//	go run ./benchmarks/cmd/benchcheck -baseline old.txt -current new.txt -max-time 0.15
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/hwh/hwhkit-go/benchmarks"
+)
+
+func main() {
+	baselinePath := flag.String("baseline", "", "baseline benchmark output")
+	currentPath := flag.String("current", "", "current benchmark output")
+	maxTime := flag.Float64("max-time", benchmarks.DefaultBudget.MaxTimeIncrease, "allowed ns/op increase ratio, negative to disable")
+	maxBytes := flag.Float64("max-bytes", benchmarks.DefaultBudget.MaxBytesIncrease, "allowed B/op increase ratio, negative to disable")
+	maxAllocs := flag.Float64("max-allocs", benchmarks.DefaultBudget.MaxAllocsIncrease, "allowed allocs/op increase ratio, negative to disable")
+	flag.Parse()
+
+	if *baselinePath == "" || *currentPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	baseline, err := readResults(*baselinePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	current, err := readResults(*currentPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cur := current[name]
+		base, ok := baseline[name]
+		if !ok {
+			fmt.Printf("%-40s %12.0f ns/op %10.0f B/op %8.0f allocs/op (new)\n", name, cur.NsPerOp, cur.BytesPerOp, cur.AllocsPerOp)
+			continue
+		}
+		fmt.Printf("%-40s %12.0f ns/op %+7.1f%% %10.0f B/op %8.0f allocs/op\n",
+			name, cur.NsPerOp, change(base.NsPerOp, cur.NsPerOp), cur.BytesPerOp, cur.AllocsPerOp)
+	}
+
+	regressions := benchmarks.Compare(baseline, current, benchmarks.Budget{
+		MaxTimeIncrease:   *maxTime,
+		MaxBytesIncrease:  *maxBytes,
+		MaxAllocsIncrease: *maxAllocs,
+	})
+	if len(regressions) == 0 {
+		fmt.Println("PASS: no regressions beyond budget")
+		return
+	}
+	fmt.Println("FAIL: regressions beyond budget:")
+	for _, regression := range regressions {
+		fmt.Println("  " + regression.String())
+	}
+	os.Exit(1)
+}
+
+// readResults 读取并解析基准输出文件
+func readResults(path string) (map[string]benchmarks.Result, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+	return benchmarks.ParseResults(file)
+}
+
+// change 计算相对变化百分比
+func change(base, cur float64) float64 {
+	if base == 0 {
+		return 0
+	}
+	return (cur - base) / base * 100
+}
@@ -0,0 +1,164 @@
+// Package benchmarks 提供热点路径的基准测试（中间件链、仓储分页、缓存 Remember）
+// 以及基准结果对比工具，用于在CI中发现延迟和内存分配的回归
+//
+//	go test -run=^$ -bench=. -benchmem -count=5 ./benchmarks > new.txt
+//	go run ./benchmarks/cmd/benchcheck -baseline old.txt -current new.txt
+package benchmarks
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Result 单个基准测试的结果，多次运行（-count）时取中位数
+type Result struct {
+	Name        string  `json:"name"`
+	Runs        int     `json:"runs"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	BytesPerOp  float64 `json:"bytes_per_op"`
+	AllocsPerOp float64 `json:"allocs_per_op"`
+}
+
+// procSuffix 基准名称末尾的 GOMAXPROCS 后缀，如 -8
+var procSuffix = regexp.MustCompile(`-\d+$`)
+
+// ParseResults 解析 go test -bench -benchmem 的输出
+func ParseResults(r io.Reader) (map[string]Result, error) {
+	samples := make(map[string][][3]float64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+
+		var sample [3]float64
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid benchmark line %q: %w", scanner.Text(), err)
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				sample[0] = value
+			case "B/op":
+				sample[1] = value
+			case "allocs/op":
+				sample[2] = value
+			}
+		}
+		name := procSuffix.ReplaceAllString(fields[0], "")
+		samples[name] = append(samples[name], sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read benchmark output: %w", err)
+	}
+
+	results := make(map[string]Result, len(samples))
+	for name, runs := range samples {
+		results[name] = Result{
+			Name:        name,
+			Runs:        len(runs),
+			NsPerOp:     median(runs, 0),
+			BytesPerOp:  median(runs, 1),
+			AllocsPerOp: median(runs, 2),
+		}
+	}
+	return results, nil
+}
+
+// median 计算第 index 项指标的中位数
+func median(runs [][3]float64, index int) float64 {
+	values := make([]float64, len(runs))
+	for i, run := range runs {
+		values[i] = run[index]
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
+
+// Budget 性能预算，比例为相对基线允许的增幅，如 0.1 表示允许慢10%，负数表示不检查该指标
+type Budget struct {
+	MaxTimeIncrease   float64 // 每次操作耗时
+	MaxBytesIncrease  float64 // 每次操作分配的字节数
+	MaxAllocsIncrease float64 // 每次操作的分配次数
+}
+
+// DefaultBudget 默认性能预算：耗时允许增加15%，内存分配允许增加10%
+var DefaultBudget = Budget{
+	MaxTimeIncrease:   0.15,
+	MaxBytesIncrease:  0.10,
+	MaxAllocsIncrease: 0.10,
+}
+
+// Regression 超出预算的指标
+type Regression struct {
+	Name     string  `json:"name"`
+	Metric   string  `json:"metric"` // ns/op、B/op、allocs/op
+	Baseline float64 `json:"baseline"`
+	Current  float64 `json:"current"`
+	Increase float64 `json:"increase"` // 相对基线的增幅
+}
+
+// String 格式化输出
+func (r Regression) String() string {
+	return fmt.Sprintf("%s %s: %.2f -> %.2f (+%.1f%%)", r.Name, r.Metric, r.Baseline, r.Current, r.Increase*100)
+}
+
+// Compare 对比基线和当前结果，返回超出预算的指标；只在一侧存在的基准会被忽略
+func Compare(baseline, current map[string]Result, budget Budget) []Regression {
+	var regressions []Regression
+	for name, base := range baseline {
+		cur, ok := current[name]
+		if !ok {
+			continue
+		}
+		checks := []struct {
+			metric    string
+			base, cur float64
+			limit     float64
+		}{
+			{"ns/op", base.NsPerOp, cur.NsPerOp, budget.MaxTimeIncrease},
+			{"B/op", base.BytesPerOp, cur.BytesPerOp, budget.MaxBytesIncrease},
+			{"allocs/op", base.AllocsPerOp, cur.AllocsPerOp, budget.MaxAllocsIncrease},
+		}
+		for _, check := range checks {
+			if check.limit < 0 || check.cur <= check.base {
+				continue
+			}
+			// 基线为0时（如零分配），任何增加都视为回归
+			increase := 1.0
+			if check.base > 0 {
+				increase = (check.cur - check.base) / check.base
+			}
+			if increase > check.limit {
+				regressions = append(regressions, Regression{
+					Name:     name,
+					Metric:   check.metric,
+					Baseline: check.base,
+					Current:  check.cur,
+					Increase: increase,
+				})
+			}
+		}
+	}
+	sort.Slice(regressions, func(i, j int) bool {
+		if regressions[i].Name != regressions[j].Name {
+			return regressions[i].Name < regressions[j].Name
+		}
+		return regressions[i].Metric < regressions[j].Metric
+	})
+	return regressions
+}
@@ -0,0 +1,66 @@
+package benchmarks
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const baselineOutput = `goos: linux
+goarch: amd64
+pkg: github.com/hwh/hwhkit-go/benchmarks
+BenchmarkMiddlewareChain-8   	  100000	     10000 ns/op	    2048 B/op	      20 allocs/op
+BenchmarkMiddlewareChain-8   	  100000	     12000 ns/op	    2048 B/op	      20 allocs/op
+BenchmarkMiddlewareChain-8   	  100000	     11000 ns/op	    2048 B/op	      20 allocs/op
+BenchmarkCacheRememberHit-8  	   50000	     30000 ns/op	     512 B/op	       0 allocs/op
+BenchmarkRemoved-8           	   50000	      1000 ns/op
+PASS
+ok  	github.com/hwh/hwhkit-go/benchmarks	3.210s
+`
+
+func TestParseResults(t *testing.T) {
+	results, err := ParseResults(strings.NewReader(baselineOutput))
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	chain := results["BenchmarkMiddlewareChain"]
+	assert.Equal(t, 3, chain.Runs)
+	assert.Equal(t, 11000.0, chain.NsPerOp)
+	assert.Equal(t, 2048.0, chain.BytesPerOp)
+	assert.Equal(t, 20.0, chain.AllocsPerOp)
+
+	assert.Equal(t, 1000.0, results["BenchmarkRemoved"].NsPerOp)
+	assert.Zero(t, results["BenchmarkRemoved"].AllocsPerOp)
+
+	_, err = ParseResults(strings.NewReader("BenchmarkBad-8 100 abc ns/op\n"))
+	assert.Error(t, err)
+}
+
+func TestCompare(t *testing.T) {
+	baseline, err := ParseResults(strings.NewReader(baselineOutput))
+	require.NoError(t, err)
+
+	current, err := ParseResults(strings.NewReader(`
+BenchmarkMiddlewareChain-8   	  100000	     12100 ns/op	    2560 B/op	      21 allocs/op
+BenchmarkCacheRememberHit-8  	   50000	     25000 ns/op	     512 B/op	       1 allocs/op
+BenchmarkAdded-8             	   50000	      1000 ns/op
+`))
+	require.NoError(t, err)
+
+	regressions := Compare(baseline, current, DefaultBudget)
+	require.Len(t, regressions, 2)
+	assert.Equal(t, "BenchmarkCacheRememberHit", regressions[0].Name)
+	assert.Equal(t, "allocs/op", regressions[0].Metric)
+	assert.Equal(t, "BenchmarkMiddlewareChain", regressions[1].Name)
+	assert.Equal(t, "B/op", regressions[1].Metric)
+	assert.InDelta(t, 0.25, regressions[1].Increase, 0.001)
+	assert.Contains(t, regressions[1].String(), "+25.0%")
+
+	// 负数预算跳过对应指标
+	budget := DefaultBudget
+	budget.MaxBytesIncrease = -1
+	budget.MaxAllocsIncrease = -1
+	assert.Empty(t, Compare(baseline, current, budget))
+}
@@ -0,0 +1,204 @@
+package benchmarks
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/auth"
+	"github.com/hwh/hwhkit-go/pkg/cache"
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/hwh/hwhkit-go/pkg/database"
+	"github.com/hwh/hwhkit-go/pkg/logger"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// newMiddlewareChain 构建 JWT + 日志 + 限流中间件链，返回路由和有效令牌
+func newMiddlewareChain(b *testing.B) (*gin.Engine, string) {
+	gin.SetMode(gin.TestMode)
+
+	authManager := auth.New(&config.JWTConfig{
+		Secret:       "benchmark-secret",
+		ExpireHours:  1,
+		RefreshHours: 24,
+		Issuer:       "hwhkit-bench",
+	})
+	token, err := authManager.GenerateToken(1, "bench", "bench@example.com", "user")
+	if err != nil {
+		b.Fatalf("Failed to generate token: %v", err)
+	}
+
+	log, err := logger.New(&config.LogConfig{Level: "info", Format: "json", Output: "console"})
+	if err != nil {
+		b.Fatalf("Failed to create logger: %v", err)
+	}
+	log.GetLogger().SetOutput(io.Discard)
+
+	limit := middleware.DefaultRateLimiterConfig()
+	limit.Rate = 1 << 30
+	limit.Burst = 1 << 30
+
+	router := gin.New()
+	router.Use(middleware.LoggerWithManager(log), middleware.RateLimit(limit), middleware.JWTWithManager(authManager))
+	router.GET("/users/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": c.Param("id")})
+	})
+	return router, token
+}
+
+func BenchmarkMiddlewareChain(b *testing.B) {
+	router, token := newMiddlewareChain(b)
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			b.Fatalf("Unexpected status %d: %s", w.Code, w.Body.String())
+		}
+	}
+}
+
+// benchUser 仓储分页基准使用的模型
+type benchUser struct {
+	ID     uint `gorm:"primaryKey"`
+	Name   string
+	Status string
+}
+
+// BenchmarkRepositoryPaginate 测量分页查询的构建开销（计数 + 分页查询），使用 DryRun 排除数据库往返
+func BenchmarkRepositoryPaginate(b *testing.B) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		b.Fatalf("Failed to open database: %v", err)
+	}
+	repo := database.NewBaseRepository[benchUser](db)
+	condition := map[string]interface{}{"status = ?": "active"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.Paginate(i%10+1, 20, condition); err != nil {
+			b.Fatalf("Paginate failed: %v", err)
+		}
+	}
+}
+
+// newCache 创建连接到 miniredis 的缓存管理器
+func newCache(b *testing.B) *cache.Manager {
+	mr := miniredis.RunT(b)
+	port, _ := strconv.Atoi(mr.Port())
+	manager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		b.Fatalf("Failed to create cache: %v", err)
+	}
+	b.Cleanup(func() { manager.Close() })
+	return manager
+}
+
+type benchProfile struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+}
+
+func BenchmarkCacheRememberHit(b *testing.B) {
+	manager := newCache(b)
+	loader := func() (benchProfile, error) {
+		return benchProfile{ID: 1, Name: "bench", Roles: []string{"user"}}, nil
+	}
+	if _, err := cache.Remember(manager, "profile:1", time.Hour, loader); err != nil {
+		b.Fatalf("Remember failed: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cache.Remember(manager, "profile:1", time.Hour, loader); err != nil {
+			b.Fatalf("Remember failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkCacheRememberMiss(b *testing.B) {
+	manager := newCache(b)
+	errLoad := errors.New("not found")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// 加载失败时不会写入缓存，每次都走未命中路径
+		if _, err := cache.Remember(manager, "profile:missing", time.Hour, func() (benchProfile, error) {
+			return benchProfile{}, errLoad
+		}); !errors.Is(err, errLoad) {
+			b.Fatalf("Expected loader error, got %v", err)
+		}
+	}
+}
+
+// BenchmarkRateLimitParallel 测量多个客户端并发经过各限流策略时的开销和锁竞争
+func BenchmarkRateLimitParallel(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+
+	strategies := []string{
+		middleware.StrategyTokenBucket,
+		middleware.StrategySlidingWindow,
+		middleware.StrategyFixedWindow,
+		middleware.StrategyGCRA,
+	}
+	for _, strategy := range strategies {
+		b.Run(strategy, func(b *testing.B) {
+			limit := middleware.DefaultRateLimiterConfig()
+			limit.Rate = 1 << 20
+			limit.Burst = 1 << 20
+			limit.Duration = time.Second
+			limit.Strategy = strategy
+			limit.KeyFunc = func(c *gin.Context) string {
+				return c.GetHeader("X-Client")
+			}
+
+			limiter := middleware.NewRateLimiter(limit)
+			b.Cleanup(limiter.Stop)
+
+			router := gin.New()
+			router.Use(limiter.Middleware())
+			router.GET("/ping", func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+
+			var clients atomic.Int64
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+				client := clients.Add(1)
+				i := 0
+				for pb.Next() {
+					req.Header.Set("X-Client", strconv.FormatInt(client*1000+int64(i%64), 10))
+					w := httptest.NewRecorder()
+					router.ServeHTTP(w, req)
+					if w.Code != http.StatusNoContent {
+						b.Errorf("Unexpected status %d", w.Code)
+						return
+					}
+					i++
+				}
+			})
+		})
+	}
+}
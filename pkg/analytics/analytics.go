@@ -0,0 +1,405 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/cache"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// hourLayout Redis键中小时窗口的格式（UTC）
+const hourLayout = "2006010215"
+
+// bucketTTL Redis中小时用量的保留时间，汇总任务未运行时数据也不会无限增长
+const bucketTTL = 48 * time.Hour
+
+// 小时用量哈希中的字段
+const (
+	fieldRequests     = "requests"
+	fieldClientErrors = "client_errors"
+	fieldServerErrors = "server_errors"
+	fieldLatency      = "latency_us"
+)
+
+// ErrNoDatabase 未配置数据库时无法汇总到数据库
+var ErrNoDatabase = errors.New("analytics database is not configured")
+
+// UsageRecord 客户端（用户或API Key）每小时的API用量
+type UsageRecord struct {
+	Client        string    `json:"client" gorm:"primaryKey;size:128"`
+	Hour          time.Time `json:"hour" gorm:"primaryKey;index"` // 小时窗口起始时间（UTC）
+	Requests      int64     `json:"requests"`
+	ClientErrors  int64     `json:"client_errors"` // 4xx 响应数
+	ServerErrors  int64     `json:"server_errors"` // 5xx 响应数
+	LatencyMicros int64     `json:"latency_us"`    // 累计处理耗时（微秒）
+}
+
+// TableName 小时用量表名
+func (UsageRecord) TableName() string {
+	return "api_usage_hourly"
+}
+
+// ErrorRate 错误率（4xx和5xx响应占比）
+func (r *UsageRecord) ErrorRate() float64 {
+	return errorRate(r.Requests, r.ClientErrors+r.ServerErrors)
+}
+
+// AvgLatency 平均处理耗时
+func (r *UsageRecord) AvgLatency() time.Duration {
+	return avgLatency(r.Requests, r.LatencyMicros)
+}
+
+// ClientSummary 客户端在查询时间范围内的用量汇总
+type ClientSummary struct {
+	Client       string    `json:"client"`
+	Requests     int64     `json:"requests"`
+	ClientErrors int64     `json:"client_errors"`
+	ServerErrors int64     `json:"server_errors"`
+	ErrorRate    float64   `json:"error_rate"`
+	AvgLatencyMs float64   `json:"avg_latency_ms"`
+	FirstSeen    time.Time `json:"first_seen"` // 第一个有请求的小时
+	LastSeen     time.Time `json:"last_seen"`  // 最后一个有请求的小时
+}
+
+// Filter 用量查询条件，时间按小时窗口起始时间比较，零值表示不限制
+type Filter struct {
+	Client string
+	From   time.Time
+	To     time.Time
+}
+
+// Manager API用量统计管理器
+// 请求实时累计到Redis的小时窗口中，汇总任务（Rollup/Schedule）将已结束的小时写入数据库并清理Redis；
+// 查询时合并数据库中的历史数据和Redis中尚未汇总的数据
+type Manager struct {
+	cache  *cache.Manager
+	db     *gorm.DB
+	prefix string
+	now    func() time.Time
+}
+
+// New 创建用量统计管理器并自动迁移用量表，db 为 nil 时只在Redis中统计
+func New(cacheManager *cache.Manager, db *gorm.DB, prefix string) (*Manager, error) {
+	if prefix == "" {
+		prefix = "analytics"
+	}
+	if db != nil {
+		if err := db.AutoMigrate(&UsageRecord{}); err != nil {
+			return nil, fmt.Errorf("failed to migrate usage table: %w", err)
+		}
+	}
+	return &Manager{
+		cache:  cacheManager,
+		db:     db,
+		prefix: prefix,
+		now:    time.Now,
+	}, nil
+}
+
+// Record 记录一次请求的状态码和处理耗时
+func (m *Manager) Record(ctx context.Context, client string, status int, latency time.Duration) error {
+	hour := m.now().UTC().Truncate(time.Hour)
+	bucket := m.cache.Key(m.bucketKey(hour, client))
+	clients := m.cache.Key(m.clientsKey(hour))
+
+	pipe := m.cache.Pipeline()
+	pipe.HIncrBy(ctx, bucket, fieldRequests, 1)
+	switch {
+	case status >= 500:
+		pipe.HIncrBy(ctx, bucket, fieldServerErrors, 1)
+	case status >= 400:
+		pipe.HIncrBy(ctx, bucket, fieldClientErrors, 1)
+	}
+	pipe.HIncrBy(ctx, bucket, fieldLatency, latency.Microseconds())
+	pipe.Expire(ctx, bucket, bucketTTL)
+	pipe.SAdd(ctx, clients, client)
+	pipe.Expire(ctx, clients, bucketTTL)
+	pipe.ZAdd(ctx, m.cache.Key(m.hoursKey()), redis.Z{Score: float64(hour.Unix()), Member: hour.Format(hourLayout)})
+	// 只用Redis统计时，超过保留时间的小时不会被汇总任务移除
+	pipe.ZRemRangeByScore(ctx, m.cache.Key(m.hoursKey()), "-inf", "("+strconv.FormatInt(hour.Add(-bucketTTL).Unix(), 10))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+	return nil
+}
+
+// Usage 查询每小时用量，按小时和客户端排序
+func (m *Manager) Usage(ctx context.Context, filter Filter) ([]UsageRecord, error) {
+	merged := make(map[string]UsageRecord)
+	if m.db != nil {
+		query := m.db.WithContext(ctx).Model(&UsageRecord{})
+		if filter.Client != "" {
+			query = query.Where("client = ?", filter.Client)
+		}
+		if !filter.From.IsZero() {
+			query = query.Where("hour >= ?", filter.From.UTC())
+		}
+		if !filter.To.IsZero() {
+			query = query.Where("hour <= ?", filter.To.UTC())
+		}
+		var stored []UsageRecord
+		if err := query.Find(&stored).Error; err != nil {
+			return nil, fmt.Errorf("failed to query usage: %w", err)
+		}
+		for _, record := range stored {
+			merged[recordKey(record)] = record
+		}
+	}
+
+	pending, err := m.pendingRecords(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	// 汇总中断时同一小时可能同时存在于数据库和Redis，Redis中的数据是该小时的完整累计值
+	for _, record := range pending {
+		merged[recordKey(record)] = record
+	}
+
+	records := make([]UsageRecord, 0, len(merged))
+	for _, record := range merged {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].Hour.Equal(records[j].Hour) {
+			return records[i].Hour.Before(records[j].Hour)
+		}
+		return records[i].Client < records[j].Client
+	})
+	return records, nil
+}
+
+// Summary 按客户端汇总查询时间范围内的用量，按请求数从多到少排序
+func (m *Manager) Summary(ctx context.Context, filter Filter) ([]ClientSummary, error) {
+	records, err := m.Usage(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return Summarize(records), nil
+}
+
+// Summarize 按客户端汇总小时用量，按请求数从多到少排序
+func Summarize(records []UsageRecord) []ClientSummary {
+	type totals struct {
+		summary ClientSummary
+		latency int64
+	}
+	byClient := make(map[string]*totals)
+	for _, record := range records {
+		t, ok := byClient[record.Client]
+		if !ok {
+			t = &totals{summary: ClientSummary{Client: record.Client, FirstSeen: record.Hour, LastSeen: record.Hour}}
+			byClient[record.Client] = t
+		}
+		t.summary.Requests += record.Requests
+		t.summary.ClientErrors += record.ClientErrors
+		t.summary.ServerErrors += record.ServerErrors
+		t.latency += record.LatencyMicros
+		if record.Hour.Before(t.summary.FirstSeen) {
+			t.summary.FirstSeen = record.Hour
+		}
+		if record.Hour.After(t.summary.LastSeen) {
+			t.summary.LastSeen = record.Hour
+		}
+	}
+
+	summaries := make([]ClientSummary, 0, len(byClient))
+	for _, t := range byClient {
+		summary := t.summary
+		summary.ErrorRate = errorRate(summary.Requests, summary.ClientErrors+summary.ServerErrors)
+		summary.AvgLatencyMs = float64(avgLatency(summary.Requests, t.latency).Microseconds()) / 1000
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Requests != summaries[j].Requests {
+			return summaries[i].Requests > summaries[j].Requests
+		}
+		return summaries[i].Client < summaries[j].Client
+	})
+	return summaries
+}
+
+// Rollup 将已结束的小时用量写入数据库并从Redis清理，返回写入的记录数
+// 写入使用 (client, hour) 上的 upsert，中断后重新执行不会重复计数
+func (m *Manager) Rollup(ctx context.Context) (int, error) {
+	if m.db == nil {
+		return 0, ErrNoDatabase
+	}
+
+	current := m.now().UTC().Truncate(time.Hour)
+	hours, err := m.cache.GetClient().ZRangeByScore(ctx, m.cache.Key(m.hoursKey()), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + strconv.FormatInt(current.Unix(), 10),
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list usage hours: %w", err)
+	}
+
+	total := 0
+	for _, value := range hours {
+		hour, err := time.Parse(hourLayout, value)
+		if err != nil {
+			continue
+		}
+		records, clients, err := m.hourRecords(ctx, hour, "")
+		if err != nil {
+			return total, err
+		}
+		if len(records) > 0 {
+			if err := m.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&records).Error; err != nil {
+				return total, fmt.Errorf("failed to store usage for %s: %w", value, err)
+			}
+		}
+
+		keys := []string{m.cache.Key(m.clientsKey(hour))}
+		for _, client := range clients {
+			keys = append(keys, m.cache.Key(m.bucketKey(hour, client)))
+		}
+		pipe := m.cache.Pipeline()
+		pipe.Del(ctx, keys...)
+		pipe.ZRem(ctx, m.cache.Key(m.hoursKey()), value)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return total, fmt.Errorf("failed to clear usage for %s: %w", value, err)
+		}
+		total += len(records)
+	}
+	return total, nil
+}
+
+// Schedule 按固定间隔执行汇总，直到 ctx 取消，通常每小时执行一次
+func (m *Manager) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := m.Rollup(ctx)
+			if err != nil {
+				log.Printf("Usage rollup failed after %d records: %v", count, err)
+				continue
+			}
+			log.Printf("Usage rollup stored %d records", count)
+		}
+	}
+}
+
+// pendingRecords 读取Redis中尚未汇总的小时用量
+func (m *Manager) pendingRecords(ctx context.Context, filter Filter) ([]UsageRecord, error) {
+	minScore, maxScore := "-inf", "+inf"
+	if !filter.From.IsZero() {
+		minScore = strconv.FormatInt(filter.From.UTC().Unix(), 10)
+	}
+	if !filter.To.IsZero() {
+		maxScore = strconv.FormatInt(filter.To.UTC().Unix(), 10)
+	}
+	hours, err := m.cache.GetClient().ZRangeByScore(ctx, m.cache.Key(m.hoursKey()), &redis.ZRangeBy{Min: minScore, Max: maxScore}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage hours: %w", err)
+	}
+
+	var records []UsageRecord
+	for _, value := range hours {
+		hour, err := time.Parse(hourLayout, value)
+		if err != nil {
+			continue
+		}
+		hourly, _, err := m.hourRecords(ctx, hour, filter.Client)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, hourly...)
+	}
+	return records, nil
+}
+
+// hourRecords 读取某一小时的用量，client 为空时读取所有客户端
+func (m *Manager) hourRecords(ctx context.Context, hour time.Time, client string) ([]UsageRecord, []string, error) {
+	var clients []string
+	if client != "" {
+		clients = []string{client}
+	} else {
+		members, err := m.cache.GetClient().SMembers(ctx, m.cache.Key(m.clientsKey(hour))).Result()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list usage clients: %w", err)
+		}
+		clients = members
+	}
+
+	pipe := m.cache.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(clients))
+	for i, c := range clients {
+		cmds[i] = pipe.HGetAll(ctx, m.cache.Key(m.bucketKey(hour, c)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+
+	records := make([]UsageRecord, 0, len(clients))
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			continue
+		}
+		records = append(records, UsageRecord{
+			Client:        clients[i],
+			Hour:          hour,
+			Requests:      parseCount(fields[fieldRequests]),
+			ClientErrors:  parseCount(fields[fieldClientErrors]),
+			ServerErrors:  parseCount(fields[fieldServerErrors]),
+			LatencyMicros: parseCount(fields[fieldLatency]),
+		})
+	}
+	return records, clients, nil
+}
+
+// bucketKey 客户端小时用量哈希的键
+func (m *Manager) bucketKey(hour time.Time, client string) string {
+	return fmt.Sprintf("%s:%s:c:%s", m.prefix, hour.Format(hourLayout), client)
+}
+
+// clientsKey 小时内有请求的客户端集合的键
+func (m *Manager) clientsKey(hour time.Time) string {
+	return fmt.Sprintf("%s:%s:clients", m.prefix, hour.Format(hourLayout))
+}
+
+// hoursKey 尚未汇总的小时索引（有序集合，分数为小时起始时间戳）的键
+func (m *Manager) hoursKey() string {
+	return m.prefix + ":hours"
+}
+
+// recordKey 合并记录时使用的键
+func recordKey(record UsageRecord) string {
+	return record.Client + "|" + record.Hour.UTC().Format(hourLayout)
+}
+
+// parseCount 解析计数，无效值按0处理
+func parseCount(value string) int64 {
+	count, _ := strconv.ParseInt(value, 10, 64)
+	return count
+}
+
+// errorRate 计算错误率
+func errorRate(requests, failed int64) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return float64(failed) / float64(requests)
+}
+
+// avgLatency 计算平均耗时
+func avgLatency(requests, latencyMicros int64) time.Duration {
+	if requests == 0 {
+		return 0
+	}
+	return time.Duration(latencyMicros/requests) * time.Microsecond
+}
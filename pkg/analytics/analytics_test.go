@@ -0,0 +1,133 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hwh/hwhkit-go/pkg/cache"
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newTestManager(t *testing.T, db *gorm.DB) (*Manager, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	port, err := strconv.Atoi(mr.Port())
+	require.NoError(t, err)
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	require.NoError(t, err)
+	t.Cleanup(func() { cacheManager.Close() })
+
+	manager, err := New(cacheManager, db, "")
+	require.NoError(t, err)
+	return manager, mr
+}
+
+// recordSample 在两个小时内记录请求
+func recordSample(t *testing.T, manager *Manager) time.Time {
+	ctx := context.Background()
+	hour := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	manager.now = func() time.Time { return hour.Add(30 * time.Minute) }
+	require.NoError(t, manager.Record(ctx, "user:1", 200, 10*time.Millisecond))
+	require.NoError(t, manager.Record(ctx, "user:1", 404, 20*time.Millisecond))
+	require.NoError(t, manager.Record(ctx, "user:1", 500, 30*time.Millisecond))
+	require.NoError(t, manager.Record(ctx, "key:abc", 200, 5*time.Millisecond))
+
+	manager.now = func() time.Time { return hour.Add(70 * time.Minute) }
+	require.NoError(t, manager.Record(ctx, "user:1", 200, 10*time.Millisecond))
+	return hour
+}
+
+func TestUsageAndSummary(t *testing.T) {
+	manager, _ := newTestManager(t, nil)
+	hour := recordSample(t, manager)
+	ctx := context.Background()
+
+	records, err := manager.Usage(ctx, Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "key:abc", records[0].Client)
+	assert.Equal(t, "user:1", records[1].Client)
+	assert.Equal(t, hour, records[1].Hour)
+	assert.Equal(t, int64(3), records[1].Requests)
+	assert.Equal(t, int64(1), records[1].ClientErrors)
+	assert.Equal(t, int64(1), records[1].ServerErrors)
+	assert.InDelta(t, 2.0/3, records[1].ErrorRate(), 0.0001)
+	assert.Equal(t, 20*time.Millisecond, records[1].AvgLatency())
+	assert.Equal(t, hour.Add(time.Hour), records[2].Hour)
+
+	records, err = manager.Usage(ctx, Filter{Client: "user:1", From: hour.Add(time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, int64(1), records[0].Requests)
+
+	summaries, err := manager.Summary(ctx, Filter{})
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, "user:1", summaries[0].Client)
+	assert.Equal(t, int64(4), summaries[0].Requests)
+	assert.Equal(t, 0.5, summaries[0].ErrorRate)
+	assert.Equal(t, 17.5, summaries[0].AvgLatencyMs)
+	assert.Equal(t, hour, summaries[0].FirstSeen)
+	assert.Equal(t, hour.Add(time.Hour), summaries[0].LastSeen)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteUsageCSV(&buf, records))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "hour,client,requests,client_errors,server_errors,error_rate,avg_latency_ms", lines[0])
+	assert.Equal(t, "2024-05-01T11:00:00Z,user:1,1,0,0,0.0000,10.0000", lines[1])
+
+	buf.Reset()
+	require.NoError(t, WriteSummaryCSV(&buf, summaries))
+	assert.Contains(t, buf.String(), "user:1,4,1,1,0.5000,17.5000,2024-05-01T10:00:00Z,2024-05-01T11:00:00Z")
+}
+
+func TestRollup(t *testing.T) {
+	manager, _ := newTestManager(t, nil)
+	recordSample(t, manager)
+	_, err := manager.Rollup(context.Background())
+	assert.ErrorIs(t, err, ErrNoDatabase)
+
+	// 试运行模式下只生成SQL不连接数据库
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	var statements []string
+	db.Callback().Create().After("gorm:create").Register("test:capture", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	})
+
+	manager, mr := newTestManager(t, db)
+	hour := recordSample(t, manager)
+
+	count, err := manager.Rollup(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	require.Len(t, statements, 1)
+	assert.Contains(t, statements[0], `INSERT INTO "api_usage_hourly"`)
+	assert.Contains(t, statements[0], "ON CONFLICT")
+
+	// 已汇总的小时从Redis清理，当前小时保留
+	assert.False(t, mr.Exists("app:analytics:2024050110:c:user:1"))
+	assert.False(t, mr.Exists("app:analytics:2024050110:clients"))
+	assert.True(t, mr.Exists("app:analytics:2024050111:c:user:1"))
+	members, err := mr.ZMembers("app:analytics:hours")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2024050111"}, members)
+
+	records, err := manager.Usage(context.Background(), Filter{From: hour})
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+}
@@ -0,0 +1,61 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// WriteUsageCSV 以CSV格式输出每小时用量，用于计费和滥用分析
+func WriteUsageCSV(w io.Writer, records []UsageRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"hour", "client", "requests", "client_errors", "server_errors", "error_rate", "avg_latency_ms"}); err != nil {
+		return err
+	}
+	for i := range records {
+		record := &records[i]
+		if err := writer.Write([]string{
+			record.Hour.UTC().Format(time.RFC3339),
+			record.Client,
+			strconv.FormatInt(record.Requests, 10),
+			strconv.FormatInt(record.ClientErrors, 10),
+			strconv.FormatInt(record.ServerErrors, 10),
+			formatFloat(record.ErrorRate()),
+			formatFloat(float64(record.AvgLatency().Microseconds()) / 1000),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteSummaryCSV 以CSV格式输出客户端用量汇总
+func WriteSummaryCSV(w io.Writer, summaries []ClientSummary) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"client", "requests", "client_errors", "server_errors", "error_rate", "avg_latency_ms", "first_seen", "last_seen"}); err != nil {
+		return err
+	}
+	for _, summary := range summaries {
+		if err := writer.Write([]string{
+			summary.Client,
+			strconv.FormatInt(summary.Requests, 10),
+			strconv.FormatInt(summary.ClientErrors, 10),
+			strconv.FormatInt(summary.ServerErrors, 10),
+			formatFloat(summary.ErrorRate),
+			formatFloat(summary.AvgLatencyMs),
+			summary.FirstSeen.UTC().Format(time.RFC3339),
+			summary.LastSeen.UTC().Format(time.RFC3339),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatFloat 格式化小数，保留4位
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 4, 64)
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/hwh/hwhkit-go/pkg/config"
 )
 
@@ -124,13 +125,18 @@ func TestJWTManagerExtractToken(t *testing.T) {
 		t.Errorf("Expected token abc123def456, got %s", token)
 	}
 	
+	// 认证方案不区分大小写（RFC 7235）
+	token, err = jwtManager.ExtractTokenFromHeader("bearer abc123")
+	if err != nil || token != "abc123" {
+		t.Errorf("Expected lowercase scheme to be accepted, got %q, %v", token, err)
+	}
+	
 	// 测试错误的格式
 	invalidHeaders := []string{
 		"",
 		"abc123",
 		"Basic abc123",
 		"Bearer",
-		"bearer abc123",
 	}
 	
 	for _, header := range invalidHeaders {
@@ -296,8 +302,16 @@ func TestAuthService(t *testing.T) {
 		t.Error("Should fail with wrong password")
 	}
 	
-	// 测试修改密码
-	err = authService.ChangePassword("generated-id-123", "Password123!", "NewPassword456!", userProvider, userUpdater)
+	// 测试修改密码，修改密码按用户ID查找用户
+	userByID := func(id string) (*User, error) {
+		for _, user := range users {
+			if user.ID == id {
+				return user, nil
+			}
+		}
+		return nil, &TestError{Message: "user not found"}
+	}
+	err = authService.ChangePassword("generated-id-123", "Password123!", "NewPassword456!", userByID, userUpdater)
 	if err != nil {
 		t.Errorf("Failed to change password: %v", err)
 	}
@@ -414,4 +428,52 @@ func BenchmarkPasswordHash(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		pm.HashPassword(password)
 	}
-}
\ No newline at end of file
+}
+
+func TestJWTManagerClockSkewLeeway(t *testing.T) {
+	cfg := &config.JWTConfig{Secret: "test-secret", ExpireHours: 1, RefreshHours: 24, Issuer: "test"}
+	now := time.Now()
+
+	sign := func(issuedAt, expiresAt time.Time) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+			UserID: "123",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    cfg.Issuer,
+				IssuedAt:  jwt.NewNumericDate(issuedAt),
+				NotBefore: jwt.NewNumericDate(issuedAt),
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+			},
+		})
+		signed, err := token.SignedString([]byte(cfg.Secret))
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return signed
+	}
+
+	// 签发方时钟快10秒
+	future := sign(now.Add(10*time.Second), now.Add(time.Hour))
+	// 校验方时钟快5秒
+	expired := sign(now.Add(-time.Hour), now.Add(-5*time.Second))
+
+	strict := NewJWTManager(cfg)
+	if _, err := strict.ValidateToken(future); err == nil {
+		t.Error("Token issued in the future should fail without leeway")
+	}
+	if _, err := strict.ValidateToken(expired); err == nil {
+		t.Error("Expired token should fail without leeway")
+	}
+
+	tolerant := NewJWTManager(&config.JWTConfig{Secret: cfg.Secret, Issuer: cfg.Issuer, LeewaySeconds: 30})
+	if _, err := tolerant.ValidateToken(future); err != nil {
+		t.Errorf("Token within leeway should be valid: %v", err)
+	}
+	if _, err := tolerant.ValidateToken(expired); err != nil {
+		t.Errorf("Recently expired token within leeway should be valid: %v", err)
+	}
+
+	tooFarAhead := sign(now.Add(time.Minute), now.Add(time.Hour))
+	if _, err := tolerant.ValidateToken(tooFarAhead); err == nil {
+		t.Error("Token beyond leeway should fail")
+	}
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// refreshSubjectPrefix 刷新令牌的 Subject 前缀
+const refreshSubjectPrefix = "refresh:"
+
+// Claims JWT声明，Manager、JWTManager 和中间件共用
+// 用户ID统一为字符串，角色支持多个；解析时兼容旧版令牌的数字 user_id 和单个 role 字段
+type Claims struct {
+	UserID   string   `json:"user_id"`
+	Username string   `json:"username"`
+	Email    string   `json:"email"`
+	Roles    []string `json:"roles,omitempty"`
+	Device   string   `json:"dev,omitempty"` // 设备指纹，绑定后令牌只能在同一设备使用
+	jwt.RegisteredClaims
+}
+
+// TokenPair 令牌对，同时提供有效期秒数和过期时间戳
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // 访问令牌有效期（秒）
+	ExpiresAt    int64  `json:"expires_at"` // 访问令牌过期时间（Unix时间戳）
+	TokenType    string `json:"token_type"`
+}
+
+// User 用户信息
+type User struct {
+	ID       string   `json:"id"`
+	Username string   `json:"username"`
+	Email    string   `json:"email"`
+	Password string   `json:"-"` // 不返回密码
+	Roles    []string `json:"roles"`
+	IsActive bool     `json:"is_active"`
+}
+
+// UnmarshalJSON 解析声明，兼容旧版令牌的数字 user_id 和单个 role 字段
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	type claimsAlias Claims
+	aux := struct {
+		*claimsAlias
+		UserID json.RawMessage `json:"user_id"`
+		Role   string          `json:"role"`
+	}{claimsAlias: (*claimsAlias)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	c.UserID = ""
+	if raw := bytes.TrimSpace(aux.UserID); len(raw) > 0 && !bytes.Equal(raw, []byte("null")) {
+		if raw[0] == '"' {
+			if err := json.Unmarshal(raw, &c.UserID); err != nil {
+				return fmt.Errorf("invalid user_id claim: %w", err)
+			}
+		} else {
+			var number json.Number
+			if err := json.Unmarshal(raw, &number); err != nil {
+				return fmt.Errorf("invalid user_id claim: %w", err)
+			}
+			c.UserID = number.String()
+		}
+	}
+	if aux.Role != "" && !c.HasRole(aux.Role) {
+		c.Roles = append([]string{aux.Role}, c.Roles...)
+	}
+	return nil
+}
+
+// PrimaryRole 获取主角色（第一个角色），没有角色时返回空
+func (c *Claims) PrimaryRole() string {
+	if len(c.Roles) == 0 {
+		return ""
+	}
+	return c.Roles[0]
+}
+
+// HasRole 检查是否具有指定角色
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAnyRole 检查是否具有任意指定角色
+func (c *Claims) HasAnyRole(roles ...string) bool {
+	for _, role := range roles {
+		if c.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAllRoles 检查是否具有所有指定角色
+func (c *Claims) HasAllRoles(roles ...string) bool {
+	for _, role := range roles {
+		if !c.HasRole(role) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsRefresh 是否为刷新令牌
+func (c *Claims) IsRefresh() bool {
+	return strings.HasPrefix(c.Subject, refreshSubjectPrefix)
+}
+
+// UserIDInt64 将用户ID解析为 int64，用于使用数字主键的应用
+func (c *Claims) UserIDInt64() (int64, error) {
+	id, err := strconv.ParseInt(c.UserID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("user id %q is not numeric: %w", c.UserID, err)
+	}
+	return id, nil
+}
+
+// ToUser 转换为用户信息
+func (c *Claims) ToUser() *User {
+	return &User{
+		ID:       c.UserID,
+		Username: c.Username,
+		Email:    c.Email,
+		Roles:    append([]string(nil), c.Roles...),
+		IsActive: true, // 令牌有效则视为用户活跃
+	}
+}
+
+// legacyUser 将旧版的数字用户ID和单个角色转换为用户信息
+func legacyUser(userID int64, username, email, role string) *User {
+	user := &User{ID: strconv.FormatInt(userID, 10), Username: username, Email: email}
+	if role != "" {
+		user.Roles = []string{role}
+	}
+	return user
+}
+
+// newTokenID 生成令牌唯一ID（jti），同一秒内签发的令牌也互不相同
+func newTokenID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
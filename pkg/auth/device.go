@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// DeviceIDHeader 客户端提供设备ID的请求头，移动端等可自行生成并持久化
+const DeviceIDHeader = "X-Device-ID"
+
+// DeviceBindingMode 设备绑定校验严格程度
+type DeviceBindingMode string
+
+const (
+	// DeviceBindingOff 不校验设备
+	DeviceBindingOff DeviceBindingMode = "off"
+	// DeviceBindingLenient 仅校验已绑定设备的令牌和会话
+	DeviceBindingLenient DeviceBindingMode = "lenient"
+	// DeviceBindingStrict 要求令牌和会话绑定设备且设备一致
+	DeviceBindingStrict DeviceBindingMode = "strict"
+)
+
+// 设备绑定错误
+var (
+	ErrDeviceMismatch = errors.New("device fingerprint mismatch")
+	ErrDeviceNotBound = errors.New("credential is not bound to a device")
+)
+
+// ParseDeviceBindingMode 解析设备绑定模式，无法识别时关闭校验
+func ParseDeviceBindingMode(mode string) DeviceBindingMode {
+	switch DeviceBindingMode(strings.ToLower(mode)) {
+	case DeviceBindingLenient:
+		return DeviceBindingLenient
+	case DeviceBindingStrict:
+		return DeviceBindingStrict
+	default:
+		return DeviceBindingOff
+	}
+}
+
+// DeviceFingerprint 计算请求的设备指纹
+// 优先使用客户端提供的设备ID，否则使用 User-Agent 和 Accept-Language、Accept-Encoding 的哈希
+// Accept 随请求类型变化（页面、接口、图片），不参与计算
+func DeviceFingerprint(r *http.Request) string {
+	var source string
+	if deviceID := strings.TrimSpace(r.Header.Get(DeviceIDHeader)); deviceID != "" {
+		source = "id:" + deviceID
+	} else {
+		source = strings.Join([]string{
+			"ua:" + r.UserAgent(),
+			"lang:" + r.Header.Get("Accept-Language"),
+			"enc:" + r.Header.Get("Accept-Encoding"),
+		}, "\n")
+	}
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:16])
+}
+
+// CheckDevice 按模式校验凭证绑定的设备指纹与当前请求是否一致
+func CheckDevice(mode DeviceBindingMode, bound, current string) error {
+	switch mode {
+	case DeviceBindingLenient, DeviceBindingStrict:
+	default:
+		return nil
+	}
+
+	if bound == "" {
+		if mode == DeviceBindingStrict {
+			return ErrDeviceNotBound
+		}
+		return nil
+	}
+	if subtle.ConstantTimeCompare([]byte(bound), []byte(current)) != 1 {
+		return ErrDeviceMismatch
+	}
+	return nil
+}
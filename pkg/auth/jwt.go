@@ -4,160 +4,54 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/hwh/hwhkit-go/pkg/config"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// JWTManager JWT管理器
+// JWTManager 面向 User 的JWT管理器，与 Manager 签发和校验同一种令牌
+// 中间件需要 *Manager 时可通过 Manager() 获取
 type JWTManager struct {
-	config *config.JWTConfig
-}
-
-// Claims JWT声明
-type Claims struct {
-	UserID   string   `json:"user_id"`
-	Username string   `json:"username"`
-	Email    string   `json:"email"`
-	Roles    []string `json:"roles"`
-	jwt.RegisteredClaims
-}
-
-// TokenPair 令牌对
-type TokenPair struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresIn    int64  `json:"expires_in"`
-	TokenType    string `json:"token_type"`
-}
-
-// User 用户信息
-type User struct {
-	ID       string   `json:"id"`
-	Username string   `json:"username"`
-	Email    string   `json:"email"`
-	Password string   `json:"-"` // 不返回密码
-	Roles    []string `json:"roles"`
-	IsActive bool     `json:"is_active"`
+	manager *Manager
 }
 
 // NewJWTManager 创建JWT管理器
 func NewJWTManager(cfg *config.JWTConfig) *JWTManager {
 	return &JWTManager{
-		config: cfg,
+		manager: New(cfg),
 	}
 }
 
+// Manager 获取底层的JWT认证管理器，用于 middleware.JWTWithManager 等
+func (jm *JWTManager) Manager() *Manager {
+	return jm.manager
+}
+
 // GenerateToken 生成访问令牌
 func (jm *JWTManager) GenerateToken(user *User) (string, error) {
-	now := time.Now()
-	expiresAt := now.Add(time.Duration(jm.config.ExpireHours) * time.Hour)
-	
-	claims := &Claims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Roles:    user.Roles,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			IssuedAt:  jwt.NewNumericDate(now),
-			NotBefore: jwt.NewNumericDate(now),
-			Issuer:    jm.config.Issuer,
-			Subject:   user.ID,
-		},
-	}
-	
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(jm.config.Secret))
+	return jm.manager.GenerateTokenForUser(user)
 }
 
 // GenerateRefreshToken 生成刷新令牌
 func (jm *JWTManager) GenerateRefreshToken(user *User) (string, error) {
-	now := time.Now()
-	expiresAt := now.Add(time.Duration(jm.config.RefreshHours) * time.Hour)
-	
-	claims := &Claims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Roles:    user.Roles,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			IssuedAt:  jwt.NewNumericDate(now),
-			NotBefore: jwt.NewNumericDate(now),
-			Issuer:    jm.config.Issuer,
-			Subject:   user.ID,
-		},
-	}
-	
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(jm.config.Secret))
+	return jm.manager.generateRefreshToken(user, "")
 }
 
 // GenerateTokenPair 生成令牌对
 func (jm *JWTManager) GenerateTokenPair(user *User) (*TokenPair, error) {
-	accessToken, err := jm.GenerateToken(user)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
-	}
-	
-	refreshToken, err := jm.GenerateRefreshToken(user)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
-	}
-	
-	return &TokenPair{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    int64(jm.config.ExpireHours * 3600), // 转换为秒
-		TokenType:    "Bearer",
-	}, nil
+	return jm.manager.GenerateTokenPairForUser(user, "")
 }
 
 // ValidateToken 验证令牌
 func (jm *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(jm.config.Secret), nil
-	})
-	
-	if err != nil {
-		return nil, err
-	}
-	
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
-	}
-	
-	return nil, errors.New("invalid token")
+	return jm.manager.ValidateToken(tokenString)
 }
 
-// RefreshToken 刷新令牌
+// RefreshToken 刷新令牌，只接受刷新令牌
 func (jm *JWTManager) RefreshToken(refreshTokenString string) (*TokenPair, error) {
-	claims, err := jm.ValidateToken(refreshTokenString)
-	if err != nil {
-		return nil, fmt.Errorf("invalid refresh token: %w", err)
-	}
-	
-	// 检查是否为有效的刷新令牌（通常刷新令牌的过期时间更长）
-	now := time.Now()
-	if claims.ExpiresAt.Time.Before(now) {
-		return nil, errors.New("refresh token expired")
-	}
-	
-	// 创建用户对象用于生成新令牌
-	user := &User{
-		ID:       claims.UserID,
-		Username: claims.Username,
-		Email:    claims.Email,
-		Roles:    claims.Roles,
-	}
-	
-	return jm.GenerateTokenPair(user)
+	return jm.manager.RefreshToken(refreshTokenString)
 }
 
 // ExtractTokenFromHeader 从请求头中提取令牌
@@ -181,43 +75,22 @@ func (jm *JWTManager) GetUserFromToken(tokenString string) (*User, error) {
 		return nil, err
 	}
 	
-	return &User{
-		ID:       claims.UserID,
-		Username: claims.Username,
-		Email:    claims.Email,
-		Roles:    claims.Roles,
-		IsActive: true, // 假设令牌有效则用户活跃
-	}, nil
+	return claims.ToUser(), nil
 }
 
 // HasRole 检查用户是否具有指定角色
 func (jm *JWTManager) HasRole(claims *Claims, role string) bool {
-	for _, userRole := range claims.Roles {
-		if userRole == role {
-			return true
-		}
-	}
-	return false
+	return claims.HasRole(role)
 }
 
 // HasAnyRole 检查用户是否具有任意指定角色
 func (jm *JWTManager) HasAnyRole(claims *Claims, roles []string) bool {
-	for _, role := range roles {
-		if jm.HasRole(claims, role) {
-			return true
-		}
-	}
-	return false
+	return claims.HasAnyRole(roles...)
 }
 
 // HasAllRoles 检查用户是否具有所有指定角色
 func (jm *JWTManager) HasAllRoles(claims *Claims, roles []string) bool {
-	for _, role := range roles {
-		if !jm.HasRole(claims, role) {
-			return false
-		}
-	}
-	return true
+	return claims.HasAllRoles(roles...)
 }
 
 // IsTokenExpired 检查令牌是否过期
@@ -230,29 +103,48 @@ func (jm *JWTManager) GetTokenRemainingTime(claims *Claims) time.Duration {
 	return time.Until(claims.ExpiresAt.Time)
 }
 
-// PasswordManager 密码管理器
+// PasswordManager 密码管理器，支持 bcrypt 和 Argon2id
 type PasswordManager struct {
-	cost int
+	cost   int
+	config config.PasswordConfig
+	
+	dummyOnce sync.Once
+	dummyHash string
 }
 
-// NewPasswordManager 创建密码管理器
+// NewPasswordManager 创建使用 bcrypt 的密码管理器
 func NewPasswordManager(cost int) *PasswordManager {
-	if cost == 0 {
-		cost = bcrypt.DefaultCost
-	}
-	return &PasswordManager{
-		cost: cost,
-	}
+	return NewPasswordManagerWithConfig(&config.PasswordConfig{
+		Algorithm:  AlgorithmBcrypt,
+		BcryptCost: cost,
+	})
 }
 
-// HashPassword 哈希密码
+// HashPassword 按配置的算法哈希密码
 func (pm *PasswordManager) HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), pm.cost)
-	return string(bytes), err
+	start := time.Now()
+	defer authPasswordHashDuration.ObserveSince(start, "hash")
+	
+	switch pm.config.Algorithm {
+	case AlgorithmArgon2id:
+		return hashArgon2id(password, pm.argon2Params())
+	case AlgorithmBcrypt:
+		bytes, err := bcrypt.GenerateFromPassword([]byte(password), pm.cost)
+		return string(bytes), err
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnknownAlgorithm, pm.config.Algorithm)
+	}
 }
 
-// CheckPassword 检查密码
+// CheckPassword 检查密码，根据哈希格式自动识别算法
 func (pm *PasswordManager) CheckPassword(password, hash string) bool {
+	start := time.Now()
+	defer authPasswordHashDuration.ObserveSince(start, "verify")
+	
+	if strings.HasPrefix(hash, argon2idPrefix) {
+		ok, err := verifyArgon2id(password, hash)
+		return err == nil && ok
+	}
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
@@ -297,22 +189,42 @@ func (pm *PasswordManager) ValidatePasswordStrength(password string) error {
 	return nil
 }
 
+// 外部认证器错误，Login 据此区分登录失败原因
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator 外部认证器，由身份源（如LDAP）直接校验用户名和密码
+type Authenticator func(username, password string) (*User, error)
+
 // AuthService 认证服务
 type AuthService struct {
 	jwtManager      *JWTManager
 	passwordManager *PasswordManager
 	config          *config.JWTConfig
+	passwordUpdater func(*User) error
+	authenticator   Authenticator
 }
 
 // NewAuthService 创建认证服务
 func NewAuthService(cfg *config.JWTConfig) *AuthService {
 	return &AuthService{
 		jwtManager:      NewJWTManager(cfg),
-		passwordManager: NewPasswordManager(bcrypt.DefaultCost),
+		passwordManager: NewPasswordManagerWithConfig(&cfg.Password),
 		config:          cfg,
 	}
 }
 
+// NewAuthServiceWithManager 使用已有的JWT认证管理器创建认证服务，签发的令牌可直接被JWT中间件校验
+func NewAuthServiceWithManager(manager *Manager) *AuthService {
+	return &AuthService{
+		jwtManager:      &JWTManager{manager: manager},
+		passwordManager: NewPasswordManagerWithConfig(&manager.config.Password),
+		config:          manager.config,
+	}
+}
+
 // GetJWTManager 获取JWT管理器
 func (as *AuthService) GetJWTManager() *JWTManager {
 	return as.jwtManager
@@ -323,25 +235,92 @@ func (as *AuthService) GetPasswordManager() *PasswordManager {
 	return as.passwordManager
 }
 
+// SetPasswordUpdater 设置密码哈希升级回调
+// 登录成功且哈希算法或参数与当前配置不一致时，会用新哈希更新用户并调用该回调
+func (as *AuthService) SetPasswordUpdater(updater func(*User) error) {
+	as.passwordUpdater = updater
+}
+
+// SetAuthenticator 设置外部认证器
+// 设置后 Login 不再读取本地密码哈希，而是交由认证器校验，userProvider 可以为nil
+func (as *AuthService) SetAuthenticator(authenticator Authenticator) {
+	as.authenticator = authenticator
+}
+
 // Login 用户登录
 func (as *AuthService) Login(username, password string, userProvider func(string) (*User, error)) (*TokenPair, error) {
+	if as.authenticator != nil {
+		return as.loginWithAuthenticator(username, password)
+	}
+	
 	// 获取用户信息
 	user, err := userProvider(username)
 	if err != nil {
+		// 执行一次占位校验，避免通过响应耗时判断用户是否存在
+		as.passwordManager.CheckDummyPassword(password)
+		RecordLogin(LoginResultUserNotFound)
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
 	
 	if !user.IsActive {
+		as.passwordManager.CheckDummyPassword(password)
+		RecordLogin(LoginResultDisabled)
 		return nil, errors.New("user account is disabled")
 	}
 	
 	// 验证密码
 	if !as.passwordManager.CheckPassword(password, user.Password) {
+		RecordLogin(LoginResultInvalidPassword)
 		return nil, errors.New("invalid password")
 	}
 	
+	// 按当前配置升级密码哈希，升级失败不影响本次登录
+	if as.passwordUpdater != nil {
+		if rehash, err := as.passwordManager.NeedsRehash(user.Password); err == nil && rehash {
+			if hashedPassword, err := as.passwordManager.HashPassword(password); err == nil {
+				user.Password = hashedPassword
+				_ = as.passwordUpdater(user)
+			}
+		}
+	}
+	
 	// 生成令牌对
-	return as.jwtManager.GenerateTokenPair(user)
+	pair, err := as.jwtManager.GenerateTokenPair(user)
+	if err != nil {
+		RecordLogin(LoginResultError)
+		return nil, err
+	}
+	RecordLogin(LoginResultSuccess)
+	return pair, nil
+}
+
+// loginWithAuthenticator 使用外部认证器登录
+func (as *AuthService) loginWithAuthenticator(username, password string) (*TokenPair, error) {
+	user, err := as.authenticator(username, password)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			RecordLogin(LoginResultUserNotFound)
+		case errors.Is(err, ErrInvalidCredentials):
+			RecordLogin(LoginResultInvalidPassword)
+		default:
+			RecordLogin(LoginResultError)
+		}
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+	
+	if !user.IsActive {
+		RecordLogin(LoginResultDisabled)
+		return nil, errors.New("user account is disabled")
+	}
+	
+	pair, err := as.jwtManager.GenerateTokenPair(user)
+	if err != nil {
+		RecordLogin(LoginResultError)
+		return nil, err
+	}
+	RecordLogin(LoginResultSuccess)
+	return pair, nil
 }
 
 // Register 用户注册
@@ -376,7 +355,9 @@ func (as *AuthService) Register(username, email, password string, roles []string
 }
 
 // ChangePassword 修改密码
-func (as *AuthService) ChangePassword(userID, oldPassword, newPassword string, userProvider func(string) (*User, error), userUpdater func(*User) error) error {
+func (as *AuthService) ChangePassword(userID, oldPassword, newPassword string, userProvider func(string) (*User, error), userUpdater func(*User) error) (err error) {
+	defer func() { authPasswordChanges.Inc(resultLabel(err)) }()
+	
 	// 获取用户信息
 	user, err := userProvider(userID)
 	if err != nil {
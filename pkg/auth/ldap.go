@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPConfig LDAP/Active Directory 认证配置
+type LDAPConfig struct {
+	URL               string            // 服务地址，如 ldap://ldap.example.com:389 或 ldaps://dc.example.com:636
+	StartTLS          bool              // ldap:// 连接是否升级为TLS
+	TLSConfig         *tls.Config       // TLS配置，为nil时按URL主机名校验证书
+	BindDN            string            // 用于查找用户的服务账号DN，为空时匿名查找
+	BindPassword      string            // 服务账号密码
+	BaseDN            string            // 用户查找的根DN
+	UserFilter        string            // 用户过滤器，%s 替换为转义后的用户名
+	IDAttribute       string            // 用户ID属性，为空时使用DN
+	UsernameAttribute string            // 用户名属性
+	EmailAttribute    string            // 邮箱属性，默认 mail
+	GroupAttribute    string            // 用户组属性，默认 memberOf
+	RoleMap           map[string]string // 用户组DN到角色的映射，为nil时直接使用组DN作为角色
+	DefaultRoles      []string          // 所有目录用户都具备的角色
+	PoolSize          int               // 连接池大小，默认 5
+	Timeout           time.Duration     // 连接和请求超时，默认 10s
+}
+
+// 常用目录的默认用户过滤器
+const (
+	LDAPFilterOpenLDAP        = "(&(objectClass=inetOrgPerson)(uid=%s))"
+	LDAPFilterActiveDirectory = "(&(objectCategory=person)(objectClass=user)(sAMAccountName=%s))"
+)
+
+// LDAPProvider LDAP用户提供方，通过绑定用户DN校验密码
+type LDAPProvider struct {
+	config LDAPConfig
+	pool   chan *ldap.Conn
+}
+
+// NewLDAPProvider 创建LDAP用户提供方
+func NewLDAPProvider(cfg LDAPConfig) (*LDAPProvider, error) {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return nil, errors.New("ldap url and base dn are required")
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = LDAPFilterOpenLDAP
+	}
+	if !strings.Contains(cfg.UserFilter, "%s") {
+		return nil, fmt.Errorf("ldap user filter must contain %%s: %q", cfg.UserFilter)
+	}
+	if cfg.UsernameAttribute == "" {
+		cfg.UsernameAttribute = "uid"
+		if cfg.UserFilter == LDAPFilterActiveDirectory {
+			cfg.UsernameAttribute = "sAMAccountName"
+		}
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 5
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return &LDAPProvider{
+		config: cfg,
+		pool:   make(chan *ldap.Conn, cfg.PoolSize),
+	}, nil
+}
+
+// dialConn 建立新连接
+func (p *LDAPProvider) dialConn() (*ldap.Conn, error) {
+	opts := []ldap.DialOpt{ldap.DialWithDialer(&net.Dialer{Timeout: p.config.Timeout})}
+	if p.config.TLSConfig != nil {
+		opts = append(opts, ldap.DialWithTLSConfig(p.config.TLSConfig))
+	}
+
+	conn, err := ldap.DialURL(p.config.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ldap: %w", err)
+	}
+	conn.SetTimeout(p.config.Timeout)
+
+	if p.config.StartTLS {
+		tlsConfig := p.config.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: hostFromURL(p.config.URL)}
+		}
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// getConn 从连接池获取连接，池为空时新建
+func (p *LDAPProvider) getConn() (*ldap.Conn, error) {
+	select {
+	case conn := <-p.pool:
+		if !conn.IsClosing() {
+			return conn, nil
+		}
+	default:
+	}
+	return p.dialConn()
+}
+
+// putConn 归还连接，池已满或连接已断开时关闭
+func (p *LDAPProvider) putConn(conn *ldap.Conn, healthy bool) {
+	if !healthy || conn.IsClosing() {
+		conn.Close()
+		return
+	}
+	select {
+	case p.pool <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// bindService 以服务账号身份绑定，用于查找用户
+func (p *LDAPProvider) bindService(conn *ldap.Conn) error {
+	if p.config.BindDN == "" {
+		return conn.UnauthenticatedBind("")
+	}
+	return conn.Bind(p.config.BindDN, p.config.BindPassword)
+}
+
+// findUser 按用户名查找目录条目
+func (p *LDAPProvider) findUser(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	if err := p.bindService(conn); err != nil {
+		return nil, fmt.Errorf("ldap service bind failed: %w", err)
+	}
+
+	attributes := []string{p.config.UsernameAttribute, p.config.EmailAttribute, p.config.GroupAttribute}
+	if p.config.IDAttribute != "" {
+		attributes = append(attributes, p.config.IDAttribute)
+	}
+	req := ldap.NewSearchRequest(
+		p.config.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(p.config.Timeout/time.Second), false,
+		fmt.Sprintf(p.config.UserFilter, ldap.EscapeFilter(username)),
+		attributes,
+		nil,
+	)
+
+	result, err := conn.Search(req)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("ldap search failed: %w", err)
+	}
+	switch {
+	case result == nil || len(result.Entries) == 0:
+		return nil, ErrUserNotFound
+	case len(result.Entries) > 1:
+		return nil, fmt.Errorf("ldap filter matched multiple entries for %q", username)
+	}
+	return result.Entries[0], nil
+}
+
+// Authenticate 查找用户并以其DN和密码绑定，成功后返回映射的用户
+// 可以通过 AuthService.SetAuthenticator 接入登录流程
+func (p *LDAPProvider) Authenticate(username, password string) (*User, error) {
+	// 空密码在多数目录上会被当作匿名绑定而成功，必须拒绝
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := p.getConn()
+	if err != nil {
+		return nil, err
+	}
+	healthy := true
+	defer func() { p.putConn(conn, healthy) }()
+
+	entry, err := p.findUser(conn, username)
+	if err != nil {
+		healthy = errors.Is(err, ErrUserNotFound)
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		healthy = false
+		return nil, fmt.Errorf("ldap bind failed: %w", err)
+	}
+
+	return p.entryToUser(entry), nil
+}
+
+// LookupUser 按用户名查找用户，不校验密码
+// 签名与 Login、ChangePassword 的 userProvider 参数一致
+func (p *LDAPProvider) LookupUser(username string) (*User, error) {
+	conn, err := p.getConn()
+	if err != nil {
+		return nil, err
+	}
+	healthy := true
+	defer func() { p.putConn(conn, healthy) }()
+
+	entry, err := p.findUser(conn, username)
+	if err != nil {
+		healthy = errors.Is(err, ErrUserNotFound)
+		return nil, err
+	}
+	return p.entryToUser(entry), nil
+}
+
+// entryToUser 将目录条目映射为用户
+func (p *LDAPProvider) entryToUser(entry *ldap.Entry) *User {
+	user := &User{
+		ID:       entry.DN,
+		Username: entry.GetAttributeValue(p.config.UsernameAttribute),
+		Email:    entry.GetAttributeValue(p.config.EmailAttribute),
+		IsActive: true,
+	}
+	if p.config.IDAttribute != "" {
+		if id := entry.GetAttributeValue(p.config.IDAttribute); id != "" {
+			user.ID = id
+		}
+	}
+
+	roles := append([]string(nil), p.config.DefaultRoles...)
+	roles = append(roles, mapGroupsToRoles(entry.GetAttributeValues(p.config.GroupAttribute), p.config.RoleMap)...)
+	user.Roles = mapGroupsToRoles(roles, nil)
+	return user
+}
+
+// Close 关闭连接池中的所有连接
+func (p *LDAPProvider) Close() {
+	for {
+		select {
+		case conn := <-p.pool:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// hostFromURL 从LDAP地址中提取主机名，用于TLS证书校验
+func hostFromURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hwh/hwhkit-go/pkg/config"
+)
+
+func TestLDAPProviderConfig(t *testing.T) {
+	if _, err := NewLDAPProvider(LDAPConfig{URL: "ldap://localhost"}); err == nil {
+		t.Error("Expected error without base DN")
+	}
+	if _, err := NewLDAPProvider(LDAPConfig{URL: "ldap://localhost", BaseDN: "dc=example,dc=com", UserFilter: "(uid=alice)"}); err == nil {
+		t.Error("Expected error for filter without placeholder")
+	}
+
+	provider, err := NewLDAPProvider(LDAPConfig{
+		URL:        "ldaps://dc.example.com:636",
+		BaseDN:     "dc=example,dc=com",
+		UserFilter: LDAPFilterActiveDirectory,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if provider.config.UsernameAttribute != "sAMAccountName" {
+		t.Errorf("Expected sAMAccountName for Active Directory, got %s", provider.config.UsernameAttribute)
+	}
+	if hostFromURL(provider.config.URL) != "dc.example.com" {
+		t.Errorf("Unexpected host: %s", hostFromURL(provider.config.URL))
+	}
+}
+
+func TestLDAPProviderEntryToUser(t *testing.T) {
+	provider, _ := NewLDAPProvider(LDAPConfig{
+		URL:          "ldap://localhost",
+		BaseDN:       "dc=example,dc=com",
+		RoleMap:      map[string]string{"cn=admins,ou=groups,dc=example,dc=com": "admin"},
+		DefaultRoles: []string{"user"},
+	})
+
+	entry := ldap.NewEntry("uid=alice,ou=people,dc=example,dc=com", map[string][]string{
+		"uid":      {"alice"},
+		"mail":     {"alice@example.com"},
+		"memberOf": {"cn=admins,ou=groups,dc=example,dc=com", "cn=other,ou=groups,dc=example,dc=com"},
+	})
+
+	user := provider.entryToUser(entry)
+	if user.ID != entry.DN || user.Username != "alice" || user.Email != "alice@example.com" || !user.IsActive {
+		t.Errorf("Unexpected user: %+v", user)
+	}
+	if len(user.Roles) != 2 || user.Roles[0] != "user" || user.Roles[1] != "admin" {
+		t.Errorf("Unexpected roles: %v", user.Roles)
+	}
+}
+
+func TestLDAPProviderAuthenticateErrors(t *testing.T) {
+	provider, _ := NewLDAPProvider(LDAPConfig{URL: "ldap://127.0.0.1:1", BaseDN: "dc=example,dc=com"})
+
+	if _, err := provider.Authenticate("alice", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials for empty password, got %v", err)
+	}
+	if _, err := provider.Authenticate("alice", "secret"); err == nil {
+		t.Error("Expected connection error")
+	}
+}
+
+func TestAuthServiceLoginWithAuthenticator(t *testing.T) {
+	service := NewAuthService(&config.JWTConfig{Secret: "test-secret", ExpireHours: 1, RefreshHours: 24, Issuer: "test"})
+	service.SetAuthenticator(func(username, password string) (*User, error) {
+		switch {
+		case username != "alice":
+			return nil, ErrUserNotFound
+		case password != "secret":
+			return nil, ErrInvalidCredentials
+		}
+		return &User{ID: "uid=alice", Username: "alice", Roles: []string{"user"}, IsActive: true}, nil
+	})
+
+	pair, err := service.Login("alice", "secret", nil)
+	if err != nil {
+		t.Fatalf("Login should succeed: %v", err)
+	}
+	if pair.AccessToken == "" {
+		t.Error("Expected access token")
+	}
+
+	if _, err := service.Login("alice", "wrong", nil); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := service.Login("bob", "secret", nil); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
@@ -3,33 +3,19 @@ package auth
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/hwh/hwhkit-go/pkg/config"
 )
 
-// Claims JWT声明结构
-type Claims struct {
-	UserID   int64  `json:"user_id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
-	jwt.RegisteredClaims
-}
-
-// TokenPair 令牌对
-type TokenPair struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresAt    int64  `json:"expires_at"`
-	TokenType    string `json:"token_type"`
-}
-
 // Manager JWT认证管理器
 type Manager struct {
 	config        *config.JWTConfig
 	signingMethod jwt.SigningMethod
+	revocations   RevocationStore
+	userLoader    func(userID string) (*User, error)
 }
 
 // New 创建新的JWT认证管理器
@@ -41,18 +27,32 @@ func New(cfg *config.JWTConfig) *Manager {
 }
 
 // GenerateToken 生成访问令牌
+//
+// Deprecated: 使用 GenerateTokenForUser，支持字符串用户ID和多角色
 func (m *Manager) GenerateToken(userID int64, username, email, role string) (string, error) {
+	return m.generateToken(legacyUser(userID, username, email, role), "")
+}
+
+// GenerateTokenForUser 为用户生成访问令牌
+func (m *Manager) GenerateTokenForUser(user *User) (string, error) {
+	return m.generateToken(user, "")
+}
+
+// generateToken 生成访问令牌，device 为空时不绑定设备
+func (m *Manager) generateToken(user *User, device string) (string, error) {
 	now := time.Now()
 	expiresAt := now.Add(time.Duration(m.config.ExpireHours) * time.Hour)
 	
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
-		Role:     role,
+		UserID:   user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Roles:    user.Roles,
+		Device:   device,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    m.config.Issuer,
-			Subject:   fmt.Sprintf("%d", userID),
+			Subject:   user.ID,
+			ID:        newTokenID(),
 			Audience:  []string{m.config.Issuer},
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			NotBefore: jwt.NewNumericDate(now),
@@ -65,16 +65,26 @@ func (m *Manager) GenerateToken(userID int64, username, email, role string) (str
 }
 
 // GenerateRefreshToken 生成刷新令牌
+//
+// Deprecated: 使用 GenerateTokenPairForUser
 func (m *Manager) GenerateRefreshToken(userID int64, username string) (string, error) {
+	return m.generateRefreshToken(legacyUser(userID, username, "", ""), "")
+}
+
+// generateRefreshToken 生成刷新令牌，device 为空时不绑定设备
+// 刷新令牌不携带邮箱和角色，刷新时由 SetUserLoader 设置的回调重新加载
+func (m *Manager) generateRefreshToken(user *User, device string) (string, error) {
 	now := time.Now()
 	expiresAt := now.Add(time.Duration(m.config.RefreshHours) * time.Hour)
 	
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:   user.ID,
+		Username: user.Username,
+		Device:   device,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    m.config.Issuer,
-			Subject:   fmt.Sprintf("refresh:%d", userID),
+			Subject:   refreshSubjectPrefix + user.ID,
+			ID:        newTokenID(),
 			Audience:  []string{m.config.Issuer},
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			NotBefore: jwt.NewNumericDate(now),
@@ -87,36 +97,54 @@ func (m *Manager) GenerateRefreshToken(userID int64, username string) (string, e
 }
 
 // GenerateTokenPair 生成令牌对
+//
+// Deprecated: 使用 GenerateTokenPairForUser，支持字符串用户ID和多角色
 func (m *Manager) GenerateTokenPair(userID int64, username, email, role string) (*TokenPair, error) {
-	accessToken, err := m.GenerateToken(userID, username, email, role)
+	return m.GenerateTokenPairForUser(legacyUser(userID, username, email, role), "")
+}
+
+// GenerateTokenPairForDevice 生成绑定设备指纹的令牌对
+//
+// Deprecated: 使用 GenerateTokenPairForUser
+func (m *Manager) GenerateTokenPairForDevice(userID int64, username, email, role, device string) (*TokenPair, error) {
+	return m.GenerateTokenPairForUser(legacyUser(userID, username, email, role), device)
+}
+
+// GenerateTokenPairForUser 为用户生成令牌对，device 为空时不绑定设备，通常由 DeviceFingerprint 计算
+// 刷新时新令牌沿用原设备指纹
+func (m *Manager) GenerateTokenPairForUser(user *User, device string) (*TokenPair, error) {
+	accessToken, err := m.generateToken(user, device)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 	
-	refreshToken, err := m.GenerateRefreshToken(userID, username)
+	refreshToken, err := m.generateRefreshToken(user, device)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 	
-	expiresAt := time.Now().Add(time.Duration(m.config.ExpireHours) * time.Hour).Unix()
+	expiresIn := int64(m.config.ExpireHours * 3600)
 	
 	return &TokenPair{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		ExpiresAt:    expiresAt,
+		ExpiresIn:    expiresIn,
+		ExpiresAt:    time.Now().Unix() + expiresIn,
 		TokenType:    "Bearer",
 	}, nil
 }
 
 // ValidateToken 验证令牌
-func (m *Manager) ValidateToken(tokenString string) (*Claims, error) {
+func (m *Manager) ValidateToken(tokenString string) (result *Claims, err error) {
+	defer func(start time.Time) { observeTokenValidation(start, err) }(time.Now())
+	
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		// 验证签名方法
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(m.config.Secret), nil
-	})
+	}, jwt.WithLeeway(m.config.Leeway()), jwt.WithIssuedAt())
 	
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -136,11 +164,24 @@ func (m *Manager) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, errors.New("invalid token issuer")
 	}
 	
+	// 检查吊销列表，吊销列表不可用时拒绝令牌
+	if m.revocations != nil && claims.ID != "" {
+		revoked, err := m.revocations.IsRevoked(claims.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check token revocation: %w", err)
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
+	}
+	
 	return claims, nil
 }
 
 // RefreshToken 刷新令牌
-func (m *Manager) RefreshToken(refreshTokenString string) (*TokenPair, error) {
+func (m *Manager) RefreshToken(refreshTokenString string) (pair *TokenPair, err error) {
+	defer func() { authTokenRefreshes.Inc(resultLabel(err)) }()
+	
 	// 验证刷新令牌
 	claims, err := m.ValidateToken(refreshTokenString)
 	if err != nil {
@@ -148,21 +189,57 @@ func (m *Manager) RefreshToken(refreshTokenString string) (*TokenPair, error) {
 	}
 	
 	// 检查是否为刷新令牌
-	if claims.Subject == "" || claims.Subject[:8] != "refresh:" {
+	if !claims.IsRefresh() {
 		return nil, errors.New("not a refresh token")
 	}
 	
+	// 重新加载用户，使新令牌使用当前的角色；未设置加载回调时新令牌不携带邮箱和角色
+	user := claims.ToUser()
+	if m.userLoader != nil {
+		if user, err = m.userLoader(claims.UserID); err != nil {
+			return nil, fmt.Errorf("failed to load user: %w", err)
+		}
+		if !user.IsActive {
+			return nil, errors.New("user account is disabled")
+		}
+	}
+	
 	// 生成新的令牌对
-	return m.GenerateTokenPair(claims.UserID, claims.Username, claims.Email, claims.Role)
+	return m.GenerateTokenPairForUser(user, claims.Device)
+}
+
+// SetUserLoader 设置刷新令牌时加载用户的回调，刷新后的访问令牌使用回调返回的邮箱和角色
+// 用户被禁用或回调返回错误时拒绝刷新
+func (m *Manager) SetUserLoader(loader func(userID string) (*User, error)) {
+	m.userLoader = loader
+}
+
+// DeviceBinding 获取配置的设备绑定模式
+func (m *Manager) DeviceBinding() DeviceBindingMode {
+	return ParseDeviceBindingMode(m.config.DeviceBinding)
+}
+
+// ValidateTokenForDevice 验证令牌并按配置的设备绑定模式校验设备指纹
+func (m *Manager) ValidateTokenForDevice(tokenString, device string) (*Claims, error) {
+	claims, err := m.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckDevice(m.DeviceBinding(), claims.Device, device); err != nil {
+		return nil, err
+	}
+	return claims, nil
 }
 
-// ExtractUserID 从令牌中提取用户ID
+// ExtractUserID 从令牌中提取数字用户ID
+//
+// Deprecated: 使用 ValidateToken 获取 Claims.UserID，非数字用户ID会返回错误
 func (m *Manager) ExtractUserID(tokenString string) (int64, error) {
 	claims, err := m.ValidateToken(tokenString)
 	if err != nil {
 		return 0, err
 	}
-	return claims.UserID, nil
+	return claims.UserIDInt64()
 }
 
 // ExtractUsername 从令牌中提取用户名
@@ -174,13 +251,22 @@ func (m *Manager) ExtractUsername(tokenString string) (string, error) {
 	return claims.Username, nil
 }
 
-// ExtractRole 从令牌中提取角色
+// ExtractRole 从令牌中提取主角色
 func (m *Manager) ExtractRole(tokenString string) (string, error) {
 	claims, err := m.ValidateToken(tokenString)
 	if err != nil {
 		return "", err
 	}
-	return claims.Role, nil
+	return claims.PrimaryRole(), nil
+}
+
+// ExtractRoles 从令牌中提取所有角色
+func (m *Manager) ExtractRoles(tokenString string) ([]string, error) {
+	claims, err := m.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	return claims.Roles, nil
 }
 
 // IsTokenExpired 检查令牌是否过期
@@ -190,7 +276,7 @@ func (m *Manager) IsTokenExpired(tokenString string) (bool, error) {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(m.config.Secret), nil
-	})
+	}, jwt.WithLeeway(m.config.Leeway()))
 	
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -204,7 +290,7 @@ func (m *Manager) IsTokenExpired(tokenString string) (bool, error) {
 		return false, errors.New("invalid token claims")
 	}
 	
-	return claims.ExpiresAt.Before(time.Now()), nil
+	return claims.ExpiresAt.Add(m.config.Leeway()).Before(time.Now()), nil
 }
 
 // GetTokenClaims 获取令牌声明信息（包括过期的令牌）
@@ -239,14 +325,11 @@ func (m *Manager) ValidateRole(tokenString string, requiredRoles ...string) erro
 		return nil // 无角色要求
 	}
 	
-	userRole := claims.Role
-	for _, role := range requiredRoles {
-		if userRole == role {
-			return nil
-		}
+	if claims.HasAnyRole(requiredRoles...) {
+		return nil
 	}
 	
-	return fmt.Errorf("insufficient permissions: required one of %v, got %s", requiredRoles, userRole)
+	return fmt.Errorf("insufficient permissions: required one of %v, got %s", requiredRoles, strings.Join(claims.Roles, ","))
 }
 
 // GetTokenExpiration 获取令牌过期时间
@@ -1,9 +1,12 @@
 package auth
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/hwh/hwhkit-go/pkg/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -36,10 +39,10 @@ func TestGenerateToken(t *testing.T) {
 	// 验证生成的令牌
 	claims, err := manager.ValidateToken(token)
 	require.NoError(t, err)
-	assert.Equal(t, int64(123), claims.UserID)
+	assert.Equal(t, "123", claims.UserID)
 	assert.Equal(t, "testuser", claims.Username)
 	assert.Equal(t, "test@example.com", claims.Email)
-	assert.Equal(t, "admin", claims.Role)
+	assert.Equal(t, []string{"admin"}, claims.Roles)
 	assert.Equal(t, "test-app", claims.Issuer)
 }
 
@@ -70,7 +73,7 @@ func TestGenerateTokenPair(t *testing.T) {
 	// 验证访问令牌
 	claims, err := manager.ValidateToken(tokenPair.AccessToken)
 	require.NoError(t, err)
-	assert.Equal(t, int64(123), claims.UserID)
+	assert.Equal(t, "123", claims.UserID)
 	assert.Equal(t, "testuser", claims.Username)
 	
 	// 验证刷新令牌
@@ -89,7 +92,7 @@ func TestValidateToken(t *testing.T) {
 	// 验证有效令牌
 	claims, err := manager.ValidateToken(token)
 	require.NoError(t, err)
-	assert.Equal(t, int64(123), claims.UserID)
+	assert.Equal(t, "123", claims.UserID)
 	assert.Equal(t, "testuser", claims.Username)
 	
 	// 测试无效令牌
@@ -136,7 +139,7 @@ func TestRefreshToken(t *testing.T) {
 	// 验证新的访问令牌
 	claims, err := manager.ValidateToken(newPair.AccessToken)
 	require.NoError(t, err)
-	assert.Equal(t, int64(123), claims.UserID)
+	assert.Equal(t, "123", claims.UserID)
 	assert.Equal(t, "testuser", claims.Username)
 }
 
@@ -246,8 +249,8 @@ func TestGetTokenExpiration(t *testing.T) {
 	expiration, err := manager.GetTokenExpiration(token)
 	require.NoError(t, err)
 	
-	// 过期时间应该在生成前+1小时到生成后+1小时之间
-	expectedMin := beforeGeneration.Add(time.Hour)
+	// 过期时间应该在生成前+1小时到生成后+1小时之间，JWT的时间精确到秒
+	expectedMin := beforeGeneration.Truncate(time.Second).Add(time.Hour)
 	expectedMax := afterGeneration.Add(time.Hour)
 	
 	assert.True(t, expiration.After(expectedMin) || expiration.Equal(expectedMin))
@@ -272,6 +275,174 @@ func TestGetTokenClaims(t *testing.T) {
 	// 但GetTokenClaims应该成功
 	claims, err := manager.GetTokenClaims(token)
 	require.NoError(t, err)
-	assert.Equal(t, int64(123), claims.UserID)
+	assert.Equal(t, "123", claims.UserID)
 	assert.Equal(t, "testuser", claims.Username)
-}
\ No newline at end of file
+}
+
+func TestValidateTokenWithLeeway(t *testing.T) {
+	cfg := getTestConfig()
+	now := time.Now()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		UserID: "123",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    cfg.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now.Add(10 * time.Second)),
+			NotBefore: jwt.NewNumericDate(now.Add(10 * time.Second)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	})
+	signed, err := token.SignedString([]byte(cfg.Secret))
+	require.NoError(t, err)
+
+	_, err = New(cfg).ValidateToken(signed)
+	assert.Error(t, err)
+
+	cfg.LeewaySeconds = 30
+	claims, err := New(cfg).ValidateToken(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "123", claims.UserID)
+}
+
+func TestDeviceBoundTokens(t *testing.T) {
+	cfg := getTestConfig()
+	cfg.DeviceBinding = "strict"
+	manager := New(cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "laptop")
+	device := DeviceFingerprint(req)
+
+	pair, err := manager.GenerateTokenPairForDevice(1, "alice", "alice@example.com", "user", device)
+	require.NoError(t, err)
+
+	claims, err := manager.ValidateTokenForDevice(pair.AccessToken, device)
+	require.NoError(t, err)
+	assert.Equal(t, device, claims.Device)
+
+	other := httptest.NewRequest(http.MethodGet, "/", nil)
+	other.Header.Set("User-Agent", "attacker")
+	_, err = manager.ValidateTokenForDevice(pair.AccessToken, DeviceFingerprint(other))
+	assert.ErrorIs(t, err, ErrDeviceMismatch)
+
+	// 严格模式拒绝未绑定设备的令牌
+	unbound, err := manager.GenerateToken(1, "alice", "alice@example.com", "user")
+	require.NoError(t, err)
+	_, err = manager.ValidateTokenForDevice(unbound, device)
+	assert.ErrorIs(t, err, ErrDeviceNotBound)
+
+	// 宽松模式只校验已绑定的令牌
+	cfg.DeviceBinding = "lenient"
+	_, err = manager.ValidateTokenForDevice(unbound, device)
+	assert.NoError(t, err)
+
+	// 刷新后沿用设备指纹
+	refreshed, err := manager.RefreshToken(pair.RefreshToken)
+	require.NoError(t, err)
+	claims, err = manager.ValidateToken(refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, device, claims.Device)
+
+	// 客户端提供的设备ID优先
+	req.Header.Set(DeviceIDHeader, "device-123")
+	other.Header.Set(DeviceIDHeader, "device-123")
+	assert.Equal(t, DeviceFingerprint(req), DeviceFingerprint(other))
+}
+
+func TestUnifiedTokenModel(t *testing.T) {
+	cfg := getTestConfig()
+	manager := New(cfg)
+	user := &User{ID: "u-42", Username: "alice", Email: "alice@example.com", Roles: []string{"editor", "admin"}}
+
+	// JWTManager 和 Manager 签发的令牌可以互相校验
+	jwtManager := NewJWTManager(cfg)
+	pair, err := jwtManager.GenerateTokenPair(user)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3600), pair.ExpiresIn)
+	assert.Greater(t, pair.ExpiresAt, time.Now().Unix())
+
+	claims, err := manager.ValidateToken(pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "u-42", claims.UserID)
+	assert.Equal(t, "editor", claims.PrimaryRole())
+	assert.True(t, claims.HasAnyRole("viewer", "admin"))
+	assert.True(t, claims.HasAllRoles("editor", "admin"))
+	assert.False(t, claims.HasAllRoles("editor", "owner"))
+	_, err = claims.UserIDInt64()
+	assert.Error(t, err)
+
+	// 刷新令牌不携带邮箱和角色
+	refreshClaims, err := manager.ValidateToken(pair.RefreshToken)
+	require.NoError(t, err)
+	assert.True(t, refreshClaims.IsRefresh())
+	assert.Empty(t, refreshClaims.Roles)
+	assert.Empty(t, refreshClaims.Email)
+
+	// 刷新时重新加载用户，保留全部角色和邮箱
+	manager.SetUserLoader(func(userID string) (*User, error) {
+		assert.Equal(t, "u-42", userID)
+		return &User{ID: userID, Username: "alice", Email: "alice@example.com", Roles: []string{"editor", "admin"}, IsActive: true}, nil
+	})
+	refreshed, err := manager.RefreshToken(pair.RefreshToken)
+	require.NoError(t, err)
+	claims, err = jwtManager.ValidateToken(refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"editor", "admin"}, claims.Roles)
+	assert.Equal(t, "alice@example.com", claims.Email)
+
+	// 用户被禁用或加载失败时拒绝刷新
+	manager.SetUserLoader(func(userID string) (*User, error) {
+		return &User{ID: userID, Roles: []string{"admin"}}, nil
+	})
+	_, err = manager.RefreshToken(pair.RefreshToken)
+	assert.Error(t, err)
+	manager.SetUserLoader(func(userID string) (*User, error) {
+		return nil, ErrUserNotFound
+	})
+	_, err = manager.RefreshToken(pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	manager.SetUserLoader(nil)
+
+	// JWTManager 不再接受访问令牌刷新
+	_, err = jwtManager.RefreshToken(pair.AccessToken)
+	assert.Error(t, err)
+
+	// 认证服务与中间件共用同一个管理器
+	service := NewAuthServiceWithManager(manager)
+	assert.Same(t, manager, service.GetJWTManager().Manager())
+}
+
+func TestLegacyClaimsCompatibility(t *testing.T) {
+	cfg := getTestConfig()
+	now := time.Now()
+
+	// 旧版 Manager 签发的令牌：数字 user_id 和单个 role
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":  123,
+		"username": "legacy",
+		"role":     "admin",
+		"iss":      cfg.Issuer,
+		"iat":      now.Unix(),
+		"exp":      now.Add(time.Hour).Unix(),
+	})
+	signed, err := token.SignedString([]byte(cfg.Secret))
+	require.NoError(t, err)
+
+	manager := New(cfg)
+	claims, err := manager.ValidateToken(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "123", claims.UserID)
+	assert.Equal(t, []string{"admin"}, claims.Roles)
+
+	userID, err := claims.UserIDInt64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(123), userID)
+	assert.NoError(t, manager.ValidateRole(signed, "admin"))
+
+	// 旧接口生成的令牌使用字符串ID和角色列表
+	legacy, err := manager.GenerateToken(7, "bob", "bob@example.com", "user")
+	require.NoError(t, err)
+	roles, err := manager.ExtractRoles(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user"}, roles)
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/metrics"
+)
+
+// 登录结果标签
+const (
+	LoginResultSuccess         = "success"
+	LoginResultUserNotFound    = "user_not_found"
+	LoginResultDisabled        = "disabled"
+	LoginResultInvalidPassword = "invalid_password"
+	LoginResultError           = "error"
+)
+
+var (
+	authLogins = metrics.Default.Counter("hwhkit_auth_logins_total",
+		"Total number of login attempts", "result")
+	authTokenRefreshes = metrics.Default.Counter("hwhkit_auth_token_refreshes_total",
+		"Total number of token refresh attempts", "result")
+	authTokenRevocations = metrics.Default.Counter("hwhkit_auth_token_revocations_total",
+		"Total number of revoked tokens")
+	authPasswordChanges = metrics.Default.Counter("hwhkit_auth_password_changes_total",
+		"Total number of password change attempts", "result")
+	authPasswordResets = metrics.Default.Counter("hwhkit_auth_password_resets_total",
+		"Total number of password resets")
+	authLockouts = metrics.Default.Counter("hwhkit_auth_lockouts_total",
+		"Total number of account lockouts")
+	authPasswordHashDuration = metrics.Default.Histogram("hwhkit_auth_password_hash_duration_seconds",
+		"Password hashing and verification latency in seconds", nil, "operation")
+	authTokenValidationDuration = metrics.Default.Histogram("hwhkit_auth_token_validation_duration_seconds",
+		"Token validation latency in seconds", nil, "result")
+	authRevokedTokens = metrics.Default.Gauge("hwhkit_auth_revoked_tokens",
+		"Current number of entries in the token revocation list")
+	rbacChecks = metrics.Default.Counter("hwhkit_rbac_checks_total",
+		"Total number of RBAC checks", "check", "result")
+)
+
+// RBAC检查类型标签
+const (
+	RBACCheckPermission = "permission"
+	RBACCheckResource   = "resource"
+	RBACCheckRole       = "role"
+	RBACCheckPolicy     = "policy"
+)
+
+// RecordLogin 记录一次登录结果，供自定义登录流程使用
+func RecordLogin(result string) {
+	authLogins.Inc(result)
+}
+
+// RecordTokenRevocation 记录令牌吊销，供应用层的吊销逻辑调用
+func RecordTokenRevocation() {
+	authTokenRevocations.Inc()
+}
+
+// SetRevokedTokenCount 设置令牌吊销列表的当前大小，供应用层的吊销列表在增删时调用
+func SetRevokedTokenCount(count int) {
+	authRevokedTokens.Set(float64(count))
+}
+
+// RecordRBACCheck 记录一次权限检查结果并原样返回，供中间件等外部权限检查使用
+func RecordRBACCheck(check string, allowed bool) bool {
+	result := "allowed"
+	if !allowed {
+		result = "denied"
+	}
+	rbacChecks.Inc(check, result)
+	return allowed
+}
+
+// recordRBACCheck 返回记录检查结果的函数，存储读取失败时记录为 error
+func recordRBACCheck(check string) func(allowed bool, err error) (bool, error) {
+	return func(allowed bool, err error) (bool, error) {
+		if err != nil {
+			rbacChecks.Inc(check, "error")
+			return false, err
+		}
+		return RecordRBACCheck(check, allowed), nil
+	}
+}
+
+// RecordPasswordReset 记录密码重置，供应用层的重置流程调用
+func RecordPasswordReset() {
+	authPasswordResets.Inc()
+}
+
+// RecordLockout 记录账户锁定，供应用层的锁定策略调用
+func RecordLockout() {
+	authLockouts.Inc()
+}
+
+// resultLabel 根据错误生成结果标签
+func resultLabel(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// observeTokenValidation 记录令牌验证耗时
+func observeTokenValidation(start time.Time, err error) {
+	result := "valid"
+	if err != nil {
+		result = "invalid"
+	}
+	authTokenValidationDuration.ObserveSince(start, result)
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hwh/hwhkit-go/pkg/config"
+)
+
+func TestAuthMetrics(t *testing.T) {
+	cfg := &config.JWTConfig{
+		Secret:       "test-secret-key",
+		ExpireHours:  1,
+		RefreshHours: 24,
+		Issuer:       "test-issuer",
+	}
+	service := NewAuthService(cfg)
+
+	hash, err := service.GetPasswordManager().HashPassword("Password123!")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	user := &User{ID: "1", Username: "alice", Password: hash, IsActive: true}
+	provider := func(username string) (*User, error) {
+		if username != user.Username {
+			return nil, errors.New("not found")
+		}
+		return user, nil
+	}
+
+	successBefore := authLogins.Value(LoginResultSuccess)
+	invalidBefore := authLogins.Value(LoginResultInvalidPassword)
+	notFoundBefore := authLogins.Value(LoginResultUserNotFound)
+	validationsBefore := authTokenValidationDuration.Snapshot("valid").Count
+
+	pair, err := service.Login("alice", "Password123!", provider)
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	_, _ = service.Login("alice", "wrong", provider)
+	_, _ = service.Login("bob", "Password123!", provider)
+
+	if got := authLogins.Value(LoginResultSuccess) - successBefore; got != 1 {
+		t.Errorf("Expected 1 successful login, got %v", got)
+	}
+	if got := authLogins.Value(LoginResultInvalidPassword) - invalidBefore; got != 1 {
+		t.Errorf("Expected 1 invalid password login, got %v", got)
+	}
+	if got := authLogins.Value(LoginResultUserNotFound) - notFoundBefore; got != 1 {
+		t.Errorf("Expected 1 user not found login, got %v", got)
+	}
+
+	refreshBefore := authTokenRefreshes.Value("success")
+	if _, err := service.GetJWTManager().RefreshToken(pair.RefreshToken); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if got := authTokenRefreshes.Value("success") - refreshBefore; got != 1 {
+		t.Errorf("Expected 1 successful refresh, got %v", got)
+	}
+	if got := authTokenValidationDuration.Snapshot("valid").Count - validationsBefore; got != 1 {
+		t.Errorf("Expected 1 token validation, got %v", got)
+	}
+	if authPasswordHashDuration.Snapshot("verify").Count == 0 {
+		t.Error("Expected password verification latency to be recorded")
+	}
+}
+
+func TestRBACMetrics(t *testing.T) {
+	rbac := NewRBAC()
+	if err := CreateDefaultRolesAndPermissions(rbac); err != nil {
+		t.Fatalf("Failed to create defaults: %v", err)
+	}
+	rbac.AssignRoleToUser("u1", "user")
+
+	allowedBefore := rbacChecks.Value(RBACCheckPermission, "allowed")
+	deniedBefore := rbacChecks.Value(RBACCheckPermission, "denied")
+	roleDeniedBefore := rbacChecks.Value(RBACCheckRole, "denied")
+	policyBefore := rbacChecks.Value(RBACCheckPolicy, "allowed")
+
+	rbac.HasPermission("u1", "user.read")
+	rbac.HasPermission("u1", "user.delete")
+	// 多角色检查只记录一次
+	rbac.HasAnyRole("u1", []string{"admin", "editor"})
+	NewPolicyEvaluator(rbac).EvaluatePolicy("u1", &Policy{Resource: "user", Actions: []string{"read"}, Roles: []string{"user"}})
+
+	if got := rbacChecks.Value(RBACCheckPermission, "allowed") - allowedBefore; got != 1 {
+		t.Errorf("Expected 1 allowed permission check, got %v", got)
+	}
+	if got := rbacChecks.Value(RBACCheckPermission, "denied") - deniedBefore; got != 1 {
+		t.Errorf("Expected 1 denied permission check, got %v", got)
+	}
+	if got := rbacChecks.Value(RBACCheckRole, "denied") - roleDeniedBefore; got != 1 {
+		t.Errorf("Expected 1 denied role check, got %v", got)
+	}
+	if got := rbacChecks.Value(RBACCheckPolicy, "allowed") - policyBefore; got != 1 {
+		t.Errorf("Expected 1 allowed policy check, got %v", got)
+	}
+
+	SetRevokedTokenCount(42)
+	if got := authRevokedTokens.Value(); got != 42 {
+		t.Errorf("Expected revoked token gauge 42, got %v", got)
+	}
+}
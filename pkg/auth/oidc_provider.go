@@ -0,0 +1,707 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OAuth2/OIDC 授权类型
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// OIDC 标准 scope
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// 令牌头部的 typ，访问令牌按RFC 9068 使用 at+jwt，避免ID令牌被当作访问令牌使用
+const (
+	oidcAccessTokenType = "at+jwt"
+	oidcIDTokenType     = "JWT"
+)
+
+// OAuth2 错误，错误信息即规范中的 error 代码
+var (
+	ErrOIDCInvalidRequest       = errors.New("invalid_request")
+	ErrOIDCInvalidClient        = errors.New("invalid_client")
+	ErrOIDCInvalidGrant         = errors.New("invalid_grant")
+	ErrOIDCUnauthorizedClient   = errors.New("unauthorized_client")
+	ErrOIDCUnsupportedGrantType = errors.New("unsupported_grant_type")
+	ErrOIDCInvalidScope         = errors.New("invalid_scope")
+	ErrOIDCInvalidToken         = errors.New("invalid_token")
+)
+
+// oidcErrors 用于将错误转换为OAuth2错误代码
+var oidcErrors = []error{
+	ErrOIDCInvalidRequest,
+	ErrOIDCInvalidClient,
+	ErrOIDCInvalidGrant,
+	ErrOIDCUnauthorizedClient,
+	ErrOIDCUnsupportedGrantType,
+	ErrOIDCInvalidScope,
+	ErrOIDCInvalidToken,
+}
+
+// OIDCErrorCode 获取错误对应的OAuth2错误代码，未知错误返回 server_error
+func OIDCErrorCode(err error) string {
+	for _, target := range oidcErrors {
+		if errors.Is(err, target) {
+			return target.Error()
+		}
+	}
+	return "server_error"
+}
+
+// OIDCClient 注册的第三方客户端
+type OIDCClient struct {
+	ID           string    `json:"client_id"`
+	SecretHash   string    `json:"-"`
+	Name         string    `json:"client_name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	GrantTypes   []string  `json:"grant_types"`
+	Scopes       []string  `json:"scopes"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// AllowsGrant 检查客户端是否允许指定授权类型
+func (c *OIDCClient) AllowsGrant(grantType string) bool {
+	return containsString(c.GrantTypes, grantType)
+}
+
+// AllowsRedirect 检查回调地址是否已注册（精确匹配）
+func (c *OIDCClient) AllowsRedirect(redirectURI string) bool {
+	return containsString(c.RedirectURIs, redirectURI)
+}
+
+// OIDCClientStore 客户端存储接口
+type OIDCClientStore interface {
+	GetClient(clientID string) (*OIDCClient, error)
+	SaveClient(client *OIDCClient) error
+	DeleteClient(clientID string) error
+	ListClients() ([]*OIDCClient, error)
+}
+
+// MemoryOIDCClientStore 内存客户端存储
+type MemoryOIDCClientStore struct {
+	mu      sync.RWMutex
+	clients map[string]*OIDCClient
+}
+
+// NewMemoryOIDCClientStore 创建内存客户端存储
+func NewMemoryOIDCClientStore() *MemoryOIDCClientStore {
+	return &MemoryOIDCClientStore{clients: make(map[string]*OIDCClient)}
+}
+
+// GetClient 获取客户端
+func (s *MemoryOIDCClientStore) GetClient(clientID string) (*OIDCClient, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	client, exists := s.clients[clientID]
+	if !exists {
+		return nil, fmt.Errorf("client %s not found", clientID)
+	}
+	return client, nil
+}
+
+// SaveClient 保存客户端
+func (s *MemoryOIDCClientStore) SaveClient(client *OIDCClient) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[client.ID] = client
+	return nil
+}
+
+// DeleteClient 删除客户端
+func (s *MemoryOIDCClientStore) DeleteClient(clientID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, clientID)
+	return nil
+}
+
+// ListClients 列出所有客户端
+func (s *MemoryOIDCClientStore) ListClients() ([]*OIDCClient, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	clients := make([]*OIDCClient, 0, len(s.clients))
+	for _, client := range s.clients {
+		clients = append(clients, client)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].CreatedAt.Before(clients[j].CreatedAt) })
+	return clients, nil
+}
+
+// OIDCProviderConfig OIDC提供方配置
+type OIDCProviderConfig struct {
+	Issuer         string                             // 签发者，如 https://id.example.com
+	SigningKey     *rsa.PrivateKey                    // RS256签名私钥，为nil时自动生成（重启后失效，仅用于开发）
+	KeyID          string                             // JWKS中的 kid，为空时根据公钥生成
+	AccessTokenTTL time.Duration                      // 访问令牌有效期，默认1小时
+	IDTokenTTL     time.Duration                      // ID令牌有效期，默认1小时
+	CodeTTL        time.Duration                      // 授权码有效期，默认5分钟
+	ClientStore    OIDCClientStore                    // 客户端存储，默认内存存储
+	UserProvider   func(userID string) (*User, error) // 根据用户ID获取用户，用于ID令牌和userinfo
+	Leeway         time.Duration                      // 校验访问令牌时允许的时钟偏差
+}
+
+// OIDCProvider 最小化的OIDC提供方，支持授权码（含PKCE）和客户端凭证模式
+type OIDCProvider struct {
+	config      OIDCProviderConfig
+	revocations RevocationStore
+
+	mu    sync.Mutex
+	codes map[string]*authorizationCode
+}
+
+// authorizationCode 已签发的授权码
+type authorizationCode struct {
+	clientID            string
+	redirectURI         string
+	userID              string
+	scopes              []string
+	nonce               string
+	codeChallenge       string
+	codeChallengeMethod string
+	authTime            time.Time
+	expiresAt           time.Time
+}
+
+// AuthorizeRequest 授权请求参数
+type AuthorizeRequest struct {
+	ClientID            string
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// TokenRequest 令牌请求参数
+type TokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	Scope        string
+}
+
+// OIDCTokenResponse 令牌响应
+type OIDCTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	IDToken     string `json:"id_token,omitempty"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// OIDCAccessClaims 访问令牌声明
+type OIDCAccessClaims struct {
+	Scope    string `json:"scope"`
+	ClientID string `json:"client_id"`
+	jwt.RegisteredClaims
+}
+
+// Scopes 获取访问令牌的scope列表
+func (c *OIDCAccessClaims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// NewOIDCProvider 创建OIDC提供方
+func NewOIDCProvider(cfg OIDCProviderConfig) (*OIDCProvider, error) {
+	if cfg.Issuer == "" {
+		return nil, errors.New("oidc issuer is required")
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+
+	if cfg.SigningKey == nil {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate signing key: %w", err)
+		}
+		cfg.SigningKey = key
+	}
+	if cfg.KeyID == "" {
+		sum := sha256.Sum256(cfg.SigningKey.PublicKey.N.Bytes())
+		cfg.KeyID = hex.EncodeToString(sum[:8])
+	}
+	if cfg.AccessTokenTTL <= 0 {
+		cfg.AccessTokenTTL = time.Hour
+	}
+	if cfg.IDTokenTTL <= 0 {
+		cfg.IDTokenTTL = time.Hour
+	}
+	if cfg.CodeTTL <= 0 {
+		cfg.CodeTTL = 5 * time.Minute
+	}
+	if cfg.ClientStore == nil {
+		cfg.ClientStore = NewMemoryOIDCClientStore()
+	}
+
+	return &OIDCProvider{
+		config: cfg,
+		codes:  make(map[string]*authorizationCode),
+	}, nil
+}
+
+// Issuer 获取签发者
+func (p *OIDCProvider) Issuer() string {
+	return p.config.Issuer
+}
+
+// Clients 获取客户端存储
+func (p *OIDCProvider) Clients() OIDCClientStore {
+	return p.config.ClientStore
+}
+
+// RegisterClient 注册客户端，返回客户端信息和明文密钥（密钥仅返回这一次）
+func (p *OIDCProvider) RegisterClient(name string, redirectURIs, grantTypes, scopes []string) (*OIDCClient, string, error) {
+	if len(grantTypes) == 0 {
+		grantTypes = []string{GrantTypeAuthorizationCode}
+	}
+	for _, grantType := range grantTypes {
+		if grantType != GrantTypeAuthorizationCode && grantType != GrantTypeClientCredentials {
+			return nil, "", fmt.Errorf("%w: %s", ErrOIDCUnsupportedGrantType, grantType)
+		}
+	}
+	if containsString(grantTypes, GrantTypeAuthorizationCode) && len(redirectURIs) == 0 {
+		return nil, "", fmt.Errorf("%w: redirect_uris required for authorization_code", ErrOIDCInvalidRequest)
+	}
+	if len(scopes) == 0 {
+		scopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+	}
+
+	clientID, err := randomOIDCToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomOIDCToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client := &OIDCClient{
+		ID:           clientID,
+		SecretHash:   hashClientSecret(secret),
+		Name:         name,
+		RedirectURIs: redirectURIs,
+		GrantTypes:   grantTypes,
+		Scopes:       scopes,
+		CreatedAt:    time.Now(),
+	}
+	if err := p.config.ClientStore.SaveClient(client); err != nil {
+		return nil, "", fmt.Errorf("failed to save client: %w", err)
+	}
+	return client, secret, nil
+}
+
+// AuthenticateClient 校验客户端凭证
+func (p *OIDCProvider) AuthenticateClient(clientID, clientSecret string) (*OIDCClient, error) {
+	client, err := p.config.ClientStore.GetClient(clientID)
+	if err != nil {
+		// 仍然计算一次哈希，避免通过耗时判断客户端是否存在
+		SecureCompare(hashClientSecret(clientSecret), "")
+		return nil, ErrOIDCInvalidClient
+	}
+	if !SecureCompare(hashClientSecret(clientSecret), client.SecretHash) {
+		return nil, ErrOIDCInvalidClient
+	}
+	return client, nil
+}
+
+// ValidateAuthorizeRequest 校验授权请求，返回客户端和最终授予的scope
+// 回调地址校验失败时不应重定向回客户端，调用方应直接展示错误
+func (p *OIDCProvider) ValidateAuthorizeRequest(req AuthorizeRequest) (*OIDCClient, []string, error) {
+	client, err := p.config.ClientStore.GetClient(req.ClientID)
+	if err != nil {
+		return nil, nil, ErrOIDCInvalidClient
+	}
+	if !client.AllowsRedirect(req.RedirectURI) {
+		return nil, nil, fmt.Errorf("%w: redirect_uri is not registered", ErrOIDCInvalidRequest)
+	}
+	if req.ResponseType != "code" {
+		return client, nil, fmt.Errorf("%w: response_type must be code", ErrOIDCInvalidRequest)
+	}
+	if !client.AllowsGrant(GrantTypeAuthorizationCode) {
+		return client, nil, ErrOIDCUnauthorizedClient
+	}
+	if req.CodeChallenge != "" && req.CodeChallengeMethod != "" &&
+		req.CodeChallengeMethod != "S256" && req.CodeChallengeMethod != "plain" {
+		return client, nil, fmt.Errorf("%w: unsupported code_challenge_method", ErrOIDCInvalidRequest)
+	}
+
+	scopes, err := p.grantScopes(client, req.Scope)
+	if err != nil {
+		return client, nil, err
+	}
+	if !containsString(scopes, ScopeOpenID) {
+		return client, nil, fmt.Errorf("%w: openid scope is required", ErrOIDCInvalidScope)
+	}
+	return client, scopes, nil
+}
+
+// IssueCode 用户同意授权后签发授权码
+func (p *OIDCProvider) IssueCode(req AuthorizeRequest, userID string) (string, error) {
+	_, scopes, err := p.ValidateAuthorizeRequest(req)
+	if err != nil {
+		return "", err
+	}
+
+	code, err := randomOIDCToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	method := req.CodeChallengeMethod
+	if req.CodeChallenge != "" && method == "" {
+		method = "plain"
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// 顺便清理过期授权码
+	for key, issued := range p.codes {
+		if now.After(issued.expiresAt) {
+			delete(p.codes, key)
+		}
+	}
+
+	p.codes[code] = &authorizationCode{
+		clientID:            req.ClientID,
+		redirectURI:         req.RedirectURI,
+		userID:              userID,
+		scopes:              scopes,
+		nonce:               req.Nonce,
+		codeChallenge:       req.CodeChallenge,
+		codeChallengeMethod: method,
+		authTime:            now,
+		expiresAt:           now.Add(p.config.CodeTTL),
+	}
+	return code, nil
+}
+
+// Exchange 处理令牌请求
+func (p *OIDCProvider) Exchange(req TokenRequest) (*OIDCTokenResponse, error) {
+	client, err := p.AuthenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsGrant(req.GrantType) {
+		if req.GrantType != GrantTypeAuthorizationCode && req.GrantType != GrantTypeClientCredentials {
+			return nil, ErrOIDCUnsupportedGrantType
+		}
+		return nil, ErrOIDCUnauthorizedClient
+	}
+
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode:
+		return p.exchangeCode(client, req)
+	case GrantTypeClientCredentials:
+		return p.exchangeClientCredentials(client, req)
+	default:
+		return nil, ErrOIDCUnsupportedGrantType
+	}
+}
+
+// exchangeCode 授权码换取令牌
+func (p *OIDCProvider) exchangeCode(client *OIDCClient, req TokenRequest) (*OIDCTokenResponse, error) {
+	// 授权码只能使用一次，取出即删除
+	p.mu.Lock()
+	issued, exists := p.codes[req.Code]
+	delete(p.codes, req.Code)
+	p.mu.Unlock()
+
+	if !exists || time.Now().After(issued.expiresAt) {
+		return nil, fmt.Errorf("%w: code is invalid or expired", ErrOIDCInvalidGrant)
+	}
+	if issued.clientID != client.ID || issued.redirectURI != req.RedirectURI {
+		return nil, fmt.Errorf("%w: code was issued to another client or redirect_uri", ErrOIDCInvalidGrant)
+	}
+	if err := verifyPKCE(issued, req.CodeVerifier); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := p.signAccessToken(issued.userID, client.ID, issued.scopes)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := p.signIDToken(issued, client.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(p.config.AccessTokenTTL.Seconds()),
+		IDToken:     idToken,
+		Scope:       strings.Join(issued.scopes, " "),
+	}, nil
+}
+
+// exchangeClientCredentials 客户端凭证模式，令牌主体为客户端本身，不签发ID令牌
+func (p *OIDCProvider) exchangeClientCredentials(client *OIDCClient, req TokenRequest) (*OIDCTokenResponse, error) {
+	scopes, err := p.grantScopes(client, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+	// openid 相关scope仅适用于用户授权
+	filtered := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if scope != ScopeOpenID && scope != ScopeProfile && scope != ScopeEmail {
+			filtered = append(filtered, scope)
+		}
+	}
+
+	accessToken, err := p.signAccessToken(client.ID, client.ID, filtered)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(p.config.AccessTokenTTL.Seconds()),
+		Scope:       strings.Join(filtered, " "),
+	}, nil
+}
+
+// ValidateAccessToken 校验本提供方签发的访问令牌
+// 要求头部 typ 为 at+jwt 且包含 client_id 和 jti，同一密钥签发的ID令牌不会被当作访问令牌接受
+func (p *OIDCProvider) ValidateAccessToken(tokenString string) (*OIDCAccessClaims, error) {
+	claims := &OIDCAccessClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		typ, _ := token.Header["typ"].(string)
+		if !strings.EqualFold(typ, oidcAccessTokenType) && !strings.EqualFold(typ, "application/"+oidcAccessTokenType) {
+			return nil, fmt.Errorf("unexpected token type %q", typ)
+		}
+		return &p.config.SigningKey.PublicKey, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithLeeway(p.config.Leeway),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCInvalidToken, err)
+	}
+	if claims.ClientID == "" || claims.ID == "" {
+		return nil, fmt.Errorf("%w: token is missing client_id or jti", ErrOIDCInvalidToken)
+	}
+	if p.revocations != nil {
+		revoked, err := p.revocations.IsRevoked(claims.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check token revocation: %w", err)
+		}
+		if revoked {
+			return nil, fmt.Errorf("%w: %v", ErrOIDCInvalidToken, ErrTokenRevoked)
+		}
+	}
+	return claims, nil
+}
+
+// UserInfo 根据访问令牌返回用户信息，按scope返回 profile、email 声明
+func (p *OIDCProvider) UserInfo(accessToken string) (map[string]interface{}, error) {
+	claims, err := p.ValidateAccessToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	scopes := claims.Scopes()
+	if !containsString(scopes, ScopeOpenID) {
+		return nil, fmt.Errorf("%w: token was not issued for openid", ErrOIDCInvalidToken)
+	}
+
+	info := map[string]interface{}{"sub": claims.Subject}
+	if p.config.UserProvider == nil {
+		return info, nil
+	}
+	user, err := p.config.UserProvider(claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: user not found", ErrOIDCInvalidToken)
+	}
+	addUserClaims(info, user, scopes)
+	return info, nil
+}
+
+// Discovery 返回 /.well-known/openid-configuration 元数据
+func (p *OIDCProvider) Discovery() map[string]interface{} {
+	issuer := p.config.Issuer
+	return map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oauth/authorize",
+		"token_endpoint":                        issuer + "/oauth/token",
+		"userinfo_endpoint":                     issuer + "/oauth/userinfo",
+		"jwks_uri":                              issuer + "/oauth/jwks",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{GrantTypeAuthorizationCode, GrantTypeClientCredentials},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{jwt.SigningMethodRS256.Alg()},
+		"scopes_supported":                      []string{ScopeOpenID, ScopeProfile, ScopeEmail},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"code_challenge_methods_supported":      []string{"S256", "plain"},
+		"claims_supported":                      []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "name", "preferred_username", "email"},
+	}
+}
+
+// JWKS 返回公钥集合
+func (p *OIDCProvider) JWKS() map[string]interface{} {
+	pub := p.config.SigningKey.PublicKey
+	return map[string]interface{}{
+		"keys": []map[string]interface{}{
+			{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": jwt.SigningMethodRS256.Alg(),
+				"kid": p.config.KeyID,
+				"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			},
+		},
+	}
+}
+
+// grantScopes 计算授予的scope，请求为空时使用客户端允许的全部scope
+func (p *OIDCProvider) grantScopes(client *OIDCClient, requested string) ([]string, error) {
+	scopes := strings.Fields(requested)
+	if len(scopes) == 0 {
+		return client.Scopes, nil
+	}
+	for _, scope := range scopes {
+		if !containsString(client.Scopes, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrOIDCInvalidScope, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// signAccessToken 签发访问令牌
+func (p *OIDCProvider) signAccessToken(subject, clientID string, scopes []string) (string, error) {
+	jti, err := randomOIDCToken(16)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims := &OIDCAccessClaims{
+		Scope:    strings.Join(scopes, " "),
+		ClientID: clientID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.config.Issuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{clientID},
+			ExpiresAt: jwt.NewNumericDate(now.Add(p.config.AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        jti,
+		},
+	}
+	return p.sign(claims, oidcAccessTokenType)
+}
+
+// signIDToken 签发ID令牌
+func (p *OIDCProvider) signIDToken(issued *authorizationCode, clientID string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":       p.config.Issuer,
+		"sub":       issued.userID,
+		"aud":       clientID,
+		"exp":       now.Add(p.config.IDTokenTTL).Unix(),
+		"iat":       now.Unix(),
+		"auth_time": issued.authTime.Unix(),
+	}
+	if issued.nonce != "" {
+		claims["nonce"] = issued.nonce
+	}
+	if p.config.UserProvider != nil {
+		if user, err := p.config.UserProvider(issued.userID); err == nil {
+			addUserClaims(claims, user, issued.scopes)
+		}
+	}
+	return p.sign(claims, oidcIDTokenType)
+}
+
+// sign 使用RS256签名，typ 写入令牌头部用于区分访问令牌和ID令牌
+func (p *OIDCProvider) sign(claims jwt.Claims, typ string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.config.KeyID
+	token.Header["typ"] = typ
+	signed, err := token.SignedString(p.config.SigningKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signed, nil
+}
+
+// addUserClaims 按scope添加用户声明
+func addUserClaims(claims map[string]interface{}, user *User, scopes []string) {
+	if containsString(scopes, ScopeProfile) {
+		claims["name"] = user.Username
+		claims["preferred_username"] = user.Username
+		claims["roles"] = user.Roles
+	}
+	if containsString(scopes, ScopeEmail) && user.Email != "" {
+		claims["email"] = user.Email
+	}
+}
+
+// verifyPKCE 校验PKCE code_verifier
+func verifyPKCE(issued *authorizationCode, verifier string) error {
+	if issued.codeChallenge == "" {
+		return nil
+	}
+	if verifier == "" {
+		return fmt.Errorf("%w: code_verifier is required", ErrOIDCInvalidGrant)
+	}
+
+	expected := verifier
+	if issued.codeChallengeMethod == "S256" {
+		sum := sha256.Sum256([]byte(verifier))
+		expected = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	if !SecureCompare(expected, issued.codeChallenge) {
+		return fmt.Errorf("%w: code_verifier does not match", ErrOIDCInvalidGrant)
+	}
+	return nil
+}
+
+// hashClientSecret 计算客户端密钥摘要（密钥为高熵随机值，无需慢哈希）
+func hashClientSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomOIDCToken 生成URL安全的随机字符串
+func randomOIDCToken(byteLength int) (string, error) {
+	buf := make([]byte, byteLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// containsString 检查切片是否包含指定字符串
+func containsString(items []string, target string) bool {
+	for _, item := range items {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func newTestOIDCProvider(t *testing.T) *OIDCProvider {
+	t.Helper()
+	provider, err := NewOIDCProvider(OIDCProviderConfig{
+		Issuer: "https://id.example.com/",
+		UserProvider: func(userID string) (*User, error) {
+			if userID != "42" {
+				return nil, errors.New("not found")
+			}
+			return &User{ID: "42", Username: "alice", Email: "alice@example.com", Roles: []string{"user"}}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	return provider
+}
+
+func TestOIDCProviderAuthorizationCode(t *testing.T) {
+	provider := newTestOIDCProvider(t)
+
+	client, secret, err := provider.RegisterClient("wiki", []string{"https://wiki.example.com/callback"}, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to register client: %v", err)
+	}
+
+	verifier := "a-long-random-code-verifier-for-pkce-tests"
+	sum := sha256.Sum256([]byte(verifier))
+	req := AuthorizeRequest{
+		ClientID:            client.ID,
+		RedirectURI:         "https://wiki.example.com/callback",
+		ResponseType:        "code",
+		Scope:               "openid profile email",
+		Nonce:               "n-123",
+		CodeChallenge:       base64.RawURLEncoding.EncodeToString(sum[:]),
+		CodeChallengeMethod: "S256",
+	}
+
+	if _, _, err := provider.ValidateAuthorizeRequest(req); err != nil {
+		t.Fatalf("Authorize request should be valid: %v", err)
+	}
+
+	code, err := provider.IssueCode(req, "42")
+	if err != nil {
+		t.Fatalf("Failed to issue code: %v", err)
+	}
+
+	tokenReq := TokenRequest{
+		GrantType:    GrantTypeAuthorizationCode,
+		ClientID:     client.ID,
+		ClientSecret: secret,
+		Code:         code,
+		RedirectURI:  req.RedirectURI,
+		CodeVerifier: verifier,
+	}
+	resp, err := provider.Exchange(tokenReq)
+	if err != nil {
+		t.Fatalf("Failed to exchange code: %v", err)
+	}
+	if resp.IDToken == "" || resp.AccessToken == "" {
+		t.Fatal("Expected access token and ID token")
+	}
+
+	info, err := provider.UserInfo(resp.AccessToken)
+	if err != nil {
+		t.Fatalf("Failed to get userinfo: %v", err)
+	}
+	if info["sub"] != "42" || info["email"] != "alice@example.com" || info["preferred_username"] != "alice" {
+		t.Errorf("Unexpected userinfo: %v", info)
+	}
+
+	// ID令牌使用同一密钥签名，但不能当作访问令牌使用
+	if _, err := provider.ValidateAccessToken(resp.IDToken); !errors.Is(err, ErrOIDCInvalidToken) {
+		t.Errorf("Expected ID token to be rejected as access token, got %v", err)
+	}
+	if _, err := provider.UserInfo(resp.IDToken); !errors.Is(err, ErrOIDCInvalidToken) {
+		t.Errorf("Expected userinfo to reject ID token, got %v", err)
+	}
+
+	// 授权码只能使用一次
+	if _, err := provider.Exchange(tokenReq); OIDCErrorCode(err) != "invalid_grant" {
+		t.Errorf("Expected invalid_grant for reused code, got %v", err)
+	}
+}
+
+func TestOIDCProviderRejectsInvalidRequests(t *testing.T) {
+	provider := newTestOIDCProvider(t)
+	client, secret, _ := provider.RegisterClient("wiki", []string{"https://wiki.example.com/callback"}, nil, nil)
+
+	req := AuthorizeRequest{
+		ClientID:     client.ID,
+		RedirectURI:  "https://evil.example.com/callback",
+		ResponseType: "code",
+		Scope:        "openid",
+	}
+	if _, _, err := provider.ValidateAuthorizeRequest(req); OIDCErrorCode(err) != "invalid_request" {
+		t.Errorf("Expected invalid_request for unregistered redirect, got %v", err)
+	}
+
+	req.RedirectURI = "https://wiki.example.com/callback"
+	req.Scope = "openid admin"
+	if _, _, err := provider.ValidateAuthorizeRequest(req); OIDCErrorCode(err) != "invalid_scope" {
+		t.Errorf("Expected invalid_scope, got %v", err)
+	}
+
+	// PKCE校验失败
+	req.Scope = "openid"
+	req.CodeChallenge = "expected-verifier"
+	req.CodeChallengeMethod = "plain"
+	code, _ := provider.IssueCode(req, "42")
+	_, err := provider.Exchange(TokenRequest{
+		GrantType:    GrantTypeAuthorizationCode,
+		ClientID:     client.ID,
+		ClientSecret: secret,
+		Code:         code,
+		RedirectURI:  req.RedirectURI,
+		CodeVerifier: "wrong-verifier",
+	})
+	if OIDCErrorCode(err) != "invalid_grant" {
+		t.Errorf("Expected invalid_grant for wrong verifier, got %v", err)
+	}
+
+	// 错误的客户端密钥
+	_, err = provider.Exchange(TokenRequest{GrantType: GrantTypeAuthorizationCode, ClientID: client.ID, ClientSecret: "wrong"})
+	if OIDCErrorCode(err) != "invalid_client" {
+		t.Errorf("Expected invalid_client, got %v", err)
+	}
+
+	// 未授权的授权类型
+	_, err = provider.Exchange(TokenRequest{GrantType: GrantTypeClientCredentials, ClientID: client.ID, ClientSecret: secret})
+	if OIDCErrorCode(err) != "unauthorized_client" {
+		t.Errorf("Expected unauthorized_client, got %v", err)
+	}
+}
+
+func TestOIDCProviderClientCredentials(t *testing.T) {
+	provider := newTestOIDCProvider(t)
+	client, secret, err := provider.RegisterClient("ci", nil, []string{GrantTypeClientCredentials}, []string{"deploy"})
+	if err != nil {
+		t.Fatalf("Failed to register client: %v", err)
+	}
+
+	resp, err := provider.Exchange(TokenRequest{
+		GrantType:    GrantTypeClientCredentials,
+		ClientID:     client.ID,
+		ClientSecret: secret,
+	})
+	if err != nil {
+		t.Fatalf("Failed to exchange client credentials: %v", err)
+	}
+	if resp.IDToken != "" {
+		t.Error("Client credentials grant should not return an ID token")
+	}
+
+	claims, err := provider.ValidateAccessToken(resp.AccessToken)
+	if err != nil {
+		t.Fatalf("Failed to validate access token: %v", err)
+	}
+	if claims.Subject != client.ID || claims.Scope != "deploy" {
+		t.Errorf("Unexpected claims: sub=%s scope=%s", claims.Subject, claims.Scope)
+	}
+
+	if _, err := provider.UserInfo(resp.AccessToken); OIDCErrorCode(err) != "invalid_token" {
+		t.Errorf("Expected invalid_token for userinfo without openid scope, got %v", err)
+	}
+
+	keys := provider.JWKS()["keys"].([]map[string]interface{})
+	if len(keys) != 1 || keys[0]["kty"] != "RSA" {
+		t.Errorf("Unexpected JWKS: %v", keys)
+	}
+	if provider.Discovery()["issuer"] != "https://id.example.com" {
+		t.Errorf("Unexpected issuer in discovery: %v", provider.Discovery()["issuer"])
+	}
+}
@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 密码哈希算法
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// argon2idPrefix Argon2id哈希的PHC格式前缀
+const argon2idPrefix = "$argon2id$"
+
+// dummyPassword 生成占位哈希使用的密码
+const dummyPassword = "hwhkit-dummy-password"
+
+// ErrInvalidHash 无法识别的密码哈希格式
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// ErrUnknownAlgorithm 配置了不支持的密码哈希算法
+var ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+
+// Argon2id参数范围，哈希中的参数超出范围时视为无效，避免构造的哈希导致panic或耗尽内存
+const (
+	maxArgon2Memory     = 1024 * 1024 // KB，即1GB
+	maxArgon2Iterations = 64
+	minArgon2SaltLength = 8
+	minArgon2KeyLength  = 16
+	maxArgon2KeyLength  = 1024
+)
+
+// SecureCompare 常量时间比较两个字符串，用于API密钥、会话令牌等凭证
+// 先计算SHA-256摘要再比较，避免通过耗时泄露长度信息
+func SecureCompare(a, b string) bool {
+	hashA := sha256.Sum256([]byte(a))
+	hashB := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(hashA[:], hashB[:]) == 1
+}
+
+// argon2Params Argon2id参数
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	saltLength  uint32
+	keyLength   uint32
+}
+
+// NewPasswordManagerWithConfig 根据配置创建密码管理器，未设置的参数使用默认值
+func NewPasswordManagerWithConfig(cfg *config.PasswordConfig) *PasswordManager {
+	c := *cfg
+	if c.Algorithm == "" {
+		c.Algorithm = AlgorithmBcrypt
+	}
+	if c.BcryptCost == 0 {
+		c.BcryptCost = bcrypt.DefaultCost
+	}
+	if c.Argon2Memory == 0 {
+		c.Argon2Memory = 64 * 1024
+	}
+	if c.Argon2Iterations == 0 {
+		c.Argon2Iterations = 3
+	}
+	if c.Argon2Parallelism == 0 {
+		c.Argon2Parallelism = 2
+	}
+	if c.Argon2SaltLength == 0 {
+		c.Argon2SaltLength = 16
+	}
+	if c.Argon2KeyLength == 0 {
+		c.Argon2KeyLength = 32
+	}
+	return &PasswordManager{
+		cost:   c.BcryptCost,
+		config: c,
+	}
+}
+
+// CheckDummyPassword 使用占位哈希执行一次完整的密码校验
+// 用户不存在时调用，使响应耗时与密码错误时一致，防止通过耗时枚举用户
+func (pm *PasswordManager) CheckDummyPassword(password string) {
+	pm.dummyOnce.Do(func() {
+		pm.dummyHash, _ = pm.HashPassword(dummyPassword)
+	})
+	pm.CheckPassword(password, pm.dummyHash)
+}
+
+// Algorithm 获取当前使用的哈希算法
+func (pm *PasswordManager) Algorithm() string {
+	return pm.config.Algorithm
+}
+
+// NeedsRehash 检查哈希是否需要按当前配置重新生成（算法或参数发生变化）
+// 配置的算法不受支持时返回 ErrUnknownAlgorithm，避免每次登录都用无法生成的算法重新哈希
+func (pm *PasswordManager) NeedsRehash(hash string) (bool, error) {
+	if pm.config.Algorithm != AlgorithmBcrypt && pm.config.Algorithm != AlgorithmArgon2id {
+		return false, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, pm.config.Algorithm)
+	}
+
+	if strings.HasPrefix(hash, argon2idPrefix) {
+		if pm.config.Algorithm != AlgorithmArgon2id {
+			return true, nil
+		}
+		params, _, _, err := decodeArgon2Hash(hash)
+		if err != nil {
+			return true, nil
+		}
+		return *params != pm.argon2Params(), nil
+	}
+
+	if pm.config.Algorithm != AlgorithmBcrypt {
+		return true, nil
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != pm.cost, nil
+}
+
+// argon2Params 当前配置的Argon2id参数
+func (pm *PasswordManager) argon2Params() argon2Params {
+	return argon2Params{
+		memory:      pm.config.Argon2Memory,
+		iterations:  pm.config.Argon2Iterations,
+		parallelism: pm.config.Argon2Parallelism,
+		saltLength:  pm.config.Argon2SaltLength,
+		keyLength:   pm.config.Argon2KeyLength,
+	}
+}
+
+// validate 检查Argon2id参数，并行度为0时 argon2.IDKey 会panic
+func (p argon2Params) validate() error {
+	switch {
+	case p.parallelism < 1:
+		return errors.New("argon2 parallelism must be at least 1")
+	case p.iterations < 1 || p.iterations > maxArgon2Iterations:
+		return fmt.Errorf("argon2 iterations must be between 1 and %d", maxArgon2Iterations)
+	case p.memory < 8*uint32(p.parallelism) || p.memory > maxArgon2Memory:
+		return fmt.Errorf("argon2 memory must be between %d and %d KB", 8*uint32(p.parallelism), maxArgon2Memory)
+	case p.saltLength < minArgon2SaltLength:
+		return fmt.Errorf("argon2 salt length must be at least %d bytes", minArgon2SaltLength)
+	case p.keyLength < minArgon2KeyLength || p.keyLength > maxArgon2KeyLength:
+		return fmt.Errorf("argon2 key length must be between %d and %d bytes", minArgon2KeyLength, maxArgon2KeyLength)
+	}
+	return nil
+}
+
+// hashArgon2id 生成PHC格式的Argon2id哈希
+// 格式：$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func hashArgon2id(password string, p argon2Params) (string, error) {
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+	salt := make([]byte, p.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// verifyArgon2id 校验Argon2id哈希
+func verifyArgon2id(password, hash string) (bool, error) {
+	params, salt, key, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return false, err
+	}
+	actual := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, params.keyLength)
+	return subtle.ConstantTimeCompare(key, actual) == 1, nil
+}
+
+// decodeArgon2Hash 解析PHC格式的Argon2id哈希
+func decodeArgon2Hash(hash string) (*argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return nil, nil, nil, fmt.Errorf("unsupported argon2 version: %d", version)
+	}
+
+	p := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	p.saltLength = uint32(len(salt))
+	p.keyLength = uint32(len(key))
+	if p.validate() != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/config"
+)
+
+// testArgon2Config 测试用的低成本Argon2id配置
+func testArgon2Config() *config.PasswordConfig {
+	return &config.PasswordConfig{
+		Algorithm:         AlgorithmArgon2id,
+		Argon2Memory:      1024,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+	}
+}
+
+func TestPasswordManagerArgon2id(t *testing.T) {
+	pm := NewPasswordManagerWithConfig(testArgon2Config())
+
+	hash, err := pm.HashPassword("Password123!")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("Unexpected argon2id hash format: %s", hash)
+	}
+
+	if !pm.CheckPassword("Password123!", hash) {
+		t.Error("Password should match argon2id hash")
+	}
+	if pm.CheckPassword("wrong", hash) {
+		t.Error("Wrong password should not match argon2id hash")
+	}
+	if rehash, err := pm.NeedsRehash(hash); err != nil || rehash {
+		t.Error("Hash with current parameters should not need rehash")
+	}
+
+	// 同一个管理器可以校验 bcrypt 哈希
+	bcryptHash, _ := NewPasswordManager(4).HashPassword("Password123!")
+	if !pm.CheckPassword("Password123!", bcryptHash) {
+		t.Error("Argon2id manager should verify bcrypt hashes")
+	}
+	if rehash, _ := pm.NeedsRehash(bcryptHash); !rehash {
+		t.Error("bcrypt hash should need rehash when argon2id is configured")
+	}
+
+	if pm.CheckPassword("Password123!", "$argon2id$broken") {
+		t.Error("Malformed hash should not match")
+	}
+
+	// 哈希中的参数超出范围时视为无效，不能让 argon2 panic 或分配过多内存
+	parts := strings.Split(hash, "$")
+	for _, params := range []string{"m=1024,t=1,p=0", "m=1024,t=0,p=1", "m=4,t=1,p=1", "m=4194304,t=1,p=1"} {
+		forged := "$argon2id$v=19$" + params + "$" + parts[4] + "$" + parts[5]
+		if pm.CheckPassword("Password123!", forged) {
+			t.Errorf("Hash with %s should not match", params)
+		}
+		if _, _, _, err := decodeArgon2Hash(forged); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("Expected ErrInvalidHash for %s, got %v", params, err)
+		}
+	}
+}
+
+func TestPasswordManagerInvalidConfig(t *testing.T) {
+	cfg := testArgon2Config()
+	cfg.Argon2KeyLength = 4
+	if _, err := NewPasswordManagerWithConfig(cfg).HashPassword("Password123!"); err == nil {
+		t.Error("Expected short argon2 key length to be rejected")
+	}
+
+	pm := NewPasswordManagerWithConfig(&config.PasswordConfig{Algorithm: "scrypt"})
+	if _, err := pm.HashPassword("Password123!"); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("Expected ErrUnknownAlgorithm from HashPassword, got %v", err)
+	}
+	hash, _ := NewPasswordManager(4).HashPassword("Password123!")
+	if rehash, err := pm.NeedsRehash(hash); !errors.Is(err, ErrUnknownAlgorithm) || rehash {
+		t.Errorf("Expected ErrUnknownAlgorithm from NeedsRehash, got %v %v", rehash, err)
+	}
+}
+
+func TestPasswordManagerNeedsRehash(t *testing.T) {
+	hash, _ := NewPasswordManager(4).HashPassword("Password123!")
+
+	if rehash, _ := NewPasswordManager(4).NeedsRehash(hash); rehash {
+		t.Error("Hash with same cost should not need rehash")
+	}
+	if rehash, _ := NewPasswordManager(5).NeedsRehash(hash); !rehash {
+		t.Error("Hash with different cost should need rehash")
+	}
+
+	cfg := testArgon2Config()
+	argonHash, _ := NewPasswordManagerWithConfig(cfg).HashPassword("Password123!")
+	cfg.Argon2Iterations = 2
+	if rehash, _ := NewPasswordManagerWithConfig(cfg).NeedsRehash(argonHash); !rehash {
+		t.Error("Hash with different argon2 parameters should need rehash")
+	}
+}
+
+func TestAuthServiceRehashOnLogin(t *testing.T) {
+	cfg := &config.JWTConfig{
+		Secret:       "test-secret-key",
+		ExpireHours:  1,
+		RefreshHours: 24,
+		Issuer:       "test-issuer",
+		Password:     *testArgon2Config(),
+	}
+	service := NewAuthService(cfg)
+
+	oldHash, _ := NewPasswordManager(4).HashPassword("Password123!")
+	user := &User{ID: "1", Username: "alice", Password: oldHash, IsActive: true}
+	provider := func(username string) (*User, error) {
+		if username != user.Username {
+			return nil, errors.New("not found")
+		}
+		return user, nil
+	}
+
+	updated := 0
+	service.SetPasswordUpdater(func(u *User) error {
+		updated++
+		return nil
+	})
+
+	if _, err := service.Login("alice", "Password123!", provider); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if updated != 1 {
+		t.Fatalf("Expected password updater to be called once, got %d", updated)
+	}
+	if !strings.HasPrefix(user.Password, "$argon2id$") {
+		t.Errorf("Expected password to be upgraded to argon2id, got %s", user.Password)
+	}
+
+	// 已是最新参数时不再升级
+	if _, err := service.Login("alice", "Password123!", provider); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if updated != 1 {
+		t.Errorf("Expected no further upgrade, got %d calls", updated)
+	}
+}
+
+func TestSecureCompare(t *testing.T) {
+	if !SecureCompare("api-key-123", "api-key-123") {
+		t.Error("Equal strings should match")
+	}
+	if SecureCompare("api-key-123", "api-key-124") {
+		t.Error("Different strings should not match")
+	}
+	if SecureCompare("short", "much-longer-value") {
+		t.Error("Strings with different lengths should not match")
+	}
+}
+
+func TestLoginTimingEqualized(t *testing.T) {
+	cfg := &config.JWTConfig{
+		Secret:       "test-secret-key",
+		ExpireHours:  1,
+		RefreshHours: 24,
+		Issuer:       "test-issuer",
+		Password:     config.PasswordConfig{BcryptCost: 8},
+	}
+	service := NewAuthService(cfg)
+
+	hash, _ := service.GetPasswordManager().HashPassword("Password123!")
+	provider := func(username string) (*User, error) {
+		if username != "alice" {
+			return nil, errors.New("not found")
+		}
+		return &User{ID: "1", Username: "alice", Password: hash, IsActive: true}, nil
+	}
+
+	// 预热占位哈希
+	_, _ = service.Login("nobody", "wrong", provider)
+
+	measure := func(username string) time.Duration {
+		samples := make([]time.Duration, 7)
+		for i := range samples {
+			start := time.Now()
+			_, _ = service.Login(username, "wrong", provider)
+			samples[i] = time.Since(start)
+		}
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		return samples[len(samples)/2]
+	}
+
+	existing := measure("alice")
+	missing := measure("nobody")
+
+	ratio := float64(missing) / float64(existing)
+	if ratio < 0.5 || ratio > 2 {
+		t.Errorf("Login timing differs too much: existing=%v missing=%v", existing, missing)
+	}
+}
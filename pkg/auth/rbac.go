@@ -24,20 +24,26 @@ type Role struct {
 
 // RBAC RBAC权限管理器
 type RBAC struct {
-	roles       map[string]*Role
-	permissions map[string]*Permission
-	userRoles   map[string][]string // userID -> roleNames
+	store RBACStore
 }
 
-// NewRBAC 创建RBAC管理器
+// NewRBAC 创建基于内存存储的RBAC管理器
 func NewRBAC() *RBAC {
+	return NewRBACWithStore(NewMemoryRBACStore())
+}
+
+// NewRBACWithStore 创建使用指定存储的RBAC管理器，如 GORM、Redis 存储可在重启和多实例间共享数据
+func NewRBACWithStore(store RBACStore) *RBAC {
 	return &RBAC{
-		roles:       make(map[string]*Role),
-		permissions: make(map[string]*Permission),
-		userRoles:   make(map[string][]string),
+		store: store,
 	}
 }
 
+// Store 获取RBAC存储
+func (rbac *RBAC) Store() RBACStore {
+	return rbac.store
+}
+
 // AddPermission 添加权限
 func (rbac *RBAC) AddPermission(permission *Permission) error {
 	if permission.ID == "" {
@@ -47,32 +53,34 @@ func (rbac *RBAC) AddPermission(permission *Permission) error {
 		return errors.New("permission name cannot be empty")
 	}
 	
-	rbac.permissions[permission.ID] = permission
-	return nil
+	return rbac.store.SavePermission(permission)
 }
 
 // GetPermission 获取权限
 func (rbac *RBAC) GetPermission(permissionID string) (*Permission, error) {
-	permission, exists := rbac.permissions[permissionID]
-	if !exists {
-		return nil, errors.New("permission not found")
-	}
-	return permission, nil
+	return rbac.store.GetPermission(permissionID)
 }
 
 // RemovePermission 移除权限
 func (rbac *RBAC) RemovePermission(permissionID string) error {
-	if _, exists := rbac.permissions[permissionID]; !exists {
-		return errors.New("permission not found")
+	if _, err := rbac.store.GetPermission(permissionID); err != nil {
+		return err
 	}
 	
 	// 从所有角色中移除该权限
-	for _, role := range rbac.roles {
-		rbac.removePermissionFromRole(role, permissionID)
+	roles, err := rbac.store.ListRoles()
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if rbac.removePermissionFromRole(role, permissionID) {
+			if err := rbac.store.SaveRole(role); err != nil {
+				return err
+			}
+		}
 	}
 	
-	delete(rbac.permissions, permissionID)
-	return nil
+	return rbac.store.DeletePermission(permissionID)
 }
 
 // AddRole 添加角色
@@ -84,44 +92,38 @@ func (rbac *RBAC) AddRole(role *Role) error {
 		return errors.New("role name cannot be empty")
 	}
 	
-	rbac.roles[role.ID] = role
-	return nil
+	return rbac.store.SaveRole(role)
 }
 
 // GetRole 获取角色
 func (rbac *RBAC) GetRole(roleID string) (*Role, error) {
-	role, exists := rbac.roles[roleID]
-	if !exists {
-		return nil, errors.New("role not found")
-	}
-	return role, nil
+	return rbac.store.GetRole(roleID)
 }
 
 // RemoveRole 移除角色
 func (rbac *RBAC) RemoveRole(roleID string) error {
-	if _, exists := rbac.roles[roleID]; !exists {
-		return errors.New("role not found")
+	if _, err := rbac.store.GetRole(roleID); err != nil {
+		return err
 	}
 	
 	// 从所有用户中移除该角色
-	for userID, roles := range rbac.userRoles {
-		rbac.userRoles[userID] = rbac.removeRoleFromSlice(roles, roleID)
+	if err := rbac.store.RemoveRoleAssignments(roleID); err != nil {
+		return err
 	}
 	
-	delete(rbac.roles, roleID)
-	return nil
+	return rbac.store.DeleteRole(roleID)
 }
 
 // AddPermissionToRole 为角色添加权限
 func (rbac *RBAC) AddPermissionToRole(roleID, permissionID string) error {
-	role, exists := rbac.roles[roleID]
-	if !exists {
-		return errors.New("role not found")
+	role, err := rbac.store.GetRole(roleID)
+	if err != nil {
+		return err
 	}
 	
-	permission, exists := rbac.permissions[permissionID]
-	if !exists {
-		return errors.New("permission not found")
+	permission, err := rbac.store.GetPermission(permissionID)
+	if err != nil {
+		return err
 	}
 	
 	// 检查权限是否已存在
@@ -132,185 +134,224 @@ func (rbac *RBAC) AddPermissionToRole(roleID, permissionID string) error {
 	}
 	
 	role.Permissions = append(role.Permissions, *permission)
-	return nil
+	return rbac.store.SaveRole(role)
 }
 
 // RemovePermissionFromRole 从角色中移除权限
 func (rbac *RBAC) RemovePermissionFromRole(roleID, permissionID string) error {
-	role, exists := rbac.roles[roleID]
-	if !exists {
-		return errors.New("role not found")
+	role, err := rbac.store.GetRole(roleID)
+	if err != nil {
+		return err
 	}
 	
-	rbac.removePermissionFromRole(role, permissionID)
-	return nil
+	if !rbac.removePermissionFromRole(role, permissionID) {
+		return nil
+	}
+	return rbac.store.SaveRole(role)
 }
 
-// AssignRoleToUser 为用户分配角色
+// AssignRoleToUser 为用户分配角色，角色已分配时不重复添加
+// 由存储原子地追加，并发分配不同角色时不会互相覆盖
 func (rbac *RBAC) AssignRoleToUser(userID, roleID string) error {
-	if _, exists := rbac.roles[roleID]; !exists {
-		return errors.New("role not found")
+	if _, err := rbac.store.GetRole(roleID); err != nil {
+		return err
 	}
 	
-	userRoles := rbac.userRoles[userID]
-	
-	// 检查角色是否已分配
-	for _, role := range userRoles {
-		if role == roleID {
-			return nil // 角色已分配，不重复添加
-		}
-	}
-	
-	rbac.userRoles[userID] = append(userRoles, roleID)
-	return nil
+	return rbac.store.AddUserRole(userID, roleID)
 }
 
 // RemoveRoleFromUser 从用户中移除角色
 func (rbac *RBAC) RemoveRoleFromUser(userID, roleID string) error {
-	userRoles, exists := rbac.userRoles[userID]
-	if !exists {
+	userRoles, err := rbac.store.GetUserRoles(userID)
+	if err != nil {
+		return err
+	}
+	if len(userRoles) == 0 {
 		return errors.New("user has no roles")
 	}
 	
-	rbac.userRoles[userID] = rbac.removeRoleFromSlice(userRoles, roleID)
-	return nil
+	return rbac.store.RemoveUserRole(userID, roleID)
 }
 
 // GetUserRoles 获取用户角色
-func (rbac *RBAC) GetUserRoles(userID string) []string {
-	return rbac.userRoles[userID]
+func (rbac *RBAC) GetUserRoles(userID string) ([]string, error) {
+	return rbac.store.GetUserRoles(userID)
 }
 
 // GetUserPermissions 获取用户所有权限
-func (rbac *RBAC) GetUserPermissions(userID string) []Permission {
-	var permissions []Permission
-	userRoles := rbac.userRoles[userID]
+func (rbac *RBAC) GetUserPermissions(userID string) ([]Permission, error) {
+	roles, err := rbac.GetRolesByUser(userID)
+	if err != nil {
+		return nil, err
+	}
 	
-	for _, roleID := range userRoles {
-		if role, exists := rbac.roles[roleID]; exists {
-			permissions = append(permissions, role.Permissions...)
-		}
+	var permissions []Permission
+	for _, role := range roles {
+		permissions = append(permissions, role.Permissions...)
 	}
 	
 	// 去重
-	return rbac.deduplicatePermissions(permissions)
+	return rbac.deduplicatePermissions(permissions), nil
 }
 
-// HasPermission 检查用户是否拥有指定权限
+// HasPermission 检查用户是否拥有指定权限，存储读取失败时返回 false，需要区分失败原因时使用 CheckPermission
 func (rbac *RBAC) HasPermission(userID, permissionID string) bool {
-	userPermissions := rbac.GetUserPermissions(userID)
+	allowed, _ := rbac.CheckPermission(userID, permissionID)
+	return allowed
+}
+
+// CheckPermission 检查用户是否拥有指定权限，存储读取失败时返回错误
+func (rbac *RBAC) CheckPermission(userID, permissionID string) (bool, error) {
+	return recordRBACCheck(RBACCheckPermission)(rbac.hasPermission(userID, permissionID))
+}
+
+// hasPermission 检查用户是否拥有指定权限，不记录指标
+func (rbac *RBAC) hasPermission(userID, permissionID string) (bool, error) {
+	userPermissions, err := rbac.GetUserPermissions(userID)
+	if err != nil {
+		return false, err
+	}
 	
 	for _, permission := range userPermissions {
 		if permission.ID == permissionID {
-			return true
+			return true, nil
 		}
 	}
 	
-	return false
+	return false, nil
 }
 
-// HasResourcePermission 检查用户是否拥有资源权限
+// HasResourcePermission 检查用户是否拥有资源权限，存储读取失败时返回 false，需要区分失败原因时使用 CheckResourcePermission
 func (rbac *RBAC) HasResourcePermission(userID, resource, action string) bool {
-	userPermissions := rbac.GetUserPermissions(userID)
+	allowed, _ := rbac.CheckResourcePermission(userID, resource, action)
+	return allowed
+}
+
+// CheckResourcePermission 检查用户是否拥有资源权限，存储读取失败时返回错误
+func (rbac *RBAC) CheckResourcePermission(userID, resource, action string) (bool, error) {
+	return recordRBACCheck(RBACCheckResource)(rbac.hasResourcePermission(userID, resource, action))
+}
+
+// hasResourcePermission 检查用户是否拥有资源权限，不记录指标
+// 权限资源按 ResourceMatcher 匹配，动作支持通配符 *
+func (rbac *RBAC) hasResourcePermission(userID, resource, action string) (bool, error) {
+	userPermissions, err := rbac.GetUserPermissions(userID)
+	if err != nil {
+		return false, err
+	}
+	matcher := NewResourceMatcher()
 	
 	for _, permission := range userPermissions {
-		if permission.Resource == resource && permission.Action == action {
-			return true
+		if !matcher.Match(permission.Resource, resource) {
+			continue
 		}
-		// 支持通配符
-		if permission.Resource == "*" || permission.Action == "*" {
-			return true
+		if permission.Action == "*" || permission.Action == action {
+			return true, nil
 		}
 	}
 	
-	return false
+	return false, nil
 }
 
-// HasRole 检查用户是否拥有指定角色
+// HasRole 检查用户是否拥有指定角色，存储读取失败时返回 false
 func (rbac *RBAC) HasRole(userID, roleID string) bool {
-	userRoles := rbac.userRoles[userID]
-	
-	for _, role := range userRoles {
-		if role == roleID {
-			return true
-		}
-	}
-	
-	return false
+	allowed, _ := recordRBACCheck(RBACCheckRole)(rbac.hasAnyRole(userID, []string{roleID}))
+	return allowed
 }
 
-// HasAnyRole 检查用户是否拥有任意指定角色
+// HasAnyRole 检查用户是否拥有任意指定角色，存储读取失败时返回 false
 func (rbac *RBAC) HasAnyRole(userID string, roleIDs []string) bool {
+	allowed, _ := recordRBACCheck(RBACCheckRole)(rbac.hasAnyRole(userID, roleIDs))
+	return allowed
+}
+
+// hasAnyRole 检查用户是否拥有任意指定角色，不记录指标
+func (rbac *RBAC) hasAnyRole(userID string, roleIDs []string) (bool, error) {
+	userRoles, err := rbac.GetUserRoles(userID)
+	if err != nil {
+		return false, err
+	}
 	for _, roleID := range roleIDs {
-		if rbac.HasRole(userID, roleID) {
-			return true
+		if containsRole(userRoles, roleID) {
+			return true, nil
 		}
 	}
-	return false
+	return false, nil
 }
 
-// HasAllRoles 检查用户是否拥有所有指定角色
+// HasAllRoles 检查用户是否拥有所有指定角色，存储读取失败时返回 false
 func (rbac *RBAC) HasAllRoles(userID string, roleIDs []string) bool {
+	allowed, _ := recordRBACCheck(RBACCheckRole)(rbac.hasAllRoles(userID, roleIDs))
+	return allowed
+}
+
+// hasAllRoles 检查用户是否拥有所有指定角色，不记录指标
+func (rbac *RBAC) hasAllRoles(userID string, roleIDs []string) (bool, error) {
+	userRoles, err := rbac.GetUserRoles(userID)
+	if err != nil {
+		return false, err
+	}
 	for _, roleID := range roleIDs {
-		if !rbac.HasRole(userID, roleID) {
-			return false
+		if !containsRole(userRoles, roleID) {
+			return false, nil
 		}
 	}
-	return true
+	return true, nil
 }
 
 // ListRoles 列出所有角色
-func (rbac *RBAC) ListRoles() []*Role {
-	var roles []*Role
-	for _, role := range rbac.roles {
-		roles = append(roles, role)
-	}
-	return roles
+func (rbac *RBAC) ListRoles() ([]*Role, error) {
+	return rbac.store.ListRoles()
 }
 
 // ListPermissions 列出所有权限
-func (rbac *RBAC) ListPermissions() []*Permission {
-	var permissions []*Permission
-	for _, permission := range rbac.permissions {
-		permissions = append(permissions, permission)
-	}
-	return permissions
+func (rbac *RBAC) ListPermissions() ([]*Permission, error) {
+	return rbac.store.ListPermissions()
 }
 
-// GetRolesByUser 获取用户的所有角色详情
-func (rbac *RBAC) GetRolesByUser(userID string) []*Role {
-	var roles []*Role
-	userRoles := rbac.userRoles[userID]
+// GetRolesByUser 获取用户的所有角色详情，跳过已删除的角色
+func (rbac *RBAC) GetRolesByUser(userID string) ([]*Role, error) {
+	userRoles, err := rbac.GetUserRoles(userID)
+	if err != nil {
+		return nil, err
+	}
 	
+	var roles []*Role
 	for _, roleID := range userRoles {
-		if role, exists := rbac.roles[roleID]; exists {
-			roles = append(roles, role)
+		role, err := rbac.store.GetRole(roleID)
+		if errors.Is(err, ErrRoleNotFound) {
+			continue
 		}
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
 	}
 	
-	return roles
+	return roles, nil
 }
 
 // 辅助方法
 
-// removePermissionFromRole 从角色中移除权限
-func (rbac *RBAC) removePermissionFromRole(role *Role, permissionID string) {
-	for i, permission := range role.Permissions {
-		if permission.ID == permissionID {
-			role.Permissions = append(role.Permissions[:i], role.Permissions[i+1:]...)
-			break
+// containsRole 检查角色列表是否包含指定角色
+func containsRole(roles []string, roleID string) bool {
+	for _, role := range roles {
+		if role == roleID {
+			return true
 		}
 	}
+	return false
 }
 
-// removeRoleFromSlice 从角色切片中移除指定角色
-func (rbac *RBAC) removeRoleFromSlice(roles []string, roleID string) []string {
-	for i, role := range roles {
-		if role == roleID {
-			return append(roles[:i], roles[i+1:]...)
+// removePermissionFromRole 从角色中移除权限，返回是否有修改
+func (rbac *RBAC) removePermissionFromRole(role *Role, permissionID string) bool {
+	for i, permission := range role.Permissions {
+		if permission.ID == permissionID {
+			role.Permissions = append(role.Permissions[:i], role.Permissions[i+1:]...)
+			return true
 		}
 	}
-	return roles
+	return false
 }
 
 // deduplicatePermissions 去重权限
@@ -348,32 +389,43 @@ type Policy struct {
 	Permissions []string `json:"permissions,omitempty"`
 }
 
-// EvaluatePolicy 评估策略
+// EvaluatePolicy 评估策略，整体记录为一次 policy 检查，存储读取失败时返回 false
 func (pe *PolicyEvaluator) EvaluatePolicy(userID string, policy *Policy) bool {
+	allowed, _ := pe.CheckPolicy(userID, policy)
+	return allowed
+}
+
+// CheckPolicy 评估策略，存储读取失败时返回错误
+func (pe *PolicyEvaluator) CheckPolicy(userID string, policy *Policy) (bool, error) {
+	return recordRBACCheck(RBACCheckPolicy)(pe.evaluate(userID, policy))
+}
+
+// evaluate 评估策略，不记录指标
+func (pe *PolicyEvaluator) evaluate(userID string, policy *Policy) (bool, error) {
 	// 检查角色
 	if len(policy.Roles) > 0 {
-		if !pe.rbac.HasAnyRole(userID, policy.Roles) {
-			return false
+		if allowed, err := pe.rbac.hasAnyRole(userID, policy.Roles); err != nil || !allowed {
+			return false, err
 		}
 	}
 	
 	// 检查权限
 	if len(policy.Permissions) > 0 {
 		for _, permissionID := range policy.Permissions {
-			if !pe.rbac.HasPermission(userID, permissionID) {
-				return false
+			if allowed, err := pe.rbac.hasPermission(userID, permissionID); err != nil || !allowed {
+				return false, err
 			}
 		}
 	}
 	
 	// 检查资源和动作
 	for _, action := range policy.Actions {
-		if !pe.rbac.HasResourcePermission(userID, policy.Resource, action) {
-			return false
+		if allowed, err := pe.rbac.hasResourcePermission(userID, policy.Resource, action); err != nil || !allowed {
+			return false, err
 		}
 	}
 	
-	return true
+	return true, nil
 }
 
 // 预定义角色和权限
@@ -428,6 +480,7 @@ func NewResourceMatcher() *ResourceMatcher {
 }
 
 // Match 匹配资源
+// 资源类型匹配该类型下的所有实例，如 "article" 匹配 "article:42"
 func (rm *ResourceMatcher) Match(pattern, resource string) bool {
 	// 简单的通配符匹配
 	if pattern == "*" {
@@ -438,6 +491,10 @@ func (rm *ResourceMatcher) Match(pattern, resource string) bool {
 		return true
 	}
 	
+	if strings.HasPrefix(resource, pattern+":") {
+		return true
+	}
+	
 	// 支持前缀匹配，如 "user.*" 匹配 "user.profile"
 	if strings.HasSuffix(pattern, "*") {
 		prefix := strings.TrimSuffix(pattern, "*")
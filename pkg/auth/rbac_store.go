@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrRoleNotFound 角色不存在
+	ErrRoleNotFound = errors.New("role not found")
+	// ErrPermissionNotFound 权限不存在
+	ErrPermissionNotFound = errors.New("permission not found")
+)
+
+// RBACStore RBAC数据存储
+// 角色不存在时 GetRole 返回 ErrRoleNotFound，权限不存在时 GetPermission 返回 ErrPermissionNotFound
+type RBACStore interface {
+	SavePermission(permission *Permission) error
+	GetPermission(permissionID string) (*Permission, error)
+	DeletePermission(permissionID string) error
+	ListPermissions() ([]*Permission, error)
+
+	SaveRole(role *Role) error
+	GetRole(roleID string) (*Role, error)
+	DeleteRole(roleID string) error
+	ListRoles() ([]*Role, error)
+
+	GetUserRoles(userID string) ([]string, error)
+	SetUserRoles(userID string, roleIDs []string) error
+	// AddUserRole 原子地为用户追加角色，已分配时不做修改
+	AddUserRole(userID, roleID string) error
+	// RemoveUserRole 原子地移除用户的角色
+	RemoveUserRole(userID, roleID string) error
+	// RemoveRoleAssignments 从所有用户中移除角色
+	RemoveRoleAssignments(roleID string) error
+}
+
+// MemoryRBACStore 内存RBAC存储，重启后数据丢失，仅适用于单实例
+// 保存和读取时复制角色和权限，调用方修改返回的对象不会影响存储中的数据
+type MemoryRBACStore struct {
+	mu          sync.RWMutex
+	roles       map[string]*Role
+	permissions map[string]*Permission
+	userRoles   map[string][]string // userID -> roleIDs
+}
+
+// NewMemoryRBACStore 创建内存RBAC存储
+func NewMemoryRBACStore() *MemoryRBACStore {
+	return &MemoryRBACStore{
+		roles:       make(map[string]*Role),
+		permissions: make(map[string]*Permission),
+		userRoles:   make(map[string][]string),
+	}
+}
+
+// SavePermission 保存权限
+func (s *MemoryRBACStore) SavePermission(permission *Permission) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *permission
+	s.permissions[permission.ID] = &copied
+	return nil
+}
+
+// GetPermission 获取权限
+func (s *MemoryRBACStore) GetPermission(permissionID string) (*Permission, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	permission, exists := s.permissions[permissionID]
+	if !exists {
+		return nil, ErrPermissionNotFound
+	}
+	copied := *permission
+	return &copied, nil
+}
+
+// DeletePermission 删除权限
+func (s *MemoryRBACStore) DeletePermission(permissionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.permissions, permissionID)
+	return nil
+}
+
+// ListPermissions 列出所有权限
+func (s *MemoryRBACStore) ListPermissions() ([]*Permission, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	permissions := make([]*Permission, 0, len(s.permissions))
+	for _, permission := range s.permissions {
+		copied := *permission
+		permissions = append(permissions, &copied)
+	}
+	return permissions, nil
+}
+
+// SaveRole 保存角色
+func (s *MemoryRBACStore) SaveRole(role *Role) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roles[role.ID] = cloneRole(role)
+	return nil
+}
+
+// GetRole 获取角色
+func (s *MemoryRBACStore) GetRole(roleID string) (*Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	role, exists := s.roles[roleID]
+	if !exists {
+		return nil, ErrRoleNotFound
+	}
+	return cloneRole(role), nil
+}
+
+// DeleteRole 删除角色
+func (s *MemoryRBACStore) DeleteRole(roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.roles, roleID)
+	return nil
+}
+
+// ListRoles 列出所有角色
+func (s *MemoryRBACStore) ListRoles() ([]*Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	roles := make([]*Role, 0, len(s.roles))
+	for _, role := range s.roles {
+		roles = append(roles, cloneRole(role))
+	}
+	return roles, nil
+}
+
+// GetUserRoles 获取用户角色ID
+func (s *MemoryRBACStore) GetUserRoles(userID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.userRoles[userID]...), nil
+}
+
+// SetUserRoles 设置用户角色ID
+func (s *MemoryRBACStore) SetUserRoles(userID string, roleIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.userRoles[userID] = append([]string(nil), roleIDs...)
+	return nil
+}
+
+// AddUserRole 为用户追加角色
+func (s *MemoryRBACStore) AddUserRole(userID, roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !containsRole(s.userRoles[userID], roleID) {
+		s.userRoles[userID] = append(s.userRoles[userID], roleID)
+	}
+	return nil
+}
+
+// RemoveUserRole 移除用户的角色
+func (s *MemoryRBACStore) RemoveUserRole(userID, roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.userRoles[userID] = removeString(s.userRoles[userID], roleID)
+	return nil
+}
+
+// RemoveRoleAssignments 从所有用户中移除角色
+func (s *MemoryRBACStore) RemoveRoleAssignments(roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for userID, roles := range s.userRoles {
+		s.userRoles[userID] = removeString(roles, roleID)
+	}
+	return nil
+}
+
+// removeString 从切片中移除指定字符串
+func removeString(values []string, target string) []string {
+	result := values[:0]
+	for _, value := range values {
+		if value != target {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// cloneRole 复制角色及其权限列表
+func cloneRole(role *Role) *Role {
+	copied := *role
+	copied.Permissions = append([]Permission(nil), role.Permissions...)
+	return &copied
+}
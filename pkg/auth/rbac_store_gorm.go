@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rbacPermissionRecord 权限表记录
+type rbacPermissionRecord struct {
+	ID          string `gorm:"primaryKey;size:128"`
+	Name        string `gorm:"size:255"`
+	Description string `gorm:"size:1024"`
+	Resource    string `gorm:"size:255"`
+	Action      string `gorm:"size:128"`
+}
+
+// TableName 权限表名
+func (rbacPermissionRecord) TableName() string {
+	return "rbac_permissions"
+}
+
+// rbacRoleRecord 角色表记录，权限以JSON保存
+type rbacRoleRecord struct {
+	ID          string `gorm:"primaryKey;size:128"`
+	Name        string `gorm:"size:255"`
+	Description string `gorm:"size:1024"`
+	Permissions string `gorm:"type:text"`
+}
+
+// TableName 角色表名
+func (rbacRoleRecord) TableName() string {
+	return "rbac_roles"
+}
+
+// rbacUserRoleRecord 用户角色表记录
+type rbacUserRoleRecord struct {
+	UserID   string `gorm:"primaryKey;size:128"`
+	RoleID   string `gorm:"primaryKey;size:128;index"`
+	Position int
+}
+
+// TableName 用户角色表名
+func (rbacUserRoleRecord) TableName() string {
+	return "rbac_user_roles"
+}
+
+// GormRBACStore 基于GORM的RBAC存储，数据保存在 rbac_permissions、rbac_roles、rbac_user_roles 表
+type GormRBACStore struct {
+	db *gorm.DB
+}
+
+// NewGormRBACStore 创建GORM RBAC存储并自动迁移表结构
+func NewGormRBACStore(db *gorm.DB) (*GormRBACStore, error) {
+	if err := db.AutoMigrate(&rbacPermissionRecord{}, &rbacRoleRecord{}, &rbacUserRoleRecord{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate rbac tables: %w", err)
+	}
+	return &GormRBACStore{db: db}, nil
+}
+
+// SavePermission 保存权限
+func (s *GormRBACStore) SavePermission(permission *Permission) error {
+	record := rbacPermissionRecord{
+		ID:          permission.ID,
+		Name:        permission.Name,
+		Description: permission.Description,
+		Resource:    permission.Resource,
+		Action:      permission.Action,
+	}
+	return s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&record).Error
+}
+
+// GetPermission 获取权限
+func (s *GormRBACStore) GetPermission(permissionID string) (*Permission, error) {
+	var record rbacPermissionRecord
+	if err := s.db.First(&record, "id = ?", permissionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPermissionNotFound
+		}
+		return nil, err
+	}
+	return record.toPermission(), nil
+}
+
+// DeletePermission 删除权限
+func (s *GormRBACStore) DeletePermission(permissionID string) error {
+	return s.db.Delete(&rbacPermissionRecord{}, "id = ?", permissionID).Error
+}
+
+// ListPermissions 列出所有权限
+func (s *GormRBACStore) ListPermissions() ([]*Permission, error) {
+	var records []rbacPermissionRecord
+	if err := s.db.Order("id").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	permissions := make([]*Permission, 0, len(records))
+	for i := range records {
+		permissions = append(permissions, records[i].toPermission())
+	}
+	return permissions, nil
+}
+
+// SaveRole 保存角色
+func (s *GormRBACStore) SaveRole(role *Role) error {
+	permissions, err := json.Marshal(role.Permissions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal role permissions: %w", err)
+	}
+	record := rbacRoleRecord{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: string(permissions),
+	}
+	return s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&record).Error
+}
+
+// GetRole 获取角色
+func (s *GormRBACStore) GetRole(roleID string) (*Role, error) {
+	var record rbacRoleRecord
+	if err := s.db.First(&record, "id = ?", roleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return record.toRole()
+}
+
+// DeleteRole 删除角色
+func (s *GormRBACStore) DeleteRole(roleID string) error {
+	return s.db.Delete(&rbacRoleRecord{}, "id = ?", roleID).Error
+}
+
+// ListRoles 列出所有角色
+func (s *GormRBACStore) ListRoles() ([]*Role, error) {
+	var records []rbacRoleRecord
+	if err := s.db.Order("id").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	roles := make([]*Role, 0, len(records))
+	for i := range records {
+		role, err := records[i].toRole()
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// GetUserRoles 获取用户角色ID，按分配顺序返回
+func (s *GormRBACStore) GetUserRoles(userID string) ([]string, error) {
+	var roleIDs []string
+	err := s.db.Model(&rbacUserRoleRecord{}).
+		Where("user_id = ?", userID).
+		Order("position").
+		Pluck("role_id", &roleIDs).Error
+	return roleIDs, err
+}
+
+// SetUserRoles 在事务中替换用户角色ID
+func (s *GormRBACStore) SetUserRoles(userID string, roleIDs []string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&rbacUserRoleRecord{}, "user_id = ?", userID).Error; err != nil {
+			return err
+		}
+		if len(roleIDs) == 0 {
+			return nil
+		}
+		records := make([]rbacUserRoleRecord, 0, len(roleIDs))
+		for i, roleID := range roleIDs {
+			records = append(records, rbacUserRoleRecord{UserID: userID, RoleID: roleID, Position: i})
+		}
+		return tx.Create(&records).Error
+	})
+}
+
+// AddUserRole 在事务中为用户追加角色，位置为当前最大位置加一；并发追加同一角色时由主键冲突忽略
+func (s *GormRBACStore) AddUserRole(userID, roleID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var positions []int
+		if err := tx.Model(&rbacUserRoleRecord{}).
+			Where("user_id = ?", userID).
+			Pluck("COALESCE(MAX(position) + 1, 0)", &positions).Error; err != nil {
+			return err
+		}
+		record := rbacUserRoleRecord{UserID: userID, RoleID: roleID}
+		if len(positions) > 0 {
+			record.Position = positions[0]
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error
+	})
+}
+
+// RemoveUserRole 移除用户的角色
+func (s *GormRBACStore) RemoveUserRole(userID, roleID string) error {
+	return s.db.Delete(&rbacUserRoleRecord{}, "user_id = ? AND role_id = ?", userID, roleID).Error
+}
+
+// RemoveRoleAssignments 从所有用户中移除角色
+func (s *GormRBACStore) RemoveRoleAssignments(roleID string) error {
+	return s.db.Delete(&rbacUserRoleRecord{}, "role_id = ?", roleID).Error
+}
+
+// toPermission 转换为权限
+func (r *rbacPermissionRecord) toPermission() *Permission {
+	return &Permission{
+		ID:          r.ID,
+		Name:        r.Name,
+		Description: r.Description,
+		Resource:    r.Resource,
+		Action:      r.Action,
+	}
+}
+
+// toRole 转换为角色
+func (r *rbacRoleRecord) toRole() (*Role, error) {
+	role := &Role{ID: r.ID, Name: r.Name, Description: r.Description}
+	if r.Permissions != "" {
+		if err := json.Unmarshal([]byte(r.Permissions), &role.Permissions); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal role permissions: %w", err)
+		}
+	}
+	return role, nil
+}
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hwh/hwhkit-go/pkg/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+// addUserRoleScript 原子地为用户追加角色，已分配时不做修改
+var addUserRoleScript = redis.NewScript(`
+local value = redis.call("HGET", KEYS[1], ARGV[1])
+local roles = {}
+if value then
+	roles = cjson.decode(value)
+	if type(roles) ~= "table" then
+		roles = {}
+	end
+end
+for _, role in ipairs(roles) do
+	if role == ARGV[2] then
+		return 0
+	end
+end
+table.insert(roles, ARGV[2])
+redis.call("HSET", KEYS[1], ARGV[1], cjson.encode(roles))
+return 1
+`)
+
+// removeUserRoleScript 原子地移除用户的角色，没有剩余角色时删除字段
+var removeUserRoleScript = redis.NewScript(`
+local value = redis.call("HGET", KEYS[1], ARGV[1])
+if not value then
+	return 0
+end
+local roles = cjson.decode(value)
+local kept = {}
+if type(roles) == "table" then
+	for _, role in ipairs(roles) do
+		if role ~= ARGV[2] then
+			table.insert(kept, role)
+		end
+	end
+end
+if #kept == 0 then
+	redis.call("HDEL", KEYS[1], ARGV[1])
+else
+	redis.call("HSET", KEYS[1], ARGV[1], cjson.encode(kept))
+end
+return 1
+`)
+
+// RedisRBACStore 基于Redis的RBAC存储
+// 权限、角色和用户角色分别保存在 <prefix>permissions、<prefix>roles、<prefix>user_roles 三个哈希中
+type RedisRBACStore struct {
+	cache  *cache.Manager
+	prefix string
+}
+
+// NewRedisRBACStore 创建Redis RBAC存储，prefix 为空时使用 rbac:
+func NewRedisRBACStore(cacheManager *cache.Manager, prefix string) *RedisRBACStore {
+	if prefix == "" {
+		prefix = "rbac:"
+	}
+	return &RedisRBACStore{cache: cacheManager, prefix: prefix}
+}
+
+// key 获取哈希键
+func (s *RedisRBACStore) key(name string) string {
+	return s.prefix + name
+}
+
+// SavePermission 保存权限
+func (s *RedisRBACStore) SavePermission(permission *Permission) error {
+	return s.save("permissions", permission.ID, permission)
+}
+
+// GetPermission 获取权限
+func (s *RedisRBACStore) GetPermission(permissionID string) (*Permission, error) {
+	var permission Permission
+	if err := s.load("permissions", permissionID, &permission); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrPermissionNotFound
+		}
+		return nil, err
+	}
+	return &permission, nil
+}
+
+// DeletePermission 删除权限
+func (s *RedisRBACStore) DeletePermission(permissionID string) error {
+	return s.cache.HDel(s.key("permissions"), permissionID)
+}
+
+// ListPermissions 列出所有权限
+func (s *RedisRBACStore) ListPermissions() ([]*Permission, error) {
+	values, err := s.cache.HGetAll(s.key("permissions"))
+	if err != nil {
+		return nil, err
+	}
+	permissions := make([]*Permission, 0, len(values))
+	for _, value := range values {
+		var permission Permission
+		if err := json.Unmarshal([]byte(value), &permission); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal permission: %w", err)
+		}
+		permissions = append(permissions, &permission)
+	}
+	return permissions, nil
+}
+
+// SaveRole 保存角色
+func (s *RedisRBACStore) SaveRole(role *Role) error {
+	return s.save("roles", role.ID, role)
+}
+
+// GetRole 获取角色
+func (s *RedisRBACStore) GetRole(roleID string) (*Role, error) {
+	var role Role
+	if err := s.load("roles", roleID, &role); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// DeleteRole 删除角色
+func (s *RedisRBACStore) DeleteRole(roleID string) error {
+	return s.cache.HDel(s.key("roles"), roleID)
+}
+
+// ListRoles 列出所有角色
+func (s *RedisRBACStore) ListRoles() ([]*Role, error) {
+	values, err := s.cache.HGetAll(s.key("roles"))
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]*Role, 0, len(values))
+	for _, value := range values {
+		var role Role
+		if err := json.Unmarshal([]byte(value), &role); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal role: %w", err)
+		}
+		roles = append(roles, &role)
+	}
+	return roles, nil
+}
+
+// GetUserRoles 获取用户角色ID
+func (s *RedisRBACStore) GetUserRoles(userID string) ([]string, error) {
+	var roleIDs []string
+	if err := s.load("user_roles", userID, &roleIDs); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	return roleIDs, nil
+}
+
+// SetUserRoles 设置用户角色ID
+func (s *RedisRBACStore) SetUserRoles(userID string, roleIDs []string) error {
+	if len(roleIDs) == 0 {
+		return s.cache.HDel(s.key("user_roles"), userID)
+	}
+	return s.save("user_roles", userID, roleIDs)
+}
+
+// AddUserRole 通过Lua脚本原子地为用户追加角色
+func (s *RedisRBACStore) AddUserRole(userID, roleID string) error {
+	key := s.cache.Key(s.key("user_roles"))
+	return addUserRoleScript.Run(context.Background(), s.cache.GetClient(), []string{key}, userID, roleID).Err()
+}
+
+// RemoveUserRole 通过Lua脚本原子地移除用户的角色
+func (s *RedisRBACStore) RemoveUserRole(userID, roleID string) error {
+	key := s.cache.Key(s.key("user_roles"))
+	return removeUserRoleScript.Run(context.Background(), s.cache.GetClient(), []string{key}, userID, roleID).Err()
+}
+
+// RemoveRoleAssignments 从所有用户中移除角色
+func (s *RedisRBACStore) RemoveRoleAssignments(roleID string) error {
+	values, err := s.cache.HGetAll(s.key("user_roles"))
+	if err != nil {
+		return err
+	}
+	for userID, value := range values {
+		var roleIDs []string
+		if err := json.Unmarshal([]byte(value), &roleIDs); err != nil {
+			return fmt.Errorf("failed to unmarshal user roles: %w", err)
+		}
+		remaining := removeString(roleIDs, roleID)
+		if len(remaining) == len(roleIDs) {
+			continue
+		}
+		if err := s.SetUserRoles(userID, remaining); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// save 以JSON写入哈希字段
+func (s *RedisRBACStore) save(hash, field string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", hash, err)
+	}
+	return s.cache.HSet(s.key(hash), field, data)
+}
+
+// load 读取哈希字段并解析JSON，字段不存在时返回 redis.Nil
+func (s *RedisRBACStore) load(hash, field string, dest interface{}) error {
+	value, err := s.cache.HGet(s.key(hash), field)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(value), dest); err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %w", hash, err)
+	}
+	return nil
+}
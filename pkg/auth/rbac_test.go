@@ -1,8 +1,19 @@
 package auth
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hwh/hwhkit-go/pkg/cache"
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
 )
 
 func TestRBAC(t *testing.T) {
@@ -148,13 +159,19 @@ func TestRBACRoleManagement(t *testing.T) {
 	rbac.AssignRoleToUser(userID, "role2")
 	
 	// 测试用户拥有的所有权限
-	userPermissions := rbac.GetUserPermissions(userID)
+	userPermissions, err := rbac.GetUserPermissions(userID)
+	if err != nil {
+		t.Fatalf("Failed to get user permissions: %v", err)
+	}
 	if len(userPermissions) != 3 {
 		t.Errorf("Expected 3 permissions, got %d", len(userPermissions))
 	}
 	
 	// 测试用户角色
-	userRoles := rbac.GetUserRoles(userID)
+	userRoles, err := rbac.GetUserRoles(userID)
+	if err != nil {
+		t.Fatalf("Failed to get user roles: %v", err)
+	}
 	if len(userRoles) != 2 {
 		t.Errorf("Expected 2 roles, got %d", len(userRoles))
 	}
@@ -410,6 +427,38 @@ func TestResourceMatcher(t *testing.T) {
 	if matcher.Match("user.*", "admin.settings") {
 		t.Error("Should not match different prefix")
 	}
+	
+	// 测试资源实例匹配
+	if !matcher.Match("article", "article:42") {
+		t.Error("Resource type should match its instances")
+	}
+	if matcher.Match("article:41", "article:42") || matcher.Match("article", "articles:42") {
+		t.Error("Should not match other instances or types")
+	}
+}
+
+func TestResourceInstancePermission(t *testing.T) {
+	rbac := NewRBAC()
+	rbac.AddPermission(&Permission{ID: "article.update", Name: "更新文章", Resource: "article", Action: "update"})
+	rbac.AddPermission(&Permission{ID: "draft.all", Name: "管理草稿7", Resource: "draft:7", Action: "*"})
+	rbac.AddRole(&Role{ID: "editor", Name: "编辑"})
+	rbac.AddPermissionToRole("editor", "article.update")
+	rbac.AddPermissionToRole("editor", "draft.all")
+	rbac.AssignRoleToUser("u1", "editor")
+	
+	if !rbac.HasResourcePermission("u1", "article:42", "update") {
+		t.Error("Type permission should cover article instances")
+	}
+	if rbac.HasResourcePermission("u1", "article:42", "delete") {
+		t.Error("Action should still be checked")
+	}
+	if !rbac.HasResourcePermission("u1", "draft:7", "publish") {
+		t.Error("Wildcard action should cover any action on draft:7")
+	}
+	// 动作通配符只作用于所在的资源
+	if rbac.HasResourcePermission("u1", "user", "publish") || rbac.HasResourcePermission("u1", "draft:8", "read") {
+		t.Error("Wildcard action should not grant other resources")
+	}
 }
 
 func TestRBACListMethods(t *testing.T) {
@@ -435,12 +484,18 @@ func TestRBACListMethods(t *testing.T) {
 	}
 	
 	// 测试列表方法
-	allPermissions := rbac.ListPermissions()
+	allPermissions, err := rbac.ListPermissions()
+	if err != nil {
+		t.Fatalf("Failed to list permissions: %v", err)
+	}
 	if len(allPermissions) != 2 {
 		t.Errorf("Expected 2 permissions, got %d", len(allPermissions))
 	}
 	
-	allRoles := rbac.ListRoles()
+	allRoles, err := rbac.ListRoles()
+	if err != nil {
+		t.Fatalf("Failed to list roles: %v", err)
+	}
 	if len(allRoles) != 2 {
 		t.Errorf("Expected 2 roles, got %d", len(allRoles))
 	}
@@ -450,7 +505,10 @@ func TestRBACListMethods(t *testing.T) {
 	rbac.AssignRoleToUser(userID, "r1")
 	rbac.AssignRoleToUser(userID, "r2")
 	
-	userRoleDetails := rbac.GetRolesByUser(userID)
+	userRoleDetails, err := rbac.GetRolesByUser(userID)
+	if err != nil {
+		t.Fatalf("Failed to get role details: %v", err)
+	}
 	if len(userRoleDetails) != 2 {
 		t.Errorf("Expected 2 role details, got %d", len(userRoleDetails))
 	}
@@ -491,3 +549,225 @@ func BenchmarkRBACHasPermission(b *testing.B) {
 		rbac.HasPermission(userID, "permission_50")
 	}
 }
+
+func TestRBACStores(t *testing.T) {
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatalf("Failed to create cache manager: %v", err)
+	}
+	defer cacheManager.Close()
+
+	stores := map[string]RBACStore{
+		"memory": NewMemoryRBACStore(),
+		"redis":  NewRedisRBACStore(cacheManager, ""),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			rbac := NewRBACWithStore(store)
+			if err := CreateDefaultRolesAndPermissions(rbac); err != nil {
+				t.Fatalf("Failed to create defaults: %v", err)
+			}
+			if err := rbac.AddPermissionToRole("user", "user.write"); err != nil {
+				t.Fatalf("Failed to add permission to role: %v", err)
+			}
+			rbac.AssignRoleToUser("u1", "user")
+			rbac.AssignRoleToUser("u1", "admin")
+
+			// 使用同一存储的新实例可以读取到已保存的数据
+			reloaded := NewRBACWithStore(store)
+			userRoles, err := reloaded.GetUserRoles("u1")
+			if err != nil || !reloaded.HasPermission("u1", "user.write") || len(userRoles) != 2 {
+				t.Errorf("Expected persisted roles and permissions, got %v (%v)", userRoles, err)
+			}
+			permissions, _ := reloaded.ListPermissions()
+			roles, _ := reloaded.ListRoles()
+			if len(permissions) != 4 || len(roles) != 2 {
+				t.Errorf("Unexpected list sizes: %d permissions, %d roles", len(permissions), len(roles))
+			}
+
+			// 重复分配不会产生重复记录，移除只影响指定角色
+			if err := rbac.AssignRoleToUser("u1", "user"); err != nil {
+				t.Fatalf("Failed to reassign role: %v", err)
+			}
+			if userRoles, _ := rbac.GetUserRoles("u1"); len(userRoles) != 2 {
+				t.Errorf("Duplicate assignment should be ignored, got %v", userRoles)
+			}
+			rbac.AssignRoleToUser("u2", "user")
+			if err := rbac.RemoveRoleFromUser("u2", "user"); err != nil {
+				t.Fatalf("Failed to remove role from user: %v", err)
+			}
+			if userRoles, _ := rbac.GetUserRoles("u2"); len(userRoles) != 0 {
+				t.Errorf("Expected no roles for u2, got %v", userRoles)
+			}
+			if userRoles, _ := rbac.GetUserRoles("u1"); len(userRoles) != 2 {
+				t.Errorf("Removing u2's role should not affect u1, got %v", userRoles)
+			}
+
+			if err := reloaded.RemovePermission("user.write"); err != nil {
+				t.Fatalf("Failed to remove permission: %v", err)
+			}
+			if rbac.HasPermission("u1", "user.write") {
+				t.Error("Permission should be removed from roles")
+			}
+
+			if err := rbac.RemoveRole("admin"); err != nil {
+				t.Fatalf("Failed to remove role: %v", err)
+			}
+			if roles, _ := rbac.GetUserRoles("u1"); len(roles) != 1 || roles[0] != "user" {
+				t.Errorf("Expected only user role, got %v", roles)
+			}
+
+			if _, err := rbac.GetRole("admin"); !errors.Is(err, ErrRoleNotFound) {
+				t.Errorf("Expected ErrRoleNotFound, got %v", err)
+			}
+			if _, err := rbac.GetPermission("missing"); !errors.Is(err, ErrPermissionNotFound) {
+				t.Errorf("Expected ErrPermissionNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+// failingRBACStore 读取用户角色时返回错误的存储
+type failingRBACStore struct {
+	*MemoryRBACStore
+}
+
+func (s *failingRBACStore) GetUserRoles(userID string) ([]string, error) {
+	return nil, errors.New("store unavailable")
+}
+
+func TestRBACStoreErrors(t *testing.T) {
+	rbac := NewRBACWithStore(&failingRBACStore{NewMemoryRBACStore()})
+	if err := CreateDefaultRolesAndPermissions(rbac); err != nil {
+		t.Fatalf("Failed to create defaults: %v", err)
+	}
+
+	// 存储故障时布尔接口拒绝访问，Check 接口返回错误
+	if rbac.HasPermission("u1", "user.read") || rbac.HasResourcePermission("u1", "user", "read") || rbac.HasRole("u1", "user") {
+		t.Error("Checks should fail closed when the store errors")
+	}
+	if _, err := rbac.CheckResourcePermission("u1", "user", "read"); err == nil {
+		t.Error("Expected CheckResourcePermission to return the store error")
+	}
+	evaluator := NewPolicyEvaluator(rbac)
+	policy := &Policy{Resource: "user", Actions: []string{"read"}, Roles: []string{"user"}}
+	if _, err := evaluator.CheckPolicy("u1", policy); err == nil {
+		t.Error("Expected CheckPolicy to return the store error")
+	}
+	if _, err := rbac.GetUserPermissions("u1"); err == nil {
+		t.Error("Expected GetUserPermissions to return the store error")
+	}
+}
+
+func TestMemoryRBACStoreCopies(t *testing.T) {
+	store := NewMemoryRBACStore()
+	role := &Role{ID: "editor", Permissions: []Permission{{ID: "article.write"}}}
+	if err := store.SaveRole(role); err != nil {
+		t.Fatalf("Failed to save role: %v", err)
+	}
+
+	// 修改保存前后的对象都不会影响存储中的数据
+	role.Permissions[0].ID = "changed"
+	got, err := store.GetRole("editor")
+	if err != nil {
+		t.Fatalf("Failed to get role: %v", err)
+	}
+	got.Permissions = append(got.Permissions[:0], Permission{ID: "other"})
+	roles, _ := store.ListRoles()
+	roles[0].Name = "renamed"
+	if again, _ := store.GetRole("editor"); again.Name != "" || len(again.Permissions) != 1 || again.Permissions[0].ID != "article.write" {
+		t.Errorf("Stored role was mutated: %+v", again)
+	}
+
+	store.AddUserRole("u1", "editor")
+	userRoles, _ := store.GetUserRoles("u1")
+	userRoles[0] = "admin"
+	if again, _ := store.GetUserRoles("u1"); again[0] != "editor" {
+		t.Errorf("Stored user roles were mutated: %v", again)
+	}
+}
+
+// recordingPool 记录事务边界的连接池，配合试运行模式测试 GormRBACStore
+type recordingPool struct {
+	events *[]string
+}
+
+func (p *recordingPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *recordingPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *recordingPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *recordingPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *recordingPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	*p.events = append(*p.events, "BEGIN")
+	return &recordingTx{recordingPool: p}, nil
+}
+
+// recordingTx 记录提交和回滚的事务
+type recordingTx struct {
+	*recordingPool
+}
+
+func (t *recordingTx) Commit() error {
+	*t.events = append(*t.events, "COMMIT")
+	return nil
+}
+
+func (t *recordingTx) Rollback() error {
+	*t.events = append(*t.events, "ROLLBACK")
+	return nil
+}
+
+func TestGormRBACStoreUserRoles(t *testing.T) {
+	var events []string
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: &recordingPool{events: &events}}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open dry-run database: %v", err)
+	}
+	capture := func(tx *gorm.DB) {
+		events = append(events, tx.Statement.SQL.String())
+	}
+	db.Callback().Create().After("gorm:create").Register("test:capture", capture)
+	db.Callback().Query().After("gorm:query").Register("test:capture", capture)
+	db.Callback().Delete().After("gorm:delete").Register("test:capture", capture)
+	store := &GormRBACStore{db: db}
+
+	// 追加角色在同一事务中读取下一个位置并忽略重复分配，不再整体重写用户角色
+	if err := store.AddUserRole("u1", "editor"); err != nil {
+		t.Fatalf("Failed to add user role: %v", err)
+	}
+	if len(events) != 4 || events[0] != "BEGIN" || events[3] != "COMMIT" {
+		t.Fatalf("Unexpected statements: %q", events)
+	}
+	if !strings.Contains(events[1], "COALESCE(MAX(position) + 1, 0)") || !strings.Contains(events[1], "user_id = $1") {
+		t.Errorf("Unexpected position query: %s", events[1])
+	}
+	if !strings.Contains(events[2], `INSERT INTO "rbac_user_roles"`) || !strings.Contains(events[2], "ON CONFLICT DO NOTHING") {
+		t.Errorf("Unexpected insert: %s", events[2])
+	}
+
+	// 移除只删除指定用户的指定角色
+	events = nil
+	if err := store.RemoveUserRole("u1", "editor"); err != nil {
+		t.Fatalf("Failed to remove user role: %v", err)
+	}
+	if len(events) != 1 || !strings.Contains(events[0], `DELETE FROM "rbac_user_roles" WHERE user_id = $1 AND role_id = $2`) {
+		t.Errorf("Unexpected delete: %q", events)
+	}
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hwh/hwhkit-go/pkg/cache"
+)
+
+// ErrTokenRevoked 令牌已被吊销
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// RevocationStore 令牌吊销列表，按令牌ID（jti）记录，令牌过期后记录可以删除
+type RevocationStore interface {
+	// Revoke 吊销令牌，expiresAt 为令牌的过期时间
+	Revoke(tokenID string, expiresAt time.Time) error
+	// IsRevoked 令牌是否已被吊销
+	IsRevoked(tokenID string) (bool, error)
+}
+
+// MemoryRevocationStore 内存吊销列表，只在单实例内生效，过期记录在吊销新令牌时清理
+type MemoryRevocationStore struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time
+}
+
+// NewMemoryRevocationStore 创建内存吊销列表
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{revoked: make(map[string]time.Time)}
+}
+
+// Revoke 吊销令牌
+func (s *MemoryRevocationStore) Revoke(tokenID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, expiry := range s.revoked {
+		if now.After(expiry) {
+			delete(s.revoked, id)
+		}
+	}
+	s.revoked[tokenID] = expiresAt
+	SetRevokedTokenCount(len(s.revoked))
+	return nil
+}
+
+// IsRevoked 令牌是否已被吊销
+func (s *MemoryRevocationStore) IsRevoked(tokenID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, revoked := s.revoked[tokenID]
+	return revoked, nil
+}
+
+// RedisRevocationStore 基于Redis的吊销列表，键为 <prefix><jti>，在令牌过期时自动删除，多实例共享
+type RedisRevocationStore struct {
+	cache  *cache.Manager
+	prefix string
+}
+
+// NewRedisRevocationStore 创建Redis吊销列表，prefix 为空时使用 token:revoked:
+func NewRedisRevocationStore(cacheManager *cache.Manager, prefix string) *RedisRevocationStore {
+	if prefix == "" {
+		prefix = "token:revoked:"
+	}
+	return &RedisRevocationStore{cache: cacheManager, prefix: prefix}
+}
+
+// Revoke 吊销令牌，已过期的令牌无需记录
+func (s *RedisRevocationStore) Revoke(tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.cache.Set(s.prefix+tokenID, expiresAt.Unix(), ttl)
+}
+
+// IsRevoked 令牌是否已被吊销
+func (s *RedisRevocationStore) IsRevoked(tokenID string) (bool, error) {
+	return s.cache.Exists(s.prefix + tokenID)
+}
+
+// TokenIntrospection RFC 7662 令牌内省结果，无效令牌只返回 active: false
+type TokenIntrospection struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Exp       int64    `json:"exp,omitempty"`
+	Iat       int64    `json:"iat,omitempty"`
+	Nbf       int64    `json:"nbf,omitempty"`
+	Sub       string   `json:"sub,omitempty"`
+	Aud       []string `json:"aud,omitempty"`
+	Iss       string   `json:"iss,omitempty"`
+	Jti       string   `json:"jti,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	TokenUse  string   `json:"token_use,omitempty"` // access_token 或 refresh_token
+}
+
+// 内省结果中的令牌用途，与RFC 7009 的 token_type_hint 取值一致
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// numericDate 转换为Unix时间戳，nil 时为0
+func numericDate(date *jwt.NumericDate) int64 {
+	if date == nil {
+		return 0
+	}
+	return date.Unix()
+}
+
+// revokeClaims 将令牌ID加入吊销列表并记录指标
+func revokeClaims(store RevocationStore, tokenID string, expiresAt time.Time) error {
+	if tokenID == "" {
+		return errors.New("token has no id and cannot be revoked")
+	}
+	if err := store.Revoke(tokenID, expiresAt); err != nil {
+		return err
+	}
+	RecordTokenRevocation()
+	return nil
+}
+
+// SetRevocationStore 设置令牌吊销列表，设置后 ValidateToken 拒绝已吊销的令牌
+func (m *Manager) SetRevocationStore(store RevocationStore) {
+	m.revocations = store
+}
+
+// RevocationStore 获取令牌吊销列表，未设置时返回 nil
+func (m *Manager) RevocationStore() RevocationStore {
+	return m.revocations
+}
+
+// RevokeToken 吊销访问令牌或刷新令牌，返回被吊销令牌的声明
+// 吊销刷新令牌不会吊销已用它签发的访问令牌，访问令牌需要单独吊销或等待过期
+func (m *Manager) RevokeToken(tokenString string) (*Claims, error) {
+	if m.revocations == nil {
+		return nil, errors.New("token revocation store is not configured")
+	}
+	claims, err := m.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(time.Duration(m.config.RefreshHours) * time.Hour)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	if err := revokeClaims(m.revocations, claims.ID, expiresAt); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// IntrospectToken 按RFC 7662 返回令牌信息，无效、过期或已吊销的令牌返回 active: false
+func (m *Manager) IntrospectToken(tokenString string) *TokenIntrospection {
+	claims, err := m.ValidateToken(tokenString)
+	if err != nil {
+		return &TokenIntrospection{Active: false}
+	}
+	tokenUse := TokenTypeHintAccessToken
+	if claims.IsRefresh() {
+		tokenUse = TokenTypeHintRefreshToken
+	}
+	return &TokenIntrospection{
+		Active:    true,
+		Username:  claims.Username,
+		TokenType: "Bearer",
+		Exp:       numericDate(claims.ExpiresAt),
+		Iat:       numericDate(claims.IssuedAt),
+		Nbf:       numericDate(claims.NotBefore),
+		Sub:       claims.UserID,
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+		Roles:     claims.Roles,
+		TokenUse:  tokenUse,
+	}
+}
+
+// SetRevocationStore 设置访问令牌吊销列表，设置后 ValidateAccessToken 拒绝已吊销的令牌
+func (p *OIDCProvider) SetRevocationStore(store RevocationStore) {
+	p.revocations = store
+}
+
+// RevokeAccessToken 吊销本提供方签发的访问令牌并返回其声明，clientID 不为空时只能吊销签发给该客户端的令牌
+func (p *OIDCProvider) RevokeAccessToken(tokenString, clientID string) (*OIDCAccessClaims, error) {
+	if p.revocations == nil {
+		return nil, errors.New("token revocation store is not configured")
+	}
+	claims, err := p.ValidateAccessToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if clientID != "" && claims.ClientID != clientID {
+		return nil, fmt.Errorf("%w: token was issued to another client", ErrOIDCUnauthorizedClient)
+	}
+	if err := revokeClaims(p.revocations, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// IntrospectAccessToken 按RFC 7662 返回本提供方签发的访问令牌信息
+func (p *OIDCProvider) IntrospectAccessToken(tokenString string) *TokenIntrospection {
+	claims, err := p.ValidateAccessToken(tokenString)
+	if err != nil {
+		return &TokenIntrospection{Active: false}
+	}
+	return &TokenIntrospection{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		Exp:       numericDate(claims.ExpiresAt),
+		Iat:       numericDate(claims.IssuedAt),
+		Sub:       claims.Subject,
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+		TokenUse:  TokenTypeHintAccessToken,
+	}
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hwh/hwhkit-go/pkg/cache"
+	"github.com/hwh/hwhkit-go/pkg/config"
+)
+
+func TestManagerRevocation(t *testing.T) {
+	manager := New(getTestConfig())
+	pair, err := manager.GenerateTokenPairForUser(&User{ID: "42", Username: "alice", Roles: []string{"admin"}}, "")
+	if err != nil {
+		t.Fatalf("Failed to generate token pair: %v", err)
+	}
+	if _, err := manager.RevokeToken(pair.AccessToken); err == nil {
+		t.Error("Expected error without revocation store")
+	}
+
+	manager.SetRevocationStore(NewMemoryRevocationStore())
+	info := manager.IntrospectToken(pair.AccessToken)
+	if !info.Active || info.Sub != "42" || info.Username != "alice" || info.TokenUse != TokenTypeHintAccessToken || info.Jti == "" {
+		t.Errorf("Unexpected introspection: %+v", info)
+	}
+	if info := manager.IntrospectToken(pair.RefreshToken); !info.Active || info.TokenUse != TokenTypeHintRefreshToken {
+		t.Errorf("Unexpected refresh token introspection: %+v", info)
+	}
+	if info := manager.IntrospectToken("not-a-token"); info.Active || info.Sub != "" {
+		t.Errorf("Expected inactive token with no details, got %+v", info)
+	}
+
+	claims, err := manager.RevokeToken(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("Failed to revoke token: %v", err)
+	}
+	if claims.UserID != "42" {
+		t.Errorf("Expected revoked claims, got %+v", claims)
+	}
+	if _, err := manager.ValidateToken(pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Expected ErrTokenRevoked, got %v", err)
+	}
+	if manager.IntrospectToken(pair.AccessToken).Active {
+		t.Error("Expected revoked token to be inactive")
+	}
+
+	// 吊销刷新令牌后不能再刷新
+	if _, err := manager.RevokeToken(pair.RefreshToken); err != nil {
+		t.Fatalf("Failed to revoke refresh token: %v", err)
+	}
+	if _, err := manager.RefreshToken(pair.RefreshToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Expected refresh with revoked token to fail, got %v", err)
+	}
+}
+
+func TestRedisRevocationStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	if err != nil {
+		t.Fatalf("Failed to create cache manager: %v", err)
+	}
+	defer cacheManager.Close()
+
+	store := NewRedisRevocationStore(cacheManager, "")
+	if err := store.Revoke("jti-1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to revoke: %v", err)
+	}
+	if err := store.Revoke("jti-expired", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("Failed to revoke expired token: %v", err)
+	}
+	if revoked, _ := store.IsRevoked("jti-1"); !revoked {
+		t.Error("Expected jti-1 to be revoked")
+	}
+	if revoked, _ := store.IsRevoked("jti-expired"); revoked {
+		t.Error("Expected expired token not to be recorded")
+	}
+	if ttl := mr.TTL("app:token:revoked:jti-1"); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("Expected entry to expire with the token, got ttl %v", ttl)
+	}
+
+	mr.FastForward(2 * time.Hour)
+	if revoked, _ := store.IsRevoked("jti-1"); revoked {
+		t.Error("Expected entry to be removed after token expiry")
+	}
+}
+
+func TestOIDCProviderRevocation(t *testing.T) {
+	provider := newTestOIDCProvider(t)
+	provider.SetRevocationStore(NewMemoryRevocationStore())
+	client, secret, err := provider.RegisterClient("ci", nil, []string{GrantTypeClientCredentials}, []string{"deploy"})
+	if err != nil {
+		t.Fatalf("Failed to register client: %v", err)
+	}
+	other, _, err := provider.RegisterClient("other", nil, []string{GrantTypeClientCredentials}, nil)
+	if err != nil {
+		t.Fatalf("Failed to register client: %v", err)
+	}
+	resp, err := provider.Exchange(TokenRequest{GrantType: GrantTypeClientCredentials, ClientID: client.ID, ClientSecret: secret})
+	if err != nil {
+		t.Fatalf("Failed to exchange client credentials: %v", err)
+	}
+
+	info := provider.IntrospectAccessToken(resp.AccessToken)
+	if !info.Active || info.ClientID != client.ID || info.Scope != "deploy" || info.Iss != "https://id.example.com" {
+		t.Errorf("Unexpected introspection: %+v", info)
+	}
+
+	if _, err := provider.RevokeAccessToken(resp.AccessToken, other.ID); OIDCErrorCode(err) != "unauthorized_client" {
+		t.Errorf("Expected unauthorized_client when revoking another client's token, got %v", err)
+	}
+	if _, err := provider.RevokeAccessToken(resp.AccessToken, client.ID); err != nil {
+		t.Fatalf("Failed to revoke access token: %v", err)
+	}
+	if _, err := provider.ValidateAccessToken(resp.AccessToken); OIDCErrorCode(err) != "invalid_token" || !errors.Is(err, ErrOIDCInvalidToken) {
+		t.Errorf("Expected invalid_token after revocation, got %v", err)
+	}
+	if provider.IntrospectAccessToken(resp.AccessToken).Active {
+		t.Error("Expected revoked access token to be inactive")
+	}
+}
@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+)
+
+// SAMLAttributeMapping SAML属性到 auth.User 的映射，按属性 Name 或 FriendlyName 匹配
+type SAMLAttributeMapping struct {
+	ID       string            // 用户ID属性，为空时使用 NameID
+	Username string            // 用户名属性，默认 uid
+	Email    string            // 邮箱属性，默认 mail
+	Groups   string            // 用户组属性，默认 memberOf
+	RoleMap  map[string]string // 用户组到角色的映射，未映射的组不授予角色，为nil时不授予任何角色
+}
+
+// SAMLIdentityPrefix SAML用户ID的前缀，外部身份与本地用户ID处于不同命名空间，不会被当作本地账号
+const SAMLIdentityPrefix = "saml:"
+
+// SAMLConfig SAML服务提供方配置
+type SAMLConfig struct {
+	RootURL           string            // 应用根地址，如 https://app.example.com
+	EntityID          string            // SP实体ID，默认为元数据地址
+	Certificate       *x509.Certificate // SP签名证书
+	PrivateKey        *rsa.PrivateKey   // SP签名私钥
+	IDPMetadata       []byte            // IdP元数据XML，与 IDPMetadataURL 二选一
+	IDPMetadataURL    string            // IdP元数据地址
+	AllowIDPInitiated bool              // 是否允许IdP发起的登录
+	Mapping           SAMLAttributeMapping
+}
+
+// SAMLServiceProvider SAML服务提供方
+type SAMLServiceProvider struct {
+	sp      *saml.ServiceProvider
+	mapping SAMLAttributeMapping
+}
+
+// SAML路由路径
+const (
+	SAMLMetadataPath = "/saml/metadata"
+	SAMLACSPath      = "/saml/acs"
+	SAMLLoginPath    = "/saml/login"
+)
+
+// NewSAMLServiceProvider 创建SAML服务提供方
+func NewSAMLServiceProvider(ctx context.Context, cfg SAMLConfig) (*SAMLServiceProvider, error) {
+	if cfg.Certificate == nil || cfg.PrivateKey == nil {
+		return nil, errors.New("saml certificate and private key are required")
+	}
+
+	rootURL, err := url.Parse(strings.TrimSuffix(cfg.RootURL, "/"))
+	if err != nil || rootURL.Host == "" {
+		return nil, fmt.Errorf("invalid saml root url: %q", cfg.RootURL)
+	}
+
+	idpMetadata, err := loadIDPMetadata(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataURL := *rootURL
+	metadataURL.Path += SAMLMetadataPath
+	acsURL := *rootURL
+	acsURL.Path += SAMLACSPath
+
+	entityID := cfg.EntityID
+	if entityID == "" {
+		entityID = metadataURL.String()
+	}
+
+	mapping := cfg.Mapping
+	if mapping.Username == "" {
+		mapping.Username = "uid"
+	}
+	if mapping.Email == "" {
+		mapping.Email = "mail"
+	}
+	if mapping.Groups == "" {
+		mapping.Groups = "memberOf"
+	}
+
+	return &SAMLServiceProvider{
+		sp: &saml.ServiceProvider{
+			EntityID:          entityID,
+			Key:               cfg.PrivateKey,
+			Certificate:       cfg.Certificate,
+			MetadataURL:       metadataURL,
+			AcsURL:            acsURL,
+			IDPMetadata:       idpMetadata,
+			AllowIDPInitiated: cfg.AllowIDPInitiated,
+		},
+		mapping: mapping,
+	}, nil
+}
+
+// loadIDPMetadata 解析或下载IdP元数据
+func loadIDPMetadata(ctx context.Context, cfg SAMLConfig) (*saml.EntityDescriptor, error) {
+	if len(cfg.IDPMetadata) > 0 {
+		metadata, err := samlsp.ParseMetadata(cfg.IDPMetadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse idp metadata: %w", err)
+		}
+		return metadata, nil
+	}
+
+	if cfg.IDPMetadataURL == "" {
+		return nil, errors.New("idp metadata or metadata url is required")
+	}
+	metadataURL, err := url.Parse(cfg.IDPMetadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid idp metadata url: %w", err)
+	}
+	metadata, err := samlsp.FetchMetadata(ctx, http.DefaultClient, *metadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch idp metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// EntityID 获取SP实体ID
+func (s *SAMLServiceProvider) EntityID() string {
+	return s.sp.EntityID
+}
+
+// Metadata 生成SP元数据XML，提供给IdP管理员配置
+func (s *SAMLServiceProvider) Metadata() ([]byte, error) {
+	data, err := xml.MarshalIndent(s.sp.Metadata(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal sp metadata: %w", err)
+	}
+	return data, nil
+}
+
+// MakeAuthnRequest 生成跳转到IdP的登录地址（HTTP-Redirect绑定）
+// 返回的请求ID需要由调用方保存（如Cookie），在 ParseResponse 时用于校验 InResponseTo
+func (s *SAMLServiceProvider) MakeAuthnRequest(relayState string) (redirectURL string, requestID string, err error) {
+	idpURL := s.sp.GetSSOBindingLocation(saml.HTTPRedirectBinding)
+	if idpURL == "" {
+		return "", "", errors.New("idp does not support HTTP-Redirect binding")
+	}
+
+	req, err := s.sp.MakeAuthenticationRequest(idpURL, saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to make authn request: %w", err)
+	}
+	target, err := req.Redirect(relayState, s.sp)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build redirect url: %w", err)
+	}
+	return target.String(), req.ID, nil
+}
+
+// ParseResponse 校验ACS收到的SAML响应（签名、受众、有效期、InResponseTo）并映射为用户
+func (s *SAMLServiceProvider) ParseResponse(r *http.Request, possibleRequestIDs []string) (*User, error) {
+	assertion, err := s.sp.ParseResponse(r, possibleRequestIDs)
+	if err != nil {
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) && invalid.PrivateErr != nil {
+			return nil, fmt.Errorf("invalid saml response: %w", invalid.PrivateErr)
+		}
+		return nil, fmt.Errorf("invalid saml response: %w", err)
+	}
+	return s.MapAssertion(assertion)
+}
+
+// MapAssertion 根据属性映射将断言转换为用户
+// 用户ID为 SAMLIdentityPrefix 加IdP的主体标识，角色只来自 RoleMap 中映射的用户组
+func (s *SAMLServiceProvider) MapAssertion(assertion *saml.Assertion) (*User, error) {
+	attributes := make(map[string][]string)
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			values := make([]string, 0, len(attr.Values))
+			for _, value := range attr.Values {
+				values = append(values, value.Value)
+			}
+			attributes[attr.Name] = append(attributes[attr.Name], values...)
+			if attr.FriendlyName != "" && attr.FriendlyName != attr.Name {
+				attributes[attr.FriendlyName] = append(attributes[attr.FriendlyName], values...)
+			}
+		}
+	}
+
+	first := func(name string) string {
+		if values := attributes[name]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	nameID := ""
+	if assertion.Subject != nil && assertion.Subject.NameID != nil {
+		nameID = assertion.Subject.NameID.Value
+	}
+
+	user := &User{
+		ID:       nameID,
+		Username: first(s.mapping.Username),
+		Email:    first(s.mapping.Email),
+		IsActive: true,
+	}
+	if s.mapping.ID != "" {
+		user.ID = first(s.mapping.ID)
+	}
+	if user.ID == "" {
+		return nil, errors.New("saml assertion has no user identifier")
+	}
+	if user.Username == "" {
+		user.Username = user.ID
+	}
+	user.ID = SAMLIdentityPrefix + user.ID
+
+	// IdP的用户组名不可信，不在 RoleMap 中的组不授予角色
+	if s.mapping.RoleMap != nil {
+		user.Roles = mapGroupsToRoles(attributes[s.mapping.Groups], s.mapping.RoleMap)
+	}
+
+	return user, nil
+}
+
+// mapGroupsToRoles 将外部身份源的用户组映射为角色并去重
+// roleMap 为nil时直接使用组名作为角色，否则忽略未配置映射的组
+func mapGroupsToRoles(groups []string, roleMap map[string]string) []string {
+	var roles []string
+	seen := make(map[string]bool)
+	for _, group := range groups {
+		role := group
+		if roleMap != nil {
+			mapped, exists := roleMap[group]
+			if !exists {
+				continue
+			}
+			role = mapped
+		}
+		if !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+)
+
+const testIDPMetadata = `<EntityDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata" entityID="https://idp.example.com/metadata">
+  <IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="https://idp.example.com/sso"/>
+  </IDPSSODescriptor>
+</EntityDescriptor>`
+
+func newTestSAMLServiceProvider(t *testing.T, mapping SAMLAttributeMapping) *SAMLServiceProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+
+	sp, err := NewSAMLServiceProvider(context.Background(), SAMLConfig{
+		RootURL:     "https://app.example.com/",
+		Certificate: cert,
+		PrivateKey:  key,
+		IDPMetadata: []byte(testIDPMetadata),
+		Mapping:     mapping,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create service provider: %v", err)
+	}
+	return sp
+}
+
+func TestSAMLServiceProviderMetadataAndAuthnRequest(t *testing.T) {
+	sp := newTestSAMLServiceProvider(t, SAMLAttributeMapping{})
+
+	if sp.EntityID() != "https://app.example.com/saml/metadata" {
+		t.Errorf("Unexpected entity ID: %s", sp.EntityID())
+	}
+
+	metadata, err := sp.Metadata()
+	if err != nil {
+		t.Fatalf("Failed to build metadata: %v", err)
+	}
+	if !strings.Contains(string(metadata), "https://app.example.com/saml/acs") {
+		t.Error("Metadata should contain the ACS URL")
+	}
+
+	redirectURL, requestID, err := sp.MakeAuthnRequest("/dashboard")
+	if err != nil {
+		t.Fatalf("Failed to make authn request: %v", err)
+	}
+	if requestID == "" {
+		t.Error("Expected a request ID")
+	}
+	target, err := url.Parse(redirectURL)
+	if err != nil {
+		t.Fatalf("Invalid redirect URL: %v", err)
+	}
+	if target.Host != "idp.example.com" || target.Query().Get("SAMLRequest") == "" {
+		t.Errorf("Unexpected redirect URL: %s", redirectURL)
+	}
+	if target.Query().Get("RelayState") != "/dashboard" {
+		t.Errorf("Expected relay state to be preserved, got %q", target.Query().Get("RelayState"))
+	}
+}
+
+func TestSAMLServiceProviderMapAssertion(t *testing.T) {
+	sp := newTestSAMLServiceProvider(t, SAMLAttributeMapping{
+		RoleMap: map[string]string{"cn=admins": "admin", "cn=staff": "user"},
+	})
+
+	assertion := &saml.Assertion{
+		Subject: &saml.Subject{NameID: &saml.NameID{Value: "alice@corp"}},
+		AttributeStatements: []saml.AttributeStatement{{
+			Attributes: []saml.Attribute{
+				{Name: "urn:oid:0.9.2342.19200300.100.1.1", FriendlyName: "uid", Values: []saml.AttributeValue{{Value: "alice"}}},
+				{Name: "mail", Values: []saml.AttributeValue{{Value: "alice@example.com"}}},
+				{Name: "memberOf", Values: []saml.AttributeValue{{Value: "cn=admins"}, {Value: "cn=staff"}, {Value: "cn=other"}}},
+			},
+		}},
+	}
+
+	user, err := sp.MapAssertion(assertion)
+	if err != nil {
+		t.Fatalf("Failed to map assertion: %v", err)
+	}
+	if user.ID != SAMLIdentityPrefix+"alice@corp" || user.Username != "alice" || user.Email != "alice@example.com" {
+		t.Errorf("Unexpected user: %+v", user)
+	}
+	if len(user.Roles) != 2 || user.Roles[0] != "admin" || user.Roles[1] != "user" {
+		t.Errorf("Unexpected roles: %v", user.Roles)
+	}
+
+	// 未配置 RoleMap 时不把IdP的组名当作角色
+	unmapped, err := newTestSAMLServiceProvider(t, SAMLAttributeMapping{}).MapAssertion(assertion)
+	if err != nil {
+		t.Fatalf("Failed to map assertion: %v", err)
+	}
+	if len(unmapped.Roles) != 0 {
+		t.Errorf("Expected no roles without a role map, got %v", unmapped.Roles)
+	}
+
+	if _, err := sp.MapAssertion(&saml.Assertion{}); err == nil {
+		t.Error("Expected error for assertion without identifier")
+	}
+}
+
+func TestSAMLServiceProviderRejectsInvalidResponse(t *testing.T) {
+	sp := newTestSAMLServiceProvider(t, SAMLAttributeMapping{})
+
+	req := httptest.NewRequest(http.MethodPost, "https://app.example.com/saml/acs", strings.NewReader("SAMLResponse=bm90LXNhbWw%3D"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if _, err := sp.ParseResponse(req, []string{"id-1"}); err == nil {
+		t.Error("Expected error for malformed SAML response")
+	}
+}
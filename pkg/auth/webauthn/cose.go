@@ -0,0 +1,119 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// 支持的COSE签名算法
+const (
+	AlgES256 = -7   // ECDSA P-256 + SHA-256
+	AlgEdDSA = -8   // Ed25519
+	AlgRS256 = -257 // RSASSA-PKCS1-v1_5 + SHA-256
+)
+
+// COSE密钥类型和参数标签
+const (
+	coseKeyType  = 1
+	coseKeyAlg   = 3
+	coseKeyCrv   = -1
+	coseKeyX     = -2
+	coseKeyY     = -3
+	coseKeyRSAN  = -1
+	coseKeyRSAE  = -2
+	coseKtyOKP   = 1
+	coseKtyEC2   = 2
+	coseKtyRSA   = 3
+	coseCrvP256  = 1
+	coseCrvEd255 = 6
+)
+
+// ErrUnsupportedAlgorithm 凭证公钥使用了不支持的算法
+var ErrUnsupportedAlgorithm = errors.New("unsupported credential algorithm")
+
+// supportedAlgorithms 注册时声明的算法，按优先级排列
+var supportedAlgorithms = []int{AlgES256, AlgEdDSA, AlgRS256}
+
+// publicKey 解析后的凭证公钥
+type publicKey struct {
+	alg int
+	key crypto.PublicKey
+}
+
+// parsePublicKey 解析COSE编码的凭证公钥
+func parsePublicKey(data []byte) (*publicKey, error) {
+	value, rest, err := decodeCBOR(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cose key: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("invalid cose key: trailing data")
+	}
+	key, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("invalid cose key: not a map")
+	}
+	alg, _ := key[int64(coseKeyAlg)].(int64)
+	kty, _ := key[int64(coseKeyType)].(int64)
+
+	switch {
+	case alg == AlgES256 && kty == coseKtyEC2:
+		crv, _ := key[int64(coseKeyCrv)].(int64)
+		x, _ := key[int64(coseKeyX)].([]byte)
+		y, _ := key[int64(coseKeyY)].([]byte)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid cose key: bad P-256 parameters")
+		}
+		// 校验点在曲线上
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, fmt.Errorf("invalid cose key: %w", err)
+		}
+		return &publicKey{alg: int(alg), key: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}}, nil
+	case alg == AlgEdDSA && kty == coseKtyOKP:
+		crv, _ := key[int64(coseKeyCrv)].(int64)
+		x, _ := key[int64(coseKeyX)].([]byte)
+		if crv != coseCrvEd255 || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid cose key: bad Ed25519 parameters")
+		}
+		return &publicKey{alg: int(alg), key: ed25519.PublicKey(x)}, nil
+	case alg == AlgRS256 && kty == coseKtyRSA:
+		n, _ := key[int64(coseKeyRSAN)].([]byte)
+		e, _ := key[int64(coseKeyRSAE)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid cose key: bad RSA parameters")
+		}
+		exponent := new(big.Int).SetBytes(e)
+		return &publicKey{alg: int(alg), key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}}, nil
+	default:
+		return nil, fmt.Errorf("%w: alg %d, kty %d", ErrUnsupportedAlgorithm, alg, kty)
+	}
+}
+
+// verify 校验签名
+func (k *publicKey) verify(data, signature []byte) bool {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, data, signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return false
+	}
+}
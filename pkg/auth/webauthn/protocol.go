@@ -0,0 +1,270 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Base64URL 以无填充的 base64url 编码序列化的字节串，与浏览器 PublicKeyCredential.toJSON() 的格式一致
+type Base64URL []byte
+
+// String 编码为 base64url 字符串
+func (b Base64URL) String() string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// MarshalJSON 实现 json.Marshaler 接口
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.String())
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler 接口，同时接受带填充的编码
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	decoded, err := DecodeBase64URL(value)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// DecodeBase64URL 解码 base64url 字符串，同时接受带填充的编码
+func DecodeBase64URL(value string) ([]byte, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64url value: %w", err)
+	}
+	return decoded, nil
+}
+
+// 客户端数据类型
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+)
+
+// 用户验证要求
+const (
+	UserVerificationRequired    = "required"
+	UserVerificationPreferred   = "preferred"
+	UserVerificationDiscouraged = "discouraged"
+)
+
+// RelyingParty 依赖方信息
+type RelyingParty struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+// UserEntity 注册选项中的用户信息
+type UserEntity struct {
+	ID          Base64URL `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
+}
+
+// CredentialParameter 可接受的凭证算法
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// CredentialDescriptor 凭证描述，用于排除已注册的凭证或限定可用凭证
+type CredentialDescriptor struct {
+	Type       string    `json:"type"`
+	ID         Base64URL `json:"id"`
+	Transports []string  `json:"transports,omitempty"`
+}
+
+// AuthenticatorSelection 认证器选择条件
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey,omitempty"`
+	UserVerification string `json:"userVerification,omitempty"`
+}
+
+// CreationOptions 注册选项，对应 navigator.credentials.create 的 publicKey 参数
+type CreationOptions struct {
+	Challenge              Base64URL              `json:"challenge"`
+	RP                     RelyingParty           `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout,omitempty"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials,omitempty"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions 登录选项，对应 navigator.credentials.get 的 publicKey 参数
+type RequestOptions struct {
+	Challenge        Base64URL              `json:"challenge"`
+	Timeout          int64                  `json:"timeout,omitempty"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification string                 `json:"userVerification,omitempty"`
+}
+
+// RegistrationResponse 浏览器返回的注册结果
+type RegistrationResponse struct {
+	ID       string                      `json:"id"`
+	RawID    Base64URL                   `json:"rawId"`
+	Type     string                      `json:"type"`
+	Response AuthenticatorAttestationRaw `json:"response"`
+}
+
+// AuthenticatorAttestationRaw 注册结果中的认证器响应
+type AuthenticatorAttestationRaw struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON"`
+	AttestationObject Base64URL `json:"attestationObject"`
+	Transports        []string  `json:"transports,omitempty"`
+}
+
+// AssertionResponse 浏览器返回的登录断言
+type AssertionResponse struct {
+	ID       string                    `json:"id"`
+	RawID    Base64URL                 `json:"rawId"`
+	Type     string                    `json:"type"`
+	Response AuthenticatorAssertionRaw `json:"response"`
+}
+
+// AuthenticatorAssertionRaw 登录断言中的认证器响应
+type AuthenticatorAssertionRaw struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON"`
+	AuthenticatorData Base64URL `json:"authenticatorData"`
+	Signature         Base64URL `json:"signature"`
+	UserHandle        Base64URL `json:"userHandle,omitempty"`
+}
+
+// clientData 客户端数据
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// parseClientData 解析客户端数据并检查类型
+func parseClientData(raw []byte, ceremony string) (*clientData, error) {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("%w: invalid client data: %v", ErrVerification, err)
+	}
+	if data.Type != ceremony {
+		return nil, fmt.Errorf("%w: unexpected client data type %q", ErrVerification, data.Type)
+	}
+	if data.Challenge == "" {
+		return nil, fmt.Errorf("%w: missing challenge", ErrVerification)
+	}
+	return &data, nil
+}
+
+// 认证器数据标志位
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+	flagExtensionData    = 0x80
+)
+
+// authenticatorData 认证器数据
+type authenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte // COSE编码的公钥
+}
+
+// userPresent 用户是否在场
+func (d *authenticatorData) userPresent() bool {
+	return d.Flags&flagUserPresent != 0
+}
+
+// userVerified 用户是否已验证（PIN、生物识别）
+func (d *authenticatorData) userVerified() bool {
+	return d.Flags&flagUserVerified != 0
+}
+
+// parseAuthenticatorData 解析认证器数据，注册时包含凭证ID和公钥
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, fmt.Errorf("%w: authenticator data too short", ErrVerification)
+	}
+	data := &authenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	rest := raw[37:]
+
+	if data.Flags&flagAttestedCredData != 0 {
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("%w: attested credential data too short", ErrVerification)
+		}
+		data.AAGUID = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength == 0 || idLength > 1023 || len(rest) < idLength {
+			return nil, fmt.Errorf("%w: invalid credential id length", ErrVerification)
+		}
+		data.CredentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		// 公钥后可能紧跟扩展数据，按CBOR数据项长度截取
+		_, remaining, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid credential public key: %v", ErrVerification, err)
+		}
+		data.PublicKey = rest[:len(rest)-len(remaining)]
+		rest = remaining
+	}
+	if data.Flags&flagExtensionData != 0 {
+		var err error
+		if _, rest, err = decodeCBOR(rest); err != nil {
+			return nil, fmt.Errorf("%w: invalid extension data: %v", ErrVerification, err)
+		}
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing authenticator data", ErrVerification)
+	}
+	return data, nil
+}
+
+// parseAttestationObject 解析注册结果中的 attestationObject，返回认证器数据
+// 注册选项使用 attestation: none，不校验认证器证明（attStmt），公钥在首次注册时即被信任
+func parseAttestationObject(raw []byte) (*authenticatorData, error) {
+	value, rest, err := decodeCBOR(raw)
+	if err != nil || len(rest) != 0 {
+		return nil, fmt.Errorf("%w: invalid attestation object", ErrVerification)
+	}
+	object, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid attestation object", ErrVerification)
+	}
+	authData, ok := object["authData"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing authenticator data", ErrVerification)
+	}
+	if _, ok := object["fmt"].(string); !ok {
+		return nil, fmt.Errorf("%w: missing attestation format", ErrVerification)
+	}
+	return parseAuthenticatorData(authData)
+}
+
+// containsID 凭证ID是否在描述列表中
+func containsID(ids []Base64URL, id []byte) bool {
+	for _, candidate := range ids {
+		if bytes.Equal(candidate, id) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package webauthn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/cache"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Credential 已注册的凭证（通行密钥）
+type Credential struct {
+	ID         Base64URL  `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	PublicKey  []byte     `json:"-"` // COSE编码的公钥
+	Algorithm  int        `json:"algorithm"`
+	SignCount  uint32     `json:"sign_count"`
+	AAGUID     Base64URL  `json:"aaguid"`
+	Transports []string   `json:"transports,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CredentialStore 凭证存储
+type CredentialStore interface {
+	// SaveCredential 保存凭证，已存在时更新
+	SaveCredential(credential *Credential) error
+	// GetCredential 按凭证ID获取凭证，不存在时返回 ErrCredentialNotFound
+	GetCredential(id []byte) (*Credential, error)
+	// ListCredentials 列出用户的凭证，按注册时间排序
+	ListCredentials(userID string) ([]*Credential, error)
+	// DeleteCredential 删除用户的凭证，不存在时返回 ErrCredentialNotFound
+	DeleteCredential(userID string, id []byte) error
+}
+
+// Challenge 进行中的注册或登录仪式
+type Challenge struct {
+	Challenge        string      `json:"challenge"`
+	Ceremony         string      `json:"ceremony"`
+	UserID           string      `json:"user_id,omitempty"` // 登录时为空表示由用户选择凭证（可发现凭证）
+	AllowCredentials []Base64URL `json:"allow_credentials,omitempty"`
+	UserVerification string      `json:"user_verification"`
+}
+
+// ChallengeStore 挑战存储，挑战只能使用一次
+type ChallengeStore interface {
+	// SaveChallenge 保存挑战，超过 ttl 后失效
+	SaveChallenge(challenge *Challenge, ttl time.Duration) error
+	// ConsumeChallenge 取出并删除挑战，不存在或已过期时返回 ErrChallengeNotFound
+	ConsumeChallenge(challenge string) (*Challenge, error)
+}
+
+// webauthnCredentialRecord 凭证表记录
+type webauthnCredentialRecord struct {
+	ID         string `gorm:"primaryKey;size:768"` // base64url编码的凭证ID，常见认证器不超过64字节
+	UserID     string `gorm:"size:128;index"`
+	Name       string `gorm:"size:255"`
+	PublicKey  []byte
+	Algorithm  int
+	SignCount  uint32
+	AAGUID     []byte
+	Transports string `gorm:"size:255"`
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+// TableName 凭证表名
+func (webauthnCredentialRecord) TableName() string {
+	return "webauthn_credentials"
+}
+
+// GormCredentialStore 基于GORM的凭证存储，数据保存在 webauthn_credentials 表
+type GormCredentialStore struct {
+	db *gorm.DB
+}
+
+// NewGormCredentialStore 创建GORM凭证存储并自动迁移表结构
+func NewGormCredentialStore(db *gorm.DB) (*GormCredentialStore, error) {
+	if err := db.AutoMigrate(&webauthnCredentialRecord{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate webauthn tables: %w", err)
+	}
+	return &GormCredentialStore{db: db}, nil
+}
+
+// SaveCredential 保存凭证
+func (s *GormCredentialStore) SaveCredential(credential *Credential) error {
+	record := webauthnCredentialRecord{
+		ID:         credential.ID.String(),
+		UserID:     credential.UserID,
+		Name:       credential.Name,
+		PublicKey:  credential.PublicKey,
+		Algorithm:  credential.Algorithm,
+		SignCount:  credential.SignCount,
+		AAGUID:     credential.AAGUID,
+		Transports: strings.Join(credential.Transports, ","),
+		CreatedAt:  credential.CreatedAt,
+		LastUsedAt: credential.LastUsedAt,
+	}
+	return s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&record).Error
+}
+
+// GetCredential 获取凭证
+func (s *GormCredentialStore) GetCredential(id []byte) (*Credential, error) {
+	var record webauthnCredentialRecord
+	if err := s.db.First(&record, "id = ?", Base64URL(id).String()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCredentialNotFound
+		}
+		return nil, err
+	}
+	return record.toCredential()
+}
+
+// ListCredentials 列出用户的凭证
+func (s *GormCredentialStore) ListCredentials(userID string) ([]*Credential, error) {
+	var records []webauthnCredentialRecord
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	credentials := make([]*Credential, 0, len(records))
+	for i := range records {
+		credential, err := records[i].toCredential()
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
+}
+
+// DeleteCredential 删除凭证
+func (s *GormCredentialStore) DeleteCredential(userID string, id []byte) error {
+	result := s.db.Delete(&webauthnCredentialRecord{}, "id = ? AND user_id = ?", Base64URL(id).String(), userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCredentialNotFound
+	}
+	return nil
+}
+
+// toCredential 转换为凭证
+func (r *webauthnCredentialRecord) toCredential() (*Credential, error) {
+	id, err := DecodeBase64URL(r.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stored credential id: %w", err)
+	}
+	var transports []string
+	if r.Transports != "" {
+		transports = strings.Split(r.Transports, ",")
+	}
+	return &Credential{
+		ID:         id,
+		UserID:     r.UserID,
+		Name:       r.Name,
+		PublicKey:  r.PublicKey,
+		Algorithm:  r.Algorithm,
+		SignCount:  r.SignCount,
+		AAGUID:     r.AAGUID,
+		Transports: transports,
+		CreatedAt:  r.CreatedAt,
+		LastUsedAt: r.LastUsedAt,
+	}, nil
+}
+
+// RedisChallengeStore 基于Redis的挑战存储，键为 <prefix><challenge>
+type RedisChallengeStore struct {
+	cache  *cache.Manager
+	prefix string
+}
+
+// NewRedisChallengeStore 创建Redis挑战存储，prefix 为空时使用 webauthn:challenge:
+func NewRedisChallengeStore(cacheManager *cache.Manager, prefix string) *RedisChallengeStore {
+	if prefix == "" {
+		prefix = "webauthn:challenge:"
+	}
+	return &RedisChallengeStore{cache: cacheManager, prefix: prefix}
+}
+
+// SaveChallenge 保存挑战
+func (s *RedisChallengeStore) SaveChallenge(challenge *Challenge, ttl time.Duration) error {
+	return s.cache.SetJSON(s.prefix+challenge.Challenge, challenge, ttl)
+}
+
+// ConsumeChallenge 使用 GETDEL 原子取出挑战，并发提交同一挑战时只有一个成功
+func (s *RedisChallengeStore) ConsumeChallenge(challenge string) (*Challenge, error) {
+	value, err := s.cache.GetClient().GetDel(context.Background(), s.cache.Key(s.prefix+challenge)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrChallengeNotFound
+		}
+		return nil, err
+	}
+	var stored Challenge
+	if err := json.Unmarshal(value, &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal challenge: %w", err)
+	}
+	return &stored, nil
+}
@@ -0,0 +1,349 @@
+// Package webauthn 实现WebAuthn（通行密钥）注册和登录仪式
+//
+// 凭证公钥通过 CredentialStore 保存（默认 GormCredentialStore），一次性挑战通过 ChallengeStore 保存
+// （默认 RedisChallengeStore）。注册选项使用 attestation: none，不校验认证器证明，支持 ES256、EdDSA、RS256 算法。
+//
+//	wa, err := webauthn.New(webauthn.Config{RPID: "example.com", RPName: "Example", Origins: []string{"https://example.com"}}, credentials, challenges)
+//	options, err := wa.BeginRegistration(webauthn.User{ID: "42", Name: "alice"})
+//	credential, err := wa.FinishRegistration("42", &response, "MacBook")
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrVerification 浏览器响应校验失败
+	ErrVerification = errors.New("webauthn verification failed")
+	// ErrCredentialNotFound 凭证不存在
+	ErrCredentialNotFound = errors.New("webauthn credential not found")
+	// ErrChallengeNotFound 挑战不存在、已使用或已过期
+	ErrChallengeNotFound = errors.New("webauthn challenge not found or expired")
+	// ErrNoCredentials 用户没有注册凭证，应回退到密码登录
+	ErrNoCredentials = errors.New("user has no webauthn credentials")
+	// ErrCredentialExists 凭证已注册
+	ErrCredentialExists = errors.New("webauthn credential already registered")
+	// ErrSignCount 签名计数器没有增长，凭证可能被克隆
+	ErrSignCount = errors.New("webauthn signature counter did not increase, credential may be cloned")
+)
+
+// defaultTimeout 默认的仪式超时时间，同时作为挑战有效期
+const defaultTimeout = 5 * time.Minute
+
+// Config WebAuthn依赖方配置
+type Config struct {
+	RPID             string        // 依赖方ID，通常为站点的注册域名，如 example.com
+	RPName           string        // 依赖方名称，展示在认证器的提示中
+	Origins          []string      // 允许的来源，如 https://example.com，不能为空
+	Timeout          time.Duration // 仪式超时时间，默认5分钟
+	UserVerification string        // 用户验证要求：required、preferred（默认）、discouraged
+}
+
+// User 注册凭证的用户
+type User struct {
+	ID          string // 用户ID，作为凭证的 user handle，最长64字节
+	Name        string // 用户名
+	DisplayName string // 展示名称，为空时使用用户名
+}
+
+// WebAuthn WebAuthn依赖方
+type WebAuthn struct {
+	config      Config
+	rpIDHash    [32]byte
+	credentials CredentialStore
+	challenges  ChallengeStore
+}
+
+// New 创建WebAuthn依赖方
+func New(cfg Config, credentials CredentialStore, challenges ChallengeStore) (*WebAuthn, error) {
+	if cfg.RPID == "" {
+		return nil, errors.New("webauthn rp id is required")
+	}
+	if len(cfg.Origins) == 0 {
+		return nil, errors.New("webauthn origins are required")
+	}
+	if credentials == nil || challenges == nil {
+		return nil, errors.New("webauthn credential store and challenge store are required")
+	}
+	if cfg.RPName == "" {
+		cfg.RPName = cfg.RPID
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	switch cfg.UserVerification {
+	case "":
+		cfg.UserVerification = UserVerificationPreferred
+	case UserVerificationRequired, UserVerificationPreferred, UserVerificationDiscouraged:
+	default:
+		return nil, fmt.Errorf("invalid webauthn user verification: %q", cfg.UserVerification)
+	}
+
+	return &WebAuthn{
+		config:      cfg,
+		rpIDHash:    sha256.Sum256([]byte(cfg.RPID)),
+		credentials: credentials,
+		challenges:  challenges,
+	}, nil
+}
+
+// Credentials 获取凭证存储
+func (w *WebAuthn) Credentials() CredentialStore {
+	return w.credentials
+}
+
+// BeginRegistration 开始注册，返回传给 navigator.credentials.create 的选项
+// 用户已注册的凭证放入 excludeCredentials，避免同一认证器重复注册
+func (w *WebAuthn) BeginRegistration(user User) (*CreationOptions, error) {
+	if user.ID == "" || len(user.ID) > 64 {
+		return nil, errors.New("webauthn user id must be 1 to 64 bytes")
+	}
+	existing, err := w.credentials.ListCredentials(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credentials: %w", err)
+	}
+	challenge, err := w.newChallenge(&Challenge{Ceremony: ceremonyCreate, UserID: user.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := user.DisplayName
+	if displayName == "" {
+		displayName = user.Name
+	}
+	params := make([]CredentialParameter, 0, len(supportedAlgorithms))
+	for _, alg := range supportedAlgorithms {
+		params = append(params, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+	return &CreationOptions{
+		Challenge:          challenge,
+		RP:                 RelyingParty{ID: w.config.RPID, Name: w.config.RPName},
+		User:               UserEntity{ID: Base64URL(user.ID), Name: user.Name, DisplayName: displayName},
+		PubKeyCredParams:   params,
+		Timeout:            w.config.Timeout.Milliseconds(),
+		ExcludeCredentials: descriptors(existing),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: w.config.UserVerification,
+		},
+		Attestation: "none",
+	}, nil
+}
+
+// FinishRegistration 校验注册结果并保存凭证，userID 为当前登录用户，须与开始注册时一致
+func (w *WebAuthn) FinishRegistration(userID string, response *RegistrationResponse, name string) (*Credential, error) {
+	client, err := parseClientData(response.Response.ClientDataJSON, ceremonyCreate)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := w.consumeChallenge(client, ceremonyCreate)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UserID != userID {
+		return nil, fmt.Errorf("%w: challenge was issued to another user", ErrVerification)
+	}
+
+	authData, err := parseAttestationObject(response.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.verifyAuthenticatorData(authData, challenge.UserVerification); err != nil {
+		return nil, err
+	}
+	if authData.CredentialID == nil {
+		return nil, fmt.Errorf("%w: missing attested credential data", ErrVerification)
+	}
+	if !bytes.Equal(authData.CredentialID, response.RawID) {
+		return nil, fmt.Errorf("%w: credential id mismatch", ErrVerification)
+	}
+	key, err := parsePublicKey(authData.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVerification, err)
+	}
+
+	if _, err := w.credentials.GetCredential(authData.CredentialID); err == nil {
+		return nil, ErrCredentialExists
+	} else if !errors.Is(err, ErrCredentialNotFound) {
+		return nil, fmt.Errorf("failed to check credential: %w", err)
+	}
+
+	credential := &Credential{
+		ID:         append(Base64URL(nil), authData.CredentialID...),
+		UserID:     userID,
+		Name:       name,
+		PublicKey:  append([]byte(nil), authData.PublicKey...),
+		Algorithm:  key.alg,
+		SignCount:  authData.SignCount,
+		AAGUID:     append(Base64URL(nil), authData.AAGUID...),
+		Transports: response.Response.Transports,
+		CreatedAt:  time.Now(),
+	}
+	if err := w.credentials.SaveCredential(credential); err != nil {
+		return nil, fmt.Errorf("failed to save credential: %w", err)
+	}
+	return credential, nil
+}
+
+// BeginLogin 开始登录，返回传给 navigator.credentials.get 的选项
+// userID 为空时不限定凭证，由认证器列出可发现凭证（无用户名登录）；用户没有凭证时返回 ErrNoCredentials
+func (w *WebAuthn) BeginLogin(userID string) (*RequestOptions, error) {
+	var allowed []*Credential
+	if userID != "" {
+		var err error
+		if allowed, err = w.credentials.ListCredentials(userID); err != nil {
+			return nil, fmt.Errorf("failed to list credentials: %w", err)
+		}
+		if len(allowed) == 0 {
+			return nil, ErrNoCredentials
+		}
+	}
+
+	state := &Challenge{Ceremony: ceremonyGet, UserID: userID}
+	for _, credential := range allowed {
+		state.AllowCredentials = append(state.AllowCredentials, credential.ID)
+	}
+	challenge, err := w.newChallenge(state)
+	if err != nil {
+		return nil, err
+	}
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          w.config.Timeout.Milliseconds(),
+		RPID:             w.config.RPID,
+		AllowCredentials: descriptors(allowed),
+		UserVerification: w.config.UserVerification,
+	}, nil
+}
+
+// FinishLogin 校验登录断言，成功时更新签名计数器和最后使用时间并返回凭证，凭证的 UserID 即登录用户
+func (w *WebAuthn) FinishLogin(response *AssertionResponse) (*Credential, error) {
+	client, err := parseClientData(response.Response.ClientDataJSON, ceremonyGet)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := w.consumeChallenge(client, ceremonyGet)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := w.credentials.GetCredential(response.RawID)
+	if err != nil {
+		if errors.Is(err, ErrCredentialNotFound) {
+			return nil, fmt.Errorf("%w: %v", ErrVerification, err)
+		}
+		return nil, fmt.Errorf("failed to get credential: %w", err)
+	}
+	if challenge.UserID != "" && credential.UserID != challenge.UserID {
+		return nil, fmt.Errorf("%w: credential belongs to another user", ErrVerification)
+	}
+	if len(challenge.AllowCredentials) > 0 && !containsID(challenge.AllowCredentials, credential.ID) {
+		return nil, fmt.Errorf("%w: credential was not allowed", ErrVerification)
+	}
+	if len(response.Response.UserHandle) > 0 && string(response.Response.UserHandle) != credential.UserID {
+		return nil, fmt.Errorf("%w: user handle mismatch", ErrVerification)
+	}
+
+	authData, err := parseAuthenticatorData(response.Response.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.verifyAuthenticatorData(authData, challenge.UserVerification); err != nil {
+		return nil, err
+	}
+
+	key, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stored credential key: %w", err)
+	}
+	clientDataHash := sha256.Sum256(response.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), response.Response.AuthenticatorData...), clientDataHash[:]...)
+	if !key.verify(signed, response.Response.Signature) {
+		return nil, fmt.Errorf("%w: invalid signature", ErrVerification)
+	}
+
+	// 不支持计数器的认证器始终返回0
+	if authData.SignCount != 0 || credential.SignCount != 0 {
+		if authData.SignCount <= credential.SignCount {
+			return nil, ErrSignCount
+		}
+	}
+	now := time.Now()
+	credential.SignCount = authData.SignCount
+	credential.LastUsedAt = &now
+	if err := w.credentials.SaveCredential(credential); err != nil {
+		return nil, fmt.Errorf("failed to update credential: %w", err)
+	}
+	return credential, nil
+}
+
+// newChallenge 生成随机挑战并保存仪式状态
+func (w *WebAuthn) newChallenge(state *Challenge) (Base64URL, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	state.Challenge = Base64URL(challenge).String()
+	state.UserVerification = w.config.UserVerification
+	if err := w.challenges.SaveChallenge(state, w.config.Timeout); err != nil {
+		return nil, fmt.Errorf("failed to save challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// consumeChallenge 取出客户端数据中的挑战并校验仪式类型和来源
+func (w *WebAuthn) consumeChallenge(client *clientData, ceremony string) (*Challenge, error) {
+	challenge, err := w.challenges.ConsumeChallenge(client.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.Ceremony != ceremony || subtle.ConstantTimeCompare([]byte(challenge.Challenge), []byte(client.Challenge)) != 1 {
+		return nil, fmt.Errorf("%w: challenge mismatch", ErrVerification)
+	}
+	if !w.allowedOrigin(client.Origin) {
+		return nil, fmt.Errorf("%w: origin %q is not allowed", ErrVerification, client.Origin)
+	}
+	return challenge, nil
+}
+
+// verifyAuthenticatorData 校验依赖方ID哈希、用户在场和用户验证标志
+func (w *WebAuthn) verifyAuthenticatorData(authData *authenticatorData, userVerification string) error {
+	if subtle.ConstantTimeCompare(authData.RPIDHash, w.rpIDHash[:]) != 1 {
+		return fmt.Errorf("%w: rp id hash mismatch", ErrVerification)
+	}
+	if !authData.userPresent() {
+		return fmt.Errorf("%w: user not present", ErrVerification)
+	}
+	if userVerification == UserVerificationRequired && !authData.userVerified() {
+		return fmt.Errorf("%w: user not verified", ErrVerification)
+	}
+	return nil
+}
+
+// allowedOrigin 来源是否在允许列表中
+func (w *WebAuthn) allowedOrigin(origin string) bool {
+	for _, allowed := range w.config.Origins {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// descriptors 将凭证转换为凭证描述
+func descriptors(credentials []*Credential) []CredentialDescriptor {
+	if len(credentials) == 0 {
+		return nil
+	}
+	result := make([]CredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		result = append(result, CredentialDescriptor{Type: "public-key", ID: credential.ID, Transports: credential.Transports})
+	}
+	return result
+}
@@ -0,0 +1,388 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hwh/hwhkit-go/pkg/cache"
+	"github.com/hwh/hwhkit-go/pkg/config"
+)
+
+// memoryCredentialStore 测试用的内存凭证存储
+type memoryCredentialStore struct {
+	mu          sync.Mutex
+	credentials map[string]Credential
+}
+
+func newMemoryCredentialStore() *memoryCredentialStore {
+	return &memoryCredentialStore{credentials: make(map[string]Credential)}
+}
+
+func (s *memoryCredentialStore) SaveCredential(credential *Credential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.credentials[credential.ID.String()] = *credential
+	return nil
+}
+
+func (s *memoryCredentialStore) GetCredential(id []byte) (*Credential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	credential, ok := s.credentials[Base64URL(id).String()]
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+	return &credential, nil
+}
+
+func (s *memoryCredentialStore) ListCredentials(userID string) ([]*Credential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []*Credential
+	for _, credential := range s.credentials {
+		if credential.UserID == userID {
+			credential := credential
+			result = append(result, &credential)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result, nil
+}
+
+func (s *memoryCredentialStore) DeleteCredential(userID string, id []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := Base64URL(id).String()
+	if credential, ok := s.credentials[key]; !ok || credential.UserID != userID {
+		return ErrCredentialNotFound
+	}
+	delete(s.credentials, key)
+	return nil
+}
+
+// encodeCBOR 测试用的CBOR编码，只支持认证器响应用到的类型
+func encodeCBOR(value interface{}) []byte {
+	header := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n < 256:
+			return []byte{major<<5 | 24, byte(n)}
+		default:
+			buf := []byte{major<<5 | 25, 0, 0}
+			binary.BigEndian.PutUint16(buf[1:], uint16(n))
+			return buf
+		}
+	}
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			return header(1, uint64(-1-v))
+		}
+		return header(0, uint64(v))
+	case []byte:
+		return append(header(2, uint64(len(v))), v...)
+	case string:
+		return append(header(3, uint64(len(v))), v...)
+	case map[interface{}]interface{}:
+		out := header(5, uint64(len(v)))
+		for key, item := range v {
+			out = append(out, encodeCBOR(key)...)
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	default:
+		panic("unsupported cbor value")
+	}
+}
+
+// testAuthenticator 使用ES256密钥的软件认证器
+type testAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+	rpID         string
+	origin       string
+}
+
+func newTestAuthenticator(t *testing.T, rpID, origin string) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &testAuthenticator{key: key, credentialID: id, rpID: rpID, origin: origin}
+}
+
+// clientDataJSON 生成客户端数据
+func (a *testAuthenticator) clientDataJSON(ceremony string, challenge Base64URL) []byte {
+	data, _ := json.Marshal(clientData{Type: ceremony, Challenge: challenge.String(), Origin: a.origin})
+	return data
+}
+
+// authData 生成认证器数据，attested 为 true 时包含凭证ID和公钥
+func (a *testAuthenticator) authData(flags byte, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append([]byte(nil), rpIDHash[:]...)
+	counter := make([]byte, 4)
+	binary.BigEndian.PutUint32(counter, a.signCount)
+	if attested {
+		flags |= flagAttestedCredData
+	}
+	data = append(append(data, flags), counter...)
+	if attested {
+		length := make([]byte, 2)
+		binary.BigEndian.PutUint16(length, uint16(len(a.credentialID)))
+		data = append(data, make([]byte, 16)...)
+		data = append(append(data, length...), a.credentialID...)
+		data = append(data, encodeCBOR(map[interface{}]interface{}{
+			coseKeyType: coseKtyEC2,
+			coseKeyAlg:  AlgES256,
+			coseKeyCrv:  coseCrvP256,
+			coseKeyX:    a.key.X.FillBytes(make([]byte, 32)),
+			coseKeyY:    a.key.Y.FillBytes(make([]byte, 32)),
+		})...)
+	}
+	return data
+}
+
+// register 生成注册结果
+func (a *testAuthenticator) register(options *CreationOptions) *RegistrationResponse {
+	attestation := encodeCBOR(map[interface{}]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[interface{}]interface{}{},
+		"authData": a.authData(flagUserPresent|flagUserVerified, true),
+	})
+	return &RegistrationResponse{
+		ID:    Base64URL(a.credentialID).String(),
+		RawID: a.credentialID,
+		Type:  "public-key",
+		Response: AuthenticatorAttestationRaw{
+			ClientDataJSON:    a.clientDataJSON(ceremonyCreate, options.Challenge),
+			AttestationObject: attestation,
+			Transports:        []string{"internal"},
+		},
+	}
+}
+
+// login 生成登录断言，签名计数器加1
+func (a *testAuthenticator) login(t *testing.T, options *RequestOptions, userHandle string) *AssertionResponse {
+	a.signCount++
+	authData := a.authData(flagUserPresent|flagUserVerified, false)
+	clientDataJSON := a.clientDataJSON(ceremonyGet, options.Challenge)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign assertion: %v", err)
+	}
+	return &AssertionResponse{
+		ID:    Base64URL(a.credentialID).String(),
+		RawID: a.credentialID,
+		Type:  "public-key",
+		Response: AuthenticatorAssertionRaw{
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authData,
+			Signature:         signature,
+			UserHandle:        Base64URL(userHandle),
+		},
+	}
+}
+
+func newTestWebAuthn(t *testing.T) (*WebAuthn, *memoryCredentialStore) {
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	if err != nil {
+		t.Fatalf("Failed to create cache manager: %v", err)
+	}
+	t.Cleanup(func() { cacheManager.Close() })
+
+	store := newMemoryCredentialStore()
+	wa, err := New(Config{
+		RPID:             "example.com",
+		RPName:           "Example",
+		Origins:          []string{"https://example.com"},
+		UserVerification: UserVerificationRequired,
+	}, store, NewRedisChallengeStore(cacheManager, ""))
+	if err != nil {
+		t.Fatalf("Failed to create webauthn: %v", err)
+	}
+	return wa, store
+}
+
+func TestRegistrationAndLogin(t *testing.T) {
+	wa, store := newTestWebAuthn(t)
+	authenticator := newTestAuthenticator(t, "example.com", "https://example.com")
+
+	if _, err := wa.BeginLogin("42"); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected ErrNoCredentials before registration, got %v", err)
+	}
+
+	options, err := wa.BeginRegistration(User{ID: "42", Name: "alice"})
+	if err != nil {
+		t.Fatalf("Failed to begin registration: %v", err)
+	}
+	if options.RP.ID != "example.com" || options.User.DisplayName != "alice" || len(options.PubKeyCredParams) != 3 {
+		t.Errorf("Unexpected creation options: %+v", options)
+	}
+	credential, err := wa.FinishRegistration("42", authenticator.register(options), "laptop")
+	if err != nil {
+		t.Fatalf("Failed to finish registration: %v", err)
+	}
+	if credential.UserID != "42" || credential.Algorithm != AlgES256 || credential.Name != "laptop" {
+		t.Errorf("Unexpected credential: %+v", credential)
+	}
+
+	// 已注册的凭证会被排除，重复注册被拒绝
+	options, _ = wa.BeginRegistration(User{ID: "42", Name: "alice"})
+	if len(options.ExcludeCredentials) != 1 {
+		t.Errorf("Expected registered credential to be excluded, got %v", options.ExcludeCredentials)
+	}
+	if _, err := wa.FinishRegistration("42", authenticator.register(options), "again"); !errors.Is(err, ErrCredentialExists) {
+		t.Errorf("Expected ErrCredentialExists, got %v", err)
+	}
+
+	// 按用户登录
+	requestOptions, err := wa.BeginLogin("42")
+	if err != nil {
+		t.Fatalf("Failed to begin login: %v", err)
+	}
+	if len(requestOptions.AllowCredentials) != 1 || requestOptions.RPID != "example.com" {
+		t.Errorf("Unexpected request options: %+v", requestOptions)
+	}
+	assertion := authenticator.login(t, requestOptions, "42")
+	loggedIn, err := wa.FinishLogin(assertion)
+	if err != nil {
+		t.Fatalf("Failed to finish login: %v", err)
+	}
+	if loggedIn.UserID != "42" || loggedIn.SignCount != 1 || loggedIn.LastUsedAt == nil {
+		t.Errorf("Unexpected login credential: %+v", loggedIn)
+	}
+
+	// 挑战只能使用一次
+	if _, err := wa.FinishLogin(assertion); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("Expected replayed assertion to fail, got %v", err)
+	}
+
+	// 无用户名登录（可发现凭证）
+	requestOptions, _ = wa.BeginLogin("")
+	if len(requestOptions.AllowCredentials) != 0 {
+		t.Errorf("Expected no allowed credentials for discoverable login, got %v", requestOptions.AllowCredentials)
+	}
+	if loggedIn, err = wa.FinishLogin(authenticator.login(t, requestOptions, "42")); err != nil || loggedIn.SignCount != 2 {
+		t.Errorf("Expected discoverable login to succeed, got %v (%v)", loggedIn, err)
+	}
+
+	// 签名计数器回退视为克隆
+	requestOptions, _ = wa.BeginLogin("42")
+	authenticator.signCount = 0
+	if _, err := wa.FinishLogin(authenticator.login(t, requestOptions, "42")); !errors.Is(err, ErrSignCount) {
+		t.Errorf("Expected ErrSignCount, got %v", err)
+	}
+	stored, _ := store.GetCredential(authenticator.credentialID)
+	if stored.SignCount != 2 {
+		t.Errorf("Expected sign count to stay at 2, got %d", stored.SignCount)
+	}
+}
+
+func TestLoginVerificationFailures(t *testing.T) {
+	wa, _ := newTestWebAuthn(t)
+	authenticator := newTestAuthenticator(t, "example.com", "https://example.com")
+	options, _ := wa.BeginRegistration(User{ID: "42", Name: "alice"})
+	if _, err := wa.FinishRegistration("42", authenticator.register(options), ""); err != nil {
+		t.Fatalf("Failed to finish registration: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*AssertionResponse)
+	}{
+		{"bad signature", func(r *AssertionResponse) { r.Response.Signature[len(r.Response.Signature)-1] ^= 0xff }},
+		{"wrong user handle", func(r *AssertionResponse) { r.Response.UserHandle = Base64URL("43") }},
+		{"user not verified", func(r *AssertionResponse) { r.Response.AuthenticatorData[32] &^= flagUserVerified }},
+		{"unknown credential", func(r *AssertionResponse) { r.RawID = Base64URL("unknown") }},
+	}
+	for _, tt := range tests {
+		requestOptions, _ := wa.BeginLogin("42")
+		assertion := authenticator.login(t, requestOptions, "42")
+		tt.modify(assertion)
+		if _, err := wa.FinishLogin(assertion); !errors.Is(err, ErrVerification) {
+			t.Errorf("%s: expected ErrVerification, got %v", tt.name, err)
+		}
+	}
+
+	// 来源和依赖方ID不匹配
+	phishing := newTestAuthenticator(t, "example.com", "https://evil.example")
+	phishing.key, phishing.credentialID = authenticator.key, authenticator.credentialID
+	requestOptions, _ := wa.BeginLogin("42")
+	if _, err := wa.FinishLogin(phishing.login(t, requestOptions, "42")); !errors.Is(err, ErrVerification) {
+		t.Errorf("Expected origin mismatch to fail, got %v", err)
+	}
+	otherRP := newTestAuthenticator(t, "evil.example", "https://example.com")
+	options, _ = wa.BeginRegistration(User{ID: "42", Name: "alice"})
+	if _, err := wa.FinishRegistration("42", otherRP.register(options), ""); !errors.Is(err, ErrVerification) {
+		t.Errorf("Expected rp id mismatch to fail, got %v", err)
+	}
+
+	// 注册挑战属于其他用户
+	options, _ = wa.BeginRegistration(User{ID: "42", Name: "alice"})
+	if _, err := wa.FinishRegistration("43", newTestAuthenticator(t, "example.com", "https://example.com").register(options), ""); !errors.Is(err, ErrVerification) {
+		t.Errorf("Expected challenge user mismatch to fail, got %v", err)
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	value, rest, err := decodeCBOR(append(encodeCBOR(map[interface{}]interface{}{1: -7, "key": []byte{1, 2}, -2: "text"}), 0xff))
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	decoded := value.(map[interface{}]interface{})
+	if decoded[int64(1)] != int64(-7) || decoded[int64(-2)] != "text" || !bytes.Equal(decoded["key"].([]byte), []byte{1, 2}) {
+		t.Errorf("Unexpected decoded value: %v", decoded)
+	}
+	if !bytes.Equal(rest, []byte{0xff}) {
+		t.Errorf("Expected trailing byte to remain, got %v", rest)
+	}
+
+	for _, data := range [][]byte{{}, {0x42, 0x01}, {0x5f}, {0xa1, 0x41, 0x00, 0x01}} {
+		if _, _, err := decodeCBOR(data); err == nil {
+			t.Errorf("Expected error decoding %x", data)
+		}
+	}
+}
+
+func TestChallengeExpiry(t *testing.T) {
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	if err != nil {
+		t.Fatalf("Failed to create cache manager: %v", err)
+	}
+	defer cacheManager.Close()
+
+	store := NewRedisChallengeStore(cacheManager, "")
+	if err := store.SaveChallenge(&Challenge{Challenge: "abc", Ceremony: ceremonyGet}, time.Minute); err != nil {
+		t.Fatalf("Failed to save challenge: %v", err)
+	}
+	if !mr.Exists("app:webauthn:challenge:abc") {
+		t.Error("Expected challenge key with cache prefix")
+	}
+	mr.FastForward(2 * time.Minute)
+	if _, err := store.ConsumeChallenge("abc"); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("Expected expired challenge to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/metrics"
+)
+
+// ErrKeyNotFound 键不存在
+var ErrKeyNotFound = errors.New("cache key not found")
+
+// ErrPrefixedFlushAll 设置了键前缀的管理器不能清空所有数据库
+var ErrPrefixedFlushAll = errors.New("cache: FlushAll is not allowed with a key prefix")
+
+const (
+	// inspectLimit 查看集合类型键时最多返回的元素数
+	inspectLimit = 100
+	// scanBatchSize 每次 SCAN 和批量删除的键数量
+	scanBatchSize = 500
+	// sampleSize 按模式删除时返回的键样本数量
+	sampleSize = 100
+)
+
+// KeyInfo 缓存键信息
+type KeyInfo struct {
+	Key        string      `json:"key"`
+	Type       string      `json:"type"`
+	TTLSeconds int64       `json:"ttl_seconds"` // -1 表示永不过期
+	Size       int64       `json:"size"`        // 字符串为字节数，集合类型为元素数
+	Value      interface{} `json:"value"`
+	Truncated  bool        `json:"truncated"` // 集合元素超过 inspectLimit 时只返回部分
+}
+
+// PatternDeleteResult 按模式删除的结果
+type PatternDeleteResult struct {
+	Pattern string   `json:"pattern"`
+	DryRun  bool     `json:"dry_run"`
+	Matched int64    `json:"matched"`
+	Deleted int64    `json:"deleted"`
+	Sample  []string `json:"sample"` // 匹配键样本（不含前缀），最多 sampleSize 个
+}
+
+// Inspect 查看键的类型、剩余过期时间和值，集合类型最多返回 inspectLimit 个元素
+func (m *Manager) Inspect(key string) (*KeyInfo, error) {
+	fullKey := m.Key(key)
+	keyType, err := m.client.Type(m.ctx, fullKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key type: %w", err)
+	}
+	if keyType == "none" {
+		return nil, ErrKeyNotFound
+	}
+
+	info := &KeyInfo{Key: key, Type: keyType, TTLSeconds: -1}
+	ttl, err := m.client.TTL(m.ctx, fullKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key ttl: %w", err)
+	}
+	if ttl > 0 {
+		info.TTLSeconds = int64(ttl / time.Second)
+	}
+
+	switch keyType {
+	case "string":
+		value, err := m.client.Get(m.ctx, fullKey).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get value: %w", err)
+		}
+		info.Size = int64(len(value))
+		info.Value = value
+	case "list":
+		info.Size, err = m.client.LLen(m.ctx, fullKey).Result()
+		if err == nil {
+			info.Value, err = m.client.LRange(m.ctx, fullKey, 0, inspectLimit-1).Result()
+		}
+	case "set":
+		info.Size, err = m.client.SCard(m.ctx, fullKey).Result()
+		if err == nil {
+			info.Value, _, err = m.client.SScan(m.ctx, fullKey, 0, "", inspectLimit).Result()
+		}
+	case "zset":
+		info.Size, err = m.client.ZCard(m.ctx, fullKey).Result()
+		if err == nil {
+			info.Value, err = m.client.ZRangeWithScores(m.ctx, fullKey, 0, inspectLimit-1).Result()
+		}
+	case "hash":
+		info.Size, err = m.client.HLen(m.ctx, fullKey).Result()
+		if err == nil {
+			var fields []string
+			fields, _, err = m.client.HScan(m.ctx, fullKey, 0, "", inspectLimit).Result()
+			info.Value = pairsToMap(fields)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get value: %w", err)
+	}
+	info.Truncated = keyType != "string" && info.Size > inspectLimit
+	return info, nil
+}
+
+// pairsToMap 将 HSCAN 返回的字段值列表转换为映射
+func pairsToMap(pairs []string) map[string]string {
+	result := make(map[string]string, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		result[pairs[i]] = pairs[i+1]
+	}
+	return result
+}
+
+// ScanKeys 使用 SCAN 遍历匹配模式的键（返回的键不含前缀），limit 为0时不限制数量
+// 与 Keys 不同，SCAN 不会长时间阻塞Redis，适合在生产环境使用
+func (m *Manager) ScanKeys(pattern string, limit int) ([]string, error) {
+	var keys []string
+	iter := m.client.Scan(m.ctx, 0, m.patternKey(pattern), scanBatchSize).Iterator()
+	for iter.Next(m.ctx) {
+		keys = append(keys, iter.Val()[len(m.prefix):])
+		if limit > 0 && len(keys) >= limit {
+			break
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan keys: %w", err)
+	}
+	return keys, nil
+}
+
+// DeleteByPattern 使用 SCAN 分批删除匹配模式的键，dryRun 为true时只统计不删除
+func (m *Manager) DeleteByPattern(pattern string, dryRun bool) (*PatternDeleteResult, error) {
+	if pattern == "" {
+		return nil, errors.New("pattern is required")
+	}
+
+	result := &PatternDeleteResult{Pattern: pattern, DryRun: dryRun, Sample: []string{}}
+	batch := make([]string, 0, scanBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		deleted, err := m.client.Del(m.ctx, batch...).Result()
+		if err != nil {
+			return fmt.Errorf("failed to delete keys: %w", err)
+		}
+		result.Deleted += deleted
+		batch = batch[:0]
+		return nil
+	}
+
+	iter := m.client.Scan(m.ctx, 0, m.patternKey(pattern), scanBatchSize).Iterator()
+	for iter.Next(m.ctx) {
+		key := iter.Val()
+		result.Matched++
+		if len(result.Sample) < sampleSize {
+			result.Sample = append(result.Sample, key[len(m.prefix):])
+		}
+		if dryRun {
+			continue
+		}
+		batch = append(batch, key)
+		if len(batch) >= scanBatchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return result, fmt.Errorf("failed to scan keys: %w", err)
+	}
+	return result, flush()
+}
+
+// FlushNamespace 删除子命名空间下的所有键，如 FlushNamespace("user") 删除 <前缀>user:*
+func (m *Manager) FlushNamespace(namespace string, dryRun bool) (*PatternDeleteResult, error) {
+	if namespace == "" {
+		return nil, errors.New("namespace is required")
+	}
+	return m.WithPrefix(namespace).DeleteByPattern("*", dryRun)
+}
+
+// HitStats 获取命中率统计和命令延迟，不包含 Redis INFO
+func (m *Manager) HitStats() map[string]interface{} {
+	stats := map[string]interface{}{}
+	if m.hook != nil {
+		stats = m.hook.stats()
+	}
+	stats["command_latency"] = metrics.Default.Snapshot()["hwhkit_cache_command_duration_seconds"]
+	return stats
+}
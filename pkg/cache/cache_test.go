@@ -1,10 +1,21 @@
 package cache
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestCacheManager(t *testing.T) {
@@ -118,6 +129,282 @@ func TestCacheManagerJSON(t *testing.T) {
 	}
 }
 
+func TestCacheManagerSortedSetAndGeo(t *testing.T) {
+	t.Skip("Skipping cache integration test - requires actual Redis")
+
+	cfg := &config.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		PoolSize: 10,
+	}
+
+	manager, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create cache manager: %v", err)
+	}
+	defer manager.Close()
+
+	// 测试排行榜
+	board := "test_leaderboard"
+	defer manager.Delete(board)
+
+	if err := manager.ZAdd(board, redis.Z{Score: 10, Member: "alice"}, redis.Z{Score: 20, Member: "bob"}); err != nil {
+		t.Fatalf("Failed to zadd: %v", err)
+	}
+	if score, err := manager.ZIncrBy(board, 15, "alice"); err != nil || score != 25 {
+		t.Errorf("Expected score 25, got %v (%v)", score, err)
+	}
+
+	top, err := manager.ZRevRangeWithScores(board, 0, 0)
+	if err != nil || len(top) != 1 || top[0].Member != "alice" {
+		t.Errorf("Expected alice on top, got %v (%v)", top, err)
+	}
+
+	members, err := manager.ZRangeByScore(board, "15", "+inf", 0, 10)
+	if err != nil || len(members) != 2 {
+		t.Errorf("Expected 2 members, got %v (%v)", members, err)
+	}
+
+	// 测试附近搜索
+	places := "test_places"
+	defer manager.Delete(places)
+
+	if err := manager.GeoAdd(places,
+		&redis.GeoLocation{Name: "tiananmen", Longitude: 116.397, Latitude: 39.908},
+		&redis.GeoLocation{Name: "shanghai", Longitude: 121.473, Latitude: 31.230},
+	); err != nil {
+		t.Fatalf("Failed to geoadd: %v", err)
+	}
+
+	nearby, err := manager.GeoSearch(places, &redis.GeoSearchQuery{
+		Longitude:  116.40,
+		Latitude:   39.90,
+		Radius:     10,
+		RadiusUnit: "km",
+	})
+	if err != nil || len(nearby) != 1 || nearby[0] != "tiananmen" {
+		t.Errorf("Expected tiananmen nearby, got %v (%v)", nearby, err)
+	}
+}
+
+func TestCacheManagerPrefix(t *testing.T) {
+	manager := &Manager{prefix: normalizePrefix("myapp")}
+
+	if manager.Key("token") != "myapp:token" {
+		t.Errorf("Expected myapp:token, got %s", manager.Key("token"))
+	}
+
+	userCache := manager.WithPrefix("user:")
+	if userCache.Prefix() != "myapp:user:" {
+		t.Errorf("Expected prefix myapp:user:, got %s", userCache.Prefix())
+	}
+	if userCache.Key("1") != "myapp:user:1" {
+		t.Errorf("Expected myapp:user:1, got %s", userCache.Key("1"))
+	}
+
+	// 未配置前缀时键保持不变
+	plain := &Manager{}
+	if plain.Key("token") != "token" {
+		t.Errorf("Expected token, got %s", plain.Key("token"))
+	}
+}
+
+func TestCacheManagerPrefixedFlush(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	manager := &Manager{client: client, ctx: context.Background(), prefix: normalizePrefix("app[1]")}
+
+	mr.Set("app[1]:token", "a")
+	mr.Set("app[1]:user:1", "b")
+	mr.Set("app1:token", "other")
+	mr.Set("billing:token", "other")
+
+	// 设置前缀时 FlushDB 只删除本前缀下的键，前缀中的 glob 字符按字面匹配
+	if err := manager.FlushDB(); err != nil {
+		t.Fatalf("FlushDB failed: %v", err)
+	}
+	if mr.Exists("app[1]:token") || mr.Exists("app[1]:user:1") {
+		t.Error("Expected prefixed keys to be deleted")
+	}
+	if !mr.Exists("app1:token") || !mr.Exists("billing:token") {
+		t.Error("Expected keys outside the prefix to be kept")
+	}
+
+	if err := manager.FlushAll(); !errors.Is(err, ErrPrefixedFlushAll) {
+		t.Errorf("Expected ErrPrefixedFlushAll, got %v", err)
+	}
+	if !mr.Exists("billing:token") {
+		t.Error("Expected FlushAll to be refused with a prefix")
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	type item struct {
+		Name string
+		Tags []string
+	}
+	value := item{Name: "widget", Tags: []string{"a", "b"}}
+
+	for _, name := range []string{"json", "msgpack", "gob"} {
+		codec, err := CodecByName(name)
+		if err != nil {
+			t.Fatalf("Failed to get %s codec: %v", name, err)
+		}
+		data, err := codec.Marshal(value)
+		if err != nil {
+			t.Fatalf("%s marshal failed: %v", name, err)
+		}
+		var decoded item
+		if err := codec.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("%s unmarshal failed: %v", name, err)
+		}
+		if decoded.Name != value.Name || len(decoded.Tags) != 2 {
+			t.Errorf("%s round trip mismatch: %+v", name, decoded)
+		}
+	}
+
+	data, err := ProtoCodec.Marshal(wrapperspb.String("hello"))
+	if err != nil {
+		t.Fatalf("proto marshal failed: %v", err)
+	}
+	var msg wrapperspb.StringValue
+	if err := ProtoCodec.Unmarshal(data, &msg); err != nil || msg.GetValue() != "hello" {
+		t.Errorf("proto round trip failed: %v %q", err, msg.GetValue())
+	}
+	if _, err := ProtoCodec.Marshal(value); err == nil {
+		t.Error("Expected proto codec to reject non-proto values")
+	}
+
+	if _, err := CodecByName("xml"); err == nil {
+		t.Error("Expected unsupported codec error")
+	}
+	if (&Manager{}).Codec().Name() != "json" {
+		t.Error("Expected JSON as default codec")
+	}
+}
+
+func TestTypedHelpers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+		codec:  MsgPackCodec,
+	}
+	defer manager.Close()
+
+	type profile struct {
+		ID   int
+		Name string
+	}
+
+	if err := Set(manager, "profile:1", profile{ID: 1, Name: "alice"}, time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	got, err := Get[profile](manager, "profile:1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.ID != 1 || got.Name != "alice" {
+		t.Errorf("Unexpected value: %+v", got)
+	}
+
+	if _, err := Get[profile](manager, "profile:2"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss, got %v", err)
+	}
+
+	loads := 0
+	loader := func() (profile, error) {
+		loads++
+		return profile{ID: 2, Name: "bob"}, nil
+	}
+	for i := 0; i < 2; i++ {
+		got, err := Remember(manager, "profile:2", time.Minute, loader)
+		if err != nil || got.Name != "bob" {
+			t.Fatalf("Remember returned %+v, %v", got, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("Expected loader to run once, ran %d times", loads)
+	}
+	if _, err := Remember(manager, "profile:3", time.Minute, func() (profile, error) {
+		return profile{}, errors.New("load failed")
+	}); err == nil {
+		t.Error("Expected loader error")
+	}
+}
+
+func TestMetricsHook(t *testing.T) {
+	hook := &metricsHook{}
+	ctx := context.Background()
+
+	hit := redis.NewStringCmd(ctx, "get", "a")
+	miss := redis.NewStringCmd(ctx, "get", "b")
+	miss.SetErr(redis.Nil)
+	failed := redis.NewStatusCmd(ctx, "set", "c", "1")
+	failed.SetErr(errors.New("connection refused"))
+
+	for _, cmd := range []redis.Cmder{hit, miss, failed} {
+		hook.record(cmd)
+	}
+
+	stats := hook.stats()
+	if stats["hits"] != int64(1) || stats["misses"] != int64(1) || stats["errors"] != int64(1) {
+		t.Errorf("Unexpected hook stats: %v", stats)
+	}
+	if stats["hit_ratio"] != 0.5 {
+		t.Errorf("Expected hit ratio 0.5, got %v", stats["hit_ratio"])
+	}
+}
+
+func TestTracingHook(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+		codec:  JSONCodec,
+	}
+	defer manager.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	manager.EnableTracing(tracer)
+
+	ctx, parent := tracer.Start(context.Background(), "request")
+	scoped := manager.WithContext(ctx)
+	if err := scoped.Set("a", "1", time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, err := scoped.Get("missing"); err == nil {
+		t.Fatal("Expected cache miss")
+	}
+	pipe := scoped.GetClient().Pipeline()
+	pipe.Incr(ctx, scoped.Key("counter"))
+	pipe.Expire(ctx, scoped.Key("counter"), time.Minute)
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("Expected 4 spans, got %d", len(spans))
+	}
+	names := []string{spans[0].Name(), spans[1].Name(), spans[2].Name()}
+	if names[0] != "redis.set" || names[1] != "redis.get" || names[2] != "redis.pipeline" {
+		t.Errorf("Unexpected span names: %v", names)
+	}
+	for _, span := range spans[:3] {
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("Expected %s to be a child of the request span", span.Name())
+		}
+	}
+	// 键不存在不视为错误
+	if spans[1].Status().Code != codes.Unset {
+		t.Errorf("Expected cache miss span without error status, got %v", spans[1].Status())
+	}
+}
+
 func TestSessionManager(t *testing.T) {
 	t.Skip("Skipping session test - requires actual Redis")
 	
@@ -275,4 +562,883 @@ func BenchmarkCacheGet(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		manager.Get(key)
 	}
-}
\ No newline at end of file
+}
+func TestSessionManagerRegenerateID(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+		prefix: "app:",
+	}
+	defer manager.Close()
+
+	sm := NewSessionManager(manager, "session", time.Hour)
+	session, err := sm.CreateSession("user1")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if err := sm.SetSessionData(session.ID, "cart", "3 items"); err != nil {
+		t.Fatalf("Failed to set session data: %v", err)
+	}
+
+	regenerated, err := sm.RegenerateID(session.ID)
+	if err != nil {
+		t.Fatalf("Failed to regenerate session ID: %v", err)
+	}
+	if regenerated.ID == session.ID {
+		t.Error("Expected a new session ID")
+	}
+	if regenerated.UserID != "user1" || regenerated.Data["cart"] != "3 items" {
+		t.Errorf("Expected session data to be copied, got %+v", regenerated)
+	}
+
+	if sm.IsValidSession(session.ID) {
+		t.Error("Expected old session ID to be invalid")
+	}
+	if !sm.IsValidSession(regenerated.ID) {
+		t.Error("Expected new session ID to be valid")
+	}
+	if _, err := sm.RegenerateID(session.ID); err == nil {
+		t.Error("Expected regenerating a deleted session to fail")
+	}
+}
+
+func TestSessionManagerLimit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+	}
+	defer manager.Close()
+
+	sm := NewSessionManager(manager, "session", time.Hour)
+	sm.SetSessionLimit(2, SessionLimitRejectNew)
+
+	first, err := sm.CreateSession("user1")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if _, err := sm.CreateSession("user1"); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if _, err := sm.CreateSession("user1"); !errors.Is(err, ErrSessionLimitExceeded) {
+		t.Errorf("Expected ErrSessionLimitExceeded, got %v", err)
+	}
+	if _, err := sm.CreateSession("user2"); err != nil {
+		t.Errorf("Expected other users to be unaffected, got %v", err)
+	}
+
+	var evicted []string
+	sm.SetSessionLimit(2, SessionLimitEvictOldest)
+	sm.OnSessionEvicted(func(session *Session) {
+		evicted = append(evicted, session.ID)
+	})
+
+	third, err := sm.CreateSession("user1")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if len(evicted) != 1 || evicted[0] != first.ID {
+		t.Errorf("Expected oldest session %s to be evicted, got %v", first.ID, evicted)
+	}
+	if sm.IsValidSession(first.ID) || !sm.IsValidSession(third.ID) {
+		t.Error("Expected oldest session to be deleted and new session to be valid")
+	}
+
+	sessions, err := sm.GetUserSessions("user1")
+	if err != nil || len(sessions) != 2 {
+		t.Errorf("Expected 2 sessions for user1, got %d (%v)", len(sessions), err)
+	}
+
+	// 会话ID重置后索引同步更新
+	regenerated, err := sm.RegenerateID(third.ID)
+	if err != nil {
+		t.Fatalf("Failed to regenerate session ID: %v", err)
+	}
+	sessions, _ = sm.GetUserSessions("user1")
+	found := false
+	for _, session := range sessions {
+		if session.ID == third.ID {
+			t.Error("Expected old session ID to be removed from index")
+		}
+		found = found || session.ID == regenerated.ID
+	}
+	if !found {
+		t.Error("Expected regenerated session ID in index")
+	}
+}
+
+func TestSessionIndexes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+		prefix: "app:",
+	}
+	defer manager.Close()
+
+	sm := NewSessionManager(manager, "session", time.Hour)
+	first, _ := sm.CreateSession("user1")
+	second, _ := sm.CreateSession("user1")
+	sm.CreateSession("user2")
+	sm.CreateSession("")
+	expired, err := sm.CreateSession("user3")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := sm.saveSession(expired); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	stats, err := sm.GetStats()
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats.TotalSessions != 5 || stats.ActiveSessions != 4 || stats.ExpiredSessions != 1 {
+		t.Errorf("Unexpected session stats: %+v", stats)
+	}
+	if len(stats.UserSessions) != 2 || stats.UserSessions["user1"] != 2 || stats.UserSessions["user2"] != 1 {
+		t.Errorf("Unexpected user sessions: %v", stats.UserSessions)
+	}
+	if members, _ := mr.Members("app:session_users"); len(members) != 2 {
+		t.Errorf("Expected users without active sessions to be removed, got %v", members)
+	}
+
+	// 刷新后仍按创建时间排序
+	first.ExpiresAt = time.Now().Add(2 * time.Hour)
+	if err := sm.saveSession(first); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+	sessions, err := sm.GetUserSessions("user1")
+	if err != nil || len(sessions) != 2 || sessions[0].ID != first.ID || sessions[1].ID != second.ID {
+		t.Errorf("Expected user1 sessions in creation order, got %v (%v)", sessions, err)
+	}
+
+	if err := sm.CleanExpiredSessions(); err != nil {
+		t.Fatalf("Failed to clean sessions: %v", err)
+	}
+	if mr.Exists("app:session:" + expired.ID) {
+		t.Error("Expected expired session to be deleted")
+	}
+	if count, _ := sm.GetSessionCount(); count != 4 {
+		t.Errorf("Expected 4 active sessions, got %d", count)
+	}
+	if members, _ := mr.ZMembers("app:session_expiry"); len(members) != 4 {
+		t.Errorf("Expected expired session removed from index, got %v", members)
+	}
+
+	// 删除会话同时移出索引
+	if err := sm.DeleteSession(second.ID); err != nil {
+		t.Fatalf("Failed to delete session: %v", err)
+	}
+	if members, _ := mr.ZMembers("app:session_user:user1"); len(members) != 1 {
+		t.Errorf("Expected 1 indexed session for user1, got %v", members)
+	}
+
+	// 升级前创建、没有索引的会话通过 RebuildIndexes 补充索引
+	mr.Del("app:session_expiry")
+	mr.Del("app:session_user:user1")
+	indexed, err := sm.RebuildIndexes()
+	if err != nil || indexed != 3 {
+		t.Errorf("Expected 3 sessions to be indexed, got %d (%v)", indexed, err)
+	}
+	if count, _ := sm.GetSessionCount(); count != 3 {
+		t.Errorf("Expected 3 active sessions after rebuild, got %d", count)
+	}
+	if sessions, _ := sm.GetUserSessions("user1"); len(sessions) != 1 || sessions[0].ID != first.ID {
+		t.Errorf("Expected rebuilt user index, got %v", sessions)
+	}
+}
+
+func TestSessionTimeoutsAndRotation(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+	}
+	defer manager.Close()
+
+	sm := NewSessionManager(manager, "session", time.Hour)
+	sm.SetIdleTimeout(10 * time.Minute)
+	sm.SetAbsoluteTimeout(30 * time.Minute)
+	near := func(expected, actual time.Time) bool {
+		return actual.Sub(expected).Abs() < 5*time.Second
+	}
+
+	session, err := sm.CreateSession("")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if !near(time.Now().Add(10*time.Minute), session.ExpiresAt) {
+		t.Errorf("Expected idle expiry, got %v", session.ExpiresAt)
+	}
+
+	// 剩余时间不足一半时访问会顺延空闲超时
+	session.ExpiresAt = time.Now().Add(2 * time.Minute)
+	sm.saveSession(session)
+	loaded, err := sm.GetSession(session.ID)
+	if err != nil || !near(time.Now().Add(10*time.Minute), loaded.ExpiresAt) {
+		t.Errorf("Expected idle timeout to be extended, got %v (%v)", loaded, err)
+	}
+
+	// 顺延不超过绝对超时
+	session.CreatedAt = time.Now().Add(-25 * time.Minute)
+	session.ExpiresAt = time.Now().Add(2 * time.Minute)
+	sm.saveSession(session)
+	loaded, err = sm.GetSession(session.ID)
+	if err != nil || !near(session.CreatedAt.Add(30*time.Minute), loaded.ExpiresAt) {
+		t.Errorf("Expected expiry capped by absolute timeout, got %v (%v)", loaded, err)
+	}
+	if err := sm.RefreshSession(session.ID); err != nil {
+		t.Fatalf("Failed to refresh session: %v", err)
+	}
+	if loaded, _ = sm.GetSession(session.ID); !near(session.CreatedAt.Add(30*time.Minute), loaded.ExpiresAt) {
+		t.Errorf("Expected refresh capped by absolute timeout, got %v", loaded.ExpiresAt)
+	}
+
+	// 超过绝对超时的会话失效，更换ID也不能延长
+	session.CreatedAt = time.Now().Add(-31 * time.Minute)
+	session.ExpiresAt = time.Now().Add(5 * time.Minute)
+	sm.saveSession(session)
+	if _, err := sm.RotateSession(session.ID); err == nil {
+		t.Error("Expected rotating an absolutely expired session to fail")
+	}
+	if _, err := sm.GetSession(session.ID); err == nil {
+		t.Error("Expected absolute timeout to expire session")
+	}
+
+	// 登录时自动更换会话ID，保留数据和创建时间
+	sm.SetRotateOnLogin(true)
+	anonymous, _ := sm.CreateSession("")
+	anonymous.Set("cart", "3 items")
+	sm.UpdateSession(anonymous)
+	bound, err := sm.BindUser(anonymous.ID, "user1")
+	if err != nil {
+		t.Fatalf("Failed to bind user: %v", err)
+	}
+	if bound.ID == anonymous.ID || bound.UserID != "user1" || bound.GetString("cart", "") != "3 items" {
+		t.Errorf("Expected rotated session with copied data, got %+v", bound)
+	}
+	if !bound.CreatedAt.Equal(anonymous.CreatedAt) {
+		t.Errorf("Expected creation time to be kept, got %v", bound.CreatedAt)
+	}
+	if sm.IsValidSession(anonymous.ID) || !sm.IsValidSession(bound.ID) {
+		t.Error("Expected pre-login session ID to be invalidated")
+	}
+	if sessions, _ := sm.GetUserSessions("user1"); len(sessions) != 1 || sessions[0].ID != bound.ID {
+		t.Errorf("Expected rotated session in user index, got %v", sessions)
+	}
+
+	// 相同用户再次绑定不更换ID
+	again, err := sm.BindUser(bound.ID, "user1")
+	if err != nil || again.ID != bound.ID {
+		t.Errorf("Expected rebinding the same user to keep the ID, got %v (%v)", again, err)
+	}
+}
+
+func TestSessionIndexPruning(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+		prefix: "app:",
+	}
+	defer manager.Close()
+
+	sm := NewSessionManager(manager, "session", time.Hour)
+	expiredBefore := sessionsExpired.Value("session")
+	// 过期超过 indexTTL 的条目（会话键早已过期）在下一次写入时裁剪，并计入过期数
+	mr.ZAdd("app:session_expiry", float64(time.Now().Add(-2*time.Hour).UnixMilli()), "gone")
+	mr.ZAdd("app:session_expiry", float64(time.Now().Add(-time.Minute).UnixMilli()), "recent")
+	if _, err := sm.CreateSession("user1"); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	members, _ := mr.ZMembers("app:session_expiry")
+	if len(members) != 2 || members[0] != "recent" {
+		t.Errorf("Expected stale index entry to be pruned on write, got %v", members)
+	}
+	if got := sessionsExpired.Value("session") - expiredBefore; got != 1 {
+		t.Errorf("Expected pruned entry to count as expired, got %v", got)
+	}
+
+	// 用户会话全部过期后，清理时移出用户集合
+	expired, _ := sm.CreateSession("user2")
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := sm.saveSession(expired); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+	if err := sm.CleanExpiredSessions(); err != nil {
+		t.Fatalf("Failed to clean sessions: %v", err)
+	}
+	if users, _ := mr.Members("app:session_users"); len(users) != 1 || users[0] != "user1" {
+		t.Errorf("Expected only user1 in session users, got %v", users)
+	}
+	if members, _ := mr.ZMembers("app:session_expiry"); len(members) != 1 {
+		t.Errorf("Expected only the active session in the expiry index, got %v", members)
+	}
+
+	// 清理时删除的过期会话计入过期数，并刷新活跃会话数
+	if got := sessionsExpired.Value("session") - expiredBefore; got != 3 {
+		t.Errorf("Expected 3 expired sessions, got %v", got)
+	}
+	if got := sessionsActive.Value("session"); got != 1 {
+		t.Errorf("Expected active session gauge 1 after cleanup, got %v", got)
+	}
+}
+
+func TestSessionValuesAndFlash(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+	}
+	defer manager.Close()
+
+	sm := NewSessionManager(manager, "session", time.Hour)
+	session, err := sm.CreateSession("user1")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	loginAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	session.Set("name", "alice")
+	session.Set("visits", 3)
+	session.Set("login_at", loginAt)
+	session.SetFlash("notice", "saved")
+	if err := sm.UpdateSession(session); err != nil {
+		t.Fatalf("Failed to update session: %v", err)
+	}
+
+	loaded, err := sm.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	if loaded.GetString("name", "") != "alice" || loaded.GetInt("visits", 0) != 3 {
+		t.Errorf("Unexpected typed values: %v", loaded.Data)
+	}
+	if !loaded.GetTime("login_at", time.Time{}).Equal(loginAt) {
+		t.Errorf("Expected login_at %v, got %v", loaded.GetTime("login_at", time.Time{}), loginAt)
+	}
+	if loaded.GetInt("missing", 7) != 7 || loaded.GetString("visits", "none") != "none" {
+		t.Error("Expected defaults for missing or mismatched values")
+	}
+
+	// 未修改的会话不写入Redis
+	before := loaded.UpdatedAt
+	if loaded.IsModified() {
+		t.Error("Expected freshly loaded session to be unmodified")
+	}
+	if err := sm.UpdateSession(loaded); err != nil || !loaded.UpdatedAt.Equal(before) {
+		t.Error("Expected UpdateSession to skip unmodified session")
+	}
+
+	// 闪存数据读取一次后清除
+	if value, ok := loaded.GetFlash("notice"); !ok || value != "saved" {
+		t.Errorf("Expected flash value, got %v", value)
+	}
+	if !loaded.IsModified() {
+		t.Error("Expected reading flash to modify session")
+	}
+	if err := sm.UpdateSession(loaded); err != nil {
+		t.Fatalf("Failed to update session: %v", err)
+	}
+	reloaded, _ := sm.GetSession(session.ID)
+	if _, ok := reloaded.GetFlash("notice"); ok {
+		t.Error("Expected flash value to be cleared after read")
+	}
+}
+
+func TestQuotaManager(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+	}
+	defer manager.Close()
+
+	qm := NewQuotaManager(manager, "", QuotaPlan{Name: "free", Daily: 2, Monthly: 3})
+	qm.now = func() time.Time { return time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC) }
+	mr.SetTime(qm.now())
+
+	for i := 0; i < 2; i++ {
+		usage, err := qm.Consume("user:1", "free")
+		if err != nil {
+			t.Fatalf("Consume failed: %v", err)
+		}
+		if usage.Exceeded() {
+			t.Fatalf("Quota exceeded too early: %+v", usage)
+		}
+	}
+
+	usage, err := qm.Consume("user:1", "free")
+	if err != nil {
+		t.Fatalf("Consume failed: %v", err)
+	}
+	if !usage.Exceeded() || usage.DailyRemaining() != 0 || usage.MonthlyRemaining() != 0 {
+		t.Errorf("Expected exceeded quota, got %+v", usage)
+	}
+	if !usage.DailyReset.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) || !usage.MonthlyReset.Equal(usage.DailyReset) {
+		t.Errorf("Unexpected reset times: %v %v", usage.DailyReset, usage.MonthlyReset)
+	}
+
+	// 新的一天重新计算日配额，月配额继续累计
+	qm.now = func() time.Time { return time.Date(2024, 1, 30, 12, 0, 0, 0, time.UTC) }
+	usage, _ = qm.Usage("user:1", "free")
+	if usage.DailyUsed != 0 || usage.MonthlyUsed != 3 {
+		t.Errorf("Unexpected usage on another day: %+v", usage)
+	}
+
+	if err := qm.Reset("user:1"); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	usage, _ = qm.Usage("user:1", "free")
+	if usage.MonthlyUsed != 0 {
+		t.Errorf("Expected reset usage, got %+v", usage)
+	}
+
+	if _, err := qm.Consume("user:1", "gold"); !errors.Is(err, ErrUnknownQuotaPlan) {
+		t.Errorf("Expected ErrUnknownQuotaPlan, got %v", err)
+	}
+	if (&QuotaUsage{}).DailyRemaining() != -1 {
+		t.Error("Expected unlimited quota")
+	}
+}
+
+func TestCacheAdmin(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+		prefix: "app:",
+	}
+	defer manager.Close()
+
+	manager.Set("user:1", "alice", time.Minute)
+	manager.Set("user:2", "bob", 0)
+	manager.Set("order:1", "book", 0)
+	manager.HSet("profile:1", "name", "alice")
+
+	info, err := manager.Inspect("user:1")
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if info.Type != "string" || info.Value != "alice" || info.TTLSeconds != 60 || info.Size != 5 {
+		t.Errorf("Unexpected key info: %+v", info)
+	}
+	info, _ = manager.Inspect("profile:1")
+	if info.Type != "hash" || info.TTLSeconds != -1 || info.Value.(map[string]string)["name"] != "alice" {
+		t.Errorf("Unexpected hash info: %+v", info)
+	}
+	if _, err := manager.Inspect("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	keys, err := manager.ScanKeys("user:*", 0)
+	if err != nil || len(keys) != 2 {
+		t.Errorf("Expected 2 user keys, got %v (%v)", keys, err)
+	}
+
+	// 试运行只统计不删除
+	result, err := manager.DeleteByPattern("user:*", true)
+	if err != nil {
+		t.Fatalf("DeleteByPattern dry run failed: %v", err)
+	}
+	if result.Matched != 2 || result.Deleted != 0 || len(result.Sample) != 2 {
+		t.Errorf("Unexpected dry run result: %+v", result)
+	}
+	if exists, _ := manager.Exists("user:1"); !exists {
+		t.Error("Dry run should not delete keys")
+	}
+
+	result, err = manager.FlushNamespace("user", false)
+	if err != nil {
+		t.Fatalf("FlushNamespace failed: %v", err)
+	}
+	if result.Matched != 2 || result.Deleted != 2 {
+		t.Errorf("Unexpected flush result: %+v", result)
+	}
+	if exists, _ := manager.Exists("order:1"); !exists {
+		t.Error("Keys outside the namespace should be kept")
+	}
+	if _, err := manager.DeleteByPattern("", false); err == nil {
+		t.Error("Expected error for empty pattern")
+	}
+}
+
+func TestSessionMetrics(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+	}
+	defer manager.Close()
+
+	sm := NewSessionManager(manager, "metrics_session", time.Hour)
+	sm.SetSessionLimit(1, SessionLimitEvictOldest)
+	createdBefore := sessionsCreated.Value("metrics_session")
+	expiredBefore := sessionsExpired.Value("metrics_session")
+	evictedBefore := sessionsEvicted.Value("metrics_session")
+
+	sm.CreateSession("user1")
+	if _, err := sm.CreateSession("user1"); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	anonymous, _ := sm.CreateSession("")
+	anonymous.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := sm.saveSession(anonymous); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	if count, _ := sm.GetSessionCount(); count != 1 {
+		t.Errorf("Expected 1 active session, got %d", count)
+	}
+	if _, err := sm.GetSession(anonymous.ID); err == nil {
+		t.Error("Expected expired session error")
+	}
+
+	if got := sessionsCreated.Value("metrics_session") - createdBefore; got != 3 {
+		t.Errorf("Expected 3 created sessions, got %v", got)
+	}
+	if got := sessionsEvicted.Value("metrics_session") - evictedBefore; got != 1 {
+		t.Errorf("Expected 1 evicted session, got %v", got)
+	}
+	if got := sessionsExpired.Value("metrics_session") - expiredBefore; got != 1 {
+		t.Errorf("Expected 1 expired session, got %v", got)
+	}
+	if got := sessionsActive.Value("metrics_session"); got != 1 {
+		t.Errorf("Expected active session gauge 1, got %v", got)
+	}
+}
+
+func TestTypedCache(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+		prefix: "app:",
+	}
+	defer manager.Close()
+
+	type profile struct {
+		ID   int
+		Name string
+	}
+
+	profiles := NewTypedCache[profile](manager, "profile", time.Minute)
+	profiles.SetCodec(GobCodec)
+	if manager.Codec() != JSONCodec {
+		t.Error("Expected manager codec to be unchanged")
+	}
+
+	if err := profiles.Set("1", profile{ID: 1, Name: "alice"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if !mr.Exists("app:profile:1") {
+		t.Fatal("Expected key with type prefix")
+	}
+	if ttl := mr.TTL("app:profile:1"); ttl != time.Minute {
+		t.Errorf("Expected default TTL, got %v", ttl)
+	}
+	got, err := profiles.Get("1")
+	if err != nil || got.Name != "alice" {
+		t.Fatalf("Get returned %+v, %v", got, err)
+	}
+	if _, err := profiles.Get("2"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss, got %v", err)
+	}
+
+	var loads int32
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := profiles.GetOrLoad("2", func() (profile, error) {
+				atomic.AddInt32(&loads, 1)
+				time.Sleep(20 * time.Millisecond)
+				return profile{ID: 2, Name: "bob"}, nil
+			})
+			if err != nil || got.Name != "bob" {
+				t.Errorf("GetOrLoad returned %+v, %v", got, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if loads != 1 {
+		t.Errorf("Expected loader to run once, ran %d times", loads)
+	}
+
+	if _, err := profiles.GetOrLoad("3", func() (profile, error) {
+		return profile{}, errors.New("load failed")
+	}); err == nil {
+		t.Error("Expected loader error")
+	}
+
+	if err := profiles.Delete("1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if exists, _ := profiles.Exists("1"); exists {
+		t.Error("Expected key to be deleted")
+	}
+}
+
+func TestMutex(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+		prefix: "app:",
+	}
+	defer manager.Close()
+	ctx := context.Background()
+
+	lock, err := manager.TryAcquire(ctx, "report", time.Minute)
+	if err != nil {
+		t.Fatalf("TryAcquire failed: %v", err)
+	}
+	if lock.Key() != "app:lock:report" || !mr.Exists("app:lock:report") {
+		t.Fatalf("Expected lock key app:lock:report, got %s", lock.Key())
+	}
+	if _, err := manager.TryAcquire(ctx, "report", time.Minute); !errors.Is(err, ErrLockNotAcquired) {
+		t.Errorf("Expected ErrLockNotAcquired, got %v", err)
+	}
+
+	// 等待超时
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	mutex := NewMutex(manager, "report", time.Minute)
+	mutex.SetRetryInterval(10 * time.Millisecond)
+	if _, err := mutex.Lock(waitCtx); !errors.Is(err, ErrLockNotAcquired) {
+		t.Errorf("Expected Lock to time out, got %v", err)
+	}
+	cancel()
+
+	if ttl, err := lock.TTL(ctx); err != nil || ttl != time.Minute {
+		t.Errorf("TTL returned %v, %v", ttl, err)
+	}
+
+	// 他人持有的锁不能被释放
+	other := &Lock{client: manager.client, key: lock.Key(), token: "other", stop: make(chan struct{})}
+	if err := other.Release(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Errorf("Expected ErrLockNotHeld, got %v", err)
+	}
+	if err := lock.Release(ctx); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if mr.Exists("app:lock:report") {
+		t.Error("Expected lock key to be deleted")
+	}
+	if err := lock.Refresh(ctx, time.Minute); !errors.Is(err, ErrLockNotHeld) {
+		t.Errorf("Expected ErrLockNotHeld after release, got %v", err)
+	}
+
+	// 锁释放后等待者获取成功
+	lock, err = manager.TryAcquire(ctx, "report", time.Minute)
+	if err != nil {
+		t.Fatalf("TryAcquire after release failed: %v", err)
+	}
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		lock.Release(ctx)
+		close(released)
+	}()
+	waiter, err := mutex.Lock(ctx)
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	<-released
+	waiter.Release(ctx)
+
+	// TryDo 锁被占用时不执行
+	held, _ := manager.TryAcquire(ctx, "job", time.Minute)
+	executed := false
+	err = NewMutex(manager, "job", time.Minute).TryDo(ctx, func(context.Context) error {
+		executed = true
+		return nil
+	})
+	if !errors.Is(err, ErrLockNotAcquired) || executed {
+		t.Errorf("Expected TryDo to skip, got %v, executed=%v", err, executed)
+	}
+	held.Release(ctx)
+}
+
+func TestMutexWatchdog(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+	}
+	defer manager.Close()
+	ctx := context.Background()
+
+	// 看门狗续期
+	lock, err := manager.TryAcquire(ctx, "renew", 150*time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryAcquire failed: %v", err)
+	}
+	mr.SetTTL("lock:renew", time.Millisecond)
+	time.Sleep(120 * time.Millisecond)
+	if ttl := mr.TTL("lock:renew"); ttl != 150*time.Millisecond {
+		t.Errorf("Expected watchdog to renew TTL, got %v", ttl)
+	}
+	lock.Release(ctx)
+
+	// 锁被他人抢占时 Do 的 ctx 被取消
+	err = NewMutex(manager, "lost", 150*time.Millisecond).Do(ctx, func(ctx context.Context) error {
+		mr.Set("lock:lost", "other")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected ctx to be canceled after lock lost, got %v", err)
+	}
+	if value, _ := mr.Get("lock:lost"); value != "other" {
+		t.Error("Expected lock held by other owner to be kept")
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+		prefix: "app:",
+	}
+	defer manager.Close()
+
+	type event struct {
+		UserID int    `json:"user_id"`
+		Action string `json:"action"`
+	}
+
+	ps := NewPubSub(manager)
+	ps.SetReconnectDelay(10*time.Millisecond, 50*time.Millisecond)
+	errs := make(chan error, 10)
+	ps.OnError(func(channel string, err error) {
+		if channel != "" {
+			errs <- err
+		}
+	})
+
+	events := make(chan event, 10)
+	if err := Subscribe(ps, "user.updated", func(ctx context.Context, e event) error {
+		events <- e
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if err := ps.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	receive := func() event {
+		t.Helper()
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for message")
+			return event{}
+		}
+	}
+
+	if _, err := ps.Publish(context.Background(), "user.updated", event{UserID: 1, Action: "rename"}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if e := receive(); e.UserID != 1 || e.Action != "rename" {
+		t.Errorf("Unexpected event %+v", e)
+	}
+	if mr.PubSubNumSub("app:user.updated")["app:user.updated"] != 1 {
+		t.Error("Expected subscription on prefixed channel")
+	}
+
+	// 启动后注册的频道立即订阅，处理函数的错误和panic交给错误回调
+	if err := ps.Handle("broken", func(ctx context.Context, msg *Message) error {
+		panic("boom")
+	}); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for mr.PubSubNumSub("app:broken")["app:broken"] == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	manager.Publish("broken", "x")
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("Expected panic to be reported")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for handler error")
+	}
+
+	// 连接断开后自动重连并重新订阅
+	mr.Restart()
+	deadline = time.Now().Add(3 * time.Second)
+	for mr.PubSubNumSub("app:user.updated")["app:user.updated"] == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	manager.Publish("user.updated", event{UserID: 2})
+	if e := receive(); e.UserID != 2 {
+		t.Errorf("Unexpected event after reconnect %+v", e)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ps.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if err := ps.Handle("late", func(context.Context, *Message) error { return nil }); !errors.Is(err, ErrPubSubClosed) {
+		t.Errorf("Expected ErrPubSubClosed, got %v", err)
+	}
+}
+
+func TestPubSubChannelLabelCap(t *testing.T) {
+	mr := miniredis.RunT(t)
+	manager := &Manager{
+		client: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		ctx:    context.Background(),
+		prefix: "app:",
+	}
+	defer manager.Close()
+
+	trackedChannels.mu.Lock()
+	saved := trackedChannels.channels
+	trackedChannels.channels = make(map[string]struct{}, maxTrackedChannels)
+	for i := 0; len(trackedChannels.channels) < maxTrackedChannels-1; i++ {
+		trackedChannels.channels[fmt.Sprintf("tracked-%d", i)] = struct{}{}
+	}
+	trackedChannels.mu.Unlock()
+	defer func() {
+		trackedChannels.mu.Lock()
+		trackedChannels.channels = saved
+		trackedChannels.mu.Unlock()
+	}()
+
+	// 最后一个名额分配给首个新频道，之后的新频道合并为 other，已统计的频道保留自己的标签
+	if _, err := manager.Publish("orders", "x"); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	before := pubsubPublished.Value(otherChannel)
+	for i := 0; i < 3; i++ {
+		if _, err := manager.Publish(fmt.Sprintf("user.%d", i), "x"); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	if got := pubsubPublished.Value(otherChannel) - before; got != 3 {
+		t.Errorf("Expected 3 publishes labelled %q, got %v", otherChannel, got)
+	}
+	if pubsubPublished.Value("user.0") != 0 {
+		t.Error("Expected untracked channel to have no label of its own")
+	}
+	ordersBefore := pubsubPublished.Value("orders")
+	manager.Publish("orders", "x")
+	if got := pubsubPublished.Value("orders") - ordersBefore; got != 1 {
+		t.Errorf("Expected tracked channel to keep its label, got %v", got)
+	}
+}
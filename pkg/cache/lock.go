@@ -0,0 +1,298 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrLockNotAcquired 锁已被其他持有者占用
+	ErrLockNotAcquired = errors.New("lock not acquired")
+	// ErrLockNotHeld 锁已过期或已被其他持有者获取
+	ErrLockNotHeld = errors.New("lock not held")
+)
+
+// releaseScript 仅当锁仍由当前持有者持有时删除
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// refreshScript 仅当锁仍由当前持有者持有时续期
+var refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Mutex 基于Redis的分布式互斥锁，通过 SET NX PX 加锁、Lua脚本校验持有者后释放，用于跨实例协调定时任务和临界区
+//
+//	mutex := cache.NewMutex(cacheManager, "jobs:daily-report", 30*time.Second)
+//	err := mutex.Do(ctx, func(ctx context.Context) error {
+//		return generateReport(ctx) // 锁丢失时 ctx 被取消
+//	})
+type Mutex struct {
+	cache         *Manager
+	key           string
+	ttl           time.Duration
+	retryInterval time.Duration
+	watchdog      bool
+}
+
+// NewMutex 创建分布式互斥锁，键为 lock:<key>（含缓存管理器的前缀），ttl 为0时默认30秒
+// 默认开启看门狗：持有期间每 ttl/3 自动续期，进程崩溃时锁在 ttl 后自动释放
+func NewMutex(cache *Manager, key string, ttl time.Duration) *Mutex {
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	return &Mutex{
+		cache:         cache,
+		key:           key,
+		ttl:           ttl,
+		retryInterval: 100 * time.Millisecond,
+		watchdog:      true,
+	}
+}
+
+// SetRetryInterval 设置 Lock 等待锁时的重试间隔，默认100毫秒
+func (mu *Mutex) SetRetryInterval(interval time.Duration) {
+	if interval > 0 {
+		mu.retryInterval = interval
+	}
+}
+
+// SetWatchdog 设置是否自动续期，关闭后持有时间超过 ttl 锁即失效，需要自行调用 Refresh
+func (mu *Mutex) SetWatchdog(enabled bool) {
+	mu.watchdog = enabled
+}
+
+// Key 锁在Redis中的完整键名
+func (mu *Mutex) Key() string {
+	return mu.cache.Key("lock:" + mu.key)
+}
+
+// TryLock 尝试获取锁，锁被占用时立即返回 ErrLockNotAcquired
+func (mu *Mutex) TryLock(ctx context.Context) (*Lock, error) {
+	token, err := generateLockToken()
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := mu.cache.client.SetNX(ctx, mu.Key(), token, mu.ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", mu.key, err)
+	}
+	if !ok {
+		return nil, ErrLockNotAcquired
+	}
+
+	lock := &Lock{
+		client: mu.cache.client,
+		key:    mu.Key(),
+		token:  token,
+		ttl:    mu.ttl,
+		lost:   make(chan struct{}),
+		stop:   make(chan struct{}),
+	}
+	if mu.watchdog {
+		go lock.watch()
+	}
+	return lock, nil
+}
+
+// Lock 获取锁，锁被占用时按重试间隔等待，直到获取成功或 ctx 结束
+func (mu *Mutex) Lock(ctx context.Context) (*Lock, error) {
+	ticker := time.NewTicker(mu.retryInterval)
+	defer ticker.Stop()
+
+	for {
+		lock, err := mu.TryLock(ctx)
+		if err == nil {
+			return lock, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%w: %v", ErrLockNotAcquired, ctx.Err())
+		}
+		if !errors.Is(err, ErrLockNotAcquired) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %v", ErrLockNotAcquired, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Do 获取锁（等待直到 ctx 结束）后执行 fn，执行完毕释放锁
+// 传给 fn 的 ctx 在锁丢失（续期失败）时被取消，fn 应据此停止写入共享资源
+func (mu *Mutex) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	lock, err := mu.Lock(ctx)
+	if err != nil {
+		return err
+	}
+	return lock.run(ctx, fn)
+}
+
+// TryDo 尝试获取锁后执行 fn，锁被占用时不执行并返回 ErrLockNotAcquired，适合每个周期只需一个实例执行的定时任务
+func (mu *Mutex) TryDo(ctx context.Context, fn func(ctx context.Context) error) error {
+	lock, err := mu.TryLock(ctx)
+	if err != nil {
+		return err
+	}
+	return lock.run(ctx, fn)
+}
+
+// Acquire 获取分布式锁（开启看门狗），锁被占用时等待直到获取成功或 ctx 结束，用完后调用 Release
+func (m *Manager) Acquire(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	return NewMutex(m, key, ttl).Lock(ctx)
+}
+
+// TryAcquire 尝试获取分布式锁（开启看门狗），锁被占用时立即返回 ErrLockNotAcquired
+func (m *Manager) TryAcquire(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	return NewMutex(m, key, ttl).TryLock(ctx)
+}
+
+// Lock 已获取的分布式锁
+type Lock struct {
+	client *redis.Client
+	key    string
+	token  string
+	ttl    time.Duration
+
+	lost     chan struct{} // 续期失败时关闭
+	lostOnce sync.Once
+	stop     chan struct{} // 释放时关闭，停止看门狗
+	stopOnce sync.Once
+}
+
+// Key 锁的完整键名
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Token 持有者标识，每次加锁随机生成
+func (l *Lock) Token() string {
+	return l.token
+}
+
+// Lost 返回锁丢失时关闭的通道，仅在开启看门狗时有效
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Refresh 将锁的有效期重置为 ttl，锁已过期或被他人持有时返回 ErrLockNotHeld
+func (l *Lock) Refresh(ctx context.Context, ttl time.Duration) error {
+	result, err := refreshScript.Run(ctx, l.client, []string{l.key}, l.token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to refresh lock %s: %w", l.key, err)
+	}
+	if result == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// TTL 锁的剩余有效期，锁已不由当前持有者持有时返回 ErrLockNotHeld
+func (l *Lock) TTL(ctx context.Context) (time.Duration, error) {
+	pipe := l.client.TxPipeline()
+	get := pipe.Get(ctx, l.key)
+	pttl := pipe.PTTL(ctx, l.key)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return 0, fmt.Errorf("failed to get lock TTL %s: %w", l.key, err)
+	}
+	if get.Val() != l.token {
+		return 0, ErrLockNotHeld
+	}
+	return pttl.Val(), nil
+}
+
+// Release 释放锁并停止看门狗，锁已过期或被他人持有时返回 ErrLockNotHeld
+func (l *Lock) Release(ctx context.Context) error {
+	l.stopOnce.Do(func() { close(l.stop) })
+
+	result, err := releaseScript.Run(ctx, l.client, []string{l.key}, l.token).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", l.key, err)
+	}
+	if result == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// watch 看门狗，每 ttl/3 续期一次；锁已被他人持有或连续续期失败直到锁过期时标记锁丢失
+func (l *Lock) watch() {
+	interval := l.ttl / 3
+	if interval <= 0 {
+		interval = l.ttl
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	expiresAt := time.Now().Add(l.ttl)
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := l.Refresh(ctx, l.ttl)
+		cancel()
+		switch {
+		case err == nil:
+			expiresAt = time.Now().Add(l.ttl)
+		case errors.Is(err, ErrLockNotHeld) || !time.Now().Before(expiresAt):
+			l.markLost()
+			return
+		}
+	}
+}
+
+// markLost 标记锁丢失
+func (l *Lock) markLost() {
+	l.lostOnce.Do(func() { close(l.lost) })
+}
+
+// run 在持有锁期间执行 fn，锁丢失时取消 fn 的 ctx，结束后释放锁
+func (l *Lock) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-l.lost:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := fn(ctx)
+	// 使用独立的 ctx 释放，避免调用方 ctx 已取消时锁残留到过期
+	releaseCtx, releaseCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer releaseCancel()
+	if releaseErr := l.Release(releaseCtx); err == nil && releaseErr != nil {
+		err = releaseErr
+	}
+	return err
+}
+
+// generateLockToken 生成持有者标识
+func generateLockToken() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/hwh/hwhkit-go/pkg/metrics"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -15,12 +17,26 @@ type Manager struct {
 	client *redis.Client
 	config *config.RedisConfig
 	ctx    context.Context
+	prefix string
+	hook   *metricsHook
+	codec  Codec
 }
 
 // New 创建新的Redis缓存管理器
 func New(cfg *config.RedisConfig) (*Manager, error) {
 	ctx := context.Background()
-	
+
+	// 构建TLS配置
+	tlsConfig, err := cfg.TLS.BuildTLS(cfg.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Redis TLS config: %w", err)
+	}
+
+	codec, err := CodecByName(cfg.Codec)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建Redis客户端
 	rdb := redis.NewClient(&redis.Options{
 		Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
@@ -32,7 +48,12 @@ func New(cfg *config.RedisConfig) (*Manager, error) {
 		DialTimeout:  time.Duration(cfg.DialTimeout) * time.Second,
 		ReadTimeout:  time.Duration(cfg.ReadTimeout) * time.Second,
 		WriteTimeout: time.Duration(cfg.WriteTimeout) * time.Second,
+		TLSConfig:    tlsConfig,
 	})
+
+	// 注册指标采集钩子
+	hook := &metricsHook{}
+	rdb.AddHook(hook)
 	
 	// 测试连接
 	if err := rdb.Ping(ctx).Err(); err != nil {
@@ -43,9 +64,63 @@ func New(cfg *config.RedisConfig) (*Manager, error) {
 		client: rdb,
 		config: cfg,
 		ctx:    ctx,
+		prefix: normalizePrefix(cfg.KeyPrefix),
+		hook:   hook,
+		codec:  codec,
 	}, nil
 }
 
+// normalizePrefix 规范化键前缀，非空时保证以冒号结尾
+func normalizePrefix(prefix string) string {
+	if prefix == "" {
+		return ""
+	}
+	return strings.TrimSuffix(prefix, ":") + ":"
+}
+
+// WithPrefix 创建共享连接、追加子命名空间的缓存管理器
+// 例如全局前缀为 app: 时，WithPrefix("user") 的键前缀为 app:user:
+func (m *Manager) WithPrefix(sub string) *Manager {
+	return &Manager{
+		client: m.client,
+		config: m.config,
+		ctx:    m.ctx,
+		prefix: m.prefix + normalizePrefix(sub),
+		hook:   m.hook,
+		codec:  m.codec,
+	}
+}
+
+// Prefix 获取当前键前缀
+func (m *Manager) Prefix() string {
+	return m.prefix
+}
+
+// Key 返回添加前缀后的完整键名，可用于Pipeline等直接操作客户端的场景
+func (m *Manager) Key(key string) string {
+	return m.prefix + key
+}
+
+// globEscaper 转义 Redis glob 模式中的特殊字符
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// patternKey 返回添加前缀后的匹配模式，前缀中的 glob 特殊字符按字面匹配
+func (m *Manager) patternKey(pattern string) string {
+	return globEscaper.Replace(m.prefix) + pattern
+}
+
+// prefixKeys 为多个键添加前缀
+func (m *Manager) prefixKeys(keys []string) []string {
+	if m.prefix == "" {
+		return keys
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = m.prefix + key
+	}
+	return prefixed
+}
+
 // GetClient 获取Redis客户端
 func (m *Manager) GetClient() *redis.Client {
 	return m.client
@@ -63,17 +138,17 @@ func (m *Manager) Health() error {
 
 // Set 设置缓存值
 func (m *Manager) Set(key string, value interface{}, expiration time.Duration) error {
-	return m.client.Set(m.ctx, key, value, expiration).Err()
+	return m.client.Set(m.ctx, m.Key(key), value, expiration).Err()
 }
 
 // Get 获取缓存值
 func (m *Manager) Get(key string) (string, error) {
-	return m.client.Get(m.ctx, key).Result()
+	return m.client.Get(m.ctx, m.Key(key)).Result()
 }
 
 // GetBytes 获取缓存值（字节）
 func (m *Manager) GetBytes(key string) ([]byte, error) {
-	return m.client.Get(m.ctx, key).Bytes()
+	return m.client.Get(m.ctx, m.Key(key)).Bytes()
 }
 
 // SetJSON 设置JSON缓存
@@ -82,146 +157,257 @@ func (m *Manager) SetJSON(key string, value interface{}, expiration time.Duratio
 	if err != nil {
 		return fmt.Errorf("failed to marshal JSON: %w", err)
 	}
-	return m.client.Set(m.ctx, key, jsonData, expiration).Err()
+	return m.client.Set(m.ctx, m.Key(key), jsonData, expiration).Err()
 }
 
 // GetJSON 获取JSON缓存
 func (m *Manager) GetJSON(key string, dest interface{}) error {
-	jsonData, err := m.client.Get(m.ctx, key).Bytes()
+	jsonData, err := m.client.Get(m.ctx, m.Key(key)).Bytes()
 	if err != nil {
 		return err
 	}
 	return json.Unmarshal(jsonData, dest)
 }
 
+// SetCodec 设置 SetAny/GetAny 使用的序列化方式
+func (m *Manager) SetCodec(codec Codec) {
+	m.codec = codec
+}
+
+// Codec 获取当前序列化方式，默认JSON
+func (m *Manager) Codec() Codec {
+	if m.codec == nil {
+		return JSONCodec
+	}
+	return m.codec
+}
+
+// SetAny 使用配置的序列化方式设置缓存
+func (m *Manager) SetAny(key string, value interface{}, expiration time.Duration) error {
+	data, err := m.Codec().Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache value with %s codec: %w", m.Codec().Name(), err)
+	}
+	return m.client.Set(m.ctx, m.Key(key), data, expiration).Err()
+}
+
+// GetAny 使用配置的序列化方式获取缓存，dest 必须为指针
+func (m *Manager) GetAny(key string, dest interface{}) error {
+	data, err := m.client.Get(m.ctx, m.Key(key)).Bytes()
+	if err != nil {
+		return err
+	}
+	if err := m.Codec().Unmarshal(data, dest); err != nil {
+		return fmt.Errorf("failed to unmarshal cache value with %s codec: %w", m.Codec().Name(), err)
+	}
+	return nil
+}
+
 // Delete 删除缓存
 func (m *Manager) Delete(keys ...string) error {
-	return m.client.Del(m.ctx, keys...).Err()
+	return m.client.Del(m.ctx, m.prefixKeys(keys)...).Err()
 }
 
 // Exists 检查键是否存在
 func (m *Manager) Exists(key string) (bool, error) {
-	count, err := m.client.Exists(m.ctx, key).Result()
+	count, err := m.client.Exists(m.ctx, m.Key(key)).Result()
 	return count > 0, err
 }
 
 // Expire 设置过期时间
 func (m *Manager) Expire(key string, expiration time.Duration) error {
-	return m.client.Expire(m.ctx, key, expiration).Err()
+	return m.client.Expire(m.ctx, m.Key(key), expiration).Err()
 }
 
 // TTL 获取剩余过期时间
 func (m *Manager) TTL(key string) (time.Duration, error) {
-	return m.client.TTL(m.ctx, key).Result()
+	return m.client.TTL(m.ctx, m.Key(key)).Result()
 }
 
 // Increment 递增
 func (m *Manager) Increment(key string) (int64, error) {
-	return m.client.Incr(m.ctx, key).Result()
+	return m.client.Incr(m.ctx, m.Key(key)).Result()
 }
 
 // IncrementBy 按指定值递增
 func (m *Manager) IncrementBy(key string, value int64) (int64, error) {
-	return m.client.IncrBy(m.ctx, key, value).Result()
+	return m.client.IncrBy(m.ctx, m.Key(key), value).Result()
 }
 
 // Decrement 递减
 func (m *Manager) Decrement(key string) (int64, error) {
-	return m.client.Decr(m.ctx, key).Result()
+	return m.client.Decr(m.ctx, m.Key(key)).Result()
 }
 
 // DecrementBy 按指定值递减
 func (m *Manager) DecrementBy(key string, value int64) (int64, error) {
-	return m.client.DecrBy(m.ctx, key, value).Result()
+	return m.client.DecrBy(m.ctx, m.Key(key), value).Result()
 }
 
 // HSet 设置哈希字段
 func (m *Manager) HSet(key string, field string, value interface{}) error {
-	return m.client.HSet(m.ctx, key, field, value).Err()
+	return m.client.HSet(m.ctx, m.Key(key), field, value).Err()
 }
 
 // HGet 获取哈希字段
 func (m *Manager) HGet(key string, field string) (string, error) {
-	return m.client.HGet(m.ctx, key, field).Result()
+	return m.client.HGet(m.ctx, m.Key(key), field).Result()
 }
 
 // HGetAll 获取所有哈希字段
 func (m *Manager) HGetAll(key string) (map[string]string, error) {
-	return m.client.HGetAll(m.ctx, key).Result()
+	return m.client.HGetAll(m.ctx, m.Key(key)).Result()
 }
 
 // HDel 删除哈希字段
 func (m *Manager) HDel(key string, fields ...string) error {
-	return m.client.HDel(m.ctx, key, fields...).Err()
+	return m.client.HDel(m.ctx, m.Key(key), fields...).Err()
 }
 
 // LPush 从左侧推入列表
 func (m *Manager) LPush(key string, values ...interface{}) error {
-	return m.client.LPush(m.ctx, key, values...).Err()
+	return m.client.LPush(m.ctx, m.Key(key), values...).Err()
 }
 
 // RPush 从右侧推入列表
 func (m *Manager) RPush(key string, values ...interface{}) error {
-	return m.client.RPush(m.ctx, key, values...).Err()
+	return m.client.RPush(m.ctx, m.Key(key), values...).Err()
 }
 
 // LPop 从左侧弹出列表元素
 func (m *Manager) LPop(key string) (string, error) {
-	return m.client.LPop(m.ctx, key).Result()
+	return m.client.LPop(m.ctx, m.Key(key)).Result()
 }
 
 // RPop 从右侧弹出列表元素
 func (m *Manager) RPop(key string) (string, error) {
-	return m.client.RPop(m.ctx, key).Result()
+	return m.client.RPop(m.ctx, m.Key(key)).Result()
 }
 
 // LLen 获取列表长度
 func (m *Manager) LLen(key string) (int64, error) {
-	return m.client.LLen(m.ctx, key).Result()
+	return m.client.LLen(m.ctx, m.Key(key)).Result()
 }
 
 // LRange 获取列表范围
 func (m *Manager) LRange(key string, start, stop int64) ([]string, error) {
-	return m.client.LRange(m.ctx, key, start, stop).Result()
+	return m.client.LRange(m.ctx, m.Key(key), start, stop).Result()
 }
 
 // SAdd 添加集合成员
 func (m *Manager) SAdd(key string, members ...interface{}) error {
-	return m.client.SAdd(m.ctx, key, members...).Err()
+	return m.client.SAdd(m.ctx, m.Key(key), members...).Err()
 }
 
 // SMembers 获取集合所有成员
 func (m *Manager) SMembers(key string) ([]string, error) {
-	return m.client.SMembers(m.ctx, key).Result()
+	return m.client.SMembers(m.ctx, m.Key(key)).Result()
 }
 
 // SIsMember 检查是否为集合成员
 func (m *Manager) SIsMember(key string, member interface{}) (bool, error) {
-	return m.client.SIsMember(m.ctx, key, member).Result()
+	return m.client.SIsMember(m.ctx, m.Key(key), member).Result()
 }
 
 // SRem 移除集合成员
 func (m *Manager) SRem(key string, members ...interface{}) error {
-	return m.client.SRem(m.ctx, key, members...).Err()
+	return m.client.SRem(m.ctx, m.Key(key), members...).Err()
 }
 
 // SCard 获取集合成员数量
 func (m *Manager) SCard(key string) (int64, error) {
-	return m.client.SCard(m.ctx, key).Result()
+	return m.client.SCard(m.ctx, m.Key(key)).Result()
+}
+
+// ZAdd 添加有序集合成员
+func (m *Manager) ZAdd(key string, members ...redis.Z) error {
+	return m.client.ZAdd(m.ctx, m.Key(key), members...).Err()
+}
+
+// ZIncrBy 增加有序集合成员的分数
+func (m *Manager) ZIncrBy(key string, increment float64, member string) (float64, error) {
+	return m.client.ZIncrBy(m.ctx, m.Key(key), increment, member).Result()
+}
+
+// ZRem 移除有序集合成员
+func (m *Manager) ZRem(key string, members ...interface{}) error {
+	return m.client.ZRem(m.ctx, m.Key(key), members...).Err()
+}
+
+// ZScore 获取有序集合成员的分数
+func (m *Manager) ZScore(key string, member string) (float64, error) {
+	return m.client.ZScore(m.ctx, m.Key(key), member).Result()
+}
+
+// ZCard 获取有序集合成员数量
+func (m *Manager) ZCard(key string) (int64, error) {
+	return m.client.ZCard(m.ctx, m.Key(key)).Result()
+}
+
+// ZRangeByScore 按分数区间获取有序集合成员（min/max 支持 "-inf"、"+inf" 和 "(" 开区间）
+func (m *Manager) ZRangeByScore(key string, min, max string, offset, count int64) ([]string, error) {
+	return m.client.ZRangeByScore(m.ctx, m.Key(key), &redis.ZRangeBy{
+		Min:    min,
+		Max:    max,
+		Offset: offset,
+		Count:  count,
+	}).Result()
+}
+
+// ZRevRangeWithScores 按分数从高到低获取成员及分数，适用于排行榜
+func (m *Manager) ZRevRangeWithScores(key string, start, stop int64) ([]redis.Z, error) {
+	return m.client.ZRevRangeWithScores(m.ctx, m.Key(key), start, stop).Result()
+}
+
+// GeoAdd 添加地理位置
+func (m *Manager) GeoAdd(key string, locations ...*redis.GeoLocation) error {
+	return m.client.GeoAdd(m.ctx, m.Key(key), locations...).Err()
+}
+
+// GeoSearch 搜索指定范围内的成员
+func (m *Manager) GeoSearch(key string, query *redis.GeoSearchQuery) ([]string, error) {
+	return m.client.GeoSearch(m.ctx, m.Key(key), query).Result()
+}
+
+// GeoSearchLocation 搜索指定范围内的成员，并返回坐标和距离
+func (m *Manager) GeoSearchLocation(key string, query *redis.GeoSearchLocationQuery) ([]redis.GeoLocation, error) {
+	return m.client.GeoSearchLocation(m.ctx, m.Key(key), query).Result()
 }
 
-// Keys 获取匹配模式的键
+// GeoDist 获取两个成员之间的距离，unit 可选 m、km、mi、ft
+func (m *Manager) GeoDist(key string, member1, member2, unit string) (float64, error) {
+	return m.client.GeoDist(m.ctx, m.Key(key), member1, member2, unit).Result()
+}
+
+// Keys 获取匹配模式的键（返回的键不含前缀）
+//
+// Deprecated: KEYS 会遍历整个键空间并阻塞Redis，不应在生产环境的请求路径中使用；会话查询和统计已改用索引，
+// 按模式遍历键请使用基于 SCAN 的 DeleteByPattern 或 SessionManager.RebuildIndexes
 func (m *Manager) Keys(pattern string) ([]string, error) {
-	return m.client.Keys(m.ctx, pattern).Result()
+	keys, err := m.client.Keys(m.ctx, m.patternKey(pattern)).Result()
+	if err != nil || m.prefix == "" {
+		return keys, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, m.prefix)
+	}
+	return keys, nil
 }
 
-// FlushDB 清空当前数据库
+// FlushDB 清空当前数据库，设置了键前缀时只通过 SCAN 删除本前缀下的键，不影响共用数据库的其他服务
 func (m *Manager) FlushDB() error {
+	if m.prefix != "" {
+		_, err := m.DeleteByPattern("*", false)
+		return err
+	}
 	return m.client.FlushDB(m.ctx).Err()
 }
 
-// FlushAll 清空所有数据库
+// FlushAll 清空所有数据库，设置了键前缀时返回 ErrPrefixedFlushAll
 func (m *Manager) FlushAll() error {
+	if m.prefix != "" {
+		return ErrPrefixedFlushAll
+	}
 	return m.client.FlushAll(m.ctx).Err()
 }
 
@@ -250,8 +436,8 @@ func (m *Manager) GetStats() map[string]interface{} {
 	}
 	
 	poolStats := m.client.PoolStats()
-	
-	return map[string]interface{}{
+
+	stats := map[string]interface{}{
 		"pool_hits":         poolStats.Hits,
 		"pool_misses":       poolStats.Misses,
 		"pool_timeouts":     poolStats.Timeouts,
@@ -260,4 +446,14 @@ func (m *Manager) GetStats() map[string]interface{} {
 		"pool_stale_conns":  poolStats.StaleConns,
 		"redis_info":        info.Val(),
 	}
-}
\ No newline at end of file
+
+	// 命中率与命令延迟
+	if m.hook != nil {
+		for k, v := range m.hook.stats() {
+			stats[k] = v
+		}
+	}
+	stats["command_latency"] = metrics.Default.Snapshot()["hwhkit_cache_command_duration_seconds"]
+
+	return stats
+}
@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// readCommands 统计命中/未命中的读命令
+var readCommands = map[string]bool{
+	"get":    true,
+	"getex":  true,
+	"getdel": true,
+	"hget":   true,
+}
+
+var (
+	cacheHits = metrics.Default.Counter("hwhkit_cache_hits_total",
+		"Total number of cache hits")
+	cacheMisses = metrics.Default.Counter("hwhkit_cache_misses_total",
+		"Total number of cache misses")
+	cacheErrors = metrics.Default.Counter("hwhkit_cache_errors_total",
+		"Total number of failed cache commands", "command")
+	cacheDuration = metrics.Default.Histogram("hwhkit_cache_command_duration_seconds",
+		"Cache command latency in seconds", nil, "command")
+
+	sessionsCreated = metrics.Default.Counter("hwhkit_sessions_created_total",
+		"Total number of sessions created", "prefix")
+	sessionsExpired = metrics.Default.Counter("hwhkit_sessions_expired_total",
+		"Total number of expired sessions removed", "prefix")
+	sessionsEvicted = metrics.Default.Counter("hwhkit_sessions_evicted_total",
+		"Total number of sessions evicted by the concurrent session limit", "prefix")
+	sessionsActive = metrics.Default.Gauge("hwhkit_sessions_active",
+		"Number of active sessions at the last count", "prefix")
+
+	pubsubPublished = metrics.Default.Counter("hwhkit_pubsub_published_total",
+		"Total number of messages published", "channel")
+	pubsubReceived = metrics.Default.Counter("hwhkit_pubsub_received_total",
+		"Total number of messages handled by subscribers", "channel", "status")
+	pubsubReconnects = metrics.Default.Counter("hwhkit_pubsub_reconnects_total",
+		"Total number of subscription receive errors followed by a reconnect")
+)
+
+// maxTrackedChannels 发布订阅指标统计的最大频道数，超出后新频道归入 otherChannel，避免按用户或租户动态生成的频道名让标签无限增长
+const maxTrackedChannels = 100
+
+// otherChannel 超出 maxTrackedChannels 的频道共用的指标标签
+const otherChannel = "other"
+
+// trackedChannels 已分配指标标签的频道（不含键前缀），所有 Manager 和 PubSub 共用
+var trackedChannels = struct {
+	mu       sync.Mutex
+	channels map[string]struct{}
+}{channels: make(map[string]struct{})}
+
+// channelLabel 返回频道的指标标签，频道数达到上限后未见过的频道返回 otherChannel
+func channelLabel(channel string) string {
+	trackedChannels.mu.Lock()
+	defer trackedChannels.mu.Unlock()
+
+	if _, exists := trackedChannels.channels[channel]; exists {
+		return channel
+	}
+	if len(trackedChannels.channels) >= maxTrackedChannels {
+		return otherChannel
+	}
+	trackedChannels.channels[channel] = struct{}{}
+	return channel
+}
+
+// metricsHook Redis命令指标采集钩子
+type metricsHook struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+	errors atomic.Int64
+}
+
+// DialHook 实现 redis.Hook 接口
+func (h *metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook 实现 redis.Hook 接口，记录单条命令的延迟和结果
+func (h *metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		cacheDuration.ObserveSince(start, cmd.Name())
+		h.record(cmd)
+		return err
+	}
+}
+
+// ProcessPipelineHook 实现 redis.Hook 接口，管道整体计时，逐条统计结果
+func (h *metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		cacheDuration.ObserveSince(start, "pipeline")
+		for _, cmd := range cmds {
+			h.record(cmd)
+		}
+		return err
+	}
+}
+
+// record 根据命令结果记录命中、未命中和错误
+func (h *metricsHook) record(cmd redis.Cmder) {
+	err := cmd.Err()
+	switch {
+	case errors.Is(err, redis.Nil):
+		if readCommands[cmd.Name()] {
+			h.misses.Add(1)
+			cacheMisses.Inc()
+		}
+	case err != nil:
+		h.errors.Add(1)
+		cacheErrors.Inc(cmd.Name())
+	default:
+		if readCommands[cmd.Name()] {
+			h.hits.Add(1)
+			cacheHits.Inc()
+		}
+	}
+}
+
+// stats 获取当前管理器的命中统计
+func (h *metricsHook) stats() map[string]interface{} {
+	hits := h.hits.Load()
+	misses := h.misses.Load()
+
+	var hitRatio float64
+	if total := hits + misses; total > 0 {
+		hitRatio = float64(hits) / float64(total)
+	}
+
+	return map[string]interface{}{
+		"hits":      hits,
+		"misses":    misses,
+		"errors":    h.errors.Load(),
+		"hit_ratio": hitRatio,
+	}
+}
+
+// timingHook 将Redis命令耗时累加到请求上下文的 cache 耗时分段
+type timingHook struct{}
+
+// EnableTiming 注册请求耗时分段钩子，通过 WithContext 传入带 metrics.Timings 的上下文时，
+// 命令耗时计入 Server-Timing 的 cache 分段
+func (m *Manager) EnableTiming() {
+	m.client.AddHook(timingHook{})
+}
+
+// DialHook 实现 redis.Hook 接口
+func (timingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook 实现 redis.Hook 接口
+func (timingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		timings := metrics.TimingsFromContext(ctx)
+		if timings == nil {
+			return next(ctx, cmd)
+		}
+		start := time.Now()
+		err := next(ctx, cmd)
+		timings.Add(metrics.TimingCache, time.Since(start))
+		return err
+	}
+}
+
+// ProcessPipelineHook 实现 redis.Hook 接口，管道整体计为一次
+func (timingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		timings := metrics.TimingsFromContext(ctx)
+		if timings == nil {
+			return next(ctx, cmds)
+		}
+		start := time.Now()
+		err := next(ctx, cmds)
+		timings.Add(metrics.TimingCache, time.Since(start))
+		return err
+	}
+}
@@ -0,0 +1,276 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrPubSubClosed 发布订阅已关闭
+var ErrPubSubClosed = errors.New("pubsub closed")
+
+// Message 订阅收到的消息
+type Message struct {
+	Channel string // 不含键前缀的频道名
+	Payload []byte
+}
+
+// Decode 将JSON消息体解码到 v
+func (m *Message) Decode(v interface{}) error {
+	return json.Unmarshal(m.Payload, v)
+}
+
+// MessageHandler 消息处理函数
+type MessageHandler func(ctx context.Context, msg *Message) error
+
+// Publish 以JSON编码发布消息，频道名添加键前缀，返回收到消息的订阅者数量
+func (m *Manager) Publish(channel string, payload interface{}) (int64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode message: %w", err)
+	}
+	receivers, err := m.client.Publish(m.ctx, m.Key(channel), data).Result()
+	if err != nil {
+		return 0, err
+	}
+	pubsubPublished.Inc(channelLabel(channel))
+	return receivers, nil
+}
+
+// PubSub 基于Redis的发布订阅，按频道注册处理函数，连接断开时自动重连并重新订阅
+// Redis Pub/Sub 不持久化消息，断线期间发布的消息会丢失，需要可靠投递时应使用队列
+//
+//	ps := cache.NewPubSub(cacheManager)
+//	cache.Subscribe(ps, "user.updated", func(ctx context.Context, event UserUpdated) error { ... })
+//	ps.Start()
+//	srv.OnShutdown(ps.Shutdown)
+type PubSub struct {
+	cache *Manager
+
+	mu       sync.Mutex
+	handlers map[string][]MessageHandler // 键为添加前缀后的频道名
+	sub      *redis.PubSub
+	started  bool
+	closed   bool
+
+	ctx    context.Context // 传给处理函数，关闭超时后取消
+	cancel context.CancelFunc
+	stop   chan struct{}
+	done   chan struct{}
+
+	reconnectDelay    time.Duration
+	maxReconnectDelay time.Duration
+	onError           func(channel string, err error)
+}
+
+// NewPubSub 创建发布订阅，默认重连间隔从100毫秒开始指数增长，最长5秒
+func NewPubSub(cache *Manager) *PubSub {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &PubSub{
+		cache:             cache,
+		handlers:          make(map[string][]MessageHandler),
+		ctx:               ctx,
+		cancel:            cancel,
+		stop:              make(chan struct{}),
+		done:              make(chan struct{}),
+		reconnectDelay:    100 * time.Millisecond,
+		maxReconnectDelay: 5 * time.Second,
+	}
+}
+
+// SetReconnectDelay 设置接收失败后的重连间隔，从 min 开始每次翻倍，最长 max
+func (p *PubSub) SetReconnectDelay(min, max time.Duration) {
+	if min > 0 {
+		p.reconnectDelay = min
+	}
+	if max >= p.reconnectDelay {
+		p.maxReconnectDelay = max
+	}
+}
+
+// OnError 设置错误回调：处理函数返回错误或panic时 channel 为对应频道，连接错误时 channel 为空
+func (p *PubSub) OnError(handler func(channel string, err error)) {
+	p.onError = handler
+}
+
+// Publish 以JSON编码发布消息
+func (p *PubSub) Publish(ctx context.Context, channel string, payload interface{}) (int64, error) {
+	return p.cache.WithContext(ctx).Publish(channel, payload)
+}
+
+// Handle 注册频道处理函数，同一频道可注册多个，按注册顺序执行；启动后注册的频道立即订阅
+func (p *PubSub) Handle(channel string, handler MessageHandler) error {
+	key := p.cache.Key(channel)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrPubSubClosed
+	}
+	_, subscribed := p.handlers[key]
+	p.handlers[key] = append(p.handlers[key], handler)
+	if p.started && !subscribed {
+		if err := p.sub.Subscribe(p.ctx, key); err != nil {
+			return fmt.Errorf("failed to subscribe %s: %w", channel, err)
+		}
+	}
+	return nil
+}
+
+// Subscribe 注册类型化的频道处理函数，消息体按JSON解码为 T
+func Subscribe[T any](p *PubSub, channel string, handler func(ctx context.Context, payload T) error) error {
+	return p.Handle(channel, func(ctx context.Context, msg *Message) error {
+		var payload T
+		if err := msg.Decode(&payload); err != nil {
+			return fmt.Errorf("failed to decode message: %w", err)
+		}
+		return handler(ctx, payload)
+	})
+}
+
+// Unsubscribe 取消订阅频道并移除其处理函数
+func (p *PubSub) Unsubscribe(channel string) error {
+	key := p.cache.Key(channel)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.handlers[key]; !exists {
+		return nil
+	}
+	delete(p.handlers, key)
+	if p.started && !p.closed {
+		return p.sub.Unsubscribe(p.ctx, key)
+	}
+	return nil
+}
+
+// Start 订阅已注册的频道并在后台接收消息，同一频道的消息按顺序处理
+func (p *PubSub) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrPubSubClosed
+	}
+	if p.started {
+		return nil
+	}
+
+	channels := make([]string, 0, len(p.handlers))
+	for key := range p.handlers {
+		channels = append(channels, key)
+	}
+	p.sub = p.cache.client.Subscribe(p.ctx, channels...)
+	if len(channels) > 0 {
+		// 等待订阅确认，尽早发现连接错误
+		if _, err := p.sub.Receive(p.ctx); err != nil {
+			p.sub.Close()
+			return fmt.Errorf("failed to subscribe: %w", err)
+		}
+	}
+	p.started = true
+	go p.run(p.sub)
+	return nil
+}
+
+// Shutdown 停止接收新消息并等待正在执行的处理函数返回，ctx 结束时取消处理函数的 ctx 并返回
+func (p *PubSub) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	started := p.started
+	close(p.stop)
+	p.mu.Unlock()
+
+	if !started {
+		p.cancel()
+		return nil
+	}
+	// 关闭连接以中断阻塞中的接收
+	p.sub.Close()
+	select {
+	case <-p.done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
+
+// run 接收并分发消息，接收失败时按退避间隔重试，go-redis 会在重连后重新订阅所有频道
+func (p *PubSub) run(sub *redis.PubSub) {
+	defer close(p.done)
+
+	delay := p.reconnectDelay
+	for {
+		msg, err := sub.ReceiveMessage(p.ctx)
+		if err != nil {
+			select {
+			case <-p.stop:
+				return
+			default:
+			}
+			pubsubReconnects.Inc()
+			p.reportError("", fmt.Errorf("pubsub receive failed: %w", err))
+
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > p.maxReconnectDelay {
+				delay = p.maxReconnectDelay
+			}
+			continue
+		}
+		delay = p.reconnectDelay
+		p.dispatch(msg)
+	}
+}
+
+// dispatch 依次调用频道的处理函数
+func (p *PubSub) dispatch(msg *redis.Message) {
+	p.mu.Lock()
+	handlers := append([]MessageHandler(nil), p.handlers[msg.Channel]...)
+	p.mu.Unlock()
+
+	message := &Message{
+		Channel: strings.TrimPrefix(msg.Channel, p.cache.Prefix()),
+		Payload: []byte(msg.Payload),
+	}
+	label := channelLabel(message.Channel)
+	for _, handler := range handlers {
+		status := "ok"
+		if err := p.invoke(handler, message); err != nil {
+			status = "error"
+			p.reportError(message.Channel, err)
+		}
+		pubsubReceived.Inc(label, status)
+	}
+}
+
+// invoke 调用处理函数，panic 转为错误
+func (p *PubSub) invoke(handler MessageHandler, msg *Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("pubsub handler panic: %v", r)
+		}
+	}()
+	return handler(p.ctx, msg)
+}
+
+// reportError 调用错误回调
+func (p *PubSub) reportError(channel string, err error) {
+	if p.onError != nil {
+		p.onError(channel, err)
+	}
+}
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnknownQuotaPlan 未注册的配额套餐
+var ErrUnknownQuotaPlan = errors.New("unknown quota plan")
+
+// QuotaPlan 配额套餐，为0的项不限制
+type QuotaPlan struct {
+	Name    string `json:"name"`
+	Daily   int64  `json:"daily"`
+	Monthly int64  `json:"monthly"`
+}
+
+// QuotaUsage 配额使用情况，窗口按UTC自然日和自然月计算
+type QuotaUsage struct {
+	Key          string    `json:"key"`
+	Plan         string    `json:"plan"`
+	DailyUsed    int64     `json:"daily_used"`
+	DailyLimit   int64     `json:"daily_limit"`
+	DailyReset   time.Time `json:"daily_reset"`
+	MonthlyUsed  int64     `json:"monthly_used"`
+	MonthlyLimit int64     `json:"monthly_limit"`
+	MonthlyReset time.Time `json:"monthly_reset"`
+}
+
+// DailyRemaining 当日剩余配额，不限制时返回-1
+func (u *QuotaUsage) DailyRemaining() int64 {
+	return remaining(u.DailyLimit, u.DailyUsed)
+}
+
+// MonthlyRemaining 当月剩余配额，不限制时返回-1
+func (u *QuotaUsage) MonthlyRemaining() int64 {
+	return remaining(u.MonthlyLimit, u.MonthlyUsed)
+}
+
+// Exceeded 是否超出当日或当月配额
+func (u *QuotaUsage) Exceeded() bool {
+	return (u.DailyLimit > 0 && u.DailyUsed > u.DailyLimit) ||
+		(u.MonthlyLimit > 0 && u.MonthlyUsed > u.MonthlyLimit)
+}
+
+// remaining 计算剩余配额
+func remaining(limit, used int64) int64 {
+	if limit <= 0 {
+		return -1
+	}
+	if used >= limit {
+		return 0
+	}
+	return limit - used
+}
+
+// QuotaManager 基于Redis的配额管理器，按套餐跟踪每个键（用户、API Key等）的日/月用量
+type QuotaManager struct {
+	cache  *Manager
+	prefix string
+	plans  map[string]QuotaPlan
+	now    func() time.Time
+}
+
+// NewQuotaManager 创建配额管理器
+func NewQuotaManager(cache *Manager, prefix string, plans ...QuotaPlan) *QuotaManager {
+	if prefix == "" {
+		prefix = "quota"
+	}
+
+	qm := &QuotaManager{
+		cache:  cache,
+		prefix: prefix,
+		plans:  make(map[string]QuotaPlan, len(plans)),
+		now:    time.Now,
+	}
+	for _, plan := range plans {
+		qm.plans[plan.Name] = plan
+	}
+	return qm
+}
+
+// Plan 获取套餐
+func (qm *QuotaManager) Plan(name string) (QuotaPlan, bool) {
+	plan, ok := qm.plans[name]
+	return plan, ok
+}
+
+// Consume 记录一次请求并返回最新用量，超出配额时仍会计数，由调用方决定是否拒绝
+func (qm *QuotaManager) Consume(key, planName string) (*QuotaUsage, error) {
+	return qm.usage(key, planName, 1)
+}
+
+// Usage 查询用量，不计数
+func (qm *QuotaManager) Usage(key, planName string) (*QuotaUsage, error) {
+	return qm.usage(key, planName, 0)
+}
+
+// Reset 清除键在当前窗口内的用量
+func (qm *QuotaManager) Reset(key string) error {
+	dailyKey, monthlyKey := qm.keys(key, qm.now().UTC())
+	if err := qm.cache.Delete(dailyKey, monthlyKey); err != nil {
+		return fmt.Errorf("failed to reset quota: %w", err)
+	}
+	return nil
+}
+
+// usage 按增量更新并读取用量
+func (qm *QuotaManager) usage(key, planName string, delta int64) (*QuotaUsage, error) {
+	plan, ok := qm.plans[planName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownQuotaPlan, planName)
+	}
+
+	now := qm.now().UTC()
+	dailyReset := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	monthlyReset := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	dailyKey, monthlyKey := qm.keys(key, now)
+
+	pipe := qm.cache.Pipeline()
+	daily := pipe.IncrBy(qm.cache.ctx, qm.cache.Key(dailyKey), delta)
+	monthly := pipe.IncrBy(qm.cache.ctx, qm.cache.Key(monthlyKey), delta)
+	if delta > 0 {
+		// 保留一小时余量，避免窗口切换时的时钟偏差
+		pipe.ExpireAt(qm.cache.ctx, qm.cache.Key(dailyKey), dailyReset.Add(time.Hour))
+		pipe.ExpireAt(qm.cache.ctx, qm.cache.Key(monthlyKey), monthlyReset.Add(time.Hour))
+	}
+	if _, err := pipe.Exec(qm.cache.ctx); err != nil {
+		return nil, fmt.Errorf("failed to update quota usage: %w", err)
+	}
+
+	return &QuotaUsage{
+		Key:          key,
+		Plan:         plan.Name,
+		DailyUsed:    daily.Val(),
+		DailyLimit:   plan.Daily,
+		DailyReset:   dailyReset,
+		MonthlyUsed:  monthly.Val(),
+		MonthlyLimit: plan.Monthly,
+		MonthlyReset: monthlyReset,
+	}, nil
+}
+
+// keys 获取当前日/月窗口的Redis键
+func (qm *QuotaManager) keys(key string, now time.Time) (daily, monthly string) {
+	return fmt.Sprintf("%s:%s:d:%s", qm.prefix, key, now.Format("20060102")),
+		fmt.Sprintf("%s:%s:m:%s", qm.prefix, key, now.Format("200601"))
+}
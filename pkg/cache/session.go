@@ -4,19 +4,46 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 // SessionManager 会话管理器
+// 会话键为 prefix:ID，另外维护两个以会话过期时间为分值的有序集合索引：prefix_expiry（全部会话）和
+// prefix_user:用户ID（用户的会话），以及有会话的用户集合 prefix_users，用户会话查询、过期清理和统计不再扫描键空间
 type SessionManager struct {
 	cache      *Manager
 	prefix     string
 	expiration time.Duration
+	
+	maxSessions   int                  // 每个用户最多同时存在的会话数，0为不限制
+	limitStrategy SessionLimitStrategy // 超出限制时的处理策略
+	onEvicted     func(session *Session)
+	
+	idleTimeout     time.Duration // 空闲超时，访问会话时顺延，0时使用 expiration 且只在 RefreshSession 时顺延
+	absoluteTimeout time.Duration // 绝对超时，从创建时间起计算，顺延和更换ID都不会超过，0为不限制
+	rotateOnLogin   bool          // BindUser 绑定用户时自动更换会话ID
 }
 
+// SessionLimitStrategy 超出并发会话限制时的处理策略
+type SessionLimitStrategy int
+
+const (
+	// SessionLimitRejectNew 拒绝创建新会话
+	SessionLimitRejectNew SessionLimitStrategy = iota
+	// SessionLimitEvictOldest 淘汰最早创建的会话
+	SessionLimitEvictOldest
+)
+
+// ErrSessionLimitExceeded 用户会话数已达上限
+var ErrSessionLimitExceeded = errors.New("session limit exceeded")
+
 // Session 会话数据结构
 type Session struct {
 	ID        string                 `json:"id"`
@@ -25,9 +52,13 @@ type Session struct {
 	CreatedAt time.Time              `json:"created_at"`
 	UpdatedAt time.Time              `json:"updated_at"`
 	ExpiresAt time.Time              `json:"expires_at"`
+	Flash     map[string]interface{} `json:"flash,omitempty"`  // 读取一次后即清除的数据
+	Device    string                 `json:"device,omitempty"` // 绑定的设备指纹
+
+	snapshot string // 加载或保存时的数据快照，用于判断会话是否被修改
 }
 
-// NewSessionManager 创建会话管理器
+// NewSessionManager 创建会话管理器，expiration 为会话有效期（默认24小时），SetIdleTimeout、SetAbsoluteTimeout 可改为空闲超时和绝对超时
 func NewSessionManager(cache *Manager, prefix string, expiration time.Duration) *SessionManager {
 	if prefix == "" {
 		prefix = "session"
@@ -43,8 +74,40 @@ func NewSessionManager(cache *Manager, prefix string, expiration time.Duration)
 	}
 }
 
+// SetSessionLimit 设置每个用户的最大并发会话数（如3台设备），max 为0时不限制
+func (sm *SessionManager) SetSessionLimit(max int, strategy SessionLimitStrategy) {
+	sm.maxSessions = max
+	sm.limitStrategy = strategy
+}
+
+// SetIdleTimeout 设置空闲超时：会话在 d 时间内没有访问即过期，GetSession 在剩余时间不足一半时顺延，d 为0时恢复为固定的 expiration
+func (sm *SessionManager) SetIdleTimeout(d time.Duration) {
+	sm.idleTimeout = d
+}
+
+// SetAbsoluteTimeout 设置绝对超时：会话自创建起最长存活 d，空闲顺延、RefreshSession 和 RotateSession 都不会延长，d 为0时不限制
+func (sm *SessionManager) SetAbsoluteTimeout(d time.Duration) {
+	sm.absoluteTimeout = d
+}
+
+// SetRotateOnLogin 设置 BindUser 将会话绑定到用户（登录、切换用户）时是否自动更换会话ID，防止会话固定攻击
+// 开启后 BindUser 返回的会话ID与传入的不同，调用方需要下发新的会话ID
+func (sm *SessionManager) SetRotateOnLogin(enabled bool) {
+	sm.rotateOnLogin = enabled
+}
+
+// OnSessionEvicted 设置会话因超出并发限制被淘汰时的回调，可用于通知用户或记录审计日志
+func (sm *SessionManager) OnSessionEvicted(handler func(session *Session)) {
+	sm.onEvicted = handler
+}
+
 // CreateSession 创建新会话
+// 设置了并发会话限制时，超出限制按策略拒绝（返回 ErrSessionLimitExceeded）或淘汰最早的会话
 func (sm *SessionManager) CreateSession(userID string) (*Session, error) {
+	if err := sm.enforceSessionLimit(userID, ""); err != nil {
+		return nil, err
+	}
+	
 	sessionID, err := sm.generateSessionID()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate session ID: %w", err)
@@ -57,12 +120,13 @@ func (sm *SessionManager) CreateSession(userID string) (*Session, error) {
 		Data:      make(map[string]interface{}),
 		CreatedAt: now,
 		UpdatedAt: now,
-		ExpiresAt: now.Add(sm.expiration),
+		ExpiresAt: sm.nextExpiry(now, now),
 	}
 	
 	if err := sm.saveSession(session); err != nil {
 		return nil, fmt.Errorf("failed to save session: %w", err)
 	}
+	sessionsCreated.Inc(sm.prefix)
 	
 	return session, nil
 }
@@ -79,38 +143,161 @@ func (sm *SessionManager) GetSession(sessionID string) (*Session, error) {
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 	
-	// 检查会话是否过期
-	if time.Now().After(session.ExpiresAt) {
+	// 检查会话是否过期（空闲超时或绝对超时）
+	now := time.Now()
+	if now.After(session.ExpiresAt) || sm.absoluteExpired(&session, now) {
 		sm.DeleteSession(sessionID) // 删除过期会话
+		sessionsExpired.Inc(sm.prefix)
 		return nil, fmt.Errorf("session expired")
 	}
 	
+	session.markClean()
+	
+	// 设置了空闲超时时，剩余时间不足一半才顺延，避免每次读取都写入Redis
+	if sm.idleTimeout > 0 && session.ExpiresAt.Sub(now) < sm.idleTimeout/2 {
+		if expiresAt := sm.nextExpiry(session.CreatedAt, now); expiresAt.After(session.ExpiresAt) {
+			session.ExpiresAt = expiresAt
+			if err := sm.saveSession(&session); err != nil {
+				return nil, err
+			}
+		}
+	}
 	return &session, nil
 }
 
-// UpdateSession 更新会话
+// BindUser 将匿名会话绑定到用户，并执行并发会话限制
+// 设置了 SetRotateOnLogin 时先更换会话ID再绑定用户，返回的会话为新ID；更换失败时返回错误，登录前的会话ID不会被认证
+func (sm *SessionManager) BindUser(sessionID, userID string) (*Session, error) {
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID == userID {
+		return session, nil
+	}
+	
+	if err := sm.enforceSessionLimit(userID, sessionID); err != nil {
+		return nil, err
+	}
+	if sm.rotateOnLogin {
+		if session, err = sm.RotateSession(sessionID); err != nil {
+			return nil, err
+		}
+	}
+	if session.UserID != "" {
+		sm.cache.ZRem(sm.getUserIndexKey(session.UserID), session.ID)
+	}
+	
+	session.UserID = userID
+	if err := sm.UpdateSession(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// UpdateSession 更新会话，会话数据未修改时不写入Redis
 func (sm *SessionManager) UpdateSession(session *Session) error {
+	if !session.IsModified() {
+		return nil
+	}
 	session.UpdatedAt = time.Now()
 	return sm.saveSession(session)
 }
 
-// DeleteSession 删除会话
+// DeleteSession 删除会话并从索引中移除
 func (sm *SessionManager) DeleteSession(sessionID string) error {
 	key := sm.getSessionKey(sessionID)
+	
+	var session Session
+	if err := sm.cache.GetJSON(key, &session); err == nil && session.UserID != "" {
+		sm.cache.ZRem(sm.getUserIndexKey(session.UserID), sessionID)
+	}
+	if err := sm.cache.ZRem(sm.getExpiryIndexKey(), sessionID); err != nil {
+		return fmt.Errorf("failed to update session expiry index: %w", err)
+	}
 	return sm.cache.Delete(key)
 }
 
-// RefreshSession 刷新会话过期时间
+// RefreshSession 刷新会话过期时间，不超过绝对超时
 func (sm *SessionManager) RefreshSession(sessionID string) error {
 	session, err := sm.GetSession(sessionID)
 	if err != nil {
 		return err
 	}
 	
-	session.ExpiresAt = time.Now().Add(sm.expiration)
+	session.ExpiresAt = sm.nextExpiry(session.CreatedAt, time.Now())
 	return sm.UpdateSession(session)
 }
 
+// RegenerateID 为会话生成新ID并使旧ID失效，是 RotateSession 的简写
+func (sm *SessionManager) RegenerateID(sessionID string) (*Session, error) {
+	return sm.RotateSession(sessionID)
+}
+
+// RotateSession 将会话数据复制到新ID并使旧ID失效，整个过程是原子的
+// 登录、提升权限等权限变更时调用，防止会话固定攻击；新会话保留原创建时间，绝对超时不会因更换ID而重置
+func (sm *SessionManager) RotateSession(sessionID string) (*Session, error) {
+	newID, err := sm.generateSessionID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	
+	ctx := sm.cache.ctx
+	oldKey := sm.cache.Key(sm.getSessionKey(sessionID))
+	newKey := sm.cache.Key(sm.getSessionKey(newID))
+	
+	var session Session
+	err = sm.cache.client.Watch(ctx, func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, oldKey).Bytes()
+		if err != nil {
+			if err == redis.Nil {
+				return fmt.Errorf("session not found")
+			}
+			return fmt.Errorf("failed to get session: %w", err)
+		}
+		if err := json.Unmarshal(data, &session); err != nil {
+			return fmt.Errorf("failed to unmarshal session: %w", err)
+		}
+		now := time.Now()
+		if now.After(session.ExpiresAt) || sm.absoluteExpired(&session, now) {
+			return fmt.Errorf("session expired")
+		}
+		
+		session.ID = newID
+		session.UpdatedAt = now
+		sessionData, err := json.Marshal(&session)
+		if err != nil {
+			return fmt.Errorf("failed to marshal session: %w", err)
+		}
+		
+		// 旧会话在读取后被修改时事务失败，避免丢失并发写入
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, newKey, sessionData, sm.keyTTL(&session))
+			pipe.Del(ctx, oldKey)
+			score := sessionScore(session.ExpiresAt)
+			expiryKey := sm.cache.Key(sm.getExpiryIndexKey())
+			pipe.ZRem(ctx, expiryKey, sessionID)
+			pipe.ZAdd(ctx, expiryKey, redis.Z{Score: score, Member: newID})
+			if session.UserID != "" {
+				indexKey := sm.cache.Key(sm.getUserIndexKey(session.UserID))
+				pipe.ZRem(ctx, indexKey, sessionID)
+				pipe.ZAdd(ctx, indexKey, redis.Z{Score: score, Member: newID})
+			}
+			return nil
+		})
+		return err
+	}, oldKey)
+	if err != nil {
+		if err == redis.TxFailedErr {
+			return nil, fmt.Errorf("session modified concurrently, retry rotation")
+		}
+		return nil, err
+	}
+	
+	session.markClean()
+	return &session, nil
+}
+
 // SetSessionData 设置会话数据
 func (sm *SessionManager) SetSessionData(sessionID string, key string, value interface{}) error {
 	session, err := sm.GetSession(sessionID)
@@ -148,26 +335,46 @@ func (sm *SessionManager) RemoveSessionData(sessionID string, key string) error
 	return sm.UpdateSession(session)
 }
 
-// GetUserSessions 获取用户的所有会话
+// GetUserSessions 获取用户的所有会话，按创建时间升序
+// 基于用户会话索引查询，只读取该用户的会话，同时清理索引中已过期或已删除的会话
 func (sm *SessionManager) GetUserSessions(userID string) ([]*Session, error) {
-	pattern := sm.prefix + ":*"
-	keys, err := sm.cache.Keys(pattern)
+	indexKey := sm.getUserIndexKey(userID)
+	now := time.Now()
+	if err := sm.cache.client.ZRemRangeByScore(sm.cache.ctx, sm.cache.Key(indexKey), "-inf", "("+scoreString(now)).Err(); err != nil {
+		return nil, fmt.Errorf("failed to prune user session index: %w", err)
+	}
+	sessionIDs, err := sm.cache.client.ZRange(sm.cache.ctx, sm.cache.Key(indexKey), 0, -1).Result()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get session keys: %w", err)
+		return nil, fmt.Errorf("failed to get user session index: %w", err)
 	}
 	
 	var userSessions []*Session
-	for _, key := range keys {
+	var stale []interface{}
+	for _, sessionID := range sessionIDs {
 		var session Session
-		if err := sm.cache.GetJSON(key, &session); err != nil {
+		if err := sm.cache.GetJSON(sm.getSessionKey(sessionID), &session); err != nil {
+			if err == redis.Nil {
+				stale = append(stale, sessionID)
+			}
 			continue // 跳过无法解析的会话
 		}
 		
-		if session.UserID == userID && time.Now().Before(session.ExpiresAt) {
-			userSessions = append(userSessions, &session)
+		if session.UserID != userID || now.After(session.ExpiresAt) {
+			stale = append(stale, sessionID)
+			continue
 		}
+		session.markClean()
+		userSessions = append(userSessions, &session)
+	}
+	
+	if len(stale) > 0 {
+		sm.cache.ZRem(indexKey, stale...)
 	}
 	
+	// 索引按过期时间排序，刷新过的会话需按创建时间重新排序
+	sort.SliceStable(userSessions, func(i, j int) bool {
+		return userSessions[i].CreatedAt.Before(userSessions[j].CreatedAt)
+	})
 	return userSessions, nil
 }
 
@@ -187,53 +394,101 @@ func (sm *SessionManager) DeleteUserSessions(userID string) error {
 	return nil
 }
 
-// CleanExpiredSessions 清理过期会话
+// CleanExpiredSessions 按过期索引分批删除已过期的会话，只访问已过期的会话，并将没有活跃会话的用户移出用户集合
+// 需要定期调用（server.Server 启动后每5分钟执行一次），否则已过期会话的索引只在写入时按 indexTTL 裁剪
+// 清理完成后刷新 hwhkit_sessions_active 指标
 func (sm *SessionManager) CleanExpiredSessions() error {
-	pattern := sm.prefix + ":*"
-	keys, err := sm.cache.Keys(pattern)
-	if err != nil {
-		return fmt.Errorf("failed to get session keys: %w", err)
-	}
-	
-	now := time.Now()
-	for _, key := range keys {
-		var session Session
-		if err := sm.cache.GetJSON(key, &session); err != nil {
-			continue
+	max := scoreString(time.Now())
+	for {
+		sessionIDs, err := sm.cache.ZRangeByScore(sm.getExpiryIndexKey(), "-inf", max, 0, scanBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to get expired sessions: %w", err)
 		}
 		
-		if now.After(session.ExpiresAt) {
-			sessionID := sm.extractSessionID(key)
-			sm.DeleteSession(sessionID)
+		for _, sessionID := range sessionIDs {
+			if err := sm.DeleteSession(sessionID); err != nil {
+				return fmt.Errorf("failed to delete session %s: %w", sessionID, err)
+			}
+			sessionsExpired.Inc(sm.prefix)
+		}
+		
+		if len(sessionIDs) < scanBatchSize {
+			if _, err := sm.GetSessionCount(); err != nil {
+				return err
+			}
+			return sm.pruneSessionUsers()
+		}
+	}
+}
+
+// pruneSessionUsers 使用 SSCAN 分批检查用户集合，移除用户会话索引中已没有活跃会话的用户
+func (sm *SessionManager) pruneSessionUsers() error {
+	ctx := sm.cache.ctx
+	usersKey := sm.cache.Key(sm.getUsersKey())
+	now := "(" + scoreString(time.Now())
+	var cursor uint64
+	for {
+		userIDs, next, err := sm.cache.client.SScan(ctx, usersKey, cursor, "", scanBatchSize).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan session users: %w", err)
+		}
+		counts := make([]*redis.IntCmd, len(userIDs))
+		if _, err := sm.cache.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, userID := range userIDs {
+				counts[i] = pipe.ZCount(ctx, sm.cache.Key(sm.getUserIndexKey(userID)), now, "+inf")
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("failed to count user sessions: %w", err)
+		}
+		var inactive []interface{}
+		for i, userID := range userIDs {
+			if counts[i].Val() == 0 {
+				inactive = append(inactive, userID)
+			}
+		}
+		if len(inactive) > 0 {
+			if err := sm.cache.client.SRem(ctx, usersKey, inactive...).Err(); err != nil {
+				return fmt.Errorf("failed to prune session users: %w", err)
+			}
+		}
+		if cursor = next; cursor == 0 {
+			return nil
 		}
 	}
-	
-	return nil
 }
 
-// GetSessionCount 获取活跃会话数量
+// GetSessionCount 获取活跃会话数量，同时更新 hwhkit_sessions_active 指标，可定时调用以刷新仪表盘
 func (sm *SessionManager) GetSessionCount() (int64, error) {
-	pattern := sm.prefix + ":*"
-	keys, err := sm.cache.Keys(pattern)
+	activeCount, err := sm.cache.client.ZCount(sm.cache.ctx, sm.cache.Key(sm.getExpiryIndexKey()), "("+scoreString(time.Now()), "+inf").Result()
 	if err != nil {
-		return 0, fmt.Errorf("failed to get session keys: %w", err)
+		return 0, fmt.Errorf("failed to count sessions: %w", err)
 	}
+	sessionsActive.Set(float64(activeCount), sm.prefix)
 	
-	var activeCount int64
-	now := time.Now()
-	
-	for _, key := range keys {
+	return activeCount, nil
+}
+
+// RebuildIndexes 使用 SCAN 遍历会话键，为未建立索引的会话（如升级前创建的会话）补充索引，返回处理的会话数
+// 只需在升级后执行一次，日常的会话查询、清理和统计都基于索引完成
+func (sm *SessionManager) RebuildIndexes() (int, error) {
+	ctx := sm.cache.ctx
+	indexed := 0
+	iter := sm.cache.client.Scan(ctx, 0, sm.cache.Key(sm.prefix+":*"), scanBatchSize).Iterator()
+	for iter.Next(ctx) {
 		var session Session
-		if err := sm.cache.GetJSON(key, &session); err != nil {
-			continue
+		if err := sm.cache.GetJSON(strings.TrimPrefix(iter.Val(), sm.cache.prefix), &session); err != nil || session.ID == "" {
+			continue // 跳过无法解析的键
 		}
-		
-		if now.Before(session.ExpiresAt) {
-			activeCount++
+		if err := sm.indexSession(&session); err != nil {
+			return indexed, err
 		}
+		indexed++
 	}
-	
-	return activeCount, nil
+	if err := iter.Err(); err != nil {
+		return indexed, fmt.Errorf("failed to scan session keys: %w", err)
+	}
+	return indexed, nil
 }
 
 // IsValidSession 检查会话是否有效
@@ -244,6 +499,45 @@ func (sm *SessionManager) IsValidSession(sessionID string) bool {
 
 // 私有方法
 
+// enforceSessionLimit 检查用户并发会话数，excludeID 为即将绑定到用户的现有会话
+func (sm *SessionManager) enforceSessionLimit(userID, excludeID string) error {
+	if sm.maxSessions <= 0 || userID == "" {
+		return nil
+	}
+	
+	sessions, err := sm.GetUserSessions(userID)
+	if err != nil {
+		return err
+	}
+	
+	active := make([]*Session, 0, len(sessions))
+	for _, session := range sessions {
+		if session.ID != excludeID {
+			active = append(active, session)
+		}
+	}
+	
+	excess := len(active) - sm.maxSessions + 1
+	if excess <= 0 {
+		return nil
+	}
+	if sm.limitStrategy != SessionLimitEvictOldest {
+		return ErrSessionLimitExceeded
+	}
+	
+	// 会话已按创建时间升序排列
+	for _, session := range active[:excess] {
+		if err := sm.DeleteSession(session.ID); err != nil {
+			return fmt.Errorf("failed to evict session %s: %w", session.ID, err)
+		}
+		sessionsEvicted.Inc(sm.prefix)
+		if sm.onEvicted != nil {
+			sm.onEvicted(session)
+		}
+	}
+	return nil
+}
+
 // generateSessionID 生成会话ID
 func (sm *SessionManager) generateSessionID() (string, error) {
 	bytes := make([]byte, 32)
@@ -258,13 +552,70 @@ func (sm *SessionManager) getSessionKey(sessionID string) string {
 	return fmt.Sprintf("%s:%s", sm.prefix, sessionID)
 }
 
-// extractSessionID 从Redis键中提取会话ID
-func (sm *SessionManager) extractSessionID(key string) string {
-	prefix := sm.prefix + ":"
-	if len(key) > len(prefix) {
-		return key[len(prefix):]
+// getUserIndexKey 获取用户会话索引的Redis键（有序集合，分值为会话过期时间）
+// 使用独立前缀，避免被 prefix:* 的会话扫描匹配
+func (sm *SessionManager) getUserIndexKey(userID string) string {
+	return fmt.Sprintf("%s_user:%s", sm.prefix, userID)
+}
+
+// getExpiryIndexKey 获取全部会话的过期索引键（有序集合，分值为会话过期时间）
+func (sm *SessionManager) getExpiryIndexKey() string {
+	return sm.prefix + "_expiry"
+}
+
+// getUsersKey 获取有会话的用户集合键
+func (sm *SessionManager) getUsersKey() string {
+	return sm.prefix + "_users"
+}
+
+// nextExpiry 计算会话在 now 访问后的过期时间：空闲超时或 expiration 之后，且不超过绝对超时
+func (sm *SessionManager) nextExpiry(createdAt, now time.Time) time.Time {
+	lifetime := sm.expiration
+	if sm.idleTimeout > 0 {
+		lifetime = sm.idleTimeout
+	}
+	expiresAt := now.Add(lifetime)
+	if sm.absoluteTimeout > 0 {
+		if deadline := createdAt.Add(sm.absoluteTimeout); expiresAt.After(deadline) {
+			expiresAt = deadline
+		}
 	}
-	return ""
+	return expiresAt
+}
+
+// absoluteExpired 会话是否已超过绝对超时
+func (sm *SessionManager) absoluteExpired(session *Session, now time.Time) bool {
+	return sm.absoluteTimeout > 0 && now.After(session.CreatedAt.Add(sm.absoluteTimeout))
+}
+
+// keyTTL 会话键的过期时间，与会话过期时间一致；已过期的会话保留 expiration，由读取或清理时删除
+func (sm *SessionManager) keyTTL(session *Session) time.Duration {
+	if ttl := time.Until(session.ExpiresAt); ttl > 0 {
+		return ttl
+	}
+	return sm.expiration
+}
+
+// indexTTL 用户会话索引的过期时间，不短于任何会话的最长存活时间
+func (sm *SessionManager) indexTTL() time.Duration {
+	ttl := sm.expiration
+	if sm.idleTimeout > ttl {
+		ttl = sm.idleTimeout
+	}
+	if sm.absoluteTimeout > ttl {
+		ttl = sm.absoluteTimeout
+	}
+	return ttl
+}
+
+// sessionScore 会话索引的分值（过期时间的毫秒时间戳）
+func sessionScore(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
+
+// scoreString 将时间转换为有序集合区间查询使用的分值
+func scoreString(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
 }
 
 // saveSession 保存会话到Redis
@@ -276,7 +627,40 @@ func (sm *SessionManager) saveSession(session *Session) error {
 		return fmt.Errorf("failed to marshal session: %w", err)
 	}
 	
-	return sm.cache.Set(key, sessionData, sm.expiration)
+	if err := sm.cache.Set(key, sessionData, sm.keyTTL(session)); err != nil {
+		return err
+	}
+	session.markClean()
+	
+	return sm.indexSession(session)
+}
+
+// indexSession 更新会话在过期索引和用户会话索引中的分值，用户索引的过期时间随最新会话延长
+// 同时裁剪过期索引中过期超过 indexTTL 的条目，这些会话键已由Redis过期删除，避免未运行清理时索引无限增长，裁剪数计入 hwhkit_sessions_expired_total
+func (sm *SessionManager) indexSession(session *Session) error {
+	ctx := sm.cache.ctx
+	score := sessionScore(session.ExpiresAt)
+	expiryKey := sm.cache.Key(sm.getExpiryIndexKey())
+	var pruned *redis.IntCmd
+	_, err := sm.cache.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pruned = pipe.ZRemRangeByScore(ctx, expiryKey, "-inf", "("+scoreString(time.Now().Add(-sm.indexTTL())))
+		pipe.ZAdd(ctx, expiryKey, redis.Z{Score: score, Member: session.ID})
+		if session.UserID != "" {
+			indexKey := sm.cache.Key(sm.getUserIndexKey(session.UserID))
+			pipe.ZAdd(ctx, indexKey, redis.Z{Score: score, Member: session.ID})
+			pipe.Expire(ctx, indexKey, sm.indexTTL())
+			pipe.SAdd(ctx, sm.cache.Key(sm.getUsersKey()), session.UserID)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update session index: %w", err)
+	}
+	// 裁剪的会话已由Redis按TTL过期，同样计入过期数
+	if count := pruned.Val(); count > 0 {
+		sessionsExpired.Add(float64(count), sm.prefix)
+	}
+	return nil
 }
 
 // SessionStats 会话统计信息
@@ -287,35 +671,55 @@ type SessionStats struct {
 	UserSessions    map[string]int64   `json:"user_sessions"`
 }
 
-// GetStats 获取会话统计信息
+// GetStats 获取会话统计信息，基于索引计算，耗时与有会话的用户数成正比
+// ExpiredSessions 为已过期但尚未被 CleanExpiredSessions 清理的会话数
 func (sm *SessionManager) GetStats() (*SessionStats, error) {
-	pattern := sm.prefix + ":*"
-	keys, err := sm.cache.Keys(pattern)
+	ctx := sm.cache.ctx
+	now := "(" + scoreString(time.Now())
+	expiryKey := sm.cache.Key(sm.getExpiryIndexKey())
+	
+	total, err := sm.cache.client.ZCard(ctx, expiryKey).Result()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get session keys: %w", err)
+		return nil, fmt.Errorf("failed to count sessions: %w", err)
+	}
+	active, err := sm.cache.client.ZCount(ctx, expiryKey, now, "+inf").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count sessions: %w", err)
 	}
-	
 	stats := &SessionStats{
-		TotalSessions: int64(len(keys)),
-		UserSessions:  make(map[string]int64),
+		TotalSessions:   total,
+		ActiveSessions:  active,
+		ExpiredSessions: total - active,
+		UserSessions:    make(map[string]int64),
 	}
 	
-	now := time.Now()
-	for _, key := range keys {
-		var session Session
-		if err := sm.cache.GetJSON(key, &session); err != nil {
-			continue
+	userIDs, err := sm.cache.SMembers(sm.getUsersKey())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session users: %w", err)
+	}
+	counts := make([]*redis.IntCmd, len(userIDs))
+	if _, err := sm.cache.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, userID := range userIDs {
+			counts[i] = pipe.ZCount(ctx, sm.cache.Key(sm.getUserIndexKey(userID)), now, "+inf")
 		}
-		
-		if now.Before(session.ExpiresAt) {
-			stats.ActiveSessions++
-			if session.UserID != "" {
-				stats.UserSessions[session.UserID]++
-			}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to count user sessions: %w", err)
+	}
+	
+	// 没有活跃会话的用户移出用户集合
+	var inactive []interface{}
+	for i, userID := range userIDs {
+		if count := counts[i].Val(); count > 0 {
+			stats.UserSessions[userID] = count
 		} else {
-			stats.ExpiredSessions++
+			inactive = append(inactive, userID)
 		}
 	}
+	if len(inactive) > 0 {
+		sm.cache.SRem(sm.getUsersKey(), inactive...)
+	}
+	sessionsActive.Set(float64(stats.ActiveSessions), sm.prefix)
 	
 	return stats, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Get 获取会话数据
+func (s *Session) Get(key string) (interface{}, bool) {
+	value, exists := s.Data[key]
+	return value, exists
+}
+
+// Set 设置会话数据
+func (s *Session) Set(key string, value interface{}) {
+	if s.Data == nil {
+		s.Data = make(map[string]interface{})
+	}
+	s.Data[key] = value
+}
+
+// Delete 删除会话数据
+func (s *Session) Delete(key string) {
+	delete(s.Data, key)
+}
+
+// GetString 获取字符串数据，不存在或类型不匹配时返回默认值
+func (s *Session) GetString(key string, defaultValue string) string {
+	if value, ok := s.Data[key].(string); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetInt 获取整数数据，兼容JSON反序列化后的 float64 和数字字符串
+func (s *Session) GetInt(key string, defaultValue int) int {
+	switch value := s.Data[key].(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return int(n)
+		}
+	case string:
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+// GetBool 获取布尔数据，不存在或类型不匹配时返回默认值
+func (s *Session) GetBool(key string, defaultValue bool) bool {
+	if value, ok := s.Data[key].(bool); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetTime 获取时间数据，兼容JSON反序列化后的RFC3339字符串
+func (s *Session) GetTime(key string, defaultValue time.Time) time.Time {
+	switch value := s.Data[key].(type) {
+	case time.Time:
+		return value
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t
+		}
+	}
+	return defaultValue
+}
+
+// SetFlash 设置闪存数据，下次读取后自动清除，常用于重定向后的提示消息
+func (s *Session) SetFlash(key string, value interface{}) {
+	if s.Flash == nil {
+		s.Flash = make(map[string]interface{})
+	}
+	s.Flash[key] = value
+}
+
+// GetFlash 读取并清除闪存数据，需要保存会话后清除才会生效
+func (s *Session) GetFlash(key string) (interface{}, bool) {
+	value, exists := s.Flash[key]
+	if exists {
+		delete(s.Flash, key)
+	}
+	return value, exists
+}
+
+// IsModified 会话自加载或上次保存后是否被修改
+func (s *Session) IsModified() bool {
+	return s.snapshot == "" || s.state() != s.snapshot
+}
+
+// markClean 记录当前数据快照
+func (s *Session) markClean() {
+	s.snapshot = s.state()
+}
+
+// state 序列化会影响存储内容的字段，UpdatedAt 不参与比较
+func (s *Session) state() string {
+	data, err := json.Marshal(struct {
+		UserID    string
+		Data      map[string]interface{}
+		Flash     map[string]interface{}
+		Device    string
+		ExpiresAt time.Time
+	}{s.UserID, s.Data, s.Flash, s.Device, s.ExpiresAt})
+	if err != nil {
+		// 无法序列化时视为已修改，由保存时返回错误
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingHook Redis命令OpenTelemetry追踪钩子，只记录命令名和键数量，不记录键值
+type tracingHook struct {
+	tracer trace.Tracer
+	addr   string
+}
+
+// EnableTracing 为Redis命令注册OpenTelemetry追踪钩子
+// 通过 WithContext 传入请求上下文时，命令span成为请求span的子span
+func (m *Manager) EnableTracing(tracer trace.Tracer) {
+	m.client.AddHook(&tracingHook{tracer: tracer, addr: m.client.Options().Addr})
+}
+
+// WithContext 创建使用指定上下文执行命令的缓存管理器，与原管理器共享连接和前缀
+func (m *Manager) WithContext(ctx context.Context) *Manager {
+	return &Manager{
+		client: m.client,
+		config: m.config,
+		ctx:    ctx,
+		prefix: m.prefix,
+		hook:   m.hook,
+		codec:  m.codec,
+	}
+}
+
+// DialHook 实现 redis.Hook 接口
+func (h *tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook 实现 redis.Hook 接口，为单条命令创建span
+func (h *tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := h.tracer.Start(ctx, "redis."+cmd.Name(),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(h.attributes(cmd.Name(), 1)...),
+		)
+		defer span.End()
+
+		err := next(ctx, cmd)
+		recordSpanError(span, err)
+		return err
+	}
+}
+
+// ProcessPipelineHook 实现 redis.Hook 接口，整个管道一个span
+func (h *tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		names := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			names = append(names, cmd.Name())
+		}
+		ctx, span := h.tracer.Start(ctx, "redis.pipeline",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(h.attributes(strings.Join(names, " "), len(cmds))...),
+		)
+		defer span.End()
+
+		err := next(ctx, cmds)
+		recordSpanError(span, err)
+		return err
+	}
+}
+
+// attributes 命令span的公共属性
+func (h *tracingHook) attributes(operation string, count int) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("db.system", "redis"),
+		attribute.String("db.operation", operation),
+		attribute.Int("db.redis.num_cmd", count),
+		attribute.String("net.peer.name", h.addr),
+	}
+}
+
+// recordSpanError 记录命令错误，键不存在（redis.Nil）不视为错误
+func recordSpanError(span trace.Span, err error) {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
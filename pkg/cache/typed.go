@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrCacheMiss 缓存键不存在
+var ErrCacheMiss = errors.New("cache: key not found")
+
+// Get 获取缓存并反序列化为 T，键不存在时返回 ErrCacheMiss
+//
+//	user, err := cache.Get[User](m, "user:1")
+//	if errors.Is(err, cache.ErrCacheMiss) { ... }
+func Get[T any](m *Manager, key string) (T, error) {
+	var value T
+	if err := m.GetAny(key, &value); err != nil {
+		var zero T
+		if errors.Is(err, redis.Nil) {
+			return zero, ErrCacheMiss
+		}
+		return zero, err
+	}
+	return value, nil
+}
+
+// Set 使用管理器配置的序列化方式设置缓存
+func Set[T any](m *Manager, key string, value T, expiration time.Duration) error {
+	return m.SetAny(key, value, expiration)
+}
+
+// Remember 获取缓存，未命中时调用 loader 加载并写入缓存；写入缓存失败不影响返回结果
+//
+//	user, err := cache.Remember(m, "user:1", time.Minute, func() (User, error) {
+//		return repo.GetByID(1)
+//	})
+func Remember[T any](m *Manager, key string, expiration time.Duration, loader func() (T, error)) (T, error) {
+	value, err := Get[T](m, key)
+	if err == nil {
+		return value, nil
+	}
+	if !errors.Is(err, ErrCacheMiss) {
+		return value, err
+	}
+
+	value, err = loader()
+	if err != nil {
+		return value, err
+	}
+	_ = Set(m, key, value, expiration)
+	return value, nil
+}
+
+// TypedCache 固定值类型的缓存，键自动添加类型前缀，序列化方式可单独配置
+//
+//	users := cache.NewTypedCache[User](m, "user", 10*time.Minute)
+//	users.SetCodec(cache.MsgPackCodec)
+//	user, err := users.GetOrLoad("1", func() (User, error) { return repo.GetByID(1) })
+type TypedCache[T any] struct {
+	cache      *Manager
+	expiration time.Duration
+	group      singleflight.Group
+}
+
+// NewTypedCache 创建类型化缓存，prefix 追加在管理器前缀之后，expiration 为默认过期时间（0为不过期）
+// 序列化方式默认沿用管理器的配置
+func NewTypedCache[T any](m *Manager, prefix string, expiration time.Duration) *TypedCache[T] {
+	return &TypedCache[T]{
+		cache:      m.WithPrefix(prefix),
+		expiration: expiration,
+	}
+}
+
+// SetCodec 设置序列化方式，不影响原管理器
+func (tc *TypedCache[T]) SetCodec(codec Codec) {
+	tc.cache.SetCodec(codec)
+}
+
+// Codec 获取当前序列化方式
+func (tc *TypedCache[T]) Codec() Codec {
+	return tc.cache.Codec()
+}
+
+// Key 返回添加前缀后的完整键名
+func (tc *TypedCache[T]) Key(key string) string {
+	return tc.cache.Key(key)
+}
+
+// Get 获取缓存，键不存在时返回 ErrCacheMiss
+func (tc *TypedCache[T]) Get(key string) (T, error) {
+	return Get[T](tc.cache, key)
+}
+
+// Set 使用默认过期时间设置缓存
+func (tc *TypedCache[T]) Set(key string, value T) error {
+	return Set(tc.cache, key, value, tc.expiration)
+}
+
+// SetWithTTL 使用指定过期时间设置缓存
+func (tc *TypedCache[T]) SetWithTTL(key string, value T, expiration time.Duration) error {
+	return Set(tc.cache, key, value, expiration)
+}
+
+// GetOrLoad 获取缓存，未命中时调用 loader 加载并按默认过期时间写入缓存
+// 同一键的并发未命中只调用一次 loader；写入缓存失败不影响返回结果
+func (tc *TypedCache[T]) GetOrLoad(key string, loader func() (T, error)) (T, error) {
+	value, err := tc.Get(key)
+	if !errors.Is(err, ErrCacheMiss) {
+		return value, err
+	}
+
+	result, err, _ := tc.group.Do(key, func() (interface{}, error) {
+		value, err := loader()
+		if err != nil {
+			return value, err
+		}
+		_ = tc.Set(key, value)
+		return value, nil
+	})
+	value, _ = result.(T)
+	return value, err
+}
+
+// Delete 删除缓存
+func (tc *TypedCache[T]) Delete(keys ...string) error {
+	return tc.cache.Delete(keys...)
+}
+
+// Exists 检查缓存是否存在
+func (tc *TypedCache[T]) Exists(key string) (bool, error) {
+	return tc.cache.Exists(key)
+}
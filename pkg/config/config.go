@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -18,57 +19,184 @@ type Config struct {
 	Redis    RedisConfig    `json:"redis"`
 	JWT      JWTConfig      `json:"jwt"`
 	Log      LogConfig      `json:"log"`
+	Tracing  TracingConfig  `json:"tracing"`
+}
+
+// TracingConfig OpenTelemetry分布式追踪配置
+type TracingConfig struct {
+	Enabled     bool    `json:"enabled"`
+	ServiceName string  `json:"service_name"`
+	Endpoint    string  `json:"endpoint"`    // OTLP/HTTP 导出地址，如 localhost:4318；为空时只生成追踪上下文不导出
+	URLPath     string  `json:"url_path"`    // OTLP/HTTP 导出路径，默认 /v1/traces
+	Insecure    bool    `json:"insecure"`    // 使用HTTP而非HTTPS连接导出端点
+	SampleRate  float64 `json:"sample_rate"` // 根span采样率 0-1，上游已决定采样的请求沿用上游结果
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port         int    `json:"port"`
-	Mode         string `json:"mode"`         // debug, release, test
-	ReadTimeout  int    `json:"read_timeout"` // 秒
-	WriteTimeout int    `json:"write_timeout"`
-	Host         string `json:"host"`
-	EnableCORS   bool   `json:"enable_cors"`
-	EnableSwagger bool  `json:"enable_swagger"`
-	TemplateDir  string `json:"template_dir"`
-	StaticDir    string `json:"static_dir"`
+	Port                   int         `json:"port"`
+	Mode                   string      `json:"mode"`         // debug, release, test
+	ReadTimeout            int         `json:"read_timeout"` // 秒
+	WriteTimeout           int         `json:"write_timeout"`
+	Host                   string      `json:"host"`
+	EnableCORS             bool        `json:"enable_cors"`
+	EnableSwagger          bool        `json:"enable_swagger"`
+	TemplateDir            string      `json:"template_dir"`
+	StaticDir              string      `json:"static_dir"`
+	CORSAllowOrigins       []string    `json:"cors_allow_origins"`
+	CORSAllowCredentials   bool        `json:"cors_allow_credentials"`
+	FailOnInsecure         bool        `json:"fail_on_insecure"` // release模式下存在严重安全问题时拒绝启动
+	Chaos                  ChaosConfig `json:"chaos"`
+	ShowBanner             bool        `json:"show_banner"`               // 启动时在标准输出打印ASCII横幅
+	Environment            string      `json:"environment"`               // 部署环境，如 development、staging、production
+	AllowDebugInProduction bool        `json:"allow_debug_in_production"` // 允许生产环境以debug模式运行
+	ShutdownTimeout        int         `json:"shutdown_timeout"`          // 优雅关闭超时（秒）
+	DrainGracePeriod       int         `json:"drain_grace_period"`        // 关闭时长连接的宽限期（秒），应小于关闭超时
+	PaginationLinks        bool        `json:"pagination_links"`          // 分页响应体中包含 _links（Link 响应头始终输出）
+	EnableTracing          bool        `json:"enable_tracing"`            // 启用追踪：使用 traceparent 中的追踪ID作为日志、响应头和指标的关联ID
+	Timezone               string      `json:"timezone"`                  // 全局默认时区（IANA名称，如 UTC、Asia/Shanghai），用于时间工具和展示格式化
+	TimezoneHeader         string      `json:"timezone_header"`           // 客户端指定展示时区的请求头，为空时不按请求解析时区
+	StatusPage             bool        `json:"status_page"`               // 启用 /status 状态页，需要配置Basic认证账号或管理员JWT
+	StatusPageUser         string      `json:"status_page_user"`          // 状态页Basic认证用户名
+	StatusPagePassword     string      `json:"-"`                         // 状态页Basic认证密码
+	URLSigningKey          string      `json:"-"`                         // 签名URL的HMAC密钥，为空时由JWT密钥派生
+	DevRoutes              bool        `json:"dev_routes"`                // 启用 /demo 开发演示路由（Server.EnableDevRoutes），release模式或生产环境禁止开启
+	ServerTiming           bool        `json:"server_timing"`             // 输出 Server-Timing（db、cache、handler耗时分段）和 X-Response-Time 响应头
+	CallCounts             bool        `json:"call_counts"`               // 统计每个请求的SQL语句数和缓存命令数，用于发现N+1查询
+	DBQueryWarnThreshold   int         `json:"db_query_warn_threshold"`   // 单个请求的SQL语句数超过该值时记录警告，0为不警告
+	CacheOpWarnThreshold   int         `json:"cache_op_warn_threshold"`   // 单个请求的缓存命令数超过该值时记录警告，0为不警告
+	VerifyMiddleware       bool        `json:"verify_middleware"`         // 启动时校验路由的中间件链（认证在授权之前、分页中间件、公开写接口限流），不通过时拒绝启动
+
+	// StaticPaths 带缓存头、访问控制、跨域和限流选项的静态文件目录，环境变量只能配置一个目录（STATIC_*）
+	StaticPaths []StaticPathConfig `json:"static_paths"`
+
+	// TLS 启用后服务器直接提供HTTPS，可要求并校验客户端证书（双向TLS）
+	TLS ServerTLSConfig `json:"tls"`
+}
+
+// ServerTLSConfig HTTPS服务配置
+type ServerTLSConfig struct {
+	Enabled      bool   `json:"enabled"`
+	CertFile     string `json:"cert_file"`      // 服务器证书路径
+	KeyFile      string `json:"key_file"`       // 服务器私钥路径
+	ClientCAFile string `json:"client_ca_file"` // 校验客户端证书的CA证书路径
+	ClientAuth   string `json:"client_auth"`    // 客户端证书：none（默认）, request, verify_if_given, require（要求并校验）
+}
+
+// StaticPathConfig 静态文件目录配置
+type StaticPathConfig struct {
+	Path        string   `json:"path"`          // 路由前缀，如 /downloads
+	Root        string   `json:"root"`          // 文件目录
+	CacheMaxAge int      `json:"cache_max_age"` // Cache-Control 的 max-age（秒），0为不设置，-1为 no-store
+	RequireAuth bool     `json:"require_auth"`  // 仅允许已登录用户（JWT或页面会话）访问
+	Listing     bool     `json:"listing"`       // 允许目录列表，关闭时没有 index.html 的目录返回404
+	CORSOrigins []string `json:"cors_origins"`  // 允许跨域访问的来源，为空时不输出CORS头
+	RateLimit   int      `json:"rate_limit"`    // 每个IP每秒请求数，0为不限流
+}
+
+// ChaosConfig 故障注入配置，用于非生产环境的韧性测试，release模式下不生效
+type ChaosConfig struct {
+	Enabled bool        `json:"enabled"`
+	Rules   []ChaosRule `json:"rules"`
+}
+
+// ChaosRule 故障注入规则，比例取值 0-1
+type ChaosRule struct {
+	Path        string  `json:"path"`         // 生效路径，支持 * 后缀通配，为空时匹配所有路径
+	LatencyRate float64 `json:"latency_rate"` // 注入延迟的请求比例
+	LatencyMs   int     `json:"latency_ms"`   // 注入的延迟（毫秒）
+	ErrorRate   float64 `json:"error_rate"`   // 返回错误的请求比例
+	ErrorStatus int     `json:"error_status"` // 错误状态码，默认503
+	DropRate    float64 `json:"drop_rate"`    // 直接断开连接的请求比例
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
-	Type            string `json:"type"`             // mysql, postgres
-	Host            string `json:"host"`
-	Port            int    `json:"port"`
-	User            string `json:"user"`
-	Password        string `json:"password"`
-	Name            string `json:"name"`
-	MaxOpenConns    int    `json:"max_open_conns"`
-	MaxIdleConns    int    `json:"max_idle_conns"`
-	ConnMaxLifetime int    `json:"conn_max_lifetime"` // 分钟
-	SSLMode         string `json:"ssl_mode"`
-	Charset         string `json:"charset"`
-	AutoMigrate     bool   `json:"auto_migrate"`
+	Type            string    `json:"type"` // mysql, postgres
+	Host            string    `json:"host"`
+	Port            int       `json:"port"`
+	User            string    `json:"user"`
+	Password        string    `json:"password"`
+	Name            string    `json:"name"`
+	MaxOpenConns    int       `json:"max_open_conns"`
+	MaxIdleConns    int       `json:"max_idle_conns"`
+	ConnMaxLifetime int       `json:"conn_max_lifetime"` // 分钟
+	SSLMode         string    `json:"ssl_mode"`
+	Charset         string    `json:"charset"`
+	AutoMigrate     bool      `json:"auto_migrate"`
+	TLS             TLSConfig `json:"tls"`
+	LogLevel        string    `json:"log_level"` // GORM日志级别：silent, error, warn, info
+	TimeZone        string    `json:"timezone"`  // 数据库连接时区，为空时保持 MySQL loc=Local、PostgreSQL Asia/Shanghai
 }
 
 // RedisConfig Redis配置
 type RedisConfig struct {
-	Host         string `json:"host"`
-	Port         int    `json:"port"`
-	Password     string `json:"password"`
-	DB           int    `json:"db"`
-	PoolSize     int    `json:"pool_size"`
-	MinIdleConns int    `json:"min_idle_conns"`
-	MaxRetries   int    `json:"max_retries"`
-	DialTimeout  int    `json:"dial_timeout"`  // 秒
-	ReadTimeout  int    `json:"read_timeout"`  // 秒
-	WriteTimeout int    `json:"write_timeout"` // 秒
+	Host         string    `json:"host"`
+	Port         int       `json:"port"`
+	Password     string    `json:"password"`
+	DB           int       `json:"db"`
+	PoolSize     int       `json:"pool_size"`
+	MinIdleConns int       `json:"min_idle_conns"`
+	MaxRetries   int       `json:"max_retries"`
+	DialTimeout  int       `json:"dial_timeout"`  // 秒
+	ReadTimeout  int       `json:"read_timeout"`  // 秒
+	WriteTimeout int       `json:"write_timeout"` // 秒
+	KeyPrefix    string    `json:"key_prefix"`    // 全局键前缀，多个应用共享同一Redis实例时使用
+	TLS          TLSConfig `json:"tls"`
+	Codec        string    `json:"codec"` // 缓存值序列化方式：json, msgpack, gob, proto
+}
+
+// TLSConfig TLS连接配置（用于Redis、数据库等客户端连接）
+type TLSConfig struct {
+	Enabled            bool   `json:"enabled"`
+	CAFile             string `json:"ca_file"`              // CA证书路径，为空时使用系统证书
+	CertFile           string `json:"cert_file"`            // 客户端证书路径（双向TLS）
+	KeyFile            string `json:"key_file"`             // 客户端私钥路径（双向TLS）
+	ServerName         string `json:"server_name"`          // 证书校验使用的服务器名，为空时使用Host
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"` // 跳过证书校验，仅用于测试环境
 }
 
 // JWTConfig JWT配置
 type JWTConfig struct {
-	Secret       string `json:"secret"`
-	ExpireHours  int    `json:"expire_hours"`
-	RefreshHours int    `json:"refresh_hours"`
-	Issuer       string `json:"issuer"`
+	Secret        string              `json:"secret"`
+	ExpireHours   int                 `json:"expire_hours"`
+	RefreshHours  int                 `json:"refresh_hours"`
+	Issuer        string              `json:"issuer"`
+	Password      PasswordConfig      `json:"password"`
+	LeewaySeconds int                 `json:"leeway_seconds"` // 校验 exp/nbf/iat 时允许的时钟偏差（秒）
+	DeviceBinding string              `json:"device_binding"` // 令牌和会话的设备绑定：off, lenient, strict
+	TrustedHeader TrustedHeaderConfig `json:"trusted_header"`
+}
+
+// TrustedHeaderConfig 可信请求头认证配置，用于由上游网关完成认证并注入身份请求头的部署
+// 只接受来自 TrustedProxies 中地址、或持有 ClientCertNames 中证书的连接的身份请求头，两者都配置时需同时满足
+type TrustedHeaderConfig struct {
+	Enabled         bool     `json:"enabled"`
+	UserHeader      string   `json:"user_header"`       // 用户ID请求头，默认 X-Auth-User
+	UsernameHeader  string   `json:"username_header"`   // 用户名请求头，默认 X-Auth-Name
+	EmailHeader     string   `json:"email_header"`      // 邮箱请求头，默认 X-Auth-Email
+	RolesHeader     string   `json:"roles_header"`      // 角色请求头（逗号分隔），默认 X-Auth-Roles
+	TrustedProxies  []string `json:"trusted_proxies"`   // 网关地址，IP或CIDR，按连接的对端地址匹配（不读取 X-Forwarded-For）
+	ClientCertNames []string `json:"client_cert_names"` // 网关客户端证书的CN或DNS SAN，要求连接使用已校验的客户端证书
+}
+
+// Leeway 获取令牌校验允许的时钟偏差
+func (c *JWTConfig) Leeway() time.Duration {
+	if c == nil || c.LeewaySeconds <= 0 {
+		return 0
+	}
+	return time.Duration(c.LeewaySeconds) * time.Second
+}
+
+// PasswordConfig 密码哈希配置
+type PasswordConfig struct {
+	Algorithm         string `json:"algorithm"`          // bcrypt, argon2id
+	BcryptCost        int    `json:"bcrypt_cost"`        // bcrypt 计算成本
+	Argon2Memory      uint32 `json:"argon2_memory"`      // KB
+	Argon2Iterations  uint32 `json:"argon2_iterations"`  // 迭代次数
+	Argon2Parallelism uint8  `json:"argon2_parallelism"` // 并行度
+	Argon2SaltLength  uint32 `json:"argon2_salt_length"` // 盐长度（字节）
+	Argon2KeyLength   uint32 `json:"argon2_key_length"`  // 哈希长度（字节）
 }
 
 // LogConfig 日志配置
@@ -81,13 +209,50 @@ type LogConfig struct {
 	MaxBackups int    `json:"max_backups"` // 保留的备份数量
 	MaxAge     int    `json:"max_age"`     // 保留天数
 	Compress   bool   `json:"compress"`    // 是否压缩
+
+	// 访问日志隐私设置（GDPR）
+	RetentionDays      int      `json:"retention_days"`       // 日志数据保留天数，设置后作为文件轮转的最长保留时间
+	AnonymizeIP        string   `json:"anonymize_ip"`         // 客户端IP匿名化方式：hash、truncate，为空时不处理
+	IPHashSalt         string   `json:"-"`                    // IP哈希盐值，hash 方式下应设置以防止通过枚举还原IP
+	DropUserAgent      bool     `json:"drop_user_agent"`      // 不记录User-Agent
+	ExcludeQueryParams []string `json:"exclude_query_params"` // 从日志中移除的查询参数，如 token、email
+
+	Alert AlertConfig `json:"alert"` // 基于日志的告警
+}
+
+// AlertConfig 基于日志的告警配置
+type AlertConfig struct {
+	Enabled        bool   `json:"enabled"`
+	MinLevel       string `json:"min_level"`       // 触发告警的最低日志级别，默认 error
+	MessagePattern string `json:"message_pattern"` // 消息匹配的正则表达式，为空时匹配所有消息
+	Threshold      int    `json:"threshold"`       // 统计窗口内匹配次数达到阈值才告警，默认1
+	Window         int    `json:"window"`          // 统计窗口（秒）
+	DedupWindow    int    `json:"dedup_window"`    // 去重窗口（秒），相同告警在窗口内只发送一次
+
+	WebhookURL         string `json:"webhook_url"`
+	SlackWebhookURL    string `json:"slack_webhook_url"`
+	DingTalkWebhookURL string `json:"dingtalk_webhook_url"`
+	DingTalkSecret     string `json:"-"`
+	FeishuWebhookURL   string `json:"feishu_webhook_url"`
+	FeishuSecret       string `json:"-"`
+	WeComWebhookURL    string `json:"wecom_webhook_url"`
+	MessageFormat      string `json:"message_format"` // 钉钉、飞书、企业微信的消息格式：text、card
 }
 
 // ConfigManager 配置管理器
 type ConfigManager struct {
-	config     *Config
-	configURL  string // 远程配置API地址
-	httpClient *http.Client
+	config       *Config
+	configURL    string // 远程配置API地址
+	httpClient   *http.Client
+	snapshotPath string    // 远程配置快照文件路径，为空时不缓存
+	etag         string    // 远程配置的ETag
+	source       string    // 当前配置来源
+	loadedAt     time.Time // 当前配置的获取时间
+	configFile   string    // 本地配置文件路径，为空时自动查找
+	loadedFile   string    // 当前配置实际使用的配置文件
+	flags        *Flags    // 命令行参数，优先级最高
+
+	watch keyWatchers // 按配置键订阅的变更回调
 }
 
 // New 创建新的配置管理器
@@ -103,10 +268,55 @@ func New() *ConfigManager {
 	return cm
 }
 
+// NewWithFile 创建从指定配置文件加载的配置管理器，文件格式按扩展名识别（.yaml/.yml/.json/.toml）
+// 文件不存在、格式不支持或解析失败时返回错误，不会静默回退到默认值（包括默认的JWT密钥）
+func NewWithFile(path string) (*ConfigManager, error) {
+	cm := &ConfigManager{
+		configFile: path,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+	
+	// 加载配置
+	if err := cm.Load(); err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
+
+// NewWithFlags 创建使用命令行参数的配置管理器，flags 由 BindFlags 注册并已解析
+// 通过 -config 指定的配置文件无效时返回错误
+func NewWithFlags(flags *Flags) (*ConfigManager, error) {
+	cm := &ConfigManager{
+		flags: flags,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+	if flags != nil {
+		cm.configFile = flags.ConfigFile
+	}
+	
+	// 加载配置
+	if err := cm.Load(); err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
+
 // NewWithURL 创建支持远程配置的配置管理器
+// 设置 CONFIG_SNAPSHOT_PATH 时远程配置会缓存到该快照文件，远程不可用时使用快照；未设置时不缓存
 func NewWithURL(configURL string) *ConfigManager {
+	return NewWithSnapshot(configURL, getEnv("CONFIG_SNAPSHOT_PATH", ""))
+}
+
+// NewWithSnapshot 创建支持远程配置的配置管理器，并指定快照文件路径，为空时不缓存
+// 快照包含数据库、Redis和JWT密钥，应位于只有当前用户可访问的目录，不要使用共享的临时目录
+func NewWithSnapshot(configURL, snapshotPath string) *ConfigManager {
 	cm := &ConfigManager{
-		configURL: configURL,
+		configURL:    configURL,
+		snapshotPath: snapshotPath,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
@@ -117,11 +327,21 @@ func NewWithURL(configURL string) *ConfigManager {
 	return cm
 }
 
-// Load 加载配置
+// Load 加载配置，配置值变化时通知 OnChange 订阅的回调
 func (cm *ConfigManager) Load() error {
-	// 首先尝试从远程加载
+	previous := cm.config
+	defer func() {
+		cm.watch.notify(previous, cm.config)
+	}()
+	
+	// 首先尝试从远程加载，失败时使用最近一次成功的快照
 	if cm.configURL != "" {
-		if err := cm.loadFromRemote(); err == nil {
+		err := cm.loadFromRemote()
+		if err == nil {
+			return nil
+		}
+		if snapErr := cm.loadFromSnapshot(); snapErr == nil {
+			fmt.Printf("Failed to load config from remote: %v, using snapshot from %s\n", err, cm.loadedAt.Format(time.RFC3339))
 			return nil
 		}
 		fmt.Printf("Failed to load config from remote: %v, fallback to local\n", err)
@@ -131,79 +351,305 @@ func (cm *ConfigManager) Load() error {
 	return cm.loadFromLocal()
 }
 
-// loadFromLocal 从本地环境变量和.env文件加载配置
+// loadFromLocal 从配置文件、环境变量和.env文件加载配置
+// 优先级：命令行参数 > 环境变量 > 配置文件 > 默认值
 func (cm *ConfigManager) loadFromLocal() error {
 	// 加载.env文件
 	_ = godotenv.Load()
 	
-	config := &Config{
+	// 配置文件统一转换为JSON，复用结构体的json标签
+	// 配置文件无效时仍使用环境变量和默认值加载，并返回错误
+	var fileData []byte
+	var fileErr error
+	path := cm.configFilePath()
+	if path != "" {
+		fileData, fileErr = readConfigFile(path)
+	}
+	
+	// 默认值依赖运行模式，需先按优先级确定模式
+	mode := ModeDebug
+	if fileData != nil {
+		var peek Config
+		if err := json.Unmarshal(fileData, &peek); err != nil {
+			fileData, fileErr = nil, fmt.Errorf("failed to decode config file %s: %w", path, err)
+		} else if peek.Server.Mode != "" {
+			mode = peek.Server.Mode
+		}
+	}
+	if fileErr != nil {
+		fmt.Printf("%v, fallback to environment variables\n", fileErr)
+		path = ""
+	}
+	mode = getEnv("SERVER_MODE", mode)
+	if cm.flags != nil && cm.flags.isSet("mode") {
+		mode = cm.flags.Mode
+	}
+	
+	config := defaultConfig(mode)
+	if fileData != nil {
+		_ = json.Unmarshal(fileData, config) // 已在确定模式时校验过
+	}
+	applyEnv(config)
+	if cm.flags != nil {
+		cm.flags.apply(config)
+	}
+	
+	cm.config = config
+	cm.loadedFile = path
+	cm.source = ConfigSourceLocal
+	if path != "" {
+		cm.source = ConfigSourceFile
+	}
+	cm.loadedAt = time.Now()
+	return fileErr
+}
+
+// defaultConfig 获取默认配置，Swagger开关和GORM日志级别的默认值取决于运行模式
+func defaultConfig(mode string) *Config {
+	return &Config{
 		Server: ServerConfig{
-			Port:          getEnvAsInt("SERVER_PORT", 8080),
-			Mode:          getEnv("SERVER_MODE", "debug"),
-			ReadTimeout:   getEnvAsInt("SERVER_READ_TIMEOUT", 60),
-			WriteTimeout:  getEnvAsInt("SERVER_WRITE_TIMEOUT", 60),
-			Host:          getEnv("SERVER_HOST", "0.0.0.0"),
-			EnableCORS:    getEnvAsBool("SERVER_ENABLE_CORS", true),
-			EnableSwagger: getEnvAsBool("SERVER_ENABLE_SWAGGER", true),
-			TemplateDir:   getEnv("SERVER_TEMPLATE_DIR", "templates"),
-			StaticDir:     getEnv("SERVER_STATIC_DIR", "static"),
+			Port:                 8080,
+			Mode:                 mode,
+			ReadTimeout:          60,
+			WriteTimeout:         60,
+			Host:                 "0.0.0.0",
+			EnableCORS:           true,
+			EnableSwagger:        mode != ModeRelease,
+			TemplateDir:          "templates",
+			StaticDir:            "static",
+			CORSAllowOrigins:     []string{"*"},
+			Environment:          "development",
+			ShutdownTimeout:      10,
+			DrainGracePeriod:     5,
+			Timezone:             "Asia/Shanghai",
+			TimezoneHeader:       "X-Timezone",
+			DBQueryWarnThreshold: 20,
+			CacheOpWarnThreshold: 50,
 		},
 		Database: DatabaseConfig{
-			Type:            getEnv("DB_TYPE", "mysql"),
-			Host:            getEnv("DB_HOST", "localhost"),
-			Port:            getEnvAsInt("DB_PORT", 3306),
-			User:            getEnv("DB_USER", "root"),
-			Password:        getEnv("DB_PASSWORD", ""),
-			Name:            getEnv("DB_NAME", "hwhkit"),
-			MaxOpenConns:    getEnvAsInt("DB_MAX_OPEN_CONNS", 100),
-			MaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 10),
-			ConnMaxLifetime: getEnvAsInt("DB_CONN_MAX_LIFETIME", 60),
-			SSLMode:         getEnv("DB_SSL_MODE", "disable"),
-			Charset:         getEnv("DB_CHARSET", "utf8mb4"),
-			AutoMigrate:     getEnvAsBool("DB_AUTO_MIGRATE", true),
+			Type:            "mysql",
+			Host:            "localhost",
+			Port:            3306,
+			User:            "root",
+			Name:            "hwhkit",
+			MaxOpenConns:    100,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 60,
+			SSLMode:         "disable",
+			Charset:         "utf8mb4",
+			AutoMigrate:     true,
+			LogLevel:        defaultDBLogLevel(mode),
 		},
 		Redis: RedisConfig{
-			Host:         getEnv("REDIS_HOST", "localhost"),
-			Port:         getEnvAsInt("REDIS_PORT", 6379),
-			Password:     getEnv("REDIS_PASSWORD", ""),
-			DB:           getEnvAsInt("REDIS_DB", 0),
-			PoolSize:     getEnvAsInt("REDIS_POOL_SIZE", 10),
-			MinIdleConns: getEnvAsInt("REDIS_MIN_IDLE_CONNS", 5),
-			MaxRetries:   getEnvAsInt("REDIS_MAX_RETRIES", 3),
-			DialTimeout:  getEnvAsInt("REDIS_DIAL_TIMEOUT", 5),
-			ReadTimeout:  getEnvAsInt("REDIS_READ_TIMEOUT", 3),
-			WriteTimeout: getEnvAsInt("REDIS_WRITE_TIMEOUT", 3),
+			Host:         "localhost",
+			Port:         6379,
+			PoolSize:     10,
+			MinIdleConns: 5,
+			MaxRetries:   3,
+			DialTimeout:  5,
+			ReadTimeout:  3,
+			WriteTimeout: 3,
+			Codec:        "json",
 		},
 		JWT: JWTConfig{
-			Secret:       getEnv("JWT_SECRET", "hwhkit-default-secret-change-in-production"),
-			ExpireHours:  getEnvAsInt("JWT_EXPIRE_HOURS", 24),
-			RefreshHours: getEnvAsInt("JWT_REFRESH_HOURS", 168), // 7天
-			Issuer:       getEnv("JWT_ISSUER", "hwhkit-go"),
+			Secret:        DefaultJWTSecret,
+			ExpireHours:   24,
+			RefreshHours:  168, // 7天
+			Issuer:        "hwhkit-go",
+			DeviceBinding: "off",
+			TrustedHeader: TrustedHeaderConfig{
+				UserHeader:     "X-Auth-User",
+				UsernameHeader: "X-Auth-Name",
+				EmailHeader:    "X-Auth-Email",
+				RolesHeader:    "X-Auth-Roles",
+			},
+			Password: PasswordConfig{
+				Algorithm:         "bcrypt",
+				BcryptCost:        10,
+				Argon2Memory:      64 * 1024,
+				Argon2Iterations:  3,
+				Argon2Parallelism: 2,
+				Argon2SaltLength:  16,
+				Argon2KeyLength:   32,
+			},
 		},
 		Log: LogConfig{
-			Level:      getEnv("LOG_LEVEL", "info"),
-			Format:     getEnv("LOG_FORMAT", "json"),
-			Output:     getEnv("LOG_OUTPUT", "console"),
-			FilePath:   getEnv("LOG_FILE_PATH", "logs/app.log"),
-			MaxSize:    getEnvAsInt("LOG_MAX_SIZE", 100),
-			MaxBackups: getEnvAsInt("LOG_MAX_BACKUPS", 10),
-			MaxAge:     getEnvAsInt("LOG_MAX_AGE", 30),
-			Compress:   getEnvAsBool("LOG_COMPRESS", true),
+			Level:      "info",
+			Format:     "json",
+			Output:     "console",
+			FilePath:   "logs/app.log",
+			MaxSize:    100,
+			MaxBackups: 10,
+			MaxAge:     30,
+			Compress:   true,
+			Alert: AlertConfig{
+				MinLevel:      "error",
+				Threshold:     1,
+				Window:        60,
+				DedupWindow:   300,
+				MessageFormat: "card",
+			},
 		},
+		Tracing: TracingConfig{
+			ServiceName: "hwhkit-go",
+			SampleRate:  1,
+		},
+	}
+}
+
+// applyEnv 使用环境变量覆盖配置，未设置的环境变量保留当前值
+func applyEnv(config *Config) {
+	server := &config.Server
+	server.Port = getEnvAsInt("SERVER_PORT", server.Port)
+	server.Mode = getEnv("SERVER_MODE", server.Mode)
+	server.ReadTimeout = getEnvAsInt("SERVER_READ_TIMEOUT", server.ReadTimeout)
+	server.WriteTimeout = getEnvAsInt("SERVER_WRITE_TIMEOUT", server.WriteTimeout)
+	server.Host = getEnv("SERVER_HOST", server.Host)
+	server.EnableCORS = getEnvAsBool("SERVER_ENABLE_CORS", server.EnableCORS)
+	server.EnableSwagger = getEnvAsBool("SERVER_ENABLE_SWAGGER", server.EnableSwagger)
+	server.TemplateDir = getEnv("SERVER_TEMPLATE_DIR", server.TemplateDir)
+	server.StaticDir = getEnv("SERVER_STATIC_DIR", server.StaticDir)
+	server.CORSAllowOrigins = getEnvAsSlice("SERVER_CORS_ALLOW_ORIGINS", server.CORSAllowOrigins)
+	server.CORSAllowCredentials = getEnvAsBool("SERVER_CORS_ALLOW_CREDENTIALS", server.CORSAllowCredentials)
+	server.FailOnInsecure = getEnvAsBool("SERVER_FAIL_ON_INSECURE", server.FailOnInsecure)
+	if getEnv("CHAOS_ENABLED", "") != "" {
+		server.Chaos = getChaosConfigFromEnv()
+	}
+	if getEnv("STATIC_PATH", "") != "" {
+		server.StaticPaths = []StaticPathConfig{getStaticPathConfigFromEnv()}
 	}
+	server.ShowBanner = getEnvAsBool("SERVER_SHOW_BANNER", server.ShowBanner)
+	server.Environment = getEnv("ENV", server.Environment)
+	server.AllowDebugInProduction = getEnvAsBool("SERVER_ALLOW_DEBUG_IN_PRODUCTION", server.AllowDebugInProduction)
+	server.ShutdownTimeout = getEnvAsInt("SERVER_SHUTDOWN_TIMEOUT", server.ShutdownTimeout)
+	server.DrainGracePeriod = getEnvAsInt("SERVER_DRAIN_GRACE_PERIOD", server.DrainGracePeriod)
+	server.PaginationLinks = getEnvAsBool("SERVER_PAGINATION_LINKS", server.PaginationLinks)
+	server.EnableTracing = getEnvAsBool("SERVER_ENABLE_TRACING", server.EnableTracing)
+	server.Timezone = getEnv("SERVER_TIMEZONE", server.Timezone)
+	server.TimezoneHeader = getEnv("SERVER_TIMEZONE_HEADER", server.TimezoneHeader)
+	server.StatusPage = getEnvAsBool("SERVER_STATUS_PAGE", server.StatusPage)
+	server.StatusPageUser = getEnv("SERVER_STATUS_PAGE_USER", server.StatusPageUser)
+	server.StatusPagePassword = getEnv("SERVER_STATUS_PAGE_PASSWORD", server.StatusPagePassword)
+	server.URLSigningKey = getEnv("SERVER_URL_SIGNING_KEY", server.URLSigningKey)
+	server.DevRoutes = getEnvAsBool("SERVER_DEV_ROUTES", server.DevRoutes)
+	server.ServerTiming = getEnvAsBool("SERVER_TIMING", server.ServerTiming)
+	server.CallCounts = getEnvAsBool("SERVER_CALL_COUNTS", server.CallCounts)
+	server.DBQueryWarnThreshold = getEnvAsInt("SERVER_DB_QUERY_WARN_THRESHOLD", server.DBQueryWarnThreshold)
+	server.CacheOpWarnThreshold = getEnvAsInt("SERVER_CACHE_OP_WARN_THRESHOLD", server.CacheOpWarnThreshold)
+	server.VerifyMiddleware = getEnvAsBool("SERVER_VERIFY_MIDDLEWARE", server.VerifyMiddleware)
+	server.TLS.Enabled = getEnvAsBool("SERVER_TLS_ENABLED", server.TLS.Enabled)
+	server.TLS.CertFile = getEnv("SERVER_TLS_CERT_FILE", server.TLS.CertFile)
+	server.TLS.KeyFile = getEnv("SERVER_TLS_KEY_FILE", server.TLS.KeyFile)
+	server.TLS.ClientCAFile = getEnv("SERVER_TLS_CLIENT_CA_FILE", server.TLS.ClientCAFile)
+	server.TLS.ClientAuth = getEnv("SERVER_TLS_CLIENT_AUTH", server.TLS.ClientAuth)
 	
-	cm.config = config
-	return nil
+	db := &config.Database
+	db.Type = getEnv("DB_TYPE", db.Type)
+	db.Host = getEnv("DB_HOST", db.Host)
+	db.Port = getEnvAsInt("DB_PORT", db.Port)
+	db.User = getEnv("DB_USER", db.User)
+	db.Password = getEnv("DB_PASSWORD", db.Password)
+	db.Name = getEnv("DB_NAME", db.Name)
+	db.MaxOpenConns = getEnvAsInt("DB_MAX_OPEN_CONNS", db.MaxOpenConns)
+	db.MaxIdleConns = getEnvAsInt("DB_MAX_IDLE_CONNS", db.MaxIdleConns)
+	db.ConnMaxLifetime = getEnvAsInt("DB_CONN_MAX_LIFETIME", db.ConnMaxLifetime)
+	db.SSLMode = getEnv("DB_SSL_MODE", db.SSLMode)
+	db.Charset = getEnv("DB_CHARSET", db.Charset)
+	db.AutoMigrate = getEnvAsBool("DB_AUTO_MIGRATE", db.AutoMigrate)
+	db.TLS = getTLSConfigFromEnv("DB_TLS", db.TLS)
+	db.LogLevel = getEnv("DB_LOG_LEVEL", db.LogLevel)
+	db.TimeZone = getEnv("DB_TIMEZONE", db.TimeZone)
+	
+	redis := &config.Redis
+	redis.Host = getEnv("REDIS_HOST", redis.Host)
+	redis.Port = getEnvAsInt("REDIS_PORT", redis.Port)
+	redis.Password = getEnv("REDIS_PASSWORD", redis.Password)
+	redis.DB = getEnvAsInt("REDIS_DB", redis.DB)
+	redis.PoolSize = getEnvAsInt("REDIS_POOL_SIZE", redis.PoolSize)
+	redis.MinIdleConns = getEnvAsInt("REDIS_MIN_IDLE_CONNS", redis.MinIdleConns)
+	redis.MaxRetries = getEnvAsInt("REDIS_MAX_RETRIES", redis.MaxRetries)
+	redis.DialTimeout = getEnvAsInt("REDIS_DIAL_TIMEOUT", redis.DialTimeout)
+	redis.ReadTimeout = getEnvAsInt("REDIS_READ_TIMEOUT", redis.ReadTimeout)
+	redis.WriteTimeout = getEnvAsInt("REDIS_WRITE_TIMEOUT", redis.WriteTimeout)
+	redis.KeyPrefix = getEnv("REDIS_KEY_PREFIX", redis.KeyPrefix)
+	redis.TLS = getTLSConfigFromEnv("REDIS_TLS", redis.TLS)
+	redis.Codec = getEnv("REDIS_CODEC", redis.Codec)
+	
+	jwt := &config.JWT
+	jwt.Secret = getEnv("JWT_SECRET", jwt.Secret)
+	jwt.ExpireHours = getEnvAsInt("JWT_EXPIRE_HOURS", jwt.ExpireHours)
+	jwt.RefreshHours = getEnvAsInt("JWT_REFRESH_HOURS", jwt.RefreshHours)
+	jwt.Issuer = getEnv("JWT_ISSUER", jwt.Issuer)
+	jwt.LeewaySeconds = getEnvAsInt("JWT_LEEWAY_SECONDS", jwt.LeewaySeconds)
+	jwt.DeviceBinding = getEnv("JWT_DEVICE_BINDING", jwt.DeviceBinding)
+	
+	trusted := &config.JWT.TrustedHeader
+	trusted.Enabled = getEnvAsBool("TRUSTED_HEADER_ENABLED", trusted.Enabled)
+	trusted.UserHeader = getEnv("TRUSTED_HEADER_USER", trusted.UserHeader)
+	trusted.UsernameHeader = getEnv("TRUSTED_HEADER_USERNAME", trusted.UsernameHeader)
+	trusted.EmailHeader = getEnv("TRUSTED_HEADER_EMAIL", trusted.EmailHeader)
+	trusted.RolesHeader = getEnv("TRUSTED_HEADER_ROLES", trusted.RolesHeader)
+	trusted.TrustedProxies = getEnvAsSlice("TRUSTED_HEADER_PROXIES", trusted.TrustedProxies)
+	trusted.ClientCertNames = getEnvAsSlice("TRUSTED_HEADER_CLIENT_CERT_NAMES", trusted.ClientCertNames)
+	
+	password := &config.JWT.Password
+	password.Algorithm = getEnv("PASSWORD_ALGORITHM", password.Algorithm)
+	password.BcryptCost = getEnvAsInt("PASSWORD_BCRYPT_COST", password.BcryptCost)
+	password.Argon2Memory = uint32(getEnvAsInt("PASSWORD_ARGON2_MEMORY", int(password.Argon2Memory)))
+	password.Argon2Iterations = uint32(getEnvAsInt("PASSWORD_ARGON2_ITERATIONS", int(password.Argon2Iterations)))
+	password.Argon2Parallelism = uint8(getEnvAsInt("PASSWORD_ARGON2_PARALLELISM", int(password.Argon2Parallelism)))
+	password.Argon2SaltLength = uint32(getEnvAsInt("PASSWORD_ARGON2_SALT_LENGTH", int(password.Argon2SaltLength)))
+	password.Argon2KeyLength = uint32(getEnvAsInt("PASSWORD_ARGON2_KEY_LENGTH", int(password.Argon2KeyLength)))
+	
+	log := &config.Log
+	log.Level = getEnv("LOG_LEVEL", log.Level)
+	log.Format = getEnv("LOG_FORMAT", log.Format)
+	log.Output = getEnv("LOG_OUTPUT", log.Output)
+	log.FilePath = getEnv("LOG_FILE_PATH", log.FilePath)
+	log.MaxSize = getEnvAsInt("LOG_MAX_SIZE", log.MaxSize)
+	log.MaxBackups = getEnvAsInt("LOG_MAX_BACKUPS", log.MaxBackups)
+	log.MaxAge = getEnvAsInt("LOG_MAX_AGE", log.MaxAge)
+	log.Compress = getEnvAsBool("LOG_COMPRESS", log.Compress)
+	log.RetentionDays = getEnvAsInt("LOG_RETENTION_DAYS", log.RetentionDays)
+	log.AnonymizeIP = getEnv("LOG_ANONYMIZE_IP", log.AnonymizeIP)
+	log.IPHashSalt = getEnv("LOG_IP_HASH_SALT", log.IPHashSalt)
+	log.DropUserAgent = getEnvAsBool("LOG_DROP_USER_AGENT", log.DropUserAgent)
+	log.ExcludeQueryParams = getEnvAsSlice("LOG_EXCLUDE_QUERY_PARAMS", log.ExcludeQueryParams)
+	log.Alert = getAlertConfigFromEnv(log.Alert)
+	
+	tracing := &config.Tracing
+	tracing.Enabled = getEnvAsBool("TRACING_ENABLED", tracing.Enabled)
+	tracing.ServiceName = getEnv("TRACING_SERVICE_NAME", tracing.ServiceName)
+	tracing.Endpoint = getEnv("TRACING_ENDPOINT", tracing.Endpoint)
+	tracing.URLPath = getEnv("TRACING_URL_PATH", tracing.URLPath)
+	tracing.Insecure = getEnvAsBool("TRACING_INSECURE", tracing.Insecure)
+	tracing.SampleRate = getEnvAsFloat("TRACING_SAMPLE_RATE", tracing.SampleRate)
 }
 
 // loadFromRemote 从远程API加载配置
+// 已有远程配置时携带 If-None-Match，服务端返回304则沿用当前配置
 func (cm *ConfigManager) loadFromRemote() error {
-	resp, err := cm.httpClient.Get(cm.configURL)
+	req, err := http.NewRequest(http.MethodGet, cm.configURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create remote config request: %w", err)
+	}
+	if cm.etag != "" && cm.config != nil && cm.source != ConfigSourceLocal {
+		req.Header.Set("If-None-Match", cm.etag)
+	}
+	
+	resp, err := cm.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to fetch config from remote: %w", err)
 	}
 	defer resp.Body.Close()
 	
+	if resp.StatusCode == http.StatusNotModified && req.Header.Get("If-None-Match") != "" {
+		cm.source = ConfigSourceRemote
+		cm.loadedAt = time.Now()
+		cm.saveSnapshot()
+		return nil
+	}
+	
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("remote config API returned status: %d", resp.StatusCode)
 	}
@@ -214,6 +660,10 @@ func (cm *ConfigManager) loadFromRemote() error {
 	}
 	
 	cm.config = &config
+	cm.etag = resp.Header.Get("ETag")
+	cm.source = ConfigSourceRemote
+	cm.loadedAt = time.Now()
+	cm.saveSnapshot()
 	return nil
 }
 
@@ -274,4 +724,90 @@ func getEnvAsBool(name string, defaultVal bool) bool {
 		return value
 	}
 	return defaultVal
-}
\ No newline at end of file
+}
+
+// getEnvAsSlice 读取逗号分隔的环境变量
+func getEnvAsSlice(name string, defaultVal []string) []string {
+	valueStr := getEnv(name, "")
+	if valueStr == "" {
+		return defaultVal
+	}
+	var values []string
+	for _, value := range strings.Split(valueStr, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getTLSConfigFromEnv 从带前缀的环境变量读取TLS配置，如 REDIS_TLS_ENABLED，未设置的项保留 defaults 中的值
+func getTLSConfigFromEnv(prefix string, defaults TLSConfig) TLSConfig {
+	return TLSConfig{
+		Enabled:            getEnvAsBool(prefix+"_ENABLED", defaults.Enabled),
+		CAFile:             getEnv(prefix+"_CA_FILE", defaults.CAFile),
+		CertFile:           getEnv(prefix+"_CERT_FILE", defaults.CertFile),
+		KeyFile:            getEnv(prefix+"_KEY_FILE", defaults.KeyFile),
+		ServerName:         getEnv(prefix+"_SERVER_NAME", defaults.ServerName),
+		InsecureSkipVerify: getEnvAsBool(prefix+"_SKIP_VERIFY", defaults.InsecureSkipVerify),
+	}
+}
+
+// getEnvAsFloat 读取浮点数环境变量
+func getEnvAsFloat(name string, defaultVal float64) float64 {
+	valueStr := getEnv(name, "")
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return defaultVal
+}
+
+// getChaosConfigFromEnv 从 CHAOS_* 环境变量读取故障注入配置，环境变量只能配置一条规则
+func getChaosConfigFromEnv() ChaosConfig {
+	cfg := ChaosConfig{Enabled: getEnvAsBool("CHAOS_ENABLED", false)}
+	if !cfg.Enabled {
+		return cfg
+	}
+	cfg.Rules = []ChaosRule{{
+		Path:        getEnv("CHAOS_PATH", ""),
+		LatencyRate: getEnvAsFloat("CHAOS_LATENCY_RATE", 0),
+		LatencyMs:   getEnvAsInt("CHAOS_LATENCY_MS", 0),
+		ErrorRate:   getEnvAsFloat("CHAOS_ERROR_RATE", 0),
+		ErrorStatus: getEnvAsInt("CHAOS_ERROR_STATUS", 503),
+		DropRate:    getEnvAsFloat("CHAOS_DROP_RATE", 0),
+	}}
+	return cfg
+}
+
+// getStaticPathConfigFromEnv 从 STATIC_* 环境变量读取静态文件目录配置
+func getStaticPathConfigFromEnv() StaticPathConfig {
+	return StaticPathConfig{
+		Path:        getEnv("STATIC_PATH", ""),
+		Root:        getEnv("STATIC_ROOT", ""),
+		CacheMaxAge: getEnvAsInt("STATIC_CACHE_MAX_AGE", 0),
+		RequireAuth: getEnvAsBool("STATIC_REQUIRE_AUTH", false),
+		Listing:     getEnvAsBool("STATIC_LISTING", false),
+		CORSOrigins: getEnvAsSlice("STATIC_CORS_ORIGINS", nil),
+		RateLimit:   getEnvAsInt("STATIC_RATE_LIMIT", 0),
+	}
+}
+
+// getAlertConfigFromEnv 从 LOG_ALERT_* 环境变量读取告警配置，未设置的项保留 defaults 中的值
+func getAlertConfigFromEnv(defaults AlertConfig) AlertConfig {
+	return AlertConfig{
+		Enabled:            getEnvAsBool("LOG_ALERT_ENABLED", defaults.Enabled),
+		MinLevel:           getEnv("LOG_ALERT_MIN_LEVEL", defaults.MinLevel),
+		MessagePattern:     getEnv("LOG_ALERT_MESSAGE_PATTERN", defaults.MessagePattern),
+		Threshold:          getEnvAsInt("LOG_ALERT_THRESHOLD", defaults.Threshold),
+		Window:             getEnvAsInt("LOG_ALERT_WINDOW", defaults.Window),
+		DedupWindow:        getEnvAsInt("LOG_ALERT_DEDUP_WINDOW", defaults.DedupWindow),
+		WebhookURL:         getEnv("LOG_ALERT_WEBHOOK_URL", defaults.WebhookURL),
+		SlackWebhookURL:    getEnv("LOG_ALERT_SLACK_WEBHOOK_URL", defaults.SlackWebhookURL),
+		DingTalkWebhookURL: getEnv("LOG_ALERT_DINGTALK_WEBHOOK_URL", defaults.DingTalkWebhookURL),
+		DingTalkSecret:     getEnv("LOG_ALERT_DINGTALK_SECRET", defaults.DingTalkSecret),
+		FeishuWebhookURL:   getEnv("LOG_ALERT_FEISHU_WEBHOOK_URL", defaults.FeishuWebhookURL),
+		FeishuSecret:       getEnv("LOG_ALERT_FEISHU_SECRET", defaults.FeishuSecret),
+		WeComWebhookURL:    getEnv("LOG_ALERT_WECOM_WEBHOOK_URL", defaults.WeComWebhookURL),
+		MessageFormat:      getEnv("LOG_ALERT_MESSAGE_FORMAT", defaults.MessageFormat),
+	}
+}
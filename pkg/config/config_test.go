@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -105,4 +106,102 @@ func TestGetEnvFunctions(t *testing.T) {
 	if defaultBool {
 		t.Error("Expected false, got true")
 	}
-}
\ No newline at end of file
+}
+func TestGetTLSConfigFromEnv(t *testing.T) {
+	os.Setenv("REDIS_TLS_ENABLED", "true")
+	os.Setenv("REDIS_TLS_SERVER_NAME", "redis.internal")
+	defer func() {
+		os.Unsetenv("REDIS_TLS_ENABLED")
+		os.Unsetenv("REDIS_TLS_SERVER_NAME")
+	}()
+
+	cm := New()
+	redisTLS := cm.GetRedis().TLS
+	if !redisTLS.Enabled {
+		t.Error("Expected Redis TLS to be enabled")
+	}
+	if redisTLS.ServerName != "redis.internal" {
+		t.Errorf("Expected server name redis.internal, got %s", redisTLS.ServerName)
+	}
+
+	if cm.GetDatabase().TLS.Enabled {
+		t.Error("Expected database TLS to be disabled by default")
+	}
+}
+
+func TestTLSConfig_BuildTLS(t *testing.T) {
+	disabled := &TLSConfig{}
+	tlsConfig, err := disabled.BuildTLS("localhost")
+	if err != nil || tlsConfig != nil {
+		t.Errorf("Expected nil config for disabled TLS, got %v, %v", tlsConfig, err)
+	}
+
+	enabled := &TLSConfig{Enabled: true}
+	tlsConfig, err = enabled.BuildTLS("db.example.com")
+	if err != nil {
+		t.Fatalf("BuildTLS failed: %v", err)
+	}
+	if tlsConfig.ServerName != "db.example.com" {
+		t.Errorf("Expected server name db.example.com, got %s", tlsConfig.ServerName)
+	}
+
+	missingCA := &TLSConfig{Enabled: true, CAFile: "/non/existent/ca.pem"}
+	if _, err := missingCA.BuildTLS("localhost"); err == nil {
+		t.Error("Expected error for missing CA file")
+	}
+
+	halfPair := &TLSConfig{Enabled: true, CertFile: "client.pem"}
+	if _, err := halfPair.BuildTLS("localhost"); err == nil {
+		t.Error("Expected error when key file is missing")
+	}
+}
+
+func TestServerTLSConfig_BuildServerTLS(t *testing.T) {
+	disabled := &ServerTLSConfig{}
+	tlsConfig, err := disabled.BuildServerTLS()
+	if err != nil || tlsConfig != nil {
+		t.Errorf("Expected nil config for disabled TLS, got %v, %v", tlsConfig, err)
+	}
+
+	if _, err := (&ServerTLSConfig{Enabled: true, CertFile: "server.pem"}).BuildServerTLS(); err == nil {
+		t.Error("Expected error when key file is missing")
+	}
+	if _, err := (&ServerTLSConfig{Enabled: true, CertFile: "/non/existent/server.pem", KeyFile: "/non/existent/server-key.pem"}).BuildServerTLS(); err == nil {
+		t.Error("Expected error for missing certificate files")
+	}
+
+	cfg := defaultConfig(ModeDebug)
+	cfg.Server.TLS = ServerTLSConfig{Enabled: true, ClientAuth: "require"}
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "SERVER_TLS_CERT_FILE") || !strings.Contains(err.Error(), "SERVER_TLS_CLIENT_CA_FILE") {
+		t.Errorf("Expected missing certificate and client CA errors, got %v", err)
+	}
+	cfg.Server.TLS = ServerTLSConfig{Enabled: true, CertFile: "server.pem", KeyFile: "server-key.pem", ClientAuth: "optional"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `"optional"`) {
+		t.Errorf("Expected invalid client auth error, got %v", err)
+	}
+}
+
+func TestGetChaosConfigFromEnv(t *testing.T) {
+	if cfg := getChaosConfigFromEnv(); cfg.Enabled || len(cfg.Rules) != 0 {
+		t.Errorf("Expected chaos to be disabled by default, got %+v", cfg)
+	}
+
+	os.Setenv("CHAOS_ENABLED", "true")
+	os.Setenv("CHAOS_PATH", "/api/*")
+	os.Setenv("CHAOS_ERROR_RATE", "0.25")
+	defer func() {
+		os.Unsetenv("CHAOS_ENABLED")
+		os.Unsetenv("CHAOS_PATH")
+		os.Unsetenv("CHAOS_ERROR_RATE")
+	}()
+
+	cfg := getChaosConfigFromEnv()
+	if !cfg.Enabled || len(cfg.Rules) != 1 {
+		t.Fatalf("Expected one chaos rule, got %+v", cfg)
+	}
+	rule := cfg.Rules[0]
+	if rule.Path != "/api/*" || rule.ErrorRate != 0.25 || rule.ErrorStatus != 503 {
+		t.Errorf("Unexpected chaos rule: %+v", rule)
+	}
+}
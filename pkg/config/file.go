@@ -0,0 +1,122 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFiles 未指定配置文件时在工作目录中依次查找的文件
+var defaultConfigFiles = []string{"config.yaml", "config.yml", "config.json", "config.toml"}
+
+// configFilePath 获取配置文件路径：显式指定的路径 > CONFIG_FILE 环境变量 > 工作目录中的默认文件
+func (cm *ConfigManager) configFilePath() string {
+	if cm.configFile != "" {
+		return cm.configFile
+	}
+	if path := getEnv("CONFIG_FILE", ""); path != "" {
+		return path
+	}
+	for _, name := range defaultConfigFiles {
+		if info, err := os.Stat(name); err == nil && !info.IsDir() {
+			return name
+		}
+	}
+	return ""
+}
+
+// readConfigFile 读取配置文件并统一转换为JSON，字段名与结构体的json标签一致
+// 配置文件只需包含要覆盖的字段，未出现的字段保留默认值
+func readConfigFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	var values map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		return data, nil
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q, expected .yaml, .yml, .json or .toml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	converted, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert config file %s: %w", path, err)
+	}
+	return converted, nil
+}
+
+// Flags 命令行参数，优先级最高，只有显式传入的参数会覆盖配置
+type Flags struct {
+	ConfigFile string
+	Host       string
+	Port       int
+	Mode       string
+	LogLevel   string
+
+	fs *flag.FlagSet
+}
+
+// BindFlags 在 FlagSet 上注册配置相关的命令行参数，fs 为 nil 时使用 flag.CommandLine
+// 解析参数后传给 NewWithFlags：
+//
+//	flags := config.BindFlags(nil)
+//	flag.Parse()
+//	cm, err := config.NewWithFlags(flags)
+func BindFlags(fs *flag.FlagSet) *Flags {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	f := &Flags{fs: fs}
+	fs.StringVar(&f.ConfigFile, "config", "", "config file path (.yaml, .yml, .json or .toml)")
+	fs.StringVar(&f.Host, "host", "", "server listen host")
+	fs.IntVar(&f.Port, "port", 0, "server listen port")
+	fs.StringVar(&f.Mode, "mode", "", "server mode: debug, release or test")
+	fs.StringVar(&f.LogLevel, "log-level", "", "log level: debug, info, warn or error")
+	return f
+}
+
+// isSet 参数是否在命令行中显式传入
+func (f *Flags) isSet(name string) bool {
+	if f.fs == nil {
+		return false
+	}
+	set := false
+	f.fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// apply 使用显式传入的命令行参数覆盖配置
+func (f *Flags) apply(config *Config) {
+	if f.isSet("host") {
+		config.Server.Host = f.Host
+	}
+	if f.isSet("port") {
+		config.Server.Port = f.Port
+	}
+	if f.isSet("mode") {
+		config.Server.Mode = f.Mode
+	}
+	if f.isSet("log-level") {
+		config.Log.Level = f.LogLevel
+	}
+}
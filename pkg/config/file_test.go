@@ -0,0 +1,196 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigFile 在临时目录写入配置文件
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadFromFileFormats(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "server:\n  port: 9100\n  mode: release\nredis:\n  tls:\n    enabled: true\n",
+		"config.json": `{"server": {"port": 9100, "mode": "release"}, "redis": {"tls": {"enabled": true}}}`,
+		"config.toml": "[server]\nport = 9100\nmode = \"release\"\n\n[redis.tls]\nenabled = true\n",
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			cm, err := NewWithFile(writeConfigFile(t, name, content))
+			if err != nil {
+				t.Fatalf("Failed to load config file: %v", err)
+			}
+			cfg := cm.Get()
+
+			if cfg.Server.Port != 9100 || cfg.Server.Mode != ModeRelease || !cfg.Redis.TLS.Enabled {
+				t.Errorf("Config file values not applied: %+v", cfg.Server)
+			}
+			// 未出现的字段保留默认值，且默认值按文件中的模式确定
+			if cfg.Server.Host != "0.0.0.0" || cfg.Redis.Port != 6379 {
+				t.Errorf("Expected defaults for missing fields, got host %s redis port %d", cfg.Server.Host, cfg.Redis.Port)
+			}
+			if cfg.Server.EnableSwagger || cfg.Database.LogLevel != "warn" {
+				t.Error("Expected release mode defaults from config file mode")
+			}
+			if status := cm.Status(); status.Source != ConfigSourceFile || !strings.HasSuffix(status.File, name) {
+				t.Errorf("Unexpected status %+v", status)
+			}
+		})
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "server:\n  port: 9100\n  host: file.local\nlog:\n  level: warn\njwt:\n  issuer: from-file\n")
+	t.Setenv("SERVER_PORT", "9200")
+	t.Setenv("LOG_LEVEL", "error")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := BindFlags(fs)
+	if err := fs.Parse([]string{"-config", path, "-port", "9300"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	cm, err := NewWithFlags(flags)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cfg := cm.Get()
+	if cfg.Server.Port != 9300 {
+		t.Errorf("Expected flag to override env, got port %d", cfg.Server.Port)
+	}
+	if cfg.Log.Level != "error" {
+		t.Errorf("Expected env to override file, got log level %s", cfg.Log.Level)
+	}
+	if cfg.Server.Host != "file.local" || cfg.JWT.Issuer != "from-file" {
+		t.Errorf("Expected file to override defaults, got host %s issuer %s", cfg.Server.Host, cfg.JWT.Issuer)
+	}
+	if cfg.JWT.ExpireHours != 24 {
+		t.Errorf("Expected default expire hours, got %d", cfg.JWT.ExpireHours)
+	}
+}
+
+func TestTimezoneConfig(t *testing.T) {
+	t.Setenv("SERVER_TIMEZONE", "UTC")
+	cfg := New().Get()
+	if cfg.Server.Timezone != "UTC" || cfg.Database.TimeZone != "" {
+		t.Errorf("Expected database timezone to stay unset unless configured, got %s and %s", cfg.Server.Timezone, cfg.Database.TimeZone)
+	}
+
+	t.Setenv("DB_TIMEZONE", "America/New_York")
+	cfg = New().Get()
+	if cfg.Database.TimeZone != "America/New_York" {
+		t.Errorf("Expected DB_TIMEZONE to override, got %s", cfg.Database.TimeZone)
+	}
+	location, err := cfg.Database.Location()
+	if err != nil || location.String() != "America/New_York" {
+		t.Errorf("Expected America/New_York location, got %v %v", location, err)
+	}
+
+	invalid := defaultConfig(ModeDebug)
+	invalid.Server.Timezone = "Mars/Olympus"
+	if err := invalid.Validate(); err == nil || !strings.Contains(err.Error(), "Mars/Olympus") {
+		t.Errorf("Expected invalid timezone to fail validation, got %v", err)
+	}
+}
+
+func TestLoadInvalidFile(t *testing.T) {
+	t.Setenv("SERVER_PORT", "9200")
+
+	// 显式指定的配置文件无效时返回错误，不回退到默认值
+	if cm, err := NewWithFile(writeConfigFile(t, "config.ini", "port=1")); err == nil || cm != nil || !strings.Contains(err.Error(), "unsupported config file format") {
+		t.Errorf("Expected unsupported format error, got %v", err)
+	}
+	if _, err := NewWithFile(writeConfigFile(t, "config.yaml", "server: [1, 2")); err == nil {
+		t.Error("Expected parse error for malformed YAML")
+	}
+	if _, err := NewWithFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for missing config file")
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := BindFlags(fs)
+	if err := fs.Parse([]string{"-config", writeConfigFile(t, "config.ini", "port=1")}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if _, err := NewWithFlags(flags); err == nil {
+		t.Error("Expected NewWithFlags to return the config file error")
+	}
+
+	// 自动发现的配置文件无效时 Reload 返回错误，并从环境变量加载
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "config.ini", "port=1"))
+	cm := New()
+	if err := cm.Reload(); err == nil || !strings.Contains(err.Error(), "unsupported config file format") {
+		t.Errorf("Expected unsupported format error, got %v", err)
+	}
+	if cm.Get() == nil || cm.Get().Server.Port != 9200 || cm.Status().Source != ConfigSourceLocal {
+		t.Errorf("Expected fallback to environment variables")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := defaultConfig(ModeRelease)
+	valid.JWT.Secret = "release-secret"
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+
+	invalid := defaultConfig(ModeRelease)
+	invalid.Server.Port = 0
+	invalid.JWT.Secret = ""
+	invalid.Database.Type = "oracle"
+	invalid.JWT.Password.Algorithm = "scrypt"
+	err := invalid.Validate()
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+	if len(validationErr.Problems) != 4 {
+		t.Errorf("Expected 4 problems, got %v", validationErr.Problems)
+	}
+	for _, want := range []string{"port 0", "JWT secret", "oracle", "scrypt"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got %v", want, err)
+		}
+	}
+
+	// 非release模式允许空密钥（由安全检查给出警告）
+	debug := defaultConfig(ModeDebug)
+	debug.JWT.Secret = ""
+	if err := debug.Validate(); err != nil {
+		t.Errorf("Expected empty secret to be allowed in debug mode, got %v", err)
+	}
+}
+
+func TestValidateTrustedHeader(t *testing.T) {
+	cfg := defaultConfig(ModeDebug)
+	cfg.JWT.TrustedHeader.Enabled = true
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "TRUSTED_HEADER_PROXIES") {
+		t.Errorf("Expected missing trusted source error, got %v", err)
+	}
+
+	cfg.JWT.TrustedHeader.TrustedProxies = []string{"10.0.0.0/8", "192.0.2.1", "::1", "gateway"}
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `"gateway"`) {
+		t.Errorf("Expected invalid proxy error, got %v", err)
+	}
+
+	cfg.JWT.TrustedHeader.TrustedProxies = cfg.JWT.TrustedHeader.TrustedProxies[:3]
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid trusted header config, got %v", err)
+	}
+	if cfg.JWT.TrustedHeader.UserHeader != "X-Auth-User" || cfg.JWT.TrustedHeader.RolesHeader != "X-Auth-Roles" {
+		t.Errorf("Unexpected default headers: %+v", cfg.JWT.TrustedHeader)
+	}
+}
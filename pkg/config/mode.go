@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 运行模式，与 gin 的模式保持一致
+const (
+	ModeDebug   = "debug"
+	ModeRelease = "release"
+	ModeTest    = "test"
+)
+
+// IsProduction 是否为生产环境（ENV=production 或 prod）
+func (c *Config) IsProduction() bool {
+	env := strings.ToLower(c.Server.Environment)
+	return env == "production" || env == "prod"
+}
+
+// IsRelease 是否以release模式运行
+func (c *Config) IsRelease() bool {
+	return c.Server.Mode == ModeRelease
+}
+
+// IsDebug 是否以debug模式运行，未设置模式时与 gin 一致按debug处理
+func (c *Config) IsDebug() bool {
+	return c.Server.Mode == ModeDebug || c.Server.Mode == ""
+}
+
+// ValidateMode 校验运行模式
+// 生产环境下以debug模式运行会返回错误，除非开启 AllowDebugInProduction
+func (c *Config) ValidateMode() error {
+	switch c.Server.Mode {
+	case "", ModeDebug, ModeRelease, ModeTest:
+	default:
+		return fmt.Errorf("invalid server mode %q, expected debug, release or test", c.Server.Mode)
+	}
+
+	if c.IsProduction() && c.IsDebug() && !c.Server.AllowDebugInProduction {
+		return fmt.Errorf("refusing to run in debug mode with ENV=%s, set SERVER_MODE=release or SERVER_ALLOW_DEBUG_IN_PRODUCTION=true", c.Server.Environment)
+	}
+	return nil
+}
+
+// defaultDBLogLevel 根据运行模式获取默认的GORM日志级别，release模式下不记录每条SQL
+func defaultDBLogLevel(mode string) string {
+	if mode == ModeRelease {
+		return "warn"
+	}
+	return "info"
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultJWTSecret 未配置 JWT_SECRET 时使用的默认密钥，生产环境必须修改
+const DefaultJWTSecret = "hwhkit-default-secret-change-in-production"
+
+// minJWTSecretLength 建议的JWT密钥最小长度
+const minJWTSecretLength = 32
+
+// 安全检查结果级别
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// SecurityFinding 单项安全检查结果
+type SecurityFinding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// SecurityReport 启动安全检查报告
+type SecurityReport struct {
+	Mode      string            `json:"mode"`
+	Passed    bool              `json:"passed"`
+	Findings  []SecurityFinding `json:"findings"`
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+// HasCritical 是否存在严重问题
+func (r *SecurityReport) HasCritical() bool {
+	for _, finding := range r.Findings {
+		if finding.Severity == SeverityCritical {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckSecurity 检查不安全的默认配置
+// release 模式下默认密钥、空数据库密码、带凭证的通配CORS为严重问题，其他模式下仅为警告
+func (c *Config) CheckSecurity() *SecurityReport {
+	report := &SecurityReport{
+		Mode:      c.Server.Mode,
+		Findings:  make([]SecurityFinding, 0),
+		CheckedAt: time.Now(),
+	}
+
+	severity := SeverityWarning
+	if c.IsRelease() {
+		severity = SeverityCritical
+	}
+
+	add := func(check, severity, message string) {
+		report.Findings = append(report.Findings, SecurityFinding{
+			Check:    check,
+			Severity: severity,
+			Message:  message,
+		})
+	}
+
+	switch {
+	case c.JWT.Secret == "" || c.JWT.Secret == DefaultJWTSecret:
+		add("jwt_default_secret", severity, "JWT secret is empty or uses the built-in default, set JWT_SECRET")
+	case len(c.JWT.Secret) < minJWTSecretLength:
+		add("jwt_weak_secret", SeverityWarning, "JWT secret is shorter than 32 characters")
+	}
+
+	if c.Database.Password == "" {
+		add("db_empty_password", severity, "database password is empty, set DB_PASSWORD")
+	}
+
+	if c.Server.EnableCORS && c.Server.CORSAllowCredentials {
+		for _, origin := range c.Server.CORSAllowOrigins {
+			if origin == "*" {
+				add("cors_wildcard_credentials", severity, "CORS allows credentials with wildcard origin, set SERVER_CORS_ALLOW_ORIGINS explicitly")
+				break
+			}
+		}
+	}
+
+	if c.IsRelease() && c.Server.EnableSwagger {
+		add("swagger_enabled", SeverityWarning, "Swagger UI is enabled in release mode, set SERVER_ENABLE_SWAGGER=false")
+	}
+
+	if (c.IsRelease() || c.IsProduction()) && strings.EqualFold(c.Database.LogLevel, "info") {
+		add("gorm_verbose_logging", SeverityWarning, "GORM logs every SQL statement in production, set DB_LOG_LEVEL=warn")
+	}
+
+	if c.IsProduction() && c.IsDebug() {
+		add("debug_in_production", SeverityWarning, "server runs in debug mode with ENV=production")
+	}
+
+	if c.Server.Chaos.Enabled {
+		add("chaos_enabled", SeverityWarning, "fault injection is enabled, it is ignored in release mode")
+	}
+
+	if c.Server.DevRoutes {
+		add("dev_routes_enabled", SeverityWarning, "development routes under /demo are enabled, set SERVER_DEV_ROUTES=false before deploying")
+	}
+
+	report.Passed = len(report.Findings) == 0
+	return report
+}
@@ -0,0 +1,92 @@
+package config
+
+import "testing"
+
+func TestConfig_CheckSecurity(t *testing.T) {
+	cfg := &Config{
+		Server: ServerConfig{
+			Mode:                 "release",
+			EnableCORS:           true,
+			CORSAllowOrigins:     []string{"*"},
+			CORSAllowCredentials: true,
+		},
+		JWT: JWTConfig{Secret: DefaultJWTSecret},
+	}
+
+	report := cfg.CheckSecurity()
+	if report.Passed {
+		t.Fatal("Expected insecure config to fail")
+	}
+	if !report.HasCritical() {
+		t.Error("Expected critical findings in release mode")
+	}
+
+	checks := make(map[string]string)
+	for _, finding := range report.Findings {
+		checks[finding.Check] = finding.Severity
+	}
+	for _, check := range []string{"jwt_default_secret", "db_empty_password", "cors_wildcard_credentials"} {
+		if checks[check] != SeverityCritical {
+			t.Errorf("Expected %s to be critical, got %q", check, checks[check])
+		}
+	}
+
+	// 非 release 模式仅警告
+	cfg.Server.Mode = "debug"
+	if cfg.CheckSecurity().HasCritical() {
+		t.Error("Expected only warnings in debug mode")
+	}
+
+	// 安全配置
+	cfg.Server.Mode = "release"
+	cfg.Server.CORSAllowOrigins = []string{"https://example.com"}
+	cfg.JWT.Secret = "a-very-long-and-random-secret-value-for-tests"
+	cfg.Database.Password = "secret"
+	if report := cfg.CheckSecurity(); !report.Passed {
+		t.Errorf("Expected secure config to pass, got %+v", report.Findings)
+	}
+}
+
+func TestConfig_CheckSecurityProduction(t *testing.T) {
+	cfg := &Config{
+		Server:   ServerConfig{Mode: "release", EnableSwagger: true},
+		Database: DatabaseConfig{Password: "secret", LogLevel: "info"},
+		JWT:      JWTConfig{Secret: "a-very-long-and-random-secret-value-for-tests"},
+	}
+
+	checks := make(map[string]bool)
+	for _, finding := range cfg.CheckSecurity().Findings {
+		checks[finding.Check] = true
+	}
+	for _, check := range []string{"swagger_enabled", "gorm_verbose_logging"} {
+		if !checks[check] {
+			t.Errorf("Expected %s warning in release mode", check)
+		}
+	}
+}
+
+func TestConfig_ValidateMode(t *testing.T) {
+	cfg := &Config{Server: ServerConfig{Mode: "debug", Environment: "production"}}
+	if err := cfg.ValidateMode(); err == nil {
+		t.Error("Expected debug mode to be refused in production")
+	}
+
+	cfg.Server.AllowDebugInProduction = true
+	if err := cfg.ValidateMode(); err != nil {
+		t.Errorf("Expected override to allow debug mode, got %v", err)
+	}
+
+	cfg.Server = ServerConfig{Mode: "release", Environment: "production"}
+	if err := cfg.ValidateMode(); err != nil {
+		t.Errorf("Expected release mode to pass, got %v", err)
+	}
+
+	cfg.Server.Mode = "verbose"
+	if err := cfg.ValidateMode(); err == nil {
+		t.Error("Expected invalid mode to fail")
+	}
+
+	if defaultDBLogLevel("release") != "warn" || defaultDBLogLevel("debug") != "info" {
+		t.Error("Expected quieter GORM logging by default in release mode")
+	}
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 配置来源
+const (
+	ConfigSourceRemote   = "remote"   // 远程配置API
+	ConfigSourceSnapshot = "snapshot" // 远程不可用时使用的本地快照
+	ConfigSourceLocal    = "local"    // 环境变量和.env文件
+	ConfigSourceFile     = "file"     // 本地配置文件，环境变量和命令行参数仍可覆盖
+)
+
+// ConfigStatus 当前配置的来源信息
+type ConfigStatus struct {
+	Source   string    `json:"source"`
+	ETag     string    `json:"etag,omitempty"`
+	File     string    `json:"file,omitempty"` // 本地配置文件路径
+	LoadedAt time.Time `json:"loaded_at"`
+	Age      string    `json:"age"`
+}
+
+// configSnapshot 远程配置快照文件内容
+type configSnapshot struct {
+	ETag      string    `json:"etag"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Config    *Config   `json:"config"`
+}
+
+// Status 获取当前配置的来源和获取时间
+func (cm *ConfigManager) Status() ConfigStatus {
+	status := ConfigStatus{
+		Source:   cm.source,
+		ETag:     cm.etag,
+		LoadedAt: cm.loadedAt,
+		Age:      time.Since(cm.loadedAt).Truncate(time.Second).String(),
+	}
+	if cm.source == ConfigSourceFile {
+		status.File = cm.loadedFile
+	}
+	return status
+}
+
+// saveSnapshot 将当前远程配置写入快照文件，写入失败不影响配置加载
+func (cm *ConfigManager) saveSnapshot() {
+	if cm.snapshotPath == "" || cm.config == nil {
+		return
+	}
+
+	data, err := json.Marshal(configSnapshot{
+		ETag:      cm.etag,
+		FetchedAt: cm.loadedAt,
+		Config:    cm.config,
+	})
+	if err != nil {
+		fmt.Printf("Failed to encode config snapshot: %v\n", err)
+		return
+	}
+
+	if err := writeSnapshot(cm.snapshotPath, data); err != nil {
+		fmt.Printf("Failed to write config snapshot: %v\n", err)
+	}
+}
+
+// writeSnapshot 写入快照文件：快照含密钥，所在目录不存在时以 0700 创建，
+// 先写入同目录下随机命名的临时文件（0600）再重命名，避免被其他用户预先创建或进程中断留下不完整的快照
+func writeSnapshot(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// checkSnapshotFile 校验快照文件可信：必须是普通文件、属于当前用户且其他用户不可读写
+func checkSnapshotFile(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("config snapshot %s is not a regular file", path)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("config snapshot %s has insecure permissions %s, expected 0600", path, info.Mode().Perm())
+	}
+	return checkSnapshotOwner(path, info)
+}
+
+// loadFromSnapshot 从快照文件加载最近一次成功获取的远程配置
+func (cm *ConfigManager) loadFromSnapshot() error {
+	if cm.snapshotPath == "" {
+		return fmt.Errorf("config snapshot is disabled")
+	}
+
+	if err := checkSnapshotFile(cm.snapshotPath); err != nil {
+		return fmt.Errorf("refusing config snapshot: %w", err)
+	}
+	data, err := os.ReadFile(cm.snapshotPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config snapshot: %w", err)
+	}
+
+	var snapshot configSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to decode config snapshot: %w", err)
+	}
+	if snapshot.Config == nil {
+		return fmt.Errorf("config snapshot is empty")
+	}
+
+	cm.config = snapshot.Config
+	cm.etag = snapshot.ETag
+	cm.source = ConfigSourceSnapshot
+	cm.loadedAt = snapshot.FetchedAt
+	return nil
+}
+
+// RemoteURL 远程配置API地址，未使用远程配置时为空
+func (cm *ConfigManager) RemoteURL() string {
+	return cm.configURL
+}
+
+// CheckRemote 检查远程配置API是否可用且返回有效配置，不替换当前配置
+func (cm *ConfigManager) CheckRemote(ctx context.Context) error {
+	if cm.configURL == "" {
+		return fmt.Errorf("remote config is not configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cm.configURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create remote config request: %w", err)
+	}
+	resp, err := cm.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch config from remote: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("remote config API returned status: %d", resp.StatusCode)
+	}
+	var config Config
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return fmt.Errorf("failed to decode remote config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package config
+
+import "os"
+
+// checkSnapshotOwner 非Unix系统不校验文件属主，依赖目录权限
+func checkSnapshotOwner(path string, info os.FileInfo) error {
+	return nil
+}
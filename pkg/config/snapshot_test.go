@@ -0,0 +1,112 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigManager_RemoteSnapshot(t *testing.T) {
+	var conditional int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		json.NewEncoder(w).Encode(Config{Server: ServerConfig{Port: 7070}})
+	}))
+
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot.json")
+	cm := NewWithSnapshot(srv.URL, snapshotPath)
+	if cm.Get().Server.Port != 7070 {
+		t.Fatalf("Expected remote port 7070, got %d", cm.Get().Server.Port)
+	}
+	if status := cm.Status(); status.Source != ConfigSourceRemote || status.ETag != `"v1"` {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	// 未修改时沿用当前配置
+	if err := cm.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if conditional != 1 || cm.Get().Server.Port != 7070 {
+		t.Errorf("Expected conditional request to keep config, got %d requests, port %d", conditional, cm.Get().Server.Port)
+	}
+
+	// 远程不可用时使用快照而不是环境变量默认值
+	srv.Close()
+	fallback := NewWithSnapshot(srv.URL, snapshotPath)
+	if fallback.Get().Server.Port != 7070 {
+		t.Errorf("Expected snapshot port 7070, got %d", fallback.Get().Server.Port)
+	}
+	if status := fallback.Status(); status.Source != ConfigSourceSnapshot {
+		t.Errorf("Expected snapshot source, got %s", status.Source)
+	}
+
+	// 没有快照时回退到本地配置
+	local := NewWithSnapshot(srv.URL, filepath.Join(t.TempDir(), "missing.json"))
+	if status := local.Status(); status.Source != ConfigSourceLocal {
+		t.Errorf("Expected local source, got %s", status.Source)
+	}
+}
+
+func TestConfigManager_SnapshotPermissions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Config{Server: ServerConfig{Port: 7070}})
+	}))
+	defer srv.Close()
+
+	// 快照目录不存在时以 0700 创建，文件为 0600，不留下临时文件
+	dir := filepath.Join(t.TempDir(), "private")
+	snapshotPath := filepath.Join(dir, "snapshot.json")
+	NewWithSnapshot(srv.URL, snapshotPath)
+	info, err := os.Stat(snapshotPath)
+	if err != nil {
+		t.Fatalf("Expected snapshot to be written: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Expected snapshot mode 0600, got %s", perm)
+	}
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Expected snapshot dir mode 0700, got %v %v", info, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected only the snapshot file, got %d entries", len(entries))
+	}
+	if err := checkSnapshotFile(snapshotPath); err != nil {
+		t.Errorf("Expected snapshot to be trusted: %v", err)
+	}
+
+	// 其他用户可读写的快照和符号链接不被信任
+	if err := os.Chmod(snapshotPath, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSnapshotFile(snapshotPath); err == nil {
+		t.Error("Expected world readable snapshot to be rejected")
+	}
+	cm := &ConfigManager{snapshotPath: snapshotPath}
+	if err := cm.loadFromSnapshot(); err == nil {
+		t.Error("Expected loadFromSnapshot to refuse insecure snapshot")
+	}
+	if err := os.Chmod(snapshotPath, 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.json")
+	if err := os.Symlink(snapshotPath, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSnapshotFile(link); err == nil {
+		t.Error("Expected symlinked snapshot to be rejected")
+	}
+
+	// 未设置 CONFIG_SNAPSHOT_PATH 时不缓存
+	t.Setenv("CONFIG_SNAPSHOT_PATH", "")
+	if cm := NewWithURL(srv.URL); cm.snapshotPath != "" {
+		t.Errorf("Expected snapshots to be disabled by default, got %s", cm.snapshotPath)
+	}
+}
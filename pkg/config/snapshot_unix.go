@@ -0,0 +1,21 @@
+//go:build unix
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkSnapshotOwner 校验快照文件属于当前用户
+func checkSnapshotOwner(path string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if uid := os.Getuid(); int(stat.Uid) != uid {
+		return fmt.Errorf("config snapshot %s is owned by uid %d, expected %d", path, stat.Uid, uid)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Location 获取全局默认时区，未配置时为UTC
+func (c *ServerConfig) Location() (*time.Location, error) {
+	return loadLocation(c.Timezone)
+}
+
+// Location 获取数据库连接时区，未配置时为UTC（连接时未配置则不覆盖驱动的默认时区）
+func (c *DatabaseConfig) Location() (*time.Location, error) {
+	return loadLocation(c.TimeZone)
+}
+
+// loadLocation 按IANA名称加载时区
+func loadLocation(name string) (*time.Location, error) {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", name, err)
+	}
+	return location, nil
+}
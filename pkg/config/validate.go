@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ValidationError 配置校验错误，包含所有未通过的检查项
+type ValidationError struct {
+	Problems []string
+}
+
+// Error 实现 error 接口
+func (e *ValidationError) Error() string {
+	return "invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate 校验配置，拒绝明显错误的配置（如端口为0、release模式下JWT密钥为空），应在服务器启动前调用
+// 运行模式的校验规则见 ValidateMode；默认密钥等安全隐患由 CheckSecurity 报告
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if err := c.ValidateMode(); err != nil {
+		add("%v", err)
+	}
+
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		add("server port %d is out of range 1-65535", c.Server.Port)
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.ShutdownTimeout < 0 {
+		add("server timeouts must not be negative")
+	}
+
+	if c.IsRelease() && strings.TrimSpace(c.JWT.Secret) == "" {
+		add("JWT secret must not be empty in release mode, set JWT_SECRET")
+	}
+	if c.JWT.ExpireHours < 0 || c.JWT.RefreshHours < 0 {
+		add("JWT expire hours must not be negative")
+	}
+	switch c.JWT.Password.Algorithm {
+	case "", "bcrypt", "argon2id":
+	default:
+		add("unsupported password algorithm %q, expected bcrypt or argon2id", c.JWT.Password.Algorithm)
+	}
+	if trusted := c.JWT.TrustedHeader; trusted.Enabled {
+		if trusted.UserHeader == "" {
+			add("trusted header auth requires a user header, set TRUSTED_HEADER_USER")
+		}
+		if len(trusted.TrustedProxies) == 0 && len(trusted.ClientCertNames) == 0 {
+			add("trusted header auth requires trusted proxies or client certificate names, set TRUSTED_HEADER_PROXIES or TRUSTED_HEADER_CLIENT_CERT_NAMES")
+		}
+		for _, proxy := range trusted.TrustedProxies {
+			if !validIPOrCIDR(proxy) {
+				add("trusted header proxy %q is not an IP address or CIDR", proxy)
+			}
+		}
+	}
+	if c.Server.StatusPageUser != "" && c.Server.StatusPagePassword == "" {
+		add("status page password must be set when status page user is configured")
+	}
+	if c.Server.DevRoutes && (c.IsRelease() || c.IsProduction()) {
+		add("dev routes must not be enabled in release mode or production, unset SERVER_DEV_ROUTES")
+	}
+	if tlsConfig := c.Server.TLS; tlsConfig.Enabled {
+		if tlsConfig.CertFile == "" || tlsConfig.KeyFile == "" {
+			add("server TLS requires a certificate and key, set SERVER_TLS_CERT_FILE and SERVER_TLS_KEY_FILE")
+		}
+		switch tlsConfig.ClientAuth {
+		case "", "none", "request":
+		case "verify_if_given", "require":
+			if tlsConfig.ClientCAFile == "" {
+				add("server TLS client auth %q requires a client CA, set SERVER_TLS_CLIENT_CA_FILE", tlsConfig.ClientAuth)
+			}
+		default:
+			add("invalid server TLS client auth %q, expected none, request, verify_if_given or require", tlsConfig.ClientAuth)
+		}
+	}
+	staticPaths := make(map[string]bool)
+	for _, static := range c.Server.StaticPaths {
+		switch {
+		case !strings.HasPrefix(static.Path, "/"):
+			add("static path %q must start with /", static.Path)
+		case static.Root == "":
+			add("static path %s requires a root directory", static.Path)
+		case staticPaths[static.Path]:
+			add("static path %s is configured more than once", static.Path)
+		case static.CacheMaxAge < -1 || static.RateLimit < 0:
+			add("static path %s cache max age and rate limit must not be negative", static.Path)
+		}
+		staticPaths[static.Path] = true
+	}
+
+	switch c.Database.Type {
+	case "", "mysql", "postgres":
+	default:
+		add("unsupported database type %q, expected mysql or postgres", c.Database.Type)
+	}
+	if c.Database.Port < 0 || c.Database.Port > 65535 {
+		add("database port %d is out of range 1-65535", c.Database.Port)
+	}
+	if c.Redis.Port < 0 || c.Redis.Port > 65535 {
+		add("redis port %d is out of range 1-65535", c.Redis.Port)
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "", "trace", "debug", "info", "warn", "warning", "error", "fatal", "panic":
+	default:
+		add("invalid log level %q", c.Log.Level)
+	}
+
+	if c.Tracing.SampleRate < 0 || c.Tracing.SampleRate > 1 {
+		add("tracing sample rate %v is out of range 0-1", c.Tracing.SampleRate)
+	}
+
+	if _, err := c.Server.Location(); err != nil {
+		add("server %v", err)
+	}
+	if _, err := c.Database.Location(); err != nil {
+		add("database %v", err)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validIPOrCIDR 是否为合法的IP地址或CIDR
+func validIPOrCIDR(value string) bool {
+	if strings.Contains(value, "/") {
+		_, _, err := net.ParseCIDR(value)
+		return err == nil
+	}
+	return net.ParseIP(value) != nil
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Change 配置键的变更
+type Change struct {
+	Key string      // 配置键，如 server.port
+	Old interface{} // 变更前的值
+	New interface{} // 变更后的值
+}
+
+// keyWatcher 单个配置键的订阅
+type keyWatcher struct {
+	id  uint64
+	key string
+	fn  func(Change)
+}
+
+// keyWatchers 按配置键订阅的变更回调
+type keyWatchers struct {
+	mu       sync.Mutex
+	nextID   uint64
+	watchers []*keyWatcher
+}
+
+// OnChange 订阅配置键的变更，Load 或 Reload 后该键的值发生变化时按订阅顺序同步调用 fn
+// 键为 json 标签组成的路径，如 server.port、log.level、server.cors_allow_origins；
+// 也可以订阅整个配置段（如 server.chaos），段内任一字段变化时触发。键不存在时返回错误，返回的函数用于取消订阅
+func (cm *ConfigManager) OnChange(key string, fn func(Change)) (func(), error) {
+	if fn == nil {
+		return nil, fmt.Errorf("config change callback for %s is nil", key)
+	}
+	if _, err := lookupKey(reflect.TypeOf(Config{}), key); err != nil {
+		return nil, err
+	}
+	return cm.watch.add(key, fn), nil
+}
+
+// OnChangeAs 订阅配置键的变更并以具体类型接收新旧值，如
+//
+//	config.OnChangeAs(cm, "log.level", func(old, new string) { ... })
+//
+// T 与配置字段类型不一致时返回错误
+func OnChangeAs[T any](cm *ConfigManager, key string, fn func(old, new T)) (func(), error) {
+	if fn == nil {
+		return nil, fmt.Errorf("config change callback for %s is nil", key)
+	}
+	fieldType, err := lookupKey(reflect.TypeOf(Config{}), key)
+	if err != nil {
+		return nil, err
+	}
+	if want := reflect.TypeOf((*T)(nil)).Elem(); fieldType != want {
+		return nil, fmt.Errorf("config key %s is %s, not %s", key, fieldType, want)
+	}
+	return cm.watch.add(key, func(change Change) {
+		fn(change.Old.(T), change.New.(T))
+	}), nil
+}
+
+// add 添加订阅
+func (w *keyWatchers) add(key string, fn func(Change)) func() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.nextID++
+	id := w.nextID
+	w.watchers = append(w.watchers, &keyWatcher{id: id, key: key, fn: fn})
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		for i, watcher := range w.watchers {
+			if watcher.id == id {
+				w.watchers = append(w.watchers[:i], w.watchers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// notify 比较新旧配置，对值发生变化的订阅键调用回调；首次加载或配置未替换时不触发
+func (w *keyWatchers) notify(previous, current *Config) {
+	if previous == nil || current == nil || previous == current {
+		return
+	}
+	w.mu.Lock()
+	watchers := make([]*keyWatcher, len(w.watchers))
+	copy(watchers, w.watchers)
+	w.mu.Unlock()
+
+	oldRoot, newRoot := reflect.ValueOf(previous).Elem(), reflect.ValueOf(current).Elem()
+	for _, watcher := range watchers {
+		oldValue, newValue := valueAt(oldRoot, watcher.key), valueAt(newRoot, watcher.key)
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		watcher.fn(Change{Key: watcher.key, Old: oldValue, New: newValue})
+	}
+}
+
+// lookupKey 按 json 标签路径查找配置字段类型
+func lookupKey(root reflect.Type, key string) (reflect.Type, error) {
+	if key == "" {
+		return nil, fmt.Errorf("config key is empty")
+	}
+	current := root
+	for _, name := range strings.Split(key, ".") {
+		if current.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("unknown config key %s", key)
+		}
+		field, ok := fieldByTag(current, name)
+		if !ok {
+			return nil, fmt.Errorf("unknown config key %s", key)
+		}
+		current = field.Type
+	}
+	return current, nil
+}
+
+// valueAt 按 json 标签路径读取配置值，路径已由 lookupKey 校验
+func valueAt(root reflect.Value, key string) interface{} {
+	current := root
+	for _, name := range strings.Split(key, ".") {
+		field, _ := fieldByTag(current.Type(), name)
+		current = current.FieldByIndex(field.Index)
+	}
+	return current.Interface()
+}
+
+// fieldByTag 按 json 标签名查找结构体字段，忽略标签为 - 的字段
+func fieldByTag(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		if tag == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfigManager_OnChange(t *testing.T) {
+	t.Setenv("SERVER_PORT", "8080")
+	t.Setenv("LOG_LEVEL", "info")
+	t.Setenv("SERVER_CORS_ALLOW_ORIGINS", "https://a.example.com")
+	cm := New()
+
+	var changes []Change
+	if _, err := cm.OnChange("server.port", func(change Change) {
+		changes = append(changes, change)
+	}); err != nil {
+		t.Fatalf("OnChange failed: %v", err)
+	}
+	var levels [][2]string
+	cancelLevel, err := OnChangeAs(cm, "log.level", func(old, new string) {
+		levels = append(levels, [2]string{old, new})
+	})
+	if err != nil {
+		t.Fatalf("OnChangeAs failed: %v", err)
+	}
+	var origins []string
+	if _, err := OnChangeAs(cm, "server.cors_allow_origins", func(old, new []string) {
+		origins = new
+	}); err != nil {
+		t.Fatalf("OnChangeAs failed: %v", err)
+	}
+	sectionChanges := 0
+	if _, err := cm.OnChange("redis", func(Change) { sectionChanges++ }); err != nil {
+		t.Fatalf("OnChange failed: %v", err)
+	}
+
+	// 未变化的键不触发
+	if err := cm.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if len(changes) != 0 || len(levels) != 0 || origins != nil || sectionChanges != 0 {
+		t.Fatalf("Expected no changes, got %v %v %v %d", changes, levels, origins, sectionChanges)
+	}
+
+	t.Setenv("SERVER_PORT", "9090")
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("SERVER_CORS_ALLOW_ORIGINS", "https://a.example.com,https://b.example.com")
+	if err := cm.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Key != "server.port" || changes[0].Old != 8080 || changes[0].New != 9090 {
+		t.Errorf("Unexpected port changes: %+v", changes)
+	}
+	if len(levels) != 1 || levels[0] != [2]string{"info", "debug"} {
+		t.Errorf("Unexpected level changes: %v", levels)
+	}
+	if !reflect.DeepEqual(origins, []string{"https://a.example.com", "https://b.example.com"}) {
+		t.Errorf("Unexpected origins: %v", origins)
+	}
+	if sectionChanges != 0 {
+		t.Errorf("Expected redis section to be unchanged, got %d changes", sectionChanges)
+	}
+
+	// 取消订阅后不再触发
+	cancelLevel()
+	t.Setenv("LOG_LEVEL", "warn")
+	if err := cm.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if len(levels) != 1 {
+		t.Errorf("Expected cancelled watcher not to run, got %v", levels)
+	}
+	t.Setenv("REDIS_DB", "3")
+	if err := cm.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if sectionChanges != 1 {
+		t.Errorf("Expected redis section change, got %d", sectionChanges)
+	}
+
+	// 未知键、类型不一致、标签为 - 的字段都拒绝订阅
+	if _, err := cm.OnChange("server.missing", func(Change) {}); err == nil {
+		t.Error("Expected error for unknown key")
+	}
+	if _, err := cm.OnChange("server.port.value", func(Change) {}); err == nil {
+		t.Error("Expected error for key below a scalar")
+	}
+	if _, err := cm.OnChange("server.status_page_password", func(Change) {}); err == nil {
+		t.Error("Expected error for untagged secret field")
+	}
+	if _, err := OnChangeAs(cm, "server.port", func(old, new string) {}); err == nil {
+		t.Error("Expected error for mismatched type")
+	}
+}
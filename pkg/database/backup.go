@@ -0,0 +1,354 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/config"
+)
+
+// backupExt 备份文件扩展名（gzip压缩的SQL）
+const backupExt = ".sql.gz"
+
+// BackupInfo 备份文件信息
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BackupStorage 备份存储驱动
+type BackupStorage interface {
+	Save(ctx context.Context, name string, r io.Reader) error
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	Delete(ctx context.Context, name string) error
+	List(ctx context.Context) ([]BackupInfo, error)
+}
+
+// LocalBackupStorage 本地目录备份存储
+type LocalBackupStorage struct {
+	Dir string
+}
+
+// path 获取备份文件路径，拒绝包含目录的名称
+func (s *LocalBackupStorage) path(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) {
+		return "", fmt.Errorf("invalid backup name: %q", name)
+	}
+	return filepath.Join(s.Dir, name), nil
+}
+
+// Save 保存备份，写入完成前使用临时文件
+func (s *LocalBackupStorage) Save(ctx context.Context, name string, r io.Reader) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create backup dir: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Open 打开备份
+func (s *LocalBackupStorage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete 删除备份
+func (s *LocalBackupStorage) Delete(ctx context.Context, name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// List 列出备份，按创建时间倒序
+func (s *LocalBackupStorage) List(ctx context.Context) ([]BackupInfo, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []BackupInfo{}, nil
+		}
+		return nil, fmt.Errorf("failed to read backup dir: %w", err)
+	}
+
+	backups := make([]BackupInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), backupExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{Name: entry.Name(), Size: info.Size(), CreatedAt: info.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// BackupAuditEvent 备份操作审计事件
+type BackupAuditEvent struct {
+	Action   string        // backup、restore、list
+	Name     string        // 备份名称
+	Database string        // 源数据库或恢复的目标数据库
+	Duration time.Duration // 耗时
+	Err      error         // 操作失败时的错误
+}
+
+// BackupManager 数据库备份管理器，使用 mysqldump/pg_dump 导出并以gzip压缩写入存储驱动
+type BackupManager struct {
+	config  *config.DatabaseConfig
+	storage BackupStorage
+	audit   func(BackupAuditEvent)
+	now     func() time.Time
+	command func(ctx context.Context, name string, args ...string) *exec.Cmd
+}
+
+// NewBackupManager 创建备份管理器
+func NewBackupManager(cfg *config.DatabaseConfig, storage BackupStorage) *BackupManager {
+	return &BackupManager{
+		config:  cfg,
+		storage: storage,
+		audit:   logBackupAudit,
+		now:     time.Now,
+		command: exec.CommandContext,
+	}
+}
+
+// SetAuditFunc 设置审计回调，默认写入标准日志
+func (b *BackupManager) SetAuditFunc(fn func(BackupAuditEvent)) *BackupManager {
+	if fn != nil {
+		b.audit = fn
+	}
+	return b
+}
+
+// logBackupAudit 默认审计输出
+func logBackupAudit(event BackupAuditEvent) {
+	if event.Err != nil {
+		log.Printf("Database %s failed: name=%s database=%s duration=%s error=%v",
+			event.Action, event.Name, event.Database, event.Duration, event.Err)
+		return
+	}
+	log.Printf("Database %s completed: name=%s database=%s duration=%s",
+		event.Action, event.Name, event.Database, event.Duration)
+}
+
+// record 记录审计事件
+func (b *BackupManager) record(action, name, database string, start time.Time, err error) {
+	b.audit(BackupAuditEvent{
+		Action:   action,
+		Name:     name,
+		Database: database,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+}
+
+// Backup 执行备份，备份名称格式为 <数据库名>-<UTC时间>.sql.gz
+func (b *BackupManager) Backup(ctx context.Context) (info *BackupInfo, err error) {
+	start := time.Now()
+	name := fmt.Sprintf("%s-%s%s", b.config.Name, b.now().UTC().Format("20060102T150405Z"), backupExt)
+	defer func() { b.record("backup", name, b.config.Name, start, err) }()
+
+	tool, args, env, err := dumpCommand(b.config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := b.command(ctx, tool, args...)
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", tool, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", tool, err)
+	}
+
+	// 边导出边压缩写入存储
+	pr, pw := io.Pipe()
+	go func() {
+		gz := gzip.NewWriter(pw)
+		_, err := io.Copy(gz, stdout)
+		if closeErr := gz.Close(); err == nil {
+			err = closeErr
+		}
+		pw.CloseWithError(err)
+	}()
+
+	counter := &countingReader{r: pr}
+	saveErr := b.storage.Save(ctx, name, counter)
+	if saveErr != nil {
+		// 存储失败时终止导出进程，避免阻塞在写入
+		cancel()
+		pr.CloseWithError(saveErr)
+	}
+	waitErr := cmd.Wait()
+
+	switch {
+	case saveErr != nil:
+		return nil, fmt.Errorf("failed to save backup: %w", saveErr)
+	case waitErr != nil:
+		_ = b.storage.Delete(context.Background(), name)
+		return nil, fmt.Errorf("%s failed: %w: %s", tool, waitErr, strings.TrimSpace(stderr.String()))
+	}
+	return &BackupInfo{Name: name, Size: counter.n, CreatedAt: b.now()}, nil
+}
+
+// countingReader 统计读取的字节数
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read 实现 io.Reader 接口
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// List 列出可用备份
+func (b *BackupManager) List(ctx context.Context) (backups []BackupInfo, err error) {
+	start := time.Now()
+	defer func() { b.record("list", "", b.config.Name, start, err) }()
+	return b.storage.List(ctx)
+}
+
+// Restore 将备份恢复到目标数据库，target 为nil时恢复到源数据库
+func (b *BackupManager) Restore(ctx context.Context, name string, target *config.DatabaseConfig) (err error) {
+	if target == nil {
+		target = b.config
+	}
+	start := time.Now()
+	defer func() { b.record("restore", name, target.Name, start, err) }()
+
+	tool, args, env, err := restoreCommand(target)
+	if err != nil {
+		return err
+	}
+
+	reader, err := b.storage.Open(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer reader.Close()
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	defer gz.Close()
+
+	cmd := b.command(ctx, tool, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = gz
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", tool, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Schedule 按固定间隔执行备份，直到 ctx 取消；失败会记录审计日志，不会中断调度
+func (b *BackupManager) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = b.Backup(ctx)
+		}
+	}
+}
+
+// dumpCommand 构建导出命令，密码通过环境变量传递，避免出现在进程列表中
+func dumpCommand(cfg *config.DatabaseConfig) (string, []string, []string, error) {
+	switch cfg.Type {
+	case "mysql":
+		args := append(mysqlClientArgs(cfg), "--single-transaction", "--routines", "--triggers", cfg.Name)
+		return "mysqldump", args, []string{"MYSQL_PWD=" + cfg.Password}, nil
+	case "postgres", "postgresql":
+		args := append(postgresClientArgs(cfg), "--no-owner", "--no-privileges")
+		return "pg_dump", args, postgresEnv(cfg), nil
+	default:
+		return "", nil, nil, fmt.Errorf("unsupported database type: %s", cfg.Type)
+	}
+}
+
+// restoreCommand 构建恢复命令，从标准输入读取SQL
+func restoreCommand(cfg *config.DatabaseConfig) (string, []string, []string, error) {
+	switch cfg.Type {
+	case "mysql":
+		return "mysql", append(mysqlClientArgs(cfg), cfg.Name), []string{"MYSQL_PWD=" + cfg.Password}, nil
+	case "postgres", "postgresql":
+		args := append(postgresClientArgs(cfg), "-v", "ON_ERROR_STOP=1", "--single-transaction")
+		return "psql", args, postgresEnv(cfg), nil
+	default:
+		return "", nil, nil, fmt.Errorf("unsupported database type: %s", cfg.Type)
+	}
+}
+
+// mysqlClientArgs MySQL客户端连接参数
+func mysqlClientArgs(cfg *config.DatabaseConfig) []string {
+	return []string{"-h", cfg.Host, "-P", strconv.Itoa(cfg.Port), "-u", cfg.User}
+}
+
+// postgresClientArgs PostgreSQL客户端连接参数
+func postgresClientArgs(cfg *config.DatabaseConfig) []string {
+	return []string{"-h", cfg.Host, "-p", strconv.Itoa(cfg.Port), "-U", cfg.User, "-d", cfg.Name}
+}
+
+// postgresEnv PostgreSQL客户端环境变量
+func postgresEnv(cfg *config.DatabaseConfig) []string {
+	env := []string{"PGPASSWORD=" + cfg.Password}
+	if cfg.SSLMode != "" {
+		env = append(env, "PGSSLMODE="+cfg.SSLMode)
+	}
+	return env
+}
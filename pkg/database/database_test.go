@@ -1,9 +1,28 @@
 package database
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/hwh/hwhkit-go/pkg/config"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
@@ -148,7 +167,88 @@ func TestIndexDefinition(t *testing.T) {
 	}
 }
 
+func TestTimezoneParams(t *testing.T) {
+	// 未配置时保持原有的连接时区
+	manager := &Manager{config: &config.DatabaseConfig{}}
+	mysqlLoc, postgresTimeZone, nowFunc, err := manager.timezoneParams()
+	if err != nil || mysqlLoc != "Local" || postgresTimeZone != "Asia/Shanghai" || nowFunc != nil {
+		t.Errorf("Expected legacy timezone params, got %s %s %v %v", mysqlLoc, postgresTimeZone, nowFunc != nil, err)
+	}
+
+	manager = &Manager{config: &config.DatabaseConfig{TimeZone: "America/New_York"}}
+	mysqlLoc, postgresTimeZone, nowFunc, err = manager.timezoneParams()
+	if err != nil || mysqlLoc != "America/New_York" || postgresTimeZone != "America/New_York" {
+		t.Fatalf("Expected configured timezone params, got %s %s %v", mysqlLoc, postgresTimeZone, err)
+	}
+	if location := nowFunc().Location().String(); location != "America/New_York" {
+		t.Errorf("Expected timestamps in configured timezone, got %s", location)
+	}
+
+	manager = &Manager{config: &config.DatabaseConfig{TimeZone: "Mars/Olympus"}}
+	if _, _, _, err := manager.timezoneParams(); err == nil {
+		t.Error("Expected invalid timezone to fail")
+	}
+}
+
 // 测试辅助函数
+func TestPostgresConnConfig(t *testing.T) {
+	// 证书路径包含空格
+	dir := filepath.Join(t.TempDir(), "db certs")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(dir, "root ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	dsn := "host=10.0.0.5 user=app password=secret dbname=hwhkit port=5432 sslmode=disable TimeZone=UTC"
+	tlsConfig := &config.TLSConfig{Enabled: true, CAFile: caFile, ServerName: "db.internal"}
+	connConfig, err := postgresConnConfig(dsn, tlsConfig, "10.0.0.5")
+	if err != nil {
+		t.Fatalf("postgresConnConfig failed: %v", err)
+	}
+	if connConfig.TLSConfig == nil || connConfig.TLSConfig.RootCAs == nil {
+		t.Fatal("Expected TLS config with the CA pool")
+	}
+	if connConfig.TLSConfig.ServerName != "db.internal" {
+		t.Errorf("Expected configured server name, got %q", connConfig.TLSConfig.ServerName)
+	}
+	if len(connConfig.Fallbacks) != 0 {
+		t.Errorf("Expected no plaintext fallbacks, got %d", len(connConfig.Fallbacks))
+	}
+	if connConfig.Host != "10.0.0.5" || connConfig.RuntimeParams["TimeZone"] != "UTC" {
+		t.Errorf("Unexpected connection config: %s %v", connConfig.Host, connConfig.RuntimeParams)
+	}
+
+	// 未配置服务器名时使用Host校验证书
+	connConfig, err = postgresConnConfig(dsn, &config.TLSConfig{Enabled: true}, "10.0.0.5")
+	if err != nil || connConfig.TLSConfig.ServerName != "10.0.0.5" {
+		t.Errorf("Expected host as server name, got %v", err)
+	}
+
+	if _, err := postgresConnConfig(dsn, &config.TLSConfig{Enabled: true, CAFile: filepath.Join(dir, "missing.pem")}, "10.0.0.5"); err == nil {
+		t.Error("Expected error for missing CA file")
+	}
+}
+
 func TestJoinColumns(t *testing.T) {
 	// 测试空列
 	result := joinColumns([]string{})
@@ -168,4 +268,551 @@ func TestJoinColumns(t *testing.T) {
 	if result != expected {
 		t.Errorf("Expected '%s', got '%s'", expected, result)
 	}
-}
\ No newline at end of file
+}
+func TestVersionedMigrations(t *testing.T) {
+	migrator := NewMigrator(nil)
+	err := migrator.AddMigrationsFS(fstest.MapFS{
+		"migrations/20240201000000_add_orders.sql": {Data: []byte("CREATE TABLE orders (id INT)")},
+		"migrations/20240101000000_add_users.sql":  {Data: []byte("CREATE TABLE users (id INT)")},
+		"migrations/README.md":                     {Data: []byte("ignored")},
+	}, "migrations")
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	migrator.AddMigration("20240301000000", "", func(*gorm.DB) error { return nil })
+
+	sorted := migrator.sortedMigrations()
+	if len(sorted) != 3 || sorted[0].ID() != "20240101000000_add_users" || sorted[2].ID() != "20240301000000" {
+		t.Fatalf("Unexpected migration order: %+v", sorted)
+	}
+
+	pending := pendingMigrations(sorted, []AppliedMigration{{Version: "20240101000000"}})
+	if len(pending) != 2 || pending[0].Version != "20240201000000" {
+		t.Errorf("Unexpected pending migrations: %+v", pending)
+	}
+}
+
+func TestBackupManager(t *testing.T) {
+	dir := t.TempDir()
+	restored := filepath.Join(dir, "restored.sql")
+	cfg := &config.DatabaseConfig{Type: "postgres", Host: "localhost", Port: 5432, User: "app", Password: "secret", Name: "hwhkit"}
+
+	var events []BackupAuditEvent
+	manager := NewBackupManager(cfg, &LocalBackupStorage{Dir: filepath.Join(dir, "backups")}).
+		SetAuditFunc(func(event BackupAuditEvent) { events = append(events, event) })
+	manager.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	// 使用shell模拟 pg_dump 和 psql
+	manager.command = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		switch name {
+		case "pg_dump":
+			return exec.CommandContext(ctx, "sh", "-c", `echo "CREATE TABLE users (id INT); -- $PGPASSWORD"`)
+		case "psql":
+			return exec.CommandContext(ctx, "sh", "-c", "cat > "+restored)
+		}
+		return exec.CommandContext(ctx, "false")
+	}
+
+	info, err := manager.Backup(context.Background())
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if info.Name != "hwhkit-20240102T030405Z.sql.gz" || info.Size == 0 {
+		t.Errorf("Unexpected backup info: %+v", info)
+	}
+
+	backups, err := manager.List(context.Background())
+	if err != nil || len(backups) != 1 || backups[0].Name != info.Name {
+		t.Fatalf("Unexpected backups: %+v (%v)", backups, err)
+	}
+
+	if err := manager.Restore(context.Background(), info.Name, nil); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	content, _ := os.ReadFile(restored)
+	if string(content) != "CREATE TABLE users (id INT); -- secret\n" {
+		t.Errorf("Unexpected restored content: %q", content)
+	}
+
+	if err := manager.Restore(context.Background(), "../etc/passwd", nil); err == nil {
+		t.Error("Expected error for invalid backup name")
+	}
+	if len(events) != 4 || events[0].Action != "backup" || events[2].Action != "restore" || events[3].Err == nil {
+		t.Errorf("Unexpected audit events: %+v", events)
+	}
+}
+
+// AuditLog 测试保留规则的模型
+type AuditLog struct {
+	ID        uint `gorm:"primaryKey"`
+	Action    string
+	CreatedAt time.Time
+}
+
+// RetentionRules 审计日志保留6个月
+func (AuditLog) RetentionRules() []RetentionRule {
+	return []RetentionRule{{Months: 6}}
+}
+
+func TestRetentionPurger(t *testing.T) {
+	// 试运行模式下只生成SQL不连接数据库
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open dry run db: %v", err)
+	}
+	var queries []string
+	db.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+		queries = append(queries, tx.Statement.SQL.String())
+	})
+
+	purger := NewPurger(db)
+	purger.now = func() time.Time { return time.Date(2024, 8, 31, 0, 0, 0, 0, time.UTC) }
+	if err := purger.AddModels(AuditLog{}); err != nil {
+		t.Fatalf("Failed to add model rules: %v", err)
+	}
+	if err := purger.AddRule(RetentionRule{Model: &TestUser{}, MaxAge: 30 * 24 * time.Hour, SoftDeleted: true}); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	if err := purger.AddRule(RetentionRule{Model: &TestUser{}}); err == nil {
+		t.Error("Expected error for rule without max age")
+	}
+
+	results, err := purger.Run(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Rule != "audit_logs" || results[1].Rule != "test_users_soft_deleted" {
+		t.Fatalf("Unexpected results: %+v", results)
+	}
+	// 2月没有31日，按 time.AddDate 规则顺延到3月2日
+	if !results[0].Cutoff.Equal(time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected cutoff: %v", results[0].Cutoff)
+	}
+	if len(queries) != 2 ||
+		queries[0] != `SELECT count(*) FROM "audit_logs" WHERE created_at < $1` ||
+		queries[1] != `SELECT count(*) FROM "test_users" WHERE deleted_at < $1 AND deleted_at IS NOT NULL` {
+		t.Errorf("Unexpected queries: %q", queries)
+	}
+}
+
+func TestRetentionPurgerBatches(t *testing.T) {
+	var events []string
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: &recordingPool{events: &events}}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open dry run db: %v", err)
+	}
+
+	// 试运行模式不查询数据库，由回调按批次返回主键并设置删除行数
+	batches := [][]interface{}{{1, 2}, {3, 4}, {5}}
+	var statements []string
+	db.Callback().Query().After("gorm:query").Register("test:batch", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+		if ids, ok := tx.Statement.Dest.(*[]interface{}); ok && len(batches) > 0 {
+			*ids, batches = batches[0], batches[1:]
+		}
+	})
+	db.Callback().Delete().After("gorm:delete").Register("test:batch", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+		tx.RowsAffected = int64(len(tx.Statement.Vars))
+	})
+
+	purger := NewPurger(db).SetBatchSize(2, 0)
+	if err := purger.AddRule(RetentionRule{Model: &AuditLog{}, MaxAge: time.Hour}); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	var progress []int64
+	purger.OnProgress(func(result PurgeResult) {
+		progress = append(progress, result.Deleted)
+	})
+
+	// 不足一批时结束，不再多查询一次
+	results, err := purger.Run(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if results[0].Batches != 3 || results[0].Matched != 5 || results[0].Deleted != 5 {
+		t.Errorf("Unexpected result: %+v", results[0])
+	}
+	if len(progress) != 3 || progress[0] != 2 || progress[1] != 4 || progress[2] != 5 {
+		t.Errorf("Unexpected progress: %v", progress)
+	}
+	if len(statements) != 6 ||
+		statements[0] != `SELECT "id" FROM "audit_logs" WHERE created_at < $1 LIMIT $2` ||
+		statements[1] != `DELETE FROM "audit_logs" WHERE id IN ($1,$2)` ||
+		statements[5] != `DELETE FROM "audit_logs" WHERE id IN ($1)` {
+		t.Errorf("Unexpected statements: %q", statements)
+	}
+
+	// 恰好整批时再查询一次，查不到数据后结束
+	batches = [][]interface{}{{1, 2}}
+	statements = nil
+	results, err = purger.Run(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if results[0].Batches != 1 || results[0].Deleted != 2 || len(statements) != 3 {
+		t.Errorf("Unexpected result %+v with statements %q", results[0], statements)
+	}
+
+	// 批次间等待时取消任务，返回已删除的部分结果
+	batches = [][]interface{}{{1, 2}, {3, 4}}
+	ctx, cancel := context.WithCancel(context.Background())
+	purger.SetBatchSize(2, time.Hour).OnProgress(func(PurgeResult) { cancel() })
+	results, err = purger.Run(ctx, false)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if results[0].Batches != 1 || results[0].Deleted != 2 || results[0].Error == "" {
+		t.Errorf("Unexpected result after cancel: %+v", results[0])
+	}
+}
+
+func TestTracingCallbacks(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open dry run db: %v", err)
+	}
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	if err := RegisterTracing(db, tracer); err != nil {
+		t.Fatalf("Failed to register tracing: %v", err)
+	}
+
+	ctx, parent := tracer.Start(context.Background(), "request")
+	var users []TestUser
+	db.WithContext(ctx).Where("name = ?", "alice").Find(&users)
+	db.WithContext(ctx).Create(&TestUser{Name: "bob"})
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+	query, create := spans[0], spans[1]
+	if query.Name() != "gorm.query" || create.Name() != "gorm.create" {
+		t.Errorf("Unexpected span names: %s, %s", query.Name(), create.Name())
+	}
+	if query.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("Expected query span to be a child of the request span")
+	}
+
+	attrs := map[string]string{}
+	for _, attr := range query.Attributes() {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	if attrs["db.system"] != "postgres" || attrs["db.sql.table"] != "test_users" {
+		t.Errorf("Unexpected span attributes: %v", attrs)
+	}
+	// 只记录带占位符的SQL
+	if !strings.Contains(attrs["db.statement"], "$1") || strings.Contains(attrs["db.statement"], "alice") {
+		t.Errorf("Unexpected statement: %s", attrs["db.statement"])
+	}
+}
+
+func TestRepositoryQueryOptions(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open dry run db: %v", err)
+	}
+	var queries []string
+	db.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+		queries = append(queries, tx.Statement.SQL.String())
+	})
+	repo := NewBaseRepository[TestUser](db)
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+	var seen interface{}
+	db.Callback().Query().Before("gorm:query").Register("test:context", func(tx *gorm.DB) {
+		seen = tx.Statement.Context.Value(ctxKey{})
+	})
+
+	if _, err := repo.List(20, 10, WithContext(ctx), WithSelect("id", "name"), WithWhere("name LIKE ?", "a%"), WithOrder("created_at DESC"), WithOrder("id")); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if seen != "request" {
+		t.Error("Expected query to use the context from WithContext")
+	}
+	if _, err := repo.Find(WithJoins("JOIN emails ON emails.user_id = test_users.id"), WithWhere("emails.verified = ?", true), WithWhere(map[string]interface{}{"name": "bob"})); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if _, err := repo.Paginate(2, 5, map[string]interface{}{"email": "a@example.com"}, WithWhere("id > ?", 3), WithOrder("name"), WithSelect("id")); err != nil {
+		t.Fatalf("Paginate failed: %v", err)
+	}
+
+	expected := []string{
+		`SELECT "id","name" FROM "test_users" WHERE name LIKE $1 AND "test_users"."deleted_at" IS NULL ORDER BY created_at DESC,id LIMIT $2 OFFSET $3`,
+		`SELECT "test_users"."id","test_users"."created_at","test_users"."updated_at","test_users"."deleted_at","test_users"."name","test_users"."email" FROM "test_users" JOIN emails ON emails.user_id = test_users.id WHERE emails.verified = $1 AND "name" = $2 AND "test_users"."deleted_at" IS NULL`,
+		`SELECT count(*) FROM "test_users" WHERE "email" = $1 AND id > $2 AND "test_users"."deleted_at" IS NULL`,
+		`SELECT "id" FROM "test_users" WHERE "email" = $1 AND id > $2 AND "test_users"."deleted_at" IS NULL ORDER BY name LIMIT $3 OFFSET $4`,
+	}
+	if len(queries) != len(expected) {
+		t.Fatalf("Expected %d queries, got %q", len(expected), queries)
+	}
+	for i := range expected {
+		if queries[i] != expected[i] {
+			t.Errorf("Query %d:\nexpected %s\n     got %s", i, expected[i], queries[i])
+		}
+	}
+}
+
+func TestContextAwareMethods(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open dry run db: %v", err)
+	}
+	type ctxKey struct{}
+	var seen []interface{}
+	db.Callback().Query().Before("gorm:query").Register("test:context", func(tx *gorm.DB) {
+		seen = append(seen, tx.Statement.Context.Value(ctxKey{}))
+	})
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+	repo := NewBaseRepository[TestUser](db)
+	scoped := repo.WithContext(ctx)
+	if _, err := scoped.GetByID(1); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if _, err := scoped.Paginate(1, 10, nil); err != nil {
+		t.Fatalf("Paginate failed: %v", err)
+	}
+	if _, err := repo.List(0, 10); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(seen) != 4 || seen[0] != "request" || seen[1] != "request" || seen[2] != "request" || seen[3] != nil {
+		t.Errorf("Expected scoped repository queries to use the context, got %v", seen)
+	}
+
+	manager := &Manager{db: db}
+	if manager.Context() == nil || manager.WithContext(ctx).Context().Value(ctxKey{}) != "request" {
+		t.Error("Expected manager context to follow WithContext")
+	}
+	if manager.Context().Value(ctxKey{}) != nil {
+		t.Error("Expected WithContext not to modify the original manager")
+	}
+
+	// 已取消的上下文不会发起连接
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := manager.WithContext(canceled).Health(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected canceled health check, got %v", err)
+	}
+}
+
+func TestRepositorySoftDelete(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open dry run db: %v", err)
+	}
+	var queries []string
+	capture := func(tx *gorm.DB) {
+		queries = append(queries, tx.Statement.SQL.String())
+	}
+	db.Callback().Query().After("gorm:query").Register("test:capture", capture)
+	db.Callback().Update().After("gorm:update").Register("test:capture", capture)
+	db.Callback().Delete().After("gorm:delete").Register("test:capture", capture)
+	repo := NewBaseRepository[TestUser](db)
+
+	if _, err := repo.Find(WithTrashed(), WithWhere("name = ?", "alice")); err != nil {
+		t.Fatalf("Find with trashed failed: %v", err)
+	}
+	if _, err := repo.Paginate(1, 10, nil, OnlyTrashed(), WithJoins("JOIN emails ON emails.user_id = test_users.id")); err != nil {
+		t.Fatalf("Paginate only trashed failed: %v", err)
+	}
+	// 试运行不影响任何行，恢复视为记录不存在
+	if err := repo.Restore(7); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected record not found in dry run, got %v", err)
+	}
+	if _, err := repo.PurgeOlderThan(30*24*time.Hour, WithWhere("email LIKE ?", "%@example.com")); err != nil {
+		t.Fatalf("PurgeOlderThan failed: %v", err)
+	}
+	if _, err := repo.PurgeOlderThan(-time.Hour); err == nil {
+		t.Error("Expected error for negative purge age")
+	}
+
+	expected := []string{
+		`SELECT * FROM "test_users" WHERE name = $1`,
+		`SELECT count(*) FROM "test_users" JOIN emails ON emails.user_id = test_users.id WHERE "test_users"."deleted_at" IS NOT NULL`,
+		`SELECT "test_users"."id","test_users"."created_at","test_users"."updated_at","test_users"."deleted_at","test_users"."name","test_users"."email" FROM "test_users" JOIN emails ON emails.user_id = test_users.id WHERE "test_users"."deleted_at" IS NOT NULL LIMIT $1`,
+		`UPDATE "test_users" SET "deleted_at"=$1,"updated_at"=$2 WHERE id = $3 AND "test_users"."deleted_at" IS NOT NULL`,
+		`DELETE FROM "test_users" WHERE email LIKE $1 AND "test_users"."deleted_at" IS NOT NULL AND "test_users"."deleted_at" < $2`,
+	}
+	if len(queries) != len(expected) {
+		t.Fatalf("Expected %d queries, got %q", len(expected), queries)
+	}
+	for i := range expected {
+		if queries[i] != expected[i] {
+			t.Errorf("Query %d:\nexpected %s\n     got %s", i, expected[i], queries[i])
+		}
+	}
+}
+
+// recordingPool 记录事务开始、提交和回滚的连接池，配合试运行模式使用
+type recordingPool struct {
+	events *[]string
+	ctx    context.Context
+}
+
+func (p *recordingPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *recordingPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *recordingPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *recordingPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *recordingPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	p.ctx = ctx
+	*p.events = append(*p.events, "BEGIN")
+	return &recordingTx{events: p.events}, nil
+}
+
+// recordingTx 记录提交和回滚的事务
+type recordingTx struct {
+	events *[]string
+}
+
+func (t *recordingTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (t *recordingTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (t *recordingTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (t *recordingTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (t *recordingTx) Commit() error {
+	*t.events = append(*t.events, "COMMIT")
+	return nil
+}
+
+func (t *recordingTx) Rollback() error {
+	*t.events = append(*t.events, "ROLLBACK")
+	return nil
+}
+
+func TestWithinTransaction(t *testing.T) {
+	var events []string
+	pool := &recordingPool{events: &events}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: pool}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open dry run db: %v", err)
+	}
+	capture := func(tx *gorm.DB) {
+		query := tx.Statement.SQL.String()
+		// 保存点名称由 GORM 生成，只比较语句
+		if strings.HasPrefix(query, "SAVEPOINT") || strings.HasPrefix(query, "ROLLBACK TO SAVEPOINT") {
+			query = query[:strings.LastIndex(query, " ")]
+		}
+		events = append(events, query)
+	}
+	db.Callback().Create().After("gorm:create").Register("test:capture", capture)
+	db.Callback().Raw().After("gorm:raw").Register("test:capture", capture)
+	manager := &Manager{db: db}
+
+	// 内层保存点失败只回滚保存点，外层事务提交
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+	errInner := errors.New("inner failed")
+	err = manager.WithinTransaction(ctx, func(tx *Repositories) error {
+		if tx.Context().Value(ctxKey{}) != "request" {
+			t.Error("Expected transaction to use the given context")
+		}
+		if err := RepositoryFor[TestUser](tx).Create(&TestUser{Name: "alice"}); err != nil {
+			return err
+		}
+		if err := tx.WithinTransaction(func(nested *Repositories) error {
+			if err := NewBaseRepository[TestUser](nested.DB()).Create(&TestUser{Name: "bob"}); err != nil {
+				return err
+			}
+			return errInner
+		}); !errors.Is(err, errInner) {
+			t.Errorf("Expected inner error, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithinTransaction failed: %v", err)
+	}
+	if pool.ctx == nil || pool.ctx.Value(ctxKey{}) != "request" {
+		t.Error("Expected transaction to begin with the given context")
+	}
+
+	insert := `INSERT INTO "test_users" ("created_at","updated_at","deleted_at","name","email") VALUES ($1,$2,$3,$4,$5) RETURNING "id"`
+	expected := []string{"BEGIN", insert, "SAVEPOINT", insert, "ROLLBACK TO SAVEPOINT", "COMMIT"}
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected transaction events:\nexpected %q\n     got %q", expected, events)
+	}
+
+	// 外层返回错误时回滚整个事务
+	events = nil
+	errOuter := errors.New("outer failed")
+	err = manager.WithinTransaction(nil, func(tx *Repositories) error {
+		if err := RepositoryFor[TestUser](tx).Create(&TestUser{Name: "carol"}); err != nil {
+			return err
+		}
+		return errOuter
+	})
+	if !errors.Is(err, errOuter) {
+		t.Errorf("Expected outer error, got %v", err)
+	}
+	expected = []string{"BEGIN", insert, "ROLLBACK"}
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected transaction events:\nexpected %q\n     got %q", expected, events)
+	}
+
+	// panic 时回滚并继续向上传播
+	events = nil
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected panic to propagate")
+			}
+		}()
+		_ = manager.WithinTransaction(ctx, func(tx *Repositories) error {
+			panic("boom")
+		})
+	}()
+	expected = []string{"BEGIN", "ROLLBACK"}
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected transaction events:\nexpected %q\n     got %q", expected, events)
+	}
+}
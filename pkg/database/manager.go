@@ -1,10 +1,16 @@
 package database
 
 import (
+	"context"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -13,8 +19,9 @@ import (
 
 // Manager 数据库管理器
 type Manager struct {
-	db     *gorm.DB
-	config *config.DatabaseConfig
+	db            *gorm.DB
+	config        *config.DatabaseConfig
+	tlsConfigName string // 注册到MySQL驱动的TLS配置名称，关闭时注销
 }
 
 // New 创建新的数据库管理器
@@ -30,33 +37,89 @@ func New(cfg *config.DatabaseConfig) (*Manager, error) {
 	return manager, nil
 }
 
+// gormLogLevel 解析GORM日志级别，未配置时记录所有SQL
+func gormLogLevel(level string) logger.LogLevel {
+	switch strings.ToLower(level) {
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "warn":
+		return logger.Warn
+	default:
+		return logger.Info
+	}
+}
+
+// timezoneParams 连接时区参数（MySQL loc、PostgreSQL TimeZone）和GORM时间戳函数
+// 只有显式配置了 DB_TIMEZONE 时才使用该时区，否则保持原有的 loc=Local 和 Asia/Shanghai，避免升级后已有数据的读写时区发生变化
+func (m *Manager) timezoneParams() (mysqlLoc, postgresTimeZone string, nowFunc func() time.Time, err error) {
+	if m.config.TimeZone == "" {
+		return "Local", "Asia/Shanghai", nil, nil
+	}
+	location, err := m.config.Location()
+	if err != nil {
+		return "", "", nil, err
+	}
+	return location.String(), location.String(), func() time.Time {
+		return time.Now().In(location)
+	}, nil
+}
+
 // connect 连接数据库
 func (m *Manager) connect() error {
 	var dialector gorm.Dialector
 	var dsn string
 	
+	mysqlLoc, postgresTimeZone, nowFunc, err := m.timezoneParams()
+	if err != nil {
+		return err
+	}
+	
 	switch m.config.Type {
 	case "mysql":
-		dsn = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=Local",
+		dsn = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=%s",
 			m.config.User,
 			m.config.Password,
 			m.config.Host,
 			m.config.Port,
 			m.config.Name,
 			m.config.Charset,
+			url.QueryEscape(mysqlLoc),
 		)
+		if m.config.TLS.Enabled {
+			tlsConfig, err := m.config.TLS.BuildTLS(m.config.Host)
+			if err != nil {
+				return fmt.Errorf("failed to build database TLS config: %w", err)
+			}
+			// MySQL驱动的TLS配置按名称全局注册，每个管理器使用自己的名称，避免不同TLS设置的管理器互相覆盖
+			m.tlsConfigName = fmt.Sprintf("hwhkit-%p", m)
+			if err := mysqldriver.RegisterTLSConfig(m.tlsConfigName, tlsConfig); err != nil {
+				return fmt.Errorf("failed to register database TLS config: %w", err)
+			}
+			dsn += "&tls=" + m.tlsConfigName
+		}
 		dialector = mysql.Open(dsn)
 		
 	case "postgres", "postgresql":
-		dsn = fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=Asia/Shanghai",
+		dsn = fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=%s",
 			m.config.Host,
 			m.config.User,
 			m.config.Password,
 			m.config.Name,
 			m.config.Port,
 			m.config.SSLMode,
+			postgresTimeZone,
 		)
-		dialector = postgres.Open(dsn)
+		if m.config.TLS.Enabled {
+			connConfig, err := postgresConnConfig(dsn, &m.config.TLS, m.config.Host)
+			if err != nil {
+				return err
+			}
+			dialector = postgres.New(postgres.Config{Conn: stdlib.OpenDB(*connConfig)})
+		} else {
+			dialector = postgres.Open(dsn)
+		}
 		
 	default:
 		return fmt.Errorf("unsupported database type: %s", m.config.Type)
@@ -64,7 +127,8 @@ func (m *Manager) connect() error {
 	
 	// GORM 配置
 	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger:  logger.Default.LogMode(gormLogLevel(m.config.LogLevel)),
+		NowFunc: nowFunc,
 	}
 	
 	// 连接数据库
@@ -93,17 +157,54 @@ func (m *Manager) connect() error {
 	return nil
 }
 
+// postgresConnConfig 解析DSN并使用TLS配置构建的 *tls.Config 连接PostgreSQL，忽略 DB_SSL_MODE
+// 证书路径不拼接到DSN中（含空格的路径不会破坏解析），TLS.ServerName 用于证书校验，且不回退到明文连接
+func postgresConnConfig(dsn string, t *config.TLSConfig, host string) (*pgx.ConnConfig, error) {
+	tlsConfig, err := t.BuildTLS(host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build database TLS config: %w", err)
+	}
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database DSN: %w", err)
+	}
+	connConfig.TLSConfig = tlsConfig
+	connConfig.Fallbacks = nil
+	return connConfig, nil
+}
+
 // GetDB 获取GORM数据库实例
 func (m *Manager) GetDB() *gorm.DB {
 	return m.db
 }
 
+// WithContext 创建使用指定上下文执行SQL的数据库管理器，与原管理器共享连接池
+// 请求处理器中传入 c.Request.Context()，请求取消或超时后查询随之取消，追踪和耗时分段也使用该上下文
+func (m *Manager) WithContext(ctx context.Context) *Manager {
+	return &Manager{
+		db:            m.db.WithContext(ctx),
+		config:        m.config,
+		tlsConfigName: m.tlsConfigName,
+	}
+}
+
+// Context 获取执行SQL使用的上下文
+func (m *Manager) Context() context.Context {
+	if ctx := m.db.Statement.Context; ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
 // Close 关闭数据库连接
 func (m *Manager) Close() error {
 	sqlDB, err := m.db.DB()
 	if err != nil {
 		return err
 	}
+	if m.tlsConfigName != "" {
+		mysqldriver.DeregisterTLSConfig(m.tlsConfigName)
+	}
 	return sqlDB.Close()
 }
 
@@ -123,7 +224,7 @@ func (m *Manager) Health() error {
 	if err != nil {
 		return err
 	}
-	return sqlDB.Ping()
+	return sqlDB.PingContext(m.Context())
 }
 
 // GetStats 获取数据库连接统计信息
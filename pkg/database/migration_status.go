@@ -0,0 +1,230 @@
+package database
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 版本化迁移，按版本号字典序执行且只执行一次
+type Migration struct {
+	Version string // 版本号，建议使用时间戳，如 20240101120000
+	Name    string
+	Up      func(*gorm.DB) error
+}
+
+// ID 迁移标识，格式为 版本号_名称
+func (m Migration) ID() string {
+	if m.Name == "" {
+		return m.Version
+	}
+	return m.Version + "_" + m.Name
+}
+
+// AppliedMigration 已执行的迁移记录
+type AppliedMigration struct {
+	Version   string    `gorm:"primaryKey;size:64" json:"version"`
+	Name      string    `gorm:"size:255" json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// TableName 迁移记录表名
+func (AppliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// SchemaChange AutoMigrate 将执行的结构变更
+type SchemaChange struct {
+	Model  string `json:"model"`
+	Table  string `json:"table"`
+	Action string `json:"action"` // create_table、add_column、create_index
+	Target string `json:"target,omitempty"`
+}
+
+// MigrationStatus 迁移状态
+type MigrationStatus struct {
+	Version       string             `json:"version"`
+	Applied       []AppliedMigration `json:"applied"`
+	Pending       []string           `json:"pending"`
+	SchemaChanges []SchemaChange     `json:"schema_changes"`
+	UpToDate      bool               `json:"up_to_date"`
+}
+
+// AddMigration 添加版本化迁移
+func (m *Migrator) AddMigration(version, name string, up func(*gorm.DB) error) *Migrator {
+	m.migrations = append(m.migrations, Migration{Version: version, Name: name, Up: up})
+	return m
+}
+
+// AddMigrationsFS 从目录加载SQL迁移文件，文件名格式为 <版本号>_<名称>.sql
+// 每个文件作为一条语句执行，MySQL下包含多条语句时需要在DSN中开启 multiStatements
+func (m *Migrator) AddMigrationsFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read migrations dir: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		version, name, _ := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		sql := string(content)
+		m.AddMigration(version, name, func(db *gorm.DB) error {
+			return db.Exec(sql).Error
+		})
+	}
+	return nil
+}
+
+// sortedMigrations 按版本号排序的迁移
+func (m *Migrator) sortedMigrations() []Migration {
+	migrations := append([]Migration(nil), m.migrations...)
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations
+}
+
+// Applied 获取已执行的迁移，迁移记录表不存在时返回空
+func (m *Migrator) Applied() ([]AppliedMigration, error) {
+	applied := []AppliedMigration{}
+	if !m.db.Migrator().HasTable(&AppliedMigration{}) {
+		return applied, nil
+	}
+	if err := m.db.Order("version").Find(&applied).Error; err != nil {
+		return nil, fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// Pending 获取待执行的迁移
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.Applied()
+	if err != nil {
+		return nil, err
+	}
+	return pendingMigrations(m.sortedMigrations(), applied), nil
+}
+
+// pendingMigrations 过滤出未执行的迁移
+func pendingMigrations(migrations []Migration, applied []AppliedMigration) []Migration {
+	done := make(map[string]bool, len(applied))
+	for _, a := range applied {
+		done[a.Version] = true
+	}
+
+	pending := make([]Migration, 0)
+	for _, migration := range migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+// PlanAutoMigrate 试运行 AutoMigrate，返回将要执行的结构变更（新建表、新增列和索引）
+// 列类型变更不在检查范围内
+func (m *Migrator) PlanAutoMigrate() ([]SchemaChange, error) {
+	changes := make([]SchemaChange, 0)
+	migrator := m.db.Migrator()
+
+	for _, model := range m.models {
+		stmt := &gorm.Statement{DB: m.db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		modelName := stmt.Schema.Name
+		table := stmt.Schema.Table
+
+		if !migrator.HasTable(model) {
+			changes = append(changes, SchemaChange{Model: modelName, Table: table, Action: "create_table"})
+			continue
+		}
+
+		for _, dbName := range stmt.Schema.DBNames {
+			if !migrator.HasColumn(model, dbName) {
+				changes = append(changes, SchemaChange{Model: modelName, Table: table, Action: "add_column", Target: dbName})
+			}
+		}
+
+		indexes := stmt.Schema.ParseIndexes()
+		names := make([]string, 0, len(indexes))
+		for name := range indexes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !migrator.HasIndex(model, name) {
+				changes = append(changes, SchemaChange{Model: modelName, Table: table, Action: "create_index", Target: name})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// MigrationStatus 获取迁移状态：已执行版本、待执行迁移和 AutoMigrate 待执行的结构变更
+func (m *Migrator) MigrationStatus() (*MigrationStatus, error) {
+	applied, err := m.Applied()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := m.PlanAutoMigrate()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MigrationStatus{
+		Version:       m.version,
+		Applied:       applied,
+		Pending:       make([]string, 0),
+		SchemaChanges: changes,
+	}
+	for _, migration := range pendingMigrations(m.sortedMigrations(), applied) {
+		status.Pending = append(status.Pending, migration.ID())
+	}
+	status.UpToDate = len(status.Pending) == 0 && len(status.SchemaChanges) == 0
+	return status, nil
+}
+
+// runMigrations 依次执行待执行的版本化迁移，每个迁移在独立事务中执行并记录
+func (m *Migrator) runMigrations() error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	if err := m.db.AutoMigrate(&AppliedMigration{}); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+	for _, migration := range pending {
+		log.Printf("Running migration %s...", migration.ID())
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&AppliedMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to run migration %s: %w", migration.ID(), err)
+		}
+	}
+	return nil
+}
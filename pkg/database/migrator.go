@@ -3,16 +3,19 @@ package database
 import (
 	"fmt"
 	"log"
+	"sync"
 
 	"gorm.io/gorm"
 )
 
 // Migrator 数据库迁移器
 type Migrator struct {
-	db      *gorm.DB
-	models  []interface{}
-	seeds   []SeedFunc
-	version string
+	db         *gorm.DB
+	models     []interface{}
+	migrations []Migration
+	seeds      []SeedFunc
+	version    string
+	mu         sync.Mutex // 防止并发执行迁移（如管理接口重复触发）
 }
 
 // SeedFunc 种子数据函数类型
@@ -54,6 +57,9 @@ func (m *Migrator) SetVersion(version string) *Migrator {
 
 // Migrate 执行迁移
 func (m *Migrator) Migrate() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
 	log.Printf("Starting database migration (version: %s)...", m.version)
 	
 	// 自动迁移模型
@@ -65,6 +71,11 @@ func (m *Migrator) Migrate() error {
 		log.Println("Models migrated successfully")
 	}
 	
+	// 执行版本化迁移
+	if err := m.runMigrations(); err != nil {
+		return err
+	}
+	
 	// 执行种子数据
 	if len(m.seeds) > 0 {
 		log.Printf("Running %d seed functions...", len(m.seeds))
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QueryOption 仓储查询选项，用于 List、Find、FindOne、Paginate 等方法
+type QueryOption func(*queryOptions)
+
+// trashedMode 软删除记录的查询方式
+type trashedMode int
+
+const (
+	withoutTrashed trashedMode = iota // 默认，排除已软删除的记录
+	withTrashed                       // 包含已软删除的记录
+	onlyTrashed                       // 只查询已软删除的记录
+)
+
+// queryOptions 收集的查询选项
+type queryOptions struct {
+	ctx      context.Context
+	trashed  trashedMode
+	wheres   []whereClause
+	joins    []namedClause
+	preloads []namedClause
+	selects  []string
+	orders   []interface{}
+}
+
+// whereClause 带参数的查询条件
+type whereClause struct {
+	query interface{}
+	args  []interface{}
+}
+
+// namedClause 带参数的关联或预加载子句
+type namedClause struct {
+	name string
+	args []interface{}
+}
+
+// WithContext 使用指定上下文执行查询，用于超时取消和链路追踪
+func WithContext(ctx context.Context) QueryOption {
+	return func(o *queryOptions) {
+		o.ctx = ctx
+	}
+}
+
+// WithTrashed 查询结果包含已软删除的记录，只对含 gorm.DeletedAt 字段（如 BaseModel）的模型有意义
+func WithTrashed() QueryOption {
+	return func(o *queryOptions) {
+		o.trashed = withTrashed
+	}
+}
+
+// OnlyTrashed 只查询已软删除的记录，如回收站列表
+func OnlyTrashed() QueryOption {
+	return func(o *queryOptions) {
+		o.trashed = onlyTrashed
+	}
+}
+
+// WithWhere 添加查询条件，参数同 gorm.DB.Where，如 WithWhere("age > ?", 18)、WithWhere(map[string]interface{}{"status": 1})
+// 多个条件以 AND 连接
+func WithWhere(clause interface{}, args ...interface{}) QueryOption {
+	return func(o *queryOptions) {
+		o.wheres = append(o.wheres, whereClause{query: clause, args: args})
+	}
+}
+
+// WithJoins 添加关联查询，参数同 gorm.DB.Joins，如 WithJoins("Company")、WithJoins("JOIN emails ON emails.user_id = users.id")
+func WithJoins(query string, args ...interface{}) QueryOption {
+	return func(o *queryOptions) {
+		o.joins = append(o.joins, namedClause{name: query, args: args})
+	}
+}
+
+// WithPreload 预加载关联，参数同 gorm.DB.Preload，如 WithPreload("Orders", "state = ?", "paid")
+func WithPreload(association string, args ...interface{}) QueryOption {
+	return func(o *queryOptions) {
+		o.preloads = append(o.preloads, namedClause{name: association, args: args})
+	}
+}
+
+// WithSelect 只查询指定字段
+func WithSelect(fields ...string) QueryOption {
+	return func(o *queryOptions) {
+		o.selects = append(o.selects, fields...)
+	}
+}
+
+// WithOrder 添加排序，如 WithOrder("created_at DESC")，多次调用按添加顺序排序
+func WithOrder(order interface{}) QueryOption {
+	return func(o *queryOptions) {
+		o.orders = append(o.orders, order)
+	}
+}
+
+// newQueryOptions 合并查询选项
+func newQueryOptions(opts []QueryOption) *queryOptions {
+	options := &queryOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(options)
+		}
+	}
+	return options
+}
+
+// filter 应用上下文、软删除范围、关联和查询条件，用于计数等不需要排序、字段和预加载的查询
+func (o *queryOptions) filter(db *gorm.DB) *gorm.DB {
+	if o.ctx != nil {
+		db = db.WithContext(o.ctx)
+	}
+	switch o.trashed {
+	case withTrashed:
+		db = db.Unscoped()
+	case onlyTrashed:
+		db = db.Unscoped().Where(trashedCondition)
+	}
+	for _, join := range o.joins {
+		db = db.Joins(join.name, join.args...)
+	}
+	for _, where := range o.wheres {
+		db = db.Where(where.query, where.args...)
+	}
+	return db
+}
+
+// apply 应用全部查询选项
+func (o *queryOptions) apply(db *gorm.DB) *gorm.DB {
+	return o.shape(o.filter(db))
+}
+
+// shape 应用字段、排序和预加载选项
+func (o *queryOptions) shape(db *gorm.DB) *gorm.DB {
+	if len(o.selects) > 0 {
+		db = db.Select(o.selects)
+	}
+	for _, order := range o.orders {
+		db = db.Order(order)
+	}
+	for _, preload := range o.preloads {
+		db = db.Preload(preload.name, preload.args...)
+	}
+	return db
+}
+
+// trashedCondition 已软删除记录的条件，列名带当前表名以免关联查询时歧义
+var trashedCondition = clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: "deleted_at"}, Value: nil}
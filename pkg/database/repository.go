@@ -1,9 +1,12 @@
 package database
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // BaseModel 基础模型，包含常用字段
@@ -17,13 +20,15 @@ type BaseModel struct {
 // Repository 通用仓储接口
 type Repository[T any] interface {
 	Create(entity *T) error
-	GetByID(id uint) (*T, error)
+	GetByID(id uint, opts ...QueryOption) (*T, error)
 	Update(entity *T) error
 	Delete(id uint) error
-	List(offset, limit int) ([]*T, error)
-	Count() (int64, error)
-	FindByCondition(condition map[string]interface{}) ([]*T, error)
-	FindOneByCondition(condition map[string]interface{}) (*T, error)
+	List(offset, limit int, opts ...QueryOption) ([]*T, error)
+	Count(opts ...QueryOption) (int64, error)
+	Find(opts ...QueryOption) ([]*T, error)
+	FindOne(opts ...QueryOption) (*T, error)
+	FindByCondition(condition map[string]interface{}, opts ...QueryOption) ([]*T, error)
+	FindOneByCondition(condition map[string]interface{}, opts ...QueryOption) (*T, error)
 }
 
 // BaseRepository 基础仓储实现
@@ -38,15 +43,23 @@ func NewBaseRepository[T any](db *gorm.DB) *BaseRepository[T] {
 	}
 }
 
+// WithContext 创建使用指定上下文执行SQL的仓储，与原仓储共享连接，如 repo.WithContext(c.Request.Context()).GetByID(id)
+// 只需单次查询使用上下文时也可以传入 WithContext 查询选项
+func (r *BaseRepository[T]) WithContext(ctx context.Context) *BaseRepository[T] {
+	return &BaseRepository[T]{
+		db: r.db.WithContext(ctx),
+	}
+}
+
 // Create 创建实体
 func (r *BaseRepository[T]) Create(entity *T) error {
 	return r.db.Create(entity).Error
 }
 
-// GetByID 根据ID获取实体
-func (r *BaseRepository[T]) GetByID(id uint) (*T, error) {
+// GetByID 根据ID获取实体，可通过 WithPreload 等选项预加载关联
+func (r *BaseRepository[T]) GetByID(id uint, opts ...QueryOption) (*T, error) {
 	var entity T
-	err := r.db.First(&entity, id).Error
+	err := newQueryOptions(opts).apply(r.db).First(&entity, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -70,40 +83,83 @@ func (r *BaseRepository[T]) HardDelete(id uint) error {
 	return r.db.Unscoped().Delete(&entity, id).Error
 }
 
-// List 分页获取实体列表
-func (r *BaseRepository[T]) List(offset, limit int) ([]*T, error) {
+// Restore 恢复已软删除的实体，实体不存在或未被删除时返回 gorm.ErrRecordNotFound
+func (r *BaseRepository[T]) Restore(id uint) error {
+	result := r.db.Unscoped().Model(new(T)).Where("id = ?", id).Where(trashedCondition).Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// PurgeOlderThan 永久删除软删除时间早于 age 之前的实体，返回删除的行数；可通过 WithContext、WithWhere 选项限定范围
+// 大表的定期清理建议使用支持分批和试运行的 Purger
+func (r *BaseRepository[T]) PurgeOlderThan(age time.Duration, opts ...QueryOption) (int64, error) {
+	if age < 0 {
+		return 0, fmt.Errorf("purge age must not be negative: %s", age)
+	}
+	cutoff := time.Now().Add(-age)
+	query := newQueryOptions(opts).filter(r.db).Unscoped().
+		Where(trashedCondition).
+		Where(clause.Lt{Column: clause.Column{Table: clause.CurrentTable, Name: "deleted_at"}, Value: cutoff})
+	result := query.Delete(new(T))
+	return result.RowsAffected, result.Error
+}
+
+// List 分页获取实体列表，可通过 WithOrder、WithWhere 等选项排序和过滤
+func (r *BaseRepository[T]) List(offset, limit int, opts ...QueryOption) ([]*T, error) {
 	var entities []*T
-	err := r.db.Offset(offset).Limit(limit).Find(&entities).Error
+	err := newQueryOptions(opts).apply(r.db).Offset(offset).Limit(limit).Find(&entities).Error
 	return entities, err
 }
 
-// Count 获取实体总数
-func (r *BaseRepository[T]) Count() (int64, error) {
+// Count 获取实体总数，只使用 WithContext、WithJoins、WithWhere 选项
+func (r *BaseRepository[T]) Count(opts ...QueryOption) (int64, error) {
 	var count int64
 	var entity T
-	err := r.db.Model(&entity).Count(&count).Error
+	err := newQueryOptions(opts).filter(r.db.Model(&entity)).Count(&count).Error
 	return count, err
 }
 
+// Find 按查询选项获取实体列表
+func (r *BaseRepository[T]) Find(opts ...QueryOption) ([]*T, error) {
+	var entities []*T
+	err := newQueryOptions(opts).apply(r.db).Find(&entities).Error
+	return entities, err
+}
+
+// FindOne 按查询选项获取第一个实体，不存在时返回 gorm.ErrRecordNotFound
+func (r *BaseRepository[T]) FindOne(opts ...QueryOption) (*T, error) {
+	var entity T
+	err := newQueryOptions(opts).apply(r.db).First(&entity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
 // FindByCondition 根据条件查询实体列表
-func (r *BaseRepository[T]) FindByCondition(condition map[string]interface{}) ([]*T, error) {
+func (r *BaseRepository[T]) FindByCondition(condition map[string]interface{}, opts ...QueryOption) ([]*T, error) {
 	var entities []*T
 	query := r.db
 	for key, value := range condition {
 		query = query.Where(key, value)
 	}
-	err := query.Find(&entities).Error
+	err := newQueryOptions(opts).apply(query).Find(&entities).Error
 	return entities, err
 }
 
 // FindOneByCondition 根据条件查询单个实体
-func (r *BaseRepository[T]) FindOneByCondition(condition map[string]interface{}) (*T, error) {
+func (r *BaseRepository[T]) FindOneByCondition(condition map[string]interface{}, opts ...QueryOption) (*T, error) {
 	var entity T
 	query := r.db
 	for key, value := range condition {
 		query = query.Where(key, value)
 	}
-	err := query.First(&entity).Error
+	err := newQueryOptions(opts).apply(query).First(&entity).Error
 	if err != nil {
 		return nil, err
 	}
@@ -115,10 +171,11 @@ func (r *BaseRepository[T]) GetDB() *gorm.DB {
 	return r.db
 }
 
-// Paginate 分页查询
-func (r *BaseRepository[T]) Paginate(page, pageSize int, condition map[string]interface{}) (PaginationResult[T], error) {
+// Paginate 分页查询，总数只按条件和 WithJoins、WithWhere 计算，排序、字段和预加载选项只用于数据查询
+func (r *BaseRepository[T]) Paginate(page, pageSize int, condition map[string]interface{}, opts ...QueryOption) (PaginationResult[T], error) {
 	var entities []*T
 	var total int64
+	options := newQueryOptions(opts)
 	
 	query := r.db.Model(new(T))
 	
@@ -126,15 +183,16 @@ func (r *BaseRepository[T]) Paginate(page, pageSize int, condition map[string]in
 	for key, value := range condition {
 		query = query.Where(key, value)
 	}
+	query = options.filter(query)
 	
 	// 获取总数
-	if err := query.Count(&total).Error; err != nil {
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
 		return PaginationResult[T]{}, err
 	}
 	
 	// 分页查询
 	offset := (page - 1) * pageSize
-	if err := query.Offset(offset).Limit(pageSize).Find(&entities).Error; err != nil {
+	if err := options.shape(query).Offset(offset).Limit(pageSize).Find(&entities).Error; err != nil {
 		return PaginationResult[T]{}, err
 	}
 	
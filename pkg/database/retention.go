@@ -0,0 +1,257 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/metrics"
+	"gorm.io/gorm"
+)
+
+var (
+	retentionPurged = metrics.Default.Counter("hwhkit_retention_purged_rows_total",
+		"Total number of rows purged by retention rules", "rule")
+	retentionPending = metrics.Default.Gauge("hwhkit_retention_pending_rows",
+		"Rows matching retention rules in the last dry run", "rule")
+	retentionDuration = metrics.Default.Histogram("hwhkit_retention_purge_duration_seconds",
+		"Retention purge duration in seconds", nil, "rule")
+)
+
+// RetentionRule 数据保留规则，超过保留期的数据会被物理删除
+type RetentionRule struct {
+	Name        string        // 规则名称，默认使用表名
+	Model       interface{}   // 模型，如 &AuditLog{}
+	Column      string        // 时间列，默认 created_at，SoftDeleted 时固定为 deleted_at
+	MaxAge      time.Duration // 保留时长
+	Months      int           // 按月保留，与 MaxAge 叠加
+	SoftDeleted bool          // 只清理已软删除的数据
+	Where       string        // 额外过滤条件
+	Args        []interface{} // 额外过滤条件参数
+}
+
+// RetentionModel 声明保留规则的模型，规则的 Model 为空时使用模型自身
+//
+//	func (AuditLog) RetentionRules() []database.RetentionRule {
+//		return []database.RetentionRule{{Months: 6}}
+//	}
+type RetentionModel interface {
+	RetentionRules() []RetentionRule
+}
+
+// column 获取时间列
+func (r *RetentionRule) column() string {
+	if r.SoftDeleted {
+		return "deleted_at"
+	}
+	if r.Column == "" {
+		return "created_at"
+	}
+	return r.Column
+}
+
+// cutoff 计算保留截止时间，早于该时间的数据将被清理
+func (r *RetentionRule) cutoff(now time.Time) time.Time {
+	return now.AddDate(0, -r.Months, 0).Add(-r.MaxAge)
+}
+
+// PurgeResult 单条规则的清理结果
+type PurgeResult struct {
+	Rule     string        `json:"rule"`
+	Cutoff   time.Time     `json:"cutoff"`
+	DryRun   bool          `json:"dry_run"`
+	Matched  int64         `json:"matched"` // 试运行时为匹配行数
+	Deleted  int64         `json:"deleted"`
+	Batches  int           `json:"batches"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Purger 数据清理任务，按规则分批删除过期数据
+type Purger struct {
+	db         *gorm.DB
+	rules      []RetentionRule
+	batchSize  int
+	batchPause time.Duration
+	now        func() time.Time
+	onProgress func(PurgeResult)
+}
+
+// NewPurger 创建数据清理任务，默认每批删除1000行
+func NewPurger(db *gorm.DB) *Purger {
+	return &Purger{
+		db:        db,
+		batchSize: 1000,
+		now:       time.Now,
+	}
+}
+
+// AddRule 添加保留规则
+func (p *Purger) AddRule(rule RetentionRule) error {
+	if rule.Model == nil {
+		return errors.New("retention rule model is required")
+	}
+	if rule.MaxAge <= 0 && rule.Months <= 0 {
+		return errors.New("retention rule max age is required")
+	}
+	if rule.Name == "" {
+		stmt := &gorm.Statement{DB: p.db}
+		if err := stmt.Parse(rule.Model); err != nil {
+			return fmt.Errorf("failed to parse model %T: %w", rule.Model, err)
+		}
+		rule.Name = stmt.Schema.Table
+		if rule.SoftDeleted {
+			rule.Name += "_soft_deleted"
+		}
+	}
+	p.rules = append(p.rules, rule)
+	return nil
+}
+
+// AddModels 添加模型声明的保留规则
+func (p *Purger) AddModels(models ...RetentionModel) error {
+	for _, model := range models {
+		for _, rule := range model.RetentionRules() {
+			if rule.Model == nil {
+				rule.Model = model
+			}
+			if err := p.AddRule(rule); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetBatchSize 设置每批删除的行数，pause 为批次间的等待时间，用于降低对数据库的压力
+func (p *Purger) SetBatchSize(size int, pause time.Duration) *Purger {
+	if size > 0 {
+		p.batchSize = size
+	}
+	p.batchPause = pause
+	return p
+}
+
+// OnProgress 设置进度回调，每批删除后调用
+func (p *Purger) OnProgress(fn func(PurgeResult)) *Purger {
+	p.onProgress = fn
+	return p
+}
+
+// Rules 获取所有保留规则
+func (p *Purger) Rules() []RetentionRule {
+	return p.rules
+}
+
+// Run 执行所有规则，dryRun 为true时只统计匹配行数；单条规则失败不影响其他规则
+func (p *Purger) Run(ctx context.Context, dryRun bool) ([]PurgeResult, error) {
+	results := make([]PurgeResult, 0, len(p.rules))
+	var errs []error
+	for i := range p.rules {
+		result, err := p.purge(ctx, &p.rules[i], dryRun)
+		if err != nil {
+			result.Error = err.Error()
+			errs = append(errs, fmt.Errorf("retention rule %s: %w", result.Rule, err))
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
+
+// purge 执行单条规则
+func (p *Purger) purge(ctx context.Context, rule *RetentionRule, dryRun bool) (PurgeResult, error) {
+	start := time.Now()
+	result := PurgeResult{Rule: rule.Name, Cutoff: rule.cutoff(p.now()), DryRun: dryRun}
+	defer func() {
+		result.Duration = time.Since(start)
+		retentionDuration.ObserveSince(start, rule.Name)
+	}()
+
+	query := func() *gorm.DB {
+		tx := p.db.WithContext(ctx).Unscoped().Model(rule.Model).
+			Where(rule.column()+" < ?", result.Cutoff)
+		if rule.SoftDeleted {
+			tx = tx.Where("deleted_at IS NOT NULL")
+		}
+		if rule.Where != "" {
+			tx = tx.Where(rule.Where, rule.Args...)
+		}
+		return tx
+	}
+
+	if dryRun {
+		if err := query().Count(&result.Matched).Error; err != nil {
+			return result, err
+		}
+		retentionPending.Set(float64(result.Matched), rule.Name)
+		return result, nil
+	}
+
+	stmt := &gorm.Statement{DB: p.db}
+	if err := stmt.Parse(rule.Model); err != nil {
+		return result, fmt.Errorf("failed to parse model %T: %w", rule.Model, err)
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return result, fmt.Errorf("model %T has no primary key", rule.Model)
+	}
+	primaryKey := stmt.Schema.PrioritizedPrimaryField.DBName
+
+	// 先查出一批主键再按主键删除，兼容不支持 DELETE ... LIMIT 的数据库
+	for {
+		var ids []interface{}
+		if err := query().Limit(p.batchSize).Pluck(primaryKey, &ids).Error; err != nil {
+			return result, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		deleted := p.db.WithContext(ctx).Unscoped().Where(primaryKey+" IN ?", ids).Delete(rule.Model)
+		if deleted.Error != nil {
+			return result, deleted.Error
+		}
+		result.Matched += int64(len(ids))
+		result.Deleted += deleted.RowsAffected
+		result.Batches++
+		retentionPurged.Add(float64(deleted.RowsAffected), rule.Name)
+		if p.onProgress != nil {
+			p.onProgress(result)
+		}
+
+		if len(ids) < p.batchSize {
+			break
+		}
+		if p.batchPause > 0 {
+			select {
+			case <-ctx.Done():
+				return result, ctx.Err()
+			case <-time.After(p.batchPause):
+			}
+		}
+	}
+	return result, nil
+}
+
+// Schedule 按固定间隔执行清理，直到 ctx 取消
+func (p *Purger) Schedule(ctx context.Context, interval time.Duration, dryRun bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			results, err := p.Run(ctx, dryRun)
+			for _, result := range results {
+				log.Printf("Retention purge %s: dry_run=%v matched=%d deleted=%d batches=%d duration=%s",
+					result.Rule, result.DryRun, result.Matched, result.Deleted, result.Batches, result.Duration)
+			}
+			if err != nil {
+				log.Printf("Retention purge failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/metrics"
+	"gorm.io/gorm"
+)
+
+// timingStartKey 语句实例中保存开始时间的键
+const timingStartKey = "hwhkit:timing_start"
+
+// EnableTiming 注册请求耗时分段回调，通过 GetDB().WithContext(ctx) 传入带 metrics.Timings 的上下文时，
+// SQL耗时计入 Server-Timing 的 db 分段
+func (m *Manager) EnableTiming() error {
+	return RegisterTiming(m.db)
+}
+
+// RegisterTiming 为任意 gorm.DB 注册耗时分段回调，覆盖 create/query/update/delete/row/raw 操作
+func RegisterTiming(db *gorm.DB) error {
+	callbacks := db.Callback()
+	errs := []error{
+		callbacks.Create().Before("gorm:create").Register("timing:before_create", startTiming),
+		callbacks.Create().After("gorm:create").Register("timing:after_create", endTiming),
+		callbacks.Query().Before("gorm:query").Register("timing:before_query", startTiming),
+		callbacks.Query().After("gorm:query").Register("timing:after_query", endTiming),
+		callbacks.Update().Before("gorm:update").Register("timing:before_update", startTiming),
+		callbacks.Update().After("gorm:update").Register("timing:after_update", endTiming),
+		callbacks.Delete().Before("gorm:delete").Register("timing:before_delete", startTiming),
+		callbacks.Delete().After("gorm:delete").Register("timing:after_delete", endTiming),
+		callbacks.Row().Before("gorm:row").Register("timing:before_row", startTiming),
+		callbacks.Row().After("gorm:row").Register("timing:after_row", endTiming),
+		callbacks.Raw().Before("gorm:raw").Register("timing:before_raw", startTiming),
+		callbacks.Raw().After("gorm:raw").Register("timing:after_raw", endTiming),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return fmt.Errorf("failed to register timing callback: %w", err)
+		}
+	}
+	return nil
+}
+
+// startTiming 上下文中有耗时分段记录时，在语句执行前记录开始时间
+func startTiming(tx *gorm.DB) {
+	if metrics.TimingsFromContext(tx.Statement.Context) != nil {
+		tx.InstanceSet(timingStartKey, time.Now())
+	}
+}
+
+// endTiming 在语句执行后累加 db 分段耗时
+func endTiming(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(timingStartKey)
+	if !ok {
+		return
+	}
+	if start, ok := value.(time.Time); ok {
+		metrics.TimingsFromContext(tx.Statement.Context).Add(metrics.TimingDB, time.Since(start))
+	}
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+)
+
+// Repositories 事务作用域的仓储工厂，由 WithinTransaction 传入回调
+// 通过它获取的仓储都绑定到同一事务，回调返回后不应继续使用
+type Repositories struct {
+	db *gorm.DB
+}
+
+// WithinTransaction 在事务中执行 fn，fn 返回 nil 时提交，返回错误或 panic 时回滚
+// ctx 用于事务内的全部SQL，为 nil 时使用管理器的上下文；service 层无需传递 *gorm.DB 即可组合多个仓储操作，如
+//
+//	err := db.WithinTransaction(ctx, func(tx *database.Repositories) error {
+//		if err := database.RepositoryFor[Order](tx).Create(order); err != nil {
+//			return err
+//		}
+//		return database.RepositoryFor[Stock](tx).BatchUpdate(updates, condition)
+//	})
+func (m *Manager) WithinTransaction(ctx context.Context, fn func(tx *Repositories) error, opts ...*sql.TxOptions) error {
+	db := m.db
+	if ctx != nil {
+		db = db.WithContext(ctx)
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		return fn(&Repositories{db: tx})
+	}, opts...)
+}
+
+// WithinTransaction 在当前事务中创建保存点执行 fn，fn 返回错误时只回滚到保存点，外层事务可以继续
+func (r *Repositories) WithinTransaction(fn func(tx *Repositories) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&Repositories{db: tx})
+	})
+}
+
+// DB 获取事务的GORM实例，用于自定义仓储的构造函数，如 NewUserRepository(tx.DB())
+func (r *Repositories) DB() *gorm.DB {
+	return r.db
+}
+
+// Context 获取事务使用的上下文
+func (r *Repositories) Context() context.Context {
+	if ctx := r.db.Statement.Context; ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// RepositoryFor 获取绑定到事务的基础仓储，如 database.RepositoryFor[User](tx).GetByID(id)
+func RepositoryFor[T any](tx *Repositories) *BaseRepository[T] {
+	return NewBaseRepository[T](tx.db)
+}
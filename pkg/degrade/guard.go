@@ -0,0 +1,251 @@
+package degrade
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/metrics"
+)
+
+// ErrDegraded 依赖处于降级状态，调用被短路
+var ErrDegraded = errors.New("dependency degraded")
+
+var (
+	dependencyDegraded = metrics.Default.Gauge("hwhkit_dependency_degraded",
+		"Whether an optional dependency is degraded (1) or healthy (0)", "dependency")
+	dependencyShortCircuits = metrics.Default.Counter("hwhkit_dependency_short_circuits_total",
+		"Total number of calls short-circuited because the dependency is degraded", "dependency")
+	dependencyFailures = metrics.Default.Counter("hwhkit_dependency_failures_total",
+		"Total number of failed calls to an optional dependency", "dependency")
+)
+
+// GuardConfig 依赖降级配置
+type GuardConfig struct {
+	Name             string                          // 依赖名称，如 cache、search、mq
+	FailureThreshold int                             // 连续失败多少次后降级，默认3
+	RecoveryInterval time.Duration                   // 降级后尝试恢复的间隔，默认10秒
+	ProbeTimeout     time.Duration                   // 单次探测超时，默认2秒
+	Probe            func(ctx context.Context) error // 后台恢复探测，为空时间隔到期后放行一次调用试探
+	IsFailure        func(err error) bool            // 判断错误是否计为依赖故障（如缓存未命中不算），为空时所有错误都计入
+	OnStateChange    func(name string, degraded bool, err error)
+}
+
+// Status 依赖状态
+type Status struct {
+	Name          string    `json:"name"`
+	Degraded      bool      `json:"degraded"`
+	Failures      int       `json:"consecutive_failures"`
+	ShortCircuits int64     `json:"short_circuits"`
+	LastError     string    `json:"last_error,omitempty"`
+	Since         time.Time `json:"since"` // 进入当前状态的时间
+}
+
+// DependencyGuard 可选依赖的降级保护：连续失败达到阈值后进入降级状态，降级期间调用直接返回 ErrDegraded（由调用方走兜底逻辑），
+// 后台按间隔探测，恢复后自动退出降级
+//
+//	guard := degrade.NewDependencyGuard(degrade.GuardConfig{
+//		Name:  "cache",
+//		Probe: func(ctx context.Context) error { return cacheManager.Health() },
+//		IsFailure: func(err error) bool { return !errors.Is(err, redis.Nil) },
+//	})
+//	value, err := degrade.Call(guard, func() (string, error) { return cacheManager.Get(key) },
+//		func(err error) (string, error) { return loadFromDB(key) })
+type DependencyGuard struct {
+	config GuardConfig
+
+	mu            sync.Mutex
+	degraded      bool
+	failures      int
+	shortCircuits int64
+	lastError     error
+	since         time.Time
+	retryAt       time.Time // 无探测函数时，到期后放行一次试探调用
+	probing       bool
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewDependencyGuard 创建依赖降级保护
+func NewDependencyGuard(config GuardConfig) *DependencyGuard {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 3
+	}
+	if config.RecoveryInterval <= 0 {
+		config.RecoveryInterval = 10 * time.Second
+	}
+	if config.ProbeTimeout <= 0 {
+		config.ProbeTimeout = 2 * time.Second
+	}
+	dependencyDegraded.Set(0, config.Name)
+	return &DependencyGuard{
+		config: config,
+		since:  time.Now(),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Name 依赖名称
+func (g *DependencyGuard) Name() string {
+	return g.config.Name
+}
+
+// Degraded 是否处于降级状态
+func (g *DependencyGuard) Degraded() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.degraded
+}
+
+// Status 获取依赖状态
+func (g *DependencyGuard) Status() Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	status := Status{
+		Name:          g.config.Name,
+		Degraded:      g.degraded,
+		Failures:      g.failures,
+		ShortCircuits: g.shortCircuits,
+		Since:         g.since,
+	}
+	if g.lastError != nil {
+		status.LastError = g.lastError.Error()
+	}
+	return status
+}
+
+// Do 在保护下执行调用，降级期间不执行 fn 并返回 ErrDegraded
+func (g *DependencyGuard) Do(fn func() error) error {
+	if !g.allow() {
+		return ErrDegraded
+	}
+	err := fn()
+	g.Report(err)
+	return err
+}
+
+// Call 在保护下执行有返回值的调用，降级或调用失败时返回 fallback 的结果，fallback 收到 ErrDegraded 或调用的错误
+// 不计为依赖故障的错误（见 GuardConfig.IsFailure）同样交给 fallback，fallback 为空时直接返回错误
+func Call[T any](g *DependencyGuard, fn func() (T, error), fallback func(err error) (T, error)) (T, error) {
+	var value T
+	err := g.Do(func() error {
+		var err error
+		value, err = fn()
+		return err
+	})
+	if err != nil && fallback != nil {
+		return fallback(err)
+	}
+	return value, err
+}
+
+// Report 报告一次调用结果，适用于无法用 Do 包装的调用（如流式读取）
+func (g *DependencyGuard) Report(err error) {
+	if err != nil && g.config.IsFailure != nil && !g.config.IsFailure(err) {
+		err = nil
+	}
+
+	g.mu.Lock()
+	if err == nil {
+		g.failures = 0
+		if g.degraded {
+			g.setStateLocked(false, nil)
+		}
+		g.mu.Unlock()
+		return
+	}
+
+	dependencyFailures.Inc(g.config.Name)
+	g.failures++
+	g.lastError = err
+	trip := !g.degraded && g.failures >= g.config.FailureThreshold
+	if g.degraded {
+		// 试探调用失败，重新计时
+		g.retryAt = time.Now().Add(g.config.RecoveryInterval)
+	}
+	if trip {
+		g.setStateLocked(true, err)
+	}
+	g.mu.Unlock()
+}
+
+// Stop 停止后台恢复探测
+func (g *DependencyGuard) Stop() {
+	g.once.Do(func() { close(g.stop) })
+}
+
+// allow 判断是否放行调用
+func (g *DependencyGuard) allow() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.degraded {
+		return true
+	}
+	// 没有探测函数时，间隔到期后放行一次试探调用
+	if g.config.Probe == nil && !time.Now().Before(g.retryAt) {
+		g.retryAt = time.Now().Add(g.config.RecoveryInterval)
+		return true
+	}
+	g.shortCircuits++
+	dependencyShortCircuits.Inc(g.config.Name)
+	return false
+}
+
+// setStateLocked 切换状态，调用方需持有锁
+func (g *DependencyGuard) setStateLocked(degraded bool, err error) {
+	g.degraded = degraded
+	g.since = time.Now()
+	if degraded {
+		dependencyDegraded.Set(1, g.config.Name)
+		g.retryAt = g.since.Add(g.config.RecoveryInterval)
+		if g.config.Probe != nil && !g.probing {
+			g.probing = true
+			go g.probeLoop()
+		}
+	} else {
+		dependencyDegraded.Set(0, g.config.Name)
+		g.lastError = nil
+	}
+	if g.config.OnStateChange != nil {
+		go g.config.OnStateChange(g.config.Name, degraded, err)
+	}
+}
+
+// probeLoop 降级期间按间隔探测，探测成功后恢复
+func (g *DependencyGuard) probeLoop() {
+	ticker := time.NewTicker(g.config.RecoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.stop:
+			g.mu.Lock()
+			g.probing = false
+			g.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), g.config.ProbeTimeout)
+		err := g.config.Probe(ctx)
+		cancel()
+
+		// 在同一临界区内退出，避免再次降级时漏启探测协程
+		g.mu.Lock()
+		if g.degraded && err == nil {
+			g.failures = 0
+			g.setStateLocked(false, nil)
+		}
+		if !g.degraded {
+			g.probing = false
+			g.mu.Unlock()
+			return
+		}
+		g.lastError = err
+		g.mu.Unlock()
+	}
+}
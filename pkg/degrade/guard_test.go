@@ -0,0 +1,96 @@
+package degrade
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errUnavailable = errors.New("connection refused")
+
+func TestDependencyGuard_DegradeAndProbeRecovery(t *testing.T) {
+	var healthy atomic.Bool
+	changes := make(chan bool, 2)
+	guard := NewDependencyGuard(GuardConfig{
+		Name:             "cache",
+		FailureThreshold: 2,
+		RecoveryInterval: 10 * time.Millisecond,
+		Probe: func(ctx context.Context) error {
+			if healthy.Load() {
+				return nil
+			}
+			return errUnavailable
+		},
+		OnStateChange: func(name string, degraded bool, err error) { changes <- degraded },
+	})
+	defer guard.Stop()
+
+	calls := 0
+	failing := func() error {
+		calls++
+		return errUnavailable
+	}
+	assert.ErrorIs(t, guard.Do(failing), errUnavailable)
+	assert.False(t, guard.Degraded())
+	assert.ErrorIs(t, guard.Do(failing), errUnavailable)
+	assert.True(t, guard.Degraded())
+	assert.True(t, <-changes)
+
+	// 降级期间调用被短路，走兜底逻辑
+	value, err := Call(guard, func() (string, error) {
+		calls++
+		return "cached", nil
+	}, func(err error) (string, error) {
+		assert.ErrorIs(t, err, ErrDegraded)
+		return "fallback", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", value)
+	assert.Equal(t, 2, calls)
+
+	status := guard.Status()
+	assert.Equal(t, "cache", status.Name)
+	assert.Equal(t, int64(1), status.ShortCircuits)
+	assert.Equal(t, errUnavailable.Error(), status.LastError)
+
+	// 后台探测成功后恢复
+	healthy.Store(true)
+	assert.False(t, <-changes)
+	assert.Eventually(t, func() bool { return !guard.Degraded() }, time.Second, 5*time.Millisecond)
+	value, err = Call(guard, func() (string, error) { return "cached", nil }, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "cached", value)
+}
+
+func TestDependencyGuard_TrialCallWithoutProbe(t *testing.T) {
+	guard := NewDependencyGuard(GuardConfig{
+		Name:             "search",
+		FailureThreshold: 1,
+		RecoveryInterval: 20 * time.Millisecond,
+		IsFailure:        func(err error) bool { return !errors.Is(err, context.Canceled) },
+	})
+	defer guard.Stop()
+
+	// 不计为故障的错误不触发降级
+	assert.ErrorIs(t, guard.Do(func() error { return context.Canceled }), context.Canceled)
+	assert.False(t, guard.Degraded())
+
+	assert.Error(t, guard.Do(func() error { return errUnavailable }))
+	assert.True(t, guard.Degraded())
+	assert.ErrorIs(t, guard.Do(func() error { return nil }), ErrDegraded)
+
+	// 间隔到期后放行一次试探调用，失败则继续降级
+	time.Sleep(25 * time.Millisecond)
+	assert.ErrorIs(t, guard.Do(func() error { return errUnavailable }), errUnavailable)
+	assert.ErrorIs(t, guard.Do(func() error { return nil }), ErrDegraded)
+
+	time.Sleep(25 * time.Millisecond)
+	assert.NoError(t, guard.Do(func() error { return nil }))
+	assert.False(t, guard.Degraded())
+	assert.Equal(t, 0, guard.Status().Failures)
+}
@@ -0,0 +1,229 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/hwh/hwhkit-go/pkg/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// AlertRule 告警规则
+type AlertRule struct {
+	Name      string
+	MinLevel  logrus.Level   // 最低日志级别，如 logrus.ErrorLevel 匹配 error、fatal、panic
+	Pattern   *regexp.Regexp // 消息匹配规则，为空时匹配所有消息
+	Threshold int            // Window 内匹配次数达到阈值才告警，默认1
+	Window    time.Duration  // 统计窗口，默认1分钟
+}
+
+// AlertHook 基于日志的告警钩子，匹配规则的日志经去重后异步发送到通知渠道
+type AlertHook struct {
+	rules       []AlertRule
+	notifier    notify.Notifier
+	dedupWindow time.Duration
+	timeout     time.Duration
+
+	mu       sync.Mutex
+	hits     map[string][]time.Time // 规则名 -> 窗口内的匹配时间
+	lastSent map[string]time.Time   // 去重键 -> 最近一次发送时间
+	now      func() time.Time
+	pending  sync.WaitGroup
+}
+
+// NewAlertHook 创建告警钩子，dedupWindow 内相同规则和消息的告警只发送一次
+func NewAlertHook(notifier notify.Notifier, dedupWindow time.Duration, rules ...AlertRule) *AlertHook {
+	for i := range rules {
+		if rules[i].Threshold <= 0 {
+			rules[i].Threshold = 1
+		}
+		if rules[i].Window <= 0 {
+			rules[i].Window = time.Minute
+		}
+		if rules[i].Name == "" {
+			rules[i].Name = fmt.Sprintf("rule-%d", i+1)
+		}
+	}
+
+	return &AlertHook{
+		rules:       rules,
+		notifier:    notifier,
+		dedupWindow: dedupWindow,
+		timeout:     10 * time.Second,
+		hits:        make(map[string][]time.Time),
+		lastSent:    make(map[string]time.Time),
+		now:         time.Now,
+	}
+}
+
+// NewAlertHookFromConfig 根据配置创建告警钩子，未配置任何通知渠道时返回错误
+func NewAlertHookFromConfig(cfg *config.AlertConfig) (*AlertHook, error) {
+	var channels notify.Multi
+	if cfg.WebhookURL != "" {
+		channels = append(channels, &notify.Webhook{URL: cfg.WebhookURL})
+	}
+	if cfg.SlackWebhookURL != "" {
+		channels = append(channels, &notify.Slack{WebhookURL: cfg.SlackWebhookURL})
+	}
+	if cfg.DingTalkWebhookURL != "" {
+		channels = append(channels, &notify.DingTalk{WebhookURL: cfg.DingTalkWebhookURL, Secret: cfg.DingTalkSecret, Format: cfg.MessageFormat})
+	}
+	if cfg.FeishuWebhookURL != "" {
+		channels = append(channels, &notify.Feishu{WebhookURL: cfg.FeishuWebhookURL, Secret: cfg.FeishuSecret, Format: cfg.MessageFormat})
+	}
+	if cfg.WeComWebhookURL != "" {
+		channels = append(channels, &notify.WeCom{WebhookURL: cfg.WeComWebhookURL, Format: cfg.MessageFormat})
+	}
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("no alert channel configured")
+	}
+
+	level := logrus.ErrorLevel
+	if cfg.MinLevel != "" {
+		parsed, err := logrus.ParseLevel(cfg.MinLevel)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alert level: %w", err)
+		}
+		level = parsed
+	}
+
+	rule := AlertRule{
+		Name:      "log",
+		MinLevel:  level,
+		Threshold: cfg.Threshold,
+		Window:    time.Duration(cfg.Window) * time.Second,
+	}
+	if cfg.MessagePattern != "" {
+		pattern, err := regexp.Compile(cfg.MessagePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alert message pattern: %w", err)
+		}
+		rule.Pattern = pattern
+	}
+
+	return NewAlertHook(channels, time.Duration(cfg.DedupWindow)*time.Second, rule), nil
+}
+
+// Levels 返回支持的日志级别，即所有规则中最宽的级别范围
+func (hook *AlertHook) Levels() []logrus.Level {
+	var maxLevel logrus.Level
+	for _, rule := range hook.rules {
+		if rule.MinLevel > maxLevel {
+			maxLevel = rule.MinLevel
+		}
+	}
+	return logrus.AllLevels[:maxLevel+1]
+}
+
+// Fire 执行钩子，除 fatal/panic 外发送在后台进行，不阻塞日志记录
+func (hook *AlertHook) Fire(entry *logrus.Entry) error {
+	for _, rule := range hook.rules {
+		if entry.Level > rule.MinLevel {
+			continue
+		}
+		if rule.Pattern != nil && !rule.Pattern.MatchString(entry.Message) {
+			continue
+		}
+		if count, ok := hook.trigger(rule, entry.Message); ok {
+			msg := hook.buildMessage(rule, entry, count)
+			hook.pending.Add(1)
+			if entry.Level <= logrus.FatalLevel {
+				// fatal/panic 之后进程会退出，需同步发送
+				hook.send(msg)
+			} else {
+				go hook.send(msg)
+			}
+		}
+	}
+	return nil
+}
+
+// trigger 记录一次匹配，达到阈值且不在去重窗口内时返回true
+func (hook *AlertHook) trigger(rule AlertRule, message string) (int, bool) {
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+
+	now := hook.now()
+	hits := hook.hits[rule.Name]
+	cutoff := now.Add(-rule.Window)
+	kept := hits[:0]
+	for _, t := range hits {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	kept = append(kept, now)
+	hook.hits[rule.Name] = kept
+	if len(kept) < rule.Threshold {
+		return 0, false
+	}
+
+	key := rule.Name + "\x00" + message
+	if last, exists := hook.lastSent[key]; exists && now.Sub(last) < hook.dedupWindow {
+		return 0, false
+	}
+	hook.lastSent[key] = now
+
+	// 清理过期的去重记录
+	for k, last := range hook.lastSent {
+		if now.Sub(last) >= hook.dedupWindow {
+			delete(hook.lastSent, k)
+		}
+	}
+	return len(kept), true
+}
+
+// buildMessage 根据日志条目生成告警消息
+func (hook *AlertHook) buildMessage(rule AlertRule, entry *logrus.Entry, count int) *notify.Message {
+	level := notify.LevelError
+	if entry.Level <= logrus.FatalLevel {
+		level = notify.LevelCritical
+	} else if entry.Level >= logrus.WarnLevel {
+		level = notify.LevelWarning
+	}
+
+	fields := map[string]string{
+		"rule":  rule.Name,
+		"level": entry.Level.String(),
+	}
+	if rule.Threshold > 1 {
+		fields["count"] = fmt.Sprintf("%d in %s", count, rule.Window)
+	}
+	for _, key := range []string{TraceIDField, RequestIDField, "service", "path"} {
+		if value, exists := entry.Data[key]; exists {
+			fields[key] = fmt.Sprint(value)
+		}
+	}
+	if host, err := os.Hostname(); err == nil {
+		fields["host"] = host
+	}
+
+	return &notify.Message{
+		Title:  fmt.Sprintf("[%s] %s", entry.Level.String(), rule.Name),
+		Text:   entry.Message,
+		Level:  level,
+		Fields: fields,
+		Time:   entry.Time,
+	}
+}
+
+// send 发送告警，失败时写入标准错误，避免通过日志递归触发告警
+func (hook *AlertHook) send(msg *notify.Message) {
+	defer hook.pending.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), hook.timeout)
+	defer cancel()
+	if err := hook.notifier.Send(ctx, msg); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to send log alert: %v\n", err)
+	}
+}
+
+// Flush 等待所有告警发送完成，可在程序退出前调用
+func (hook *AlertHook) Flush() {
+	hook.pending.Wait()
+}
@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/hwh/hwhkit-go/pkg/notify"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingNotifier 记录发送的告警
+type recordingNotifier struct {
+	mu       sync.Mutex
+	messages []*notify.Message
+}
+
+func (n *recordingNotifier) Name() string { return "recording" }
+
+func (n *recordingNotifier) Send(ctx context.Context, msg *notify.Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+func TestAlertHook(t *testing.T) {
+	notifier := &recordingNotifier{}
+	hook := NewAlertHook(notifier, time.Minute,
+		AlertRule{Name: "errors", MinLevel: logrus.ErrorLevel},
+		AlertRule{Name: "timeouts", MinLevel: logrus.WarnLevel, Pattern: regexp.MustCompile(`timeout`), Threshold: 3},
+	)
+	assert.Equal(t, []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel}, hook.Levels())
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	log.AddHook(hook)
+
+	log.WithField(TraceIDField, "abc").Error("database unavailable")
+	log.Error("database unavailable") // 去重窗口内不重复发送
+	log.Info("request timeout")       // 低于规则级别
+	for i := 0; i < 3; i++ {
+		log.Warn("upstream timeout")
+	}
+	hook.Flush()
+
+	require.Len(t, notifier.messages, 2)
+	sent := make(map[string]*notify.Message)
+	for _, msg := range notifier.messages {
+		sent[msg.Text] = msg
+	}
+	require.Contains(t, sent, "database unavailable")
+	assert.Equal(t, notify.LevelError, sent["database unavailable"].Level)
+	assert.Equal(t, "abc", sent["database unavailable"].Fields[TraceIDField])
+	require.Contains(t, sent, "upstream timeout")
+	assert.Equal(t, notify.LevelWarning, sent["upstream timeout"].Level)
+	assert.Equal(t, "timeouts", sent["upstream timeout"].Fields["rule"])
+
+	// 去重窗口过后再次发送
+	hook.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	log.Error("database unavailable")
+	hook.Flush()
+	assert.Len(t, notifier.messages, 3)
+}
+
+func TestNewAlertHookFromConfig(t *testing.T) {
+	_, err := NewAlertHookFromConfig(&config.AlertConfig{Enabled: true})
+	assert.Error(t, err)
+
+	_, err = NewAlertHookFromConfig(&config.AlertConfig{Enabled: true, SlackWebhookURL: "http://localhost", MessagePattern: "("})
+	assert.Error(t, err)
+
+	hook, err := NewAlertHookFromConfig(&config.AlertConfig{
+		Enabled:          true,
+		MinLevel:         "warn",
+		FeishuWebhookURL: "http://localhost",
+		Threshold:        5,
+		Window:           30,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, logrus.WarnLevel, hook.rules[0].MinLevel)
+	assert.Equal(t, 30*time.Second, hook.rules[0].Window)
+}
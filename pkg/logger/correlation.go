@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 日志中追踪相关的字段名
+const (
+	TraceIDField   = "trace_id"   // 关联ID（追踪ID或请求ID）
+	SpanIDField    = "span_id"    // OpenTelemetry span ID
+	RequestIDField = "request_id" // 请求ID（X-Request-ID）
+)
+
+// traceIDKey 上下文中保存关联ID的键
+type traceIDKey struct{}
+
+// requestIDKey 上下文中保存请求ID的键
+type requestIDKey struct{}
+
+// ContextWithRequestID 将请求ID写入上下文
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext 从上下文获取请求ID
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// ContextWithTraceID 将关联ID（追踪ID或请求ID）写入上下文
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext 从上下文获取关联ID，未写入时使用上下文中OpenTelemetry span的追踪ID
+func TraceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if traceID, _ := ctx.Value(traceIDKey{}).(string); traceID != "" {
+		return traceID
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		return span.TraceID().String()
+	}
+	return ""
+}
+
+// SpanIDFromContext 从上下文获取当前OpenTelemetry span的ID，不存在时返回空
+func SpanIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		return span.SpanID().String()
+	}
+	return ""
+}
+
+// WithContext 创建带上下文的日志条目，上下文中有请求ID、关联ID和span时自动添加 request_id、trace_id、span_id 字段
+func (m *Manager) WithContext(ctx context.Context) *logrus.Entry {
+	entry := m.logger.WithContext(ctx)
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		entry = entry.WithField(RequestIDField, requestID)
+	}
+	if traceID := TraceIDFromContext(ctx); traceID != "" {
+		entry = entry.WithField(TraceIDField, traceID)
+	}
+	if spanID := SpanIDFromContext(ctx); spanID != "" {
+		entry = entry.WithField(SpanIDField, spanID)
+	}
+	return entry
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWithContextSpanFields(t *testing.T) {
+	var buf bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&buf)
+	log.SetFormatter(&logrus.JSONFormatter{})
+	manager := &Manager{logger: log}
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9},
+		SpanID:     trace.SpanID{0x00, 0xf0},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContext)
+
+	manager.WithContext(ctx).Info("handled")
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, spanContext.TraceID().String(), entry[TraceIDField])
+	assert.Equal(t, spanContext.SpanID().String(), entry[SpanIDField])
+
+	// 显式写入的关联ID优先于span的追踪ID
+	buf.Reset()
+	manager.WithContext(ContextWithTraceID(ctx, "request-1")).Info("handled")
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "request-1", entry[TraceIDField])
+	assert.Equal(t, spanContext.SpanID().String(), entry[SpanIDField])
+
+	assert.Empty(t, SpanIDFromContext(context.Background()))
+}
+
+func TestRequestIDHook(t *testing.T) {
+	var buf bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&buf)
+	log.SetFormatter(&logrus.JSONFormatter{})
+	log.AddHook(NewRequestIDHook())
+
+	ctx := ContextWithRequestID(context.Background(), "req-42")
+	assert.Equal(t, "req-42", RequestIDFromContext(ctx))
+
+	// 直接使用 logrus 的 WithContext 也会附加请求ID
+	log.WithContext(ctx).Info("handled")
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "req-42", entry[RequestIDField])
+
+	// 显式设置的字段不会被覆盖
+	buf.Reset()
+	log.WithContext(ctx).WithField(RequestIDField, "explicit").Info("handled")
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "explicit", entry[RequestIDField])
+
+	buf.Reset()
+	log.Info("no context")
+	entry = map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.NotContains(t, entry, RequestIDField)
+}
@@ -5,6 +5,7 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/hwh/hwhkit-go/pkg/utils"
 	"github.com/sirupsen/logrus"
 )
 
@@ -79,18 +80,50 @@ func (hook *RequestIDHook) Levels() []logrus.Level {
 
 // Fire 执行钩子
 func (hook *RequestIDHook) Fire(entry *logrus.Entry) error {
-	// 如果存在请求ID，添加到日志中
-	if requestID, exists := entry.Data["request_id"]; exists {
-		entry.Data["request_id"] = requestID
+	// 通过 WithContext 记录的日志自动带上请求ID
+	if _, exists := entry.Data[RequestIDField]; !exists {
+		if requestID := RequestIDFromContext(entry.Context); requestID != "" {
+			entry.Data[RequestIDField] = requestID
+		}
+	}
+	
+	// 通过 WithContext 记录的日志自动带上关联ID
+	if _, exists := entry.Data[TraceIDField]; !exists {
+		if traceID := TraceIDFromContext(entry.Context); traceID != "" {
+			entry.Data[TraceIDField] = traceID
+		}
+	}
+	if _, exists := entry.Data[SpanIDField]; !exists {
+		if spanID := SpanIDFromContext(entry.Context); spanID != "" {
+			entry.Data[SpanIDField] = spanID
+		}
 	}
 	return nil
 }
 
 // SensitiveDataHook 敏感数据过滤钩子
 type SensitiveDataHook struct {
-	levels       []logrus.Level
+	levels        []logrus.Level
 	SensitiveKeys []string
 	Replacement   string
+	// MaskFields 需要部分脱敏的字段及其脱敏类型，如 {"phone": "phone", "email": "email"}
+	MaskFields map[string]string
+	// MaskMessage 为true时自动脱敏消息中的邮箱、手机号、身份证号和银行卡号
+	MaskMessage bool
+}
+
+// NewPIIMaskHook 创建个人信息脱敏钩子，对常见PII字段部分脱敏并扫描消息内容
+func NewPIIMaskHook(sensitiveKeys []string, levels ...logrus.Level) *SensitiveDataHook {
+	hook := NewSensitiveDataHook(sensitiveKeys, levels...)
+	hook.MaskFields = map[string]string{
+		"email":   "email",
+		"phone":   "phone",
+		"mobile":  "phone",
+		"id_card": "idcard",
+		"card_no": "card",
+	}
+	hook.MaskMessage = true
+	return hook
 }
 
 // NewSensitiveDataHook 创建敏感数据过滤钩子
@@ -126,8 +159,18 @@ func (hook *SensitiveDataHook) Fire(entry *logrus.Entry) error {
 			message = strings.ReplaceAll(message, key, hook.Replacement)
 		}
 	}
+	if hook.MaskMessage {
+		message = utils.Mask.MaskText(message)
+	}
 	entry.Message = message
 	
+	// 部分脱敏字段（保留首尾字符便于排查）
+	for key, maskType := range hook.MaskFields {
+		if value, exists := entry.Data[key]; exists {
+			entry.Data[key] = utils.Mask.MaskByType(fmt.Sprint(value), maskType)
+		}
+	}
+	
 	return nil
 }
 
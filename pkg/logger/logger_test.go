@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -27,7 +28,7 @@ func TestLoggerCreation(t *testing.T) {
 		t.Fatal("Logger should not be nil")
 	}
 	
-	if logger.GetLevel() != logrus.InfoLevel {
+	if logger.GetLevel() != "info" {
 		t.Errorf("Expected log level Info, got %v", logger.GetLevel())
 	}
 }
@@ -73,7 +74,7 @@ func TestLoggerWithFields(t *testing.T) {
 	logger.WithField("user_id", "123").Info("User logged in")
 	
 	// 测试多个字段
-	logger.WithFields(logrus.Fields{
+	logger.WithFields(Fields{
 		"user_id":   "123",
 		"action":    "login",
 		"timestamp": time.Now(),
@@ -201,7 +202,7 @@ func TestSensitiveDataHook(t *testing.T) {
 	logger.AddHook(hook)
 	
 	// 记录包含敏感数据的日志
-	logger.WithFields(logrus.Fields{
+	logger.WithFields(Fields{
 		"user":     "john",
 		"password": "secret123",
 		"token":    "abc123",
@@ -223,6 +224,36 @@ func TestSensitiveDataHook(t *testing.T) {
 	}
 }
 
+func TestPIIMaskHook(t *testing.T) {
+	var buf bytes.Buffer
+	
+	logger, err := New(&config.LogConfig{
+		Level:  "info",
+		Format: "text",
+		Output: "console",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	
+	logger.GetLogger().SetOutput(&buf)
+	logger.AddHook(NewPIIMaskHook([]string{"password"}))
+	
+	logger.WithFields(Fields{
+		"phone": "13812345678",
+	}).Info("Notify john@example.com")
+	
+	output := buf.String()
+	
+	if !strings.Contains(output, "138****5678") {
+		t.Errorf("Phone was not partially masked: %s", output)
+	}
+	
+	if strings.Contains(output, "john@example.com") {
+		t.Errorf("Email in message was not masked: %s", output)
+	}
+}
+
 func TestMetricsHook(t *testing.T) {
 	cfg := &config.LogConfig{
 		Level:  "debug",
@@ -309,8 +340,10 @@ func TestLoggerSetLevel(t *testing.T) {
 	}
 	
 	// 测试设置级别
-	logger.SetLevel(logrus.DebugLevel)
-	if logger.GetLevel() != logrus.DebugLevel {
+	if err := logger.SetLevel("debug"); err != nil {
+		t.Fatalf("Failed to set level: %v", err)
+	}
+	if logger.GetLevel() != "debug" {
 		t.Errorf("Expected debug level, got %v", logger.GetLevel())
 	}
 	
@@ -323,7 +356,9 @@ func TestLoggerSetLevel(t *testing.T) {
 		t.Error("Info level should be enabled")
 	}
 	
-	logger.SetLevel(logrus.WarnLevel)
+	if err := logger.SetLevel("warn"); err != nil {
+		t.Fatalf("Failed to set level: %v", err)
+	}
 	if logger.IsLevelEnabled(logrus.DebugLevel) {
 		t.Error("Debug level should not be enabled")
 	}
@@ -368,7 +403,7 @@ func BenchmarkLoggerWithFields(b *testing.B) {
 		b.Fatalf("Failed to create logger: %v", err)
 	}
 	
-	fields := logrus.Fields{
+	fields := Fields{
 		"user_id": "123",
 		"action":  "login",
 		"ip":      "192.168.1.1",
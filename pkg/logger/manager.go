@@ -58,6 +58,18 @@ func (m *Manager) configure() error {
 	// 设置调用者信息
 	m.logger.SetReportCaller(true)
 	
+	// 请求内的日志自动附加请求ID和关联ID
+	m.logger.AddHook(NewRequestIDHook())
+	
+	// 基于日志的告警
+	if m.config.Alert.Enabled {
+		hook, err := NewAlertHookFromConfig(&m.config.Alert)
+		if err != nil {
+			return err
+		}
+		m.logger.AddHook(hook)
+	}
+	
 	return nil
 }
 
@@ -103,7 +115,7 @@ func (m *Manager) setOutput() error {
 			Filename:   m.config.FilePath,
 			MaxSize:    m.config.MaxSize,
 			MaxBackups: m.config.MaxBackups,
-			MaxAge:     m.config.MaxAge,
+			MaxAge:     m.maxAge(),
 			Compress:   m.config.Compress,
 		}
 		m.logger.SetOutput(fileWriter)
@@ -117,7 +129,7 @@ func (m *Manager) setOutput() error {
 			Filename:   m.config.FilePath,
 			MaxSize:    m.config.MaxSize,
 			MaxBackups: m.config.MaxBackups,
-			MaxAge:     m.config.MaxAge,
+			MaxAge:     m.maxAge(),
 			Compress:   m.config.Compress,
 		}
 		
@@ -131,6 +143,14 @@ func (m *Manager) setOutput() error {
 	return nil
 }
 
+// maxAge 日志文件的最长保留天数，设置了数据保留期时以保留期为准
+func (m *Manager) maxAge() int {
+	if m.config.RetentionDays > 0 {
+		return m.config.RetentionDays
+	}
+	return m.config.MaxAge
+}
+
 // createLogDir 创建日志目录
 func (m *Manager) createLogDir() error {
 	if m.config.FilePath == "" {
@@ -145,6 +165,11 @@ func (m *Manager) createLogDir() error {
 	return nil
 }
 
+// Config 获取日志配置
+func (m *Manager) Config() *config.LogConfig {
+	return m.config
+}
+
 // GetLogger 获取原始的logrus实例
 func (m *Manager) GetLogger() *logrus.Logger {
 	return m.logger
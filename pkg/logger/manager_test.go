@@ -242,7 +242,7 @@ func TestCreateLogDir(t *testing.T) {
 		FilePath: logFile,
 	}
 	
-	manager, err := New(cfg)
+	_, err := New(cfg)
 	require.NoError(t, err)
 	
 	// 检查目录是否被创建
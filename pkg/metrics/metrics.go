@@ -0,0 +1,549 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets 默认的延迟直方图桶（秒）
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// labelSeparator 标签值拼接分隔符
+const labelSeparator = "\xff"
+
+// Registry 指标注册表
+type Registry struct {
+	mu      sync.RWMutex
+	metrics map[string]metric
+}
+
+// metric 指标通用接口
+type metric interface {
+	name() string
+	help() string
+	kind() string
+	snapshot() interface{}
+	writePrometheus(w io.Writer) error
+}
+
+// Default 默认指标注册表
+var Default = NewRegistry()
+
+// NewRegistry 创建新的指标注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]metric),
+	}
+}
+
+// Counter 获取或注册计数器
+func (r *Registry) Counter(name, help string, labelNames ...string) *Counter {
+	return r.getOrRegister(name, func() metric {
+		return &Counter{valueVec{vec: newVec(name, help, labelNames)}}
+	}).(*Counter)
+}
+
+// Gauge 获取或注册仪表盘
+func (r *Registry) Gauge(name, help string, labelNames ...string) *Gauge {
+	return r.getOrRegister(name, func() metric {
+		return &Gauge{valueVec{vec: newVec(name, help, labelNames)}}
+	}).(*Gauge)
+}
+
+// Histogram 获取或注册直方图，buckets 为空时使用 DefaultBuckets
+func (r *Registry) Histogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	return r.getOrRegister(name, func() metric {
+		if len(buckets) == 0 {
+			buckets = DefaultBuckets
+		}
+		sorted := append([]float64(nil), buckets...)
+		sort.Float64s(sorted)
+		return &Histogram{
+			vec:     newVec(name, help, labelNames),
+			buckets: sorted,
+			series:  make(map[string]*histogramSeries),
+		}
+	}).(*Histogram)
+}
+
+// getOrRegister 获取已注册的指标，不存在时创建
+func (r *Registry) getOrRegister(name string, create func() metric) metric {
+	r.mu.RLock()
+	m, exists := r.metrics[name]
+	r.mu.RUnlock()
+	if exists {
+		return m
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, exists := r.metrics[name]; exists {
+		return m
+	}
+	m = create()
+	r.metrics[name] = m
+	return m
+}
+
+// sortedMetrics 按名称排序返回所有指标
+func (r *Registry) sortedMetrics() []metric {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].name() < list[j].name()
+	})
+	return list
+}
+
+// Snapshot 获取所有指标的快照，用于JSON输出
+func (r *Registry) Snapshot() map[string]interface{} {
+	result := make(map[string]interface{})
+	for _, m := range r.sortedMetrics() {
+		result[m.name()] = m.snapshot()
+	}
+	return result
+}
+
+// WritePrometheus 以Prometheus文本格式输出所有指标
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	for _, m := range r.sortedMetrics() {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name(), m.help(), m.name(), m.kind()); err != nil {
+			return err
+		}
+		if err := m.writePrometheus(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteOpenMetrics 以OpenMetrics文本格式输出所有指标，包含直方图的 exemplar
+// 计数器的指标族名称去掉 _total 后缀，样本名称保留 _total
+func (r *Registry) WriteOpenMetrics(w io.Writer) error {
+	for _, m := range r.sortedMetrics() {
+		family := m.name()
+		if m.kind() == "counter" {
+			family = strings.TrimSuffix(family, "_total")
+		}
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", family, m.help(), family, m.kind()); err != nil {
+			return err
+		}
+
+		var err error
+		switch m := m.(type) {
+		case *Counter:
+			err = m.writeSamples(w, family+"_total")
+		case *Histogram:
+			err = m.write(w, true)
+		default:
+			err = m.writePrometheus(w)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "# EOF\n")
+	return err
+}
+
+// vec 带标签的指标基础结构
+type vec struct {
+	metricName string
+	metricHelp string
+	labelNames []string
+}
+
+// newVec 创建带标签的指标基础结构
+func newVec(name, help string, labelNames []string) vec {
+	return vec{
+		metricName: name,
+		metricHelp: help,
+		labelNames: labelNames,
+	}
+}
+
+func (v *vec) name() string { return v.metricName }
+func (v *vec) help() string { return v.metricHelp }
+
+// seriesKey 根据标签值生成序列键
+func (v *vec) seriesKey(labelValues []string) string {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.metricName, len(v.labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, labelSeparator)
+}
+
+// seriesName 生成JSON快照中的序列名，如 command=get
+func (v *vec) seriesName(key string) string {
+	if len(v.labelNames) == 0 {
+		return ""
+	}
+	values := strings.Split(key, labelSeparator)
+	parts := make([]string, len(v.labelNames))
+	for i, name := range v.labelNames {
+		parts[i] = name + "=" + values[i]
+	}
+	return strings.Join(parts, ",")
+}
+
+// formatLabels 生成Prometheus标签字符串
+func (v *vec) formatLabels(key string, extra ...string) string {
+	var parts []string
+	if len(v.labelNames) > 0 {
+		values := strings.Split(key, labelSeparator)
+		for i, name := range v.labelNames {
+			parts = append(parts, fmt.Sprintf("%s=%q", name, values[i]))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=%q", extra[i], extra[i+1]))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// valueVec 计数器和仪表盘共用的数值存储
+type valueVec struct {
+	vec
+	mu     sync.RWMutex
+	values map[string]float64
+}
+
+func (v *valueVec) add(delta float64, labelValues []string) {
+	key := v.seriesKey(labelValues)
+	v.mu.Lock()
+	if v.values == nil {
+		v.values = make(map[string]float64)
+	}
+	v.values[key] += delta
+	v.mu.Unlock()
+}
+
+func (v *valueVec) set(value float64, labelValues []string) {
+	key := v.seriesKey(labelValues)
+	v.mu.Lock()
+	if v.values == nil {
+		v.values = make(map[string]float64)
+	}
+	v.values[key] = value
+	v.mu.Unlock()
+}
+
+func (v *valueVec) get(labelValues []string) float64 {
+	key := v.seriesKey(labelValues)
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.values[key]
+}
+
+func (v *valueVec) snapshot() interface{} {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if len(v.labelNames) == 0 {
+		return v.values[""]
+	}
+	result := make(map[string]float64, len(v.values))
+	for key, value := range v.values {
+		result[v.seriesName(key)] = value
+	}
+	return result
+}
+
+func (v *valueVec) writePrometheus(w io.Writer) error {
+	return v.writeSamples(w, v.metricName)
+}
+
+// writeSamples 以指定的样本名称输出所有序列
+func (v *valueVec) writeSamples(w io.Writer, sampleName string) error {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	for _, key := range sortedKeys(v.values) {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", sampleName, v.formatLabels(key), formatFloat(v.values[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Counter 单调递增计数器
+type Counter struct {
+	valueVec
+}
+
+func (c *Counter) kind() string { return "counter" }
+
+// Inc 计数加1
+func (c *Counter) Inc(labelValues ...string) {
+	c.add(1, labelValues)
+}
+
+// Add 计数增加指定值，负值会被忽略
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	c.add(delta, labelValues)
+}
+
+// Value 获取当前计数
+func (c *Counter) Value(labelValues ...string) float64 {
+	return c.get(labelValues)
+}
+
+// Gauge 可增可减的仪表盘
+type Gauge struct {
+	valueVec
+}
+
+func (g *Gauge) kind() string { return "gauge" }
+
+// Set 设置当前值
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.set(value, labelValues)
+}
+
+// Inc 当前值加1
+func (g *Gauge) Inc(labelValues ...string) {
+	g.add(1, labelValues)
+}
+
+// Dec 当前值减1
+func (g *Gauge) Dec(labelValues ...string) {
+	g.add(-1, labelValues)
+}
+
+// Add 当前值增加指定值
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	g.add(delta, labelValues)
+}
+
+// Value 获取当前值
+func (g *Gauge) Value(labelValues ...string) float64 {
+	return g.get(labelValues)
+}
+
+// Histogram 分布直方图
+type Histogram struct {
+	vec
+	buckets []float64
+	mu      sync.RWMutex
+	series  map[string]*histogramSeries
+}
+
+// histogramSeries 单个标签组合的直方图数据
+type histogramSeries struct {
+	counts    []uint64
+	count     uint64
+	sum       float64
+	exemplars []*Exemplar // 每个桶最近一次的 exemplar，最后一个对应 +Inf
+}
+
+// Exemplar 直方图样本示例，通过标签（如 trace_id）关联到具体请求的日志和追踪
+type Exemplar struct {
+	Labels    map[string]string `json:"labels"`
+	Value     float64           `json:"value"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// HistogramSnapshot 直方图快照
+type HistogramSnapshot struct {
+	Count     uint64              `json:"count"`
+	Sum       float64             `json:"sum"`
+	Avg       float64             `json:"avg"`
+	Buckets   map[string]uint64   `json:"buckets"`
+	Exemplars map[string]Exemplar `json:"exemplars,omitempty"` // 按桶上界索引
+}
+
+func (h *Histogram) kind() string { return "histogram" }
+
+// Observe 记录一次观测值
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.observe(value, nil, labelValues)
+}
+
+// ObserveWithExemplar 记录一次观测值，并将 exemplar 标签（如 trace_id）附加到所在的桶
+func (h *Histogram) ObserveWithExemplar(value float64, exemplar map[string]string, labelValues ...string) {
+	h.observe(value, exemplar, labelValues)
+}
+
+func (h *Histogram) observe(value float64, exemplar map[string]string, labelValues []string) {
+	key := h.seriesKey(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, exists := h.series[key]
+	if !exists {
+		s = &histogramSeries{
+			counts:    make([]uint64, len(h.buckets)),
+			exemplars: make([]*Exemplar, len(h.buckets)+1),
+		}
+		h.series[key] = s
+	}
+	bucket := len(h.buckets)
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+			if bucket == len(h.buckets) {
+				bucket = i // 桶按上界升序排列，第一个匹配的即为所在桶
+			}
+		}
+	}
+	s.count++
+	s.sum += value
+
+	if len(exemplar) > 0 {
+		s.exemplars[bucket] = &Exemplar{Labels: exemplar, Value: value, Timestamp: time.Now()}
+	}
+}
+
+// ObserveSince 记录从 start 到当前的耗时（秒）
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Snapshot 获取指定标签组合的直方图快照
+func (h *Histogram) Snapshot(labelValues ...string) HistogramSnapshot {
+	key := h.seriesKey(labelValues)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.snapshotSeries(h.series[key])
+}
+
+func (h *Histogram) snapshotSeries(s *histogramSeries) HistogramSnapshot {
+	snap := HistogramSnapshot{Buckets: make(map[string]uint64, len(h.buckets))}
+	if s == nil {
+		return snap
+	}
+	snap.Count = s.count
+	snap.Sum = s.sum
+	if s.count > 0 {
+		snap.Avg = s.sum / float64(s.count)
+	}
+	for i, bound := range h.buckets {
+		snap.Buckets[formatFloat(bound)] = s.counts[i]
+	}
+	for i, exemplar := range s.exemplars {
+		if exemplar == nil {
+			continue
+		}
+		if snap.Exemplars == nil {
+			snap.Exemplars = make(map[string]Exemplar)
+		}
+		snap.Exemplars[h.bucketBound(i)] = *exemplar
+	}
+	return snap
+}
+
+// bucketBound 返回桶的上界字符串，超出最大桶时为 +Inf
+func (h *Histogram) bucketBound(i int) string {
+	if i >= len(h.buckets) {
+		return "+Inf"
+	}
+	return formatFloat(h.buckets[i])
+}
+
+func (h *Histogram) snapshot() interface{} {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.labelNames) == 0 {
+		return h.snapshotSeries(h.series[""])
+	}
+	result := make(map[string]HistogramSnapshot, len(h.series))
+	for key, s := range h.series {
+		result[h.seriesName(key)] = h.snapshotSeries(s)
+	}
+	return result
+}
+
+func (h *Histogram) writePrometheus(w io.Writer) error {
+	return h.write(w, false)
+}
+
+// write 输出直方图样本，openMetrics 为true时在桶样本后附加 exemplar
+func (h *Histogram) write(w io.Writer, openMetrics bool) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := h.series[key]
+		for i := 0; i <= len(h.buckets); i++ {
+			count := s.count
+			if i < len(h.buckets) {
+				count = s.counts[i]
+			}
+			var exemplar string
+			if openMetrics {
+				exemplar = formatExemplar(s.exemplars[i])
+			}
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d%s\n", h.metricName, h.formatLabels(key, "le", h.bucketBound(i)), count, exemplar); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_sum%s %s\n%s_count%s %d\n",
+			h.metricName, h.formatLabels(key), formatFloat(s.sum),
+			h.metricName, h.formatLabels(key), s.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatExemplar 生成OpenMetrics exemplar 后缀，如  # {trace_id="abc"} 0.05 1700000000.123
+func formatExemplar(e *Exemplar) string {
+	if e == nil {
+		return ""
+	}
+	names := make([]string, 0, len(e.Labels))
+	for name := range e.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%q", name, e.Labels[name])
+	}
+	timestamp := float64(e.Timestamp.UnixNano()) / float64(time.Second)
+	return fmt.Sprintf(" # {%s} %s %s", strings.Join(parts, ","), formatFloat(e.Value), strconv.FormatFloat(timestamp, 'f', 3, 64))
+}
+
+// sortedKeys 返回排序后的键列表
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatFloat 格式化浮点数
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("requests_total", "Total requests", "method")
+
+	c.Inc("GET")
+	c.Inc("GET")
+	c.Add(3, "POST")
+	c.Add(-1, "POST") // 负值忽略
+
+	assert.Equal(t, float64(2), c.Value("GET"))
+	assert.Equal(t, float64(3), c.Value("POST"))
+
+	// 重复注册返回同一个实例
+	assert.Same(t, c, r.Counter("requests_total", "Total requests", "method"))
+}
+
+func TestGauge(t *testing.T) {
+	r := NewRegistry()
+	g := r.Gauge("connections", "Open connections")
+
+	g.Set(10)
+	g.Inc()
+	g.Dec()
+	g.Dec()
+
+	assert.Equal(t, float64(9), g.Value())
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.Histogram("latency_seconds", "Latency", []float64{0.1, 1}, "op")
+
+	h.Observe(0.05, "get")
+	h.Observe(0.5, "get")
+	h.Observe(2, "get")
+
+	snap := h.Snapshot("get")
+	assert.Equal(t, uint64(3), snap.Count)
+	assert.InDelta(t, 2.55, snap.Sum, 1e-9)
+	assert.Equal(t, uint64(1), snap.Buckets["0.1"])
+	assert.Equal(t, uint64(2), snap.Buckets["1"])
+}
+
+func TestRegistry_WritePrometheus(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("hits_total", "Cache hits").Inc()
+	r.Histogram("op_seconds", "Op latency", []float64{1}, "command").Observe(0.5, "get")
+
+	var buf bytes.Buffer
+	assert.NoError(t, r.WritePrometheus(&buf))
+
+	output := buf.String()
+	assert.Contains(t, output, "# TYPE hits_total counter")
+	assert.Contains(t, output, "hits_total 1")
+	assert.Contains(t, output, `op_seconds_bucket{command="get",le="1"} 1`)
+	assert.Contains(t, output, `op_seconds_bucket{command="get",le="+Inf"} 1`)
+	assert.True(t, strings.Index(output, "hits_total") < strings.Index(output, "op_seconds"))
+}
+
+func TestRegistry_Snapshot(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("errors_total", "Errors", "command").Inc("set")
+
+	snap := r.Snapshot()
+	assert.Equal(t, map[string]float64{"command=set": 1}, snap["errors_total"])
+}
+
+func TestHistogramExemplars(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("requests_total", "Requests").Inc()
+	h := r.Histogram("request_seconds", "Request latency", []float64{0.1, 1})
+
+	h.ObserveWithExemplar(0.05, map[string]string{"trace_id": "abc"})
+	h.ObserveWithExemplar(5, map[string]string{"trace_id": "def"})
+	h.Observe(0.5)
+
+	snap := h.Snapshot()
+	assert.Equal(t, uint64(3), snap.Count)
+	assert.Equal(t, "abc", snap.Exemplars["0.1"].Labels["trace_id"])
+	assert.Equal(t, "def", snap.Exemplars["+Inf"].Labels["trace_id"])
+	assert.NotContains(t, snap.Exemplars, "1")
+
+	var buf bytes.Buffer
+	assert.NoError(t, r.WriteOpenMetrics(&buf))
+	output := buf.String()
+	assert.Contains(t, output, "# TYPE requests counter")
+	assert.Contains(t, output, "requests_total 1")
+	assert.Contains(t, output, `request_seconds_bucket{le="0.1"} 1 # {trace_id="abc"} 0.05 `)
+	assert.Contains(t, output, `request_seconds_bucket{le="1"} 2`+"\n")
+	assert.Contains(t, output, `request_seconds_bucket{le="+Inf"} 3 # {trace_id="def"} 5 `)
+	assert.True(t, strings.HasSuffix(output, "# EOF\n"))
+
+	// Prometheus文本格式不输出 exemplar
+	buf.Reset()
+	assert.NoError(t, r.WritePrometheus(&buf))
+	assert.NotContains(t, buf.String(), "trace_id")
+}
+
+func TestTimings(t *testing.T) {
+	timings := NewTimings()
+	ctx := WithTimings(context.Background(), timings)
+	assert.Same(t, timings, TimingsFromContext(ctx))
+	assert.Nil(t, TimingsFromContext(context.Background()))
+
+	timings.Add("db", 10*time.Millisecond)
+	timings.Add("cache", 500*time.Microsecond)
+	timings.Add("db", 2500*time.Microsecond)
+	StartTiming(ctx, "render")()
+	// 上下文中没有记录时不做任何事
+	StartTiming(context.Background(), "ignored")()
+	var empty *Timings
+	empty.Add("db", time.Second)
+
+	segments := timings.Segments()
+	assert.Len(t, segments, 3)
+	assert.Equal(t, TimingSegment{Name: "db", Duration: 12500 * time.Microsecond, Count: 2}, segments[0])
+	assert.Equal(t, `db;dur=12.50;desc="2 ops", cache;dur=0.50;desc="1 op"`, strings.SplitN(timings.Header(), ", render", 2)[0])
+	assert.Equal(t, "handler;dur=1.00", FormatTiming("handler", time.Millisecond, ""))
+}
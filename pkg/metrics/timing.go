@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 内置的耗时分段名称
+const (
+	TimingDB    = "db"    // SQL语句，由 database.Manager.EnableTiming 记录
+	TimingCache = "cache" // Redis命令，由 cache.Manager.EnableTiming 记录
+)
+
+// timingsKey 上下文中保存请求耗时分段的键
+type timingsKey struct{}
+
+// Timings 请求范围内的耗时分段，按名称累计耗时和次数，用于输出 Server-Timing 响应头
+type Timings struct {
+	mu       sync.Mutex
+	segments map[string]*TimingSegment
+	order    []string
+}
+
+// TimingSegment 耗时分段
+type TimingSegment struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	Count    int           `json:"count"`
+}
+
+// NewTimings 创建耗时分段记录
+func NewTimings() *Timings {
+	return &Timings{segments: make(map[string]*TimingSegment)}
+}
+
+// WithTimings 将耗时分段记录放入上下文
+func WithTimings(ctx context.Context, t *Timings) context.Context {
+	return context.WithValue(ctx, timingsKey{}, t)
+}
+
+// TimingsFromContext 获取上下文中的耗时分段记录，不存在时返回 nil
+func TimingsFromContext(ctx context.Context) *Timings {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(timingsKey{}).(*Timings)
+	return t
+}
+
+// StartTiming 开始记录上下文中的耗时分段，返回结束函数；上下文中没有记录时返回空函数
+func StartTiming(ctx context.Context, name string) func() {
+	t := TimingsFromContext(ctx)
+	if t == nil {
+		return func() {}
+	}
+	return t.Start(name)
+}
+
+// Add 累加分段耗时，nil 记录上调用时不做任何事
+func (t *Timings) Add(name string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	segment, exists := t.segments[name]
+	if !exists {
+		segment = &TimingSegment{Name: name}
+		t.segments[name] = segment
+		t.order = append(t.order, name)
+	}
+	segment.Duration += d
+	segment.Count++
+}
+
+// Start 开始记录分段耗时，返回结束函数
+func (t *Timings) Start(name string) func() {
+	start := time.Now()
+	return func() {
+		t.Add(name, time.Since(start))
+	}
+}
+
+// Count 获取分段的累计次数，分段不存在时返回0
+func (t *Timings) Count(name string) int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if segment, exists := t.segments[name]; exists {
+		return segment.Count
+	}
+	return 0
+}
+
+// Segments 按首次记录顺序返回所有分段
+func (t *Timings) Segments() []TimingSegment {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	segments := make([]TimingSegment, 0, len(t.order))
+	for _, name := range t.order {
+		segments = append(segments, *t.segments[name])
+	}
+	return segments
+}
+
+// Header 生成 Server-Timing 响应头的值，如 db;dur=12.5;desc="3 ops", cache;dur=0.8;desc="2 ops"
+func (t *Timings) Header() string {
+	segments := t.Segments()
+	parts := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		desc := fmt.Sprintf("%d ops", segment.Count)
+		if segment.Count == 1 {
+			desc = "1 op"
+		}
+		parts = append(parts, FormatTiming(segment.Name, segment.Duration, desc))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FormatTiming 格式化单个 Server-Timing 条目，耗时单位为毫秒，desc 为空时省略
+func FormatTiming(name string, d time.Duration, desc string) string {
+	entry := fmt.Sprintf("%s;dur=%.2f", name, float64(d)/float64(time.Millisecond))
+	if desc != "" {
+		entry += fmt.Sprintf(";desc=%q", desc)
+	}
+	return entry
+}
@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/analytics"
+)
+
+// APIKeyHeader API Key请求头
+const APIKeyHeader = "X-API-Key"
+
+// AnalyticsAnonymousClient 未认证请求（包括携带未校验API Key的请求）共用的统计客户端标识
+const AnalyticsAnonymousClient = "anonymous"
+
+// apiKeyContextKey 上下文中保存已校验API Key标识的键
+const apiKeyContextKey = "api_key_id"
+
+// SetAPIKeyID 由应用的API Key校验中间件在密钥校验通过后调用，记录密钥标识（如密钥ID），用量统计按该标识区分客户端
+func SetAPIKeyID(c *gin.Context, keyID string) {
+	c.Set(apiKeyContextKey, keyID)
+}
+
+// GetAPIKeyID 获取已校验的API Key标识
+func GetAPIKeyID(c *gin.Context) (string, bool) {
+	keyID := c.GetString(apiKeyContextKey)
+	return keyID, keyID != ""
+}
+
+// AnalyticsConfig 用量统计中间件配置
+type AnalyticsConfig struct {
+	Manager   *analytics.Manager
+	KeyFunc   func(*gin.Context) string // 获取统计的客户端标识，返回空时不统计；默认按用户，其次按已校验的API Key
+	SkipPaths []string                  // 不统计的路径
+}
+
+// Analytics 用量统计中间件，按已认证用户或已校验的API Key累计请求数、错误数和处理耗时，需在JWT和API Key校验中间件之后使用
+// Redis不可用时放行请求，避免统计系统故障影响业务
+func Analytics(config *AnalyticsConfig) gin.HandlerFunc {
+	keyFunc := config.KeyFunc
+	if keyFunc == nil {
+		keyFunc = AnalyticsClientKey
+	}
+
+	return func(c *gin.Context) {
+		if shouldSkipPath(c.Request.URL.Path, config.SkipPaths) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		client := keyFunc(c)
+		if client == "" {
+			return
+		}
+		if err := config.Manager.Record(c.Request.Context(), client, c.Writer.Status(), time.Since(start)); err != nil {
+			c.Error(err)
+		}
+	}
+}
+
+// AnalyticsClientKey 默认的统计客户端标识：已认证用户为 user:<ID>，通过 SetAPIKeyID 校验的API Key为 key:<哈希前缀>，
+// 其余请求计入 anonymous；请求头中未经校验的API Key不作为客户端标识，避免伪造的密钥无限制地产生新客户端
+// API Key标识只保存哈希前缀，统计数据和导出文件中不出现原始值
+func AnalyticsClientKey(c *gin.Context) string {
+	if userID, exists := GetUserID(c); exists {
+		return "user:" + userID
+	}
+	if keyID, exists := GetAPIKeyID(c); exists {
+		sum := sha256.Sum256([]byte(keyID))
+		return "key:" + hex.EncodeToString(sum[:8])
+	}
+	return AnalyticsAnonymousClient
+}
+
+// RegisterAnalyticsAdminRoutes 注册用量统计管理接口，应挂载在需要管理员权限的路由组下
+// 时间参数支持RFC3339或 2006-01-02 格式，未指定 from 时查询最近24小时；format=csv 时以附件形式导出CSV
+//
+//	GET  /analytics/usage?client=user:42&from=&to=  每小时用量
+//	GET  /analytics/clients?from=&to=&limit=100     按客户端汇总，按请求数排序
+//	POST /analytics/rollup                          立即将已结束的小时汇总到数据库
+func RegisterAnalyticsAdminRoutes(router gin.IRouter, manager *analytics.Manager) {
+	router.GET("/analytics/usage", func(c *gin.Context) {
+		filter, err := analyticsFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		records, err := manager.Usage(c.Request.Context(), filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if c.Query("format") == "csv" {
+			writeCSVAttachment(c, "usage.csv")
+			if err := analytics.WriteUsageCSV(c.Writer, records); err != nil {
+				c.Error(err)
+			}
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"records": records})
+	})
+
+	router.GET("/analytics/clients", func(c *gin.Context) {
+		filter, err := analyticsFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		summaries, err := manager.Summary(c.Request.Context(), filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 && limit < len(summaries) {
+			summaries = summaries[:limit]
+		}
+		if c.Query("format") == "csv" {
+			writeCSVAttachment(c, "clients.csv")
+			if err := analytics.WriteSummaryCSV(c.Writer, summaries); err != nil {
+				c.Error(err)
+			}
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"clients": summaries})
+	})
+
+	router.POST("/analytics/rollup", func(c *gin.Context) {
+		count, err := manager.Rollup(c.Request.Context())
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, analytics.ErrNoDatabase) {
+				status = http.StatusConflict
+			}
+			c.JSON(status, gin.H{"error": err.Error(), "records": count})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"records": count})
+	})
+}
+
+// analyticsFilter 解析用量查询条件
+func analyticsFilter(c *gin.Context) (analytics.Filter, error) {
+	filter := analytics.Filter{Client: c.Query("client")}
+	var err error
+	if filter.From, err = parseAnalyticsTime(c.Query("from")); err != nil {
+		return filter, fmt.Errorf("invalid from: %w", err)
+	}
+	if filter.To, err = parseAnalyticsTime(c.Query("to")); err != nil {
+		return filter, fmt.Errorf("invalid to: %w", err)
+	}
+	if filter.From.IsZero() {
+		filter.From = time.Now().Add(-24 * time.Hour)
+	}
+	return filter, nil
+}
+
+// parseAnalyticsTime 解析RFC3339或日期格式的时间，为空时返回零值
+func parseAnalyticsTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// writeCSVAttachment 设置CSV附件响应头
+func writeCSVAttachment(c *gin.Context, filename string) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+}
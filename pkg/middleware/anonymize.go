@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/url"
+
+	"github.com/hwh/hwhkit-go/pkg/logger"
+)
+
+// 客户端IP匿名化方式
+const (
+	IPAnonymizeHash     = "hash"     // HMAC-SHA256 哈希，同一IP在日志中保持一致，可用于关联但无法直接还原
+	IPAnonymizeTruncate = "truncate" // 截断，IPv4 保留前24位，IPv6 保留前48位
+)
+
+// logPrivacy 访问日志隐私处理
+type logPrivacy struct {
+	anonymizeIP   string
+	ipHashSalt    []byte
+	dropUserAgent bool
+	excludeQuery  map[string]bool
+}
+
+// newLogPrivacy 创建访问日志隐私处理
+func newLogPrivacy(anonymizeIP, ipHashSalt string, dropUserAgent bool, excludeQueryParams []string) *logPrivacy {
+	p := &logPrivacy{
+		anonymizeIP:   anonymizeIP,
+		ipHashSalt:    []byte(ipHashSalt),
+		dropUserAgent: dropUserAgent,
+		excludeQuery:  make(map[string]bool, len(excludeQueryParams)),
+	}
+	for _, param := range excludeQueryParams {
+		p.excludeQuery[param] = true
+	}
+	return p
+}
+
+// logPrivacyFromManager 从日志管理器的配置创建隐私处理
+func logPrivacyFromManager(log *logger.Manager) *logPrivacy {
+	if log == nil || log.Config() == nil {
+		return newLogPrivacy("", "", false, nil)
+	}
+	cfg := log.Config()
+	return newLogPrivacy(cfg.AnonymizeIP, cfg.IPHashSalt, cfg.DropUserAgent, cfg.ExcludeQueryParams)
+}
+
+// ip 匿名化客户端IP
+func (p *logPrivacy) ip(clientIP string) string {
+	switch p.anonymizeIP {
+	case IPAnonymizeHash:
+		mac := hmac.New(sha256.New, p.ipHashSalt)
+		mac.Write([]byte(clientIP))
+		return hex.EncodeToString(mac.Sum(nil))[:16]
+	case IPAnonymizeTruncate:
+		ip := net.ParseIP(clientIP)
+		if ip == nil {
+			return ""
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4.Mask(net.CIDRMask(24, 32)).String()
+		}
+		return ip.Mask(net.CIDRMask(48, 128)).String()
+	default:
+		return clientIP
+	}
+}
+
+// userAgent 处理User-Agent，配置为不记录时返回空
+func (p *logPrivacy) userAgent(ua string) string {
+	if p.dropUserAgent {
+		return ""
+	}
+	return ua
+}
+
+// uri 移除排除的查询参数后拼接路径
+func (p *logPrivacy) uri(path, rawQuery string) string {
+	if rawQuery == "" {
+		return path
+	}
+	if len(p.excludeQuery) > 0 {
+		query, err := url.ParseQuery(rawQuery)
+		if err != nil {
+			// 无法解析时不记录查询参数，避免泄露
+			return path
+		}
+		for param := range query {
+			if p.excludeQuery[param] {
+				query.Del(param)
+			}
+		}
+		rawQuery = query.Encode()
+		if rawQuery == "" {
+			return path
+		}
+	}
+	return path + "?" + rawQuery
+}
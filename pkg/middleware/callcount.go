@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/logger"
+	"github.com/hwh/hwhkit-go/pkg/metrics"
+)
+
+// callCountBuckets 每个请求调用次数直方图的桶
+var callCountBuckets = []float64{0, 1, 2, 5, 10, 20, 50, 100, 200, 500}
+
+var (
+	requestDBQueries = metrics.Default.Histogram("hwhkit_http_request_db_queries",
+		"Number of SQL statements executed per request", callCountBuckets, "method", "route")
+	requestCacheOps = metrics.Default.Histogram("hwhkit_http_request_cache_ops",
+		"Number of cache commands executed per request", callCountBuckets, "method", "route")
+)
+
+// CallCountConfig 每请求数据库、缓存调用计数配置
+type CallCountConfig struct {
+	// Logger 超出阈值时记录警告，为 nil 时只采集指标
+	Logger *logger.Manager
+	// DBQueryThreshold 单个请求的SQL语句数超过该值时记录警告，0为不警告
+	DBQueryThreshold int
+	// CacheOpThreshold 单个请求的缓存命令数超过该值时记录警告，0为不警告
+	CacheOpThreshold int
+}
+
+// CallCount 每请求数据库、缓存调用计数中间件，用于尽早发现N+1查询
+// 计数来自 database.Manager.EnableTiming、cache.Manager.EnableTiming 写入请求上下文的耗时分段，
+// 数据库、缓存调用需通过 WithContext(c.Request.Context()) 传入请求上下文；与 ServerTiming 共用同一份记录
+// 按路由模板输出 hwhkit_http_request_db_queries、hwhkit_http_request_cache_ops 直方图
+func CallCount(config *CallCountConfig) gin.HandlerFunc {
+	if config == nil {
+		config = &CallCountConfig{}
+	}
+
+	return func(c *gin.Context) {
+		timings := GetTimings(c)
+		if timings == nil {
+			timings = metrics.NewTimings()
+			c.Request = c.Request.WithContext(metrics.WithTimings(c.Request.Context(), timings))
+		}
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		queries := timings.Count(metrics.TimingDB)
+		cacheOps := timings.Count(metrics.TimingCache)
+		requestDBQueries.Observe(float64(queries), c.Request.Method, route)
+		requestCacheOps.Observe(float64(cacheOps), c.Request.Method, route)
+
+		if config.Logger == nil {
+			return
+		}
+		dbExceeded := config.DBQueryThreshold > 0 && queries > config.DBQueryThreshold
+		cacheExceeded := config.CacheOpThreshold > 0 && cacheOps > config.CacheOpThreshold
+		if dbExceeded || cacheExceeded {
+			config.Logger.WithFields(withCorrelation(c, logger.Fields{
+				"method":      c.Request.Method,
+				"route":       route,
+				"db_queries":  queries,
+				"cache_ops":   cacheOps,
+				"db_limit":    config.DBQueryThreshold,
+				"cache_limit": config.CacheOpThreshold,
+			})).Warn("Request exceeded call count threshold, possible N+1 pattern")
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChaosRule 故障注入规则，比例取值 0-1
+type ChaosRule struct {
+	Path        string        // 生效路径，支持 * 后缀通配，为空时匹配所有路径
+	LatencyRate float64       // 注入延迟的请求比例
+	Latency     time.Duration // 注入的延迟
+	ErrorRate   float64       // 返回错误的请求比例
+	ErrorStatus int           // 错误状态码，默认503
+	DropRate    float64       // 直接断开连接的请求比例
+}
+
+// ChaosConfig 故障注入中间件配置
+type ChaosConfig struct {
+	Enabled   bool           // 是否启用，默认关闭
+	Rules     []ChaosRule    // 按顺序匹配，使用第一条匹配的规则
+	SkipPaths []string       // 不注入故障的路径，如健康检查
+	Random    func() float64 // [0,1) 随机数来源，默认使用 math/rand
+}
+
+// ChaosHeader 标记被注入故障的响应头
+const ChaosHeader = "X-Chaos-Injected"
+
+// Chaos 故障注入中间件，用于验证客户端重试和熔断行为
+// 按规则对一定比例的请求注入延迟、返回错误或直接断开连接，仅应在非生产环境启用
+func Chaos(config *ChaosConfig) gin.HandlerFunc {
+	if config == nil || !config.Enabled || len(config.Rules) == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	random := config.Random
+	if random == nil {
+		var mu sync.Mutex
+		rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+		random = func() float64 {
+			mu.Lock()
+			defer mu.Unlock()
+			return rnd.Float64()
+		}
+	}
+
+	return func(c *gin.Context) {
+		if shouldSkipPath(c.Request.URL.Path, config.SkipPaths) {
+			c.Next()
+			return
+		}
+
+		rule := matchChaosRule(config.Rules, c.Request.URL.Path)
+		if rule == nil {
+			c.Next()
+			return
+		}
+
+		if rule.DropRate > 0 && random() < rule.DropRate {
+			if dropConnection(c) {
+				return
+			}
+		}
+
+		if rule.Latency > 0 && rule.LatencyRate > 0 && random() < rule.LatencyRate {
+			c.Header(ChaosHeader, "latency")
+			timer := time.NewTimer(rule.Latency)
+			select {
+			case <-timer.C:
+			case <-c.Request.Context().Done():
+				timer.Stop()
+				c.Abort()
+				return
+			}
+		}
+
+		if rule.ErrorRate > 0 && random() < rule.ErrorRate {
+			status := rule.ErrorStatus
+			if status == 0 {
+				status = http.StatusServiceUnavailable
+			}
+			c.Header(ChaosHeader, "error")
+			c.AbortWithStatusJSON(status, gin.H{
+				"error": "chaos: injected fault",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// matchChaosRule 查找第一条匹配路径的规则
+func matchChaosRule(rules []ChaosRule, path string) *ChaosRule {
+	for i := range rules {
+		if rules[i].Path == "" || matchPath(path, rules[i].Path) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// dropConnection 不返回响应直接关闭连接，不支持劫持时返回false
+func dropConnection(c *gin.Context) (dropped bool) {
+	// 底层 ResponseWriter 不支持劫持时 gin 会直接panic（如 httptest.ResponseRecorder）
+	defer func() {
+		if recover() != nil {
+			dropped = false
+		}
+	}()
+
+	conn, _, err := c.Writer.Hijack()
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	c.Abort()
+	return true
+}
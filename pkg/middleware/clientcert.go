@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientCertInfo 已校验的客户端证书信息（双向TLS）
+type ClientCertInfo struct {
+	CommonName          string            `json:"common_name"`
+	Organization        []string          `json:"organization,omitempty"`
+	OrganizationalUnits []string          `json:"organizational_units,omitempty"`
+	DNSNames            []string          `json:"dns_names,omitempty"`
+	EmailAddresses      []string          `json:"email_addresses,omitempty"`
+	URIs                []string          `json:"uris,omitempty"`
+	SerialNumber        string            `json:"serial_number"`
+	Issuer              string            `json:"issuer"`
+	Fingerprint         string            `json:"fingerprint"` // 证书DER的SHA-256摘要（十六进制）
+	Certificate         *x509.Certificate `json:"-"`
+}
+
+// newClientCertInfo 提取证书主题和SAN
+func newClientCertInfo(cert *x509.Certificate) *ClientCertInfo {
+	sum := sha256.Sum256(cert.Raw)
+	info := &ClientCertInfo{
+		CommonName:          cert.Subject.CommonName,
+		Organization:        cert.Subject.Organization,
+		OrganizationalUnits: cert.Subject.OrganizationalUnit,
+		DNSNames:            cert.DNSNames,
+		EmailAddresses:      cert.EmailAddresses,
+		SerialNumber:        cert.SerialNumber.String(),
+		Issuer:              cert.Issuer.String(),
+		Fingerprint:         hex.EncodeToString(sum[:]),
+		Certificate:         cert,
+	}
+	for _, uri := range cert.URIs {
+		info.URIs = append(info.URIs, uri.String())
+	}
+	return info
+}
+
+// verifiedClientCert 获取请求中已通过服务器CA校验的客户端证书，未使用TLS、未提供或未校验时返回 nil
+// 只请求不校验（request 模式）的证书可被任意伪造，不作为身份依据
+func verifiedClientCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return r.TLS.PeerCertificates[0]
+}
+
+// ClientCert 提取已校验的客户端证书信息写入上下文（键 client_cert），没有证书的请求直接放行
+// 服务器启用客户端证书校验（SERVER_TLS_CLIENT_AUTH 为 verify_if_given 或 require）时自动注册
+func ClientCert() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := c.Get("client_cert"); !exists {
+			if cert := verifiedClientCert(c.Request); cert != nil {
+				c.Set("client_cert", newClientCertInfo(cert))
+			}
+		}
+		c.Next()
+	}
+}
+
+// GetClientCert 从上下文获取客户端证书信息
+func GetClientCert(c *gin.Context) (*ClientCertInfo, bool) {
+	if value, exists := c.Get("client_cert"); exists {
+		if info, ok := value.(*ClientCertInfo); ok {
+			return info, true
+		}
+	}
+	return nil, false
+}
+
+// ClientCertConfig 客户端证书授权配置，各列表都为空时接受任意已校验的证书
+type ClientCertConfig struct {
+	CommonNames         []string // 允许的证书CN
+	OrganizationalUnits []string // 允许的证书OU
+	DNSNames            []string // 允许的DNS SAN
+}
+
+// allows 证书是否匹配任一允许的CN、OU或DNS SAN
+func (cfg *ClientCertConfig) allows(info *ClientCertInfo) bool {
+	if len(cfg.CommonNames) == 0 && len(cfg.OrganizationalUnits) == 0 && len(cfg.DNSNames) == 0 {
+		return true
+	}
+	if containsAny(cfg.CommonNames, info.CommonName) ||
+		containsAny(cfg.OrganizationalUnits, info.OrganizationalUnits...) ||
+		containsAny(cfg.DNSNames, info.DNSNames...) {
+		return true
+	}
+	return false
+}
+
+// RequireClientCert 要求请求使用已校验的客户端证书，用于内部服务间接口
+// 没有证书返回401，证书的CN、OU和DNS SAN都不在允许列表中返回403；证书信息写入上下文（键 client_cert）
+func RequireClientCert(config *ClientCertConfig) gin.HandlerFunc {
+	if config == nil {
+		config = &ClientCertConfig{}
+	}
+	return func(c *gin.Context) {
+		info, ok := GetClientCert(c)
+		if !ok {
+			cert := verifiedClientCert(c.Request)
+			if cert == nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"error":   "Unauthorized",
+					"message": "a verified client certificate is required",
+				})
+				return
+			}
+			info = newClientCertInfo(cert)
+			c.Set("client_cert", info)
+		}
+
+		if !config.allows(info) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "client certificate " + info.CommonName + " is not allowed",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// containsAny 列表是否包含任一值
+func containsAny(list []string, values ...string) bool {
+	for _, item := range list {
+		for _, value := range values {
+			if item == value {
+				return true
+			}
+		}
+	}
+	return false
+}
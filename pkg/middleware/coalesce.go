@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
+)
+
+// CoalescedHeader 标记响应由合并请求共享的响应头
+const CoalescedHeader = "X-Coalesced"
+
+// CoalesceConfig 请求合并中间件配置
+type CoalesceConfig struct {
+	KeyFunc     func(*gin.Context) string // 生成合并键，默认使用路径、排序后的查询参数和 VaryHeaders
+	VaryHeaders []string                  // 参与合并键的请求头，为空时使用默认值（包含认证相关请求头），避免不同用户共享响应
+	SkipPaths   []string                  // 不合并的路径
+	MaxBodySize int                       // 可共享的最大响应体（字节），默认1MB，超出时等待者各自执行
+}
+
+// DefaultCoalesceConfig 默认请求合并配置
+func DefaultCoalesceConfig() *CoalesceConfig {
+	return &CoalesceConfig{
+		VaryHeaders: []string{"Authorization", "Cookie", "Accept", "Accept-Encoding", "Accept-Language"},
+		MaxBodySize: 1 << 20,
+	}
+}
+
+// coalescedResponse 共享的响应
+type coalescedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+	shared bool // 响应过大时为false，等待者需自行执行
+}
+
+// Coalesce 请求合并中间件，将并发的相同GET请求合并为一次执行，结果共享给所有等待者
+// 适用于开销大的读接口，防止缓存失效时的惊群效应
+func Coalesce(config *CoalesceConfig) gin.HandlerFunc {
+	defaults := DefaultCoalesceConfig()
+	if config == nil {
+		config = defaults
+	}
+	varyHeaders := config.VaryHeaders
+	if len(varyHeaders) == 0 {
+		varyHeaders = defaults.VaryHeaders
+	}
+	keyFunc := config.KeyFunc
+	if keyFunc == nil {
+		keyFunc = func(c *gin.Context) string {
+			return coalesceKey(c, varyHeaders)
+		}
+	}
+	maxBodySize := config.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = 1 << 20
+	}
+
+	var group singleflight.Group
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet || shouldSkipPath(c.Request.URL.Path, config.SkipPaths) {
+			c.Next()
+			return
+		}
+
+		executed := false
+		result, _, _ := group.Do(keyFunc(c), func() (interface{}, error) {
+			executed = true
+			writer := &coalesceWriter{ResponseWriter: c.Writer, limit: maxBodySize}
+			c.Writer = writer
+			c.Next()
+			c.Writer = writer.ResponseWriter
+
+			return &coalescedResponse{
+				status: writer.Status(),
+				header: writer.Header().Clone(),
+				body:   writer.body.Bytes(),
+				shared: !writer.overflow,
+			}, nil
+		})
+		if executed {
+			return
+		}
+
+		resp := result.(*coalescedResponse)
+		if !resp.shared {
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		for key, values := range resp.header {
+			header[key] = values
+		}
+		header.Set(CoalescedHeader, "true")
+		c.Data(resp.status, resp.header.Get("Content-Type"), resp.body)
+		c.Abort()
+	}
+}
+
+// coalesceKey 生成默认的合并键
+func coalesceKey(c *gin.Context, varyHeaders []string) string {
+	var b strings.Builder
+	b.WriteString(c.Request.URL.Path)
+
+	query := c.Request.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			b.WriteString("&" + key + "=" + value)
+		}
+	}
+
+	for _, name := range varyHeaders {
+		b.WriteString("\n" + name + ":" + c.GetHeader(name))
+	}
+	return b.String()
+}
+
+// coalesceWriter 在写入响应的同时缓存响应体
+type coalesceWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+// Write 写入响应并缓存
+func (w *coalesceWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString 写入字符串响应并缓存
+func (w *coalesceWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// capture 缓存响应体，超过上限后停止缓存
+func (w *coalesceWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(data) > w.limit {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(data)
+}
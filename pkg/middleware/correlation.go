@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/logger"
+	"github.com/hwh/hwhkit-go/pkg/metrics"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 关联ID相关请求/响应头
+const (
+	RequestIDHeader   = "X-Request-ID"
+	TraceIDHeader     = "X-Trace-Id"
+	TraceparentHeader = "traceparent" // W3C Trace Context
+)
+
+// correlationIDKey 上下文中保存关联ID的键，与响应体中的 request_id 保持一致
+const correlationIDKey = "request_id"
+
+var (
+	// traceparentPattern W3C traceparent 格式：version-traceid-parentid-flags
+	traceparentPattern = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$`)
+	// requestIDPattern 允许透传的请求ID格式，防止日志注入
+	requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+)
+
+// CorrelationConfig 关联ID中间件配置
+type CorrelationConfig struct {
+	Tracing       bool // 是否启用追踪：启用时使用 traceparent 中的追踪ID，否则使用请求ID
+	TrustIncoming bool // 是否信任上游传入的 traceparent / X-Request-ID
+}
+
+// Correlation 关联ID中间件，应作为第一个中间件注册
+// 为每个请求确定唯一的关联ID（启用追踪时为追踪ID，否则为请求ID），写入上下文、请求的 context.Context 和
+// X-Trace-Id / X-Request-ID 响应头，日志中间件和 HTTPMetrics 使用同一ID，便于在日志、响应和指标之间关联同一请求
+func Correlation(config *CorrelationConfig) gin.HandlerFunc {
+	if config == nil {
+		config = &CorrelationConfig{TrustIncoming: true}
+	}
+
+	return func(c *gin.Context) {
+		// 重复注册时复用已确定的关联ID
+		if GetCorrelationID(c) != "" {
+			c.Next()
+			return
+		}
+
+		id := correlationID(c, config)
+		c.Set(correlationIDKey, id)
+		ctx := logger.ContextWithTraceID(c.Request.Context(), id)
+		c.Request = c.Request.WithContext(logger.ContextWithRequestID(ctx, id))
+		c.Header(TraceIDHeader, id)
+		c.Header(RequestIDHeader, id)
+
+		c.Next()
+	}
+}
+
+// correlationID 确定请求的关联ID
+func correlationID(c *gin.Context, config *CorrelationConfig) string {
+	// Tracing 中间件已开启span时与span使用同一追踪ID
+	if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+		return span.TraceID().String()
+	}
+	if config.TrustIncoming {
+		if config.Tracing {
+			if match := traceparentPattern.FindStringSubmatch(c.GetHeader(TraceparentHeader)); match != nil && strings.Trim(match[1], "0") != "" {
+				return match[1]
+			}
+		}
+		if id := c.GetHeader(RequestIDHeader); requestIDPattern.MatchString(id) {
+			return id
+		}
+	}
+	return NewCorrelationID()
+}
+
+// NewCorrelationID 生成新的关联ID，格式与W3C追踪ID相同（32位十六进制）
+func NewCorrelationID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// GetCorrelationID 从上下文获取关联ID
+func GetCorrelationID(c *gin.Context) string {
+	return c.GetString(correlationIDKey)
+}
+
+// httpRequestDuration HTTP请求耗时直方图，exemplar 中带有关联ID
+var httpRequestDuration = metrics.Default.Histogram("hwhkit_http_request_duration_seconds",
+	"HTTP request latency in seconds", nil, "method", "route", "status")
+
+// HTTPMetrics HTTP请求指标中间件，按方法、路由模板和状态码记录耗时，并以 trace_id 作为 exemplar
+func HTTPMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched" // 未匹配路由时不使用原始路径，避免标签基数膨胀
+		}
+
+		var exemplar map[string]string
+		if id := GetCorrelationID(c); id != "" {
+			exemplar = map[string]string{logger.TraceIDField: id}
+		}
+		httpRequestDuration.ObserveWithExemplar(time.Since(start).Seconds(), exemplar,
+			c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+	}
+}
+
+// withCorrelation 为日志字段添加关联ID和请求ID，存在span时同时添加 span_id
+func withCorrelation(c *gin.Context, fields logger.Fields) logger.Fields {
+	if id := GetCorrelationID(c); id != "" {
+		fields[logger.TraceIDField] = id
+		fields[logger.RequestIDField] = id
+	}
+	if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+		fields[logger.SpanIDField] = span.SpanID().String()
+	}
+	return fields
+}
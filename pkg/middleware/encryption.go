@@ -0,0 +1,230 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/utils"
+)
+
+// EncryptedKeyHeader 客户端用服务端公钥包装的字段加密密钥，格式见 utils.WrapKey
+const EncryptedKeyHeader = "X-Encrypted-Key"
+
+// fieldKeyContextKey 上下文中保存字段加密密钥的键
+const fieldKeyContextKey = "field_encryption_key"
+
+// FieldEncryptionConfig 字段加密中间件配置
+type FieldEncryptionConfig struct {
+	Keys           *utils.KeyRing
+	RequestFields  []string                        // 请求体中加密的字段，点号分隔嵌套字段，如 card.number
+	ResponseFields []string                        // 响应中需要加密的字段，如 data.card_number
+	Required       bool                            // 缺少 X-Encrypted-Key 时拒绝请求，否则按明文处理
+	MaxBodySize    int64                           // 请求体大小上限，默认1MB
+	ErrorHandler   func(c *gin.Context, err error) // 密钥或密文无效时的处理函数，默认返回400
+}
+
+// FieldEncryption 字段级混合加密中间件
+// 客户端从公钥分发接口获取当前公钥，为每个请求生成 AES-256 密钥（utils.GenerateFieldKey），用公钥包装后放入 X-Encrypted-Key，
+// 敏感字段的值先序列化为JSON再用 utils.EncryptField 以配置中的字段路径（如 card.number）为附加认证数据加密；中间件解密后将明文JSON写回请求体，处理函数按普通请求绑定
+// 响应中 ResponseFields 指定的字段使用同一密钥和各自的字段路径加密，客户端用自己生成的密钥解密
+func FieldEncryption(config *FieldEncryptionConfig) gin.HandlerFunc {
+	maxBodySize := config.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = 1024 * 1024
+	}
+	errorHandler := config.ErrorHandler
+	if errorHandler == nil {
+		errorHandler = func(c *gin.Context, err error) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Bad Request",
+				"message": err.Error(),
+			})
+			c.Abort()
+		}
+	}
+
+	return func(c *gin.Context) {
+		wrapped := c.GetHeader(EncryptedKeyHeader)
+		if wrapped == "" {
+			if config.Required {
+				errorHandler(c, fmt.Errorf("missing %s header", EncryptedKeyHeader))
+				return
+			}
+			c.Next()
+			return
+		}
+
+		fieldKey, err := config.Keys.UnwrapKey(wrapped)
+		if err != nil {
+			errorHandler(c, fmt.Errorf("invalid %s header: %w", EncryptedKeyHeader, err))
+			return
+		}
+		c.Set(fieldKeyContextKey, fieldKey)
+
+		if len(config.RequestFields) > 0 && c.Request.Body != nil {
+			if err := decryptRequestFields(c, fieldKey, config.RequestFields, maxBodySize); err != nil {
+				errorHandler(c, err)
+				return
+			}
+		}
+
+		if len(config.ResponseFields) == 0 {
+			c.Next()
+			return
+		}
+
+		writer := &encryptionWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		body := writer.body.Bytes()
+		if len(body) == 0 {
+			return
+		}
+		if strings.Contains(writer.Header().Get("Content-Type"), "json") {
+			if body, err = encryptResponseFields(body, fieldKey, config.ResponseFields); err != nil {
+				// 加密失败时不能返回明文
+				c.Error(err)
+				writer.Header().Del("Content-Length")
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Internal Server Error",
+					"message": "Failed to encrypt response",
+				})
+				return
+			}
+			writer.Header().Del("Content-Length")
+		}
+		c.Writer.Write(body)
+	}
+}
+
+// GetFieldEncryptionKey 获取当前请求的字段加密密钥，处理函数可用于加密 ResponseFields 以外的数据
+func GetFieldEncryptionKey(c *gin.Context) ([]byte, bool) {
+	if value, exists := c.Get(fieldKeyContextKey); exists {
+		if key, ok := value.([]byte); ok {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// decryptRequestFields 解密请求体中的加密字段并替换请求体，缺少的字段跳过，未加密（非字符串）的字段视为错误
+func decryptRequestFields(c *gin.Context, fieldKey []byte, fields []string, maxBodySize int64) error {
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBodySize+1))
+	c.Request.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	if int64(len(data)) > maxBodySize {
+		return errors.New("request body too large")
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		c.Request.Body = io.NopCloser(bytes.NewReader(data))
+		return nil
+	}
+
+	var payload interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+
+	for _, field := range fields {
+		parent, name, value, ok := lookupField(payload, field)
+		if !ok {
+			continue
+		}
+		ciphertext, isString := value.(string)
+		if !isString {
+			return fmt.Errorf("field %s must be encrypted", field)
+		}
+		plaintext, err := utils.DecryptField(fieldKey, field, ciphertext)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt field %s: %w", field, err)
+		}
+		if !json.Valid(plaintext) {
+			return fmt.Errorf("decrypted field %s is not valid JSON", field)
+		}
+		parent[name] = json.RawMessage(plaintext)
+	}
+
+	if data, err = json.Marshal(payload); err != nil {
+		return fmt.Errorf("failed to encode request body: %w", err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(data))
+	c.Request.ContentLength = int64(len(data))
+	c.Request.Header.Del("Content-Length")
+	return nil
+}
+
+// encryptResponseFields 将响应中的指定字段序列化为JSON后加密，缺少的字段跳过
+func encryptResponseFields(body, fieldKey []byte, fields []string) ([]byte, error) {
+	var payload interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("invalid JSON response: %w", err)
+	}
+
+	for _, field := range fields {
+		parent, name, value, ok := lookupField(payload, field)
+		if !ok {
+			continue
+		}
+		plaintext, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, err := utils.EncryptField(fieldKey, field, plaintext)
+		if err != nil {
+			return nil, err
+		}
+		parent[name] = ciphertext
+	}
+	return json.Marshal(payload)
+}
+
+// lookupField 按点号分隔的路径查找JSON对象中的字段，返回所在对象和字段名
+func lookupField(payload interface{}, path string) (map[string]interface{}, string, interface{}, bool) {
+	parts := strings.Split(path, ".")
+	current := payload
+	for i, part := range parts {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, "", nil, false
+		}
+		value, exists := object[part]
+		if !exists {
+			return nil, "", nil, false
+		}
+		if i == len(parts)-1 {
+			return object, part, value, true
+		}
+		current = value
+	}
+	return nil, "", nil, false
+}
+
+// encryptionWriter 缓存响应体，加密指定字段后再写出
+type encryptionWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write 缓存响应
+func (w *encryptionWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+// WriteString 缓存字符串响应
+func (w *encryptionWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
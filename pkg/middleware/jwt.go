@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -17,6 +18,7 @@ type JWTConfig struct {
 	SkipPaths      []string      // 跳过验证的路径
 	ErrorHandler   func(*gin.Context, error) // 错误处理函数
 	SuccessHandler func(*gin.Context, *auth.Claims) // 成功处理函数
+	DeviceBinding  auth.DeviceBindingMode // 设备绑定校验模式，为空时使用认证管理器的配置
 }
 
 // DefaultJWTConfig 默认JWT配置
@@ -37,7 +39,8 @@ func DefaultJWTConfig(authManager *auth.Manager) *JWTConfig {
 			c.Set("user_id", claims.UserID)
 			c.Set("username", claims.Username)
 			c.Set("email", claims.Email)
-			c.Set("role", claims.Role)
+			c.Set("role", claims.PrimaryRole())
+			c.Set("roles", claims.Roles)
 			c.Set("claims", claims)
 		},
 	}
@@ -52,6 +55,7 @@ func JWT(config *JWTConfig) gin.HandlerFunc {
 	if config.AuthManager == nil {
 		panic("JWT middleware requires an auth manager")
 	}
+	deviceBinding := jwtDeviceBinding(config)
 	
 	return func(c *gin.Context) {
 		// 检查是否跳过验证
@@ -73,6 +77,16 @@ func JWT(config *JWTConfig) gin.HandlerFunc {
 			config.ErrorHandler(c, fmt.Errorf("invalid token: %w", err))
 			return
 		}
+		// 刷新令牌只能用于换取新令牌，不能作为访问令牌
+		if claims.IsRefresh() {
+			config.ErrorHandler(c, errors.New("invalid token: refresh token cannot be used for authentication"))
+			return
+		}
+				// 校验设备指纹，防止令牌在其他设备上重放
+		if err := auth.CheckDevice(deviceBinding, claims.Device, auth.DeviceFingerprint(c.Request)); err != nil {
+			config.ErrorHandler(c, fmt.Errorf("invalid token: %w", err))
+			return
+		}
 		
 		// 调用成功处理函数
 		config.SuccessHandler(c, claims)
@@ -101,6 +115,7 @@ func JWTOptional(config *JWTConfig) gin.HandlerFunc {
 	if config.AuthManager == nil {
 		panic("JWT middleware requires an auth manager")
 	}
+	deviceBinding := jwtDeviceBinding(config)
 	
 	return func(c *gin.Context) {
 		// 尝试提取令牌
@@ -113,8 +128,14 @@ func JWTOptional(config *JWTConfig) gin.HandlerFunc {
 		
 		// 验证令牌
 		claims, err := config.AuthManager.ValidateToken(token)
-		if err != nil {
-			// 令牌无效时继续执行
+		if err != nil || claims.IsRefresh() {
+			// 令牌无效或为刷新令牌时继续执行
+			c.Next()
+			return
+		}
+		
+		// 设备不匹配时视为未认证
+		if err := auth.CheckDevice(deviceBinding, claims.Device, auth.DeviceFingerprint(c.Request)); err != nil {
 			c.Next()
 			return
 		}
@@ -126,6 +147,14 @@ func JWTOptional(config *JWTConfig) gin.HandlerFunc {
 	}
 }
 
+// jwtDeviceBinding 获取中间件使用的设备绑定模式
+func jwtDeviceBinding(config *JWTConfig) auth.DeviceBindingMode {
+	if config.DeviceBinding != "" {
+		return config.DeviceBinding
+	}
+	return config.AuthManager.DeviceBinding()
+}
+
 // RequireRole 创建角色验证中间件
 func RequireRole(authManager *auth.Manager, roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -150,20 +179,11 @@ func RequireRole(authManager *auth.Manager, roles ...string) gin.HandlerFunc {
 			return
 		}
 		
-		// 检查角色权限
-		userRole := claims.Role
-		hasRole := false
-		for _, role := range roles {
-			if userRole == role {
-				hasRole = true
-				break
-			}
-		}
-		
-		if !hasRole {
+		// 检查角色权限，拥有任意一个角色即可
+		if !auth.RecordRBACCheck(auth.RBACCheckRole, claims.HasAnyRole(roles...)) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error":   "Forbidden",
-				"message": fmt.Sprintf("Required role: %v, got: %s", roles, userRole),
+				"message": fmt.Sprintf("Required role: %v, got: %v", roles, claims.Roles),
 			})
 			c.Abort()
 			return
@@ -201,18 +221,13 @@ func RequireAllRoles(authManager *auth.Manager, roles ...string) gin.HandlerFunc
 			return
 		}
 		
-		// 这里简化实现，假设用户只有一个角色
-		// 在实际项目中，可能需要支持多角色
-		userRole := claims.Role
-		for _, role := range roles {
-			if userRole != role {
-				c.JSON(http.StatusForbidden, gin.H{
-					"error":   "Forbidden",
-					"message": fmt.Sprintf("Required all roles: %v, got: %s", roles, userRole),
-				})
-				c.Abort()
-				return
-			}
+		if !auth.RecordRBACCheck(auth.RBACCheckRole, claims.HasAllRoles(roles...)) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": fmt.Sprintf("Required all roles: %v, got: %v", roles, claims.Roles),
+			})
+			c.Abort()
+			return
 		}
 		
 		c.Next()
@@ -291,9 +306,19 @@ func matchPath(path, pattern string) bool {
 }
 
 // GetUserID 从上下文获取用户ID
-func GetUserID(c *gin.Context) (int64, bool) {
+func GetUserID(c *gin.Context) (string, bool) {
 	if userID, exists := c.Get("user_id"); exists {
-		if id, ok := userID.(int64); ok {
+		if id, ok := userID.(string); ok && id != "" {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// GetUserIDInt64 从上下文获取数字用户ID，用于使用数字主键的应用
+func GetUserIDInt64(c *gin.Context) (int64, bool) {
+	if claims, ok := GetClaims(c); ok {
+		if id, err := claims.UserIDInt64(); err == nil {
 			return id, true
 		}
 	}
@@ -310,7 +335,7 @@ func GetUsername(c *gin.Context) (string, bool) {
 	return "", false
 }
 
-// GetUserRole 从上下文获取用户角色
+// GetUserRole 从上下文获取用户主角色
 func GetUserRole(c *gin.Context) (string, bool) {
 	if role, exists := c.Get("role"); exists {
 		if r, ok := role.(string); ok {
@@ -320,6 +345,16 @@ func GetUserRole(c *gin.Context) (string, bool) {
 	return "", false
 }
 
+// GetUserRoles 从上下文获取用户所有角色
+func GetUserRoles(c *gin.Context) ([]string, bool) {
+	if roles, exists := c.Get("roles"); exists {
+		if r, ok := roles.([]string); ok {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
 // GetClaims 从上下文获取完整的JWT声明
 func GetClaims(c *gin.Context) (*auth.Claims, bool) {
 	if claims, exists := c.Get("claims"); exists {
@@ -17,17 +17,30 @@ type LoggerConfig struct {
 	LogRequestBody bool            // 是否记录请求体
 	LogResponseBody bool           // 是否记录响应体
 	MaxBodySize    int64           // 最大记录的请求/响应体大小
+	
+	// 隐私设置（GDPR）
+	AnonymizeIP        string   // 客户端IP匿名化方式：IPAnonymizeHash、IPAnonymizeTruncate，为空时不处理
+	IPHashSalt         string   // IP哈希盐值
+	DropUserAgent      bool     // 不记录User-Agent
+	ExcludeQueryParams []string // 从日志中移除的查询参数
 }
 
-// DefaultLoggerConfig 默认日志配置
+// DefaultLoggerConfig 默认日志配置，隐私设置取自日志管理器的配置
 func DefaultLoggerConfig(log *logger.Manager) *LoggerConfig {
-	return &LoggerConfig{
+	cfg := &LoggerConfig{
 		Logger:          log,
 		SkipPaths:       []string{"/health", "/metrics"},
 		LogRequestBody:  false,
 		LogResponseBody: false,
 		MaxBodySize:     1024 * 1024, // 1MB
 	}
+	if log != nil && log.Config() != nil {
+		cfg.AnonymizeIP = log.Config().AnonymizeIP
+		cfg.IPHashSalt = log.Config().IPHashSalt
+		cfg.DropUserAgent = log.Config().DropUserAgent
+		cfg.ExcludeQueryParams = log.Config().ExcludeQueryParams
+	}
+	return cfg
 }
 
 // responseWriter 自定义响应写入器，用于捕获响应数据
@@ -62,6 +75,7 @@ func Logger(config ...*LoggerConfig) gin.HandlerFunc {
 			MaxBodySize:     1024 * 1024,
 		}
 	}
+	privacy := newLogPrivacy(cfg.AnonymizeIP, cfg.IPHashSalt, cfg.DropUserAgent, cfg.ExcludeQueryParams)
 
 	return func(c *gin.Context) {
 		// 检查是否跳过记录
@@ -107,24 +121,23 @@ func Logger(config ...*LoggerConfig) gin.HandlerFunc {
 		// 计算处理时间
 		latency := time.Since(start)
 		statusCode := c.Writer.Status()
-		clientIP := c.ClientIP()
+		clientIP := privacy.ip(c.ClientIP())
 		method := c.Request.Method
-		userAgent := c.Request.UserAgent()
+		userAgent := privacy.userAgent(c.Request.UserAgent())
 
-		// 构建完整的URL
-		fullPath := path
-		if rawQuery != "" {
-			fullPath = path + "?" + rawQuery
-		}
+		// 构建完整的URL，移除排除的查询参数
+		fullPath := privacy.uri(path, rawQuery)
 
 		// 构建日志字段
 		fields := logger.Fields{
-			"ip":         clientIP,
-			"method":     method,
-			"path":       fullPath,
-			"status":     statusCode,
-			"latency":    latency.String(),
-			"user_agent": userAgent,
+			"ip":      clientIP,
+			"method":  method,
+			"path":    fullPath,
+			"status":  statusCode,
+			"latency": latency.String(),
+		}
+		if userAgent != "" {
+			fields["user_agent"] = userAgent
 		}
 
 		// 添加请求体字段
@@ -145,6 +158,9 @@ func Logger(config ...*LoggerConfig) gin.HandlerFunc {
 			fields["username"] = username
 		}
 
+		// 添加关联ID
+		fields = withCorrelation(c, fields)
+
 		// 添加错误信息
 		if len(c.Errors) > 0 {
 			fields["errors"] = c.Errors.String()
@@ -183,17 +199,21 @@ func LoggerWithConfig(config *LoggerConfig) gin.HandlerFunc {
 
 // RequestLogger 专门记录请求的中间件
 func RequestLogger(log *logger.Manager) gin.HandlerFunc {
+	privacy := logPrivacyFromManager(log)
 	return func(c *gin.Context) {
 		start := time.Now()
 		
 		// 记录请求开始
 		if log != nil {
-			log.WithFields(logger.Fields{
-				"ip":         c.ClientIP(),
-				"method":     c.Request.Method,
-				"path":       c.Request.URL.Path,
-				"user_agent": c.Request.UserAgent(),
-			}).Info("Request started")
+			fields := logger.Fields{
+				"ip":     privacy.ip(c.ClientIP()),
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+			}
+			if userAgent := privacy.userAgent(c.Request.UserAgent()); userAgent != "" {
+				fields["user_agent"] = userAgent
+			}
+			log.WithFields(withCorrelation(c, fields)).Info("Request started")
 		}
 		
 		c.Next()
@@ -201,31 +221,32 @@ func RequestLogger(log *logger.Manager) gin.HandlerFunc {
 		// 记录请求结束
 		latency := time.Since(start)
 		if log != nil {
-			log.WithFields(logger.Fields{
-				"ip":      c.ClientIP(),
+			log.WithFields(withCorrelation(c, logger.Fields{
+				"ip":      privacy.ip(c.ClientIP()),
 				"method":  c.Request.Method,
 				"path":    c.Request.URL.Path,
 				"status":  c.Writer.Status(),
 				"latency": latency.String(),
-			}).Info("Request completed")
+			})).Info("Request completed")
 		}
 	}
 }
 
 // ErrorLogger 错误记录中间件
 func ErrorLogger(log *logger.Manager) gin.HandlerFunc {
+	privacy := logPrivacyFromManager(log)
 	return func(c *gin.Context) {
 		c.Next()
 		
 		// 记录错误
 		if len(c.Errors) > 0 && log != nil {
 			for _, err := range c.Errors {
-				log.WithFields(logger.Fields{
-					"ip":     c.ClientIP(),
+				log.WithFields(withCorrelation(c, logger.Fields{
+					"ip":     privacy.ip(c.ClientIP()),
 					"method": c.Request.Method,
 					"path":   c.Request.URL.Path,
 					"type":   err.Type,
-				}).Error(err.Error())
+				})).Error(err.Error())
 			}
 		}
 	}
@@ -233,21 +254,22 @@ func ErrorLogger(log *logger.Manager) gin.HandlerFunc {
 
 // AccessLogger 访问日志中间件（简化版本）
 func AccessLogger(log *logger.Manager) gin.HandlerFunc {
+	privacy := logPrivacyFromManager(log)
 	return func(c *gin.Context) {
 		start := time.Now()
 		c.Next()
 		
 		if log != nil {
 			latency := time.Since(start)
-			log.WithFields(logger.Fields{
+			log.WithFields(withCorrelation(c, logger.Fields{
 				"timestamp": start.Format(time.RFC3339),
-				"client_ip": c.ClientIP(),
+				"client_ip": privacy.ip(c.ClientIP()),
 				"method":    c.Request.Method,
-				"uri":       c.Request.RequestURI,
+				"uri":       privacy.uri(c.Request.URL.Path, c.Request.URL.RawQuery),
 				"status":    c.Writer.Status(),
 				"latency":   latency.Nanoseconds(),
 				"size":      c.Writer.Size(),
-			}).Info("Access log")
+			})).Info("Access log")
 		}
 	}
 }
\ No newline at end of file
@@ -8,8 +8,9 @@ import (
 
 // MiddlewareManager 中间件管理器
 type MiddlewareManager struct {
-	authManager *auth.Manager
-	logger      *logger.Manager
+	authManager   *auth.Manager
+	logger        *logger.Manager
+	trustedHeader *TrustedHeaderConfig
 }
 
 // NewMiddlewareManager 创建中间件管理器
@@ -30,8 +31,26 @@ func (m *MiddlewareManager) CORS(config *CORSConfig) gin.HandlerFunc {
 	return CORSWithConfig(config)
 }
 
-// JWT JWT认证中间件
+// SetTrustedHeader 启用可信请求头认证，之后 JWT() 返回的中间件优先按网关注入的身份请求头认证，没有时回退到JWT校验
+func (m *MiddlewareManager) SetTrustedHeader(config *TrustedHeaderConfig) error {
+	if config == nil {
+		m.trustedHeader = nil
+		return nil
+	}
+	if _, err := newTrustedSource(config); err != nil {
+		return err
+	}
+	cfg := *config
+	cfg.AuthManager = m.authManager
+	m.trustedHeader = &cfg
+	return nil
+}
+
+// JWT JWT认证中间件，启用可信请求头认证时返回 TrustedHeader 中间件
 func (m *MiddlewareManager) JWT() gin.HandlerFunc {
+	if m.trustedHeader != nil {
+		return TrustedHeader(m.trustedHeader)
+	}
 	return JWTWithManager(m.authManager)
 }
 
@@ -71,9 +90,23 @@ func (m *MiddlewareManager) RateLimitByUser(rate, burst int) gin.HandlerFunc {
 	return RateLimitByUser(rate, burst)
 }
 
+// ResponseSchema 响应Schema校验中间件，仅在非release模式下生效
+func (m *MiddlewareManager) ResponseSchema(spec []byte) (gin.HandlerFunc, error) {
+	return ResponseSchemaValidator(&ResponseSchemaConfig{
+		Spec:   spec,
+		Logger: m.logger,
+	})
+}
+
+// RequestID 请求ID中间件
+func (m *MiddlewareManager) RequestID() gin.HandlerFunc {
+	return RequestID()
+}
+
 // Common 通用中间件组合
 func (m *MiddlewareManager) Common() []gin.HandlerFunc {
 	return []gin.HandlerFunc{
+		m.RequestID(),
 		m.DefaultCORS(),
 		m.Logger(),
 		m.ErrorLogger(),
@@ -105,6 +138,7 @@ func (m *MiddlewareManager) API() []gin.HandlerFunc {
 // PublicAPI 公共API路由中间件组合
 func (m *MiddlewareManager) PublicAPI() []gin.HandlerFunc {
 	return []gin.HandlerFunc{
+		m.RequestID(),
 		m.DefaultCORS(),
 		m.RequestLogger(),
 		m.RateLimit(50, 5), // 50 req/s, burst 5
@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/org"
+)
+
+// OrgHeader 指定当前组织的请求头，值为组织ID或标识
+const OrgHeader = "X-Org-ID"
+
+const (
+	orgContextKey           = "org"
+	orgMembershipContextKey = "org_membership"
+)
+
+// OrgContext 解析当前组织并校验用户是组织成员，需在JWT中间件之后使用
+// 优先使用路径参数 param（为空时不读取路径），其次使用请求头 X-Org-ID，值可以是组织ID或标识；
+// 未指定组织返回400，组织不存在返回404，不是成员返回403
+//
+//	orgs := router.Group("/orgs/:org", mw.JWT(), middleware.OrgContext(service, "org"))
+func OrgContext(service *org.Service, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := GetUserID(c)
+		if !ok {
+			abortUnauthenticated(c)
+			return
+		}
+
+		ref := ""
+		if param != "" {
+			ref = c.Param(param)
+		}
+		if ref == "" {
+			ref = c.GetHeader(OrgHeader)
+		}
+		if ref == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Bad Request",
+				"message": fmt.Sprintf("Organization is required (path or %s header)", OrgHeader),
+			})
+			c.Abort()
+			return
+		}
+
+		current, err := service.ResolveOrganization(c.Request.Context(), ref)
+		if err != nil {
+			abortOrgError(c, err)
+			return
+		}
+		membership, err := service.GetMembership(c.Request.Context(), current.ID, userID)
+		if err != nil {
+			abortOrgError(c, err)
+			return
+		}
+
+		c.Set(orgContextKey, current)
+		c.Set(orgMembershipContextKey, membership)
+		c.Next()
+	}
+}
+
+// RequireOrgPermission 创建组织内的资源权限中间件，需在 OrgContext 之后使用
+// 权限来自成员在当前组织中的角色，resource 同样支持 {参数名} 占位符
+func RequireOrgPermission(service *org.Service, resource, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		membership, ok := GetOrgMembership(c)
+		if !ok {
+			abortOrgError(c, org.ErrNotMember)
+			return
+		}
+
+		resolved, err := ResolveResource(c, resource)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Bad Request",
+				"message": err.Error(),
+			})
+			c.Abort()
+			return
+		}
+
+		if !service.MembershipHasPermission(membership, resolved, action) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": fmt.Sprintf("Permission denied: %s on %s", action, resolved),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireOrgRole 要求成员在当前组织中拥有任一角色，需在 OrgContext 之后使用
+func RequireOrgRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		membership, ok := GetOrgMembership(c)
+		if !ok {
+			abortOrgError(c, org.ErrNotMember)
+			return
+		}
+		if !membership.HasRole(roles...) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "Insufficient organization role",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetOrg 从上下文获取当前组织
+func GetOrg(c *gin.Context) (*org.Organization, bool) {
+	if value, exists := c.Get(orgContextKey); exists {
+		if current, ok := value.(*org.Organization); ok {
+			return current, true
+		}
+	}
+	return nil, false
+}
+
+// GetOrgMembership 从上下文获取当前用户在当前组织中的成员关系
+func GetOrgMembership(c *gin.Context) (*org.Membership, bool) {
+	if value, exists := c.Get(orgMembershipContextKey); exists {
+		if membership, ok := value.(*org.Membership); ok {
+			return membership, true
+		}
+	}
+	return nil, false
+}
+
+// abortOrgError 按组织错误中止请求，不是成员时与无权限一样返回403
+func abortOrgError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, org.ErrOrganizationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not Found",
+			"message": "Organization not found",
+		})
+	case errors.Is(err, org.ErrNotMember):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "Not a member of this organization",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": err.Error(),
+		})
+	}
+	c.Abort()
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrCrossOriginRequest 浏览器发起的跨站写请求
+var ErrCrossOriginRequest = errors.New("cross-origin request rejected")
+
+// SameOrigin 拒绝浏览器跨站提交的写请求（POST、PUT、PATCH、DELETE），用于基于Cookie会话的表单接口防止CSRF
+// allowedOrigins 为额外允许的来源（如 https://app.example.com），"*" 不生效；检查规则见 CheckOrigin
+func SameOrigin(allowedOrigins ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := CheckOrigin(c.Request, allowedOrigins); err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": err.Error(),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CheckOrigin 检查写请求是否来自同源页面
+// 优先使用 Origin，缺少时使用 Referer 的来源，与请求 Host 相同或在允许列表中时通过；
+// 两者都没有时按 Sec-Fetch-Site 判断，三者都没有的请求不是浏览器提交（如命令行或服务端调用），直接放行
+func CheckOrigin(req *http.Request, allowedOrigins []string) error {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		if referer, err := url.Parse(req.Header.Get("Referer")); err == nil && referer.Host != "" {
+			origin = referer.Scheme + "://" + referer.Host
+		}
+	}
+	if origin != "" {
+		for _, allowed := range allowedOrigins {
+			if allowed != "*" && strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+				return nil
+			}
+		}
+		// 沙箱iframe等不透明来源的 Origin 为 "null"，解析后没有 Host，不会匹配
+		if parsed, err := url.Parse(origin); err == nil && parsed.Host != "" && strings.EqualFold(parsed.Host, req.Host) {
+			return nil
+		}
+		return ErrCrossOriginRequest
+	}
+
+	switch req.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+		return nil
+	}
+	return ErrCrossOriginRequest
+}
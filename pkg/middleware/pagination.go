@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PaginationConfig 分页参数中间件配置
+type PaginationConfig struct {
+	DefaultPageSize int // 未指定 page_size 时的每页条数，默认10
+	MaxPageSize     int // 每页条数上限，默认100
+}
+
+// Pagination 解析 page、page_size 查询参数并写入上下文（键 page、page_size），
+// 无效值使用默认值，超过上限的 page_size 截断为上限；分页处理函数应在该中间件之后注册
+func Pagination(config ...*PaginationConfig) gin.HandlerFunc {
+	cfg := PaginationConfig{DefaultPageSize: 10, MaxPageSize: 100}
+	if len(config) > 0 && config[0] != nil {
+		if config[0].DefaultPageSize > 0 {
+			cfg.DefaultPageSize = config[0].DefaultPageSize
+		}
+		if config[0].MaxPageSize > 0 {
+			cfg.MaxPageSize = config[0].MaxPageSize
+		}
+	}
+
+	return func(c *gin.Context) {
+		page, _ := strconv.Atoi(c.Query("page"))
+		if page <= 0 {
+			page = 1
+		}
+		pageSize, _ := strconv.Atoi(c.Query("page_size"))
+		if pageSize <= 0 {
+			pageSize = cfg.DefaultPageSize
+		}
+		if pageSize > cfg.MaxPageSize {
+			pageSize = cfg.MaxPageSize
+		}
+
+		c.Set("page", page)
+		c.Set("page_size", pageSize)
+		c.Next()
+	}
+}
+
+// GetPagination 从上下文获取分页参数，未经过 Pagination 中间件时返回 false
+func GetPagination(c *gin.Context) (page, pageSize int, ok bool) {
+	page, pageSize = c.GetInt("page"), c.GetInt("page_size")
+	return page, pageSize, page > 0 && pageSize > 0
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/cache"
+)
+
+// 配额响应头
+const (
+	QuotaPlanHeader           = "X-Quota-Plan"
+	QuotaLimitDayHeader       = "X-Quota-Limit-Day"
+	QuotaRemainingDayHeader   = "X-Quota-Remaining-Day"
+	QuotaLimitMonthHeader     = "X-Quota-Limit-Month"
+	QuotaRemainingMonthHeader = "X-Quota-Remaining-Month"
+	QuotaResetHeader          = "X-Quota-Reset" // 最近一次配额重置的Unix时间戳
+	QuotaWarningHeader        = "X-Quota-Warning"
+)
+
+// quotaUsageContextKey 上下文中保存配额用量的键
+const quotaUsageContextKey = "quota_usage"
+
+// QuotaConfig 配额中间件配置
+type QuotaConfig struct {
+	Manager      *cache.QuotaManager
+	DefaultPlan  string                                // 默认套餐
+	PlanFunc     func(*gin.Context) string             // 获取当前请求的套餐，返回空时使用默认套餐
+	KeyFunc      func(*gin.Context) string             // 获取配额键，默认按用户，未登录时按IP
+	WarnOnly     bool                                  // 警告模式：超出配额时只添加警告响应头，不拒绝请求
+	SkipPaths    []string                              // 不计入配额的路径
+	ErrorHandler func(*gin.Context, *cache.QuotaUsage) // 超出配额时的处理函数
+}
+
+// Quota 配额中间件，按套餐跟踪每日/每月用量并输出 X-Quota-* 响应头
+// Redis不可用时放行请求，避免配额系统故障影响业务
+func Quota(config *QuotaConfig) gin.HandlerFunc {
+	keyFunc := config.KeyFunc
+	if keyFunc == nil {
+		keyFunc = func(c *gin.Context) string {
+			if userID, exists := GetUserID(c); exists {
+				return "user:" + userID
+			}
+			return "ip:" + c.ClientIP()
+		}
+	}
+	errorHandler := config.ErrorHandler
+	if errorHandler == nil {
+		errorHandler = func(c *gin.Context, usage *cache.QuotaUsage) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":   "Too Many Requests",
+				"message": fmt.Sprintf("Quota exceeded for plan %s", usage.Plan),
+			})
+			c.Abort()
+		}
+	}
+
+	return func(c *gin.Context) {
+		if shouldSkipPath(c.Request.URL.Path, config.SkipPaths) {
+			c.Next()
+			return
+		}
+
+		plan := config.DefaultPlan
+		if config.PlanFunc != nil {
+			if name := config.PlanFunc(c); name != "" {
+				plan = name
+			}
+		}
+
+		usage, err := config.Manager.Consume(keyFunc(c), plan)
+		if err != nil {
+			c.Error(err)
+			c.Next()
+			return
+		}
+		c.Set(quotaUsageContextKey, usage)
+		setQuotaHeaders(c, usage)
+
+		if usage.Exceeded() {
+			if !config.WarnOnly {
+				errorHandler(c, usage)
+				return
+			}
+			c.Header(QuotaWarningHeader, "quota exceeded")
+		}
+
+		c.Next()
+	}
+}
+
+// GetQuotaUsage 从上下文获取当前请求的配额用量
+func GetQuotaUsage(c *gin.Context) (*cache.QuotaUsage, bool) {
+	if value, exists := c.Get(quotaUsageContextKey); exists {
+		if usage, ok := value.(*cache.QuotaUsage); ok {
+			return usage, true
+		}
+	}
+	return nil, false
+}
+
+// setQuotaHeaders 输出配额响应头，不限制的项不输出
+func setQuotaHeaders(c *gin.Context, usage *cache.QuotaUsage) {
+	c.Header(QuotaPlanHeader, usage.Plan)
+	reset := usage.MonthlyReset
+	if usage.DailyLimit > 0 {
+		c.Header(QuotaLimitDayHeader, strconv.FormatInt(usage.DailyLimit, 10))
+		c.Header(QuotaRemainingDayHeader, strconv.FormatInt(usage.DailyRemaining(), 10))
+		reset = usage.DailyReset
+	}
+	if usage.MonthlyLimit > 0 {
+		c.Header(QuotaLimitMonthHeader, strconv.FormatInt(usage.MonthlyLimit, 10))
+		c.Header(QuotaRemainingMonthHeader, strconv.FormatInt(usage.MonthlyRemaining(), 10))
+		if usage.MonthlyRemaining() == 0 {
+			reset = usage.MonthlyReset
+		}
+	}
+	c.Header(QuotaResetHeader, strconv.FormatInt(reset.Unix(), 10))
+}
+
+// RegisterQuotaAdminRoutes 注册配额管理接口，应挂载在需要管理员权限的路由组下
+//
+//	GET    /quota/:key?plan=pro  查询用量
+//	DELETE /quota/:key           重置用量
+func RegisterQuotaAdminRoutes(router gin.IRouter, manager *cache.QuotaManager, defaultPlan string) {
+	router.GET("/quota/:key", func(c *gin.Context) {
+		usage, err := manager.Usage(c.Param("key"), c.DefaultQuery("plan", defaultPlan))
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, cache.ErrUnknownQuotaPlan) {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, usage)
+	})
+
+	router.DELETE("/quota/:key", func(c *gin.Context) {
+		if err := manager.Reset(c.Param("key")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "quota usage reset"})
+	})
+}
@@ -2,20 +2,53 @@ package middleware
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"runtime"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// 限流策略
+const (
+	StrategyTokenBucket   = "token_bucket"   // 令牌桶：每秒补充 Rate 个令牌，最多累积 Burst 个（默认）
+	StrategySlidingWindow = "sliding_window" // 滑动窗口日志：任意 Duration 内最多 Rate 个请求，内存随请求速率增长
+	StrategyFixedWindow   = "fixed_window"   // 固定窗口计数：每个 Duration 最多 Rate 个请求，按上一窗口加权平滑窗口边界的突发
+	StrategyGCRA          = "gcra"           // GCRA（漏桶）：严格按每秒 Rate 个的平均速率放行，允许 Burst 个突发
+)
+
+// 限流响应头
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset" // 配额完全恢复的Unix时间戳
+	RetryAfterHeader         = "Retry-After"       // 被限流时距下次允许请求的秒数
+)
+
+// rateLimitStatusContextKey 上下文中保存限流状态的键
+const rateLimitStatusContextKey = "rate_limit_status"
+
 // RateLimiterConfig 限流配置
 type RateLimiterConfig struct {
-	Rate     int           // 每秒允许的请求数
+	Rate     int           // 每秒允许的请求数（窗口策略为每个窗口允许的请求数）
 	Burst    int           // 突发请求数
 	Duration time.Duration // 限流窗口时间
+	Strategy string        // 限流策略，为空时使用令牌桶
 	KeyFunc  func(*gin.Context) string // 获取限流键的函数
 	ErrorHandler func(*gin.Context) // 限流错误处理函数
+	DisableHeaders bool // 不输出 X-RateLimit-* 和 Retry-After 响应头
+}
+
+// RateLimitStatus 限流键的当前状态
+type RateLimitStatus struct {
+	Allowed    bool          `json:"allowed"`     // 本次请求是否放行；查询状态时表示下一个请求是否会被放行
+	Limit      int           `json:"limit"`       // 配额上限（令牌桶容量、窗口请求数或突发数）
+	Remaining  int           `json:"remaining"`   // 剩余可用请求数
+	Reset      time.Time     `json:"reset"`       // 配额完全恢复的时间
+	RetryAfter time.Duration `json:"retry_after"` // 配额用尽时距下次允许请求的时间
 }
 
 // DefaultRateLimiterConfig 默认限流配置
@@ -58,10 +91,11 @@ func newTokenBucket(capacity, rate int) *tokenBucket {
 
 // consume 消费令牌
 func (tb *tokenBucket) consume() bool {
-	tb.mutex.Lock()
-	defer tb.mutex.Unlock()
-	
-	now := time.Now()
+	return tb.allow(time.Now()).Allowed
+}
+
+// refill 按经过的整秒数补充令牌
+func (tb *tokenBucket) refill(now time.Time) {
 	elapsed := now.Sub(tb.lastRefill)
 	
 	// 计算应该添加的令牌数
@@ -73,14 +107,50 @@ func (tb *tokenBucket) consume() bool {
 		}
 		tb.lastRefill = now
 	}
+}
+
+// allow 实现 keyLimiter
+func (tb *tokenBucket) allow(now time.Time) RateLimitStatus {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	
+	tb.refill(now)
 	
 	// 尝试消费一个令牌
-	if tb.tokens > 0 {
+	allowed := tb.tokens > 0
+	if allowed {
 		tb.tokens--
-		return true
 	}
+	return tb.statusLocked(now, allowed)
+}
+
+// status 实现 keyLimiter
+func (tb *tokenBucket) status(now time.Time) RateLimitStatus {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
 	
-	return false
+	tb.refill(now)
+	return tb.statusLocked(now, tb.tokens > 0)
+}
+
+// statusLocked 计算状态，调用方需持有锁；令牌每整秒补充一次
+func (tb *tokenBucket) statusLocked(now time.Time, allowed bool) RateLimitStatus {
+	status := RateLimitStatus{Allowed: allowed, Limit: tb.capacity, Remaining: tb.tokens, Reset: now}
+	if tb.rate > 0 && tb.tokens < tb.capacity {
+		seconds := (tb.capacity - tb.tokens + tb.rate - 1) / tb.rate
+		status.Reset = tb.lastRefill.Add(time.Duration(seconds) * time.Second)
+		if tb.tokens == 0 {
+			status.RetryAfter = tb.lastRefill.Add(time.Second).Sub(now)
+		}
+	}
+	return status
+}
+
+// idle 超过10分钟未补充令牌
+func (tb *tokenBucket) idle(now time.Time) bool {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	return now.Sub(tb.lastRefill) > 10*time.Minute
 }
 
 // slidingWindow 滑动窗口结构
@@ -101,14 +171,35 @@ func newSlidingWindow(limit int, window time.Duration) *slidingWindow {
 }
 
 // allow 检查是否允许请求
-func (sw *slidingWindow) allow() bool {
+func (sw *slidingWindow) allow(now time.Time) RateLimitStatus {
 	sw.mutex.Lock()
 	defer sw.mutex.Unlock()
 	
-	now := time.Now()
+	sw.prune(now)
+	
+	// 检查是否超过限制
+	if len(sw.requests) >= sw.limit {
+		return sw.statusLocked(now, false)
+	}
+	
+	// 添加当前请求
+	sw.requests = append(sw.requests, now)
+	return sw.statusLocked(now, true)
+}
+
+// status 实现 keyLimiter
+func (sw *slidingWindow) status(now time.Time) RateLimitStatus {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+	
+	sw.prune(now)
+	return sw.statusLocked(now, len(sw.requests) < sw.limit)
+}
+
+// prune 移除过期的请求
+func (sw *slidingWindow) prune(now time.Time) {
 	cutoff := now.Add(-sw.window)
 	
-	// 移除过期的请求
 	validRequests := make([]time.Time, 0, len(sw.requests))
 	for _, reqTime := range sw.requests {
 		if reqTime.After(cutoff) {
@@ -116,114 +207,379 @@ func (sw *slidingWindow) allow() bool {
 		}
 	}
 	sw.requests = validRequests
+}
+
+// statusLocked 计算状态，调用方需持有锁；最早的请求移出窗口后才能再次请求
+func (sw *slidingWindow) statusLocked(now time.Time, allowed bool) RateLimitStatus {
+	status := RateLimitStatus{Allowed: allowed, Limit: sw.limit, Remaining: sw.limit - len(sw.requests), Reset: now}
+	if status.Remaining < 0 {
+		status.Remaining = 0
+	}
+	if count := len(sw.requests); count > 0 {
+		status.Reset = sw.requests[count-1].Add(sw.window)
+		if status.Remaining == 0 {
+			status.RetryAfter = sw.requests[count-sw.limit].Add(sw.window).Sub(now)
+		}
+	}
+	return status
+}
+
+// idle 窗口内是否已没有请求
+func (sw *slidingWindow) idle(now time.Time) bool {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+	return len(sw.requests) == 0 || !sw.requests[len(sw.requests)-1].After(now.Add(-sw.window))
+}
+
+// fixedWindow 固定窗口计数器，每个键只保存两个计数
+// 当前窗口的估算请求数 = 上一窗口计数 × 上一窗口在滑动区间内的占比 + 当前窗口计数，避免窗口边界处放行两倍请求
+type fixedWindow struct {
+	limit    int           // 每个窗口的限制数量
+	window   time.Duration // 窗口时间
+	start    time.Time     // 当前窗口开始时间
+	current  int           // 当前窗口计数
+	previous int           // 上一窗口计数
+	mutex    sync.Mutex
+}
+
+// newFixedWindow 创建新的固定窗口计数器
+func newFixedWindow(limit int, window time.Duration) *fixedWindow {
+	return &fixedWindow{
+		limit:  limit,
+		window: window,
+		start:  time.Now().Truncate(window),
+	}
+}
+
+// allow 检查是否允许请求
+func (fw *fixedWindow) allow(now time.Time) RateLimitStatus {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
 	
-	// 检查是否超过限制
-	if len(sw.requests) >= sw.limit {
-		return false
+	fw.advance(now)
+	
+	if fw.estimate(now) >= float64(fw.limit) {
+		return fw.statusLocked(now, false)
 	}
+	fw.current++
+	return fw.statusLocked(now, true)
+}
+
+// status 实现 keyLimiter
+func (fw *fixedWindow) status(now time.Time) RateLimitStatus {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
 	
-	// 添加当前请求
-	sw.requests = append(sw.requests, now)
-	return true
+	fw.advance(now)
+	return fw.statusLocked(now, fw.estimate(now) < float64(fw.limit))
+}
+
+// estimate 当前窗口的估算请求数
+func (fw *fixedWindow) estimate(now time.Time) float64 {
+	weight := 1 - float64(now.Sub(fw.start))/float64(fw.window)
+	return float64(fw.previous)*weight + float64(fw.current)
+}
+
+// statusLocked 计算状态，调用方需持有锁
+// 上一窗口的权重随时间线性下降，配额用尽时按权重降到可放行的时间估算 RetryAfter
+func (fw *fixedWindow) statusLocked(now time.Time, allowed bool) RateLimitStatus {
+	end := fw.start.Add(fw.window)
+	status := RateLimitStatus{
+		Allowed:   allowed,
+		Limit:     fw.limit,
+		Remaining: int(math.Ceil(float64(fw.limit) - fw.estimate(now))),
+		Reset:     end,
+	}
+	if status.Remaining <= 0 {
+		status.Remaining = 0
+		retryAt := end
+		if fw.previous > 0 && fw.current < fw.limit {
+			retryAt = fw.start.Add(time.Duration(float64(fw.window) * (1 - float64(fw.limit-fw.current)/float64(fw.previous))))
+		}
+		if retryAt.After(now) {
+			status.RetryAfter = retryAt.Sub(now)
+		}
+	}
+	return status
+}
+
+// advance 切换到 now 所在的窗口
+func (fw *fixedWindow) advance(now time.Time) {
+	elapsed := now.Sub(fw.start)
+	if elapsed < fw.window {
+		return
+	}
+	if elapsed < 2*fw.window {
+		fw.previous = fw.current
+	} else {
+		fw.previous = 0
+	}
+	fw.current = 0
+	fw.start = now.Truncate(fw.window)
+}
+
+// idle 上一窗口和当前窗口都没有请求
+func (fw *fixedWindow) idle(now time.Time) bool {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+	
+	fw.advance(now)
+	return fw.current == 0 && fw.previous == 0
+}
+
+// gcraLimiter GCRA（通用信元速率算法）限流器，等价于漏桶，每个键只保存理论到达时间
+type gcraLimiter struct {
+	tat       time.Time     // 理论到达时间（Theoretical Arrival Time）
+	interval  time.Duration // 请求的平均间隔
+	tolerance time.Duration // 允许提前到达的时间，决定突发请求数
+	mutex     sync.Mutex
+}
+
+// newGCRALimiter 创建新的GCRA限流器，按每秒 rate 个请求的平均速率放行，最多 burst 个突发请求
+func newGCRALimiter(rate, burst int) *gcraLimiter {
+	if rate <= 0 {
+		rate = 1
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	interval := time.Second / time.Duration(rate)
+	return &gcraLimiter{
+		interval:  interval,
+		tolerance: interval * time.Duration(burst-1),
+	}
+}
+
+// allow 检查是否允许请求
+func (g *gcraLimiter) allow(now time.Time) RateLimitStatus {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	if tat.Sub(now) > g.tolerance {
+		return g.statusLocked(now, false)
+	}
+	g.tat = tat.Add(g.interval)
+	return g.statusLocked(now, true)
+}
+
+// status 实现 keyLimiter
+func (g *gcraLimiter) status(now time.Time) RateLimitStatus {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	
+	status := g.statusLocked(now, false)
+	status.Allowed = status.Remaining > 0
+	return status
+}
+
+// statusLocked 计算状态，调用方需持有锁；理论到达时间超前 now 不超过 tolerance 时放行
+func (g *gcraLimiter) statusLocked(now time.Time, allowed bool) RateLimitStatus {
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	backlog := tat.Sub(now)
+	status := RateLimitStatus{
+		Allowed: allowed,
+		Limit:   int(g.tolerance/g.interval) + 1,
+		Reset:   tat,
+	}
+	if backlog <= g.tolerance {
+		status.Remaining = int((g.tolerance-backlog)/g.interval) + 1
+	} else {
+		status.RetryAfter = backlog - g.tolerance
+	}
+	return status
+}
+
+// idle 桶已完全漏空
+func (g *gcraLimiter) idle(now time.Time) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return !g.tat.After(now)
+}
+
+// keyLimiter 单个限流键的限流状态
+type keyLimiter interface {
+	allow(now time.Time) RateLimitStatus  // 尝试放行一个请求
+	status(now time.Time) RateLimitStatus // 查询状态，不消耗配额
+	idle(now time.Time) bool              // 是否可以清理
+}
+
+// newKeyLimiterFunc 根据策略获取限流状态的构造函数，未知策略会 panic
+func newKeyLimiterFunc(config *RateLimiterConfig) func() keyLimiter {
+	window := config.Duration
+	if window <= 0 {
+		window = time.Second
+	}
+	
+	switch config.Strategy {
+	case "", StrategyTokenBucket:
+		return func() keyLimiter { return newTokenBucket(config.Burst, config.Rate) }
+	case StrategySlidingWindow:
+		return func() keyLimiter { return newSlidingWindow(config.Rate, window) }
+	case StrategyFixedWindow:
+		return func() keyLimiter { return newFixedWindow(config.Rate, window) }
+	case StrategyGCRA:
+		return func() keyLimiter { return newGCRALimiter(config.Rate, config.Burst) }
+	default:
+		panic(fmt.Sprintf("unknown rate limit strategy %q", config.Strategy))
+	}
+}
+
+// rateLimiterShards 限流器分片数，按键哈希分散到不同分片，减少高并发下的锁竞争
+const rateLimiterShards = 32
+
+// rateLimiterShard 限流器分片
+type rateLimiterShard struct {
+	limiters map[string]keyLimiter
+	mutex    sync.Mutex
 }
 
 // rateLimiter 限流器结构
 type rateLimiter struct {
-	buckets   map[string]*tokenBucket // 令牌桶映射
-	windows   map[string]*slidingWindow // 滑动窗口映射
-	config    *RateLimiterConfig
-	mutex     sync.RWMutex
+	shards     [rateLimiterShards]*rateLimiterShard
+	config     *RateLimiterConfig
+	newLimiter func() keyLimiter
 }
 
 // newRateLimiter 创建新的限流器
 func newRateLimiter(config *RateLimiterConfig) *rateLimiter {
-	return &rateLimiter{
-		buckets: make(map[string]*tokenBucket),
-		windows: make(map[string]*slidingWindow),
-		config:  config,
+	rl := &rateLimiter{config: config, newLimiter: newKeyLimiterFunc(config)}
+	for i := range rl.shards {
+		rl.shards[i] = &rateLimiterShard{limiters: make(map[string]keyLimiter)}
+	}
+	return rl
+}
+
+// shard 获取键所在的分片（FNV-1a 哈希，避免分配内存）
+func (rl *rateLimiter) shard(key string) *rateLimiterShard {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
 	}
+	return rl.shards[hash%rateLimiterShards]
 }
 
 // allow 检查是否允许请求
-func (rl *rateLimiter) allow(key string) bool {
-	rl.mutex.Lock()
-	defer rl.mutex.Unlock()
+func (rl *rateLimiter) allow(key string) RateLimitStatus {
+	shard := rl.shard(key)
 	
-	// 使用令牌桶算法
-	bucket, exists := rl.buckets[key]
+	// 分片锁只保护映射，限流状态使用自己的锁
+	shard.mutex.Lock()
+	limiter, exists := shard.limiters[key]
 	if !exists {
-		bucket = newTokenBucket(rl.config.Burst, rl.config.Rate)
-		rl.buckets[key] = bucket
+		limiter = rl.newLimiter()
+		shard.limiters[key] = limiter
 	}
+	shard.mutex.Unlock()
 	
-	return bucket.consume()
+	return limiter.allow(time.Now())
 }
 
-// allowSliding 使用滑动窗口检查是否允许请求
-func (rl *rateLimiter) allowSliding(key string) bool {
-	rl.mutex.Lock()
-	defer rl.mutex.Unlock()
+// status 查询限流键的状态，不存在的键返回配额未使用时的状态
+func (rl *rateLimiter) status(key string) RateLimitStatus {
+	shard := rl.shard(key)
 	
-	window, exists := rl.windows[key]
+	shard.mutex.Lock()
+	limiter, exists := shard.limiters[key]
+	shard.mutex.Unlock()
 	if !exists {
-		window = newSlidingWindow(rl.config.Rate, rl.config.Duration)
-		rl.windows[key] = window
+		limiter = rl.newLimiter()
 	}
+	return limiter.status(time.Now())
+}
+
+// reset 清除限流键的状态
+func (rl *rateLimiter) reset(key string) {
+	shard := rl.shard(key)
 	
-	return window.allow()
-}
-
-// cleanup 清理过期的限流器数据
-func (rl *rateLimiter) cleanup() {
-	ticker := time.NewTicker(time.Minute)
-	go func() {
-		for range ticker.C {
-			rl.mutex.Lock()
-			
-			now := time.Now()
-			
-			// 清理令牌桶（删除超过10分钟未使用的）
-			for key, bucket := range rl.buckets {
-				if now.Sub(bucket.lastRefill) > 10*time.Minute {
-					delete(rl.buckets, key)
-				}
-			}
-			
-			// 清理滑动窗口（删除空的窗口）
-			for key, window := range rl.windows {
-				window.mutex.Lock()
-				if len(window.requests) == 0 {
-					delete(rl.windows, key)
-				}
-				window.mutex.Unlock()
+	shard.mutex.Lock()
+	delete(shard.limiters, key)
+	shard.mutex.Unlock()
+}
+
+// cleanup 清理可以回收的限流状态
+func (rl *rateLimiter) cleanup(now time.Time) {
+	for _, shard := range rl.shards {
+		shard.mutex.Lock()
+		for key, limiter := range shard.limiters {
+			if limiter.idle(now) {
+				delete(shard.limiters, key)
 			}
-			
-			rl.mutex.Unlock()
 		}
-	}()
+		shard.mutex.Unlock()
+	}
 }
 
-// 全局限流器实例
-var globalRateLimiter *rateLimiter
+// rateLimitCleanupInterval 限流数据清理间隔
+const rateLimitCleanupInterval = time.Minute
 
-// RateLimit 创建限流中间件
-func RateLimit(config ...*RateLimiterConfig) gin.HandlerFunc {
-	var cfg *RateLimiterConfig
-	if len(config) > 0 && config[0] != nil {
-		cfg = config[0]
-	} else {
-		cfg = DefaultRateLimiterConfig()
-	}
-	
-	// 创建限流器
-	limiter := newRateLimiter(cfg)
-	limiter.cleanup() // 启动清理协程
+// runJanitor 定期清理可以回收的限流状态，直到 stop 关闭
+// 协程只引用内部限流器，外层 RateLimiter 不可达时由终结器关闭 stop
+func (rl *rateLimiter) runJanitor(stop chan struct{}) {
+	ticker := time.NewTicker(rateLimitCleanupInterval)
+	defer ticker.Stop()
 	
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			rl.cleanup(now)
+		}
+	}
+}
+
+// RateLimiter 限流器，除作为中间件使用外，还可以按键查询和重置限流状态
+type RateLimiter struct {
+	config   *RateLimiterConfig
+	limiter  *rateLimiter
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewRateLimiter 创建限流器，config 为空时使用默认配置
+//
+//	limiter := middleware.NewRateLimiter(cfg)
+//	api.Use(limiter.Middleware())
+//	api.GET("/rate-limit", limiter.StatusHandler())
+func NewRateLimiter(config *RateLimiterConfig) *RateLimiter {
+	if config == nil {
+		config = DefaultRateLimiterConfig()
+	}
+	r := &RateLimiter{config: config, limiter: newRateLimiter(config), stop: make(chan struct{})}
+	go r.limiter.runJanitor(r.stop)
+	// 未调用 Stop 的限流器在中间件被丢弃后由终结器停止清理协程
+	runtime.SetFinalizer(r, (*RateLimiter).Stop)
+	return r
+}
+
+// Stop 停止限流器的清理协程，可重复调用，不影响其他限流器
+// 停止后限流器仍可使用，只是空闲的限流状态不再回收
+func (r *RateLimiter) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+}
+
+// Middleware 限流中间件，输出 X-RateLimit-* 响应头，被限流时输出 Retry-After
+func (r *RateLimiter) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		key := cfg.KeyFunc(c)
+		key := r.config.KeyFunc(c)
 		
-		if !limiter.allow(key) {
-			cfg.ErrorHandler(c)
+		status := r.limiter.allow(key)
+		c.Set(rateLimitStatusContextKey, status)
+		if !r.config.DisableHeaders {
+			setRateLimitHeaders(c, status)
+		}
+		if !status.Allowed {
+			r.config.ErrorHandler(c)
 			return
 		}
 		
@@ -231,7 +587,67 @@ func RateLimit(config ...*RateLimiterConfig) gin.HandlerFunc {
 	}
 }
 
-// RateLimitWithSliding 创建滑动窗口限流中间件
+// Status 查询限流键的当前状态，不消耗配额
+func (r *RateLimiter) Status(key string) RateLimitStatus {
+	return r.limiter.status(key)
+}
+
+// Reset 重置限流键的状态
+func (r *RateLimiter) Reset(key string) {
+	r.limiter.reset(key)
+}
+
+// StatusHandler 查询当前客户端限流状态的接口，不消耗配额，挂载时不应经过同一个限流中间件
+func (r *RateLimiter) StatusHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := r.Status(r.config.KeyFunc(c))
+		if !r.config.DisableHeaders {
+			setRateLimitHeaders(c, status)
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// setRateLimitHeaders 输出限流响应头，时间向上取整到秒
+func setRateLimitHeaders(c *gin.Context, status RateLimitStatus) {
+	c.Header(RateLimitLimitHeader, strconv.Itoa(status.Limit))
+	c.Header(RateLimitRemainingHeader, strconv.Itoa(status.Remaining))
+	reset := status.Reset.Unix()
+	if status.Reset.Nanosecond() > 0 {
+		reset++
+	}
+	c.Header(RateLimitResetHeader, strconv.FormatInt(reset, 10))
+	if !status.Allowed {
+		seconds := int64(math.Ceil(status.RetryAfter.Seconds()))
+		if seconds < 1 {
+			seconds = 1
+		}
+		c.Header(RetryAfterHeader, strconv.FormatInt(seconds, 10))
+	}
+}
+
+// GetRateLimitStatus 从上下文获取当前请求的限流状态，请求经过多个限流中间件时为最后一个的状态
+func GetRateLimitStatus(c *gin.Context) (RateLimitStatus, bool) {
+	if value, exists := c.Get(rateLimitStatusContextKey); exists {
+		if status, ok := value.(RateLimitStatus); ok {
+			return status, true
+		}
+	}
+	return RateLimitStatus{}, false
+}
+
+// RateLimit 创建限流中间件，策略由 Strategy 指定，不同路由可以使用不同策略：
+//
+//	api.POST("/login", middleware.RateLimit(&middleware.RateLimiterConfig{Rate: 1, Burst: 5, Strategy: middleware.StrategyGCRA, ...}))
+func RateLimit(config ...*RateLimiterConfig) gin.HandlerFunc {
+	var cfg *RateLimiterConfig
+	if len(config) > 0 && config[0] != nil {
+		cfg = config[0]
+	}
+	return NewRateLimiter(cfg).Middleware()
+}
+
+// RateLimitWithSliding 创建滑动窗口限流中间件，忽略配置中的 Strategy
 func RateLimitWithSliding(config ...*RateLimiterConfig) gin.HandlerFunc {
 	var cfg *RateLimiterConfig
 	if len(config) > 0 && config[0] != nil {
@@ -240,23 +656,18 @@ func RateLimitWithSliding(config ...*RateLimiterConfig) gin.HandlerFunc {
 		cfg = DefaultRateLimiterConfig()
 	}
 	
-	limiter := newRateLimiter(cfg)
-	limiter.cleanup()
-	
-	return func(c *gin.Context) {
-		key := cfg.KeyFunc(c)
-		
-		if !limiter.allowSliding(key) {
-			cfg.ErrorHandler(c)
-			return
-		}
-		
-		c.Next()
-	}
+	sliding := *cfg
+	sliding.Strategy = StrategySlidingWindow
+	return NewRateLimiter(&sliding).Middleware()
 }
 
 // RateLimitByIP 基于IP的限流中间件
 func RateLimitByIP(rate, burst int) gin.HandlerFunc {
+	return NewIPRateLimiter(rate, burst).Middleware()
+}
+
+// NewIPRateLimiter 创建基于IP的限流器，需要在关闭时调用 Stop 的场景使用
+func NewIPRateLimiter(rate, burst int) *RateLimiter {
 	config := &RateLimiterConfig{
 		Rate:  rate,
 		Burst: burst,
@@ -272,7 +683,7 @@ func RateLimitByIP(rate, burst int) gin.HandlerFunc {
 		},
 	}
 	
-	return RateLimit(config)
+	return NewRateLimiter(config)
 }
 
 // RateLimitByUser 基于用户的限流中间件
@@ -282,7 +693,7 @@ func RateLimitByUser(rate, burst int) gin.HandlerFunc {
 		Burst: burst,
 		KeyFunc: func(c *gin.Context) string {
 			if userID, exists := GetUserID(c); exists {
-				return fmt.Sprintf("user:%d", userID)
+				return "user:" + userID
 			}
 			return c.ClientIP() // 回退到IP
 		},
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/auth"
+)
+
+// RequirePermission 创建基于RBAC的资源权限中间件，需在JWT中间件之后使用
+// resource 中的 {参数名} 在请求时替换为路径参数，如路由 /articles/:id 上的 "article:{id}" 解析为 "article:42"
+//
+//	router.PUT("/articles/:id", middleware.RequirePermission(rbac, "article:{id}", "update"), handler)
+func RequirePermission(rbac *auth.RBAC, resource, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := GetUserID(c)
+		if !ok {
+			abortUnauthenticated(c)
+			return
+		}
+
+		resolved, err := ResolveResource(c, resource)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Bad Request",
+				"message": err.Error(),
+			})
+			c.Abort()
+			return
+		}
+
+		allowed, err := rbac.CheckResourcePermission(userID, resolved, action)
+		if err != nil {
+			abortAuthorizationError(c, err)
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": fmt.Sprintf("Permission denied: %s on %s", action, resolved),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePolicy 创建基于策略的授权中间件，需在JWT中间件之后使用
+// 策略资源同样支持 {参数名} 占位符，每个请求使用解析后的策略副本
+func RequirePolicy(evaluator *auth.PolicyEvaluator, policy *auth.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := GetUserID(c)
+		if !ok {
+			abortUnauthenticated(c)
+			return
+		}
+
+		resolved, err := ResolveResource(c, policy.Resource)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Bad Request",
+				"message": err.Error(),
+			})
+			c.Abort()
+			return
+		}
+		requestPolicy := *policy
+		requestPolicy.Resource = resolved
+
+		allowed, err := evaluator.CheckPolicy(userID, &requestPolicy)
+		if err != nil {
+			abortAuthorizationError(c, err)
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": fmt.Sprintf("Policy denied: %v on %s", policy.Actions, resolved),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// abortAuthorizationError 权限数据读取失败时返回500，避免把存储故障当作拒绝访问
+func abortAuthorizationError(c *gin.Context, err error) {
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "Internal Server Error",
+		"message": "authorization check failed",
+	})
+	c.Error(err)
+	c.Abort()
+}
+
+// resourceSeparators 资源中有特殊含义的字符，出现在路径参数中会扩大资源范围，如 id=1:* 使 article:{id} 解析为 article:1:*
+const resourceSeparators = ":*"
+
+// ResolveResource 将资源中的 {参数名} 占位符替换为路径参数，参数不存在、为空或包含资源分隔符（: 和 *）时返回错误
+func ResolveResource(c *gin.Context, resource string) (string, error) {
+	if !strings.Contains(resource, "{") {
+		return resource, nil
+	}
+
+	var b strings.Builder
+	rest := resource
+	for {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			b.WriteString(rest)
+			return b.String(), nil
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("invalid resource pattern: %s", resource)
+		}
+		name := rest[start+1 : start+end]
+		value := c.Param(name)
+		if value == "" {
+			return "", fmt.Errorf("missing path parameter: %s", name)
+		}
+		if strings.ContainsAny(value, resourceSeparators) {
+			return "", fmt.Errorf("invalid path parameter %s: must not contain %q", name, resourceSeparators)
+		}
+		b.WriteString(rest[:start])
+		b.WriteString(value)
+		rest = rest[start+end+1:]
+	}
+}
+
+// abortUnauthenticated 未通过认证时中止请求
+func abortUnauthenticated(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"error":   "Unauthorized",
+		"message": "No authenticated user found",
+	})
+	c.Abort()
+}
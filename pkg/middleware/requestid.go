@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/logger"
+)
+
+// RequestID 请求ID中间件，沿用格式合法的 X-Request-ID 请求头或生成新的ID
+// 请求ID写入上下文的 request_id（响应体中返回）、请求的 context.Context 和 X-Request-ID 响应头，
+// 日志的 RequestIDHook 从 context.Context 中读取并附加到日志。已注册 Correlation 时沿用其确定的ID
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := GetRequestID(c)
+		if id == "" {
+			id = c.GetHeader(RequestIDHeader)
+			if !requestIDPattern.MatchString(id) {
+				id = NewCorrelationID()
+			}
+			c.Set(correlationIDKey, id)
+			c.Header(RequestIDHeader, id)
+		}
+		if logger.RequestIDFromContext(c.Request.Context()) != id {
+			c.Request = c.Request.WithContext(logger.ContextWithRequestID(c.Request.Context(), id))
+		}
+
+		c.Next()
+	}
+}
+
+// GetRequestID 从上下文获取请求ID，与响应体中的 request_id 一致
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(correlationIDKey)
+}
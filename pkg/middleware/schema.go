@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/logger"
+)
+
+// ResponseSchemaConfig 响应Schema校验中间件配置
+type ResponseSchemaConfig struct {
+	Spec        []byte                          // OpenAPI 3 文档或 swag 生成的 Swagger 2.0 文档（JSON/YAML）
+	Logger      *logger.Manager                 // 日志管理器
+	SkipPaths   []string                        // 跳过校验的路径
+	MaxBodySize int64                           // 超过该大小的响应体不校验
+	OnMismatch  func(c *gin.Context, err error) // 校验失败回调，默认记录警告日志
+}
+
+// ginParamPattern 匹配gin路由参数 :id 和 *path
+var ginParamPattern = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// ResponseSchemaValidator 创建响应Schema校验中间件
+// 仅在非release模式下生效：将JSON响应与文档中声明的响应Schema比对，不一致时记录日志，不修改响应
+func ResponseSchemaValidator(config *ResponseSchemaConfig) (gin.HandlerFunc, error) {
+	if gin.Mode() == gin.ReleaseMode {
+		return func(c *gin.Context) { c.Next() }, nil
+	}
+
+	doc, err := loadOpenAPISpec(config.Spec)
+	if err != nil {
+		return nil, err
+	}
+
+	maxBodySize := config.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = 1024 * 1024
+	}
+	onMismatch := config.OnMismatch
+	if onMismatch == nil {
+		onMismatch = func(c *gin.Context, err error) {
+			message := fmt.Sprintf("Response schema mismatch: %s %s %d", c.Request.Method, c.FullPath(), c.Writer.Status())
+			if config.Logger != nil {
+				config.Logger.WithError(err).Warn(message)
+			} else {
+				fmt.Printf("[SCHEMA] %s: %v\n", message, err)
+			}
+		}
+	}
+
+	basePaths := serverBasePaths(doc)
+
+	return func(c *gin.Context) {
+		if c.FullPath() == "" || shouldSkipPath(c.Request.URL.Path, config.SkipPaths) {
+			c.Next()
+			return
+		}
+
+		writer := &responseWriter{
+			ResponseWriter: c.Writer,
+			body:           bytes.NewBuffer(nil),
+		}
+		c.Writer = writer
+
+		c.Next()
+
+		if int64(writer.body.Len()) > maxBodySize || !strings.Contains(writer.Header().Get("Content-Type"), "json") {
+			return
+		}
+
+		route := findOpenAPIRoute(doc, basePaths, c.Request.Method, c.FullPath())
+		if route == nil {
+			return
+		}
+
+		pathParams := make(map[string]string, len(c.Params))
+		for _, param := range c.Params {
+			pathParams[param.Key] = param.Value
+		}
+
+		input := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: &openapi3filter.RequestValidationInput{
+				Request:    c.Request,
+				PathParams: pathParams,
+				Route:      route,
+			},
+			Status:  writer.Status(),
+			Header:  writer.Header(),
+			Options: &openapi3filter.Options{IncludeResponseStatus: true, MultiError: true},
+		}
+		input.SetBodyBytes(writer.body.Bytes())
+
+		if err := openapi3filter.ValidateResponse(c.Request.Context(), input); err != nil {
+			onMismatch(c, err)
+		}
+	}, nil
+}
+
+// loadOpenAPISpec 加载OpenAPI文档，Swagger 2.0 文档会转换为 OpenAPI 3
+func loadOpenAPISpec(spec []byte) (*openapi3.T, error) {
+	if len(spec) == 0 {
+		return nil, fmt.Errorf("openapi spec is empty")
+	}
+
+	var version struct {
+		Swagger string `json:"swagger"`
+	}
+	if json.Unmarshal(spec, &version) == nil && version.Swagger != "" {
+		var doc2 openapi2.T
+		if err := json.Unmarshal(spec, &doc2); err != nil {
+			return nil, fmt.Errorf("failed to parse swagger spec: %w", err)
+		}
+		doc, err := openapi2conv.ToV3(&doc2)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert swagger spec: %w", err)
+		}
+		// 未声明host时转换结果不包含basePath，需要单独保留
+		if doc2.Host == "" && doc2.BasePath != "" {
+			doc.AddServer(&openapi3.Server{URL: doc2.BasePath})
+		}
+		if err := openapi3.NewLoader().ResolveRefsIn(doc, nil); err != nil {
+			return nil, fmt.Errorf("failed to resolve swagger refs: %w", err)
+		}
+		return doc, nil
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load openapi spec: %w", err)
+	}
+	return doc, nil
+}
+
+// serverBasePaths 获取文档中服务地址的路径前缀（对应 Swagger 2.0 的 basePath）
+func serverBasePaths(doc *openapi3.T) []string {
+	var basePaths []string
+	for _, server := range doc.Servers {
+		if u, err := url.Parse(server.URL); err == nil && u.Path != "" && u.Path != "/" {
+			basePaths = append(basePaths, strings.TrimSuffix(u.Path, "/"))
+		}
+	}
+	return append(basePaths, "")
+}
+
+// findOpenAPIRoute 根据gin路由模板查找文档中的操作
+func findOpenAPIRoute(doc *openapi3.T, basePaths []string, method, fullPath string) *routers.Route {
+	template := ginParamPattern.ReplaceAllString(fullPath, "{$1}")
+	for _, basePath := range basePaths {
+		if !strings.HasPrefix(template, basePath) {
+			continue
+		}
+		path := strings.TrimPrefix(template, basePath)
+		if path == "" {
+			path = "/"
+		}
+		pathItem := doc.Paths.Find(path)
+		if pathItem == nil {
+			continue
+		}
+		operation := pathItem.GetOperation(strings.ToUpper(method))
+		if operation == nil {
+			return nil
+		}
+		return &routers.Route{
+			Spec:      doc,
+			Path:      path,
+			PathItem:  pathItem,
+			Method:    method,
+			Operation: operation,
+		}
+	}
+	return nil
+}
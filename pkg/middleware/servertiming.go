@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/metrics"
+)
+
+// ServerTiming 响应耗时中间件，应尽早注册
+// 在请求的 context.Context 中放入 metrics.Timings，响应头写出前输出：
+//   - Server-Timing：db、cache 等分段的累计耗时和次数，以及 handler（从本中间件开始到写出响应头的总耗时）
+//   - X-Response-Time：同 handler 耗时，如 12.34ms
+//
+// db、cache 分段需要 database.Manager.EnableTiming、cache.Manager.EnableTiming，并通过
+// GetDB().WithContext(c.Request.Context())、cache.WithContext(c.Request.Context()) 传入请求上下文；
+// 自定义分段使用 TrackTiming 或 metrics.StartTiming
+func ServerTiming() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timings := metrics.NewTimings()
+		writer := &serverTimingWriter{ResponseWriter: c.Writer, start: time.Now(), timings: timings}
+		c.Writer = writer
+		c.Request = c.Request.WithContext(metrics.WithTimings(c.Request.Context(), timings))
+
+		c.Next()
+
+		// 没有响应体时由gin在处理结束后写出响应头
+		if !writer.Written() {
+			writer.writeTimingHeaders()
+		}
+	}
+}
+
+// GetTimings 获取当前请求的耗时分段记录，未注册 ServerTiming 时返回 nil
+func GetTimings(c *gin.Context) *metrics.Timings {
+	return metrics.TimingsFromContext(c.Request.Context())
+}
+
+// TrackTiming 开始记录当前请求的自定义耗时分段，返回结束函数，如 defer middleware.TrackTiming(c, "render")()
+func TrackTiming(c *gin.Context, name string) func() {
+	return metrics.StartTiming(c.Request.Context(), name)
+}
+
+// serverTimingWriter 在响应头写出前输出耗时响应头
+type serverTimingWriter struct {
+	gin.ResponseWriter
+	start   time.Time
+	timings *metrics.Timings
+	done    bool
+}
+
+// writeTimingHeaders 输出耗时响应头，只执行一次
+func (w *serverTimingWriter) writeTimingHeaders() {
+	if w.done {
+		return
+	}
+	w.done = true
+
+	elapsed := time.Since(w.start)
+	value := metrics.FormatTiming("handler", elapsed, "")
+	if segments := w.timings.Header(); segments != "" {
+		value = segments + ", " + value
+	}
+	w.Header().Add("Server-Timing", value)
+	w.Header().Set("X-Response-Time", fmt.Sprintf("%.2fms", float64(elapsed)/float64(time.Millisecond)))
+}
+
+// WriteHeaderNow 写出响应头前输出耗时响应头
+func (w *serverTimingWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.writeTimingHeaders()
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Write 写出响应体前输出耗时响应头
+func (w *serverTimingWriter) Write(data []byte) (int, error) {
+	if !w.Written() {
+		w.writeTimingHeaders()
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString 写出响应体前输出耗时响应头
+func (w *serverTimingWriter) WriteString(s string) (int, error) {
+	if !w.Written() {
+		w.writeTimingHeaders()
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Flush 刷新前输出耗时响应头
+func (w *serverTimingWriter) Flush() {
+	if !w.Written() {
+		w.writeTimingHeaders()
+	}
+	w.ResponseWriter.Flush()
+}
@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/auth"
+	"github.com/hwh/hwhkit-go/pkg/cache"
+)
+
+// 会话中间件在上下文中使用的键
+const (
+	sessionContextKey       = "session"
+	sessionConfigContextKey = "session_config"
+)
+
+// SessionRoleKey 会话数据中保存角色的键
+const SessionRoleKey = "role"
+
+// ErrNoSessionMiddleware 未注册会话中间件
+var ErrNoSessionMiddleware = errors.New("session middleware is not installed")
+
+// SessionConfig 会话中间件配置
+type SessionConfig struct {
+	Manager    *cache.SessionManager
+	CookieName string        // 默认 session_id
+	CookiePath string        // 默认 /
+	Domain     string        // Cookie域名
+	MaxAge     int           // Cookie有效期（秒），为0时为浏览器会话Cookie
+	Secure     bool          // 仅通过HTTPS发送，为 false 时HTTPS请求仍设置 Secure
+	SameSite   http.SameSite // 默认 Lax
+	AutoCreate bool          // 请求没有有效会话时自动创建匿名会话，为 false 时由 EnsureSession 按需创建
+
+	DeviceBinding auth.DeviceBindingMode // 设备绑定校验模式，登录时绑定设备指纹，默认不校验
+}
+
+// Session 会话中间件，从Cookie加载会话到上下文，处理器修改的会话数据在处理结束后保存
+// Cookie始终为 HttpOnly；GetSession 读取会话，EnsureSession 按需创建匿名会话
+// 权限变更时应调用 LoginSession、SetSessionRole，它们会自动更换会话ID以防止会话固定攻击
+func Session(config *SessionConfig) gin.HandlerFunc {
+	cfg := *config
+	if cfg.CookieName == "" {
+		cfg.CookieName = "session_id"
+	}
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = "/"
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+
+	return func(c *gin.Context) {
+		c.Set(sessionConfigContextKey, &cfg)
+
+		if sessionID, err := c.Cookie(cfg.CookieName); err == nil && sessionID != "" {
+			session, err := cfg.Manager.GetSession(sessionID)
+			// 设备不匹配的会话视为不存在，避免被盗用的会话ID在其他设备上使用
+			if err == nil && auth.CheckDevice(cfg.DeviceBinding, session.Device, auth.DeviceFingerprint(c.Request)) == nil {
+				c.Set(sessionContextKey, session)
+			}
+		}
+		if cfg.AutoCreate {
+			if _, err := EnsureSession(c); err != nil {
+				c.Error(err)
+			}
+		}
+
+		c.Next()
+
+		// 处理器修改了会话数据（包括读取闪存数据）时自动保存
+		if session, exists := GetSession(c); exists && session.IsModified() {
+			if err := cfg.Manager.UpdateSession(session); err != nil {
+				c.Error(err)
+			}
+		}
+	}
+}
+
+// GetSession 获取当前请求的会话
+func GetSession(c *gin.Context) (*cache.Session, bool) {
+	if value, exists := c.Get(sessionContextKey); exists {
+		if session, ok := value.(*cache.Session); ok {
+			return session, true
+		}
+	}
+	return nil, false
+}
+
+// EnsureSession 获取当前请求的会话，不存在时创建匿名会话并下发Cookie
+// 开启设备绑定时新会话创建即绑定当前设备指纹，严格模式下后续请求才能加载该会话
+func EnsureSession(c *gin.Context) (*cache.Session, error) {
+	cfg, err := sessionConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	if session, exists := GetSession(c); exists {
+		return session, nil
+	}
+
+	session, err := cfg.Manager.CreateSession("")
+	if err != nil {
+		return nil, err
+	}
+	if err := bindSessionDevice(c, cfg, session); err != nil {
+		return nil, err
+	}
+	setSession(c, cfg, session)
+	return session, nil
+}
+
+// LoginSession 用户登录后绑定会话
+// 已有会话时先更换会话ID并保留数据，再将新ID绑定到用户，登录前被植入的会话ID随之失效且从未被认证
+// 超出用户并发会话限制时返回 cache.ErrSessionLimitExceeded
+func LoginSession(c *gin.Context, userID string) (*cache.Session, error) {
+	cfg, err := sessionConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	session, exists := GetSession(c)
+	if !exists {
+		session, err = cfg.Manager.CreateSession(userID)
+		if err != nil {
+			return nil, err
+		}
+		setSession(c, cfg, session)
+	} else {
+		if session, err = RegenerateSession(c); err != nil {
+			return nil, err
+		}
+		bound, err := cfg.Manager.BindUser(session.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if bound.ID != session.ID {
+			// 会话管理器开启了 SetRotateOnLogin，绑定时再次更换了会话ID
+			setSession(c, cfg, bound)
+		} else {
+			c.Set(sessionContextKey, bound)
+		}
+		session = bound
+	}
+
+	if err := bindSessionDevice(c, cfg, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// bindSessionDevice 开启设备绑定时将会话绑定到当前请求的设备指纹
+func bindSessionDevice(c *gin.Context, cfg *SessionConfig, session *cache.Session) error {
+	if cfg.DeviceBinding == "" || cfg.DeviceBinding == auth.DeviceBindingOff {
+		return nil
+	}
+	session.Device = auth.DeviceFingerprint(c.Request)
+	return cfg.Manager.UpdateSession(session)
+}
+
+// SetSessionRole 更新会话中的角色并更换会话ID
+func SetSessionRole(c *gin.Context, role string) (*cache.Session, error) {
+	cfg, err := sessionConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	session, exists := GetSession(c)
+	if !exists {
+		return nil, errors.New("no active session")
+	}
+
+	session.Data[SessionRoleKey] = role
+	if err := cfg.Manager.UpdateSession(session); err != nil {
+		return nil, err
+	}
+	return RegenerateSession(c)
+}
+
+// RegenerateSession 更换当前会话ID并下发新的Cookie
+func RegenerateSession(c *gin.Context) (*cache.Session, error) {
+	cfg, err := sessionConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	current, exists := GetSession(c)
+	if !exists {
+		return nil, errors.New("no active session")
+	}
+
+	session, err := cfg.Manager.RotateSession(current.ID)
+	if err != nil {
+		return nil, err
+	}
+	setSession(c, cfg, session)
+	return session, nil
+}
+
+// LogoutSession 删除当前会话并清除Cookie
+func LogoutSession(c *gin.Context) error {
+	cfg, err := sessionConfig(c)
+	if err != nil {
+		return err
+	}
+
+	if session, exists := GetSession(c); exists {
+		if err := cfg.Manager.DeleteSession(session.ID); err != nil {
+			return err
+		}
+	}
+	c.SetSameSite(cfg.SameSite)
+	c.SetCookie(cfg.CookieName, "", -1, cfg.CookiePath, cfg.Domain, cookieSecure(c, cfg), true)
+	c.Set(sessionContextKey, nil)
+	return nil
+}
+
+// sessionConfig 获取会话中间件配置
+func sessionConfig(c *gin.Context) (*SessionConfig, error) {
+	if value, exists := c.Get(sessionConfigContextKey); exists {
+		if cfg, ok := value.(*SessionConfig); ok {
+			return cfg, nil
+		}
+	}
+	return nil, ErrNoSessionMiddleware
+}
+
+// setSession 保存会话到上下文并下发Cookie
+func setSession(c *gin.Context, cfg *SessionConfig, session *cache.Session) {
+	c.Set(sessionContextKey, session)
+	c.SetSameSite(cfg.SameSite)
+	c.SetCookie(cfg.CookieName, session.ID, cfg.MaxAge, cfg.CookiePath, cfg.Domain, cookieSecure(c, cfg), true)
+}
+
+// cookieSecure 配置要求或请求通过HTTPS到达时设置 Secure
+func cookieSecure(c *gin.Context, cfg *SessionConfig) bool {
+	return cfg.Secure || c.Request.TLS != nil
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/utils"
+)
+
+// signedURLUserContextKey 上下文中保存签名URL绑定用户的键
+const signedURLUserContextKey = "signed_url_user"
+
+// SignedURLConfig 签名URL校验中间件配置
+type SignedURLConfig struct {
+	Signer       *utils.URLSigner
+	MatchUser    bool                      // 链接绑定了用户时要求当前登录用户与之一致，需放在JWT中间件之后
+	ErrorHandler func(*gin.Context, error) // 校验失败时的处理函数，默认签名无效返回403、过期返回410
+}
+
+// SignedURL 签名URL校验中间件，用于保护 Static 等文件下载路由，链接由 utils.URLSigner.Sign 生成
+func SignedURL(config *SignedURLConfig) gin.HandlerFunc {
+	errorHandler := config.ErrorHandler
+	if errorHandler == nil {
+		errorHandler = func(c *gin.Context, err error) {
+			status, message := http.StatusForbidden, "Invalid signature"
+			if errors.Is(err, utils.ErrSignedURLExpired) {
+				status, message = http.StatusGone, "Link expired"
+			}
+			c.JSON(status, gin.H{
+				"error":   http.StatusText(status),
+				"message": message,
+			})
+			c.Abort()
+		}
+	}
+
+	return func(c *gin.Context) {
+		user, err := config.Signer.Verify(c.Request.URL)
+		if err != nil {
+			errorHandler(c, err)
+			return
+		}
+		if user != "" && config.MatchUser {
+			if current, ok := GetUserID(c); !ok || current != user {
+				errorHandler(c, utils.ErrInvalidSignature)
+				return
+			}
+		}
+		c.Set(signedURLUserContextKey, user)
+		c.Next()
+	}
+}
+
+// GetSignedURLUser 获取签名URL绑定的用户ID，未绑定用户时返回false
+func GetSignedURLUser(c *gin.Context) (string, bool) {
+	user := c.GetString(signedURLUserContextKey)
+	return user, user != ""
+}
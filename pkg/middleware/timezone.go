@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/utils"
+)
+
+// TimezoneHeader 客户端指定展示时区的默认请求头，取值为IANA时区名称，如 America/New_York
+const TimezoneHeader = "X-Timezone"
+
+// timezoneKey 请求时区在上下文中的键
+const timezoneKey = "timezone"
+
+// TimezoneConfig 请求时区解析配置
+type TimezoneConfig struct {
+	// Header 时区请求头，默认 X-Timezone
+	Header string
+	// Default 无法解析时使用的时区，默认为全局默认时区
+	Default *time.Location
+	// UserTimezone 从用户资料获取时区，返回空字符串表示未设置；依赖认证信息时需在JWT中间件之后注册
+	UserTimezone func(c *gin.Context) string
+}
+
+// Timezone 请求时区中间件，按 用户资料 > 请求头 > 默认时区 的顺序解析展示时区，无效的时区名称会被忽略
+// 解析结果写入上下文（GetTimezone）和请求的 context.Context（utils.LocationFromContext），并通过同名响应头返回
+// 时区只用于展示格式化，存储和计算仍使用全局默认时区
+func Timezone(config *TimezoneConfig) gin.HandlerFunc {
+	if config == nil {
+		config = &TimezoneConfig{}
+	}
+	header := config.Header
+	if header == "" {
+		header = TimezoneHeader
+	}
+
+	return func(c *gin.Context) {
+		location := config.Default
+		if location == nil {
+			location = utils.DefaultLocation()
+		}
+		if loaded, ok := loadTimezone(c.GetHeader(header)); ok {
+			location = loaded
+		}
+		if config.UserTimezone != nil {
+			if loaded, ok := loadTimezone(config.UserTimezone(c)); ok {
+				location = loaded
+			}
+		}
+
+		c.Set(timezoneKey, location)
+		c.Request = c.Request.WithContext(utils.ContextWithLocation(c.Request.Context(), location))
+		c.Header(header, location.String())
+
+		c.Next()
+	}
+}
+
+// loadTimezone 加载时区，名称为空或无效时返回 false
+func loadTimezone(name string) (*time.Location, bool) {
+	// 空字符串会被解析为UTC，Local 依赖服务器所在时区，均不作为客户端时区
+	if name == "" || name == "Local" {
+		return nil, false
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, false
+	}
+	return location, true
+}
+
+// GetTimezone 获取请求的展示时区，未注册 Timezone 中间件时返回全局默认时区
+func GetTimezone(c *gin.Context) *time.Location {
+	if value, exists := c.Get(timezoneKey); exists {
+		if location, ok := value.(*time.Location); ok {
+			return location
+		}
+	}
+	return utils.DefaultLocation()
+}
+
+// GetTimeUtils 获取绑定请求时区的时间工具，用于按用户时区格式化响应中的时间
+func GetTimeUtils(c *gin.Context) *utils.TimeUtils {
+	return utils.Time.WithLocation(GetTimezone(c))
+}
@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/auth"
+)
+
+// TrustedHeaderConfig 可信请求头认证中间件配置
+// 上游网关完成认证后注入身份请求头，中间件只信任来自网关连接的请求头，并写入与JWT中间件相同的上下文键
+type TrustedHeaderConfig struct {
+	UserHeader      string   // 用户ID请求头，默认 X-Auth-User
+	UsernameHeader  string   // 用户名请求头，默认 X-Auth-Name
+	EmailHeader     string   // 邮箱请求头，默认 X-Auth-Email
+	RolesHeader     string   // 角色请求头（逗号分隔），默认 X-Auth-Roles
+	TrustedProxies  []string // 网关地址，IP或CIDR，按连接的对端地址匹配，不读取 X-Forwarded-For
+	ClientCertNames []string // 网关客户端证书的CN或DNS SAN，要求连接使用服务器已校验的客户端证书
+
+	// AuthManager 设置后，不带身份请求头的请求回退到JWT校验；未设置时直接拒绝
+	AuthManager    *auth.Manager
+	ErrorHandler   func(*gin.Context, error)        // 错误处理函数，默认同JWT中间件返回401
+	SuccessHandler func(*gin.Context, *auth.Claims) // 成功处理函数，默认同JWT中间件设置 user_id、roles、claims 等上下文键
+}
+
+// trustedSource 已解析的可信来源
+type trustedSource struct {
+	networks  []*net.IPNet
+	certNames []string
+}
+
+// newTrustedSource 解析可信来源配置，网关地址和证书名称至少配置一项
+func newTrustedSource(config *TrustedHeaderConfig) (*trustedSource, error) {
+	if len(config.TrustedProxies) == 0 && len(config.ClientCertNames) == 0 {
+		return nil, errors.New("trusted header auth requires trusted proxies or client certificate names")
+	}
+	source := &trustedSource{certNames: config.ClientCertNames}
+	for _, proxy := range config.TrustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			source.networks = append(source.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		source.networks = append(source.networks, network)
+	}
+	return source, nil
+}
+
+// verify 校验请求是否来自可信网关，地址和证书都配置时需同时满足
+func (s *trustedSource) verify(r *http.Request) error {
+	if len(s.networks) > 0 {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		ip := net.ParseIP(host)
+		trusted := false
+		for _, network := range s.networks {
+			if ip != nil && network.Contains(ip) {
+				trusted = true
+				break
+			}
+		}
+		if !trusted {
+			return fmt.Errorf("identity headers from untrusted address %s", host)
+		}
+	}
+
+	if len(s.certNames) > 0 {
+		cert := verifiedClientCert(r)
+		if cert == nil {
+			return errors.New("identity headers require a verified client certificate")
+		}
+		for _, name := range s.certNames {
+			if cert.Subject.CommonName == name {
+				return nil
+			}
+			for _, dnsName := range cert.DNSNames {
+				if dnsName == name {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("identity headers from untrusted client certificate %q", cert.Subject.CommonName)
+	}
+	return nil
+}
+
+// TrustedHeader 创建可信请求头认证中间件，用于边缘网关已完成认证的部署
+// 来自可信网关的请求按身份请求头认证；其他来源携带身份请求头的请求返回401，防止伪造
+func TrustedHeader(config *TrustedHeaderConfig) gin.HandlerFunc {
+	if config == nil {
+		panic("trusted header middleware requires a config")
+	}
+	source, err := newTrustedSource(config)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	cfg := *config
+	defaults := DefaultJWTConfig(cfg.AuthManager)
+	if cfg.UserHeader == "" {
+		cfg.UserHeader = "X-Auth-User"
+	}
+	if cfg.UsernameHeader == "" {
+		cfg.UsernameHeader = "X-Auth-Name"
+	}
+	if cfg.EmailHeader == "" {
+		cfg.EmailHeader = "X-Auth-Email"
+	}
+	if cfg.RolesHeader == "" {
+		cfg.RolesHeader = "X-Auth-Roles"
+	}
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = defaults.ErrorHandler
+	}
+	if cfg.SuccessHandler == nil {
+		cfg.SuccessHandler = defaults.SuccessHandler
+	}
+
+	var fallback gin.HandlerFunc
+	if cfg.AuthManager != nil {
+		defaults.ErrorHandler = cfg.ErrorHandler
+		defaults.SuccessHandler = cfg.SuccessHandler
+		fallback = JWT(defaults)
+	}
+
+	identityHeaders := []string{cfg.UserHeader, cfg.UsernameHeader, cfg.EmailHeader, cfg.RolesHeader}
+	return func(c *gin.Context) {
+		present := false
+		for _, header := range identityHeaders {
+			if c.GetHeader(header) != "" {
+				present = true
+				break
+			}
+		}
+
+		if !present {
+			if fallback != nil {
+				fallback(c)
+				return
+			}
+			cfg.ErrorHandler(c, errors.New("missing identity headers"))
+			return
+		}
+
+		if err := source.verify(c.Request); err != nil {
+			cfg.ErrorHandler(c, err)
+			return
+		}
+		userID := strings.TrimSpace(c.GetHeader(cfg.UserHeader))
+		if userID == "" {
+			cfg.ErrorHandler(c, fmt.Errorf("missing %s header", cfg.UserHeader))
+			return
+		}
+
+		claims := &auth.Claims{
+			UserID:   userID,
+			Username: strings.TrimSpace(c.GetHeader(cfg.UsernameHeader)),
+			Email:    strings.TrimSpace(c.GetHeader(cfg.EmailHeader)),
+			Roles:    splitRoles(c.GetHeader(cfg.RolesHeader)),
+		}
+		claims.Subject = userID
+		cfg.SuccessHandler(c, claims)
+
+		c.Next()
+	}
+}
+
+// splitRoles 解析逗号分隔的角色列表
+func splitRoles(value string) []string {
+	var roles []string
+	for _, role := range strings.Split(value, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
@@ -0,0 +1,285 @@
+// Package moderation 提供用户生成内容的审核管道：按顺序调用关键词、外部API等审核服务，记录审核决定，并支持异步复审
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/logger"
+	"github.com/hwh/hwhkit-go/pkg/metrics"
+	"github.com/hwh/hwhkit-go/pkg/queue"
+)
+
+// RecheckJobType 异步复审任务类型
+const RecheckJobType = "moderation.recheck"
+
+// ErrContentRejected 内容未通过审核
+var ErrContentRejected = errors.New("content rejected by moderation")
+
+var (
+	decisionsTotal = metrics.Default.Counter("hwhkit_moderation_decisions_total",
+		"Total number of moderation decisions", "type", "decision")
+	providerErrors = metrics.Default.Counter("hwhkit_moderation_provider_errors_total",
+		"Total number of moderation provider failures", "provider")
+)
+
+// Decision 审核决定
+type Decision string
+
+// 审核决定，严重程度依次递增
+const (
+	DecisionAllow  Decision = "allow"
+	DecisionReview Decision = "review" // 需要人工或异步复审
+	DecisionReject Decision = "reject"
+)
+
+// severity 决定的严重程度，用于合并多个审核服务的结果
+func (d Decision) severity() int {
+	switch d {
+	case DecisionAllow:
+		return 0
+	case DecisionReview:
+		return 1
+	case DecisionReject:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// Content 待审核内容
+type Content struct {
+	ID       string            `json:"id"`   // 业务内容ID，如评论ID，用于关联审核记录
+	Type     string            `json:"type"` // 内容类型，如 comment、nickname
+	UserID   string            `json:"user_id,omitempty"`
+	Text     string            `json:"text"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Result 单个审核服务的结果
+type Result struct {
+	Provider string   `json:"provider"`
+	Decision Decision `json:"decision"`
+	Reasons  []string `json:"reasons,omitempty"` // 命中原因，如命中的关键词或分类标签
+	Score    float64  `json:"score,omitempty"`   // 外部服务返回的风险分数
+	Error    string   `json:"error,omitempty"`   // 审核服务调用失败时的错误
+}
+
+// Verdict 审核管道的最终结论
+type Verdict struct {
+	Decision  Decision  `json:"decision"`
+	Results   []Result  `json:"results"`
+	Recheck   bool      `json:"recheck"` // 是否已安排异步复审
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Allowed 内容是否可以直接发布
+func (v *Verdict) Allowed() bool {
+	return v.Decision == DecisionAllow
+}
+
+// Reasons 汇总所有审核服务的命中原因
+func (v *Verdict) Reasons() []string {
+	var reasons []string
+	for _, result := range v.Results {
+		reasons = append(reasons, result.Reasons...)
+	}
+	return reasons
+}
+
+// Err 内容被拒绝时返回包装了 ErrContentRejected 的错误，否则返回nil
+func (v *Verdict) Err() error {
+	if v.Decision != DecisionReject {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrContentRejected, strings.Join(v.Reasons(), ", "))
+}
+
+// Provider 审核服务
+type Provider interface {
+	Name() string
+	Check(ctx context.Context, content *Content) (*Result, error)
+}
+
+// RecheckHandler 异步复审完成后的回调，业务方据此更新内容状态（如隐藏已发布的评论）
+type RecheckHandler func(ctx context.Context, content *Content, verdict *Verdict) error
+
+// Pipeline 审核管道，按注册顺序调用审核服务，取最严重的决定，遇到拒绝时提前结束
+// 处理函数在保存用户内容前调用 Check；结论为 review 或审核服务调用失败时，配置了队列则安排异步复审
+//
+//	pipeline := moderation.NewPipeline(keywords, apiProvider)
+//	pipeline.SetStore(moderation.NewGormStore(db))
+//	pipeline.SetQueue(jobQueue, 10*time.Minute)
+//	pipeline.OnRecheck(func(ctx context.Context, content *moderation.Content, verdict *moderation.Verdict) error { ... })
+//
+//	verdict, err := pipeline.Check(ctx, &moderation.Content{ID: id, Type: "comment", UserID: userID, Text: text})
+//	if err != nil { ... }
+//	if err := verdict.Err(); err != nil { ... } // 拒绝发布
+type Pipeline struct {
+	providers    []Provider
+	store        Store
+	queue        *queue.Queue
+	recheckDelay time.Duration
+	onRecheck    RecheckHandler
+	failOpen     bool
+	logger       *logger.Manager
+	now          func() time.Time
+}
+
+// NewPipeline 创建审核管道
+func NewPipeline(providers ...Provider) *Pipeline {
+	return &Pipeline{
+		providers:    providers,
+		recheckDelay: 5 * time.Minute,
+		now:          time.Now,
+	}
+}
+
+// AddProvider 追加审核服务
+func (p *Pipeline) AddProvider(provider Provider) {
+	p.providers = append(p.providers, provider)
+}
+
+// SetStore 设置审核记录存储，每次审核（含复审）的结论都会保存
+func (p *Pipeline) SetStore(store Store) {
+	p.store = store
+}
+
+// SetQueue 设置异步复审使用的任务队列和复审延迟，并在队列上注册复审任务处理函数
+func (p *Pipeline) SetQueue(q *queue.Queue, delay time.Duration) {
+	p.queue = q
+	if delay > 0 {
+		p.recheckDelay = delay
+	}
+	q.Handle(RecheckJobType, p.handleRecheck)
+}
+
+// OnRecheck 设置异步复审完成后的回调
+func (p *Pipeline) OnRecheck(handler RecheckHandler) {
+	p.onRecheck = handler
+}
+
+// SetFailOpen 设置审核服务调用失败时的处理：true 时忽略失败的服务，false（默认）时结论至少为 review
+func (p *Pipeline) SetFailOpen(failOpen bool) {
+	p.failOpen = failOpen
+}
+
+// SetLogger 设置日志管理器，记录审核服务调用失败
+func (p *Pipeline) SetLogger(l *logger.Manager) {
+	p.logger = l
+}
+
+// Check 审核内容并保存审核记录，结论为 review 或有审核服务失败时安排异步复审
+// 只有审核记录保存失败或复审任务入队失败时返回错误，审核服务调用失败体现在结论中
+func (p *Pipeline) Check(ctx context.Context, content *Content) (*Verdict, error) {
+	verdict, failed := p.evaluate(ctx, content)
+	if (verdict.Decision == DecisionReview || failed) && p.queue != nil {
+		if err := p.ScheduleRecheck(ctx, content, p.recheckDelay); err != nil {
+			return verdict, err
+		}
+		verdict.Recheck = true
+	}
+	if err := p.record(ctx, content, verdict, false); err != nil {
+		return verdict, err
+	}
+	return verdict, nil
+}
+
+// ScheduleRecheck 安排异步复审，用于规则更新后重新审核已发布的内容
+func (p *Pipeline) ScheduleRecheck(ctx context.Context, content *Content, delay time.Duration) error {
+	if p.queue == nil {
+		return errors.New("moderation queue is not configured")
+	}
+	opts := queue.EnqueueOptions{Delay: delay}
+	if content.ID != "" {
+		// 同一内容只保留一个待执行的复审任务
+		opts.ID = "moderation:" + content.Type + ":" + content.ID
+	}
+	if _, err := p.queue.EnqueueWithOptions(ctx, RecheckJobType, content, opts); err != nil {
+		return fmt.Errorf("failed to schedule moderation recheck: %w", err)
+	}
+	return nil
+}
+
+// handleRecheck 复审任务处理函数，审核服务仍然失败时返回错误由队列重试
+func (p *Pipeline) handleRecheck(ctx context.Context, job *queue.Job) error {
+	var content Content
+	if err := job.Decode(&content); err != nil {
+		return fmt.Errorf("failed to decode moderation content: %w", err)
+	}
+
+	verdict, failed := p.evaluate(ctx, &content)
+	if err := p.record(ctx, &content, verdict, true); err != nil {
+		return err
+	}
+	if failed {
+		return errors.New("moderation provider failed during recheck")
+	}
+	if p.onRecheck != nil {
+		return p.onRecheck(ctx, &content, verdict)
+	}
+	return nil
+}
+
+// evaluate 依次调用审核服务，返回合并后的结论和是否有服务调用失败
+func (p *Pipeline) evaluate(ctx context.Context, content *Content) (*Verdict, bool) {
+	verdict := &Verdict{Decision: DecisionAllow, CheckedAt: p.now()}
+	failed := false
+
+	for _, provider := range p.providers {
+		result, err := provider.Check(ctx, content)
+		if err != nil {
+			failed = true
+			providerErrors.Inc(provider.Name())
+			if p.logger != nil {
+				p.logger.WithError(err).Warnf("Moderation provider %s failed", provider.Name())
+			}
+			result = &Result{Decision: DecisionReview, Error: err.Error()}
+			if p.failOpen {
+				result.Decision = DecisionAllow
+			}
+		}
+		if result.Provider == "" {
+			result.Provider = provider.Name()
+		}
+		verdict.Results = append(verdict.Results, *result)
+		if result.Decision.severity() > verdict.Decision.severity() {
+			verdict.Decision = result.Decision
+		}
+		if verdict.Decision == DecisionReject {
+			break
+		}
+	}
+
+	decisionsTotal.Inc(content.Type, string(verdict.Decision))
+	return verdict, failed && !p.failOpen
+}
+
+// record 保存审核记录
+func (p *Pipeline) record(ctx context.Context, content *Content, verdict *Verdict, recheck bool) error {
+	if p.store == nil {
+		return nil
+	}
+
+	providers := make([]string, 0, len(verdict.Results))
+	for _, result := range verdict.Results {
+		providers = append(providers, result.Provider)
+	}
+	record := &Record{
+		ContentID:   content.ID,
+		ContentType: content.Type,
+		UserID:      content.UserID,
+		Decision:    verdict.Decision,
+		Providers:   strings.Join(providers, ","),
+		Reasons:     strings.Join(verdict.Reasons(), ","),
+		Recheck:     recheck,
+		CreatedAt:   verdict.CheckedAt,
+	}
+	if err := p.store.SaveRecord(ctx, record); err != nil {
+		return fmt.Errorf("failed to save moderation record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hwh/hwhkit-go/pkg/cache"
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/hwh/hwhkit-go/pkg/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeywordProvider(t *testing.T) {
+	keywords := NewKeywordProvider()
+	keywords.AddKeywords(DecisionReview, "casino")
+	keywords.AddKeywords(DecisionReject, "Scam", "spam")
+
+	result, err := keywords.Check(context.Background(), &Content{Text: "Visit our CASINO, no scam"})
+	require.NoError(t, err)
+	assert.Equal(t, DecisionReject, result.Decision)
+	assert.Equal(t, []string{"keyword:casino", "keyword:scam"}, result.Reasons)
+
+	keywords.RemoveKeywords("scam")
+	result, err = keywords.Check(context.Background(), &Content{Text: "Visit our casino, no scam"})
+	require.NoError(t, err)
+	assert.Equal(t, DecisionReview, result.Decision)
+
+	result, err = keywords.Check(context.Background(), &Content{Text: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, DecisionAllow, result.Decision)
+	assert.Empty(t, result.Reasons)
+}
+
+func TestAPIProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		var content Content
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&content))
+		if content.Text == "broken" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"decision":"review","reasons":["violence"],"score":0.7}`))
+	}))
+	t.Cleanup(server.Close)
+
+	provider := &APIProvider{ProviderName: "vendor", URL: server.URL, Header: http.Header{"Authorization": {"Bearer token"}}}
+	result, err := provider.Check(context.Background(), &Content{Text: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "vendor", result.Provider)
+	assert.Equal(t, DecisionReview, result.Decision)
+	assert.Equal(t, []string{"violence"}, result.Reasons)
+	assert.Equal(t, 0.7, result.Score)
+
+	_, err = provider.Check(context.Background(), &Content{Text: "broken"})
+	assert.Error(t, err)
+}
+
+// failingProvider 调用失败的审核服务，failures 次后恢复
+type failingProvider struct {
+	failures int
+}
+
+func (f *failingProvider) Name() string { return "flaky" }
+
+func (f *failingProvider) Check(ctx context.Context, content *Content) (*Result, error) {
+	if f.failures > 0 {
+		f.failures--
+		return nil, errors.New("unavailable")
+	}
+	return &Result{Decision: DecisionAllow}, nil
+}
+
+func TestPipelineCheck(t *testing.T) {
+	keywords := NewKeywordProvider()
+	keywords.AddKeywords(DecisionReject, "spam")
+	store := NewMemoryStore()
+	pipeline := NewPipeline(keywords, &failingProvider{failures: 1})
+	pipeline.SetStore(store)
+	ctx := context.Background()
+
+	// 拒绝时提前结束，不调用后续服务
+	verdict, err := pipeline.Check(ctx, &Content{ID: "1", Type: "comment", UserID: "u1", Text: "buy spam"})
+	require.NoError(t, err)
+	assert.Equal(t, DecisionReject, verdict.Decision)
+	assert.Len(t, verdict.Results, 1)
+	assert.ErrorIs(t, verdict.Err(), ErrContentRejected)
+
+	// 服务失败时结论为 review
+	verdict, err = pipeline.Check(ctx, &Content{ID: "2", Type: "comment", Text: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, DecisionReview, verdict.Decision)
+	assert.Equal(t, "unavailable", verdict.Results[1].Error)
+	assert.False(t, verdict.Recheck)
+	assert.NoError(t, verdict.Err())
+
+	pipeline.SetFailOpen(true)
+	pipeline.providers[1] = &failingProvider{failures: 1}
+	verdict, err = pipeline.Check(ctx, &Content{ID: "3", Type: "comment", Text: "hello"})
+	require.NoError(t, err)
+	assert.True(t, verdict.Allowed())
+
+	records, err := store.ListRecords(ctx, "comment", "1")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, DecisionReject, records[0].Decision)
+	assert.Equal(t, "u1", records[0].UserID)
+	assert.Equal(t, "keyword", records[0].Providers)
+	assert.Equal(t, "keyword:spam", records[0].Reasons)
+}
+
+func TestPipelineRecheck(t *testing.T) {
+	mr := miniredis.RunT(t)
+	port, err := strconv.Atoi(mr.Port())
+	require.NoError(t, err)
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	require.NoError(t, err)
+	t.Cleanup(func() { cacheManager.Close() })
+
+	jobs := queue.New(cacheManager, "moderation")
+	jobs.SetPollInterval(10 * time.Millisecond)
+	jobs.SetBackoff(func(int) time.Duration { return 0 })
+
+	store := NewMemoryStore()
+	pipeline := NewPipeline(&failingProvider{failures: 2})
+	pipeline.SetStore(store)
+	pipeline.SetQueue(jobs, time.Millisecond)
+	rechecked := make(chan *Verdict, 1)
+	pipeline.OnRecheck(func(ctx context.Context, content *Content, verdict *Verdict) error {
+		assert.Equal(t, "42", content.ID)
+		rechecked <- verdict
+		return nil
+	})
+
+	ctx := context.Background()
+	verdict, err := pipeline.Check(ctx, &Content{ID: "42", Type: "post", Text: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, DecisionReview, verdict.Decision)
+	assert.True(t, verdict.Recheck)
+
+	// 第一次复审仍然失败，由队列重试
+	require.NoError(t, jobs.Start())
+	select {
+	case verdict := <-rechecked:
+		assert.True(t, verdict.Allowed())
+	case <-time.After(3 * time.Second):
+		t.Fatal("content was not rechecked")
+	}
+	require.NoError(t, jobs.Shutdown(ctx))
+
+	records, err := store.ListRecords(ctx, "post", "42")
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, DecisionAllow, records[0].Decision)
+	assert.True(t, records[0].Recheck)
+	assert.False(t, records[2].Recheck)
+}
@@ -0,0 +1,156 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultClient 外部审核服务默认HTTP客户端
+var defaultClient = &http.Client{Timeout: 5 * time.Second}
+
+// KeywordProvider 关键词审核，不区分大小写匹配文本中的关键词
+type KeywordProvider struct {
+	mu       sync.RWMutex
+	keywords map[string]Decision // 小写关键词到决定
+}
+
+// NewKeywordProvider 创建关键词审核服务
+func NewKeywordProvider() *KeywordProvider {
+	return &KeywordProvider{keywords: make(map[string]Decision)}
+}
+
+// Name 审核服务名称
+func (k *KeywordProvider) Name() string { return "keyword" }
+
+// AddKeywords 添加关键词，命中时给出 decision（review 或 reject），重复添加时覆盖
+func (k *KeywordProvider) AddKeywords(decision Decision, keywords ...string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, keyword := range keywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			k.keywords[keyword] = decision
+		}
+	}
+}
+
+// RemoveKeywords 删除关键词
+func (k *KeywordProvider) RemoveKeywords(keywords ...string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, keyword := range keywords {
+		delete(k.keywords, strings.ToLower(strings.TrimSpace(keyword)))
+	}
+}
+
+// Check 审核内容，原因格式为 keyword:<关键词>
+func (k *KeywordProvider) Check(ctx context.Context, content *Content) (*Result, error) {
+	text := strings.ToLower(content.Text)
+	result := &Result{Provider: k.Name(), Decision: DecisionAllow}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for keyword, decision := range k.keywords {
+		if !strings.Contains(text, keyword) {
+			continue
+		}
+		result.Reasons = append(result.Reasons, "keyword:"+keyword)
+		if decision.severity() > result.Decision.severity() {
+			result.Decision = decision
+		}
+	}
+	sort.Strings(result.Reasons)
+	return result, nil
+}
+
+// APIProvider 外部审核API，以JSON格式POST待审核内容
+// 默认要求响应为 {"decision":"allow|review|reject","reasons":[...],"score":0.9}，其他格式通过 ParseResponse 转换
+type APIProvider struct {
+	ProviderName  string
+	URL           string
+	Header        http.Header // 附加请求头，如认证信息
+	Client        *http.Client
+	BuildRequest  func(content *Content) interface{}             // 自定义请求体，默认发送 Content
+	ParseResponse func(status int, body []byte) (*Result, error) // 自定义响应解析
+}
+
+// Name 审核服务名称
+func (a *APIProvider) Name() string {
+	if a.ProviderName != "" {
+		return a.ProviderName
+	}
+	return "api"
+}
+
+// Check 调用外部审核API
+func (a *APIProvider) Check(ctx context.Context, content *Content) (*Result, error) {
+	client := a.Client
+	if client == nil {
+		client = defaultClient
+	}
+	var body interface{} = content
+	if a.BuildRequest != nil {
+		body = a.BuildRequest(content)
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal moderation request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range a.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("moderation request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result *Result
+	if a.ParseResponse != nil {
+		result, err = a.ParseResponse(resp.StatusCode, data)
+	} else {
+		result, err = parseAPIResponse(resp.StatusCode, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Provider = a.Name()
+	return result, nil
+}
+
+// parseAPIResponse 解析默认格式的审核响应
+func parseAPIResponse(status int, data []byte) (*Result, error) {
+	if status < 200 || status >= 300 {
+		return nil, fmt.Errorf("moderation api returned status %d: %s", status, strings.TrimSpace(string(data)))
+	}
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse moderation response: %w", err)
+	}
+	switch result.Decision {
+	case DecisionAllow, DecisionReview, DecisionReject:
+	default:
+		return nil, fmt.Errorf("unknown moderation decision %q", result.Decision)
+	}
+	return &result, nil
+}
@@ -0,0 +1,112 @@
+package moderation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/database"
+	"gorm.io/gorm"
+)
+
+// ModerationMigrationVersion 审核记录表迁移的版本号
+const ModerationMigrationVersion = "20241101000000"
+
+// Record 审核记录，每次审核（含异步复审）保存一条，用于审计和申诉处理
+type Record struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ContentID   string    `json:"content_id" gorm:"size:128;index:idx_moderation_records_content"`
+	ContentType string    `json:"content_type" gorm:"size:64;index:idx_moderation_records_content"`
+	UserID      string    `json:"user_id" gorm:"size:128;index"`
+	Decision    Decision  `json:"decision" gorm:"size:16;index"`
+	Providers   string    `json:"providers" gorm:"size:255"` // 参与审核的服务，逗号分隔
+	Reasons     string    `json:"reasons" gorm:"type:text"`  // 命中原因，逗号分隔
+	Recheck     bool      `json:"recheck"`                   // 是否为异步复审
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName 审核记录表名
+func (Record) TableName() string {
+	return "moderation_records"
+}
+
+// Store 审核记录存储
+type Store interface {
+	// SaveRecord 保存审核记录
+	SaveRecord(ctx context.Context, record *Record) error
+	// ListRecords 查询内容的审核记录，按时间倒序
+	ListRecords(ctx context.Context, contentType, contentID string) ([]*Record, error)
+}
+
+// RegisterMigrations 向迁移管理器注册审核记录表迁移
+func RegisterMigrations(migrator *database.Migrator) *database.Migrator {
+	return migrator.AddMigration(ModerationMigrationVersion, "create_moderation_records", func(db *gorm.DB) error {
+		return db.AutoMigrate(&Record{})
+	})
+}
+
+// GormStore 基于GORM的审核记录存储，表结构由 RegisterMigrations 创建
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 创建GORM审核记录存储
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+// SaveRecord 保存审核记录
+func (s *GormStore) SaveRecord(ctx context.Context, record *Record) error {
+	return s.db.WithContext(ctx).Create(record).Error
+}
+
+// ListRecords 查询内容的审核记录
+func (s *GormStore) ListRecords(ctx context.Context, contentType, contentID string) ([]*Record, error) {
+	var records []*Record
+	err := s.db.WithContext(ctx).Where("content_type = ? AND content_id = ?", contentType, contentID).
+		Order("created_at DESC, id DESC").Find(&records).Error
+	return records, err
+}
+
+// MemoryStore 内存审核记录存储，用于开发和测试
+type MemoryStore struct {
+	records []*Record
+	nextID  uint
+	mutex   sync.Mutex
+}
+
+// NewMemoryStore 创建内存审核记录存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// SaveRecord 保存审核记录
+func (s *MemoryStore) SaveRecord(ctx context.Context, record *Record) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.nextID++
+	record.ID = s.nextID
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	stored := *record
+	s.records = append(s.records, &stored)
+	return nil
+}
+
+// ListRecords 查询内容的审核记录
+func (s *MemoryStore) ListRecords(ctx context.Context, contentType, contentID string) ([]*Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var records []*Record
+	for i := len(s.records) - 1; i >= 0; i-- {
+		record := s.records[i]
+		if record.ContentType == contentType && record.ContentID == contentID {
+			found := *record
+			records = append(records, &found)
+		}
+	}
+	return records, nil
+}
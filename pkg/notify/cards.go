@@ -0,0 +1,186 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 消息格式
+const (
+	FormatText = "text" // 纯文本
+	FormatCard = "card" // 卡片：钉钉 Markdown/ActionCard、飞书消息卡片、企业微信 Markdown
+)
+
+// Markdown 将消息格式化为Markdown，换行使用空行以兼容钉钉
+func (m *Message) Markdown() string {
+	var b strings.Builder
+	if m.Title != "" {
+		fmt.Fprintf(&b, "### %s\n\n", m.Title)
+	}
+	b.WriteString(m.Text)
+	for _, key := range m.sortedFieldKeys() {
+		fmt.Fprintf(&b, "\n\n> **%s**: %s", key, m.Fields[key])
+	}
+	if !m.Time.IsZero() {
+		fmt.Fprintf(&b, "\n\n> **time**: %s", m.Time.Format("2006-01-02 15:04:05"))
+	}
+	if m.URL != "" {
+		fmt.Fprintf(&b, "\n\n[查看详情](%s)", m.URL)
+	}
+	return b.String()
+}
+
+// DingTalkText 钉钉文本消息，atMobiles 为需要@的手机号
+func DingTalkText(msg *Message, atMobiles []string, atAll bool) map[string]interface{} {
+	return map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": msg.PlainText()},
+		"at":      dingTalkAt(atMobiles, atAll),
+	}
+}
+
+// DingTalkMarkdown 钉钉Markdown消息，消息带有链接时使用ActionCard显示跳转按钮
+// 钉钉要求被@的手机号出现在Markdown正文中
+func DingTalkMarkdown(msg *Message, atMobiles []string, atAll bool) map[string]interface{} {
+	text := msg.Markdown()
+	for _, mobile := range atMobiles {
+		text += " @" + mobile
+	}
+
+	if msg.URL != "" {
+		return map[string]interface{}{
+			"msgtype": "actionCard",
+			"actionCard": map[string]string{
+				"title":       msg.Title,
+				"text":        text,
+				"singleTitle": "查看详情",
+				"singleURL":   msg.URL,
+			},
+		}
+	}
+	return map[string]interface{}{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"title": msg.Title, "text": text},
+		"at":       dingTalkAt(atMobiles, atAll),
+	}
+}
+
+// dingTalkAt 钉钉@设置
+func dingTalkAt(atMobiles []string, atAll bool) map[string]interface{} {
+	return map[string]interface{}{
+		"atMobiles": atMobiles,
+		"isAtAll":   atAll,
+	}
+}
+
+// FeishuText 飞书文本消息
+func FeishuText(msg *Message) map[string]interface{} {
+	return map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": msg.PlainText()},
+	}
+}
+
+// FeishuCard 飞书消息卡片，标题颜色随消息级别变化，附加信息以双列显示
+func FeishuCard(msg *Message) map[string]interface{} {
+	elements := []interface{}{
+		map[string]interface{}{
+			"tag":  "div",
+			"text": map[string]string{"tag": "lark_md", "content": msg.Text},
+		},
+	}
+
+	var fields []interface{}
+	for _, key := range msg.sortedFieldKeys() {
+		fields = append(fields, map[string]interface{}{
+			"is_short": true,
+			"text":     map[string]string{"tag": "lark_md", "content": fmt.Sprintf("**%s**\n%s", key, msg.Fields[key])},
+		})
+	}
+	if len(fields) > 0 {
+		elements = append(elements, map[string]interface{}{"tag": "div", "fields": fields})
+	}
+	if !msg.Time.IsZero() {
+		elements = append(elements, map[string]interface{}{
+			"tag":      "note",
+			"elements": []interface{}{map[string]string{"tag": "plain_text", "content": msg.Time.Format("2006-01-02 15:04:05")}},
+		})
+	}
+	if msg.URL != "" {
+		elements = append(elements, map[string]interface{}{
+			"tag": "action",
+			"actions": []interface{}{map[string]interface{}{
+				"tag":  "button",
+				"text": map[string]string{"tag": "plain_text", "content": "查看详情"},
+				"url":  msg.URL,
+				"type": "primary",
+			}},
+		})
+	}
+
+	return map[string]interface{}{
+		"msg_type": "interactive",
+		"card": map[string]interface{}{
+			"header": map[string]interface{}{
+				"title":    map[string]string{"tag": "plain_text", "content": msg.Title},
+				"template": feishuTemplate(msg.Level),
+			},
+			"elements": elements,
+		},
+	}
+}
+
+// feishuTemplate 消息级别对应的飞书卡片标题颜色
+func feishuTemplate(level string) string {
+	switch level {
+	case LevelCritical, LevelError:
+		return "red"
+	case LevelWarning:
+		return "orange"
+	default:
+		return "blue"
+	}
+}
+
+// WeComText 企业微信文本消息，mentionedMobiles 为需要@的手机号，"@all" 表示所有人
+func WeComText(msg *Message, mentionedMobiles []string) map[string]interface{} {
+	return map[string]interface{}{
+		"msgtype": "text",
+		"text": map[string]interface{}{
+			"content":               msg.PlainText(),
+			"mentioned_mobile_list": mentionedMobiles,
+		},
+	}
+}
+
+// WeComMarkdown 企业微信Markdown消息，标题按消息级别着色
+// 企业微信Markdown消息不支持@手机号
+func WeComMarkdown(msg *Message) map[string]interface{} {
+	color := "comment"
+	switch msg.Level {
+	case LevelCritical, LevelError:
+		color = "warning"
+	case LevelInfo:
+		color = "info"
+	}
+
+	var b strings.Builder
+	if msg.Title != "" {
+		fmt.Fprintf(&b, "**<font color=\"%s\">%s</font>**\n", color, msg.Title)
+	}
+	b.WriteString(msg.Text)
+	for _, key := range msg.sortedFieldKeys() {
+		fmt.Fprintf(&b, "\n> %s: <font color=\"comment\">%s</font>", key, msg.Fields[key])
+	}
+	if !msg.Time.IsZero() {
+		fmt.Fprintf(&b, "\n> time: <font color=\"comment\">%s</font>", msg.Time.Format("2006-01-02 15:04:05"))
+	}
+	if msg.URL != "" {
+		fmt.Fprintf(&b, "\n[查看详情](%s)", msg.URL)
+	}
+
+	return map[string]interface{}{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"content": b.String()},
+	}
+}
@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Webhook 通用Webhook渠道，以JSON格式POST完整消息
+type Webhook struct {
+	URL    string
+	Header http.Header // 附加请求头，如认证信息
+	Client *http.Client
+}
+
+// Name 渠道名称
+func (w *Webhook) Name() string { return "webhook" }
+
+// Send 发送消息
+func (w *Webhook) Send(ctx context.Context, msg *Message) error {
+	return postJSON(ctx, w.Client, w.URL, w.Header, msg, nil)
+}
+
+// Slack Slack Incoming Webhook 渠道
+type Slack struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+// Name 渠道名称
+func (s *Slack) Name() string { return "slack" }
+
+// Send 发送消息
+func (s *Slack) Send(ctx context.Context, msg *Message) error {
+	return postJSON(ctx, s.Client, s.WebhookURL, nil, map[string]string{"text": msg.PlainText()}, nil)
+}
+
+// DingTalk 钉钉自定义机器人渠道
+type DingTalk struct {
+	WebhookURL string
+	Secret     string   // 加签密钥，机器人安全设置为"加签"时必填
+	Format     string   // 消息格式：FormatText（默认）或 FormatCard
+	AtMobiles  []string // 需要@的成员手机号
+	AtAll      bool     // 是否@所有人
+	Client     *http.Client
+}
+
+// Name 渠道名称
+func (d *DingTalk) Name() string { return "dingtalk" }
+
+// Send 发送消息
+func (d *DingTalk) Send(ctx context.Context, msg *Message) error {
+	webhookURL := d.WebhookURL
+	if d.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		mac := hmac.New(sha256.New, []byte(d.Secret))
+		mac.Write([]byte(timestamp + "\n" + d.Secret))
+		sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+		u, err := url.Parse(webhookURL)
+		if err != nil {
+			return fmt.Errorf("invalid dingtalk webhook URL: %w", err)
+		}
+		query := u.Query()
+		query.Set("timestamp", timestamp)
+		query.Set("sign", sign)
+		u.RawQuery = query.Encode()
+		webhookURL = u.String()
+	}
+
+	body := DingTalkText(msg, d.AtMobiles, d.AtAll)
+	if d.Format == FormatCard {
+		body = DingTalkMarkdown(msg, d.AtMobiles, d.AtAll)
+	}
+	return postErrCode(ctx, d.Client, webhookURL, body, "dingtalk")
+}
+
+// Feishu 飞书自定义机器人渠道
+type Feishu struct {
+	WebhookURL string
+	Secret     string // 签名校验密钥，机器人开启签名校验时必填
+	Format     string // 消息格式：FormatText（默认）或 FormatCard
+	Client     *http.Client
+}
+
+// Name 渠道名称
+func (f *Feishu) Name() string { return "feishu" }
+
+// Send 发送消息
+func (f *Feishu) Send(ctx context.Context, msg *Message) error {
+	body := FeishuText(msg)
+	if f.Format == FormatCard {
+		body = FeishuCard(msg)
+	}
+	if f.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		// 飞书以 timestamp+"\n"+secret 作为密钥对空串签名
+		mac := hmac.New(sha256.New, []byte(timestamp+"\n"+f.Secret))
+		body["timestamp"] = timestamp
+		body["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := postJSON(ctx, f.Client, f.WebhookURL, nil, body, &result); err != nil {
+		return err
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("feishu error %d: %s", result.Code, result.Msg)
+	}
+	return nil
+}
+
+// WeCom 企业微信群机器人渠道
+type WeCom struct {
+	WebhookURL       string
+	Format           string   // 消息格式：FormatText（默认）或 FormatCard
+	MentionedMobiles []string // 需要@的成员手机号，"@all" 表示所有人，仅文本消息支持
+	Client           *http.Client
+}
+
+// Name 渠道名称
+func (w *WeCom) Name() string { return "wecom" }
+
+// Send 发送消息
+func (w *WeCom) Send(ctx context.Context, msg *Message) error {
+	body := WeComText(msg, w.MentionedMobiles)
+	if w.Format == FormatCard {
+		body = WeComMarkdown(msg)
+	}
+	return postErrCode(ctx, w.Client, w.WebhookURL, body, "wecom")
+}
+
+// postErrCode 发送消息并检查钉钉、企业微信风格的 errcode 响应
+func postErrCode(ctx context.Context, client *http.Client, webhookURL string, body interface{}, channel string) error {
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := postJSON(ctx, client, webhookURL, nil, body, &result); err != nil {
+		return err
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("%s error %d: %s", channel, result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
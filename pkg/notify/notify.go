@@ -0,0 +1,138 @@
+// Package notify 提供告警和通知的发送渠道（Webhook、Slack、钉钉、飞书、企业微信等）
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 消息级别
+const (
+	LevelInfo     = "info"
+	LevelWarning  = "warning"
+	LevelError    = "error"
+	LevelCritical = "critical"
+)
+
+// Message 通知消息
+type Message struct {
+	Title  string            `json:"title"`
+	Text   string            `json:"text"`
+	Level  string            `json:"level,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"` // 附加信息，如服务名、主机、trace_id
+	URL    string            `json:"url,omitempty"`    // 详情链接，卡片消息中显示为按钮
+	Time   time.Time         `json:"time"`
+}
+
+// Notifier 通知渠道
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, msg *Message) error
+}
+
+// defaultClient 默认HTTP客户端
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// PlainText 将消息格式化为纯文本，附加信息按键排序
+func (m *Message) PlainText() string {
+	var b strings.Builder
+	if m.Title != "" {
+		b.WriteString(m.Title)
+		b.WriteString("\n")
+	}
+	b.WriteString(m.Text)
+	for _, key := range m.sortedFieldKeys() {
+		fmt.Fprintf(&b, "\n%s: %s", key, m.Fields[key])
+	}
+	if !m.Time.IsZero() {
+		fmt.Fprintf(&b, "\ntime: %s", m.Time.Format(time.RFC3339))
+	}
+	if m.URL != "" {
+		fmt.Fprintf(&b, "\n%s", m.URL)
+	}
+	return b.String()
+}
+
+// sortedFieldKeys 返回排序后的附加信息键
+func (m *Message) sortedFieldKeys() []string {
+	keys := make([]string, 0, len(m.Fields))
+	for key := range m.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Multi 组合多个通知渠道，依次发送并汇总错误
+type Multi []Notifier
+
+// Name 渠道名称
+func (m Multi) Name() string {
+	names := make([]string, len(m))
+	for i, n := range m {
+		names[i] = n.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// Send 向所有渠道发送消息，单个渠道失败不影响其他渠道
+func (m Multi) Send(ctx context.Context, msg *Message) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Send(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// postJSON 发送JSON请求，result 不为空时解析响应
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, body, result interface{}) error {
+	if client == nil {
+		client = defaultClient
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification rejected with status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if result != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, result); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+	return nil
+}
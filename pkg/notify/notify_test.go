@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRecorder 创建记录请求体的测试服务器
+func newRecorder(t *testing.T, response string) (*httptest.Server, *[]map[string]interface{}, *[]string) {
+	var bodies []map[string]interface{}
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies = append(bodies, body)
+		queries = append(queries, r.URL.RawQuery)
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies, &queries
+}
+
+func TestMessagePlainText(t *testing.T) {
+	msg := &Message{
+		Title:  "DB down",
+		Text:   "connection refused",
+		Fields: map[string]string{"service": "api", "host": "web-1"},
+		Time:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	assert.Equal(t, "DB down\nconnection refused\nhost: web-1\nservice: api\ntime: 2024-01-01T00:00:00Z", msg.PlainText())
+}
+
+func TestChannels(t *testing.T) {
+	ctx := context.Background()
+	msg := &Message{Title: "alert", Text: "boom"}
+
+	webhook, webhookBodies, _ := newRecorder(t, "")
+	require.NoError(t, (&Webhook{URL: webhook.URL}).Send(ctx, msg))
+	assert.Equal(t, "boom", (*webhookBodies)[0]["text"])
+
+	slack, slackBodies, _ := newRecorder(t, "ok")
+	require.NoError(t, (&Slack{WebhookURL: slack.URL}).Send(ctx, msg))
+	assert.Equal(t, "alert\nboom", (*slackBodies)[0]["text"])
+
+	dingtalk, dingtalkBodies, dingtalkQueries := newRecorder(t, `{"errcode":0,"errmsg":"ok"}`)
+	require.NoError(t, (&DingTalk{WebhookURL: dingtalk.URL + "?access_token=x", Secret: "SEC"}).Send(ctx, msg))
+	assert.Equal(t, "text", (*dingtalkBodies)[0]["msgtype"])
+	assert.Contains(t, (*dingtalkQueries)[0], "access_token=x")
+	assert.Contains(t, (*dingtalkQueries)[0], "sign=")
+
+	feishu, feishuBodies, _ := newRecorder(t, `{"code":0}`)
+	require.NoError(t, (&Feishu{WebhookURL: feishu.URL, Secret: "SEC"}).Send(ctx, msg))
+	assert.Equal(t, "text", (*feishuBodies)[0]["msg_type"])
+	assert.NotEmpty(t, (*feishuBodies)[0]["sign"])
+
+	// 业务错误码视为发送失败
+	rejected, _, _ := newRecorder(t, `{"code":19021,"msg":"sign match fail"}`)
+	assert.Error(t, (&Feishu{WebhookURL: rejected.URL}).Send(ctx, msg))
+
+	// 多渠道发送时汇总错误
+	err := Multi{&Slack{WebhookURL: slack.URL}, &Feishu{WebhookURL: rejected.URL}}.Send(ctx, msg)
+	assert.ErrorContains(t, err, "feishu")
+	assert.Len(t, *slackBodies, 2)
+}
+
+func TestCards(t *testing.T) {
+	msg := &Message{
+		Title:  "DB down",
+		Text:   "connection refused",
+		Level:  LevelError,
+		Fields: map[string]string{"host": "web-1"},
+		URL:    "https://grafana.example.com/d/db",
+	}
+
+	markdown := msg.Markdown()
+	assert.Contains(t, markdown, "### DB down")
+	assert.Contains(t, markdown, "> **host**: web-1")
+	assert.Contains(t, markdown, "[查看详情](https://grafana.example.com/d/db)")
+
+	// 带链接时使用ActionCard
+	assert.Equal(t, "actionCard", DingTalkMarkdown(msg, nil, false)["msgtype"])
+	plain := *msg
+	plain.URL = ""
+	dingtalk := DingTalkMarkdown(&plain, []string{"13800000000"}, false)
+	assert.Equal(t, "markdown", dingtalk["msgtype"])
+	assert.Contains(t, dingtalk["markdown"].(map[string]string)["text"], "@13800000000")
+
+	card := FeishuCard(msg)["card"].(map[string]interface{})
+	assert.Equal(t, "red", card["header"].(map[string]interface{})["template"])
+	assert.Len(t, card["elements"], 3)
+
+	wecom := WeComMarkdown(msg)["markdown"].(map[string]string)["content"]
+	assert.Contains(t, wecom, `<font color="warning">DB down</font>`)
+	assert.Contains(t, wecom, "host: <font color=\"comment\">web-1</font>")
+}
+
+func TestWeCom(t *testing.T) {
+	ctx := context.Background()
+	msg := &Message{Title: "alert", Text: "boom"}
+
+	server, bodies, _ := newRecorder(t, `{"errcode":0,"errmsg":"ok"}`)
+	require.NoError(t, (&WeCom{WebhookURL: server.URL, MentionedMobiles: []string{"@all"}}).Send(ctx, msg))
+	require.NoError(t, (&WeCom{WebhookURL: server.URL, Format: FormatCard}).Send(ctx, msg))
+	assert.Equal(t, "text", (*bodies)[0]["msgtype"])
+	assert.Equal(t, []interface{}{"@all"}, (*bodies)[0]["text"].(map[string]interface{})["mentioned_mobile_list"])
+	assert.Equal(t, "markdown", (*bodies)[1]["msgtype"])
+
+	rejected, _, _ := newRecorder(t, `{"errcode":93000,"errmsg":"invalid webhook url"}`)
+	assert.ErrorContains(t, (&WeCom{WebhookURL: rejected.URL}).Send(ctx, msg), "93000")
+}
@@ -0,0 +1,156 @@
+package org
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/auth"
+	"github.com/hwh/hwhkit-go/pkg/database"
+)
+
+var (
+	// ErrOrganizationNotFound 组织不存在
+	ErrOrganizationNotFound = errors.New("organization not found")
+	// ErrSlugTaken 组织标识已被占用
+	ErrSlugTaken = errors.New("organization slug already taken")
+	// ErrInvalidName 组织名称为空
+	ErrInvalidName = errors.New("organization name cannot be empty")
+	// ErrInvalidEmail 邀请邮箱格式无效
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrInvalidSlug 组织标识格式无效或为保留词
+	ErrInvalidSlug = errors.New("invalid organization slug")
+	// ErrNotMember 用户不是组织成员
+	ErrNotMember = errors.New("user is not a member of the organization")
+	// ErrLastOwner 不能移除或降级组织的最后一个所有者
+	ErrLastOwner = errors.New("organization must keep at least one owner")
+	// ErrUnknownRole 角色不是组织角色或未在RBAC中定义
+	ErrUnknownRole = errors.New("unknown organization role")
+	// ErrInvitationNotFound 邀请不存在、已被撤销或已被接受
+	ErrInvitationNotFound = errors.New("invitation not found")
+	// ErrInvitationExpired 邀请已过期
+	ErrInvitationExpired = errors.New("invitation has expired")
+	// ErrInvitationEmailMismatch 接受邀请的用户邮箱与被邀请邮箱不一致
+	ErrInvitationEmailMismatch = errors.New("invitation was sent to another email address")
+)
+
+// 内置组织角色，角色和权限定义在RBAC中，由 RegisterDefaultRoles 创建
+const (
+	RoleOwner  = "org_owner"
+	RoleAdmin  = "org_admin"
+	RoleMember = "org_member"
+)
+
+// 内置组织资源，应用可以为自定义角色添加其他资源的权限
+const (
+	ResourceOrg         = "org"             // 组织本身：read、update、delete
+	ResourceMembers     = "org.members"     // 成员：read、update、delete
+	ResourceInvitations = "org.invitations" // 邀请：read、create、delete
+)
+
+// Organization 组织
+type Organization struct {
+	database.BaseModel
+	Name      string `json:"name" gorm:"size:255"`
+	Slug      string `json:"slug" gorm:"size:128;uniqueIndex"`
+	CreatedBy string `json:"created_by" gorm:"size:128"`
+}
+
+// TableName 组织表名
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// Membership 组织成员关系，Roles 为组织内的角色，只在该组织内生效
+type Membership struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	OrgID     uint      `json:"org_id" gorm:"uniqueIndex:idx_org_memberships_user"`
+	UserID    string    `json:"user_id" gorm:"size:128;uniqueIndex:idx_org_memberships_user;index"`
+	Roles     []string  `json:"roles" gorm:"serializer:json;type:text"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 成员关系表名
+func (Membership) TableName() string {
+	return "org_memberships"
+}
+
+// HasRole 是否拥有任一角色
+func (m *Membership) HasRole(roles ...string) bool {
+	for _, have := range m.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Invitation 组织邀请，只保存令牌的SHA-256摘要，明文令牌只在创建时通过邮件发送
+type Invitation struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	OrgID      uint       `json:"org_id" gorm:"index"`
+	Email      string     `json:"email" gorm:"size:255;index"`
+	Roles      []string   `json:"roles" gorm:"serializer:json;type:text"`
+	TokenHash  string     `json:"-" gorm:"size:64;uniqueIndex"`
+	InvitedBy  string     `json:"invited_by" gorm:"size:128"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	AcceptedBy string     `json:"accepted_by,omitempty" gorm:"size:128"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName 邀请表名
+func (Invitation) TableName() string {
+	return "org_invitations"
+}
+
+// Pending 邀请是否仍可接受
+func (i *Invitation) Pending() bool {
+	return i.AcceptedAt == nil && time.Now().Before(i.ExpiresAt)
+}
+
+// RegisterDefaultRoles 在RBAC中创建内置组织角色和权限：
+// 所有者拥有全部组织权限，管理员可以修改组织、管理成员和邀请，成员只能查看组织和成员列表
+func RegisterDefaultRoles(rbac *auth.RBAC) error {
+	permissions := []*auth.Permission{
+		{ID: "org.all", Name: "组织全部权限", Resource: ResourceOrg, Action: "*"},
+		{ID: "org.manage", Name: "管理组织成员和邀请", Resource: "org.*", Action: "*"},
+		{ID: "org.read", Name: "查看组织", Resource: ResourceOrg, Action: "read"},
+		{ID: "org.update", Name: "修改组织", Resource: ResourceOrg, Action: "update"},
+		{ID: "org.members.read", Name: "查看组织成员", Resource: ResourceMembers, Action: "read"},
+	}
+	for _, permission := range permissions {
+		if err := rbac.AddPermission(permission); err != nil {
+			return err
+		}
+	}
+
+	roles := []*auth.Role{
+		{
+			ID:          RoleOwner,
+			Name:        "组织所有者",
+			Description: "拥有组织的全部权限，可以删除组织和授予所有者角色",
+			Permissions: []auth.Permission{*permissions[0], *permissions[1]},
+		},
+		{
+			ID:          RoleAdmin,
+			Name:        "组织管理员",
+			Description: "可以修改组织信息、管理成员和邀请",
+			Permissions: []auth.Permission{*permissions[2], *permissions[3], *permissions[1]},
+		},
+		{
+			ID:          RoleMember,
+			Name:        "组织成员",
+			Description: "可以查看组织和成员列表",
+			Permissions: []auth.Permission{*permissions[2], *permissions[4]},
+		},
+	}
+	for _, role := range roles {
+		if err := rbac.AddRole(role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
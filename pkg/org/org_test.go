@@ -0,0 +1,303 @@
+package org
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newTestService(t *testing.T) *Service {
+	rbac := auth.NewRBAC()
+	require.NoError(t, RegisterDefaultRoles(rbac))
+	require.NoError(t, rbac.AddRole(&auth.Role{
+		ID:          "org_billing",
+		Name:        "账单管理员",
+		Permissions: []auth.Permission{{ID: "invoice.all", Name: "账单", Resource: "invoice", Action: "*"}},
+	}))
+	service := New(NewMemoryStore(), rbac)
+	service.AllowRoles("org_billing")
+	return service
+}
+
+func TestOrganizationLifecycle(t *testing.T) {
+	ctx := context.Background()
+	service := newTestService(t)
+
+	acme, err := service.CreateOrganization(ctx, "Acme Inc", "", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "acme-inc", acme.Slug)
+
+	// 重名时追加后缀，纯数字和保留词标识无效
+	second, err := service.CreateOrganization(ctx, "Acme Inc", "", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "acme-inc-2", second.Slug)
+	numeric, err := service.CreateOrganization(ctx, "2024", "", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "org-2024", numeric.Slug)
+	_, err = service.CreateOrganization(ctx, "Acme", "acme-inc", "bob")
+	assert.ErrorIs(t, err, ErrSlugTaken)
+	_, err = service.CreateOrganization(ctx, "Acme", "Not A Slug", "bob")
+	assert.ErrorIs(t, err, ErrInvalidSlug)
+	_, err = service.CreateOrganization(ctx, "Acme", "admin", "bob")
+	assert.ErrorIs(t, err, ErrInvalidSlug)
+	_, err = service.CreateOrganization(ctx, " ", "", "bob")
+	assert.ErrorIs(t, err, ErrInvalidName)
+
+	// 按ID或标识解析
+	found, err := service.ResolveOrganization(ctx, "acme-inc")
+	require.NoError(t, err)
+	assert.Equal(t, acme.ID, found.ID)
+	found, err = service.ResolveOrganization(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, acme.ID, found.ID)
+	_, err = service.ResolveOrganization(ctx, "missing")
+	assert.ErrorIs(t, err, ErrOrganizationNotFound)
+
+	updated, err := service.UpdateOrganization(ctx, acme.ID, "Acme Corp", "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "Acme Corp", updated.Name)
+	assert.Equal(t, "acme", updated.Slug)
+
+	orgs, err := service.ListUserOrganizations(ctx, "bob")
+	require.NoError(t, err)
+	assert.Len(t, orgs, 2)
+
+	require.NoError(t, service.DeleteOrganization(ctx, acme.ID))
+	_, err = service.GetMembership(ctx, acme.ID, "alice")
+	assert.ErrorIs(t, err, ErrNotMember)
+	orgs, err = service.ListUserOrganizations(ctx, "alice")
+	require.NoError(t, err)
+	assert.Empty(t, orgs)
+}
+
+func TestMembersAndScopedRoles(t *testing.T) {
+	ctx := context.Background()
+	service := newTestService(t)
+
+	acme, err := service.CreateOrganization(ctx, "Acme", "", "alice")
+	require.NoError(t, err)
+	other, err := service.CreateOrganization(ctx, "Other", "", "bob")
+	require.NoError(t, err)
+
+	member, err := service.AddMember(ctx, acme.ID, "bob", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{RoleMember}, member.Roles)
+	_, err = service.AddMember(ctx, acme.ID, "carol", []string{"missing"})
+	assert.ErrorIs(t, err, ErrUnknownRole)
+	// 全局角色即使在RBAC中存在也不能作为组织角色授予
+	require.NoError(t, service.rbac.AddRole(&auth.Role{ID: "admin", Name: "管理员"}))
+	_, err = service.AddMember(ctx, acme.ID, "carol", []string{"admin"})
+	assert.ErrorIs(t, err, ErrUnknownRole)
+	_, _, err = service.Invite(ctx, acme.ID, "carol@example.com", []string{"admin"}, "alice")
+	assert.ErrorIs(t, err, ErrUnknownRole)
+	assert.False(t, service.MembershipHasPermission(&Membership{Roles: []string{"admin"}}, ResourceOrg, "read"))
+
+	// 角色只在所属组织内生效
+	check := func(orgID uint, userID, resource, action string) bool {
+		allowed, err := service.HasPermission(ctx, orgID, userID, resource, action)
+		require.NoError(t, err)
+		return allowed
+	}
+	assert.True(t, check(acme.ID, "alice", ResourceOrg, "delete"))
+	assert.True(t, check(acme.ID, "alice", ResourceInvitations, "create"))
+	assert.True(t, check(acme.ID, "bob", ResourceMembers, "read"))
+	assert.False(t, check(acme.ID, "bob", ResourceMembers, "update"))
+	assert.False(t, check(acme.ID, "bob", ResourceOrg, "update"))
+	assert.True(t, check(other.ID, "bob", ResourceOrg, "delete"))
+	assert.False(t, check(other.ID, "alice", ResourceOrg, "read"))
+
+	_, err = service.UpdateMemberRoles(ctx, acme.ID, "bob", []string{RoleAdmin, "org_billing", RoleAdmin})
+	require.NoError(t, err)
+	assert.True(t, check(acme.ID, "bob", ResourceOrg, "update"))
+	assert.True(t, check(acme.ID, "bob", ResourceMembers, "delete"))
+	assert.False(t, check(acme.ID, "bob", ResourceOrg, "delete"))
+	assert.True(t, check(acme.ID, "bob", "invoice:42", "refund"))
+	assert.False(t, check(other.ID, "bob", "invoice:42", "refund"))
+	_, err = service.UpdateMemberRoles(ctx, acme.ID, "carol", nil)
+	assert.ErrorIs(t, err, ErrNotMember)
+
+	// 最后一个所有者不能被降级或移除
+	_, err = service.UpdateMemberRoles(ctx, acme.ID, "alice", []string{RoleAdmin})
+	assert.ErrorIs(t, err, ErrLastOwner)
+	assert.ErrorIs(t, service.RemoveMember(ctx, acme.ID, "alice"), ErrLastOwner)
+	_, err = service.UpdateMemberRoles(ctx, acme.ID, "bob", []string{RoleOwner})
+	require.NoError(t, err)
+	require.NoError(t, service.RemoveMember(ctx, acme.ID, "alice"))
+	assert.ErrorIs(t, service.RemoveMember(ctx, acme.ID, "alice"), ErrNotMember)
+
+	members, err := service.ListMembers(ctx, acme.ID)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, "bob", members[0].UserID)
+}
+
+func TestInvitations(t *testing.T) {
+	ctx := context.Background()
+	service := newTestService(t)
+
+	var sent []string
+	service.SetInvitationSender(InvitationSenderFunc(func(ctx context.Context, org *Organization, invitation *Invitation, token string) error {
+		if invitation.Email == "fail@example.com" {
+			return errors.New("smtp unavailable")
+		}
+		sent = append(sent, token)
+		return nil
+	}))
+
+	acme, err := service.CreateOrganization(ctx, "Acme", "", "alice")
+	require.NoError(t, err)
+
+	invitation, token, err := service.Invite(ctx, acme.ID, " Carol@Example.com ", []string{RoleAdmin}, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "carol@example.com", invitation.Email)
+	assert.Equal(t, []string{token}, sent)
+	assert.NotEqual(t, token, invitation.TokenHash)
+	assert.True(t, invitation.Pending())
+
+	_, _, err = service.Invite(ctx, acme.ID, "not-an-email", nil, "alice")
+	assert.ErrorIs(t, err, ErrInvalidEmail)
+	_, _, err = service.Invite(ctx, acme.ID, "fail@example.com", nil, "alice")
+	assert.Error(t, err)
+	pending, err := service.ListInvitations(ctx, acme.ID)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1, "failed deliveries should not leave pending invitations")
+
+	// 邮箱不一致、令牌错误都不能接受
+	_, err = service.AcceptInvitation(ctx, token, "mallory", "mallory@example.com")
+	assert.ErrorIs(t, err, ErrInvitationEmailMismatch)
+	_, err = service.AcceptInvitation(ctx, "wrong-token", "carol", "carol@example.com")
+	assert.ErrorIs(t, err, ErrInvitationNotFound)
+
+	membership, err := service.AcceptInvitation(ctx, token, "carol", "CAROL@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{RoleAdmin}, membership.Roles)
+	_, err = service.AcceptInvitation(ctx, token, "carol", "carol@example.com")
+	assert.ErrorIs(t, err, ErrInvitationNotFound, "invitations are single use")
+	pending, err = service.ListInvitations(ctx, acme.ID)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// 已是成员时合并角色
+	_, token, err = service.Invite(ctx, acme.ID, "carol@example.com", []string{"org_billing"}, "alice")
+	require.NoError(t, err)
+	membership, err = service.AcceptInvitation(ctx, token, "carol", "carol@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{RoleAdmin, "org_billing"}, membership.Roles)
+
+	// 过期和撤销
+	service.SetInvitationTTL(time.Millisecond)
+	_, token, err = service.Invite(ctx, acme.ID, "dave@example.com", nil, "alice")
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	_, err = service.AcceptInvitation(ctx, token, "dave", "dave@example.com")
+	assert.ErrorIs(t, err, ErrInvitationExpired)
+
+	service.SetInvitationTTL(time.Hour)
+	invitation, token, err = service.Invite(ctx, acme.ID, "erin@example.com", nil, "alice")
+	require.NoError(t, err)
+	require.NoError(t, service.RevokeInvitation(ctx, acme.ID, invitation.ID))
+	assert.ErrorIs(t, service.RevokeInvitation(ctx, acme.ID, invitation.ID), ErrInvitationNotFound)
+	_, err = service.AcceptInvitation(ctx, token, "erin", "erin@example.com")
+	assert.ErrorIs(t, err, ErrInvitationNotFound)
+}
+
+// recordingPool 记录事务边界的连接池，配合试运行模式测试 GormStore
+type recordingPool struct {
+	events *[]string
+}
+
+func (p *recordingPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *recordingPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *recordingPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *recordingPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *recordingPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	*p.events = append(*p.events, "BEGIN")
+	return &recordingTx{recordingPool: p}, nil
+}
+
+// recordingTx 记录提交和回滚的事务
+type recordingTx struct {
+	*recordingPool
+}
+
+func (t *recordingTx) Commit() error {
+	*t.events = append(*t.events, "COMMIT")
+	return nil
+}
+
+func (t *recordingTx) Rollback() error {
+	*t.events = append(*t.events, "ROLLBACK")
+	return nil
+}
+
+func TestGormStore(t *testing.T) {
+	var events []string
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: &recordingPool{events: &events}}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	capture := func(tx *gorm.DB) {
+		events = append(events, tx.Statement.SQL.String())
+	}
+	db.Callback().Create().After("gorm:create").Register("test:capture", capture)
+	db.Callback().Query().After("gorm:query").Register("test:capture", capture)
+	db.Callback().Delete().After("gorm:delete").Register("test:capture", capture)
+	store := NewGormStore(db)
+	ctx := context.Background()
+
+	// 组织和所有者在同一事务中创建
+	require.NoError(t, store.CreateOrganization(ctx, &Organization{Name: "Acme", Slug: "acme"}, &Membership{UserID: "alice", Roles: []string{RoleOwner}}))
+	require.Len(t, events, 4)
+	assert.Equal(t, "BEGIN", events[0])
+	assert.Contains(t, events[1], `INSERT INTO "organizations"`)
+	assert.Contains(t, events[2], `INSERT INTO "org_memberships"`)
+	assert.Equal(t, "COMMIT", events[3])
+
+	// 已删除组织的标识仍占用唯一索引，检查时不能过滤软删除记录
+	events = nil
+	taken, err := store.SlugTaken(ctx, "acme", 3)
+	require.NoError(t, err)
+	assert.False(t, taken)
+	_, err = store.GetOrganizationBySlug(ctx, "acme")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Contains(t, events[0], `WHERE slug = $1 AND id <> $2`)
+	assert.NotContains(t, events[0], "deleted_at")
+	assert.Contains(t, events[1], `"organizations"."deleted_at" IS NULL`)
+
+	// 成员关系按组织和用户更新角色
+	events = nil
+	require.NoError(t, store.SaveMembership(ctx, &Membership{OrgID: 1, UserID: "bob", Roles: []string{RoleMember}}))
+	require.Len(t, events, 1)
+	assert.Contains(t, events[0], `ON CONFLICT ("org_id","user_id") DO UPDATE SET "roles"="excluded"."roles"`)
+
+	// 组织不存在时回滚，不删除成员关系和邀请
+	events = nil
+	assert.ErrorIs(t, store.DeleteOrganization(ctx, 1), ErrOrganizationNotFound)
+	require.Len(t, events, 3)
+	assert.True(t, strings.HasPrefix(events[1], `UPDATE "organizations" SET "deleted_at"`), events[1])
+	assert.Equal(t, "ROLLBACK", events[2])
+}
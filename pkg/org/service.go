@@ -0,0 +1,421 @@
+package org
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/auth"
+	"github.com/hwh/hwhkit-go/pkg/utils"
+)
+
+// DefaultInvitationTTL 邀请默认有效期
+const DefaultInvitationTTL = 7 * 24 * time.Hour
+
+// InvitationSender 发送邀请邮件，token 为明文邀请令牌，只在发送时可见
+type InvitationSender interface {
+	SendInvitation(ctx context.Context, org *Organization, invitation *Invitation, token string) error
+}
+
+// InvitationSenderFunc 函数形式的邀请发送器
+type InvitationSenderFunc func(ctx context.Context, org *Organization, invitation *Invitation, token string) error
+
+// SendInvitation 发送邀请
+func (f InvitationSenderFunc) SendInvitation(ctx context.Context, org *Organization, invitation *Invitation, token string) error {
+	return f(ctx, org, invitation, token)
+}
+
+// Service 组织服务，管理组织、成员和邀请，并按成员的组织内角色进行权限检查
+// 组织角色就是RBAC中定义的角色，但不分配给用户全局生效，只通过成员关系在所属组织内生效
+type Service struct {
+	store         Store
+	rbac          *auth.RBAC
+	sender        InvitationSender
+	invitationTTL time.Duration
+	roles         map[string]bool
+}
+
+// New 创建组织服务，rbac 中需已定义组织角色（见 RegisterDefaultRoles）
+func New(store Store, rbac *auth.RBAC) *Service {
+	return &Service{
+		store:         store,
+		rbac:          rbac,
+		invitationTTL: DefaultInvitationTTL,
+		roles:         map[string]bool{RoleOwner: true, RoleAdmin: true, RoleMember: true},
+	}
+}
+
+// AllowRoles 将自定义RBAC角色登记为组织角色，只有组织角色可以分配给成员或用于邀请
+// 默认只允许内置的 org_owner、org_admin、org_member，避免把 admin 等全局角色授予组织成员
+func (s *Service) AllowRoles(roles ...string) {
+	for _, role := range roles {
+		s.roles[role] = true
+	}
+}
+
+// SetInvitationSender 设置邀请邮件发送器，未设置时由调用方自行投递 Invite 返回的令牌
+func (s *Service) SetInvitationSender(sender InvitationSender) {
+	s.sender = sender
+}
+
+// HasInvitationSender 是否设置了邀请邮件发送器
+func (s *Service) HasInvitationSender() bool {
+	return s.sender != nil
+}
+
+// SetInvitationTTL 设置邀请有效期
+func (s *Service) SetInvitationTTL(ttl time.Duration) {
+	if ttl > 0 {
+		s.invitationTTL = ttl
+	}
+}
+
+// Store 获取组织存储
+func (s *Service) Store() Store {
+	return s.store
+}
+
+// CreateOrganization 创建组织，创建者成为所有者；slug 为空时由名称生成，重名时追加数字后缀
+func (s *Service) CreateOrganization(ctx context.Context, name, slug, creatorID string) (*Organization, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrInvalidName
+	}
+	slug, err := s.resolveSlug(ctx, name, slug, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	org := &Organization{Name: name, Slug: slug, CreatedBy: creatorID}
+	owner := &Membership{UserID: creatorID, Roles: []string{RoleOwner}}
+	if err := s.store.CreateOrganization(ctx, org, owner); err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+	return org, nil
+}
+
+// resolveSlug 校验或生成组织标识，纯数字标识会与组织ID混淆，统一加 org- 前缀
+func (s *Service) resolveSlug(ctx context.Context, name, slug string, orgID uint) (string, error) {
+	exists := func(candidate string) (bool, error) {
+		return s.store.SlugTaken(ctx, candidate, orgID)
+	}
+
+	if slug == "" {
+		base := utils.Str.SlugifyWithOptions(name, utils.SlugOptions{MaxLength: 64})
+		if base == "" {
+			base = "org"
+		} else if isNumeric(base) {
+			base = "org-" + base
+		}
+		return utils.Str.UniqueSlug(base, utils.SlugOptions{MaxLength: 64}, exists)
+	}
+
+	if utils.Str.Slugify(slug) != slug || isNumeric(slug) || utils.Str.IsReservedSlug(slug, nil) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidSlug, slug)
+	}
+	taken, err := exists(slug)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", ErrSlugTaken
+	}
+	return slug, nil
+}
+
+// isNumeric 是否为纯数字
+func isNumeric(value string) bool {
+	_, err := strconv.ParseUint(value, 10, 64)
+	return err == nil
+}
+
+// GetOrganization 按ID获取组织
+func (s *Service) GetOrganization(ctx context.Context, id uint) (*Organization, error) {
+	return s.store.GetOrganization(ctx, id)
+}
+
+// ResolveOrganization 按ID或标识获取组织，用于从请求头或路径参数解析当前组织
+func (s *Service) ResolveOrganization(ctx context.Context, ref string) (*Organization, error) {
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		return s.store.GetOrganization(ctx, uint(id))
+	}
+	return s.store.GetOrganizationBySlug(ctx, ref)
+}
+
+// UpdateOrganization 修改组织名称和标识，slug 为空时保持不变
+func (s *Service) UpdateOrganization(ctx context.Context, id uint, name, slug string) (*Organization, error) {
+	org, err := s.store.GetOrganization(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if name = strings.TrimSpace(name); name != "" {
+		org.Name = name
+	}
+	if slug != "" && slug != org.Slug {
+		if org.Slug, err = s.resolveSlug(ctx, org.Name, slug, org.ID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.store.UpdateOrganization(ctx, org); err != nil {
+		return nil, fmt.Errorf("failed to update organization: %w", err)
+	}
+	return org, nil
+}
+
+// DeleteOrganization 删除组织及其成员关系和邀请
+func (s *Service) DeleteOrganization(ctx context.Context, id uint) error {
+	return s.store.DeleteOrganization(ctx, id)
+}
+
+// ListUserOrganizations 列出用户所属的组织
+func (s *Service) ListUserOrganizations(ctx context.Context, userID string) ([]*Organization, error) {
+	return s.store.ListOrganizationsByUser(ctx, userID)
+}
+
+// GetMembership 获取用户在组织中的成员关系，不是成员时返回 ErrNotMember
+func (s *Service) GetMembership(ctx context.Context, orgID uint, userID string) (*Membership, error) {
+	return s.store.GetMembership(ctx, orgID, userID)
+}
+
+// ListMembers 列出组织成员
+func (s *Service) ListMembers(ctx context.Context, orgID uint) ([]*Membership, error) {
+	return s.store.ListMemberships(ctx, orgID)
+}
+
+// AddMember 直接添加成员（不经过邀请），已是成员时更新角色；roles 为空时为普通成员
+func (s *Service) AddMember(ctx context.Context, orgID uint, userID string, roles []string) (*Membership, error) {
+	roles, err := s.normalizeRoles(roles)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureOwnerRemains(ctx, orgID, userID, roles); err != nil {
+		return nil, err
+	}
+	membership := &Membership{OrgID: orgID, UserID: userID, Roles: roles}
+	if err := s.store.SaveMembership(ctx, membership); err != nil {
+		return nil, err
+	}
+	return membership, nil
+}
+
+// UpdateMemberRoles 修改成员的组织角色，不能移除最后一个所有者的所有者角色
+func (s *Service) UpdateMemberRoles(ctx context.Context, orgID uint, userID string, roles []string) (*Membership, error) {
+	if _, err := s.store.GetMembership(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	return s.AddMember(ctx, orgID, userID, roles)
+}
+
+// RemoveMember 移除成员，不能移除最后一个所有者
+func (s *Service) RemoveMember(ctx context.Context, orgID uint, userID string) error {
+	if err := s.ensureOwnerRemains(ctx, orgID, userID, nil); err != nil {
+		return err
+	}
+	return s.store.DeleteMembership(ctx, orgID, userID)
+}
+
+// ensureOwnerRemains 检查将用户角色改为 roles（nil 表示移除）后组织仍有所有者
+func (s *Service) ensureOwnerRemains(ctx context.Context, orgID uint, userID string, roles []string) error {
+	if containsRole(roles, RoleOwner) {
+		return nil
+	}
+	memberships, err := s.store.ListMemberships(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	owners, isOwner := 0, false
+	for _, membership := range memberships {
+		if membership.HasRole(RoleOwner) {
+			owners++
+			isOwner = isOwner || membership.UserID == userID
+		}
+	}
+	if isOwner && owners == 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// normalizeRoles 去重并校验角色是组织角色且已在RBAC中定义，为空时返回普通成员角色
+func (s *Service) normalizeRoles(roles []string) ([]string, error) {
+	if len(roles) == 0 {
+		return []string{RoleMember}, nil
+	}
+	normalized := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if containsRole(normalized, role) {
+			continue
+		}
+		if !s.roles[role] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRole, role)
+		}
+		if _, err := s.rbac.GetRole(role); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRole, role)
+		}
+		normalized = append(normalized, role)
+	}
+	return normalized, nil
+}
+
+// containsRole 角色列表是否包含指定角色
+func containsRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Invite 邀请邮箱加入组织，返回邀请和明文令牌；设置了发送器时同时发送邀请邮件，发送失败时撤销邀请
+func (s *Service) Invite(ctx context.Context, orgID uint, email string, roles []string, invitedBy string) (*Invitation, string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		return nil, "", fmt.Errorf("%w: %q", ErrInvalidEmail, email)
+	}
+	org, err := s.store.GetOrganization(ctx, orgID)
+	if err != nil {
+		return nil, "", err
+	}
+	if roles, err = s.normalizeRoles(roles); err != nil {
+		return nil, "", err
+	}
+
+	token, err := generateInvitationToken()
+	if err != nil {
+		return nil, "", err
+	}
+	invitation := &Invitation{
+		OrgID:     orgID,
+		Email:     email,
+		Roles:     roles,
+		TokenHash: hashInvitationToken(token),
+		InvitedBy: invitedBy,
+		ExpiresAt: time.Now().Add(s.invitationTTL),
+	}
+	if err := s.store.SaveInvitation(ctx, invitation); err != nil {
+		return nil, "", fmt.Errorf("failed to save invitation: %w", err)
+	}
+
+	if s.sender != nil {
+		if err := s.sender.SendInvitation(ctx, org, invitation, token); err != nil {
+			_ = s.store.DeleteInvitation(ctx, orgID, invitation.ID)
+			return nil, "", fmt.Errorf("failed to send invitation: %w", err)
+		}
+	}
+	return invitation, token, nil
+}
+
+// ListInvitations 列出组织未接受且未过期的邀请
+func (s *Service) ListInvitations(ctx context.Context, orgID uint) ([]*Invitation, error) {
+	invitations, err := s.store.ListInvitations(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]*Invitation, 0, len(invitations))
+	for _, invitation := range invitations {
+		if invitation.Pending() {
+			pending = append(pending, invitation)
+		}
+	}
+	return pending, nil
+}
+
+// RevokeInvitation 撤销邀请
+func (s *Service) RevokeInvitation(ctx context.Context, orgID, invitationID uint) error {
+	return s.store.DeleteInvitation(ctx, orgID, invitationID)
+}
+
+// AcceptInvitation 接受邀请，email 为当前用户已验证的邮箱，需与被邀请邮箱一致
+// 用户已是成员时合并邀请中的角色
+func (s *Service) AcceptInvitation(ctx context.Context, token, userID, email string) (*Membership, error) {
+	invitation, err := s.store.GetInvitationByTokenHash(ctx, hashInvitationToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if invitation.AcceptedAt != nil {
+		return nil, ErrInvitationNotFound
+	}
+	if !time.Now().Before(invitation.ExpiresAt) {
+		return nil, ErrInvitationExpired
+	}
+	if !strings.EqualFold(strings.TrimSpace(email), invitation.Email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+
+	roles := invitation.Roles
+	if existing, err := s.store.GetMembership(ctx, invitation.OrgID, userID); err == nil {
+		roles = append([]string(nil), existing.Roles...)
+		for _, role := range invitation.Roles {
+			if !containsRole(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	} else if !errors.Is(err, ErrNotMember) {
+		return nil, err
+	}
+
+	now := time.Now()
+	invitation.AcceptedAt, invitation.AcceptedBy = &now, userID
+	membership := &Membership{OrgID: invitation.OrgID, UserID: userID, Roles: roles}
+	if err := s.store.AcceptInvitation(ctx, invitation, membership); err != nil {
+		return nil, err
+	}
+	return membership, nil
+}
+
+// generateInvitationToken 生成32字节随机邀请令牌
+func generateInvitationToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashInvitationToken 计算邀请令牌的SHA-256摘要
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// HasPermission 检查用户在组织内是否拥有资源权限，权限来自成员关系中各角色在RBAC中的定义
+// 资源按 auth.ResourceMatcher 匹配，动作支持通配符 *；不是成员时返回 false
+func (s *Service) HasPermission(ctx context.Context, orgID uint, userID, resource, action string) (bool, error) {
+	membership, err := s.store.GetMembership(ctx, orgID, userID)
+	if errors.Is(err, ErrNotMember) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return s.MembershipHasPermission(membership, resource, action), nil
+}
+
+// MembershipHasPermission 检查成员关系是否拥有资源权限，非组织角色和已从RBAC删除的角色忽略
+func (s *Service) MembershipHasPermission(membership *Membership, resource, action string) bool {
+	matcher := auth.NewResourceMatcher()
+	for _, roleID := range membership.Roles {
+		if !s.roles[roleID] {
+			continue
+		}
+		role, err := s.rbac.GetRole(roleID)
+		if err != nil {
+			continue
+		}
+		for _, permission := range role.Permissions {
+			if matcher.Match(permission.Resource, resource) && (permission.Action == "*" || permission.Action == action) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,513 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrgMigrationVersion 组织相关表迁移的版本号
+const OrgMigrationVersion = "20241201000000"
+
+// Store 组织、成员关系和邀请存储
+type Store interface {
+	// CreateOrganization 创建组织并添加第一个成员（所有者）
+	CreateOrganization(ctx context.Context, org *Organization, owner *Membership) error
+	// GetOrganization 按ID获取组织，不存在时返回 ErrOrganizationNotFound
+	GetOrganization(ctx context.Context, id uint) (*Organization, error)
+	// GetOrganizationBySlug 按标识获取组织，不存在时返回 ErrOrganizationNotFound
+	GetOrganizationBySlug(ctx context.Context, slug string) (*Organization, error)
+	// SlugTaken 标识是否已被 excludeID 以外的组织占用，包括已删除但仍保留记录的组织
+	SlugTaken(ctx context.Context, slug string, excludeID uint) (bool, error)
+	// UpdateOrganization 更新组织
+	UpdateOrganization(ctx context.Context, org *Organization) error
+	// DeleteOrganization 删除组织及其成员关系和邀请
+	DeleteOrganization(ctx context.Context, id uint) error
+	// ListOrganizationsByUser 列出用户所属的组织
+	ListOrganizationsByUser(ctx context.Context, userID string) ([]*Organization, error)
+
+	// SaveMembership 保存成员关系，同一组织的同一用户已存在时更新角色
+	SaveMembership(ctx context.Context, membership *Membership) error
+	// GetMembership 获取成员关系，不存在时返回 ErrNotMember
+	GetMembership(ctx context.Context, orgID uint, userID string) (*Membership, error)
+	// ListMemberships 列出组织的成员关系，按加入时间排序
+	ListMemberships(ctx context.Context, orgID uint) ([]*Membership, error)
+	// DeleteMembership 删除成员关系，不存在时返回 ErrNotMember
+	DeleteMembership(ctx context.Context, orgID uint, userID string) error
+
+	// SaveInvitation 保存邀请
+	SaveInvitation(ctx context.Context, invitation *Invitation) error
+	// GetInvitationByTokenHash 按令牌摘要获取邀请，不存在时返回 ErrInvitationNotFound
+	GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*Invitation, error)
+	// ListInvitations 列出组织的邀请，按创建时间倒序
+	ListInvitations(ctx context.Context, orgID uint) ([]*Invitation, error)
+	// DeleteInvitation 删除邀请，不存在时返回 ErrInvitationNotFound
+	DeleteInvitation(ctx context.Context, orgID, id uint) error
+	// AcceptInvitation 将未接受的邀请标记为已接受并保存成员关系，邀请已被接受时返回 ErrInvitationNotFound
+	AcceptInvitation(ctx context.Context, invitation *Invitation, membership *Membership) error
+}
+
+// RegisterMigrations 向迁移管理器注册组织、成员关系和邀请表迁移
+func RegisterMigrations(migrator *database.Migrator) *database.Migrator {
+	return migrator.AddMigration(OrgMigrationVersion, "create_organizations", func(db *gorm.DB) error {
+		return db.AutoMigrate(&Organization{}, &Membership{}, &Invitation{})
+	})
+}
+
+// GormStore 基于GORM的组织存储，表结构由 RegisterMigrations 创建
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 创建GORM组织存储
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+// CreateOrganization 在事务中创建组织和所有者成员关系
+func (s *GormStore) CreateOrganization(ctx context.Context, org *Organization, owner *Membership) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		owner.OrgID = org.ID
+		return tx.Create(owner).Error
+	})
+}
+
+// GetOrganization 获取组织
+func (s *GormStore) GetOrganization(ctx context.Context, id uint) (*Organization, error) {
+	return s.findOrganization(ctx, "id = ?", id)
+}
+
+// GetOrganizationBySlug 按标识获取组织
+func (s *GormStore) GetOrganizationBySlug(ctx context.Context, slug string) (*Organization, error) {
+	return s.findOrganization(ctx, "slug = ?", slug)
+}
+
+// SlugTaken 标识是否已被占用，软删除的组织仍占用唯一索引，因此使用 Unscoped 查询
+func (s *GormStore) SlugTaken(ctx context.Context, slug string, excludeID uint) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Unscoped().Model(&Organization{}).
+		Where("slug = ? AND id <> ?", slug, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+// findOrganization 按条件查询组织
+func (s *GormStore) findOrganization(ctx context.Context, query string, arg interface{}) (*Organization, error) {
+	var org Organization
+	if err := s.db.WithContext(ctx).Where(query, arg).First(&org).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+// UpdateOrganization 更新组织
+func (s *GormStore) UpdateOrganization(ctx context.Context, org *Organization) error {
+	return s.db.WithContext(ctx).Save(org).Error
+}
+
+// DeleteOrganization 软删除组织，成员关系和邀请直接删除
+func (s *GormStore) DeleteOrganization(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&Organization{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrganizationNotFound
+		}
+		if err := tx.Where("org_id = ?", id).Delete(&Membership{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("org_id = ?", id).Delete(&Invitation{}).Error
+	})
+}
+
+// ListOrganizationsByUser 列出用户所属的组织
+func (s *GormStore) ListOrganizationsByUser(ctx context.Context, userID string) ([]*Organization, error) {
+	var orgs []*Organization
+	err := s.db.WithContext(ctx).
+		Joins("JOIN org_memberships ON org_memberships.org_id = organizations.id").
+		Where("org_memberships.user_id = ?", userID).
+		Order("organizations.id").Find(&orgs).Error
+	return orgs, err
+}
+
+// SaveMembership 保存成员关系
+func (s *GormStore) SaveMembership(ctx context.Context, membership *Membership) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "org_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"roles", "updated_at"}),
+	}).Create(membership).Error
+}
+
+// GetMembership 获取成员关系
+func (s *GormStore) GetMembership(ctx context.Context, orgID uint, userID string) (*Membership, error) {
+	var membership Membership
+	err := s.db.WithContext(ctx).Where("org_id = ? AND user_id = ?", orgID, userID).First(&membership).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotMember
+		}
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// ListMemberships 列出组织的成员关系
+func (s *GormStore) ListMemberships(ctx context.Context, orgID uint) ([]*Membership, error) {
+	var memberships []*Membership
+	err := s.db.WithContext(ctx).Where("org_id = ?", orgID).Order("created_at, id").Find(&memberships).Error
+	return memberships, err
+}
+
+// DeleteMembership 删除成员关系
+func (s *GormStore) DeleteMembership(ctx context.Context, orgID uint, userID string) error {
+	result := s.db.WithContext(ctx).Where("org_id = ? AND user_id = ?", orgID, userID).Delete(&Membership{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotMember
+	}
+	return nil
+}
+
+// SaveInvitation 保存邀请
+func (s *GormStore) SaveInvitation(ctx context.Context, invitation *Invitation) error {
+	return s.db.WithContext(ctx).Save(invitation).Error
+}
+
+// GetInvitationByTokenHash 按令牌摘要获取邀请
+func (s *GormStore) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*Invitation, error) {
+	var invitation Invitation
+	if err := s.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// ListInvitations 列出组织的邀请
+func (s *GormStore) ListInvitations(ctx context.Context, orgID uint) ([]*Invitation, error) {
+	var invitations []*Invitation
+	err := s.db.WithContext(ctx).Where("org_id = ?", orgID).Order("created_at DESC, id DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+// DeleteInvitation 删除邀请
+func (s *GormStore) DeleteInvitation(ctx context.Context, orgID, id uint) error {
+	result := s.db.WithContext(ctx).Where("org_id = ? AND id = ?", orgID, id).Delete(&Invitation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation 在事务中标记邀请已接受并保存成员关系，条件更新保证邀请只能被接受一次
+func (s *GormStore) AcceptInvitation(ctx context.Context, invitation *Invitation, membership *Membership) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Invitation{}).
+			Where("id = ? AND accepted_at IS NULL", invitation.ID).
+			Updates(map[string]interface{}{"accepted_at": invitation.AcceptedAt, "accepted_by": invitation.AcceptedBy})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvitationNotFound
+		}
+		return (&GormStore{db: tx}).SaveMembership(ctx, membership)
+	})
+}
+
+// MemoryStore 内存组织存储，用于开发和测试
+type MemoryStore struct {
+	orgs        map[uint]*Organization
+	memberships map[uint]map[string]*Membership
+	invitations map[uint]*Invitation
+	nextID      uint
+	mutex       sync.RWMutex
+}
+
+// NewMemoryStore 创建内存组织存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		orgs:        make(map[uint]*Organization),
+		memberships: make(map[uint]map[string]*Membership),
+		invitations: make(map[uint]*Invitation),
+	}
+}
+
+// CreateOrganization 创建组织和所有者成员关系
+func (s *MemoryStore) CreateOrganization(ctx context.Context, org *Organization, owner *Membership) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, existing := range s.orgs {
+		if existing.Slug == org.Slug {
+			return ErrSlugTaken
+		}
+	}
+	now := time.Now()
+	s.nextID++
+	org.ID = s.nextID
+	org.CreatedAt, org.UpdatedAt = now, now
+	stored := *org
+	s.orgs[org.ID] = &stored
+
+	owner.OrgID = org.ID
+	s.memberships[org.ID] = make(map[string]*Membership)
+	s.saveMembership(owner)
+	return nil
+}
+
+// GetOrganization 获取组织
+func (s *MemoryStore) GetOrganization(ctx context.Context, id uint) (*Organization, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	org, ok := s.orgs[id]
+	if !ok {
+		return nil, ErrOrganizationNotFound
+	}
+	found := *org
+	return &found, nil
+}
+
+// GetOrganizationBySlug 按标识获取组织
+func (s *MemoryStore) GetOrganizationBySlug(ctx context.Context, slug string) (*Organization, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, org := range s.orgs {
+		if org.Slug == slug {
+			found := *org
+			return &found, nil
+		}
+	}
+	return nil, ErrOrganizationNotFound
+}
+
+// SlugTaken 标识是否已被占用，内存存储直接删除组织，已删除组织的标识可以复用
+func (s *MemoryStore) SlugTaken(ctx context.Context, slug string, excludeID uint) (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for id, org := range s.orgs {
+		if id != excludeID && org.Slug == slug {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UpdateOrganization 更新组织
+func (s *MemoryStore) UpdateOrganization(ctx context.Context, org *Organization) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.orgs[org.ID]; !ok {
+		return ErrOrganizationNotFound
+	}
+	for id, existing := range s.orgs {
+		if id != org.ID && existing.Slug == org.Slug {
+			return ErrSlugTaken
+		}
+	}
+	org.UpdatedAt = time.Now()
+	stored := *org
+	s.orgs[org.ID] = &stored
+	return nil
+}
+
+// DeleteOrganization 删除组织及其成员关系和邀请
+func (s *MemoryStore) DeleteOrganization(ctx context.Context, id uint) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.orgs[id]; !ok {
+		return ErrOrganizationNotFound
+	}
+	delete(s.orgs, id)
+	delete(s.memberships, id)
+	for invitationID, invitation := range s.invitations {
+		if invitation.OrgID == id {
+			delete(s.invitations, invitationID)
+		}
+	}
+	return nil
+}
+
+// ListOrganizationsByUser 列出用户所属的组织
+func (s *MemoryStore) ListOrganizationsByUser(ctx context.Context, userID string) ([]*Organization, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var orgs []*Organization
+	for orgID, members := range s.memberships {
+		if _, ok := members[userID]; ok {
+			found := *s.orgs[orgID]
+			orgs = append(orgs, &found)
+		}
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].ID < orgs[j].ID })
+	return orgs, nil
+}
+
+// SaveMembership 保存成员关系
+func (s *MemoryStore) SaveMembership(ctx context.Context, membership *Membership) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.orgs[membership.OrgID]; !ok {
+		return ErrOrganizationNotFound
+	}
+	s.saveMembership(membership)
+	return nil
+}
+
+// saveMembership 保存成员关系，调用方需持有写锁
+func (s *MemoryStore) saveMembership(membership *Membership) {
+	now := time.Now()
+	if existing, ok := s.memberships[membership.OrgID][membership.UserID]; ok {
+		membership.ID, membership.CreatedAt = existing.ID, existing.CreatedAt
+	} else {
+		s.nextID++
+		membership.ID, membership.CreatedAt = s.nextID, now
+	}
+	membership.UpdatedAt = now
+	stored := *membership
+	stored.Roles = append([]string(nil), membership.Roles...)
+	s.memberships[membership.OrgID][membership.UserID] = &stored
+}
+
+// GetMembership 获取成员关系
+func (s *MemoryStore) GetMembership(ctx context.Context, orgID uint, userID string) (*Membership, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	membership, ok := s.memberships[orgID][userID]
+	if !ok {
+		return nil, ErrNotMember
+	}
+	found := *membership
+	found.Roles = append([]string(nil), membership.Roles...)
+	return &found, nil
+}
+
+// ListMemberships 列出组织的成员关系
+func (s *MemoryStore) ListMemberships(ctx context.Context, orgID uint) ([]*Membership, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	memberships := make([]*Membership, 0, len(s.memberships[orgID]))
+	for _, membership := range s.memberships[orgID] {
+		found := *membership
+		found.Roles = append([]string(nil), membership.Roles...)
+		memberships = append(memberships, &found)
+	}
+	sort.Slice(memberships, func(i, j int) bool { return memberships[i].ID < memberships[j].ID })
+	return memberships, nil
+}
+
+// DeleteMembership 删除成员关系
+func (s *MemoryStore) DeleteMembership(ctx context.Context, orgID uint, userID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.memberships[orgID][userID]; !ok {
+		return ErrNotMember
+	}
+	delete(s.memberships[orgID], userID)
+	return nil
+}
+
+// SaveInvitation 保存邀请
+func (s *MemoryStore) SaveInvitation(ctx context.Context, invitation *Invitation) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if invitation.ID == 0 {
+		s.nextID++
+		invitation.ID = s.nextID
+		invitation.CreatedAt = time.Now()
+	}
+	stored := *invitation
+	s.invitations[invitation.ID] = &stored
+	return nil
+}
+
+// GetInvitationByTokenHash 按令牌摘要获取邀请
+func (s *MemoryStore) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*Invitation, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, invitation := range s.invitations {
+		if invitation.TokenHash == tokenHash {
+			found := *invitation
+			return &found, nil
+		}
+	}
+	return nil, ErrInvitationNotFound
+}
+
+// ListInvitations 列出组织的邀请
+func (s *MemoryStore) ListInvitations(ctx context.Context, orgID uint) ([]*Invitation, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var invitations []*Invitation
+	for _, invitation := range s.invitations {
+		if invitation.OrgID == orgID {
+			found := *invitation
+			invitations = append(invitations, &found)
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool { return invitations[i].ID > invitations[j].ID })
+	return invitations, nil
+}
+
+// DeleteInvitation 删除邀请
+func (s *MemoryStore) DeleteInvitation(ctx context.Context, orgID, id uint) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	invitation, ok := s.invitations[id]
+	if !ok || invitation.OrgID != orgID {
+		return ErrInvitationNotFound
+	}
+	delete(s.invitations, id)
+	return nil
+}
+
+// AcceptInvitation 标记邀请已接受并保存成员关系
+func (s *MemoryStore) AcceptInvitation(ctx context.Context, invitation *Invitation, membership *Membership) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, ok := s.invitations[invitation.ID]
+	if !ok || stored.AcceptedAt != nil {
+		return ErrInvitationNotFound
+	}
+	if _, ok := s.orgs[membership.OrgID]; !ok {
+		return ErrOrganizationNotFound
+	}
+	stored.AcceptedAt, stored.AcceptedBy = invitation.AcceptedAt, invitation.AcceptedBy
+	s.saveMembership(membership)
+	return nil
+}
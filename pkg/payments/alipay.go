@@ -0,0 +1,196 @@
+package payments
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AlipayConfig 支付宝配置
+type AlipayConfig struct {
+	AppID           string
+	PrivateKey      string // 应用私钥（PEM或Base64）
+	AlipayPublicKey string // 支付宝公钥（PEM或Base64），用于校验异步通知
+	Gateway         string // 网关地址，默认 https://openapi.alipay.com/gateway.do，沙箱为 https://openapi-sandbox.dl.alipaydev.com/gateway.do
+	NotifyURL       string // 异步通知地址
+}
+
+// Alipay 支付宝电脑网站支付渠道（alipay.trade.page.pay）
+type Alipay struct {
+	config     AlipayConfig
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+	location   *time.Location
+	now        func() time.Time
+}
+
+// NewAlipay 创建支付宝支付渠道
+func NewAlipay(config AlipayConfig) (*Alipay, error) {
+	if config.Gateway == "" {
+		config.Gateway = "https://openapi.alipay.com/gateway.do"
+	}
+	privateKey, err := parsePrivateKey(config.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid alipay private key: %w", err)
+	}
+	publicKey, err := parsePublicKey(config.AlipayPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid alipay public key: %w", err)
+	}
+	// 支付宝要求请求时间为北京时间
+	location, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		location = time.FixedZone("CST", 8*3600)
+	}
+	return &Alipay{
+		config:     config,
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		location:   location,
+		now:        time.Now,
+	}, nil
+}
+
+// Name 渠道名称
+func (a *Alipay) Name() string {
+	return "alipay"
+}
+
+// CreateCheckout 生成签名后的支付页面地址，用户跳转后完成支付，不需要调用支付宝接口
+func (a *Alipay) CreateCheckout(ctx context.Context, req *CheckoutRequest) (*CheckoutSession, error) {
+	if req.Currency != "" && !strings.EqualFold(req.Currency, "CNY") {
+		return nil, fmt.Errorf("%w: alipay only supports CNY", ErrInvalidRequest)
+	}
+	bizContent, err := json.Marshal(map[string]string{
+		"out_trade_no": req.OrderID,
+		"total_amount": formatYuan(req.Amount),
+		"subject":      req.Description,
+		"product_code": "FAST_INSTANT_TRADE_PAY",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{
+		"app_id":      {a.config.AppID},
+		"method":      {"alipay.trade.page.pay"},
+		"format":      {"JSON"},
+		"charset":     {"utf-8"},
+		"sign_type":   {"RSA2"},
+		"timestamp":   {a.now().In(a.location).Format("2006-01-02 15:04:05")},
+		"version":     {"1.0"},
+		"biz_content": {string(bizContent)},
+	}
+	if a.config.NotifyURL != "" {
+		params.Set("notify_url", a.config.NotifyURL)
+	}
+	if req.SuccessURL != "" {
+		params.Set("return_url", req.SuccessURL)
+	}
+	sign, err := signSHA256(a.privateKey, alipaySignContent(params))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign alipay request: %w", err)
+	}
+	params.Set("sign", sign)
+
+	return &CheckoutSession{
+		Provider: a.Name(),
+		OrderID:  req.OrderID,
+		URL:      a.config.Gateway + "?" + params.Encode(),
+	}, nil
+}
+
+// ParseWebhook 校验异步通知签名并解析事件，通知ID（notify_id）作为事件ID
+func (a *Alipay) ParseWebhook(r *http.Request) (*Event, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	params := r.PostForm
+	if len(params) == 0 {
+		params = r.Form
+	}
+	if err := verifySHA256(a.publicKey, alipaySignContent(params), params.Get("sign")); err != nil {
+		return nil, err
+	}
+	if appID := params.Get("app_id"); appID != a.config.AppID {
+		return nil, fmt.Errorf("%w: app_id %q does not match", ErrInvalidSignature, appID)
+	}
+
+	event := &Event{
+		ID:            params.Get("notify_id"),
+		Type:          params.Get("trade_status"),
+		OrderID:       params.Get("out_trade_no"),
+		TransactionID: params.Get("trade_no"),
+		Amount:        parseYuan(params.Get("total_amount")),
+		Currency:      "CNY",
+		Raw:           []byte(params.Encode()),
+	}
+	switch event.Type {
+	case "TRADE_SUCCESS", "TRADE_FINISHED":
+		event.Status = StatusSucceeded
+	case "TRADE_CLOSED":
+		// 全额退款后交易同样变为关闭
+		event.Status = StatusCanceled
+		if params.Get("refund_fee") != "" {
+			event.Status = StatusRefunded
+		}
+	case "WAIT_BUYER_PAY":
+		event.Status = StatusPending
+	}
+	return event, nil
+}
+
+// WebhookAck 支付宝要求异步通知处理成功后返回纯文本 success，否则会重复通知
+func (a *Alipay) WebhookAck() (int, string, []byte) {
+	return http.StatusOK, "text/plain; charset=utf-8", []byte("success")
+}
+
+// alipaySignContent 生成待签名字符串：除 sign、sign_type 和空值外的参数按键名排序后以 & 连接
+func alipaySignContent(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if key == "sign" || key == "sign_type" || params.Get(key) == "" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(params.Get(key))
+	}
+	return b.String()
+}
+
+// formatYuan 将分转换为元，保留两位小数
+func formatYuan(cents int64) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
+
+// parseYuan 将元转换为分，无效值返回0
+func parseYuan(value string) int64 {
+	yuan, fraction, _ := strings.Cut(value, ".")
+	whole, err := strconv.ParseInt(yuan, 10, 64)
+	if err != nil {
+		return 0
+	}
+	fraction = (fraction + "00")[:2]
+	cents, err := strconv.ParseInt(fraction, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return whole*100 + cents
+}
@@ -0,0 +1,95 @@
+package payments
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// parsePrivateKey 解析PEM格式（PKCS#1或PKCS#8）的RSA私钥，也接受不带PEM头的Base64内容（支付宝开放平台导出的格式）
+func parsePrivateKey(data string) (*rsa.PrivateKey, error) {
+	der, err := decodeKey(data)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// parsePublicKey 解析PEM格式的RSA公钥或证书，也接受不带PEM头的Base64内容
+func parsePublicKey(data string) (*rsa.PublicKey, error) {
+	der, err := decodeKey(data)
+	if err != nil {
+		return nil, err
+	}
+	if cert, err := x509.ParseCertificate(der); err == nil {
+		if key, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+		return nil, errors.New("certificate does not contain an RSA key")
+	}
+	if key, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+	return key, nil
+}
+
+// decodeKey 获取密钥的DER内容
+func decodeKey(data string) ([]byte, error) {
+	if block, _ := pem.Decode([]byte(data)); block != nil {
+		return block.Bytes, nil
+	}
+	der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err != nil {
+		return nil, errors.New("key is neither PEM nor base64 encoded")
+	}
+	return der, nil
+}
+
+// signSHA256 使用 SHA256withRSA 签名并返回Base64编码的签名
+func signSHA256(key *rsa.PrivateKey, message string) (string, error) {
+	digest := sha256.Sum256([]byte(message))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// verifySHA256 校验Base64编码的 SHA256withRSA 签名
+func verifySHA256(key *rsa.PublicKey, message, signature string) error {
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	digest := sha256.Sum256([]byte(message))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], raw); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
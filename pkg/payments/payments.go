@@ -0,0 +1,195 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 支付错误
+var (
+	ErrUnknownProvider  = errors.New("unknown payment provider")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrInvalidRequest   = errors.New("invalid checkout request")
+	ErrPaymentNotFound  = errors.New("payment not found")
+	ErrPaymentMismatch  = errors.New("payment event amount or currency mismatch")
+)
+
+// Status 支付状态
+type Status string
+
+// 支付状态
+const (
+	StatusPending   Status = "pending"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled" // 会话过期或交易关闭
+	StatusRefunded  Status = "refunded"
+)
+
+// statusTransitions 允许的支付状态变更，只能向前推进；失败的支付可以重试成功或过期关闭
+var statusTransitions = map[Status][]Status{
+	StatusPending:   {StatusSucceeded, StatusFailed, StatusCanceled},
+	StatusFailed:    {StatusSucceeded, StatusCanceled},
+	StatusSucceeded: {StatusRefunded},
+}
+
+// CanTransition 是否允许从当前状态变更为目标状态，乱序或重放的旧事件不能让状态回退
+func (s Status) CanTransition(to Status) bool {
+	for _, next := range statusTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckoutRequest 创建支付会话的请求，金额使用最小货币单位（如分）
+type CheckoutRequest struct {
+	OrderID     string            // 业务订单号，同一支付渠道内唯一，用作幂等键
+	UserID      string            // 下单用户
+	Amount      int64             // 金额（最小货币单位）
+	Currency    string            // 币种，如 usd、CNY；支付宝和微信支付只支持人民币
+	Description string            // 商品描述
+	SuccessURL  string            // 支付成功后的跳转地址
+	CancelURL   string            // 取消支付后的跳转地址
+	Metadata    map[string]string // 附加数据，随会话传给支付平台
+}
+
+// Validate 校验请求
+func (r *CheckoutRequest) Validate() error {
+	if r.OrderID == "" {
+		return fmt.Errorf("%w: order id is required", ErrInvalidRequest)
+	}
+	if r.Amount <= 0 {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidRequest)
+	}
+	if r.Description == "" {
+		return fmt.Errorf("%w: description is required", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// CheckoutSession 支付会话
+type CheckoutSession struct {
+	Provider  string    `json:"provider"`
+	OrderID   string    `json:"order_id"`
+	SessionID string    `json:"session_id,omitempty"` // 支付平台的会话ID
+	URL       string    `json:"url,omitempty"`        // 支付页面地址，用户跳转后完成支付
+	QRCode    string    `json:"qr_code,omitempty"`    // 扫码支付的二维码内容（微信支付 Native）
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// Event 已校验签名的支付平台回调事件
+type Event struct {
+	ID            string    `json:"id"` // 支付平台的事件ID，用于幂等处理
+	Provider      string    `json:"provider"`
+	Type          string    `json:"type"`
+	OrderID       string    `json:"order_id"`
+	TransactionID string    `json:"transaction_id"` // 支付平台的交易/会话ID
+	Status        Status    `json:"status"`         // 为空表示该事件不改变支付状态
+	Amount        int64     `json:"amount"`
+	Currency      string    `json:"currency"`
+	ReceivedAt    time.Time `json:"received_at"`
+	Raw           []byte    `json:"-"`
+}
+
+// Provider 支付渠道驱动
+type Provider interface {
+	Name() string
+	// CreateCheckout 创建支付会话
+	CreateCheckout(ctx context.Context, req *CheckoutRequest) (*CheckoutSession, error)
+	// ParseWebhook 校验回调签名并解析事件，签名无效时返回 ErrInvalidSignature
+	ParseWebhook(r *http.Request) (*Event, error)
+}
+
+// WebhookAcknowledger 需要特定回调应答的支付渠道（如支付宝要求返回 success）
+type WebhookAcknowledger interface {
+	WebhookAck() (status int, contentType string, body []byte)
+}
+
+// EventHandler 支付事件处理函数，payment 为事件对应的支付记录，找不到时为 nil
+type EventHandler func(ctx context.Context, event *Event, payment *Payment) error
+
+// Manager 支付管理器，统一管理支付渠道、支付记录和回调处理
+type Manager struct {
+	store     Store
+	providers map[string]Provider
+	handlers  []EventHandler
+	mutex     sync.RWMutex
+}
+
+// NewManager 创建支付管理器
+func NewManager(store Store, providers ...Provider) *Manager {
+	m := &Manager{
+		store:     store,
+		providers: make(map[string]Provider),
+	}
+	for _, provider := range providers {
+		m.Register(provider)
+	}
+	return m
+}
+
+// Register 注册支付渠道，同名渠道后注册的覆盖先注册的
+func (m *Manager) Register(provider Provider) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.providers[provider.Name()] = provider
+}
+
+// Provider 获取支付渠道
+func (m *Manager) Provider(name string) (Provider, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	provider, ok := m.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return provider, nil
+}
+
+// OnEvent 注册支付事件处理函数，在支付状态更新后按注册顺序调用
+func (m *Manager) OnEvent(handler EventHandler) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+// Checkout 创建支付会话并保存待支付记录
+func (m *Manager) Checkout(ctx context.Context, providerName string, req *CheckoutRequest) (*CheckoutSession, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	provider, err := m.Provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := provider.CreateCheckout(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s checkout: %w", providerName, err)
+	}
+	payment := &Payment{
+		Provider:    providerName,
+		OrderID:     req.OrderID,
+		SessionID:   session.SessionID,
+		UserID:      req.UserID,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		Description: req.Description,
+		Status:      StatusPending,
+	}
+	if err := m.store.SavePayment(ctx, payment); err != nil {
+		return nil, fmt.Errorf("failed to save payment: %w", err)
+	}
+	return session, nil
+}
+
+// Payment 查询支付记录
+func (m *Manager) Payment(ctx context.Context, providerName, orderID string) (*Payment, error) {
+	return m.store.GetPayment(ctx, providerName, orderID)
+}
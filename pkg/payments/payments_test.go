@@ -0,0 +1,427 @@
+package payments
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func generateKey(t *testing.T) (*rsa.PrivateKey, string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	// 公钥使用不带PEM头的Base64格式
+	return key, string(privatePEM), base64.StdEncoding.EncodeToString(publicDER)
+}
+
+func newStripeServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		assert.Equal(t, "sk_test", user)
+		assert.Equal(t, "/v1/checkout/sessions", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "checkout-"+r.PostForm.Get("client_reference_id"), r.Header.Get("Idempotency-Key"))
+		assert.Equal(t, "1999", r.PostForm.Get("line_items[0][price_data][unit_amount]"))
+		assert.Equal(t, "gold", r.PostForm.Get("metadata[plan]"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"cs_test_1","url":"https://checkout.stripe.com/c/cs_test_1","expires_at":1700000000}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func stripeWebhook(t *testing.T, id, eventType, orderID string) *http.Request {
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":   id,
+		"type": eventType,
+		"data": map[string]interface{}{
+			"object": map[string]interface{}{
+				"id":                  "cs_test_1",
+				"client_reference_id": orderID,
+				"amount_total":        1999,
+				"currency":            "usd",
+				"payment_status":      "paid",
+			},
+		},
+	})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/payments/webhooks/stripe", strings.NewReader(string(payload)))
+	req.Header.Set(StripeSignatureHeader, SignStripePayload("whsec_test", payload, time.Now()))
+	return req
+}
+
+func TestCheckoutRequestValidate(t *testing.T) {
+	assert.ErrorIs(t, (&CheckoutRequest{Amount: 1, Description: "x"}).Validate(), ErrInvalidRequest)
+	assert.ErrorIs(t, (&CheckoutRequest{OrderID: "o", Description: "x"}).Validate(), ErrInvalidRequest)
+	assert.ErrorIs(t, (&CheckoutRequest{OrderID: "o", Amount: 1}).Validate(), ErrInvalidRequest)
+	assert.NoError(t, (&CheckoutRequest{OrderID: "o", Amount: 1, Description: "x"}).Validate())
+}
+
+func TestManagerStripeFlow(t *testing.T) {
+	ctx := context.Background()
+	server := newStripeServer(t)
+	store := NewMemoryStore()
+	manager := NewManager(store, NewStripe(StripeConfig{SecretKey: "sk_test", WebhookSecret: "whsec_test", BaseURL: server.URL}))
+
+	session, err := manager.Checkout(ctx, "stripe", &CheckoutRequest{
+		OrderID:     "order-1",
+		UserID:      "42",
+		Amount:      1999,
+		Currency:    "USD",
+		Description: "Gold plan",
+		Metadata:    map[string]string{"plan": "gold"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "cs_test_1", session.SessionID)
+	assert.Equal(t, "https://checkout.stripe.com/c/cs_test_1", session.URL)
+
+	payment, err := manager.Payment(ctx, "stripe", "order-1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, payment.Status)
+	assert.Equal(t, "42", payment.UserID)
+
+	var handled int
+	manager.OnEvent(func(ctx context.Context, event *Event, payment *Payment) error {
+		handled++
+		require.NotNil(t, payment)
+		assert.Equal(t, StatusSucceeded, payment.Status)
+		return nil
+	})
+
+	event, duplicate, err := manager.HandleWebhook(ctx, "stripe", stripeWebhook(t, "evt_1", "checkout.session.completed", "order-1"))
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+	assert.Equal(t, "stripe", event.Provider)
+	assert.Equal(t, int64(1999), event.Amount)
+
+	// 重复投递不会再次处理
+	_, duplicate, err = manager.HandleWebhook(ctx, "stripe", stripeWebhook(t, "evt_1", "checkout.session.completed", "order-1"))
+	require.NoError(t, err)
+	assert.True(t, duplicate)
+	assert.Equal(t, 1, handled)
+
+	payment, err = manager.Payment(ctx, "stripe", "order-1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, payment.Status)
+	assert.NotNil(t, payment.PaidAt)
+
+	_, err = manager.Checkout(ctx, "paypal", &CheckoutRequest{OrderID: "o", Amount: 1, Description: "x"})
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+}
+
+func TestHandleWebhookStatusTransitions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	manager := NewManager(store, NewStripe(StripeConfig{WebhookSecret: "whsec_test"}))
+	require.NoError(t, store.SavePayment(ctx, &Payment{Provider: "stripe", OrderID: "order-3", Amount: 1999, Currency: "USD", Status: StatusPending}))
+
+	status := func() Status {
+		payment, err := manager.Payment(ctx, "stripe", "order-3")
+		require.NoError(t, err)
+		return payment.Status
+	}
+
+	_, _, err := manager.HandleWebhook(ctx, "stripe", stripeWebhook(t, "evt_3", "checkout.session.completed", "order-3"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, status())
+
+	// 乱序到达的失败和过期事件不能让已支付的订单回退
+	_, _, err = manager.HandleWebhook(ctx, "stripe", stripeWebhook(t, "evt_4", "checkout.session.async_payment_failed", "order-3"))
+	require.NoError(t, err)
+	_, _, err = manager.HandleWebhook(ctx, "stripe", stripeWebhook(t, "evt_5", "checkout.session.expired", "order-3"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, status())
+
+	_, _, err = manager.HandleWebhook(ctx, "stripe", stripeWebhook(t, "evt_6", "charge.refunded", "order-3"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusRefunded, status())
+	_, _, err = manager.HandleWebhook(ctx, "stripe", stripeWebhook(t, "evt_7", "checkout.session.async_payment_succeeded", "order-3"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusRefunded, status())
+
+	assert.True(t, StatusPending.CanTransition(StatusSucceeded))
+	assert.True(t, StatusFailed.CanTransition(StatusSucceeded))
+	assert.False(t, StatusCanceled.CanTransition(StatusSucceeded))
+	assert.False(t, StatusSucceeded.CanTransition(StatusPending))
+}
+
+func TestHandleWebhookRejectsAmountMismatch(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	manager := NewManager(store, NewStripe(StripeConfig{WebhookSecret: "whsec_test"}))
+	require.NoError(t, store.SavePayment(ctx, &Payment{Provider: "stripe", OrderID: "order-4", Amount: 500, Currency: "usd", Status: StatusPending}))
+	require.NoError(t, store.SavePayment(ctx, &Payment{Provider: "stripe", OrderID: "order-5", Amount: 1999, Currency: "eur", Status: StatusPending}))
+
+	var handled int
+	manager.OnEvent(func(ctx context.Context, event *Event, payment *Payment) error {
+		handled++
+		return nil
+	})
+
+	for _, orderID := range []string{"order-4", "order-5"} {
+		_, _, err := manager.HandleWebhook(ctx, "stripe", stripeWebhook(t, "evt_"+orderID, "checkout.session.completed", orderID))
+		assert.ErrorIs(t, err, ErrPaymentMismatch)
+		payment, err := manager.Payment(ctx, "stripe", orderID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusPending, payment.Status)
+
+		// 不一致的事件保留记录，重试不会再次处理
+		_, duplicate, err := manager.HandleWebhook(ctx, "stripe", stripeWebhook(t, "evt_"+orderID, "checkout.session.completed", orderID))
+		require.NoError(t, err)
+		assert.True(t, duplicate)
+	}
+	assert.Zero(t, handled)
+
+	router := gin.New()
+	router.POST("/payments/webhooks/stripe", manager.WebhookHandler("stripe"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, stripeWebhook(t, "evt_8", "checkout.session.completed", "order-4"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandleWebhookReleasesFailedEvent(t *testing.T) {
+	ctx := context.Background()
+	manager := NewManager(NewMemoryStore(), NewStripe(StripeConfig{WebhookSecret: "whsec_test"}))
+
+	fail := true
+	var handled int
+	manager.OnEvent(func(ctx context.Context, event *Event, payment *Payment) error {
+		handled++
+		if fail {
+			return errors.New("downstream unavailable")
+		}
+		return nil
+	})
+
+	_, _, err := manager.HandleWebhook(ctx, "stripe", stripeWebhook(t, "evt_2", "checkout.session.completed", "order-2"))
+	require.Error(t, err)
+
+	// 处理失败后支付平台重试时重新处理
+	fail = false
+	_, duplicate, err := manager.HandleWebhook(ctx, "stripe", stripeWebhook(t, "evt_2", "checkout.session.completed", "order-2"))
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+	assert.Equal(t, 2, handled)
+}
+
+func TestStripeSignature(t *testing.T) {
+	stripe := NewStripe(StripeConfig{WebhookSecret: "whsec_test"})
+
+	req := stripeWebhook(t, "evt_3", "checkout.session.completed", "order-3")
+	req.Header.Set(StripeSignatureHeader, "t=1,v1=deadbeef")
+	_, err := stripe.ParseWebhook(req)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	// 超出时间容差的签名视为重放
+	payload := []byte(`{"id":"evt_4","type":"charge.refunded"}`)
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(payload)))
+	req.Header.Set(StripeSignatureHeader, SignStripePayload("whsec_test", payload, time.Now().Add(-time.Hour)))
+	_, err = stripe.ParseWebhook(req)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestWebhookHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager := NewManager(NewMemoryStore(), NewStripe(StripeConfig{WebhookSecret: "whsec_test"}))
+	router := gin.New()
+	router.POST("/payments/webhooks/:provider", func(c *gin.Context) {
+		manager.WebhookHandler(c.Param("provider"))(c)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, stripeWebhook(t, "evt_5", "checkout.session.completed", "order-5"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"received":true}`, w.Body.String())
+
+	req := stripeWebhook(t, "evt_6", "checkout.session.completed", "order-6")
+	req.Header.Set(StripeSignatureHeader, "invalid")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = stripeWebhook(t, "evt_7", "checkout.session.completed", "order-7")
+	req.URL.Path = "/payments/webhooks/unknown"
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAlipay(t *testing.T) {
+	_, appPrivate, appPublic := generateKey(t)
+	alipayKey, _, alipayPublic := generateKey(t)
+
+	alipay, err := NewAlipay(AlipayConfig{
+		AppID:           "2021000000000000",
+		PrivateKey:      appPrivate,
+		AlipayPublicKey: alipayPublic,
+		NotifyURL:       "https://example.com/payments/webhooks/alipay",
+	})
+	require.NoError(t, err)
+
+	session, err := alipay.CreateCheckout(context.Background(), &CheckoutRequest{
+		OrderID: "order-1", Amount: 1001, Description: "会员", SuccessURL: "https://example.com/done",
+	})
+	require.NoError(t, err)
+	checkoutURL, err := url.Parse(session.URL)
+	require.NoError(t, err)
+	params := checkoutURL.Query()
+	assert.Equal(t, "alipay.trade.page.pay", params.Get("method"))
+	assert.Contains(t, params.Get("biz_content"), `"total_amount":"10.01"`)
+	// 请求签名可以用应用公钥校验
+	publicKey, err := parsePublicKey(appPublic)
+	require.NoError(t, err)
+	assert.NoError(t, verifySHA256(publicKey, alipaySignContent(params), params.Get("sign")))
+
+	_, err = alipay.CreateCheckout(context.Background(), &CheckoutRequest{OrderID: "o", Amount: 1, Description: "x", Currency: "usd"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	// 模拟支付宝异步通知
+	notify := url.Values{
+		"app_id":       {"2021000000000000"},
+		"notify_id":    {"notify-1"},
+		"out_trade_no": {"order-1"},
+		"trade_no":     {"2024101622001"},
+		"trade_status": {"TRADE_SUCCESS"},
+		"total_amount": {"10.01"},
+		"sign_type":    {"RSA2"},
+	}
+	sign, err := signSHA256(alipayKey, alipaySignContent(notify))
+	require.NoError(t, err)
+	notify.Set("sign", sign)
+
+	req := httptest.NewRequest(http.MethodPost, "/payments/webhooks/alipay", strings.NewReader(notify.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	event, err := alipay.ParseWebhook(req)
+	require.NoError(t, err)
+	assert.Equal(t, "notify-1", event.ID)
+	assert.Equal(t, "order-1", event.OrderID)
+	assert.Equal(t, StatusSucceeded, event.Status)
+	assert.Equal(t, int64(1001), event.Amount)
+
+	notify.Set("total_amount", "99.00")
+	req = httptest.NewRequest(http.MethodPost, "/payments/webhooks/alipay", strings.NewReader(notify.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = alipay.ParseWebhook(req)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	status, _, body := alipay.WebhookAck()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "success", string(body))
+}
+
+func TestWeChatPay(t *testing.T) {
+	_, merchantPrivate, _ := generateKey(t)
+	platformKey, _, platformPublic := generateKey(t)
+	apiV3Key := "0123456789abcdef0123456789abcdef"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/pay/transactions/native", r.URL.Path)
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "WECHATPAY2-SHA256-RSA2048 "))
+		assert.Contains(t, r.Header.Get("Authorization"), `serial_no="SERIAL"`)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "order-1", body["out_trade_no"])
+		w.Write([]byte(`{"code_url":"weixin://wxpay/bizpayurl?pr=abc"}`))
+	}))
+	defer server.Close()
+
+	wechat, err := NewWeChatPay(WeChatPayConfig{
+		MchID:             "1900000001",
+		AppID:             "wx0000000000000000",
+		SerialNo:          "SERIAL",
+		PrivateKey:        merchantPrivate,
+		APIv3Key:          apiV3Key,
+		PlatformPublicKey: platformPublic,
+		BaseURL:           server.URL,
+	})
+	require.NoError(t, err)
+
+	session, err := wechat.CreateCheckout(context.Background(), &CheckoutRequest{OrderID: "order-1", Amount: 100, Description: "会员"})
+	require.NoError(t, err)
+	assert.Equal(t, "weixin://wxpay/bizpayurl?pr=abc", session.QRCode)
+
+	// 模拟微信支付回调：资源使用APIv3密钥加密，通知由平台私钥签名
+	block, err := aes.NewCipher([]byte(apiV3Key))
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	nonce := "abcdefghijkl"
+	resource := `{"out_trade_no":"order-1","transaction_id":"4200000001","trade_state":"SUCCESS","amount":{"total":100,"currency":"CNY"}}`
+	ciphertext := gcm.Seal(nil, []byte(nonce), []byte(resource), []byte("transaction"))
+	notification, err := json.Marshal(map[string]interface{}{
+		"id":         "notify-1",
+		"event_type": "TRANSACTION.SUCCESS",
+		"resource": map[string]string{
+			"algorithm":       "AEAD_AES_256_GCM",
+			"ciphertext":      base64.StdEncoding.EncodeToString(ciphertext),
+			"associated_data": "transaction",
+			"nonce":           nonce,
+		},
+	})
+	require.NoError(t, err)
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature, err := signSHA256(platformKey, timestamp+"\nnonce-1\n"+string(notification)+"\n")
+	require.NoError(t, err)
+	newRequest := func(signature string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/payments/webhooks/wechatpay", strings.NewReader(string(notification)))
+		req.Header.Set(WeChatPayTimestampHeader, timestamp)
+		req.Header.Set(WeChatPayNonceHeader, "nonce-1")
+		req.Header.Set(WeChatPaySignatureHeader, signature)
+		return req
+	}
+
+	event, err := wechat.ParseWebhook(newRequest(signature))
+	require.NoError(t, err)
+	assert.Equal(t, "notify-1", event.ID)
+	assert.Equal(t, "order-1", event.OrderID)
+	assert.Equal(t, "4200000001", event.TransactionID)
+	assert.Equal(t, StatusSucceeded, event.Status)
+
+	_, err = wechat.ParseWebhook(newRequest(base64.StdEncoding.EncodeToString([]byte("forged"))))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = NewWeChatPay(WeChatPayConfig{APIv3Key: "short"})
+	assert.Error(t, err)
+}
+
+func TestGormStore(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	store := NewGormStore(db)
+
+	require.NoError(t, store.SavePayment(context.Background(), &Payment{Provider: "stripe", OrderID: "order-1", Status: StatusPending}))
+	_, err = store.RecordEvent(context.Background(), &Event{Provider: "stripe", ID: "evt_1"})
+	assert.NoError(t, err)
+
+	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return store.Payments().GetDB().WithContext(context.Background()).Where("provider = ? AND order_id = ?", "stripe", "order-1").First(&Payment{})
+	})
+	assert.Contains(t, sql, `FROM "payments"`)
+}
@@ -0,0 +1,222 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PaymentsMigrationVersion 支付表迁移的版本号
+const PaymentsMigrationVersion = "20241016000000"
+
+// Payment 支付记录
+type Payment struct {
+	database.BaseModel
+	Provider    string     `json:"provider" gorm:"size:32;uniqueIndex:idx_payments_provider_order"`
+	OrderID     string     `json:"order_id" gorm:"size:128;uniqueIndex:idx_payments_provider_order"`
+	SessionID   string     `json:"session_id" gorm:"size:255;index"` // 支付平台的会话/交易ID
+	UserID      string     `json:"user_id" gorm:"size:128;index"`
+	Amount      int64      `json:"amount"` // 最小货币单位
+	Currency    string     `json:"currency" gorm:"size:8"`
+	Description string     `json:"description" gorm:"size:255"`
+	Status      Status     `json:"status" gorm:"size:32;index"`
+	PaidAt      *time.Time `json:"paid_at,omitempty"`
+}
+
+// TableName 支付表名
+func (Payment) TableName() string {
+	return "payments"
+}
+
+// PaymentEvent 已处理的支付回调事件，用于回调幂等
+type PaymentEvent struct {
+	Provider   string `gorm:"primaryKey;size:32"`
+	EventID    string `gorm:"primaryKey;size:255"`
+	Type       string `gorm:"size:128"`
+	OrderID    string `gorm:"size:128;index"`
+	ReceivedAt time.Time
+}
+
+// TableName 支付事件表名
+func (PaymentEvent) TableName() string {
+	return "payment_events"
+}
+
+// Store 支付数据存储
+type Store interface {
+	// SavePayment 按 (provider, order_id) 新增或更新支付记录
+	SavePayment(ctx context.Context, payment *Payment) error
+	// GetPayment 查询支付记录，不存在时返回 ErrPaymentNotFound
+	GetPayment(ctx context.Context, provider, orderID string) (*Payment, error)
+	// RecordEvent 记录回调事件，事件已记录过时返回 false
+	RecordEvent(ctx context.Context, event *Event) (bool, error)
+	// ForgetEvent 删除事件记录，处理失败后允许重新处理
+	ForgetEvent(ctx context.Context, event *Event) error
+}
+
+// RegisterMigrations 向迁移管理器注册支付表迁移
+func RegisterMigrations(migrator *database.Migrator) *database.Migrator {
+	return migrator.AddMigration(PaymentsMigrationVersion, "create_payments", func(db *gorm.DB) error {
+		return db.AutoMigrate(&Payment{}, &PaymentEvent{})
+	})
+}
+
+// PaymentRepository 支付记录仓储
+type PaymentRepository struct {
+	*database.BaseRepository[Payment]
+}
+
+// NewPaymentRepository 创建支付记录仓储
+func NewPaymentRepository(db *gorm.DB) *PaymentRepository {
+	return &PaymentRepository{BaseRepository: database.NewBaseRepository[Payment](db)}
+}
+
+// FindByOrder 按支付渠道和订单号查询支付记录
+func (r *PaymentRepository) FindByOrder(ctx context.Context, provider, orderID string) (*Payment, error) {
+	var payment Payment
+	err := r.GetDB().WithContext(ctx).Where("provider = ? AND order_id = ?", provider, orderID).First(&payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// ListByUser 分页查询用户的支付记录，按创建时间倒序
+func (r *PaymentRepository) ListByUser(ctx context.Context, userID string, offset, limit int) ([]*Payment, error) {
+	var payments []*Payment
+	err := r.GetDB().WithContext(ctx).Where("user_id = ?", userID).
+		Order("created_at DESC").Offset(offset).Limit(limit).Find(&payments).Error
+	return payments, err
+}
+
+// GormStore 基于GORM的支付存储，数据保存在 payments 和 payment_events 表，表结构由 RegisterMigrations 创建
+type GormStore struct {
+	db       *gorm.DB
+	payments *PaymentRepository
+}
+
+// NewGormStore 创建GORM支付存储
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db, payments: NewPaymentRepository(db)}
+}
+
+// Payments 获取支付记录仓储
+func (s *GormStore) Payments() *PaymentRepository {
+	return s.payments
+}
+
+// SavePayment 保存支付记录
+func (s *GormStore) SavePayment(ctx context.Context, payment *Payment) error {
+	if payment.ID != 0 {
+		return s.db.WithContext(ctx).Save(payment).Error
+	}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider"}, {Name: "order_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"session_id", "user_id", "amount", "currency", "description", "status", "updated_at"}),
+	}).Create(payment).Error
+}
+
+// GetPayment 查询支付记录
+func (s *GormStore) GetPayment(ctx context.Context, provider, orderID string) (*Payment, error) {
+	return s.payments.FindByOrder(ctx, provider, orderID)
+}
+
+// RecordEvent 记录回调事件，依赖主键冲突判断重复投递
+func (s *GormStore) RecordEvent(ctx context.Context, event *Event) (bool, error) {
+	result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&PaymentEvent{
+		Provider:   event.Provider,
+		EventID:    event.ID,
+		Type:       event.Type,
+		OrderID:    event.OrderID,
+		ReceivedAt: event.ReceivedAt,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ForgetEvent 删除事件记录
+func (s *GormStore) ForgetEvent(ctx context.Context, event *Event) error {
+	return s.db.WithContext(ctx).Delete(&PaymentEvent{}, "provider = ? AND event_id = ?", event.Provider, event.ID).Error
+}
+
+// MemoryStore 内存支付存储，用于开发和测试
+type MemoryStore struct {
+	payments map[string]*Payment
+	events   map[string]bool
+	nextID   uint
+	mutex    sync.Mutex
+}
+
+// NewMemoryStore 创建内存支付存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		payments: make(map[string]*Payment),
+		events:   make(map[string]bool),
+	}
+}
+
+// SavePayment 保存支付记录
+func (s *MemoryStore) SavePayment(ctx context.Context, payment *Payment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := payment.Provider + ":" + payment.OrderID
+	now := time.Now()
+	if existing, ok := s.payments[key]; ok && payment.ID == 0 {
+		payment.ID = existing.ID
+		payment.CreatedAt = existing.CreatedAt
+	}
+	if payment.ID == 0 {
+		s.nextID++
+		payment.ID = s.nextID
+		payment.CreatedAt = now
+	}
+	payment.UpdatedAt = now
+	stored := *payment
+	s.payments[key] = &stored
+	return nil
+}
+
+// GetPayment 查询支付记录
+func (s *MemoryStore) GetPayment(ctx context.Context, provider, orderID string) (*Payment, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	payment, ok := s.payments[provider+":"+orderID]
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+	found := *payment
+	return &found, nil
+}
+
+// RecordEvent 记录回调事件
+func (s *MemoryStore) RecordEvent(ctx context.Context, event *Event) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := event.Provider + ":" + event.ID
+	if s.events[key] {
+		return false, nil
+	}
+	s.events[key] = true
+	return true, nil
+}
+
+// ForgetEvent 删除事件记录
+func (s *MemoryStore) ForgetEvent(ctx context.Context, event *Event) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.events, event.Provider+":"+event.ID)
+	return nil
+}
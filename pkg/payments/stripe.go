@@ -0,0 +1,239 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StripeSignatureHeader Stripe回调签名请求头
+const StripeSignatureHeader = "Stripe-Signature"
+
+// StripeConfig Stripe配置
+type StripeConfig struct {
+	SecretKey     string        // API密钥 sk_...
+	WebhookSecret string        // 回调签名密钥 whsec_...
+	BaseURL       string        // API地址，默认 https://api.stripe.com
+	Tolerance     time.Duration // 回调时间戳允许的偏差，默认5分钟，用于防重放
+	HTTPClient    *http.Client
+}
+
+// Stripe Stripe Checkout 支付渠道
+type Stripe struct {
+	config StripeConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewStripe 创建Stripe支付渠道
+func NewStripe(config StripeConfig) *Stripe {
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.stripe.com"
+	}
+	if config.Tolerance <= 0 {
+		config.Tolerance = 5 * time.Minute
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Stripe{config: config, client: client, now: time.Now}
+}
+
+// Name 渠道名称
+func (s *Stripe) Name() string {
+	return "stripe"
+}
+
+// CreateCheckout 创建 Checkout Session，订单号作为幂等键和 client_reference_id
+func (s *Stripe) CreateCheckout(ctx context.Context, req *CheckoutRequest) (*CheckoutSession, error) {
+	currency := strings.ToLower(req.Currency)
+	if currency == "" {
+		currency = "usd"
+	}
+	form := url.Values{
+		"mode":                {"payment"},
+		"client_reference_id": {req.OrderID},
+		"success_url":         {req.SuccessURL},
+		"cancel_url":          {req.CancelURL},
+		"metadata[order_id]":  {req.OrderID},
+		// 写入 PaymentIntent 元数据，退款等 charge 事件同样可以关联订单
+		"payment_intent_data[metadata][order_id]":       {req.OrderID},
+		"line_items[0][quantity]":                       {"1"},
+		"line_items[0][price_data][currency]":           {currency},
+		"line_items[0][price_data][unit_amount]":        {strconv.FormatInt(req.Amount, 10)},
+		"line_items[0][price_data][product_data][name]": {req.Description},
+	}
+	for key, value := range req.Metadata {
+		form.Set("metadata["+key+"]", value)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.BaseURL+"/v1/checkout/sessions", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.SetBasicAuth(s.config.SecretKey, "")
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Idempotency-Key", "checkout-"+req.OrderID)
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(body, &apiErr)
+		return nil, fmt.Errorf("stripe returned %d: %s", resp.StatusCode, apiErr.Error.Message)
+	}
+
+	var session struct {
+		ID        string `json:"id"`
+		URL       string `json:"url"`
+		ExpiresAt int64  `json:"expires_at"`
+	}
+	if err := json.Unmarshal(body, &session); err != nil {
+		return nil, fmt.Errorf("failed to decode stripe session: %w", err)
+	}
+	return &CheckoutSession{
+		Provider:  s.Name(),
+		OrderID:   req.OrderID,
+		SessionID: session.ID,
+		URL:       session.URL,
+		ExpiresAt: time.Unix(session.ExpiresAt, 0),
+	}, nil
+}
+
+// stripeEvent Stripe回调事件
+type stripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object struct {
+			ID                string            `json:"id"`
+			ClientReferenceID string            `json:"client_reference_id"`
+			Metadata          map[string]string `json:"metadata"`
+			AmountTotal       int64             `json:"amount_total"`
+			Amount            int64             `json:"amount"`
+			Currency          string            `json:"currency"`
+			PaymentStatus     string            `json:"payment_status"`
+		} `json:"object"`
+	} `json:"data"`
+}
+
+// ParseWebhook 校验 Stripe-Signature 并解析事件
+func (s *Stripe) ParseWebhook(r *http.Request) (*Event, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySignature(r.Header.Get(StripeSignatureHeader), body); err != nil {
+		return nil, err
+	}
+
+	var raw stripeEvent
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode stripe event: %w", err)
+	}
+	object := raw.Data.Object
+	orderID := object.ClientReferenceID
+	if orderID == "" {
+		orderID = object.Metadata["order_id"]
+	}
+	amount := object.AmountTotal
+	if amount == 0 {
+		amount = object.Amount
+	}
+
+	event := &Event{
+		ID:            raw.ID,
+		Type:          raw.Type,
+		OrderID:       orderID,
+		TransactionID: object.ID,
+		Amount:        amount,
+		Currency:      object.Currency,
+		Raw:           body,
+	}
+	switch raw.Type {
+	case "checkout.session.completed":
+		// 异步支付方式完成会话时尚未付款，等待 async_payment_succeeded
+		if object.PaymentStatus == "paid" || object.PaymentStatus == "no_payment_required" {
+			event.Status = StatusSucceeded
+		}
+	case "checkout.session.async_payment_succeeded":
+		event.Status = StatusSucceeded
+	case "checkout.session.async_payment_failed":
+		event.Status = StatusFailed
+	case "checkout.session.expired":
+		event.Status = StatusCanceled
+	case "charge.refunded":
+		event.Status = StatusRefunded
+		event.TransactionID = ""
+	}
+	return event, nil
+}
+
+// verifySignature 校验签名头 t=时间戳,v1=签名，签名为 HMAC-SHA256(密钥, 时间戳.请求体)
+func (s *Stripe) verifySignature(header string, body []byte) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return fmt.Errorf("%w: malformed %s header", ErrInvalidSignature, StripeSignatureHeader)
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+	}
+	if age := s.now().Sub(time.Unix(seconds, 0)); age > s.config.Tolerance || age < -s.config.Tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.config.WebhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	for _, signature := range signatures {
+		if actual, err := hex.DecodeString(signature); err == nil && hmac.Equal(actual, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// SignStripePayload 生成 Stripe-Signature 请求头，用于测试回调处理
+func SignStripePayload(secret string, payload []byte, timestamp time.Time) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(payload)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
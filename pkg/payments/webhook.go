@@ -0,0 +1,132 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleWebhook 校验并处理支付平台回调，返回事件以及是否为重复投递
+// 同一事件只处理一次：事件先记录到存储再更新支付状态和调用处理函数，处理失败时删除记录，支付平台重试时重新处理
+// 金额或币种与支付记录不一致时返回 ErrPaymentMismatch，事件保留记录不再重试
+func (m *Manager) HandleWebhook(ctx context.Context, providerName string, r *http.Request) (*Event, bool, error) {
+	provider, err := m.Provider(providerName)
+	if err != nil {
+		return nil, false, err
+	}
+	event, err := provider.ParseWebhook(r)
+	if err != nil {
+		return nil, false, err
+	}
+	event.Provider = providerName
+	if event.ReceivedAt.IsZero() {
+		event.ReceivedAt = time.Now()
+	}
+
+	first, err := m.store.RecordEvent(ctx, event)
+	if err != nil {
+		return event, false, fmt.Errorf("failed to record payment event: %w", err)
+	}
+	if !first {
+		return event, true, nil
+	}
+
+	if err := m.processEvent(ctx, event); err != nil {
+		if errors.Is(err, ErrPaymentMismatch) {
+			return event, false, err
+		}
+		if forgetErr := m.store.ForgetEvent(ctx, event); forgetErr != nil {
+			return event, false, fmt.Errorf("%w (and failed to release event: %v)", err, forgetErr)
+		}
+		return event, false, err
+	}
+	return event, false, nil
+}
+
+// processEvent 校验金额和币种后更新支付状态并调用事件处理函数
+// 状态只按 Status.CanTransition 向前推进，乱序到达的旧事件不改变状态但仍会交给处理函数
+func (m *Manager) processEvent(ctx context.Context, event *Event) error {
+	var payment *Payment
+	if event.OrderID != "" {
+		found, err := m.store.GetPayment(ctx, event.Provider, event.OrderID)
+		switch {
+		case err == nil:
+			payment = found
+		case !errors.Is(err, ErrPaymentNotFound):
+			return err
+		}
+	}
+
+	if payment != nil && event.Status != "" {
+		if err := checkEventAmount(payment, event); err != nil {
+			return err
+		}
+	}
+
+	if payment != nil && event.Status != "" && payment.Status.CanTransition(event.Status) {
+		payment.Status = event.Status
+		if event.TransactionID != "" {
+			payment.SessionID = event.TransactionID
+		}
+		if event.Status == StatusSucceeded && payment.PaidAt == nil {
+			paidAt := event.ReceivedAt
+			payment.PaidAt = &paidAt
+		}
+		if err := m.store.SavePayment(ctx, payment); err != nil {
+			return fmt.Errorf("failed to update payment: %w", err)
+		}
+	}
+
+	m.mutex.RLock()
+	handlers := append([]EventHandler(nil), m.handlers...)
+	m.mutex.RUnlock()
+	for _, handler := range handlers {
+		if err := handler(ctx, event, payment); err != nil {
+			return fmt.Errorf("payment event handler failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkEventAmount 校验事件金额和币种与支付记录一致，事件或记录未携带金额/币种时跳过
+func checkEventAmount(payment *Payment, event *Event) error {
+	if event.Amount > 0 && payment.Amount > 0 && event.Amount != payment.Amount {
+		return fmt.Errorf("%w: order %s expects %d, event %s has %d", ErrPaymentMismatch, payment.OrderID, payment.Amount, event.ID, event.Amount)
+	}
+	if event.Currency != "" && payment.Currency != "" && !strings.EqualFold(event.Currency, payment.Currency) {
+		return fmt.Errorf("%w: order %s expects %s, event %s has %s", ErrPaymentMismatch, payment.OrderID, payment.Currency, event.ID, event.Currency)
+	}
+	return nil
+}
+
+// WebhookHandler 创建支付回调接口，签名无效或金额不一致返回400，处理失败返回500使支付平台重试
+//
+//	router.POST("/payments/webhooks/stripe", manager.WebhookHandler("stripe"))
+func (m *Manager) WebhookHandler(providerName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, _, err := m.HandleWebhook(c.Request.Context(), providerName, c.Request)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrInvalidSignature), errors.Is(err, ErrUnknownProvider), errors.Is(err, ErrPaymentMismatch):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		default:
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process webhook"})
+			return
+		}
+
+		provider, _ := m.Provider(providerName)
+		if ack, ok := provider.(WebhookAcknowledger); ok {
+			status, contentType, body := ack.WebhookAck()
+			c.Data(status, contentType, body)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"received": true})
+	}
+}
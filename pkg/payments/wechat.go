@@ -0,0 +1,282 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 微信支付回调签名请求头
+const (
+	WeChatPayTimestampHeader = "Wechatpay-Timestamp"
+	WeChatPayNonceHeader     = "Wechatpay-Nonce"
+	WeChatPaySignatureHeader = "Wechatpay-Signature"
+	WeChatPaySerialHeader    = "Wechatpay-Serial"
+)
+
+// WeChatPayConfig 微信支付配置（APIv3）
+type WeChatPayConfig struct {
+	MchID             string        // 商户号
+	AppID             string        // 公众号/小程序/应用ID
+	SerialNo          string        // 商户API证书序列号
+	PrivateKey        string        // 商户API私钥（PEM或Base64）
+	APIv3Key          string        // APIv3密钥，用于解密回调内容
+	PlatformPublicKey string        // 微信支付平台证书或公钥（PEM或Base64），用于校验回调签名
+	NotifyURL         string        // 回调通知地址
+	BaseURL           string        // API地址，默认 https://api.mch.weixin.qq.com
+	Tolerance         time.Duration // 回调时间戳允许的偏差，默认5分钟
+	HTTPClient        *http.Client
+}
+
+// WeChatPay 微信支付 Native 扫码支付渠道
+type WeChatPay struct {
+	config     WeChatPayConfig
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+	client     *http.Client
+	now        func() time.Time
+}
+
+// NewWeChatPay 创建微信支付渠道
+func NewWeChatPay(config WeChatPayConfig) (*WeChatPay, error) {
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.mch.weixin.qq.com"
+	}
+	if config.Tolerance <= 0 {
+		config.Tolerance = 5 * time.Minute
+	}
+	if len(config.APIv3Key) != 32 {
+		return nil, fmt.Errorf("wechat pay apiv3 key must be 32 bytes")
+	}
+	privateKey, err := parsePrivateKey(config.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wechat pay private key: %w", err)
+	}
+	publicKey, err := parsePublicKey(config.PlatformPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wechat pay platform key: %w", err)
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &WeChatPay{
+		config:     config,
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		client:     client,
+		now:        time.Now,
+	}, nil
+}
+
+// Name 渠道名称
+func (w *WeChatPay) Name() string {
+	return "wechatpay"
+}
+
+// CreateCheckout 创建 Native 支付订单，返回的二维码内容（code_url）由前端生成二维码供用户扫码
+func (w *WeChatPay) CreateCheckout(ctx context.Context, req *CheckoutRequest) (*CheckoutSession, error) {
+	if req.Currency != "" && !strings.EqualFold(req.Currency, "CNY") {
+		return nil, fmt.Errorf("%w: wechat pay only supports CNY", ErrInvalidRequest)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"appid":        w.config.AppID,
+		"mchid":        w.config.MchID,
+		"description":  req.Description,
+		"out_trade_no": req.OrderID,
+		"notify_url":   w.config.NotifyURL,
+		"amount": map[string]interface{}{
+			"total":    req.Amount,
+			"currency": "CNY",
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	const path = "/v3/pay/transactions/native"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	authorization, err := w.authorization(http.MethodPost, path, body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Authorization", authorization)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := w.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(respBody, &apiErr)
+		return nil, fmt.Errorf("wechat pay returned %d: %s %s", resp.StatusCode, apiErr.Code, apiErr.Message)
+	}
+
+	var result struct {
+		CodeURL string `json:"code_url"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode wechat pay response: %w", err)
+	}
+	return &CheckoutSession{
+		Provider: w.Name(),
+		OrderID:  req.OrderID,
+		QRCode:   result.CodeURL,
+	}, nil
+}
+
+// authorization 生成请求签名头，签名串为 方法\n路径\n时间戳\n随机串\n请求体\n
+func (w *WeChatPay) authorization(method, path string, body []byte) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	nonceStr := hex.EncodeToString(nonce)
+	timestamp := strconv.FormatInt(w.now().Unix(), 10)
+	signature, err := signSHA256(w.privateKey, method+"\n"+path+"\n"+timestamp+"\n"+nonceStr+"\n"+string(body)+"\n")
+	if err != nil {
+		return "", fmt.Errorf("failed to sign wechat pay request: %w", err)
+	}
+	return fmt.Sprintf(`WECHATPAY2-SHA256-RSA2048 mchid="%s",nonce_str="%s",signature="%s",timestamp="%s",serial_no="%s"`,
+		w.config.MchID, nonceStr, signature, timestamp, w.config.SerialNo), nil
+}
+
+// wechatNotification 微信支付回调通知
+type wechatNotification struct {
+	ID           string `json:"id"`
+	CreateTime   string `json:"create_time"`
+	EventType    string `json:"event_type"`
+	ResourceType string `json:"resource_type"`
+	Resource     struct {
+		Algorithm      string `json:"algorithm"`
+		Ciphertext     string `json:"ciphertext"`
+		AssociatedData string `json:"associated_data"`
+		Nonce          string `json:"nonce"`
+	} `json:"resource"`
+}
+
+// wechatTransaction 解密后的交易信息
+type wechatTransaction struct {
+	OutTradeNo    string `json:"out_trade_no"`
+	TransactionID string `json:"transaction_id"`
+	TradeState    string `json:"trade_state"`
+	RefundStatus  string `json:"refund_status"`
+	Amount        struct {
+		Total    int64  `json:"total"`
+		Refund   int64  `json:"refund"`
+		Currency string `json:"currency"`
+	} `json:"amount"`
+}
+
+// ParseWebhook 校验回调签名并解密通知内容
+func (w *WeChatPay) ParseWebhook(r *http.Request) (*Event, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := r.Header.Get(WeChatPayTimestampHeader)
+	nonce := r.Header.Get(WeChatPayNonceHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+	}
+	if age := w.now().Sub(time.Unix(seconds, 0)); age > w.config.Tolerance || age < -w.config.Tolerance {
+		return nil, fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+	message := timestamp + "\n" + nonce + "\n" + string(body) + "\n"
+	if err := verifySHA256(w.publicKey, message, r.Header.Get(WeChatPaySignatureHeader)); err != nil {
+		return nil, err
+	}
+
+	var notification wechatNotification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, fmt.Errorf("failed to decode wechat pay notification: %w", err)
+	}
+	plaintext, err := w.decrypt(notification.Resource.Ciphertext, notification.Resource.Nonce, notification.Resource.AssociatedData)
+	if err != nil {
+		return nil, err
+	}
+	var transaction wechatTransaction
+	if err := json.Unmarshal(plaintext, &transaction); err != nil {
+		return nil, fmt.Errorf("failed to decode wechat pay resource: %w", err)
+	}
+
+	event := &Event{
+		ID:            notification.ID,
+		Type:          notification.EventType,
+		OrderID:       transaction.OutTradeNo,
+		TransactionID: transaction.TransactionID,
+		Amount:        transaction.Amount.Total,
+		Currency:      transaction.Amount.Currency,
+		Raw:           plaintext,
+	}
+	switch transaction.TradeState {
+	case "SUCCESS":
+		event.Status = StatusSucceeded
+	case "REFUND":
+		event.Status = StatusRefunded
+	case "CLOSED", "REVOKED":
+		event.Status = StatusCanceled
+	case "PAYERROR":
+		event.Status = StatusFailed
+	case "NOTPAY", "USERPAYING":
+		event.Status = StatusPending
+	}
+	// 退款通知不带 trade_state
+	if transaction.RefundStatus == "SUCCESS" {
+		event.Status = StatusRefunded
+		event.TransactionID = ""
+	}
+	return event, nil
+}
+
+// decrypt 使用APIv3密钥解密 AEAD_AES_256_GCM 加密的回调内容
+func (w *WeChatPay) decrypt(ciphertext, nonce, associatedData string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wechat pay ciphertext: %w", err)
+	}
+	block, err := aes.NewCipher([]byte(w.config.APIv3Key))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(nonce))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, []byte(nonce), data, []byte(associatedData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt wechat pay resource: %w", err)
+	}
+	return plaintext, nil
+}
+
+// WebhookAck 微信支付要求回调处理成功后返回200或204
+func (w *WeChatPay) WebhookAck() (int, string, []byte) {
+	return http.StatusOK, "application/json; charset=utf-8", []byte(`{"code":"SUCCESS","message":"成功"}`)
+}
@@ -0,0 +1,620 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/cache"
+	"github.com/hwh/hwhkit-go/pkg/logger"
+	"github.com/hwh/hwhkit-go/pkg/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrQueueClosed 队列已关闭
+	ErrQueueClosed = errors.New("queue closed")
+	// ErrJobNotFound 任务不存在
+	ErrJobNotFound = errors.New("job not found")
+)
+
+var (
+	jobsProcessed = metrics.Default.Counter("hwhkit_queue_jobs_total",
+		"Total number of processed jobs", "queue", "type", "status")
+	jobDuration = metrics.Default.Histogram("hwhkit_queue_job_duration_seconds",
+		"Job handler latency in seconds", nil, "queue", "type")
+	jobsEnqueued = metrics.Default.Counter("hwhkit_queue_jobs_enqueued_total",
+		"Total number of enqueued jobs", "queue", "type")
+)
+
+// 任务处理结果，用于指标的 status 标签
+const (
+	statusSucceeded = "succeeded"
+	statusRetried   = "retried"
+	statusDead      = "dead"
+)
+
+// dequeueScript 将到期的延迟任务和租约过期的任务移入就绪列表，再取出一个任务并设置租约
+// KEYS: ready, delayed, active, jobs; ARGV: 当前时间（毫秒）, 租约到期时间（毫秒）
+var dequeueScript = redis.NewScript(`
+local due = redis.call("ZRANGEBYSCORE", KEYS[2], "-inf", ARGV[1], "LIMIT", 0, 100)
+for _, id in ipairs(due) do
+	redis.call("RPUSH", KEYS[1], id)
+	redis.call("ZREM", KEYS[2], id)
+end
+local expired = redis.call("ZRANGEBYSCORE", KEYS[3], "-inf", ARGV[1], "LIMIT", 0, 100)
+for _, id in ipairs(expired) do
+	redis.call("RPUSH", KEYS[1], id)
+	redis.call("ZREM", KEYS[3], id)
+end
+while true do
+	local id = redis.call("LPOP", KEYS[1])
+	if not id then
+		return false
+	end
+	local data = redis.call("HGET", KEYS[4], id)
+	if data then
+		redis.call("ZADD", KEYS[3], ARGV[2], id)
+		return data
+	end
+end
+`)
+
+// Job 队列任务
+type Job struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempts   int             `json:"attempts"`    // 已开始执行的次数
+	MaxRetries int             `json:"max_retries"` // 首次执行失败后的最大重试次数
+	LastError  string          `json:"last_error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	RunAt      time.Time       `json:"run_at"`
+	FailedAt   *time.Time      `json:"failed_at,omitempty"` // 进入死信队列的时间
+}
+
+// Decode 将任务负载按JSON解码到 v
+func (j *Job) Decode(v interface{}) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// Handler 任务处理函数，返回错误时按退避策略重试，重试次数用尽后进入死信队列
+type Handler func(ctx context.Context, job *Job) error
+
+// EnqueueOptions 入队选项
+type EnqueueOptions struct {
+	ID         string        // 任务ID，为空时自动生成
+	Delay      time.Duration // 延迟执行时间
+	MaxRetries int           // 最大重试次数，为0时使用队列默认值，小于0时不重试
+}
+
+// Hooks 任务生命周期钩子，用于日志、告警和自定义指标，钩子在工作协程中同步执行
+type Hooks struct {
+	OnEnqueue func(job *Job)
+	OnStart   func(job *Job)
+	OnSuccess func(job *Job, duration time.Duration)
+	OnFailure func(job *Job, err error, willRetry bool)
+	OnDead    func(job *Job, err error)
+}
+
+// Stats 队列统计
+type Stats struct {
+	Ready   int64 `json:"ready"`
+	Delayed int64 `json:"delayed"` // 延迟执行和等待重试的任务
+	Active  int64 `json:"active"`
+	Dead    int64 `json:"dead"`
+}
+
+// Queue 基于Redis的后台任务队列，复用缓存管理器的连接和键前缀
+// 任务至少执行一次：工作进程崩溃时任务在租约过期后重新投递，处理函数应保证幂等
+//
+//	q := queue.New(cacheManager, "emails")
+//	q.Handle("welcome", func(ctx context.Context, job *queue.Job) error { ... })
+//	q.Start()
+//	srv.OnShutdown(q.Shutdown)
+//	q.Enqueue(ctx, "welcome", WelcomeEmail{UserID: 1})
+type Queue struct {
+	cache  *cache.Manager
+	client *redis.Client
+	name   string
+
+	concurrency  int
+	pollInterval time.Duration
+	timeout      time.Duration
+	maxRetries   int
+	backoff      func(attempt int) time.Duration
+	hooks        Hooks
+	logger       *logger.Manager
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	started  bool
+	closed   bool
+
+	ctx    context.Context // 传给处理函数，关闭超时后取消
+	cancel context.CancelFunc
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	now    func() time.Time
+
+	redisTimeout time.Duration // 单次Redis操作的超时时间，不包含处理函数的执行时间
+}
+
+// New 创建任务队列，默认并发数5，轮询间隔1秒，单个任务超时5分钟，最多重试3次，退避间隔按 2^n 秒增长
+func New(cacheManager *cache.Manager, name string) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		cache:        cacheManager,
+		client:       cacheManager.GetClient(),
+		name:         name,
+		concurrency:  5,
+		pollInterval: time.Second,
+		timeout:      5 * time.Minute,
+		maxRetries:   3,
+		backoff:      ExponentialBackoff(time.Second, time.Hour),
+		handlers:     make(map[string]Handler),
+		ctx:          ctx,
+		cancel:       cancel,
+		stop:         make(chan struct{}),
+		now:          time.Now,
+		redisTimeout: 5 * time.Second,
+	}
+}
+
+// ExponentialBackoff 指数退避：第n次重试等待 base*2^(n-1)，最长 max
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		return delay
+	}
+}
+
+// SetConcurrency 设置工作协程数，需在 Start 之前调用
+func (q *Queue) SetConcurrency(n int) {
+	if n > 0 {
+		q.concurrency = n
+	}
+}
+
+// SetPollInterval 设置队列为空时的轮询间隔
+func (q *Queue) SetPollInterval(interval time.Duration) {
+	if interval > 0 {
+		q.pollInterval = interval
+	}
+}
+
+// SetTimeout 设置单个任务的执行超时，租约为超时的2倍，租约过期的任务会被重新投递
+func (q *Queue) SetTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		q.timeout = timeout
+	}
+}
+
+// SetMaxRetries 设置默认最大重试次数
+func (q *Queue) SetMaxRetries(n int) {
+	if n >= 0 {
+		q.maxRetries = n
+	}
+}
+
+// SetBackoff 设置重试退避策略，attempt 从1开始
+func (q *Queue) SetBackoff(backoff func(attempt int) time.Duration) {
+	if backoff != nil {
+		q.backoff = backoff
+	}
+}
+
+// SetHooks 设置任务生命周期钩子
+func (q *Queue) SetHooks(hooks Hooks) {
+	q.hooks = hooks
+}
+
+// SetLogger 设置日志管理器，记录任务失败和进入死信队列
+func (q *Queue) SetLogger(l *logger.Manager) {
+	q.logger = l
+}
+
+// Name 队列名称
+func (q *Queue) Name() string {
+	return q.name
+}
+
+// Handle 注册任务类型的处理函数
+func (q *Queue) Handle(jobType string, handler Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+}
+
+// Enqueue 将任务加入队列，payload 按JSON编码
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}) (*Job, error) {
+	return q.EnqueueWithOptions(ctx, jobType, payload, EnqueueOptions{})
+}
+
+// EnqueueWithOptions 按选项将任务加入队列，相同ID的等待中任务会被覆盖，不会重复加入队列
+func (q *Queue) EnqueueWithOptions(ctx context.Context, jobType string, payload interface{}, opts EnqueueOptions) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+	// 调用方指定的ID可能已在队列中，加入前先移除
+	custom := opts.ID != ""
+	if !custom {
+		if opts.ID, err = generateJobID(); err != nil {
+			return nil, err
+		}
+	}
+	maxRetries := opts.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = q.maxRetries
+	} else if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	now := q.now()
+	job := &Job{
+		ID:         opts.ID,
+		Type:       jobType,
+		Payload:    data,
+		MaxRetries: maxRetries,
+		CreatedAt:  now,
+		RunAt:      now.Add(opts.Delay),
+	}
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := q.client.TxPipeline()
+	pipe.HSet(ctx, q.key("jobs"), job.ID, encoded)
+	if custom {
+		pipe.LRem(ctx, q.key("ready"), 0, job.ID)
+		pipe.ZRem(ctx, q.key("delayed"), job.ID)
+	}
+	if opts.Delay > 0 {
+		pipe.ZAdd(ctx, q.key("delayed"), redis.Z{Score: float64(job.RunAt.UnixMilli()), Member: job.ID})
+	} else {
+		pipe.RPush(ctx, q.key("ready"), job.ID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	jobsEnqueued.Inc(q.name, jobType)
+	if q.hooks.OnEnqueue != nil {
+		q.hooks.OnEnqueue(job)
+	}
+	return job, nil
+}
+
+// Start 启动工作协程
+func (q *Queue) Start() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	if q.started {
+		return nil
+	}
+	q.started = true
+
+	for i := 0; i < q.concurrency; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return nil
+}
+
+// Shutdown 停止领取新任务并等待执行中的任务完成，ctx 结束时取消执行中任务的 ctx 并返回，
+// 被取消的任务在租约过期后重新投递
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.stop)
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		return ctx.Err()
+	}
+}
+
+// Stats 获取队列统计
+func (q *Queue) Stats(ctx context.Context) (*Stats, error) {
+	pipe := q.client.Pipeline()
+	ready := pipe.LLen(ctx, q.key("ready"))
+	delayed := pipe.ZCard(ctx, q.key("delayed"))
+	active := pipe.ZCard(ctx, q.key("active"))
+	dead := pipe.LLen(ctx, q.key("dead"))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return &Stats{Ready: ready.Val(), Delayed: delayed.Val(), Active: active.Val(), Dead: dead.Val()}, nil
+}
+
+// DeadJobs 获取死信队列中的任务，最近进入的在前
+func (q *Queue) DeadJobs(ctx context.Context, limit int64) ([]*Job, error) {
+	ids, err := q.client.LRange(ctx, q.key("dead"), 0, limit-1).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	values, err := q.client.HMGet(ctx, q.key("jobs"), ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var job Job
+		if err := json.Unmarshal([]byte(data), &job); err == nil {
+			jobs = append(jobs, &job)
+		}
+	}
+	return jobs, nil
+}
+
+// RetryDead 将死信队列中的任务重新加入队列，重置执行次数
+func (q *Queue) RetryDead(ctx context.Context, id string) error {
+	job, err := q.job(ctx, id)
+	if err != nil {
+		return err
+	}
+	removed, err := q.client.LRem(ctx, q.key("dead"), 1, id).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrJobNotFound
+	}
+
+	job.Attempts = 0
+	job.LastError = ""
+	job.FailedAt = nil
+	job.RunAt = q.now()
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	pipe := q.client.TxPipeline()
+	pipe.HSet(ctx, q.key("jobs"), id, encoded)
+	pipe.RPush(ctx, q.key("ready"), id)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// DeleteDead 从死信队列中删除任务
+func (q *Queue) DeleteDead(ctx context.Context, id string) error {
+	removed, err := q.client.LRem(ctx, q.key("dead"), 1, id).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrJobNotFound
+	}
+	return q.client.HDel(ctx, q.key("jobs"), id).Err()
+}
+
+// work 工作协程：领取任务并执行，队列为空时按轮询间隔等待
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		job, err := q.dequeue()
+		if err != nil && q.logger != nil {
+			q.logger.WithError(err).Errorf("Queue %s failed to fetch job", q.name)
+		}
+		if job == nil {
+			select {
+			case <-q.stop:
+				return
+			case <-time.After(q.pollInterval):
+			}
+			continue
+		}
+		q.process(job)
+	}
+}
+
+// dequeue 领取一个任务，队列为空时返回nil
+func (q *Queue) dequeue() (*Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), q.redisTimeout)
+	defer cancel()
+
+	now := q.now()
+	lease := now.Add(2 * q.timeout)
+	keys := []string{q.key("ready"), q.key("delayed"), q.key("active"), q.key("jobs")}
+	data, err := dequeueScript.Run(ctx, q.client, keys, now.UnixMilli(), lease.UnixMilli()).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %w", err)
+	}
+	return &job, nil
+}
+
+// process 执行任务并根据结果确认、重试或移入死信队列
+func (q *Queue) process(job *Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), q.redisTimeout)
+
+	// 执行前记录次数，工作进程崩溃导致的重复投递同样计入
+	job.Attempts++
+	if job.Attempts > job.MaxRetries+1 {
+		q.fail(ctx, job, errors.New("job lease expired too many times"))
+		cancel()
+		return
+	}
+	if err := q.save(ctx, job); err != nil && q.logger != nil {
+		q.logger.WithError(err).Errorf("Queue %s failed to update job %s", q.name, job.ID)
+	}
+	cancel()
+
+	q.mu.RLock()
+	handler, exists := q.handlers[job.Type]
+	q.mu.RUnlock()
+
+	if q.hooks.OnStart != nil {
+		q.hooks.OnStart(job)
+	}
+	start := time.Now()
+	var err error
+	if exists {
+		err = q.invoke(handler, job)
+	} else {
+		err = fmt.Errorf("no handler registered for job type %q", job.Type)
+	}
+	duration := time.Since(start)
+	jobDuration.Observe(duration.Seconds(), q.name, job.Type)
+
+	// 处理函数可能执行很久，确认和失败处理使用新的超时
+	ctx, cancel = context.WithTimeout(context.Background(), q.redisTimeout)
+	defer cancel()
+	if err != nil {
+		q.fail(ctx, job, err)
+		return
+	}
+
+	pipe := q.client.TxPipeline()
+	pipe.ZRem(ctx, q.key("active"), job.ID)
+	pipe.HDel(ctx, q.key("jobs"), job.ID)
+	if _, err := pipe.Exec(ctx); err != nil && q.logger != nil {
+		q.logger.WithError(err).Errorf("Queue %s failed to acknowledge job %s", q.name, job.ID)
+	}
+	jobsProcessed.Inc(q.name, job.Type, statusSucceeded)
+	if q.hooks.OnSuccess != nil {
+		q.hooks.OnSuccess(job, duration)
+	}
+}
+
+// invoke 在超时控制下调用处理函数，panic 转为错误
+func (q *Queue) invoke(handler Handler, job *Job) (err error) {
+	ctx, cancel := context.WithTimeout(q.ctx, q.timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panic: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// fail 任务失败：还有重试次数时按退避时间重新排期，否则移入死信队列
+func (q *Queue) fail(ctx context.Context, job *Job, cause error) {
+	job.LastError = cause.Error()
+	willRetry := job.Attempts <= job.MaxRetries
+
+	pipe := q.client.TxPipeline()
+	pipe.ZRem(ctx, q.key("active"), job.ID)
+	if willRetry {
+		job.RunAt = q.now().Add(q.backoff(job.Attempts))
+		pipe.ZAdd(ctx, q.key("delayed"), redis.Z{Score: float64(job.RunAt.UnixMilli()), Member: job.ID})
+	} else {
+		failedAt := q.now()
+		job.FailedAt = &failedAt
+		pipe.LPush(ctx, q.key("dead"), job.ID)
+	}
+	if encoded, err := json.Marshal(job); err == nil {
+		pipe.HSet(ctx, q.key("jobs"), job.ID, encoded)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && q.logger != nil {
+		q.logger.WithError(err).Errorf("Queue %s failed to update failed job %s", q.name, job.ID)
+	}
+
+	if q.hooks.OnFailure != nil {
+		q.hooks.OnFailure(job, cause, willRetry)
+	}
+	if willRetry {
+		jobsProcessed.Inc(q.name, job.Type, statusRetried)
+		if q.logger != nil {
+			q.logger.WithError(cause).Warnf("Queue %s job %s (%s) failed, retrying at %s", q.name, job.ID, job.Type, job.RunAt.Format(time.RFC3339))
+		}
+		return
+	}
+
+	jobsProcessed.Inc(q.name, job.Type, statusDead)
+	if q.logger != nil {
+		q.logger.WithError(cause).Errorf("Queue %s job %s (%s) moved to dead letter queue after %d attempts", q.name, job.ID, job.Type, job.Attempts)
+	}
+	if q.hooks.OnDead != nil {
+		q.hooks.OnDead(job, cause)
+	}
+}
+
+// job 读取任务
+func (q *Queue) job(ctx context.Context, id string) (*Job, error) {
+	data, err := q.client.HGet(ctx, q.key("jobs"), id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %w", err)
+	}
+	return &job, nil
+}
+
+// save 保存任务
+func (q *Queue) save(ctx context.Context, job *Job) error {
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return q.client.HSet(ctx, q.key("jobs"), job.ID, encoded).Err()
+}
+
+// key 队列在Redis中的键，包含缓存管理器的前缀
+func (q *Queue) key(suffix string) string {
+	return q.cache.Key("queue:" + q.name + ":" + suffix)
+}
+
+// generateJobID 生成任务ID
+func generateJobID() (string, error) {
+	bytes := make([]byte, 12)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate job id: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,233 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hwh/hwhkit-go/pkg/cache"
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestQueue(t *testing.T) (*Queue, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	port, err := strconv.Atoi(mr.Port())
+	require.NoError(t, err)
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	require.NoError(t, err)
+	t.Cleanup(func() { cacheManager.Close() })
+
+	q := New(cacheManager, "test")
+	q.SetPollInterval(10 * time.Millisecond)
+	q.SetBackoff(func(int) time.Duration { return 0 })
+	return q, mr
+}
+
+func TestQueueProcess(t *testing.T) {
+	q, mr := newTestQueue(t)
+	ctx := context.Background()
+
+	type welcome struct {
+		UserID int `json:"user_id"`
+	}
+	received := make(chan int, 1)
+	var succeeded atomic.Int32
+	q.SetHooks(Hooks{OnSuccess: func(job *Job, duration time.Duration) { succeeded.Add(1) }})
+	q.Handle("welcome", func(ctx context.Context, job *Job) error {
+		var payload welcome
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+		received <- payload.UserID
+		return nil
+	})
+
+	job, err := q.Enqueue(ctx, "welcome", welcome{UserID: 7})
+	require.NoError(t, err)
+	assert.NotEmpty(t, job.ID)
+	assert.True(t, mr.Exists("app:queue:test:jobs"))
+
+	require.NoError(t, q.Start())
+	select {
+	case id := <-received:
+		assert.Equal(t, 7, id)
+	case <-time.After(2 * time.Second):
+		t.Fatal("job was not processed")
+	}
+	require.NoError(t, q.Shutdown(ctx))
+	assert.Equal(t, int32(1), succeeded.Load())
+
+	stats, err := q.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Stats{}, *stats)
+	assert.ErrorIs(t, q.Start(), ErrQueueClosed)
+}
+
+func TestQueueRetryAndDeadLetter(t *testing.T) {
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	dead := make(chan *Job, 1)
+	q.SetHooks(Hooks{OnDead: func(job *Job, err error) { dead <- job }})
+	q.Handle("flaky", func(ctx context.Context, job *Job) error {
+		attempts.Add(1)
+		return errors.New("boom")
+	})
+
+	_, err := q.EnqueueWithOptions(ctx, "flaky", map[string]string{"a": "b"}, EnqueueOptions{ID: "job-1", MaxRetries: 2})
+	require.NoError(t, err)
+	require.NoError(t, q.Start())
+
+	select {
+	case job := <-dead:
+		assert.Equal(t, "job-1", job.ID)
+		assert.Equal(t, 3, job.Attempts)
+		assert.Equal(t, "boom", job.LastError)
+	case <-time.After(2 * time.Second):
+		t.Fatal("job did not reach dead letter queue")
+	}
+	require.NoError(t, q.Shutdown(ctx))
+	assert.Equal(t, int32(3), attempts.Load())
+
+	jobs, err := q.DeadJobs(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.NotNil(t, jobs[0].FailedAt)
+
+	require.NoError(t, q.RetryDead(ctx, "job-1"))
+	stats, err := q.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Ready)
+	assert.Equal(t, int64(0), stats.Dead)
+	assert.ErrorIs(t, q.RetryDead(ctx, "job-1"), ErrJobNotFound)
+}
+
+func TestQueueDelayAndShutdown(t *testing.T) {
+	q, mr := newTestQueue(t)
+	ctx := context.Background()
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+
+	_, err := q.EnqueueWithOptions(ctx, "later", nil, EnqueueOptions{Delay: time.Minute})
+	require.NoError(t, err)
+	job, err := q.dequeue()
+	require.NoError(t, err)
+	assert.Nil(t, job, "delayed job should not be ready yet")
+
+	q.now = func() time.Time { return now.Add(time.Minute) }
+	job, err = q.dequeue()
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, "later", job.Type)
+
+	// 租约过期后重新投递
+	q.now = func() time.Time { return now.Add(time.Minute + 2*q.timeout) }
+	again, err := q.dequeue()
+	require.NoError(t, err)
+	require.NotNil(t, again)
+	assert.Equal(t, job.ID, again.ID)
+	mr.Del("app:queue:test:active")
+
+	// 关闭时等待执行中的任务完成
+	q.now = time.Now
+	started := make(chan struct{})
+	var finished atomic.Bool
+	q.Handle("slow", func(ctx context.Context, job *Job) error {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		finished.Store(true)
+		return nil
+	})
+	_, err = q.Enqueue(ctx, "slow", nil)
+	require.NoError(t, err)
+	require.NoError(t, q.Start())
+	<-started
+	require.NoError(t, q.Shutdown(ctx))
+	assert.True(t, finished.Load())
+
+	// 超时后取消处理函数的 ctx
+	q2, _ := newTestQueue(t)
+	cancelled := make(chan struct{})
+	q2.Handle("stuck", func(ctx context.Context, job *Job) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+	_, err = q2.Enqueue(ctx, "stuck", nil)
+	require.NoError(t, err)
+	require.NoError(t, q2.Start())
+	time.Sleep(50 * time.Millisecond)
+	shutdownCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, q2.Shutdown(shutdownCtx), context.DeadlineExceeded)
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("handler context was not cancelled")
+	}
+	q2.wg.Wait()
+}
+
+func TestQueueAcknowledgeAfterLongJob(t *testing.T) {
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+	// 缩短Redis操作超时，处理函数耗时超过该超时后仍应确认任务
+	q.redisTimeout = 50 * time.Millisecond
+
+	done := make(chan struct{})
+	q.SetHooks(Hooks{OnSuccess: func(job *Job, duration time.Duration) { close(done) }})
+	q.Handle("long", func(ctx context.Context, job *Job) error {
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	})
+	_, err := q.Enqueue(ctx, "long", nil)
+	require.NoError(t, err)
+	require.NoError(t, q.Start())
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("long job was not processed")
+	}
+	require.NoError(t, q.Shutdown(ctx))
+
+	stats, err := q.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &Stats{}, stats)
+	assert.Equal(t, int64(0), q.client.HLen(ctx, q.key("jobs")).Val())
+}
+
+func TestQueueEnqueueSameID(t *testing.T) {
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := q.EnqueueWithOptions(ctx, "sync", map[string]int{"version": i}, EnqueueOptions{ID: "user-1"})
+		require.NoError(t, err)
+	}
+	stats, err := q.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Ready)
+
+	// 改为延迟执行时从就绪队列移除
+	_, err = q.EnqueueWithOptions(ctx, "sync", map[string]int{"version": 3}, EnqueueOptions{ID: "user-1", Delay: time.Hour})
+	require.NoError(t, err)
+	stats, err = q.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), stats.Ready)
+	assert.Equal(t, int64(1), stats.Delayed)
+
+	job, err := q.job(ctx, "user-1")
+	require.NoError(t, err)
+	var payload map[string]int
+	require.NoError(t, job.Decode(&payload))
+	assert.Equal(t, 3, payload["version"])
+}
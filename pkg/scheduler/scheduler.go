@@ -0,0 +1,432 @@
+// Package scheduler 提供基于cron表达式的定时任务调度，支持超时控制、panic恢复，以及通过Redis锁保证集群内每次调度只有一个实例执行
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/cache"
+	"github.com/hwh/hwhkit-go/pkg/logger"
+	"github.com/hwh/hwhkit-go/pkg/metrics"
+)
+
+var (
+	// ErrJobNotFound 任务不存在
+	ErrJobNotFound = errors.New("scheduled job not found")
+	// ErrJobExists 任务名称重复
+	ErrJobExists = errors.New("scheduled job already registered")
+	// ErrJobRunning 任务正在执行
+	ErrJobRunning = errors.New("scheduled job is already running")
+	// ErrSchedulerClosed 调度器已关闭
+	ErrSchedulerClosed = errors.New("scheduler closed")
+)
+
+// 任务执行结果
+const (
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+	ResultSkipped   = "skipped" // 上次执行未结束，或其他实例已执行本次调度，只计入指标和 Skips
+)
+
+var (
+	jobRuns = metrics.Default.Counter("hwhkit_scheduler_runs_total",
+		"Total number of scheduled job runs", "job", "result")
+	jobDuration = metrics.Default.Histogram("hwhkit_scheduler_run_duration_seconds",
+		"Scheduled job run duration in seconds", nil, "job")
+)
+
+// JobFunc 定时任务函数，ctx 在超时、锁丢失或调度器关闭超时后取消
+type JobFunc func(ctx context.Context) error
+
+// JobOptions 任务选项
+type JobOptions struct {
+	Timeout   time.Duration // 单次执行超时，为0时使用调度器默认值
+	Singleton bool          // 集群内每次调度只由一个实例执行，需要调用 SetLocker
+}
+
+// JobStatus 任务状态
+type JobStatus struct {
+	Name         string        `json:"name"`
+	Spec         string        `json:"spec"`
+	Singleton    bool          `json:"singleton"`
+	Running      bool          `json:"running"`
+	NextRun      *time.Time    `json:"next_run,omitempty"`
+	LastRun      *time.Time    `json:"last_run,omitempty"`
+	LastDuration time.Duration `json:"last_duration"`
+	LastResult   string        `json:"last_result,omitempty"`
+	LastError    string        `json:"last_error,omitempty"`
+	Runs         int64         `json:"runs"`
+	Failures     int64         `json:"failures"`
+	Skips        int64         `json:"skips"`
+}
+
+// job 已注册的任务
+type job struct {
+	name     string
+	spec     string
+	schedule Schedule
+	fn       JobFunc
+	opts     JobOptions
+
+	mu     sync.Mutex
+	status JobStatus
+}
+
+// Scheduler 定时任务调度器
+//
+//	sched := scheduler.New()
+//	sched.SetLocker(cacheManager, 0)
+//	sched.RegisterWithOptions("cleanup", "0 3 * * *", cleanup, scheduler.JobOptions{Singleton: true, Timeout: time.Hour})
+//	sched.Start()
+//	srv, _ := server.New(&server.ServerConfig{..., Scheduler: sched})
+type Scheduler struct {
+	mu       sync.RWMutex
+	jobs     map[string]*job
+	started  bool
+	closed   bool
+	location *time.Location
+	timeout  time.Duration
+	locker   *cache.Manager
+	lockTTL  time.Duration
+	instance string
+	logger   *logger.Manager
+
+	ctx    context.Context // 传给任务函数，关闭超时后取消
+	cancel context.CancelFunc
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	now    func() time.Time
+}
+
+// New 创建调度器，默认使用本地时区，单次执行超时1小时
+func New() *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	hostname, _ := os.Hostname()
+	return &Scheduler{
+		jobs:     make(map[string]*job),
+		location: time.Local,
+		timeout:  time.Hour,
+		lockTTL:  30 * time.Second,
+		instance: hostname + ":" + strconv.Itoa(os.Getpid()),
+		ctx:      ctx,
+		cancel:   cancel,
+		stop:     make(chan struct{}),
+		now:      time.Now,
+	}
+}
+
+// SetLocation 设置cron表达式使用的时区
+func (s *Scheduler) SetLocation(loc *time.Location) {
+	if loc != nil {
+		s.location = loc
+	}
+}
+
+// SetTimeout 设置默认的单次执行超时
+func (s *Scheduler) SetTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		s.timeout = timeout
+	}
+}
+
+// SetLocker 设置用于单实例执行的Redis连接，ttl 为执行锁的有效期（看门狗自动续期），为0时默认30秒
+func (s *Scheduler) SetLocker(cacheManager *cache.Manager, ttl time.Duration) {
+	s.locker = cacheManager
+	if ttl > 0 {
+		s.lockTTL = ttl
+	}
+}
+
+// SetLogger 设置日志管理器，记录任务失败和panic
+func (s *Scheduler) SetLogger(l *logger.Manager) {
+	s.logger = l
+}
+
+// Register 注册定时任务，spec 格式见 ParseCron
+func (s *Scheduler) Register(name, spec string, fn JobFunc) error {
+	return s.RegisterWithOptions(name, spec, fn, JobOptions{})
+}
+
+// RegisterWithOptions 按选项注册定时任务，调度器启动后注册的任务立即开始调度
+func (s *Scheduler) RegisterWithOptions(name, spec string, fn JobFunc, opts JobOptions) error {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return err
+	}
+	if opts.Singleton && s.locker == nil {
+		return fmt.Errorf("job %s: singleton jobs require SetLocker", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrSchedulerClosed
+	}
+	if _, exists := s.jobs[name]; exists {
+		return fmt.Errorf("%w: %s", ErrJobExists, name)
+	}
+	j := &job{
+		name:     name,
+		spec:     spec,
+		schedule: schedule,
+		fn:       fn,
+		opts:     opts,
+		status:   JobStatus{Name: name, Spec: spec, Singleton: opts.Singleton},
+	}
+	s.jobs[name] = j
+	if s.started {
+		s.wg.Add(1)
+		go s.loop(j)
+	}
+	return nil
+}
+
+// Start 开始调度所有已注册的任务
+func (s *Scheduler) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrSchedulerClosed
+	}
+	if s.started {
+		return nil
+	}
+	s.started = true
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(j)
+	}
+	return nil
+}
+
+// Shutdown 停止调度并等待执行中的任务结束，ctx 结束时取消任务的 ctx 并返回
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.stop)
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return ctx.Err()
+	}
+}
+
+// RunNow 立即在后台执行任务，不影响正常调度；单实例任务仍需获取执行锁
+func (s *Scheduler) RunNow(name string) error {
+	// 持有读锁直到任务开始，避免与 Shutdown 并发
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	j, exists := s.jobs[name]
+	if !exists {
+		return ErrJobNotFound
+	}
+	if s.closed {
+		return ErrSchedulerClosed
+	}
+	if !s.trigger(j, time.Time{}) {
+		return ErrJobRunning
+	}
+	return nil
+}
+
+// Jobs 获取所有任务的状态，按名称排序
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.RLock()
+	jobs := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	s.mu.RUnlock()
+
+	statuses := make([]JobStatus, 0, len(jobs))
+	for _, j := range jobs {
+		statuses = append(statuses, j.snapshot())
+	}
+	sort.Slice(statuses, func(i, k int) bool { return statuses[i].Name < statuses[k].Name })
+	return statuses
+}
+
+// Job 获取任务状态
+func (s *Scheduler) Job(name string) (JobStatus, error) {
+	s.mu.RLock()
+	j, exists := s.jobs[name]
+	s.mu.RUnlock()
+	if !exists {
+		return JobStatus{}, ErrJobNotFound
+	}
+	return j.snapshot(), nil
+}
+
+// loop 任务调度循环
+func (s *Scheduler) loop(j *job) {
+	defer s.wg.Done()
+
+	for {
+		next := j.schedule.Next(s.now().In(s.location))
+		if next.IsZero() {
+			return
+		}
+		j.mu.Lock()
+		j.status.NextRun = &next
+		j.mu.Unlock()
+
+		timer := time.NewTimer(next.Sub(s.now()))
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		// 上次执行未结束时跳过本次调度
+		if !s.trigger(j, next) {
+			jobRuns.Inc(j.name, ResultSkipped)
+			j.mu.Lock()
+			j.status.Skips++
+			j.mu.Unlock()
+		}
+	}
+}
+
+// trigger 在后台执行任务，上次执行未结束时返回 false；tick 为本次调度时间，手动执行时为零值
+func (s *Scheduler) trigger(j *job, tick time.Time) bool {
+	j.mu.Lock()
+	if j.status.Running {
+		j.mu.Unlock()
+		return false
+	}
+	j.status.Running = true
+	j.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.execute(j, tick)
+	}()
+	return true
+}
+
+// execute 执行任务；单实例任务先认领本次调度，再持有执行锁运行，避免多个实例重复执行或长任务重叠执行
+func (s *Scheduler) execute(j *job, tick time.Time) {
+	if !j.opts.Singleton || s.locker == nil {
+		s.run(j, s.ctx)
+		return
+	}
+
+	if !tick.IsZero() {
+		claimed, err := s.claim(j, tick)
+		if err != nil {
+			s.finish(j, ResultFailed, fmt.Sprintf("failed to claim run: %v", err), 0)
+			return
+		}
+		if !claimed {
+			s.finish(j, ResultSkipped, "run claimed by another instance", 0)
+			return
+		}
+	}
+
+	mutex := cache.NewMutex(s.locker, "scheduler:"+j.name, s.lockTTL)
+	err := mutex.TryDo(s.ctx, func(ctx context.Context) error {
+		s.run(j, ctx)
+		return nil
+	})
+	if errors.Is(err, cache.ErrLockNotAcquired) {
+		s.finish(j, ResultSkipped, "job is running on another instance", 0)
+	} else if err != nil {
+		s.finish(j, ResultFailed, fmt.Sprintf("failed to acquire lock: %v", err), 0)
+	}
+}
+
+// claim 认领本次调度，键在下一次调度时间前过期
+func (s *Scheduler) claim(j *job, tick time.Time) (bool, error) {
+	ttl := time.Minute
+	if next := j.schedule.Next(tick); !next.IsZero() && next.Sub(tick) > time.Second {
+		ttl = next.Sub(tick)
+	}
+	key := s.locker.Key("scheduler:" + j.name + ":" + strconv.FormatInt(tick.Unix(), 10))
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+	return s.locker.GetClient().SetNX(ctx, key, s.instance, ttl).Result()
+}
+
+// run 在超时控制下执行任务函数，panic 转为失败
+func (s *Scheduler) run(j *job, parent context.Context) {
+	timeout := j.opts.Timeout
+	if timeout <= 0 {
+		timeout = s.timeout
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("job panic: %v", r)
+			}
+		}()
+		return j.fn(ctx)
+	}()
+	duration := time.Since(start)
+	jobDuration.Observe(duration.Seconds(), j.name)
+
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("job timed out after %s: %w", timeout, err)
+		}
+		if s.logger != nil {
+			s.logger.WithError(err).Errorf("Scheduled job %s failed", j.name)
+		}
+		s.finish(j, ResultFailed, err.Error(), duration)
+		return
+	}
+	s.finish(j, ResultSucceeded, "", duration)
+}
+
+// finish 记录执行结果并清除执行中状态，跳过的调度只计数，不覆盖上次执行的结果
+func (s *Scheduler) finish(j *job, result, message string, duration time.Duration) {
+	jobRuns.Inc(j.name, result)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Running = false
+	if result == ResultSkipped {
+		j.status.Skips++
+		return
+	}
+	if result == ResultFailed {
+		j.status.Failures++
+	}
+	j.status.LastResult = result
+	j.status.LastError = message
+	now := s.now()
+	j.status.LastRun = &now
+	j.status.LastDuration = duration
+	j.status.Runs++
+}
+
+// snapshot 复制任务状态
+func (j *job) snapshot() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
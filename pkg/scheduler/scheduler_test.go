@@ -0,0 +1,195 @@
+package scheduler
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hwh/hwhkit-go/pkg/cache"
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 30, 15, 0, time.UTC) // 周三
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2024, 5, 1, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 5, 1, 10, 45, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, 5, 2, 3, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC)},
+		{"0 9 * * MON-FRI", time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// 日和周同时指定时满足任意一个
+		{"0 0 15 * 5", time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)},
+		{"5-10/5 10,12 * * *", time.Date(2024, 5, 1, 12, 5, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2024, 5, 1, 10, 31, 30, 0, time.UTC)},
+		{"@every 15m", time.Date(2024, 5, 1, 10, 45, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseCron(tt.spec)
+		require.NoError(t, err, tt.spec)
+		assert.Equal(t, tt.expected, schedule.Next(base), tt.spec)
+	}
+
+	// 按时区计算
+	shanghai := time.FixedZone("CST", 8*3600)
+	schedule, err := ParseCron("0 3 * * *")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 2, 3, 0, 0, 0, shanghai), schedule.Next(base.In(shanghai)))
+
+	for _, spec := range []string{"* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *", "@every 10ms", "@every x"} {
+		_, err := ParseCron(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	sched := New()
+	ctx := context.Background()
+
+	var runs atomic.Int32
+	require.NoError(t, sched.Register("tick", "@every 1s", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}))
+	require.NoError(t, sched.Register("panics", "0 0 1 1 *", func(ctx context.Context) error {
+		panic("boom")
+	}))
+	require.NoError(t, sched.RegisterWithOptions("slow", "0 0 1 1 *", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, JobOptions{Timeout: 20 * time.Millisecond}))
+	assert.ErrorIs(t, sched.Register("tick", "@daily", nil), ErrJobExists)
+	assert.Error(t, sched.RegisterWithOptions("locked", "@daily", nil, JobOptions{Singleton: true}))
+
+	require.NoError(t, sched.Start())
+	require.NoError(t, sched.RunNow("panics"))
+	require.NoError(t, sched.RunNow("slow"))
+	assert.ErrorIs(t, sched.RunNow("missing"), ErrJobNotFound)
+
+	assert.Eventually(t, func() bool {
+		status, _ := sched.Job("tick")
+		slow, _ := sched.Job("slow")
+		return status.Runs >= 1 && slow.Runs == 1
+	}, 3*time.Second, 10*time.Millisecond)
+
+	status, err := sched.Job("panics")
+	require.NoError(t, err)
+	assert.Equal(t, ResultFailed, status.LastResult)
+	assert.Contains(t, status.LastError, "job panic: boom")
+	assert.Equal(t, int64(1), status.Failures)
+
+	status, err = sched.Job("slow")
+	require.NoError(t, err)
+	assert.Contains(t, status.LastError, "timed out")
+
+	jobs := sched.Jobs()
+	require.Len(t, jobs, 3)
+	assert.Equal(t, "panics", jobs[0].Name)
+	assert.NotNil(t, jobs[2].NextRun)
+	assert.Equal(t, ResultSucceeded, jobs[2].LastResult)
+
+	require.NoError(t, sched.Shutdown(ctx))
+	assert.ErrorIs(t, sched.RunNow("tick"), ErrSchedulerClosed)
+}
+
+// newLockedScheduler 创建使用 miniredis 作为分布式锁的调度器，模拟一个实例
+func newLockedScheduler(t *testing.T, mr *miniredis.Miniredis) *Scheduler {
+	port, err := strconv.Atoi(mr.Port())
+	require.NoError(t, err)
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	require.NoError(t, err)
+	t.Cleanup(func() { cacheManager.Close() })
+	sched := New()
+	sched.SetLocker(cacheManager, time.Second)
+	return sched
+}
+
+func TestSchedulerSingleton(t *testing.T) {
+	mr := miniredis.RunT(t)
+	newScheduler := func() *Scheduler { return newLockedScheduler(t, mr) }
+
+	var runs atomic.Int32
+	release := make(chan struct{})
+	fn := func(ctx context.Context) error {
+		runs.Add(1)
+		<-release
+		return nil
+	}
+	first, second := newScheduler(), newScheduler()
+	require.NoError(t, first.RegisterWithOptions("report", "0 * * * *", fn, JobOptions{Singleton: true}))
+	require.NoError(t, second.RegisterWithOptions("report", "0 * * * *", fn, JobOptions{Singleton: true}))
+
+	// 同一次调度只有一个实例执行
+	tick := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	firstJob, secondJob := first.jobs["report"], second.jobs["report"]
+	require.True(t, first.trigger(firstJob, tick))
+	assert.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, 5*time.Millisecond)
+	require.True(t, second.trigger(secondJob, tick))
+	assert.Eventually(t, func() bool {
+		status, _ := second.Job("report")
+		return status.Skips == 1 && !status.Running
+	}, time.Second, 5*time.Millisecond)
+	assert.True(t, mr.Exists("app:scheduler:report:"+strconv.FormatInt(tick.Unix(), 10)))
+
+	// 上一次执行未结束时，其他实例的下一次调度也被跳过
+	require.True(t, second.trigger(secondJob, tick.Add(time.Hour)))
+	assert.Eventually(t, func() bool {
+		status, _ := second.Job("report")
+		return status.Skips == 2
+	}, time.Second, 5*time.Millisecond)
+
+	close(release)
+	ctx := context.Background()
+	require.NoError(t, first.Shutdown(ctx))
+	require.NoError(t, second.Shutdown(ctx))
+	assert.Equal(t, int32(1), runs.Load())
+
+	status, err := first.Job("report")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), status.Runs)
+}
+
+func TestSchedulerEverySingleton(t *testing.T) {
+	mr := miniredis.RunT(t)
+	first, second := newLockedScheduler(t, mr), newLockedScheduler(t, mr)
+
+	var runs atomic.Int32
+	fn := func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}
+	require.NoError(t, first.RegisterWithOptions("sync", "@every 1m", fn, JobOptions{Singleton: true}))
+	require.NoError(t, second.RegisterWithOptions("sync", "@every 1m", fn, JobOptions{Singleton: true}))
+	firstJob, secondJob := first.jobs["sync"], second.jobs["sync"]
+
+	// 两个实例在不同时间启动，计算出相同的调度时间
+	started := time.Date(2024, 5, 1, 10, 0, 12, 0, time.UTC)
+	firstTick := firstJob.schedule.Next(started)
+	secondTick := secondJob.schedule.Next(started.Add(700 * time.Millisecond))
+	require.Equal(t, time.Date(2024, 5, 1, 10, 1, 0, 0, time.UTC), firstTick)
+	require.Equal(t, firstTick, secondTick)
+
+	require.True(t, first.trigger(firstJob, firstTick))
+	require.True(t, second.trigger(secondJob, secondTick))
+	assert.Eventually(t, func() bool {
+		firstStatus, _ := first.Job("sync")
+		secondStatus, _ := second.Job("sync")
+		return !firstStatus.Running && !secondStatus.Running && firstStatus.Skips+secondStatus.Skips == 1
+	}, time.Second, 5*time.Millisecond)
+
+	ctx := context.Background()
+	require.NoError(t, first.Shutdown(ctx))
+	require.NoError(t, second.Shutdown(ctx))
+	assert.Equal(t, int32(1), runs.Load())
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/logger"
+)
+
+// banner 启动横幅
+const banner = `
+  _               _     _    _ _
+ | |____      ___| |__ | | _(_) |_
+ | '_ \ \ /\ / / '_ \| |/ / | __|
+ | | | \ V  V /| | | |   <| | |_
+ |_| |_|\_/\_/ |_| |_|_|\_\_|\__|
+`
+
+// redactedSecret 替换凭证的占位符
+const redactedSecret = "***"
+
+// closureSuffix 匹配匿名函数名后缀，如 .func1、.func2.1
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+(\.\d+)*$`)
+
+// StartupSummary 生成启动配置摘要，数据库和缓存凭证已脱敏
+func (s *Server) StartupSummary() logger.Fields {
+	cfg := s.config
+	summary := logger.Fields{
+		"addr": s.httpServer.Addr,
+		"mode": cfg.Server.Mode,
+		"subsystems": map[string]bool{
+			"database": s.db != nil,
+			"cache":    s.cache != nil,
+			"auth":     s.auth != nil,
+			"cors":     cfg.Server.EnableCORS,
+			"swagger":  cfg.Server.EnableSwagger,
+			"chaos":    cfg.Server.Chaos.Enabled && cfg.Server.Mode != gin.ReleaseMode,
+			"tracing":  s.tracing != nil,
+			"tls":      s.httpServer.TLSConfig != nil,
+		},
+		"middlewares": s.middlewareChain(),
+		"routes":      len(s.engine.Routes()),
+	}
+
+	if s.db != nil {
+		db := cfg.Database
+		summary["database"] = fmt.Sprintf("%s://%s%s:%d/%s",
+			db.Type, redactUserInfo(db.User, db.Password), db.Host, db.Port, db.Name)
+	}
+	if s.cache != nil {
+		redis := cfg.Redis
+		summary["cache"] = fmt.Sprintf("redis://%s%s:%d/%d",
+			redactUserInfo("", redis.Password), redis.Host, redis.Port, redis.DB)
+		if redis.KeyPrefix != "" {
+			summary["cache_prefix"] = redis.KeyPrefix
+		}
+	}
+	if s.securityReport != nil && len(s.securityReport.Findings) > 0 {
+		summary["security_findings"] = len(s.securityReport.Findings)
+	}
+	return summary
+}
+
+// logStartupSummary 记录启动配置摘要，按配置打印横幅
+func (s *Server) logStartupSummary() {
+	if s.config.Server.ShowBanner {
+		printBanner(os.Stdout, s.httpServer.Addr, s.config.Server.Mode)
+	}
+	if s.logger != nil {
+		s.logger.WithFields(s.StartupSummary()).Info("Server configuration")
+	}
+}
+
+// printBanner 打印ASCII横幅
+func printBanner(w io.Writer, addr, mode string) {
+	fmt.Fprintf(w, "%s\n hwhkit-go  listening on %s (%s mode)\n\n", banner, addr, mode)
+}
+
+// middlewareChain 获取全局中间件名称
+func (s *Server) middlewareChain() []string {
+	names := make([]string, 0, len(s.engine.Handlers))
+	for _, handler := range s.engine.Handlers {
+		names = append(names, handlerName(handler))
+	}
+	return names
+}
+
+// handlerName 获取处理函数的简短名称，如 middleware.CORS
+func handlerName(handler gin.HandlerFunc) string {
+	return funcName(reflect.ValueOf(handler).Pointer())
+}
+
+// funcName 获取函数的简短名称，去掉包路径、匿名函数后缀和方法值的 -fm 后缀
+func funcName(pc uintptr) string {
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return "unknown"
+	}
+	name := closureSuffix.ReplaceAllString(strings.TrimSuffix(fn.Name(), "-fm"), "")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// redactUserInfo 生成连接地址中的用户信息（含@），密码以占位符代替
+func redactUserInfo(user, password string) string {
+	if password != "" {
+		user += ":" + redactedSecret
+	}
+	if user == "" {
+		return ""
+	}
+	return user + "@"
+}
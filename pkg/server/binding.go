@@ -0,0 +1,255 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrUnsupportedMediaType 请求的 Content-Type 没有对应的解码器
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// MaxBindBodySize Bind 读取请求体的上限（字节），超出时返回 *http.MaxBytesError
+var MaxBindBodySize int64 = 10 << 20
+
+// BodyDecoder 请求体解码器，Bind 按 Content-Type 选择解码器，解码后统一执行 binding 标签校验
+type BodyDecoder interface {
+	Name() string
+	MediaTypes() []string // 支持的媒体类型，如 application/json
+	Decode(req *http.Request, obj interface{}) error
+}
+
+// 内置解码器
+var (
+	JSONDecoder     BodyDecoder = jsonDecoder{}
+	FormDecoder     BodyDecoder = formDecoder{}
+	XMLDecoder      BodyDecoder = xmlDecoder{}
+	MsgPackDecoder  BodyDecoder = msgpackDecoder{}
+	ProtobufDecoder BodyDecoder = protobufDecoder{}
+)
+
+// decoderRegistry 媒体类型到解码器的映射
+type decoderRegistry struct {
+	decoders map[string]BodyDecoder
+	mutex    sync.RWMutex
+}
+
+// decoders 全局解码器注册表
+var decoders = &decoderRegistry{decoders: make(map[string]BodyDecoder)}
+
+func init() {
+	for _, decoder := range []BodyDecoder{JSONDecoder, FormDecoder, XMLDecoder, MsgPackDecoder, ProtobufDecoder} {
+		RegisterDecoder(decoder)
+	}
+}
+
+// RegisterDecoder 注册请求体解码器，同一媒体类型后注册的覆盖先注册的
+func RegisterDecoder(decoder BodyDecoder) {
+	decoders.mutex.Lock()
+	defer decoders.mutex.Unlock()
+
+	for _, mediaType := range decoder.MediaTypes() {
+		decoders.decoders[strings.ToLower(mediaType)] = decoder
+	}
+}
+
+// DecoderFor 根据 Content-Type 获取解码器
+// 未设置 Content-Type 时按JSON处理；application/*+json、application/*+xml 等结构化后缀分别按JSON、XML处理
+func DecoderFor(contentType string) (BodyDecoder, error) {
+	if strings.TrimSpace(contentType) == "" {
+		return JSONDecoder, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType)
+	}
+
+	decoders.mutex.RLock()
+	decoder, ok := decoders.decoders[mediaType]
+	decoders.mutex.RUnlock()
+	if ok {
+		return decoder, nil
+	}
+
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return JSONDecoder, nil
+	case strings.HasSuffix(mediaType, "+xml"):
+		return XMLDecoder, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType)
+}
+
+// Bind 根据请求的 Content-Type 解码请求体并执行与 ShouldBindJSON 相同的 binding 标签校验
+// 不支持的 Content-Type 返回 ErrUnsupportedMediaType，请求体超过 MaxBindBodySize 返回 *http.MaxBytesError
+func Bind(c *gin.Context, obj interface{}) error {
+	decoder, err := DecoderFor(c.ContentType())
+	if err != nil {
+		return err
+	}
+	if c.Request.Body != nil {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxBindBodySize)
+	}
+	if err := decoder.Decode(c.Request, obj); err != nil {
+		return fmt.Errorf("failed to decode %s body: %w", decoder.Name(), err)
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// BindRequest 绑定请求体，失败时返回错误响应（不支持的类型为415，请求体过大为413，其他为400）并返回 false
+// 表单请求可由浏览器跨站提交，来源不是本站或 CORS 允许的来源时返回403，防止登录等接口被CSRF利用
+func (s *Server) BindRequest(c *gin.Context, obj interface{}) bool {
+	if isFormContentType(c.ContentType()) {
+		var allowedOrigins []string
+		if s.config != nil {
+			allowedOrigins = s.config.Server.CORSAllowOrigins
+		}
+		if err := middleware.CheckOrigin(c.Request, allowedOrigins); err != nil {
+			s.Error(c, http.StatusForbidden, err.Error())
+			return false
+		}
+	}
+
+	err := Bind(c, obj)
+	if err == nil {
+		return true
+	}
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, ErrUnsupportedMediaType):
+		s.Error(c, http.StatusUnsupportedMediaType, err.Error())
+	case errors.As(err, &maxBytesErr):
+		s.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit))
+	default:
+		s.Error(c, http.StatusBadRequest, err.Error())
+	}
+	return false
+}
+
+// isFormContentType 是否为浏览器表单可以跨站提交的类型
+func isFormContentType(contentType string) bool {
+	switch strings.ToLower(contentType) {
+	case "application/x-www-form-urlencoded", "multipart/form-data", "text/plain":
+		return true
+	}
+	return false
+}
+
+// readBody 读取请求体，请求体为空时返回 io.EOF
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, io.EOF
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, io.EOF
+	}
+	return data, nil
+}
+
+// jsonDecoder JSON解码器，与 gin 一致使用 json 标签
+type jsonDecoder struct{}
+
+func (jsonDecoder) Name() string         { return "json" }
+func (jsonDecoder) MediaTypes() []string { return []string{"application/json"} }
+
+func (jsonDecoder) Decode(req *http.Request, obj interface{}) error {
+	data, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, obj)
+}
+
+// formDecoder 表单解码器，使用 form 标签；multipart 表单中的文件请通过 c.FormFile 获取
+type formDecoder struct{}
+
+func (formDecoder) Name() string { return "form" }
+
+func (formDecoder) MediaTypes() []string {
+	return []string{"application/x-www-form-urlencoded", "multipart/form-data"}
+}
+
+func (formDecoder) Decode(req *http.Request, obj interface{}) error {
+	if err := req.ParseMultipartForm(32 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return err
+	}
+	return binding.MapFormWithTag(obj, req.PostForm, "form")
+}
+
+// xmlDecoder XML解码器，使用 xml 标签
+type xmlDecoder struct{}
+
+func (xmlDecoder) Name() string         { return "xml" }
+func (xmlDecoder) MediaTypes() []string { return []string{"application/xml", "text/xml"} }
+
+func (xmlDecoder) Decode(req *http.Request, obj interface{}) error {
+	data, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(data, obj)
+}
+
+// msgpackDecoder MessagePack解码器，字段名与 json 标签一致，同一结构体可同时用于JSON和MessagePack
+type msgpackDecoder struct{}
+
+func (msgpackDecoder) Name() string { return "msgpack" }
+
+func (msgpackDecoder) MediaTypes() []string {
+	return []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}
+}
+
+func (msgpackDecoder) Decode(req *http.Request, obj interface{}) error {
+	data, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(obj)
+}
+
+// protobufDecoder Protobuf解码器，目标必须实现 proto.Message
+type protobufDecoder struct{}
+
+func (protobufDecoder) Name() string { return "protobuf" }
+
+func (protobufDecoder) MediaTypes() []string {
+	return []string{"application/x-protobuf", "application/protobuf"}
+}
+
+func (protobufDecoder) Decode(req *http.Request, obj interface{}) error {
+	msg, ok := obj.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf body requires a proto.Message, got %T", obj)
+	}
+	if req.Body == nil {
+		return io.EOF
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	// 空消息是合法的Protobuf编码
+	return proto.Unmarshal(data, msg)
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type bindPayload struct {
+	Name  string `json:"name" form:"name" xml:"name" binding:"required"`
+	Count int    `json:"count" form:"count" xml:"count" binding:"min=1"`
+}
+
+func TestBindContentTypes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	msgpackBody, err := msgpack.Marshal(map[string]interface{}{"name": "widget", "count": 3})
+	require.NoError(t, err)
+
+	var multipartBody bytes.Buffer
+	writer := multipart.NewWriter(&multipartBody)
+	require.NoError(t, writer.WriteField("name", "widget"))
+	require.NoError(t, writer.WriteField("count", "3"))
+	require.NoError(t, writer.Close())
+
+	cases := []struct {
+		name        string
+		contentType string
+		body        []byte
+	}{
+		{"json", "application/json; charset=utf-8", []byte(`{"name":"widget","count":3}`)},
+		{"json suffix", "application/vnd.api+json", []byte(`{"name":"widget","count":3}`)},
+		{"default json", "", []byte(`{"name":"widget","count":3}`)},
+		{"form", "application/x-www-form-urlencoded", []byte(url.Values{"name": {"widget"}, "count": {"3"}}.Encode())},
+		{"multipart", writer.FormDataContentType(), multipartBody.Bytes()},
+		{"xml", "application/xml", []byte(`<payload><name>widget</name><count>3</count></payload>`)},
+		{"msgpack", "application/msgpack", msgpackBody},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/items", bytes.NewReader(tc.body))
+			if tc.contentType != "" {
+				c.Request.Header.Set("Content-Type", tc.contentType)
+			}
+
+			var payload bindPayload
+			require.NoError(t, Bind(c, &payload))
+			assert.Equal(t, bindPayload{Name: "widget", Count: 3}, payload)
+		})
+	}
+}
+
+func TestBindProtobuf(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body, err := proto.Marshal(wrapperspb.String("hello"))
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/items", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/x-protobuf")
+
+	var msg wrapperspb.StringValue
+	require.NoError(t, Bind(c, &msg))
+	assert.Equal(t, "hello", msg.GetValue())
+
+	// 非 proto.Message 目标返回错误
+	c.Request = httptest.NewRequest(http.MethodPost, "/items", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/x-protobuf")
+	assert.Error(t, Bind(c, &bindPayload{}))
+}
+
+func TestBindRequestErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{}
+	router := gin.New()
+	router.POST("/items", func(c *gin.Context) {
+		var payload bindPayload
+		if !server.BindRequest(c, &payload) {
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	send := func(contentType, body string, header ...string) (int, Response) {
+		req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp Response
+		if w.Body.Len() > 0 {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w.Code, resp
+	}
+
+	code, _ := send("application/x-www-form-urlencoded", "name=widget&count=2")
+	assert.Equal(t, http.StatusNoContent, code)
+
+	// 所有格式共用同一套校验
+	code, resp := send("application/x-www-form-urlencoded", "count=0")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, resp.Message, "Name")
+
+	code, _ = send("application/json", `{"name":`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, resp = send("text/csv", "name,count")
+	assert.Equal(t, http.StatusUnsupportedMediaType, code)
+	assert.Contains(t, resp.Message, "text/csv")
+
+	// 请求体超过上限返回413
+	code, resp = send("application/json", `{"name":"`+strings.Repeat("x", int(MaxBindBodySize))+`"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+	assert.Contains(t, resp.Message, "request body exceeds")
+
+	// 跨站提交的表单被拒绝，同源页面和非浏览器客户端不受影响；JSON请求不能由表单跨站发起，不检查来源
+	code, _ = send("application/x-www-form-urlencoded", "name=widget&count=2", "Origin", "https://evil.example")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = send("multipart/form-data; boundary=x", "--x--", "Referer", "https://evil.example/login")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = send("application/x-www-form-urlencoded", "name=widget&count=2", "Sec-Fetch-Site", "cross-site")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = send("application/x-www-form-urlencoded", "name=widget&count=2", "Origin", "http://example.com")
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _ = send("application/json", `{"name":"widget","count":2}`, "Origin", "https://evil.example")
+	assert.Equal(t, http.StatusNoContent, code)
+
+	server.config = &config.Config{Server: config.ServerConfig{CORSAllowOrigins: []string{"https://app.example"}}}
+	code, _ = send("application/x-www-form-urlencoded", "name=widget&count=2", "Origin", "https://app.example")
+	assert.Equal(t, http.StatusNoContent, code)
+}
+
+// csvDecoder 测试用的自定义解码器
+type csvDecoder struct{}
+
+func (csvDecoder) Name() string         { return "csv" }
+func (csvDecoder) MediaTypes() []string { return []string{"text/csv"} }
+
+func (csvDecoder) Decode(req *http.Request, obj interface{}) error {
+	payload := obj.(*bindPayload)
+	payload.Name = "from-csv"
+	payload.Count = 1
+	return nil
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	RegisterDecoder(csvDecoder{})
+	defer func() {
+		decoders.mutex.Lock()
+		delete(decoders.decoders, "text/csv")
+		decoders.mutex.Unlock()
+	}()
+
+	decoder, err := DecoderFor("text/csv; charset=utf-8")
+	require.NoError(t, err)
+	assert.Equal(t, "csv", decoder.Name())
+
+	_, err = DecoderFor("application/octet-stream")
+	assert.ErrorIs(t, err, ErrUnsupportedMediaType)
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/cache"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+)
+
+// maxScanLimit 列出缓存键时的最大数量
+const maxScanLimit = 1000
+
+// registerCacheAdminRoutes 注册缓存管理接口，应挂载在需要管理员权限的路由组下
+//
+//	GET    /cache/stats                     命中率统计
+//	GET    /cache/keys?pattern=user:*       列出匹配的键（SCAN）
+//	GET    /cache/key?key=user:1            查看键的值和TTL
+//	DELETE /cache/keys?pattern=user:*       按模式删除，dry_run=true 时只统计
+//	DELETE /cache/namespaces/:namespace     清空子命名空间
+func (s *Server) registerCacheAdminRoutes(router gin.IRouter) {
+	group := router.Group("/cache")
+	group.GET("/stats", s.cacheStatsHandler)
+	group.GET("/keys", s.cacheScanHandler)
+	group.GET("/key", s.cacheInspectHandler)
+	group.DELETE("/keys", s.cacheDeleteHandler)
+	group.DELETE("/namespaces/:namespace", s.cacheFlushNamespaceHandler)
+}
+
+// requireCache 检查缓存管理器是否可用
+func (s *Server) requireCache(c *gin.Context) bool {
+	if s.cache == nil {
+		s.Error(c, http.StatusServiceUnavailable, "cache is not configured")
+		return false
+	}
+	return true
+}
+
+// cacheStatsHandler 缓存命中率统计
+func (s *Server) cacheStatsHandler(c *gin.Context) {
+	if !s.requireCache(c) {
+		return
+	}
+	s.Success(c, s.cache.HitStats())
+}
+
+// cacheScanHandler 列出匹配模式的键
+func (s *Server) cacheScanHandler(c *gin.Context) {
+	if !s.requireCache(c) {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > maxScanLimit {
+		limit = maxScanLimit
+	}
+	keys, err := s.cache.ScanKeys(c.DefaultQuery("pattern", "*"), limit)
+	if err != nil {
+		s.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.Success(c, gin.H{"keys": keys, "count": len(keys)})
+}
+
+// cacheInspectHandler 查看键的值和TTL
+func (s *Server) cacheInspectHandler(c *gin.Context) {
+	if !s.requireCache(c) {
+		return
+	}
+
+	key := c.Query("key")
+	if key == "" {
+		s.Error(c, http.StatusBadRequest, "key is required")
+		return
+	}
+	info, err := s.cache.Inspect(key)
+	if err != nil {
+		if errors.Is(err, cache.ErrKeyNotFound) {
+			s.Error(c, http.StatusNotFound, err.Error())
+			return
+		}
+		s.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.Success(c, info)
+}
+
+// cacheDeleteHandler 按模式删除键
+func (s *Server) cacheDeleteHandler(c *gin.Context) {
+	if !s.requireCache(c) {
+		return
+	}
+
+	pattern := c.Query("pattern")
+	if pattern == "" {
+		s.Error(c, http.StatusBadRequest, "pattern is required")
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	result, err := s.cache.DeleteByPattern(pattern, dryRun)
+	if err != nil {
+		s.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.logCacheAdmin(c, "delete pattern", result)
+	s.Success(c, result)
+}
+
+// cacheFlushNamespaceHandler 清空子命名空间
+func (s *Server) cacheFlushNamespaceHandler(c *gin.Context) {
+	if !s.requireCache(c) {
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	result, err := s.cache.FlushNamespace(c.Param("namespace"), dryRun)
+	if err != nil {
+		s.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.logCacheAdmin(c, "flush namespace "+c.Param("namespace"), result)
+	s.Success(c, result)
+}
+
+// logCacheAdmin 记录缓存删除操作
+func (s *Server) logCacheAdmin(c *gin.Context, action string, result *cache.PatternDeleteResult) {
+	if s.logger == nil || result.DryRun {
+		return
+	}
+	operator, _ := middleware.GetUsername(c)
+	s.logger.Warnf("Cache admin %s: pattern=%s deleted=%d operator=%s", action, result.Pattern, result.Deleted, operator)
+}
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// RouteChain 路由的完整处理函数链（全局中间件、路由组中间件、路由处理函数），名称格式同 handlerName，如 middleware.JWT
+type RouteChain struct {
+	Method   string
+	Path     string
+	Handlers []string
+	Meta     *RouteMeta
+}
+
+// Index 返回第一个匹配任一名称的处理函数位置，不存在时返回 -1
+func (r RouteChain) Index(names ...string) int {
+	for i, handler := range r.Handlers {
+		for _, name := range names {
+			if handler == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// ChainRule 自定义中间件链规则，返回 nil 表示通过
+type ChainRule func(route RouteChain) error
+
+// ChainPolicy 中间件链校验策略，名称均为 handlerName 格式（包名.函数名，方法为 包名.(*类型).方法名）
+type ChainPolicy struct {
+	AuthMiddlewares       []string // 认证中间件，设置 user_id、claims 等上下文键
+	GuardMiddlewares      []string // 拒绝匿名请求但不设置 user_id、claims 的中间件，满足公开写接口限流规则，但不能放在授权中间件之前
+	AuthzMiddlewares      []string // 授权中间件，读取认证结果，必须在认证中间件之后
+	PaginationMiddlewares []string // 设置 page、page_size 上下文键的中间件
+	PaginatedHandlers     []string // 读取分页上下文键的处理函数，必须在分页中间件之后；RouteMeta.Paginated 的路由同样要求
+	RateLimitMiddlewares  []string // 限流中间件
+
+	RequirePublicWriteRateLimit bool     // 未经认证中间件的 POST 路由必须有限流中间件
+	PublicWriteExemptPaths      []string // 不要求限流的公开 POST 路径前缀，如 /webhooks/
+	Rules                       []ChainRule
+}
+
+// DefaultChainPolicy 默认校验策略，覆盖本工具包内置的认证、授权、分页和限流中间件
+func DefaultChainPolicy() *ChainPolicy {
+	return &ChainPolicy{
+		AuthMiddlewares: []string{
+			"middleware.JWT",
+			"middleware.TrustedHeader",
+			"server.(*Server).devJWT",
+		},
+		GuardMiddlewares: []string{"middleware.RequireClientCert"},
+		AuthzMiddlewares: []string{
+			"middleware.RequireRole",
+			"middleware.RequirePermission",
+			"middleware.RequirePolicy",
+			"middleware.OrgContext",
+			"middleware.RequireOrgPermission",
+			"middleware.RequireOrgRole",
+		},
+		PaginationMiddlewares: []string{"middleware.Pagination"},
+		PaginatedHandlers:     []string{"server.(*Server).handleListUsers"},
+		RateLimitMiddlewares: []string{
+			"middleware.(*RateLimiter).Middleware", // RateLimit、RateLimitByIP 等均由 RateLimiter 实现
+			"middleware.Quota",
+		},
+		RequirePublicWriteRateLimit: true,
+	}
+}
+
+// ChainViolation 中间件链违规
+type ChainViolation struct {
+	Method  string
+	Path    string
+	Message string
+}
+
+// String 违规说明
+func (v ChainViolation) String() string {
+	return fmt.Sprintf("%s %s: %s", v.Method, v.Path, v.Message)
+}
+
+// ChainVerificationError 中间件链校验失败，包含所有违规项
+type ChainVerificationError struct {
+	Violations []ChainViolation
+}
+
+// Error 错误信息，每条违规一行
+func (e *ChainVerificationError) Error() string {
+	lines := make([]string, 0, len(e.Violations)+1)
+	lines = append(lines, fmt.Sprintf("middleware chain verification failed (%d violations):", len(e.Violations)))
+	for _, violation := range e.Violations {
+		lines = append(lines, "  "+violation.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SetChainPolicy 设置启动时使用的中间件链校验策略，未设置时使用 DefaultChainPolicy
+func (s *Server) SetChainPolicy(policy *ChainPolicy) {
+	s.chainPolicy = policy
+}
+
+// RouteChains 获取所有已注册路由的完整处理函数链，按路径和方法排序
+// gin 未公开路由的处理函数链，这里通过反射只读访问路由树
+func (s *Server) RouteChains() ([]RouteChain, error) {
+	trees := reflect.ValueOf(s.engine).Elem().FieldByName("trees")
+	if !trees.IsValid() || trees.Kind() != reflect.Slice {
+		return nil, errors.New("cannot inspect gin route tree")
+	}
+
+	var chains []RouteChain
+	for i := 0; i < trees.Len(); i++ {
+		tree := trees.Index(i)
+		method, root := tree.FieldByName("method"), tree.FieldByName("root")
+		if !method.IsValid() || !root.IsValid() {
+			return nil, errors.New("cannot inspect gin route tree")
+		}
+		if err := collectRouteChains(root, method.String(), &chains); err != nil {
+			return nil, err
+		}
+	}
+
+	s.routes.mu.RLock()
+	for i := range chains {
+		meta, exists := s.routes.meta[routeKey(chains[i].Method, chains[i].Path)]
+		if !exists {
+			meta, exists = s.routes.meta[routeKey("ANY", chains[i].Path)]
+		}
+		if exists {
+			chains[i].Meta = &meta
+		}
+	}
+	s.routes.mu.RUnlock()
+
+	sort.Slice(chains, func(i, j int) bool {
+		if chains[i].Path != chains[j].Path {
+			return chains[i].Path < chains[j].Path
+		}
+		return chains[i].Method < chains[j].Method
+	})
+	return chains, nil
+}
+
+// collectRouteChains 递归收集路由树节点上的处理函数链
+func collectRouteChains(node reflect.Value, method string, chains *[]RouteChain) error {
+	if node.Kind() != reflect.Ptr || node.IsNil() {
+		return nil
+	}
+	n := node.Elem()
+	handlers, fullPath, children := n.FieldByName("handlers"), n.FieldByName("fullPath"), n.FieldByName("children")
+	if !handlers.IsValid() || !fullPath.IsValid() || !children.IsValid() {
+		return errors.New("cannot inspect gin route tree")
+	}
+
+	if handlers.Len() > 0 {
+		names := make([]string, handlers.Len())
+		for i := range names {
+			names[i] = funcName(handlers.Index(i).Pointer())
+		}
+		*chains = append(*chains, RouteChain{Method: method, Path: fullPath.String(), Handlers: names})
+	}
+	for i := 0; i < children.Len(); i++ {
+		if err := collectRouteChains(children.Index(i), method, chains); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyMiddlewareChains 按策略校验所有已注册路由的中间件链，policy 为 nil 时使用 DefaultChainPolicy
+// 有违规时返回 *ChainVerificationError，列出每条违规的路由和修复建议
+func (s *Server) VerifyMiddlewareChains(policy *ChainPolicy) error {
+	if policy == nil {
+		policy = DefaultChainPolicy()
+	}
+	chains, err := s.RouteChains()
+	if err != nil {
+		return err
+	}
+
+	var violations []ChainViolation
+	for _, route := range chains {
+		for _, message := range policy.check(route) {
+			violations = append(violations, ChainViolation{Method: route.Method, Path: route.Path, Message: message})
+		}
+	}
+	if len(violations) > 0 {
+		return &ChainVerificationError{Violations: violations}
+	}
+	return nil
+}
+
+// check 校验一条路由，返回违规说明
+func (p *ChainPolicy) check(route RouteChain) []string {
+	var messages []string
+	authAt := route.Index(p.AuthMiddlewares...)
+
+	for i, handler := range route.Handlers {
+		if containsString(p.AuthzMiddlewares, handler) && (authAt < 0 || authAt > i) {
+			messages = append(messages, fmt.Sprintf("%s runs before any auth middleware (%s) and will reject every request; register the auth middleware first",
+				handler, strings.Join(p.AuthMiddlewares, ", ")))
+		}
+	}
+
+	paginatedAt := route.Index(p.PaginatedHandlers...)
+	if paginatedAt < 0 && route.Meta != nil && route.Meta.Paginated {
+		paginatedAt = len(route.Handlers) - 1
+	}
+	if paginatedAt >= 0 {
+		if at := route.Index(p.PaginationMiddlewares...); at < 0 || at > paginatedAt {
+			messages = append(messages, fmt.Sprintf("%s reads page/page_size from the context but no pagination middleware (%s) runs before it; add middleware.Pagination() to the route or group",
+				route.Handlers[paginatedAt], strings.Join(p.PaginationMiddlewares, ", ")))
+		}
+	}
+
+	guarded := authAt >= 0 || route.Index(p.GuardMiddlewares...) >= 0
+	if p.RequirePublicWriteRateLimit && route.Method == http.MethodPost && !guarded && !p.exempt(route.Path) {
+		if route.Index(p.RateLimitMiddlewares...) < 0 {
+			messages = append(messages, "public POST route has no rate limit middleware; add one to the route or group, or list the path in PublicWriteExemptPaths")
+		}
+	}
+
+	for _, rule := range p.Rules {
+		if err := rule(route); err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+	return messages
+}
+
+// exempt 路径是否在公开写接口限流豁免列表中
+func (p *ChainPolicy) exempt(path string) bool {
+	for _, prefix := range p.PublicWriteExemptPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
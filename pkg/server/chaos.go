@@ -0,0 +1,41 @@
+package server
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+)
+
+// chaosConfig 根据服务器配置生成故障注入配置，release模式下始终关闭
+func (s *Server) chaosConfig() *middleware.ChaosConfig {
+	chaos := s.config.Server.Chaos
+	if !chaos.Enabled {
+		return nil
+	}
+	if s.config.Server.Mode == gin.ReleaseMode {
+		if s.logger != nil {
+			s.logger.Warn("Chaos fault injection is configured but ignored in release mode")
+		}
+		return nil
+	}
+
+	cfg := &middleware.ChaosConfig{
+		Enabled:   true,
+		SkipPaths: []string{"/health", "/health/*", "/metrics"},
+	}
+	for _, rule := range chaos.Rules {
+		cfg.Rules = append(cfg.Rules, middleware.ChaosRule{
+			Path:        rule.Path,
+			LatencyRate: rule.LatencyRate,
+			Latency:     time.Duration(rule.LatencyMs) * time.Millisecond,
+			ErrorRate:   rule.ErrorRate,
+			ErrorStatus: rule.ErrorStatus,
+			DropRate:    rule.DropRate,
+		})
+	}
+	if s.logger != nil {
+		s.logger.Warnf("Chaos fault injection enabled with %d rule(s)", len(cfg.Rules))
+	}
+	return cfg
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+)
+
+// registerDatabaseAdminRoutes 注册数据库迁移管理接口，应挂载在需要管理员权限的路由组下
+//
+//	GET  /database/migrations       迁移状态（已执行版本、待执行迁移、结构变更）
+//	GET  /database/migrations/plan  AutoMigrate 试运行，列出将要执行的结构变更
+//	POST /database/migrations       执行迁移
+func (s *Server) registerDatabaseAdminRoutes(router gin.IRouter) {
+	group := router.Group("/database")
+	group.GET("/migrations", s.migrationStatusHandler)
+	group.GET("/migrations/plan", s.migrationPlanHandler)
+	group.POST("/migrations", s.runMigrationsHandler)
+}
+
+// requireMigrator 检查迁移器是否可用
+func (s *Server) requireMigrator(c *gin.Context) bool {
+	if s.migrator == nil {
+		s.Error(c, http.StatusServiceUnavailable, "migrator is not configured")
+		return false
+	}
+	return true
+}
+
+// migrationStatusHandler 迁移状态
+func (s *Server) migrationStatusHandler(c *gin.Context) {
+	if !s.requireMigrator(c) {
+		return
+	}
+
+	status, err := s.migrator.MigrationStatus()
+	if err != nil {
+		s.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.Success(c, status)
+}
+
+// migrationPlanHandler AutoMigrate 试运行
+func (s *Server) migrationPlanHandler(c *gin.Context) {
+	if !s.requireMigrator(c) {
+		return
+	}
+
+	changes, err := s.migrator.PlanAutoMigrate()
+	if err != nil {
+		s.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.Success(c, gin.H{"changes": changes, "count": len(changes)})
+}
+
+// runMigrationsHandler 执行迁移并返回执行后的状态
+func (s *Server) runMigrationsHandler(c *gin.Context) {
+	if !s.requireMigrator(c) {
+		return
+	}
+
+	if s.logger != nil {
+		operator, _ := middleware.GetUsername(c)
+		s.logger.Warnf("Database migration triggered by admin: operator=%s", operator)
+	}
+	err := s.migrator.Migrate()
+	s.schemaCheck.invalidate()
+	if err != nil {
+		s.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	status, err := s.migrator.MigrationStatus()
+	if err != nil {
+		s.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.Success(c, status)
+}
+
+// schemaRecheckInterval 数据库结构未就绪时重新检查的最短间隔
+const schemaRecheckInterval = 30 * time.Second
+
+// schemaReadinessCache 缓存数据库结构检查结果，避免每次 /health/ready 都查询表结构
+// 就绪后不再检查；未就绪时按 schemaRecheckInterval 重新检查，以便其他实例执行迁移后恢复就绪
+type schemaReadinessCache struct {
+	mu        sync.Mutex
+	result    gin.H
+	checkedAt time.Time
+}
+
+// get 返回缓存的检查结果，需要时调用 check 重新检查
+func (c *schemaReadinessCache) get(check func() gin.H) gin.H {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.result == nil || (c.result["status"] != "ready" && time.Since(c.checkedAt) >= schemaRecheckInterval) {
+		c.result = check()
+		c.checkedAt = time.Now()
+	}
+	return c.result
+}
+
+// invalidate 清除缓存，执行迁移后调用
+func (c *schemaReadinessCache) invalidate() {
+	c.mu.Lock()
+	c.result = nil
+	c.mu.Unlock()
+}
+
+// schemaReadiness 就绪检查中的数据库结构检查，存在待执行迁移或结构变更时未就绪
+func (s *Server) schemaReadiness() gin.H {
+	return s.schemaCheck.get(s.checkSchema)
+}
+
+// checkSchema 查询迁移状态并转换为就绪检查结果
+func (s *Server) checkSchema() gin.H {
+	status, err := s.migrator.MigrationStatus()
+	if err != nil {
+		return gin.H{"status": "not_ready", "error": err.Error()}
+	}
+	if !status.UpToDate {
+		return gin.H{
+			"status":         "not_ready",
+			"pending":        status.Pending,
+			"schema_changes": len(status.SchemaChanges),
+		}
+	}
+	return gin.H{"status": "ready"}
+}
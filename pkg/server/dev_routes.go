@@ -0,0 +1,274 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/auth"
+	"github.com/hwh/hwhkit-go/pkg/degrade"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+	"github.com/hwh/hwhkit-go/pkg/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// devCacheNamespace 演示缓存接口使用的键前缀，避免读写业务数据
+const devCacheNamespace = "demo:"
+
+// EnableDevRoutes 注册 /demo 开发演示路由，用于新成员上手体验工具函数、JWT签发、RBAC校验和缓存操作
+// 需要配置 SERVER_DEV_ROUTES=true，release模式或 ENV=production 时始终不注册；返回是否已注册
+//
+//	GET    /demo/utils                  工具函数示例
+//	POST   /demo/auth/token             签发演示用户的令牌，请求体只能指定 username、email
+//	GET    /demo/protected/profile      JWT认证后返回令牌中的用户信息
+//	GET    /demo/rbac/roles             演示RBAC的角色和权限
+//	GET    /demo/rbac/check             按令牌中的角色检查 resource、action 权限
+//	GET    /demo/rbac/protected         需要 user:write 权限的路由
+//	GET    /demo/cache/:key             读取演示缓存，未命中时写入默认值，缓存降级时返回兜底值
+//	PUT    /demo/cache/:key             写入演示缓存，请求体 {"value": "...", "ttl_seconds": 300}
+//	DELETE /demo/cache/:key             删除演示缓存
+//	GET    /demo/db/stats               数据库连接池统计
+func (s *Server) EnableDevRoutes() bool {
+	if !s.config.Server.DevRoutes || s.config.IsRelease() || s.config.IsProduction() {
+		return false
+	}
+	if s.logger != nil {
+		s.logger.Warn("Development routes are enabled under /demo, never enable SERVER_DEV_ROUTES in production")
+	}
+
+	demo := s.engine.Group("/demo")
+	demo.GET("/utils", s.devUtilsHandler)
+
+	// JWT
+	jwt := s.devJWT()
+	demo.POST("/auth/token", s.devTokenHandler)
+	demo.GET("/protected/profile", jwt, s.devProfileHandler)
+
+	// RBAC：内存中的默认角色（user、admin），令牌中的角色在检查时分配给用户
+	rbac := auth.NewRBAC()
+	if err := auth.CreateDefaultRolesAndPermissions(rbac); err != nil && s.logger != nil {
+		s.logger.WithError(err).Warn("Failed to create demo RBAC roles")
+	}
+	assignRoles := func(c *gin.Context) {
+		if claims, ok := middleware.GetClaims(c); ok {
+			for _, role := range claims.Roles {
+				rbac.AssignRoleToUser(claims.UserID, role)
+			}
+		}
+		c.Next()
+	}
+	demo.GET("/rbac/roles", func(c *gin.Context) {
+		roles, err := rbac.ListRoles()
+		if err != nil {
+			s.Error(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		permissions, err := rbac.ListPermissions()
+		if err != nil {
+			s.Error(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.Success(c, gin.H{"roles": roles, "permissions": permissions})
+	})
+	demo.GET("/rbac/check", jwt, assignRoles, func(c *gin.Context) {
+		userID, _ := middleware.GetUserID(c)
+		resource, action := c.DefaultQuery("resource", "user"), c.DefaultQuery("action", "read")
+		allowed, err := rbac.CheckResourcePermission(userID, resource, action)
+		if err != nil {
+			s.Error(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		roles, _ := rbac.GetUserRoles(userID)
+		permissions, _ := rbac.GetUserPermissions(userID)
+		s.Success(c, gin.H{
+			"user_id":     userID,
+			"resource":    resource,
+			"action":      action,
+			"allowed":     allowed,
+			"roles":       roles,
+			"permissions": permissions,
+		})
+	})
+	demo.GET("/rbac/protected", jwt, assignRoles, middleware.RequirePermission(rbac, "user", "write"), func(c *gin.Context) {
+		s.Success(c, gin.H{"message": "You have user:write permission"})
+	})
+
+	// 缓存
+	demo.GET("/cache/:key", s.devCacheGetHandler)
+	demo.PUT("/cache/:key", s.devCacheSetHandler)
+	demo.DELETE("/cache/:key", s.devCacheDeleteHandler)
+
+	// 数据库
+	demo.GET("/db/stats", func(c *gin.Context) {
+		if s.db == nil {
+			s.Error(c, http.StatusServiceUnavailable, "database is not configured")
+			return
+		}
+		s.Success(c, s.db.GetStats())
+	})
+	return true
+}
+
+// devJWT 演示路由使用的JWT中间件，未配置认证时直接拒绝
+func (s *Server) devJWT() gin.HandlerFunc {
+	if s.middleware != nil {
+		return s.middleware.JWT()
+	}
+	return func(c *gin.Context) {
+		s.Error(c, http.StatusServiceUnavailable, "auth is not configured")
+		c.Abort()
+	}
+}
+
+// devUtilsHandler 工具函数示例
+func (s *Server) devUtilsHandler(c *gin.Context) {
+	randomStr, _ := utils.Str.RandomString(10)
+	jsonStr, _ := utils.JSON.ToPrettyJSON(map[string]interface{}{"name": "John", "age": 30})
+
+	s.Success(c, gin.H{
+		"string_examples": gin.H{
+			"original":   "hello_world",
+			"camel_case": utils.Str.CamelCase("hello_world"),
+			"snake_case": utils.Str.SnakeCase("HelloWorld"),
+			"random":     randomStr,
+		},
+		"json_example": jsonStr,
+		"time_examples": gin.H{
+			"now":       utils.Time.Now(),
+			"formatted": utils.Time.FormatNowDateTime(),
+		},
+		"http_examples": gin.H{
+			"is_valid_url": utils.HTTP.IsValidURL("https://example.com"),
+		},
+	})
+}
+
+// devTokenRequest 演示令牌请求，只能修改展示用的用户名和邮箱
+type devTokenRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// devTokenHandler 签发演示令牌，未指定的字段使用演示用户
+// 令牌使用真实的JWT密钥签名，用户ID和角色固定为演示用户，不接受调用方指定，防止签发管理员令牌
+func (s *Server) devTokenHandler(c *gin.Context) {
+	if s.auth == nil {
+		s.Error(c, http.StatusServiceUnavailable, "auth is not configured")
+		return
+	}
+
+	req := devTokenRequest{Username: "demo_user", Email: "demo@example.com"}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			s.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	user := &auth.User{
+		ID:       "123",
+		Username: req.Username,
+		Email:    req.Email,
+		Roles:    []string{"user"},
+	}
+	tokens, err := s.auth.GenerateTokenPairForUser(user, "")
+	if err != nil {
+		s.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.Success(c, gin.H{"tokens": tokens, "user": gin.H{"user_id": user.ID, "username": user.Username, "email": user.Email, "roles": user.Roles}})
+}
+
+// devProfileHandler 返回令牌中的用户信息
+func (s *Server) devProfileHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		s.Error(c, http.StatusUnauthorized, "no user information found")
+		return
+	}
+	s.Success(c, gin.H{
+		"id":         claims.UserID,
+		"username":   claims.Username,
+		"email":      claims.Email,
+		"roles":      claims.Roles,
+		"expires_at": claims.ExpiresAt,
+	})
+}
+
+// devCacheGetHandler 读取演示缓存，注册了 cache 依赖降级保护时降级期间不访问Redis
+func (s *Server) devCacheGetHandler(c *gin.Context) {
+	if !s.requireCache(c) {
+		return
+	}
+	key := devCacheNamespace + c.Param("key")
+	guard := s.GetDependency("cache")
+	store := s.Cache(c)
+
+	from := "cache"
+	fallback := func(err error) (string, error) {
+		newValue := fmt.Sprintf("cached_value_for_%s_at_%s", c.Param("key"), utils.Time.FormatNowDateTime())
+		// 缓存未命中时写入默认值（5分钟过期）；缓存降级或写入失败时直接返回兜底值
+		set := func() error { return store.Set(key, newValue, 5*time.Minute) }
+		if guard != nil {
+			write := set
+			set = func() error { return guard.Do(write) }
+		}
+		if errors.Is(err, redis.Nil) && set() == nil {
+			from = "generated"
+			return newValue, nil
+		}
+		from = "fallback"
+		return newValue, nil
+	}
+
+	var value string
+	if guard != nil {
+		value, _ = degrade.Call(guard, func() (string, error) { return store.Get(key) }, fallback)
+	} else if cached, err := store.Get(key); err != nil {
+		value, _ = fallback(err)
+	} else {
+		value = cached
+	}
+	s.Success(c, gin.H{"key": c.Param("key"), "value": value, "from": from})
+}
+
+// devCacheSetRequest 写入演示缓存请求
+type devCacheSetRequest struct {
+	Value      string `json:"value" binding:"required"`
+	TTLSeconds int    `json:"ttl_seconds"`
+}
+
+// devCacheSetHandler 写入演示缓存，过期时间最长1小时
+func (s *Server) devCacheSetHandler(c *gin.Context) {
+	if !s.requireCache(c) {
+		return
+	}
+	var req devCacheSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	if ttl <= 0 || ttl > time.Hour {
+		ttl = time.Hour
+	}
+	if err := s.Cache(c).Set(devCacheNamespace+c.Param("key"), req.Value, ttl); err != nil {
+		s.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.Success(c, gin.H{"key": c.Param("key"), "value": req.Value, "ttl_seconds": int(ttl.Seconds())})
+}
+
+// devCacheDeleteHandler 删除演示缓存
+func (s *Server) devCacheDeleteHandler(c *gin.Context) {
+	if !s.requireCache(c) {
+		return
+	}
+	if err := s.cache.Delete(devCacheNamespace + c.Param("key")); err != nil {
+		s.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.Success(c, gin.H{"key": c.Param("key"), "deleted": true})
+}
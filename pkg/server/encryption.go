@@ -0,0 +1,42 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+	"github.com/hwh/hwhkit-go/pkg/utils"
+)
+
+// SetupFieldEncryption 注册字段加密公钥分发接口 GET /.well-known/encryption-keys，并返回使用该密钥环的字段加密中间件
+// 轮换密钥（keys.Rotate 或 AddKey）后接口立即返回新公钥，旧密钥在保留期内仍可解密。
+// 可多次调用以为不同路由创建不同字段配置的中间件，但必须使用同一个密钥环，否则返回错误：
+//
+//	keys := utils.NewKeyRing()
+//	keys.AddKey("2024-01", os.Getenv("FIELD_ENCRYPTION_KEY"))
+//	encrypt, err := srv.SetupFieldEncryption(keys, &middleware.FieldEncryptionConfig{RequestFields: []string{"card_number"}})
+//	api.POST("/cards", encrypt, handler)
+func (s *Server) SetupFieldEncryption(keys *utils.KeyRing, config *middleware.FieldEncryptionConfig) (gin.HandlerFunc, error) {
+	if keys == nil {
+		return nil, errors.New("field encryption key ring is required")
+	}
+	if s.fieldKeys != nil && s.fieldKeys != keys {
+		return nil, errors.New("field encryption is already set up with a different key ring")
+	}
+	if s.fieldKeys == nil {
+		s.fieldKeys = keys
+		s.engine.GET("/.well-known/encryption-keys", func(c *gin.Context) {
+			// 客户端可短暂缓存公钥，轮换后旧公钥仍在保留期内有效
+			c.Header("Cache-Control", "public, max-age=300")
+			c.JSON(http.StatusOK, gin.H{"keys": keys.PublicKeys()})
+		})
+	}
+
+	if config == nil {
+		config = &middleware.FieldEncryptionConfig{}
+	}
+	cfg := *config
+	cfg.Keys = keys
+	return middleware.FieldEncryption(&cfg), nil
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/hwh/hwhkit-go/pkg/auth"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+	"github.com/hwh/hwhkit-go/pkg/utils"
 )
 
 // Response 统一响应结构
@@ -20,12 +22,13 @@ type Response struct {
 
 // PaginatedResponse 分页响应结构
 type PaginatedResponse struct {
-	Code       int         `json:"code"`
-	Message    string      `json:"message"`
-	Data       interface{} `json:"data"`
-	Pagination Pagination  `json:"pagination"`
-	RequestID  string      `json:"request_id"`
-	Timestamp  int64       `json:"timestamp"`
+	Code       int              `json:"code"`
+	Message    string           `json:"message"`
+	Data       interface{}      `json:"data"`
+	Pagination Pagination       `json:"pagination"`
+	Links      *PaginationLinks `json:"_links,omitempty"`
+	RequestID  string           `json:"request_id"`
+	Timestamp  int64            `json:"timestamp"`
 }
 
 // Pagination 分页信息
@@ -37,11 +40,12 @@ type Pagination struct {
 }
 
 // Success 成功响应
+// data 中带有 mask 标签的字段会自动脱敏，如 `mask:"phone"`
 func (s *Server) Success(c *gin.Context, data interface{}) {
 	c.JSON(http.StatusOK, Response{
 		Code:      0,
 		Message:   "success",
-		Data:      data,
+		Data:      utils.Mask.Masked(data),
 		RequestID: c.GetString("request_id"),
 		Timestamp: time.Now().Unix(),
 	})
@@ -58,15 +62,18 @@ func (s *Server) Error(c *gin.Context, code int, message string) {
 }
 
 // PaginatedSuccess 分页成功响应
+// 根据当前请求URL输出 RFC 5988 Link 响应头（first/prev/next/last），开启 PaginationLinks 时响应体中同时包含 _links
 func (s *Server) PaginatedSuccess(c *gin.Context, data interface{}, total int64) {
-	page := c.GetInt("page")
-	pageSize := c.GetInt("page_size")
+	page, pageSize := paginationParams(c)
 	totalPages := (total + int64(pageSize) - 1) / int64(pageSize)
 	
-	c.JSON(http.StatusOK, PaginatedResponse{
+	links := newPaginationLinks(c.Request.URL, page, pageSize, totalPages)
+	c.Header("Link", links.Header())
+	
+	response := PaginatedResponse{
 		Code:    0,
 		Message: "success",
-		Data:    data,
+		Data:    utils.Mask.Masked(data),
 		Pagination: Pagination{
 			Page:       page,
 			PageSize:   pageSize,
@@ -75,7 +82,12 @@ func (s *Server) PaginatedSuccess(c *gin.Context, data interface{}, total int64)
 		},
 		RequestID: c.GetString("request_id"),
 		Timestamp: time.Now().Unix(),
-	})
+	}
+	if s.config.Server.PaginationLinks {
+		response.Links = &links
+	}
+	
+	c.JSON(http.StatusOK, response)
 }
 
 // 路由处理器
@@ -117,7 +129,7 @@ func (s *Server) handleHealth(c *gin.Context) {
 	}
 	
 	// 检查数据库
-	if err := s.db.Health(); err != nil {
+	if err := s.db.WithContext(c.Request.Context()).Health(); err != nil {
 		health["services"].(gin.H)["database"] = gin.H{
 			"status": "error",
 			"error":  err.Error(),
@@ -129,7 +141,7 @@ func (s *Server) handleHealth(c *gin.Context) {
 	}
 	
 	// 检查缓存
-	if err := s.cache.Health(); err != nil {
+	if err := s.cache.WithContext(c.Request.Context()).Health(); err != nil {
 		health["services"].(gin.H)["cache"] = gin.H{
 			"status": "error",
 			"error":  err.Error(),
@@ -145,40 +157,39 @@ func (s *Server) handleHealth(c *gin.Context) {
 
 // LoginRequest 登录请求
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username string `json:"username" form:"username" binding:"required"`
+	Password string `json:"password" form:"password" binding:"required"`
 }
 
 // RegisterRequest 注册请求
 type RegisterRequest struct {
-	Username string   `json:"username" binding:"required"`
-	Email    string   `json:"email" binding:"required,email"`
-	Password string   `json:"password" binding:"required,min=8"`
-	Roles    []string `json:"roles"`
+	Username string   `json:"username" form:"username" binding:"required"`
+	Email    string   `json:"email" form:"email" binding:"required,email"`
+	Password string   `json:"password" form:"password" binding:"required,min=8"`
+	Roles    []string `json:"roles" form:"roles"`
 }
 
 // RefreshTokenRequest 刷新令牌请求
 type RefreshTokenRequest struct {
-	RefreshToken string `json:"refresh_token" binding:"required"`
+	RefreshToken string `json:"refresh_token" form:"refresh_token" binding:"required"`
 }
 
 // ChangePasswordRequest 修改密码请求
 type ChangePasswordRequest struct {
-	OldPassword string `json:"old_password" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=8"`
+	OldPassword string `json:"old_password" form:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" form:"new_password" binding:"required,min=8"`
 }
 
 // UpdateProfileRequest 更新个人资料请求
 type UpdateProfileRequest struct {
-	Email string `json:"email" binding:"email"`
-	Name  string `json:"name"`
+	Email string `json:"email" form:"email" binding:"email"`
+	Name  string `json:"name" form:"name"`
 }
 
 // handleLogin 登录处理器
 func (s *Server) handleLogin(c *gin.Context) {
 	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		s.Error(c, http.StatusBadRequest, err.Error())
+	if !s.BindRequest(c, &req) {
 		return
 	}
 	
@@ -212,8 +223,7 @@ func (s *Server) handleLogin(c *gin.Context) {
 // handleRegister 注册处理器
 func (s *Server) handleRegister(c *gin.Context) {
 	var req RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		s.Error(c, http.StatusBadRequest, err.Error())
+	if !s.BindRequest(c, &req) {
 		return
 	}
 	
@@ -242,8 +252,7 @@ func (s *Server) handleRegister(c *gin.Context) {
 // handleRefreshToken 刷新令牌处理器
 func (s *Server) handleRefreshToken(c *gin.Context) {
 	var req RefreshTokenRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		s.Error(c, http.StatusBadRequest, err.Error())
+	if !s.BindRequest(c, &req) {
 		return
 	}
 	
@@ -258,16 +267,17 @@ func (s *Server) handleRefreshToken(c *gin.Context) {
 
 // handleProfile 获取个人资料处理器
 func (s *Server) handleProfile(c *gin.Context) {
-	userID := c.GetString("user_id")
-	username := c.GetString("username")
-	email := c.GetString("user_email")
-	roles := c.GetStringSlice("user_roles")
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		s.Error(c, http.StatusUnauthorized, "No user information found")
+		return
+	}
 	
 	profile := gin.H{
-		"id":       userID,
-		"username": username,
-		"email":    email,
-		"roles":    roles,
+		"id":       claims.UserID,
+		"username": claims.Username,
+		"email":    claims.Email,
+		"roles":    claims.Roles,
 	}
 	
 	s.Success(c, profile)
@@ -276,8 +286,7 @@ func (s *Server) handleProfile(c *gin.Context) {
 // handleUpdateProfile 更新个人资料处理器
 func (s *Server) handleUpdateProfile(c *gin.Context) {
 	var req UpdateProfileRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		s.Error(c, http.StatusBadRequest, err.Error())
+	if !s.BindRequest(c, &req) {
 		return
 	}
 	
@@ -305,8 +314,7 @@ func (s *Server) handleLogout(c *gin.Context) {
 // handleChangePassword 修改密码处理器
 func (s *Server) handleChangePassword(c *gin.Context) {
 	var req ChangePasswordRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		s.Error(c, http.StatusBadRequest, err.Error())
+	if !s.BindRequest(c, &req) {
 		return
 	}
 	
@@ -341,10 +349,9 @@ func (s *Server) handleChangePassword(c *gin.Context) {
 
 // handleListUsers 列出用户处理器（管理员）
 func (s *Server) handleListUsers(c *gin.Context) {
-	// 使用分页中间件解析的参数
-	page := c.GetInt("page")
-	pageSize := c.GetInt("page_size")
-	offset := c.GetInt("offset")
+	// 使用分页中间件或查询参数中的分页参数
+	page, pageSize := paginationParams(c)
+	offset := (page - 1) * pageSize
 	
 	// 模拟用户列表（实际应用中应该从数据库查询）
 	users := []gin.H{
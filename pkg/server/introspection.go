@@ -0,0 +1,171 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/auth"
+)
+
+// tokenEndpointCaller 内省和吊销接口的调用方，客户端凭证认证时 client 不为空，管理员令牌认证时为 nil
+type tokenEndpointCaller struct {
+	client     *auth.OIDCClient
+	firstParty bool // 是否可以内省和吊销本服务签发的令牌
+}
+
+// tokenHandler 令牌内省和吊销路由处理器
+type tokenHandler struct {
+	server          *Server
+	provider        *auth.OIDCProvider
+	resourceServers map[string]bool
+}
+
+// SetupTokenIntrospectionRoutes 注册RFC 7662 令牌内省和RFC 7009 令牌吊销接口，需要配置认证管理器
+// 其他内部服务和网关无需共享签名密钥即可校验或吊销本服务签发的令牌：
+//
+//	POST /oauth/introspect   表单参数 token、token_type_hint，返回 {"active": true, ...}
+//	POST /oauth/revoke       表单参数 token、token_type_hint，令牌无效时同样返回200
+//
+// 调用方使用OIDC客户端凭证（Basic认证或 client_id/client_secret 表单参数）或 admin 角色的访问令牌认证；
+// provider 为 nil 时只支持管理员令牌，不为 nil 时同时内省和吊销该提供方签发的访问令牌，
+// 客户端只能吊销签发给自己的OIDC访问令牌。
+// 本服务签发的用户令牌只有管理员和 resourceServers 中列出的客户端（如网关）可以内省和吊销，
+// 其他客户端内省时返回 {"active": false}，吊销时返回 unauthorized_client。
+// 认证管理器和提供方未设置吊销列表时自动设置：有缓存时使用Redis（多实例共享），否则使用内存
+func (s *Server) SetupTokenIntrospectionRoutes(provider *auth.OIDCProvider, resourceServers ...string) error {
+	if s.auth == nil {
+		return errors.New("token introspection routes require an auth manager")
+	}
+
+	store := s.auth.RevocationStore()
+	if store == nil {
+		if s.cache != nil {
+			store = auth.NewRedisRevocationStore(s.cache, "")
+		} else {
+			store = auth.NewMemoryRevocationStore()
+		}
+		s.auth.SetRevocationStore(store)
+	}
+	if provider != nil {
+		provider.SetRevocationStore(store)
+	}
+
+	h := &tokenHandler{server: s, provider: provider, resourceServers: make(map[string]bool, len(resourceServers))}
+	for _, clientID := range resourceServers {
+		h.resourceServers[clientID] = true
+	}
+	s.engine.POST("/oauth/introspect", h.introspect)
+	s.engine.POST("/oauth/revoke", h.revoke)
+	return nil
+}
+
+// introspect 令牌内省，先按本服务的令牌校验，再按OIDC访问令牌校验
+func (h *tokenHandler) introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	caller, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+
+	token := c.PostForm("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "token is required"})
+		return
+	}
+
+	var info *auth.TokenIntrospection
+	if caller.firstParty {
+		info = h.server.auth.IntrospectToken(token)
+	}
+	if (info == nil || !info.Active) && h.provider != nil {
+		info = h.provider.IntrospectAccessToken(token)
+	}
+	if info == nil {
+		info = &auth.TokenIntrospection{}
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// revoke 令牌吊销，无效或已过期的令牌按规范同样返回200
+func (h *tokenHandler) revoke(c *gin.Context) {
+	caller, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+
+	token := c.PostForm("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "token is required"})
+		return
+	}
+
+	if caller.firstParty {
+		if claims, err := h.server.auth.RevokeToken(token); err == nil {
+			h.logRevocation(c, caller, claims.ID)
+			c.Status(http.StatusOK)
+			return
+		}
+	} else if _, err := h.server.auth.ValidateToken(token); err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unauthorized_client", "error_description": "client is not allowed to revoke first-party tokens"})
+		return
+	}
+
+	if h.provider != nil {
+		clientID := ""
+		if caller.client != nil {
+			clientID = caller.client.ID
+		}
+		claims, err := h.provider.RevokeAccessToken(token, clientID)
+		if errors.Is(err, auth.ErrOIDCUnauthorizedClient) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unauthorized_client", "error_description": err.Error()})
+			return
+		}
+		if err == nil {
+			h.logRevocation(c, caller, claims.ID)
+		}
+	}
+	c.Status(http.StatusOK)
+}
+
+// authenticate 校验调用方：OIDC客户端凭证或 admin 角色的访问令牌
+func (h *tokenHandler) authenticate(c *gin.Context) (*tokenEndpointCaller, bool) {
+	if header := c.GetHeader("Authorization"); len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		claims, err := h.server.auth.ValidateToken(strings.TrimSpace(header[7:]))
+		if err == nil && !claims.IsRefresh() && claims.HasRole("admin") {
+			return &tokenEndpointCaller{firstParty: true}, true
+		}
+	} else if h.provider != nil {
+		clientID, clientSecret, ok := c.Request.BasicAuth()
+		if !ok {
+			clientID, clientSecret = c.PostForm("client_id"), c.PostForm("client_secret")
+		}
+		if clientID != "" {
+			if client, err := h.provider.AuthenticateClient(clientID, clientSecret); err == nil {
+				return &tokenEndpointCaller{client: client, firstParty: h.resourceServers[client.ID]}, true
+			}
+		}
+	}
+
+	c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+	return nil, false
+}
+
+// logRevocation 记录令牌吊销日志
+func (h *tokenHandler) logRevocation(c *gin.Context, caller *tokenEndpointCaller, tokenID string) {
+	if h.server.logger == nil {
+		return
+	}
+	by := "admin"
+	if caller.client != nil {
+		by = "client:" + caller.client.ID
+	}
+	h.server.logger.WithFields(map[string]interface{}{
+		"jti":        tokenID,
+		"revoked_by": by,
+		"request_id": c.GetString("request_id"),
+	}).Info("Token revoked")
+}
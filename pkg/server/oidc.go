@@ -0,0 +1,227 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/auth"
+	"github.com/hwh/hwhkit-go/pkg/utils"
+)
+
+// oidcConsentCookie 授权确认页的防CSRF令牌Cookie
+const oidcConsentCookie = "oidc_consent"
+
+// scopeDescriptions 授权确认页展示的scope说明
+var scopeDescriptions = map[string]string{
+	auth.ScopeOpenID:  "确认你的身份",
+	auth.ScopeProfile: "读取你的用户名和角色",
+	auth.ScopeEmail:   "读取你的邮箱地址",
+}
+
+// oidcHandler OIDC提供方路由处理器
+type oidcHandler struct {
+	server   *Server
+	provider *auth.OIDCProvider
+}
+
+// SetupOIDCRoutes 注册OIDC提供方路由
+// 授权确认页依赖模板路由的登录会话，未登录时跳转到 /login
+func (s *Server) SetupOIDCRoutes(provider *auth.OIDCProvider) {
+	h := &oidcHandler{server: s, provider: provider}
+
+	s.engine.GET("/.well-known/openid-configuration", h.discovery)
+
+	oauth := s.engine.Group("/oauth")
+	{
+		oauth.GET("/jwks", h.jwks)
+		oauth.GET("/authorize", h.authorize)
+		oauth.POST("/authorize", h.consent)
+		oauth.POST("/token", h.token)
+		oauth.GET("/userinfo", h.userinfo)
+		oauth.POST("/userinfo", h.userinfo)
+	}
+}
+
+// discovery 发现元数据
+func (h *oidcHandler) discovery(c *gin.Context) {
+	c.JSON(http.StatusOK, h.provider.Discovery())
+}
+
+// jwks 签名公钥
+func (h *oidcHandler) jwks(c *gin.Context) {
+	c.JSON(http.StatusOK, h.provider.JWKS())
+}
+
+// authorize 校验授权请求并展示授权确认页
+func (h *oidcHandler) authorize(c *gin.Context) {
+	req := bindAuthorizeRequest(c.Query)
+
+	client, scopes, err := h.provider.ValidateAuthorizeRequest(req)
+	if err != nil {
+		h.authorizeError(c, client, req, err)
+		return
+	}
+
+	if h.server.getUserFromSession(c) == "" {
+		c.Redirect(http.StatusFound, "/login?next="+url.QueryEscape(c.Request.URL.RequestURI()))
+		return
+	}
+
+	consentToken, err := utils.Str.RandomToken(16)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	c.SetCookie(oidcConsentCookie, consentToken, 600, "/oauth", "", c.Request.TLS != nil, true)
+
+	scopeItems := make([]gin.H, 0, len(scopes))
+	for _, scope := range scopes {
+		description := scopeDescriptions[scope]
+		if description == "" {
+			description = scope
+		}
+		scopeItems = append(scopeItems, gin.H{"name": scope, "description": description})
+	}
+
+	c.HTML(http.StatusOK, "oidc/consent.html", gin.H{
+		"title":         "授权确认 - HWHKit-Go",
+		"client":        client,
+		"scopes":        scopeItems,
+		"request":       req,
+		"consent_token": consentToken,
+	})
+}
+
+// consent 处理授权确认结果
+func (h *oidcHandler) consent(c *gin.Context) {
+	req := bindAuthorizeRequest(c.PostForm)
+
+	client, _, err := h.provider.ValidateAuthorizeRequest(req)
+	if err != nil {
+		h.authorizeError(c, client, req, err)
+		return
+	}
+
+	cookieToken, _ := c.Cookie(oidcConsentCookie)
+	if cookieToken == "" || !auth.SecureCompare(cookieToken, c.PostForm("consent_token")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid_request", "error_description": "consent token mismatch"})
+		return
+	}
+	c.SetCookie(oidcConsentCookie, "", -1, "/oauth", "", c.Request.TLS != nil, true)
+
+	userID := h.server.getUserFromSession(c)
+	if userID == "" {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	if c.PostForm("decision") != "approve" {
+		redirectWithParams(c, req.RedirectURI, url.Values{"error": {"access_denied"}, "state": {req.State}})
+		return
+	}
+
+	code, err := h.provider.IssueCode(req, userID)
+	if err != nil {
+		h.authorizeError(c, client, req, err)
+		return
+	}
+	redirectWithParams(c, req.RedirectURI, url.Values{"code": {code}, "state": {req.State}})
+}
+
+// authorizeError 授权请求错误处理
+// 客户端或回调地址无效时直接返回错误，否则按规范重定向回客户端
+func (h *oidcHandler) authorizeError(c *gin.Context, client *auth.OIDCClient, req auth.AuthorizeRequest, err error) {
+	code := auth.OIDCErrorCode(err)
+	if client == nil || !client.AllowsRedirect(req.RedirectURI) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": code, "error_description": err.Error()})
+		return
+	}
+	redirectWithParams(c, req.RedirectURI, url.Values{
+		"error":             {code},
+		"error_description": {err.Error()},
+		"state":             {req.State},
+	})
+}
+
+// token 令牌端点，支持 client_secret_basic 和 client_secret_post
+func (h *oidcHandler) token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	clientID, clientSecret, ok := c.Request.BasicAuth()
+	if !ok {
+		clientID = c.PostForm("client_id")
+		clientSecret = c.PostForm("client_secret")
+	}
+
+	resp, err := h.provider.Exchange(auth.TokenRequest{
+		GrantType:    c.PostForm("grant_type"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Code:         c.PostForm("code"),
+		RedirectURI:  c.PostForm("redirect_uri"),
+		CodeVerifier: c.PostForm("code_verifier"),
+		Scope:        c.PostForm("scope"),
+	})
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, auth.ErrOIDCInvalidClient) {
+			status = http.StatusUnauthorized
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		c.JSON(status, gin.H{"error": auth.OIDCErrorCode(err), "error_description": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// userinfo 用户信息端点
+func (h *oidcHandler) userinfo(c *gin.Context) {
+	token := ""
+	if header := c.GetHeader("Authorization"); len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		token = strings.TrimSpace(header[7:])
+	}
+
+	info, err := h.provider.UserInfo(token)
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": auth.OIDCErrorCode(err)})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// bindAuthorizeRequest 从查询参数或表单读取授权请求
+func bindAuthorizeRequest(get func(string) string) auth.AuthorizeRequest {
+	return auth.AuthorizeRequest{
+		ClientID:            get("client_id"),
+		RedirectURI:         get("redirect_uri"),
+		ResponseType:        get("response_type"),
+		Scope:               get("scope"),
+		State:               get("state"),
+		Nonce:               get("nonce"),
+		CodeChallenge:       get("code_challenge"),
+		CodeChallengeMethod: get("code_challenge_method"),
+	}
+}
+
+// redirectWithParams 在回调地址上追加参数并重定向
+func redirectWithParams(c *gin.Context, redirectURI string, params url.Values) {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+		return
+	}
+	query := target.Query()
+	for key, values := range params {
+		if len(values) > 0 && values[0] != "" {
+			query.Set(key, values[0])
+		}
+	}
+	target.RawQuery = query.Encode()
+	c.Redirect(http.StatusFound, target.String())
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPIInfo OpenAPI文档基本信息
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	Description string
+}
+
+// bearerSecurityScheme JWT认证方案名称
+const bearerSecurityScheme = "bearerAuth"
+
+// OpenAPI 根据已注册路由及其元数据生成 OpenAPI 3 文档
+// 请求体和响应体类型按 json 标签生成Schema，命名结构体放入 components；需要认证的路由使用 Bearer 认证
+func (s *Server) OpenAPI(info OpenAPIInfo) (*openapi3.T, error) {
+	if info.Title == "" {
+		info.Title = "hwhkit-go API"
+	}
+	if info.Version == "" {
+		info.Version = "1.0.0"
+	}
+
+	doc := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:       info.Title,
+			Version:     info.Version,
+			Description: info.Description,
+		},
+		Paths: openapi3.Paths{},
+		Components: &openapi3.Components{
+			Schemas: openapi3.Schemas{},
+			SecuritySchemes: openapi3.SecuritySchemes{
+				bearerSecurityScheme: &openapi3.SecuritySchemeRef{
+					Value: openapi3.NewJWTSecurityScheme(),
+				},
+			},
+		},
+	}
+
+	for _, route := range s.Routes() {
+		op, err := openAPIOperation(doc, route)
+		if err != nil {
+			return nil, fmt.Errorf("failed to document %s %s: %w", route.Method, route.Path, err)
+		}
+		path, _ := openAPIPath(route.Path)
+		item := doc.Paths[path]
+		if item == nil {
+			item = &openapi3.PathItem{}
+			doc.Paths[path] = item
+		}
+		item.SetOperation(route.Method, op)
+	}
+	return doc, nil
+}
+
+// openAPIOperation 根据路由元数据生成操作
+func openAPIOperation(doc *openapi3.T, route RouteInfo) (*openapi3.Operation, error) {
+	op := openapi3.NewOperation()
+	op.OperationID = route.Method + " " + route.Path
+	op.Responses = openapi3.NewResponses()
+
+	_, params := openAPIPath(route.Path)
+	for _, name := range params {
+		op.AddParameter(openapi3.NewPathParameter(name).WithSchema(openapi3.NewStringSchema()))
+	}
+
+	meta := route.Meta
+	if meta == nil {
+		return op, nil
+	}
+	op.Summary = meta.Summary
+	op.Description = meta.Description
+	op.Tags = meta.Tags
+	op.Deprecated = meta.Deprecated
+
+	if meta.AuthRequired {
+		op.Security = openapi3.NewSecurityRequirements().With(openapi3.NewSecurityRequirement().Authenticate(bearerSecurityScheme))
+		op.AddResponse(http.StatusUnauthorized, openapi3.NewResponse().WithDescription("Unauthorized"))
+		if len(meta.Roles) > 0 {
+			op.AddResponse(http.StatusForbidden, openapi3.NewResponse().WithDescription("Forbidden"))
+		}
+	}
+
+	if meta.Request != nil {
+		schema, err := openAPISchema(doc, meta.Request)
+		if err != nil {
+			return nil, err
+		}
+		op.RequestBody = &openapi3.RequestBodyRef{
+			Value: openapi3.NewRequestBody().WithRequired(true).WithJSONSchemaRef(schema),
+		}
+		op.AddResponse(http.StatusBadRequest, openapi3.NewResponse().WithDescription("Bad Request"))
+	}
+
+	status := meta.SuccessStatus
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := openapi3.NewResponse().WithDescription(http.StatusText(status))
+	if meta.Response != nil {
+		schema, err := openAPISchema(doc, meta.Response)
+		if err != nil {
+			return nil, err
+		}
+		success.WithJSONSchemaRef(schema)
+	}
+	op.AddResponse(status, success)
+	return op, nil
+}
+
+// openAPISchema 生成值类型的Schema，命名结构体注册到 components 并返回引用
+func openAPISchema(doc *openapi3.T, value interface{}) (*openapi3.SchemaRef, error) {
+	schema, err := openapi3gen.NewSchemaRefForValue(value, doc.Components.Schemas)
+	if err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf(value)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t.Name() == "" {
+		return schema, nil
+	}
+	name := t.Name()
+	if _, exists := doc.Components.Schemas[name]; !exists {
+		doc.Components.Schemas[name] = schema
+	}
+	return openapi3.NewSchemaRef("#/components/schemas/"+name, doc.Components.Schemas[name].Value), nil
+}
+
+// openAPIPath 将 gin 路由路径转换为 OpenAPI 路径并返回路径参数，如 /users/:id 转换为 /users/{id}
+func openAPIPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	var params []string
+	for i, segment := range segments {
+		if len(segment) > 1 && (segment[0] == ':' || segment[0] == '*') {
+			params = append(params, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// openAPIHandler OpenAPI文档接口
+func (s *Server) openAPIHandler(c *gin.Context) {
+	doc, err := s.OpenAPI(OpenAPIInfo{})
+	if err != nil {
+		s.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, doc)
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type docItem struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// newDocumentedServer 创建注册了带文档注解路由的服务器
+func newDocumentedServer(t *testing.T) *Server {
+	gin.SetMode(gin.TestMode)
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode, EnableSwagger: true}},
+	})
+	require.NoError(t, err)
+
+	handler := func(c *gin.Context) { c.Status(http.StatusOK) }
+	NewRouterManager(server).RegisterRouteGroup(RouteGroup{
+		Path: "/api/v1",
+		Tags: []string{"items"},
+		Routes: []Route{
+			{Method: "GET", Path: "/items/:id", Handlers: []gin.HandlerFunc{handler}, Meta: &RouteMeta{
+				Summary:      "Get item",
+				Description:  "Returns a single item",
+				AuthRequired: true,
+				Response:     docItem{},
+			}},
+			{Method: "POST", Path: "/items", Handlers: []gin.HandlerFunc{handler}, Meta: &RouteMeta{
+				Summary:       "Create item",
+				Request:       LoginRequest{},
+				Response:      &docItem{},
+				SuccessStatus: http.StatusCreated,
+			}},
+			{Method: "DELETE", Path: "/items/:id", Handlers: []gin.HandlerFunc{handler}},
+		},
+		SubGroups: []RouteGroup{{
+			Path:   "/admin",
+			Routes: []Route{{Method: "GET", Path: "/stats", Handlers: []gin.HandlerFunc{handler}, Meta: &RouteMeta{Tags: []string{"admin"}, Deprecated: true}}},
+		}},
+	})
+	return server
+}
+
+func TestRouteGroupTags(t *testing.T) {
+	server := newDocumentedServer(t)
+
+	docs := make(map[string]RouteDoc)
+	for _, route := range server.Routes() {
+		docs[route.Method+" "+route.Path] = route.Doc()
+	}
+
+	assert.Equal(t, []string{"items"}, docs["GET /api/v1/items/:id"].Tags)
+	assert.Equal(t, "server.docItem", docs["GET /api/v1/items/:id"].Response)
+	assert.Equal(t, "server.LoginRequest", docs["POST /api/v1/items"].Request)
+	// 没有元数据的路由也继承路由组标签
+	assert.Equal(t, []string{"items"}, docs["DELETE /api/v1/items/:id"].Tags)
+	// 路由自己的标签优先
+	assert.Equal(t, []string{"admin"}, docs["GET /api/v1/admin/stats"].Tags)
+	assert.Empty(t, docs["GET /health"].Tags)
+}
+
+func TestOpenAPI(t *testing.T) {
+	server := newDocumentedServer(t)
+
+	doc, err := server.OpenAPI(OpenAPIInfo{Title: "Test API"})
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(context.Background()))
+
+	get := doc.Paths["/api/v1/items/{id}"].Get
+	require.NotNil(t, get)
+	assert.Equal(t, "Get item", get.Summary)
+	assert.Equal(t, []string{"items"}, get.Tags)
+	require.NotNil(t, get.Security)
+	require.Len(t, get.Parameters, 1)
+	assert.Equal(t, "id", get.Parameters[0].Value.Name)
+	assert.Equal(t, "#/components/schemas/docItem", get.Responses.Get(http.StatusOK).Value.Content.Get("application/json").Schema.Ref)
+	assert.NotNil(t, get.Responses.Get(http.StatusUnauthorized))
+
+	post := doc.Paths["/api/v1/items"].Post
+	require.NotNil(t, post)
+	assert.Equal(t, "#/components/schemas/LoginRequest", post.RequestBody.Value.Content.Get("application/json").Schema.Ref)
+	assert.NotNil(t, post.Responses.Get(http.StatusCreated))
+	assert.Contains(t, doc.Components.Schemas["LoginRequest"].Value.Properties, "username")
+
+	assert.True(t, doc.Paths["/api/v1/admin/stats"].Get.Deprecated)
+}
+
+func TestDocumentationEndpoints(t *testing.T) {
+	server := newDocumentedServer(t)
+
+	w := httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/routes", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data []RouteDoc `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Data)
+
+	w = httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"/api/v1/items/{id}"`)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteRoutes(&out, server.Routes()))
+	assert.Contains(t, out.String(), "Get item")
+	assert.Contains(t, out.String(), "[deprecated]")
+
+	// 未开启Swagger时不注册文档路由
+	disabled, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}},
+	})
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	disabled.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/routes", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
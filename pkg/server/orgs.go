@@ -0,0 +1,310 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+	"github.com/hwh/hwhkit-go/pkg/org"
+)
+
+// orgHandler 组织路由处理器
+type orgHandler struct {
+	server  *Server
+	service *org.Service
+}
+
+// orgRequest 创建或修改组织请求
+type orgRequest struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// orgRolesRequest 修改成员角色请求
+type orgRolesRequest struct {
+	Roles []string `json:"roles" binding:"required"`
+}
+
+// orgInviteRequest 邀请成员请求
+type orgInviteRequest struct {
+	Email string   `json:"email" binding:"required"`
+	Roles []string `json:"roles"`
+}
+
+// orgAcceptRequest 接受邀请请求
+type orgAcceptRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// SetupOrgRoutes 注册组织路由，需要配置认证管理器，所有路由需要JWT认证
+// :org 为组织ID或标识，组织内的路由按成员在该组织中的角色授权（见 org.RegisterDefaultRoles）
+//
+//	POST   /api/v1/orgs                            创建组织，创建者成为所有者
+//	GET    /api/v1/orgs                            列出当前用户所属的组织
+//	POST   /api/v1/orgs/invitations/accept         接受邀请，当前用户邮箱需与被邀请邮箱一致
+//	GET    /api/v1/orgs/:org                       查看组织和自己的成员关系
+//	PUT    /api/v1/orgs/:org                       修改组织（org update）
+//	DELETE /api/v1/orgs/:org                       删除组织（仅所有者）
+//	GET    /api/v1/orgs/:org/members               列出成员（org.members read）
+//	PUT    /api/v1/orgs/:org/members/:user         修改成员角色（org.members update）
+//	DELETE /api/v1/orgs/:org/members/:user         移除成员（org.members delete），成员可以移除自己即退出组织
+//	GET    /api/v1/orgs/:org/invitations           列出待接受的邀请（org.invitations read）
+//	POST   /api/v1/orgs/:org/invitations           邀请成员（org.invitations create）
+//	DELETE /api/v1/orgs/:org/invitations/:id       撤销邀请（org.invitations delete）
+//
+// 只有所有者可以授予或撤销所有者角色；未设置邀请发送器时，邀请接口在响应中返回令牌由调用方自行投递
+func (s *Server) SetupOrgRoutes(service *org.Service) error {
+	if s.middleware == nil {
+		return errors.New("organization routes require an auth manager")
+	}
+	h := &orgHandler{server: s, service: service}
+
+	group := s.engine.Group("/api/v1/orgs", s.middleware.JWT())
+	{
+		group.POST("", h.create)
+		group.GET("", h.list)
+		group.POST("/invitations/accept", h.acceptInvitation)
+
+		scoped := group.Group("/:org", middleware.OrgContext(service, "org"))
+		scoped.GET("", h.get)
+		scoped.PUT("", middleware.RequireOrgPermission(service, org.ResourceOrg, "update"), h.update)
+		scoped.DELETE("", middleware.RequireOrgRole(org.RoleOwner), h.delete)
+		scoped.GET("/members", middleware.RequireOrgPermission(service, org.ResourceMembers, "read"), h.listMembers)
+		scoped.PUT("/members/:user", middleware.RequireOrgPermission(service, org.ResourceMembers, "update"), h.updateMember)
+		scoped.DELETE("/members/:user", h.removeMember)
+		scoped.GET("/invitations", middleware.RequireOrgPermission(service, org.ResourceInvitations, "read"), h.listInvitations)
+		scoped.POST("/invitations", middleware.RequireOrgPermission(service, org.ResourceInvitations, "create"), h.invite)
+		scoped.DELETE("/invitations/:id", middleware.RequireOrgPermission(service, org.ResourceInvitations, "delete"), h.revokeInvitation)
+	}
+	return nil
+}
+
+// create 创建组织
+func (h *orgHandler) create(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	var req orgRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.server.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	created, err := h.service.CreateOrganization(c.Request.Context(), req.Name, req.Slug, userID)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.server.Success(c, created)
+}
+
+// list 列出当前用户所属的组织
+func (h *orgHandler) list(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	orgs, err := h.service.ListUserOrganizations(c.Request.Context(), userID)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.server.Success(c, orgs)
+}
+
+// get 查看当前组织
+func (h *orgHandler) get(c *gin.Context) {
+	current, _ := middleware.GetOrg(c)
+	membership, _ := middleware.GetOrgMembership(c)
+	h.server.Success(c, gin.H{"organization": current, "membership": membership})
+}
+
+// update 修改当前组织
+func (h *orgHandler) update(c *gin.Context) {
+	current, _ := middleware.GetOrg(c)
+	var req orgRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.server.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	updated, err := h.service.UpdateOrganization(c.Request.Context(), current.ID, req.Name, req.Slug)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.server.Success(c, updated)
+}
+
+// delete 删除当前组织
+func (h *orgHandler) delete(c *gin.Context) {
+	current, _ := middleware.GetOrg(c)
+	if err := h.service.DeleteOrganization(c.Request.Context(), current.ID); err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.server.Success(c, gin.H{"id": current.ID, "deleted": true})
+}
+
+// listMembers 列出成员
+func (h *orgHandler) listMembers(c *gin.Context) {
+	current, _ := middleware.GetOrg(c)
+	members, err := h.service.ListMembers(c.Request.Context(), current.ID)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.server.Success(c, members)
+}
+
+// updateMember 修改成员角色
+func (h *orgHandler) updateMember(c *gin.Context) {
+	current, _ := middleware.GetOrg(c)
+	actor, _ := middleware.GetOrgMembership(c)
+	var req orgRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.server.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	target, err := h.service.GetMembership(c.Request.Context(), current.ID, c.Param("user"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if !actor.HasRole(org.RoleOwner) && (target.HasRole(org.RoleOwner) || containsString(req.Roles, org.RoleOwner)) {
+		h.server.Error(c, http.StatusForbidden, "only owners can grant or revoke the owner role")
+		return
+	}
+
+	membership, err := h.service.UpdateMemberRoles(c.Request.Context(), current.ID, target.UserID, req.Roles)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.server.Success(c, membership)
+}
+
+// removeMember 移除成员，移除自己不需要成员管理权限
+func (h *orgHandler) removeMember(c *gin.Context) {
+	current, _ := middleware.GetOrg(c)
+	actor, _ := middleware.GetOrgMembership(c)
+	userID := c.Param("user")
+
+	if userID != actor.UserID {
+		if !h.service.MembershipHasPermission(actor, org.ResourceMembers, "delete") {
+			h.server.Error(c, http.StatusForbidden, "permission denied: delete on "+org.ResourceMembers)
+			return
+		}
+		target, err := h.service.GetMembership(c.Request.Context(), current.ID, userID)
+		if err != nil {
+			h.fail(c, err)
+			return
+		}
+		if target.HasRole(org.RoleOwner) && !actor.HasRole(org.RoleOwner) {
+			h.server.Error(c, http.StatusForbidden, "only owners can remove an owner")
+			return
+		}
+	}
+
+	if err := h.service.RemoveMember(c.Request.Context(), current.ID, userID); err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.server.Success(c, gin.H{"user_id": userID, "removed": true})
+}
+
+// listInvitations 列出待接受的邀请
+func (h *orgHandler) listInvitations(c *gin.Context) {
+	current, _ := middleware.GetOrg(c)
+	invitations, err := h.service.ListInvitations(c.Request.Context(), current.ID)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.server.Success(c, invitations)
+}
+
+// invite 邀请成员
+func (h *orgHandler) invite(c *gin.Context) {
+	current, _ := middleware.GetOrg(c)
+	actor, _ := middleware.GetOrgMembership(c)
+	var req orgInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.server.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if containsString(req.Roles, org.RoleOwner) && !actor.HasRole(org.RoleOwner) {
+		h.server.Error(c, http.StatusForbidden, "only owners can grant or revoke the owner role")
+		return
+	}
+
+	invitation, token, err := h.service.Invite(c.Request.Context(), current.ID, req.Email, req.Roles, actor.UserID)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	data := gin.H{"invitation": invitation}
+	if !h.service.HasInvitationSender() {
+		data["token"] = token
+	}
+	h.server.Success(c, data)
+}
+
+// revokeInvitation 撤销邀请
+func (h *orgHandler) revokeInvitation(c *gin.Context) {
+	current, _ := middleware.GetOrg(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.server.Error(c, http.StatusBadRequest, "invalid invitation id")
+		return
+	}
+	if err := h.service.RevokeInvitation(c.Request.Context(), current.ID, uint(id)); err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.server.Success(c, gin.H{"id": id, "revoked": true})
+}
+
+// acceptInvitation 接受邀请，使用令牌中的邮箱校验被邀请人
+func (h *orgHandler) acceptInvitation(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		h.server.Error(c, http.StatusUnauthorized, "no user information found")
+		return
+	}
+	var req orgAcceptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.server.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	membership, err := h.service.AcceptInvitation(c.Request.Context(), req.Token, claims.UserID, claims.Email)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.server.Success(c, membership)
+}
+
+// fail 按组织错误返回响应
+func (h *orgHandler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, org.ErrOrganizationNotFound), errors.Is(err, org.ErrNotMember), errors.Is(err, org.ErrInvitationNotFound):
+		h.server.Error(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, org.ErrSlugTaken):
+		h.server.Error(c, http.StatusConflict, err.Error())
+	case errors.Is(err, org.ErrLastOwner), errors.Is(err, org.ErrInvitationEmailMismatch):
+		h.server.Error(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, org.ErrInvitationExpired):
+		h.server.Error(c, http.StatusGone, err.Error())
+	case errors.Is(err, org.ErrInvalidName), errors.Is(err, org.ErrInvalidSlug), errors.Is(err, org.ErrInvalidEmail), errors.Is(err, org.ErrUnknownRole):
+		h.server.Error(c, http.StatusBadRequest, err.Error())
+	default:
+		h.server.Error(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// containsString 字符串列表是否包含指定值
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
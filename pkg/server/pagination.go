@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPage     = 1
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// PaginationLinks 分页导航链接，使用相对于当前请求的URL
+type PaginationLinks struct {
+	First string `json:"first"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last"`
+}
+
+// paginationParams 获取分页参数，优先使用上下文中已解析的值，否则读取 page/page_size 查询参数
+func paginationParams(c *gin.Context) (page, pageSize int) {
+	page = c.GetInt("page")
+	if page <= 0 {
+		page, _ = strconv.Atoi(c.Query("page"))
+	}
+	if page <= 0 {
+		page = defaultPage
+	}
+
+	pageSize = c.GetInt("page_size")
+	if pageSize <= 0 {
+		pageSize, _ = strconv.Atoi(c.Query("page_size"))
+	}
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return page, pageSize
+}
+
+// newPaginationLinks 根据当前请求URL生成分页链接，保留其他查询参数
+func newPaginationLinks(u *url.URL, page, pageSize int, totalPages int64) PaginationLinks {
+	last := int(totalPages)
+	if last < 1 {
+		last = 1
+	}
+
+	pageURL := func(p int) string {
+		query := u.Query()
+		query.Set("page", strconv.Itoa(p))
+		query.Set("page_size", strconv.Itoa(pageSize))
+		return (&url.URL{Path: u.Path, RawQuery: query.Encode()}).String()
+	}
+
+	links := PaginationLinks{
+		First: pageURL(1),
+		Last:  pageURL(last),
+	}
+	if page > 1 {
+		links.Prev = pageURL(min(page-1, last))
+	}
+	if page < last {
+		links.Next = pageURL(page + 1)
+	}
+	return links
+}
+
+// Header 生成 RFC 5988 Link 响应头
+func (l PaginationLinks) Header() string {
+	var parts []string
+	for _, link := range []struct{ rel, href string }{
+		{"first", l.First},
+		{"prev", l.Prev},
+		{"next", l.Next},
+		{"last", l.Last},
+	} {
+		if link.href != "" {
+			parts = append(parts, "<"+link.href+`>; rel="`+link.rel+`"`)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
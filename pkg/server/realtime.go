@@ -0,0 +1,249 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrServerDraining 服务器正在关闭，不再接受新的长连接
+var ErrServerDraining = errors.New("server is draining connections")
+
+// 长连接类型
+const (
+	RealtimeWebSocket = "websocket"
+	RealtimeSSE       = "sse"
+	RealtimeLongPoll  = "longpoll"
+)
+
+// RealtimeOptions 长连接登记选项
+type RealtimeOptions struct {
+	Kind   string       // 连接类型，如 websocket、sse
+	Notify func() error // 开始关闭时通知客户端，如发送WebSocket关闭帧或SSE重连事件
+	Close  func() error // 宽限期结束后强制关闭连接
+}
+
+// RealtimeConn 已登记的长连接
+type RealtimeConn struct {
+	id        uint64
+	kind      string
+	startedAt time.Time
+	draining  <-chan struct{}
+	notify    func() error
+	close     func() error
+	release   func()
+	once      sync.Once
+}
+
+// Kind 获取连接类型
+func (rc *RealtimeConn) Kind() string {
+	return rc.kind
+}
+
+// StartedAt 获取连接建立时间
+func (rc *RealtimeConn) StartedAt() time.Time {
+	return rc.startedAt
+}
+
+// Draining 服务器开始关闭时该通道关闭，处理器应在收到后结束连接
+func (rc *RealtimeConn) Draining() <-chan struct{} {
+	return rc.draining
+}
+
+// Done 连接结束时调用，处理器退出前必须调用
+func (rc *RealtimeConn) Done() {
+	rc.once.Do(rc.release)
+}
+
+// realtimeTracker 跟踪 WebSocket、SSE 等长连接，支持关闭时排空
+type realtimeTracker struct {
+	mu       sync.Mutex
+	conns    map[uint64]*RealtimeConn
+	nextID   uint64
+	draining chan struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// newRealtimeTracker 创建长连接跟踪器
+func newRealtimeTracker() *realtimeTracker {
+	return &realtimeTracker{
+		conns:    make(map[uint64]*RealtimeConn),
+		draining: make(chan struct{}),
+	}
+}
+
+// track 登记长连接
+func (t *realtimeTracker) track(opts RealtimeOptions) (*RealtimeConn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil, ErrServerDraining
+	}
+
+	t.nextID++
+	id := t.nextID
+	conn := &RealtimeConn{
+		id:        id,
+		kind:      opts.Kind,
+		startedAt: time.Now(),
+		draining:  t.draining,
+		notify:    opts.Notify,
+		close:     opts.Close,
+	}
+	conn.release = func() {
+		t.mu.Lock()
+		delete(t.conns, id)
+		t.mu.Unlock()
+		t.wg.Done()
+	}
+	t.conns[id] = conn
+	t.wg.Add(1)
+	return conn, nil
+}
+
+// count 获取当前长连接数量
+func (t *realtimeTracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.conns)
+}
+
+// snapshot 获取当前所有长连接
+func (t *realtimeTracker) snapshot() []*RealtimeConn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	conns := make([]*RealtimeConn, 0, len(t.conns))
+	for _, conn := range t.conns {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// drain 通知所有长连接即将关闭，宽限期内未结束的连接被强制关闭
+// 返回被强制关闭的连接数
+func (t *realtimeTracker) drain(grace time.Duration) int {
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.draining)
+	}
+	t.mu.Unlock()
+
+	for _, conn := range t.snapshot() {
+		if conn.notify != nil {
+			_ = conn.notify()
+		}
+	}
+
+	if t.wait(grace) {
+		return 0
+	}
+
+	remaining := t.snapshot()
+	for _, conn := range remaining {
+		if conn.close != nil {
+			_ = conn.close()
+		}
+	}
+	return len(remaining)
+}
+
+// wait 等待所有长连接结束，超时返回false
+func (t *realtimeTracker) wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// TrackRealtime 登记 WebSocket、SSE、长轮询等长连接
+// 服务器关闭时先关闭 Draining 通道并调用 Notify，宽限期后对仍未结束的连接调用 Close
+//
+//	conn, err := srv.TrackRealtime(server.RealtimeOptions{Kind: server.RealtimeWebSocket, Close: ws.Close})
+//	if err != nil { ... }
+//	defer conn.Done()
+func (s *Server) TrackRealtime(opts RealtimeOptions) (*RealtimeConn, error) {
+	return s.realtime.track(opts)
+}
+
+// RealtimeConnections 获取当前长连接数量
+func (s *Server) RealtimeConnections() int {
+	return s.realtime.count()
+}
+
+// SSEEvent Server-Sent Events 事件
+type SSEEvent struct {
+	Name string
+	Data interface{}
+}
+
+// StreamSSE 将通道中的事件以Server-Sent Events推送给客户端，通道关闭或客户端断开时结束
+// 服务器关闭时发送 reconnect 事件，客户端可重连到其他实例
+func (s *Server) StreamSSE(c *gin.Context, events <-chan SSEEvent) {
+	conn, err := s.TrackRealtime(RealtimeOptions{Kind: RealtimeSSE})
+	if err != nil {
+		c.AbortWithStatus(http.StatusServiceUnavailable)
+		return
+	}
+	defer conn.Done()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-conn.Draining():
+			c.SSEvent("reconnect", gin.H{"retry_ms": 1000})
+			return false
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Name, event.Data)
+			return true
+		}
+	})
+}
+
+// drainRealtime 关闭服务器时排空长连接
+func (s *Server) drainRealtime() {
+	grace := time.Duration(s.config.Server.DrainGracePeriod) * time.Second
+	if grace <= 0 {
+		grace = 5 * time.Second
+	}
+
+	count := s.realtime.count()
+	if count > 0 && s.logger != nil {
+		s.logger.Infof("Draining %d realtime connection(s), grace period %s", count, grace)
+	}
+	if forced := s.realtime.drain(grace); forced > 0 && s.logger != nil {
+		s.logger.Warnf("Force closed %d realtime connection(s) after grace period", forced)
+	}
+}
+
+// waitRealtime 等待长连接处理器退出（被劫持的连接不受 http.Server.Shutdown 等待）
+func (s *Server) waitRealtime(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(time.Second)
+	}
+	if !s.realtime.wait(time.Until(deadline)) && s.logger != nil {
+		s.logger.Warnf("%d realtime connection(s) still open after shutdown", s.realtime.count())
+	}
+}
@@ -29,6 +29,7 @@ type RouteGroup struct {
 	Middlewares []gin.HandlerFunc
 	Routes      []Route
 	SubGroups   []RouteGroup
+	Tags        []string // 文档分组标签，组内及子组中未设置标签的路由继承
 }
 
 // Route 路由配置
@@ -37,15 +38,20 @@ type Route struct {
 	Path        string
 	Handlers    []gin.HandlerFunc
 	Middlewares []gin.HandlerFunc
+	Meta        *RouteMeta
 }
 
 // RegisterRouteGroup 注册路由组
 func (rm *RouterManager) RegisterRouteGroup(group RouteGroup) {
-	rm.registerGroup(rm.engine.Group(group.Path), group)
+	rm.registerGroup(rm.engine.Group(group.Path), group, nil)
 }
 
-// registerGroup 递归注册路由组
-func (rm *RouterManager) registerGroup(ginGroup *gin.RouterGroup, group RouteGroup) {
+// registerGroup 递归注册路由组，tags 为上级路由组的文档标签
+func (rm *RouterManager) registerGroup(ginGroup *gin.RouterGroup, group RouteGroup, tags []string) {
+	if len(group.Tags) > 0 {
+		tags = group.Tags
+	}
+	
 	// 应用中间件
 	for _, mw := range group.Middlewares {
 		ginGroup.Use(mw)
@@ -73,11 +79,23 @@ func (rm *RouterManager) registerGroup(ginGroup *gin.RouterGroup, group RouteGro
 		case "ANY":
 			ginGroup.Any(route.Path, handlers...)
 		}
+		
+		meta := route.Meta
+		if meta == nil && len(tags) > 0 {
+			meta = &RouteMeta{}
+		}
+		if meta != nil {
+			resolved := *meta
+			if len(resolved.Tags) == 0 {
+				resolved.Tags = tags
+			}
+			rm.server.SetRouteMeta(route.Method, joinRoutePath(ginGroup.BasePath(), route.Path), resolved)
+		}
 	}
 	
 	// 注册子组
 	for _, subGroup := range group.SubGroups {
-		rm.registerGroup(ginGroup.Group(subGroup.Path), subGroup)
+		rm.registerGroup(ginGroup.Group(subGroup.Path), subGroup, tags)
 	}
 }
 
@@ -180,6 +198,11 @@ func (ar *APIRouter) setupAdminRoutes(router *gin.RouterGroup) {
 	
 	router.GET("/stats", ar.getStatsHandler)
 	router.GET("/logs", ar.getLogsHandler)
+	router.GET("/security", ar.server.securityReportHandler)
+	
+	ar.server.registerCacheAdminRoutes(router)
+	ar.server.registerDatabaseAdminRoutes(router)
+	ar.server.registerSchedulerAdminRoutes(router)
 }
 
 // 认证相关处理器
@@ -218,7 +241,8 @@ func (ar *APIRouter) meHandler(c *gin.Context) {
 			"user_id":  claims.UserID,
 			"username": claims.Username,
 			"email":    claims.Email,
-			"role":     claims.Role,
+			"role":     claims.PrimaryRole(),
+			"roles":    claims.Roles,
 		})
 	} else {
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouteMeta 路由元数据，契约测试、OpenAPI文档、路由列表接口和 routes 命令共用
+type RouteMeta struct {
+	Summary        string            // 路由说明
+	Description    string            // 详细描述
+	Tags           []string          // 文档分组标签，为空时继承路由组的标签
+	Request        interface{}       // 请求体类型，传零值即可，如 LoginRequest{}
+	Response       interface{}       // 成功响应体类型
+	Deprecated     bool              // 是否已废弃
+	AuthRequired   bool              // 是否需要认证
+	Roles          []string          // 访问所需角色
+	PathParams     map[string]string // 路径参数示例值
+	SampleRequest  interface{}       // 合法请求体示例
+	InvalidRequest interface{}       // 校验失败的请求体示例
+	SuccessStatus  int               // 成功状态码，为0时接受任意2xx
+	Paginated      bool              // 处理函数读取分页上下文键，校验中间件链时要求在分页中间件之后
+}
+
+// RouteInfo 已注册路由信息
+type RouteInfo struct {
+	Method  string
+	Path    string
+	Handler string
+	Meta    *RouteMeta
+}
+
+// RouteDoc 路由文档，路由列表接口和 routes 命令的输出格式
+type RouteDoc struct {
+	Method       string   `json:"method"`
+	Path         string   `json:"path"`
+	Handler      string   `json:"handler"`
+	Summary      string   `json:"summary,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	AuthRequired bool     `json:"auth_required"`
+	Roles        []string `json:"roles,omitempty"`
+	Request      string   `json:"request,omitempty"`  // 请求体类型名
+	Response     string   `json:"response,omitempty"` // 响应体类型名
+	Deprecated   bool     `json:"deprecated,omitempty"`
+}
+
+// Doc 获取路由文档
+func (r RouteInfo) Doc() RouteDoc {
+	doc := RouteDoc{Method: r.Method, Path: r.Path, Handler: r.Handler}
+	if r.Meta != nil {
+		doc.Summary = r.Meta.Summary
+		doc.Description = r.Meta.Description
+		doc.Tags = r.Meta.Tags
+		doc.AuthRequired = r.Meta.AuthRequired
+		doc.Roles = r.Meta.Roles
+		doc.Request = typeName(r.Meta.Request)
+		doc.Response = typeName(r.Meta.Response)
+		doc.Deprecated = r.Meta.Deprecated
+	}
+	return doc
+}
+
+// typeName 获取值的类型名，如 server.LoginRequest，nil 时为空
+func typeName(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return reflect.TypeOf(v).String()
+}
+
+// WriteRoutes 以表格形式输出路由列表，供应用的 routes 命令使用
+func WriteRoutes(w io.Writer, routes []RouteInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tAUTH\tTAGS\tREQUEST\tRESPONSE\tSUMMARY")
+	for _, route := range routes {
+		doc := route.Doc()
+		auth := "-"
+		if doc.AuthRequired {
+			auth = "yes"
+			if len(doc.Roles) > 0 {
+				auth = strings.Join(doc.Roles, ",")
+			}
+		}
+		summary := doc.Summary
+		if doc.Deprecated {
+			summary = "[deprecated] " + summary
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			doc.Method, doc.Path, auth, orDash(strings.Join(doc.Tags, ",")), orDash(doc.Request), orDash(doc.Response), summary)
+	}
+	return tw.Flush()
+}
+
+// orDash 空值显示为 -
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// routesHandler 路由列表接口
+func (s *Server) routesHandler(c *gin.Context) {
+	routes := s.Routes()
+	docs := make([]RouteDoc, 0, len(routes))
+	for _, route := range routes {
+		docs = append(docs, route.Doc())
+	}
+	s.Success(c, docs)
+}
+
+// routeRegistry 路由元数据注册表
+type routeRegistry struct {
+	mu   sync.RWMutex
+	meta map[string]RouteMeta
+}
+
+// routeKey 路由元数据键
+func routeKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// SetRouteMeta 设置路由元数据，path 为完整路由路径（如 /api/v1/users/:id）
+func (s *Server) SetRouteMeta(method, path string, meta RouteMeta) {
+	s.routes.mu.Lock()
+	defer s.routes.mu.Unlock()
+
+	if s.routes.meta == nil {
+		s.routes.meta = make(map[string]RouteMeta)
+	}
+	s.routes.meta[routeKey(method, path)] = meta
+}
+
+// Routes 获取已注册的路由及其元数据，按路径和方法排序
+func (s *Server) Routes() []RouteInfo {
+	s.routes.mu.RLock()
+	defer s.routes.mu.RUnlock()
+
+	ginRoutes := s.engine.Routes()
+	routes := make([]RouteInfo, 0, len(ginRoutes))
+	for _, route := range ginRoutes {
+		info := RouteInfo{
+			Method:  route.Method,
+			Path:    route.Path,
+			Handler: route.Handler,
+		}
+		meta, exists := s.routes.meta[routeKey(route.Method, route.Path)]
+		if !exists {
+			meta, exists = s.routes.meta[routeKey("ANY", route.Path)]
+		}
+		if exists {
+			info.Meta = &meta
+		}
+		routes = append(routes, info)
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// joinRoutePath 拼接路由组路径和相对路径
+func joinRoutePath(basePath, relativePath string) string {
+	if relativePath == "" {
+		return basePath
+	}
+	return strings.TrimSuffix(basePath, "/") + "/" + strings.TrimPrefix(relativePath, "/")
+}
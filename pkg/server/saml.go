@@ -0,0 +1,118 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/auth"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+)
+
+// samlRequestCookie 保存AuthnRequest ID的Cookie，用于校验响应的 InResponseTo
+const samlRequestCookie = "saml_request_id"
+
+// SAMLLoginFunc 将通过校验的SAML身份解析为本地用户，返回绑定到登录会话的本地用户ID
+// user.ID 带 auth.SAMLIdentityPrefix 前缀，应按它查找已关联的本地账号或开通新账号，不要按IdP声明的用户名匹配本地密码账号；
+// user.Roles 为 RoleMap 映射后的角色，需要时由钩子同步到本地用户；返回错误或空ID时拒绝登录
+type SAMLLoginFunc func(c *gin.Context, user *auth.User) (localUserID string, err error)
+
+// samlHandler SAML服务提供方路由处理器
+type samlHandler struct {
+	server  *Server
+	sp      *auth.SAMLServiceProvider
+	onLogin SAMLLoginFunc
+}
+
+// SetupSAMLRoutes 注册SAML服务提供方路由，onLogin 必须提供
+func (s *Server) SetupSAMLRoutes(sp *auth.SAMLServiceProvider, onLogin SAMLLoginFunc) error {
+	if onLogin == nil {
+		return errors.New("saml login hook is required to resolve local users")
+	}
+	h := &samlHandler{server: s, sp: sp, onLogin: onLogin}
+
+	s.engine.GET(auth.SAMLMetadataPath, h.metadata)
+	s.engine.GET(auth.SAMLLoginPath, h.login)
+	s.engine.POST(auth.SAMLACSPath, h.acs)
+	return nil
+}
+
+// metadata SP元数据
+func (h *samlHandler) metadata(c *gin.Context) {
+	data, err := h.sp.Metadata()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to build metadata")
+		return
+	}
+	c.Data(http.StatusOK, "application/samlmetadata+xml", data)
+}
+
+// login 跳转到IdP登录
+func (h *samlHandler) login(c *gin.Context) {
+	redirectURL, requestID, err := h.sp.MakeAuthnRequest(safeRelayState(c.Query("next")))
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to start saml login")
+		return
+	}
+	// IdP以跨站POST回调ACS，Cookie需要 SameSite=None 才能随请求发送
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     samlRequestCookie,
+		Value:    requestID,
+		Path:     auth.SAMLACSPath,
+		MaxAge:   300,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+	})
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// acs 断言消费端点
+func (h *samlHandler) acs(c *gin.Context) {
+	var requestIDs []string
+	if requestID, err := c.Cookie(samlRequestCookie); err == nil && requestID != "" {
+		requestIDs = append(requestIDs, requestID)
+	}
+	c.SetCookie(samlRequestCookie, "", -1, auth.SAMLACSPath, "", true, true)
+
+	user, err := h.sp.ParseResponse(c.Request, requestIDs)
+	if err != nil {
+		if h.server.logger != nil {
+			h.server.logger.Warnf("SAML登录失败: %v", err)
+		}
+		c.Redirect(http.StatusFound, "/login?error=saml_failed")
+		return
+	}
+	h.completeLogin(c, user, c.PostForm("RelayState"))
+}
+
+// completeLogin 通过登录钩子解析本地用户并绑定会话，然后跳转到 relayState
+func (h *samlHandler) completeLogin(c *gin.Context, user *auth.User, relayState string) {
+	localUserID, err := h.onLogin(c, user)
+	if err == nil && localUserID == "" {
+		err = errors.New("no local user resolved")
+	}
+	if err != nil {
+		if h.server.logger != nil {
+			h.server.logger.Warnf("SAML用户 %s 登录被拒绝: %v", user.ID, err)
+		}
+		c.Redirect(http.StatusFound, "/login?error=saml_denied")
+		return
+	}
+
+	// 会话绑定到钩子返回的本地用户，而不是IdP声明的用户名
+	if _, err := middleware.LoginSession(c, localUserID); err != nil {
+		c.Redirect(http.StatusFound, "/login?error=session_unavailable")
+		return
+	}
+	c.Redirect(http.StatusFound, safeRelayState(relayState))
+}
+
+// safeRelayState 只允许站内相对路径作为登录后的跳转地址，用于SAML的 RelayState 和登录表单的 next
+func safeRelayState(target string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "/dashboard"
+	}
+	return target
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+	"github.com/hwh/hwhkit-go/pkg/scheduler"
+)
+
+// registerSchedulerAdminRoutes 注册定时任务管理接口，应挂载在需要管理员权限的路由组下
+//
+//	GET  /scheduler/jobs            所有任务的状态（下次执行、上次执行时间和结果）
+//	GET  /scheduler/jobs/:name      单个任务的状态
+//	POST /scheduler/jobs/:name/run  立即执行任务
+func (s *Server) registerSchedulerAdminRoutes(router gin.IRouter) {
+	group := router.Group("/scheduler")
+	group.GET("/jobs", s.schedulerJobsHandler)
+	group.GET("/jobs/:name", s.schedulerJobHandler)
+	group.POST("/jobs/:name/run", s.schedulerRunHandler)
+}
+
+// requireScheduler 检查调度器是否可用
+func (s *Server) requireScheduler(c *gin.Context) bool {
+	if s.scheduler == nil {
+		s.Error(c, http.StatusServiceUnavailable, "scheduler is not configured")
+		return false
+	}
+	return true
+}
+
+// schedulerJobsHandler 所有任务的状态
+func (s *Server) schedulerJobsHandler(c *gin.Context) {
+	if !s.requireScheduler(c) {
+		return
+	}
+	jobs := s.scheduler.Jobs()
+	s.Success(c, gin.H{"jobs": jobs, "count": len(jobs)})
+}
+
+// schedulerJobHandler 单个任务的状态
+func (s *Server) schedulerJobHandler(c *gin.Context) {
+	if !s.requireScheduler(c) {
+		return
+	}
+	status, err := s.scheduler.Job(c.Param("name"))
+	if err != nil {
+		s.Error(c, http.StatusNotFound, err.Error())
+		return
+	}
+	s.Success(c, status)
+}
+
+// schedulerRunHandler 立即执行任务
+func (s *Server) schedulerRunHandler(c *gin.Context) {
+	if !s.requireScheduler(c) {
+		return
+	}
+
+	name := c.Param("name")
+	if s.logger != nil {
+		operator, _ := middleware.GetUsername(c)
+		s.logger.Warnf("Scheduled job %s triggered by admin: operator=%s", name, operator)
+	}
+	if err := s.scheduler.RunNow(name); err != nil {
+		switch {
+		case errors.Is(err, scheduler.ErrJobNotFound):
+			s.Error(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, scheduler.ErrJobRunning):
+			s.Error(c, http.StatusConflict, err.Error())
+		default:
+			s.Error(c, http.StatusServiceUnavailable, err.Error())
+		}
+		return
+	}
+	s.Success(c, gin.H{"job": name, "triggered": true})
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+)
+
+// runSecurityChecks 执行启动安全检查并输出警告
+// 开启 FailOnInsecure 时，存在严重问题会返回错误阻止启动
+func (s *Server) runSecurityChecks() error {
+	s.securityReport = s.config.CheckSecurity()
+
+	for _, finding := range s.securityReport.Findings {
+		if s.logger != nil {
+			s.logger.Warnf("Security check [%s] %s: %s", finding.Severity, finding.Check, finding.Message)
+		}
+	}
+
+	if s.config.Server.FailOnInsecure && s.securityReport.HasCritical() {
+		return fmt.Errorf("insecure configuration detected in %s mode, see security report", s.config.Server.Mode)
+	}
+	return nil
+}
+
+// corsConfig 根据服务器配置生成CORS配置
+func (s *Server) corsConfig() *middleware.CORSConfig {
+	cfg := middleware.DefaultCORSConfig()
+	if len(s.config.Server.CORSAllowOrigins) > 0 {
+		cfg.AllowOrigins = s.config.Server.CORSAllowOrigins
+	}
+	cfg.AllowCredentials = s.config.Server.CORSAllowCredentials
+	return cfg
+}
+
+// securityReportHandler 安全检查报告
+func (s *Server) securityReportHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.securityReport)
+}
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/auth"
+	"github.com/hwh/hwhkit-go/pkg/cache"
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/hwh/hwhkit-go/pkg/database"
+	"gorm.io/gorm"
+)
+
+// 自检结果状态
+const (
+	SelfTestPassed  = "passed"
+	SelfTestFailed  = "failed"
+	SelfTestSkipped = "skipped"
+)
+
+// 自检项名称，可用于 SelfTestConfig.Skip
+const (
+	SelfTestCheckConfig       = "config"
+	SelfTestCheckJWT          = "jwt"
+	SelfTestCheckDatabase     = "database"
+	SelfTestCheckMigrations   = "migrations"
+	SelfTestCheckRedis        = "redis"
+	SelfTestCheckRemoteConfig = "remote_config"
+	SelfTestCheckSMTP         = "smtp"
+)
+
+// SelfTestConfig 启动自检配置
+type SelfTestConfig struct {
+	Config        *config.Config
+	ConfigManager *config.ConfigManager                // 可选，使用远程配置时检查远程配置API
+	Migrations    func(db *gorm.DB) *database.Migrator // 在自检的数据库连接上创建迁移器，检查是否有待执行迁移；未设置时迁移检查标记为跳过
+	SMTPAddr      string                               // 可选，SMTP服务器地址（host:port），检查连接和EHLO
+	Timeout       time.Duration                        // 单项检查超时，默认10秒
+	Skip          []string                             // 跳过的检查项
+}
+
+// SelfTestResult 单项检查结果
+type SelfTestResult struct {
+	Name     string      `json:"name"`
+	Status   string      `json:"status"`
+	Error    string      `json:"error,omitempty"`
+	Detail   interface{} `json:"detail,omitempty"`
+	Duration string      `json:"duration"`
+}
+
+// SelfTestReport 自检报告
+type SelfTestReport struct {
+	Passed    bool             `json:"passed"`
+	Checks    []SelfTestResult `json:"checks"`
+	StartedAt time.Time        `json:"started_at"`
+	Duration  string           `json:"duration"`
+}
+
+// ExitCode 自检通过返回0，否则返回1
+func (r *SelfTestReport) ExitCode() int {
+	if r.Passed {
+		return 0
+	}
+	return 1
+}
+
+// Write 以JSON格式输出报告
+func (r *SelfTestReport) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// IsSelfTestMode 是否以自检模式运行：命令行第一个参数为 selftest 或环境变量 SELFTEST=true
+func IsSelfTestMode(args []string) bool {
+	if len(args) > 1 && args[1] == "selftest" {
+		return true
+	}
+	enabled, _ := strconv.ParseBool(os.Getenv("SELFTEST"))
+	return enabled
+}
+
+// RunSelfTest 依次检查配置、JWT密钥、数据库、迁移、Redis、远程配置和SMTP连通性，适合作为容器的 init 检查：
+//
+//	if server.IsSelfTestMode(os.Args) {
+//		report := server.RunSelfTest(ctx, &server.SelfTestConfig{Config: cfg, ConfigManager: configManager})
+//		report.Write(os.Stdout)
+//		os.Exit(report.ExitCode())
+//	}
+func RunSelfTest(ctx context.Context, cfg *SelfTestConfig) *SelfTestReport {
+	report := &SelfTestReport{Passed: true, StartedAt: time.Now()}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	skipped := make(map[string]bool, len(cfg.Skip))
+	for _, name := range cfg.Skip {
+		skipped[name] = true
+	}
+
+	run := func(name string, check func(ctx context.Context) (interface{}, error)) {
+		if skipped[name] || check == nil {
+			report.Checks = append(report.Checks, SelfTestResult{Name: name, Status: SelfTestSkipped, Duration: "0s"})
+			return
+		}
+		result := runSelfTestCheck(ctx, name, cfg.Timeout, check)
+		if result.Status == SelfTestFailed {
+			report.Passed = false
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	if cfg.Config == nil {
+		run(SelfTestCheckConfig, func(context.Context) (interface{}, error) {
+			return nil, errors.New("config is required")
+		})
+		report.Duration = time.Since(report.StartedAt).String()
+		return report
+	}
+
+	run(SelfTestCheckConfig, func(context.Context) (interface{}, error) {
+		return nil, cfg.Config.Validate()
+	})
+	run(SelfTestCheckJWT, func(context.Context) (interface{}, error) {
+		return nil, checkJWTKey(&cfg.Config.JWT)
+	})
+
+	// 迁移检查复用数据库检查建立的连接，检查超时后才建立的连接不再使用
+	connected := make(chan *database.Manager, 1)
+	run(SelfTestCheckDatabase, func(context.Context) (interface{}, error) {
+		manager, err := database.New(&cfg.Config.Database)
+		if err != nil {
+			return nil, err
+		}
+		connected <- manager
+		return map[string]interface{}{"type": cfg.Config.Database.Type}, manager.Health()
+	})
+	var db *database.Manager
+	select {
+	case db = <-connected:
+		defer db.Close()
+	default:
+	}
+
+	var migrations func(ctx context.Context) (interface{}, error)
+	if cfg.Migrations != nil && db != nil {
+		migrations = func(context.Context) (interface{}, error) {
+			status, err := cfg.Migrations(db.GetDB()).MigrationStatus()
+			if err != nil {
+				return nil, err
+			}
+			if !status.UpToDate {
+				return status, fmt.Errorf("%d pending migrations, %d schema changes", len(status.Pending), len(status.SchemaChanges))
+			}
+			return nil, nil
+		}
+	}
+	if cfg.Migrations == nil && !skipped[SelfTestCheckMigrations] {
+		// 跳过原因写入报告，避免误以为迁移已检查
+		report.Checks = append(report.Checks, SelfTestResult{
+			Name:     SelfTestCheckMigrations,
+			Status:   SelfTestSkipped,
+			Detail:   "SelfTestConfig.Migrations is not set",
+			Duration: "0s",
+		})
+	} else {
+		run(SelfTestCheckMigrations, migrations)
+	}
+
+	run(SelfTestCheckRedis, func(context.Context) (interface{}, error) {
+		manager, err := cache.New(&cfg.Config.Redis)
+		if err != nil {
+			return nil, err
+		}
+		defer manager.Close()
+		return nil, manager.Health()
+	})
+
+	var remote func(ctx context.Context) (interface{}, error)
+	if cfg.ConfigManager != nil && cfg.ConfigManager.RemoteURL() != "" {
+		remote = func(ctx context.Context) (interface{}, error) {
+			return map[string]interface{}{"source": cfg.ConfigManager.Status().Source}, cfg.ConfigManager.CheckRemote(ctx)
+		}
+	}
+	run(SelfTestCheckRemoteConfig, remote)
+
+	var mail func(ctx context.Context) (interface{}, error)
+	if cfg.SMTPAddr != "" {
+		mail = func(ctx context.Context) (interface{}, error) {
+			return nil, checkSMTP(ctx, cfg.SMTPAddr)
+		}
+	}
+	run(SelfTestCheckSMTP, mail)
+
+	report.Duration = time.Since(report.StartedAt).String()
+	return report
+}
+
+// runSelfTestCheck 执行单项检查，超时后不再等待检查返回
+func runSelfTestCheck(ctx context.Context, name string, timeout time.Duration, check func(ctx context.Context) (interface{}, error)) SelfTestResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		detail interface{}
+		err    error
+	}
+	start := time.Now()
+	done := make(chan outcome, 1)
+	go func() {
+		detail, err := check(ctx)
+		done <- outcome{detail, err}
+	}()
+
+	result := SelfTestResult{Name: name, Status: SelfTestPassed}
+	select {
+	case o := <-done:
+		result.Detail = o.detail
+		if o.err != nil {
+			result.Status = SelfTestFailed
+			result.Error = o.err.Error()
+		}
+	case <-ctx.Done():
+		result.Status = SelfTestFailed
+		result.Error = fmt.Sprintf("check timed out: %v", ctx.Err())
+	}
+	result.Duration = time.Since(start).String()
+	return result
+}
+
+// checkJWTKey 检查JWT密钥不是默认值且长度足够，并用该密钥签发和校验一次令牌
+func checkJWTKey(cfg *config.JWTConfig) error {
+	if cfg.Secret == "" || cfg.Secret == config.DefaultJWTSecret {
+		return errors.New("JWT secret is empty or uses the built-in default")
+	}
+	if len(cfg.Secret) < 32 {
+		return errors.New("JWT secret is shorter than 32 characters")
+	}
+
+	manager := auth.New(cfg)
+	token, err := manager.GenerateToken(1, "selftest", "", "")
+	if err != nil {
+		return fmt.Errorf("failed to sign token: %w", err)
+	}
+	if _, err := manager.ValidateToken(token); err != nil {
+		return fmt.Errorf("failed to validate token: %w", err)
+	}
+	return nil
+}
+
+// checkSMTP 连接SMTP服务器并完成EHLO握手
+func checkSMTP(ctx context.Context, addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address: %w", err)
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to read SMTP greeting: %w", err)
+	}
+	defer client.Close()
+	if err := client.Hello("localhost"); err != nil {
+		return fmt.Errorf("SMTP EHLO failed: %w", err)
+	}
+	return client.Quit()
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,8 +15,14 @@ import (
 	"github.com/hwh/hwhkit-go/pkg/cache"
 	"github.com/hwh/hwhkit-go/pkg/config"
 	"github.com/hwh/hwhkit-go/pkg/database"
+	"github.com/hwh/hwhkit-go/pkg/degrade"
 	"github.com/hwh/hwhkit-go/pkg/logger"
+	"github.com/hwh/hwhkit-go/pkg/metrics"
 	"github.com/hwh/hwhkit-go/pkg/middleware"
+	"github.com/hwh/hwhkit-go/pkg/scheduler"
+	"github.com/hwh/hwhkit-go/pkg/tracing"
+	"github.com/hwh/hwhkit-go/pkg/utils"
+	"gorm.io/gorm"
 )
 
 // Server HTTP服务器
@@ -27,16 +34,42 @@ type Server struct {
 	db          *database.Manager
 	cache       *cache.Manager
 	auth        *auth.Manager
+	authService *auth.AuthService
 	middleware  *middleware.MiddlewareManager
+	
+	securityReport *config.SecurityReport
+	routes         routeRegistry
+	configManager  *config.ConfigManager
+	realtime       *realtimeTracker
+	migrator       *database.Migrator
+	schemaCheck    schemaReadinessCache
+	tracing        *tracing.Manager
+	dependencies   []*degrade.DependencyGuard
+	status         *statusTracker
+	startedAt      time.Time
+	shutdownHooks  []func(ctx context.Context) error
+	urlSigner      *utils.URLSigner
+	scheduler      *scheduler.Scheduler
+	sessions       *cache.SessionManager
+	stopJanitor    context.CancelFunc
+	rateLimiters   []*middleware.RateLimiter
+	chainPolicy    *ChainPolicy
+	fieldKeys      *utils.KeyRing
 }
 
 // ServerConfig 服务器配置选项
 type ServerConfig struct {
-	Config     *config.Config
-	Logger     *logger.Manager
-	Database   *database.Manager
-	Cache      *cache.Manager
-	Auth       *auth.Manager
+	Config        *config.Config
+	ConfigManager *config.ConfigManager // 可选，用于在 /info 中展示配置来源
+	Logger        *logger.Manager
+	Database      *database.Manager
+	Cache         *cache.Manager
+	Auth          *auth.Manager
+	Migrator      *database.Migrator // 可选，提供迁移管理接口，结构未更新时就绪检查失败
+	Tracing       *tracing.Manager   // 可选，未设置且配置启用追踪时按 Config.Tracing 创建
+	Dependencies  []*degrade.DependencyGuard // 可选依赖的降级保护，状态在 /health 中展示，降级不影响就绪检查
+	Scheduler     *scheduler.Scheduler       // 可选，提供定时任务管理接口，关闭服务器时等待执行中的任务
+	Sessions      *cache.SessionManager      // 可选，页面登录使用的会话管理器，未设置且配置了缓存时使用默认管理器（前缀 session，24小时过期）
 }
 
 // New 创建新的HTTP服务器
@@ -49,9 +82,17 @@ func New(cfg *ServerConfig) (*Server, error) {
 		return nil, fmt.Errorf("config is required")
 	}
 	
-	// 设置Gin模式
+	// 启动前校验配置并设置Gin模式
+	if err := cfg.Config.Validate(); err != nil {
+		return nil, err
+	}
 	gin.SetMode(cfg.Config.Server.Mode)
 	
+	// 全局默认时区用于时间工具和展示格式化，已在配置校验中检查
+	if location, err := cfg.Config.Server.Location(); err == nil {
+		utils.SetDefaultLocation(location)
+	}
+	
 	// 创建Gin引擎
 	engine := gin.New()
 	
@@ -63,11 +104,64 @@ func New(cfg *ServerConfig) (*Server, error) {
 		db:     cfg.Database,
 		cache:  cfg.Cache,
 		auth:   cfg.Auth,
+		
+		configManager: cfg.ConfigManager,
+		realtime:      newRealtimeTracker(),
+		migrator:      cfg.Migrator,
+		dependencies:  cfg.Dependencies,
+		startedAt:     time.Now(),
+		urlSigner:     newURLSigner(cfg.Config),
+		scheduler:     cfg.Scheduler,
+		sessions:      cfg.Sessions,
+	}
+	
+	// 页面登录、SAML和OIDC授权确认使用Cookie会话
+	if server.sessions == nil && cfg.Cache != nil {
+		server.sessions = cache.NewSessionManager(cfg.Cache, "session", sessionCookieMaxAge*time.Second)
+	}
+	
+	// 关闭时停止调度并等待执行中的定时任务
+	if cfg.Scheduler != nil {
+		server.OnShutdown(cfg.Scheduler.Shutdown)
+	}
+	
+	// 启动安全检查
+	if err := server.runSecurityChecks(); err != nil {
+		return nil, err
+	}
+	
+	// 启用追踪时为HTTP请求、数据库和缓存埋点
+	if err := server.setupTracing(cfg.Tracing); err != nil {
+		return nil, err
+	}
+	
+	// 启用响应耗时头或调用计数时统计数据库和缓存的耗时分段
+	if err := server.setupTiming(); err != nil {
+		return nil, err
+	}
+	
+	// 认证服务与JWT中间件共用同一个认证管理器，签发的令牌可直接通过中间件校验
+	if cfg.Auth != nil {
+		server.authService = auth.NewAuthServiceWithManager(cfg.Auth)
 	}
 	
 	// 创建中间件管理器
 	if cfg.Auth != nil && cfg.Logger != nil {
 		server.middleware = middleware.NewMiddlewareManager(cfg.Auth, cfg.Logger)
+		
+		// 网关已完成认证时信任其注入的身份请求头
+		if trusted := cfg.Config.JWT.TrustedHeader; trusted.Enabled {
+			if err := server.middleware.SetTrustedHeader(&middleware.TrustedHeaderConfig{
+				UserHeader:      trusted.UserHeader,
+				UsernameHeader:  trusted.UsernameHeader,
+				EmailHeader:     trusted.EmailHeader,
+				RolesHeader:     trusted.RolesHeader,
+				TrustedProxies:  trusted.TrustedProxies,
+				ClientCertNames: trusted.ClientCertNames,
+			}); err != nil {
+				return nil, fmt.Errorf("failed to configure trusted header auth: %w", err)
+			}
+		}
 	}
 	
 	// 配置HTTP服务器
@@ -78,17 +172,70 @@ func New(cfg *ServerConfig) (*Server, error) {
 		WriteTimeout: time.Duration(cfg.Config.Server.WriteTimeout) * time.Second,
 	}
 	
+	// 启用HTTPS时加载服务器证书和客户端CA
+	tlsConfig, err := cfg.Config.Server.TLS.BuildServerTLS()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure server TLS: %w", err)
+	}
+	server.httpServer.TLSConfig = tlsConfig
+	
+	// 关闭时排空WebSocket、SSE等长连接
+	server.httpServer.RegisterOnShutdown(server.drainRealtime)
+	
 	// 设置默认中间件
 	server.setupDefaultMiddlewares()
 	
 	// 设置基础路由
 	server.setupBasicRoutes()
 	
+	// 按配置注册静态文件目录
+	server.setupStaticPaths()
+	
 	return server, nil
 }
 
 // setupDefaultMiddlewares 设置默认中间件
 func (s *Server) setupDefaultMiddlewares() {
+	// 追踪span需在关联ID之前开启，关联ID与追踪ID保持一致
+	if s.tracing != nil {
+		s.engine.Use(middleware.Tracing(s.tracing.Tracer()))
+	}
+	
+	// 响应耗时从尽量靠前的位置开始计时
+	if s.config.Server.ServerTiming {
+		s.engine.Use(middleware.ServerTiming())
+	}
+	if s.config.Server.CallCounts {
+		s.engine.Use(middleware.CallCount(&middleware.CallCountConfig{
+			Logger:           s.logger,
+			DBQueryThreshold: s.config.Server.DBQueryWarnThreshold,
+			CacheOpThreshold: s.config.Server.CacheOpWarnThreshold,
+		}))
+	}
+	
+	// 关联ID需在日志等中间件之前确定
+	s.engine.Use(middleware.Correlation(&middleware.CorrelationConfig{
+		Tracing:       s.config.Server.EnableTracing,
+		TrustIncoming: true,
+	}))
+	s.engine.Use(middleware.HTTPMetrics())
+	
+	// 状态页统计最近的请求和错误数
+	if s.config.Server.StatusPage {
+		s.status = newStatusTracker()
+		s.engine.Use(s.status.middleware())
+	}
+	
+	// 双向TLS时将已校验的客户端证书写入请求上下文
+	if tlsConfig := s.httpServer.TLSConfig; tlsConfig != nil && tlsConfig.ClientCAs != nil {
+		s.engine.Use(middleware.ClientCert())
+	}
+	
+	// 按请求头解析展示时区
+	if s.config.Server.TimezoneHeader != "" {
+		s.engine.Use(middleware.Timezone(&middleware.TimezoneConfig{Header: s.config.Server.TimezoneHeader}))
+	}
+	
 	// 如果有中间件管理器，使用它
 	if s.middleware != nil {
 		for _, mw := range s.middleware.Common() {
@@ -101,9 +248,23 @@ func (s *Server) setupDefaultMiddlewares() {
 			s.engine.Use(middleware.LoggerWithManager(s.logger))
 		}
 		if s.config.Server.EnableCORS {
-			s.engine.Use(middleware.CORS())
+			s.engine.Use(middleware.CORS(s.corsConfig()))
 		}
 	}
+	
+	// 从Cookie加载会话，处理结束后保存修改
+	if s.sessions != nil {
+		s.engine.Use(middleware.Session(&middleware.SessionConfig{
+			Manager: s.sessions,
+			MaxAge:  sessionCookieMaxAge,
+			Secure:  s.config.IsProduction(),
+		}))
+	}
+	
+	// 故障注入（仅非release模式）
+	if chaos := s.chaosConfig(); chaos != nil {
+		s.engine.Use(middleware.Chaos(chaos))
+	}
 }
 
 // setupBasicRoutes 设置基础路由
@@ -118,6 +279,21 @@ func (s *Server) setupBasicRoutes() {
 	
 	// 指标路由（如果需要）
 	s.engine.GET("/metrics", s.metricsHandler)
+	
+	// 文档路由：路由列表和根据路由元数据生成的OpenAPI文档，与Swagger共用开关
+	if s.config.Server.EnableSwagger {
+		s.engine.GET("/routes", s.routesHandler)
+		s.engine.GET("/openapi.json", s.openAPIHandler)
+	}
+	
+	// 状态页，未配置Basic认证账号也没有认证管理器时不注册，避免公开暴露
+	if s.config.Server.StatusPage {
+		if s.config.Server.StatusPageUser != "" || s.auth != nil {
+			s.engine.GET("/status", s.statusAuth(), s.statusHandler)
+		} else if s.logger != nil {
+			s.logger.Warn("Status page is enabled but neither basic auth credentials nor an auth manager is configured, /status is not registered")
+		}
+	}
 }
 
 // GetEngine 获取Gin引擎
@@ -145,16 +321,73 @@ func (s *Server) GetCache() *cache.Manager {
 	return s.cache
 }
 
+// DB 获取使用请求上下文的GORM数据库实例，客户端断开或请求超时后SQL随之取消；未配置数据库时返回 nil
+func (s *Server) DB(c *gin.Context) *gorm.DB {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.GetDB().WithContext(c.Request.Context())
+}
+
+// Cache 获取使用请求上下文的缓存管理器，未配置缓存时返回 nil
+func (s *Server) Cache(c *gin.Context) *cache.Manager {
+	if s.cache == nil {
+		return nil
+	}
+	return s.cache.WithContext(c.Request.Context())
+}
+
+// GetSessionManager 获取会话管理器，未配置缓存时返回 nil
+func (s *Server) GetSessionManager() *cache.SessionManager {
+	return s.sessions
+}
+
 // GetAuth 获取认证管理器
 func (s *Server) GetAuth() *auth.Manager {
 	return s.auth
 }
 
+// GetAuthService 获取认证服务（登录、注册、修改密码），未配置认证管理器时为nil
+func (s *Server) GetAuthService() *auth.AuthService {
+	return s.authService
+}
+
+// GetMigrator 获取数据库迁移器
+func (s *Server) GetMigrator() *database.Migrator {
+	return s.migrator
+}
+
+// GetScheduler 获取定时任务调度器
+func (s *Server) GetScheduler() *scheduler.Scheduler {
+	return s.scheduler
+}
+
 // GetMiddleware 获取中间件管理器
 func (s *Server) GetMiddleware() *middleware.MiddlewareManager {
 	return s.middleware
 }
 
+// RegisterDependency 注册可选依赖的降级保护，需在 Start 之前调用
+func (s *Server) RegisterDependency(guard *degrade.DependencyGuard) {
+	s.dependencies = append(s.dependencies, guard)
+}
+
+// GetDependency 按名称获取依赖降级保护，不存在时返回nil
+func (s *Server) GetDependency(name string) *degrade.DependencyGuard {
+	for _, guard := range s.dependencies {
+		if guard.Name() == name {
+			return guard
+		}
+	}
+	return nil
+}
+
+// OnShutdown 注册关闭钩子，在HTTP服务器停止接收请求后、关闭数据库和缓存连接前按注册顺序执行，
+// 用于停止订阅、排空后台任务等，钩子应在 ctx 结束前返回
+func (s *Server) OnShutdown(hook func(ctx context.Context) error) {
+	s.shutdownHooks = append(s.shutdownHooks, hook)
+}
+
 // Group 创建路由组
 func (s *Server) Group(relativePath string, handlers ...gin.HandlerFunc) *gin.RouterGroup {
 	return s.engine.Group(relativePath, handlers...)
@@ -235,11 +468,24 @@ func (s *Server) Start() error {
 	if s.logger != nil {
 		s.logger.Infof("Starting server on %s", s.httpServer.Addr)
 	}
+	if s.config.Server.VerifyMiddleware {
+		if err := s.VerifyMiddlewareChains(s.chainPolicy); err != nil {
+			return err
+		}
+	}
+	s.logStartupSummary()
+	s.startSessionJanitor()
 	
 	// 在goroutine中启动服务器
 	errChan := make(chan error, 1)
 	go func() {
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if s.httpServer.TLSConfig != nil {
+			err = s.httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = s.httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			errChan <- fmt.Errorf("failed to start server: %w", err)
 		}
 	}()
@@ -256,6 +502,37 @@ func (s *Server) Start() error {
 	}
 }
 
+// sessionJanitorInterval 清理过期会话和会话索引的间隔
+const sessionJanitorInterval = 5 * time.Minute
+
+// startSessionJanitor 定期清理过期会话、过期索引和没有活跃会话的用户，关闭服务器时停止
+func (s *Server) startSessionJanitor() {
+	if s.sessions == nil || s.stopJanitor != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopJanitor = cancel
+	s.OnShutdown(func(context.Context) error {
+		cancel()
+		return nil
+	})
+
+	go func() {
+		ticker := time.NewTicker(sessionJanitorInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.sessions.CleanExpiredSessions(); err != nil && s.logger != nil {
+					s.logger.Errorf("Failed to clean expired sessions: %v", err)
+				}
+			}
+		}
+	}()
+}
+
 // StartWithGracefulShutdown 启动服务器并支持优雅关闭
 func (s *Server) StartWithGracefulShutdown() error {
 	// 启动服务器
@@ -278,7 +555,11 @@ func (s *Server) StartWithGracefulShutdown() error {
 
 // Shutdown 关闭服务器
 func (s *Server) Shutdown() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	timeout := time.Duration(s.config.Server.ShutdownTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 	
 	if s.logger != nil {
@@ -293,6 +574,26 @@ func (s *Server) Shutdown() error {
 		return err
 	}
 	
+	// 等待被劫持的长连接（如WebSocket）处理器退出
+	s.waitRealtime(ctx)
+	
+	// 执行关闭钩子，此时数据库和缓存仍可用
+	for _, hook := range s.shutdownHooks {
+		if err := hook(ctx); err != nil && s.logger != nil {
+			s.logger.Errorf("Shutdown hook failed: %v", err)
+		}
+	}
+	
+	// 停止本服务器创建的限流器的清理协程
+	for _, limiter := range s.rateLimiters {
+		limiter.Stop()
+	}
+	
+	// 停止依赖恢复探测
+	for _, guard := range s.dependencies {
+		guard.Stop()
+	}
+	
 	// 关闭数据库连接
 	if s.db != nil {
 		if err := s.db.Close(); err != nil {
@@ -311,6 +612,15 @@ func (s *Server) Shutdown() error {
 		}
 	}
 	
+	// 导出剩余的追踪数据
+	if s.tracing != nil {
+		if err := s.tracing.Shutdown(ctx); err != nil {
+			if s.logger != nil {
+				s.logger.Errorf("Failed to shutdown tracing: %v", err)
+			}
+		}
+	}
+	
 	if s.logger != nil {
 		s.logger.Info("Server shutdown completed")
 	}
@@ -323,12 +633,12 @@ func (s *Server) healthHandler(c *gin.Context) {
 	status := gin.H{
 		"status":    "ok",
 		"timestamp": time.Now().Unix(),
-		"version":   "1.0.0", // 可以从配置中获取
+		"version":   Version,
 	}
 	
 	// 检查数据库健康状态
 	if s.db != nil {
-		if err := s.db.Health(); err != nil {
+		if err := s.db.WithContext(c.Request.Context()).Health(); err != nil {
 			status["database"] = "error"
 			status["database_error"] = err.Error()
 		} else {
@@ -338,7 +648,7 @@ func (s *Server) healthHandler(c *gin.Context) {
 	
 	// 检查缓存健康状态
 	if s.cache != nil {
-		if err := s.cache.Health(); err != nil {
+		if err := s.cache.WithContext(c.Request.Context()).Health(); err != nil {
 			status["cache"] = "error"
 			status["cache_error"] = err.Error()
 		} else {
@@ -346,6 +656,19 @@ func (s *Server) healthHandler(c *gin.Context) {
 		}
 	}
 	
+	// 可选依赖降级时服务仍可用，只标记为degraded
+	if len(s.dependencies) > 0 {
+		dependencies := gin.H{}
+		for _, guard := range s.dependencies {
+			dependency := guard.Status()
+			dependencies[dependency.Name] = dependency
+			if dependency.Degraded {
+				status["status"] = "degraded"
+			}
+		}
+		status["dependencies"] = dependencies
+	}
+	
 	// 如果有组件错误，返回503
 	if status["database"] == "error" || status["cache"] == "error" {
 		c.JSON(http.StatusServiceUnavailable, status)
@@ -374,7 +697,7 @@ func (s *Server) readinessHandler(c *gin.Context) {
 	
 	// 检查数据库
 	if s.db != nil {
-		if err := s.db.Health(); err != nil {
+		if err := s.db.WithContext(c.Request.Context()).Health(); err != nil {
 			ready = false
 			status["checks"].(gin.H)["database"] = gin.H{
 				"status": "not_ready",
@@ -389,7 +712,7 @@ func (s *Server) readinessHandler(c *gin.Context) {
 	
 	// 检查缓存
 	if s.cache != nil {
-		if err := s.cache.Health(); err != nil {
+		if err := s.cache.WithContext(c.Request.Context()).Health(); err != nil {
 			ready = false
 			status["checks"].(gin.H)["cache"] = gin.H{
 				"status": "not_ready",
@@ -402,6 +725,25 @@ func (s *Server) readinessHandler(c *gin.Context) {
 		}
 	}
 	
+	// 可选依赖降级不影响就绪
+	for _, guard := range s.dependencies {
+		dependency := guard.Status()
+		check := gin.H{"status": "ready"}
+		if dependency.Degraded {
+			check = gin.H{"status": "degraded", "error": dependency.LastError}
+		}
+		status["checks"].(gin.H)[dependency.Name] = check
+	}
+	
+	// 检查数据库结构是否为最新
+	if s.migrator != nil {
+		check := s.schemaReadiness()
+		if check["status"] != "ready" {
+			ready = false
+		}
+		status["checks"].(gin.H)["schema"] = check
+	}
+	
 	if !ready {
 		status["status"] = "not_ready"
 		c.JSON(http.StatusServiceUnavailable, status)
@@ -415,30 +757,60 @@ func (s *Server) readinessHandler(c *gin.Context) {
 func (s *Server) infoHandler(c *gin.Context) {
 	info := gin.H{
 		"name":        "hwhkit-go",
-		"version":     "1.0.0",
+		"version":     Version,
 		"environment": s.config.Server.Mode,
+		"timezone":    utils.DefaultLocation().String(),
 		"timestamp":   time.Now().Unix(),
-		"uptime":      time.Since(time.Now()).String(), // 这里应该用服务器启动时间
+		"uptime":      time.Since(s.startedAt).Round(time.Second).String(),
+	}
+	if s.configManager != nil {
+		info["config"] = s.configManager.Status()
 	}
 	
+
 	c.JSON(http.StatusOK, info)
 }
 
 // metrics handler
+// 支持 ?format=prometheus 以Prometheus文本格式输出指标，?format=openmetrics 或 Accept 为 OpenMetrics 时输出带 exemplar 的OpenMetrics格式
 func (s *Server) metricsHandler(c *gin.Context) {
-	metrics := gin.H{
+	if c.Query("format") == "openmetrics" || strings.Contains(c.GetHeader("Accept"), "application/openmetrics-text") {
+		c.Header("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		c.Status(http.StatusOK)
+		if err := metrics.Default.WriteOpenMetrics(c.Writer); err != nil && s.logger != nil {
+			s.logger.Errorf("Failed to write metrics: %v", err)
+		}
+		return
+	}
+	
+	if c.Query("format") == "prometheus" {
+		c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Status(http.StatusOK)
+		if err := metrics.Default.WritePrometheus(c.Writer); err != nil && s.logger != nil {
+			s.logger.Errorf("Failed to write metrics: %v", err)
+		}
+		return
+	}
+
+	result := gin.H{
 		"timestamp": time.Now().Unix(),
+		"metrics":   metrics.Default.Snapshot(),
 	}
 	
 	// 添加数据库统计
 	if s.db != nil {
-		metrics["database"] = s.db.GetStats()
+		result["database"] = s.db.GetStats()
 	}
 	
 	// 添加缓存统计
 	if s.cache != nil {
-		metrics["cache"] = s.cache.GetStats()
+		result["cache"] = s.cache.GetStats()
 	}
 	
-	c.JSON(http.StatusOK, metrics)
+	c.JSON(http.StatusOK, result)
+}
+
+// generateRequestID 生成请求ID
+func generateRequestID() string {
+	return middleware.NewCorrelationID()
 }
\ No newline at end of file
@@ -1,13 +1,49 @@
 package server
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/auth"
+	"github.com/hwh/hwhkit-go/pkg/auth/webauthn"
+	"github.com/hwh/hwhkit-go/pkg/cache"
 	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/hwh/hwhkit-go/pkg/degrade"
+	"github.com/hwh/hwhkit-go/pkg/logger"
+	"github.com/hwh/hwhkit-go/pkg/metrics"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+	"github.com/hwh/hwhkit-go/pkg/org"
+	"github.com/hwh/hwhkit-go/pkg/scheduler"
+	"github.com/hwh/hwhkit-go/pkg/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -328,4 +364,2670 @@ func TestRouteBuilder(t *testing.T) {
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err)
 	assert.Equal(t, "hello from builder", response["message"])
-}
\ No newline at end of file
+}
+func TestServerRoutesMetadata(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}},
+	})
+	require.NoError(t, err)
+
+	handler := func(c *gin.Context) { c.Status(http.StatusOK) }
+	NewRouterManager(server).RegisterRouteGroup(RouteGroup{
+		Path: "/api/v1",
+		Routes: []Route{
+			{Method: "GET", Path: "/items/:id", Handlers: []gin.HandlerFunc{handler}, Meta: &RouteMeta{AuthRequired: true}},
+			{Method: "POST", Path: "/items", Handlers: []gin.HandlerFunc{handler}},
+		},
+	})
+
+	var item, create *RouteInfo
+	routes := server.Routes()
+	for i := range routes {
+		switch routes[i].Method + " " + routes[i].Path {
+		case "GET /api/v1/items/:id":
+			item = &routes[i]
+		case "POST /api/v1/items":
+			create = &routes[i]
+		}
+	}
+
+	require.NotNil(t, item)
+	require.NotNil(t, create)
+	require.NotNil(t, item.Meta)
+	assert.True(t, item.Meta.AuthRequired)
+	assert.Nil(t, create.Meta)
+}
+
+func TestServerStartupSummary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{
+			Server:   config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode, EnableCORS: true},
+			Database: config.DatabaseConfig{Type: "mysql", Host: "db", Port: 3306, User: "root", Password: "secret", Name: "app"},
+		},
+	})
+	require.NoError(t, err)
+
+	summary := server.StartupSummary()
+	assert.Equal(t, "localhost:8080", summary["addr"])
+	assert.Equal(t, gin.TestMode, summary["mode"])
+	assert.Contains(t, summary["middlewares"], "middleware.CORS")
+	assert.NotContains(t, summary, "database")
+
+	subsystems := summary["subsystems"].(map[string]bool)
+	assert.False(t, subsystems["database"])
+	assert.True(t, subsystems["cors"])
+
+	assert.Equal(t, "root:***@", redactUserInfo("root", "secret"))
+	assert.Equal(t, ":***@", redactUserInfo("", "secret"))
+	assert.Equal(t, "", redactUserInfo("", ""))
+}
+
+func TestNewRefusesDebugInProduction(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.DebugMode, Environment: "production"},
+	}
+
+	_, err := New(&ServerConfig{Config: cfg})
+	assert.Error(t, err)
+
+	cfg.Server.AllowDebugInProduction = true
+	_, err = New(&ServerConfig{Config: cfg})
+	assert.NoError(t, err)
+	gin.SetMode(gin.TestMode)
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Host: "localhost", Port: 0, Mode: gin.ReleaseMode},
+	}
+
+	_, err := New(&ServerConfig{Config: cfg})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "port 0")
+	assert.Contains(t, err.Error(), "JWT secret")
+	gin.SetMode(gin.TestMode)
+}
+
+func TestRealtimeDrain(t *testing.T) {
+	tracker := newRealtimeTracker()
+
+	// 收到通知后自行结束的连接
+	graceful, err := tracker.track(RealtimeOptions{Kind: RealtimeSSE})
+	require.NoError(t, err)
+	go func() {
+		<-graceful.Draining()
+		graceful.Done()
+	}()
+
+	// 忽略通知的连接在宽限期后被强制关闭
+	var notified, closed bool
+	var stuck *RealtimeConn
+	stuck, err = tracker.track(RealtimeOptions{
+		Kind:   RealtimeWebSocket,
+		Notify: func() error { notified = true; return nil },
+		Close:  func() error { closed = true; stuck.Done(); return nil },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, tracker.count())
+
+	forced := tracker.drain(50 * time.Millisecond)
+	assert.Equal(t, 1, forced)
+	assert.True(t, notified)
+	assert.True(t, closed)
+	assert.True(t, tracker.wait(time.Second))
+	assert.Zero(t, tracker.count())
+
+	_, err = tracker.track(RealtimeOptions{Kind: RealtimeSSE})
+	assert.ErrorIs(t, err, ErrServerDraining)
+}
+
+func TestPaginatedSuccessLinks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{
+			Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode, PaginationLinks: true},
+		},
+	})
+	require.NoError(t, err)
+	server.GET("/items", func(c *gin.Context) {
+		server.PaginatedSuccess(c, []int{1, 2}, 45)
+	})
+
+	w := httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items?page=2&page_size=20&q=go", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	link := w.Header().Get("Link")
+	assert.Contains(t, link, `</items?page=1&page_size=20&q=go>; rel="first"`)
+	assert.Contains(t, link, `</items?page=1&page_size=20&q=go>; rel="prev"`)
+	assert.Contains(t, link, `</items?page=3&page_size=20&q=go>; rel="next"`)
+	assert.Contains(t, link, `</items?page=3&page_size=20&q=go>; rel="last"`)
+
+	var body PaginatedResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, int64(3), body.Pagination.TotalPages)
+	require.NotNil(t, body.Links)
+	assert.Equal(t, "/items?page=3&page_size=20&q=go", body.Links.Next)
+
+	links := newPaginationLinks(&url.URL{Path: "/items"}, 1, 10, 0)
+	assert.Empty(t, links.Prev)
+	assert.Empty(t, links.Next)
+	assert.Equal(t, links.First, links.Last)
+}
+
+func TestCorrelationID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{
+			Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode, EnableTracing: true},
+		},
+	})
+	require.NoError(t, err)
+	server.GET("/ping", func(c *gin.Context) {
+		server.Success(c, nil)
+	})
+
+	// 使用 traceparent 中的追踪ID
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, req)
+
+	var body Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, traceID, w.Header().Get("X-Trace-Id"))
+	assert.Equal(t, traceID, body.RequestID)
+
+	// 没有上游ID时生成新的关联ID
+	w = httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body.RequestID, 32)
+	assert.Equal(t, body.RequestID, w.Header().Get("X-Trace-Id"))
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{
+			Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode},
+		},
+	})
+	require.NoError(t, err)
+	var contextID string
+	server.GET("/ping", func(c *gin.Context) {
+		contextID = logger.RequestIDFromContext(c.Request.Context())
+		server.Success(c, nil)
+	})
+
+	// 沿用格式合法的 X-Request-ID，处理器的 context.Context 中可取得同一ID
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set("X-Request-ID", "client-req-1")
+	w := httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, req)
+
+	var body Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "client-req-1", body.RequestID)
+	assert.Equal(t, "client-req-1", w.Header().Get("X-Request-ID"))
+	assert.Equal(t, "client-req-1", contextID)
+
+	// 不合法的ID被替换
+	req = httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set("X-Request-ID", "bad id\nwith newline")
+	w = httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, req)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body.RequestID, 32)
+	assert.Equal(t, body.RequestID, contextID)
+}
+
+func TestRequestTimezone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{
+			Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode, Timezone: "Asia/Tokyo", TimezoneHeader: "X-Timezone"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", utils.DefaultLocation().String())
+
+	var location string
+	server.GET("/now", func(c *gin.Context) {
+		location = utils.LocationFromContext(c.Request.Context()).String()
+		server.Success(c, nil)
+	})
+
+	// 请求头指定的时区优先，无效时区回退到全局默认时区
+	for header, expected := range map[string]string{"America/New_York": "America/New_York", "": "Asia/Tokyo", "Not/AZone": "Asia/Tokyo"} {
+		req := httptest.NewRequest(http.MethodGet, "/now", nil)
+		req.Header.Set("X-Timezone", header)
+		w := httptest.NewRecorder()
+		server.GetEngine().ServeHTTP(w, req)
+		assert.Equal(t, expected, location)
+		assert.Equal(t, expected, w.Header().Get("X-Timezone"))
+	}
+}
+
+func TestCacheAdminRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	require.NoError(t, err)
+	defer cacheManager.Close()
+	require.NoError(t, cacheManager.Set("user:1", "alice", time.Minute))
+	require.NoError(t, cacheManager.Set("user:2", "bob", 0))
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{
+			Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode},
+		},
+		Cache: cacheManager,
+	})
+	require.NoError(t, err)
+	server.registerCacheAdminRoutes(server.Group("/admin"))
+
+	do := func(method, target string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		server.GetEngine().ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		var body struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		return w, body.Data
+	}
+
+	w, data := do(http.MethodGet, "/admin/cache/key?key=user:1")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice", data["value"])
+	assert.Equal(t, float64(60), data["ttl_seconds"])
+
+	w, _ = do(http.MethodGet, "/admin/cache/key?key=missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	_, data = do(http.MethodGet, "/admin/cache/keys?pattern=user:*")
+	assert.Equal(t, float64(2), data["count"])
+
+	_, data = do(http.MethodDelete, "/admin/cache/keys?pattern=user:*&dry_run=true")
+	assert.Equal(t, float64(2), data["matched"])
+	assert.Equal(t, float64(0), data["deleted"])
+
+	_, data = do(http.MethodDelete, "/admin/cache/namespaces/user")
+	assert.Equal(t, float64(2), data["deleted"])
+	assert.False(t, mr.Exists("app:user:1"))
+
+	w, data = do(http.MethodGet, "/admin/cache/stats")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, data, "hit_ratio")
+}
+
+func TestDependencyHealth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	guard := degrade.NewDependencyGuard(degrade.GuardConfig{Name: "search", FailureThreshold: 1, RecoveryInterval: time.Minute})
+	server, err := New(&ServerConfig{
+		Config:       &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}},
+		Dependencies: []*degrade.DependencyGuard{guard},
+	})
+	require.NoError(t, err)
+	assert.Same(t, guard, server.GetDependency("search"))
+	assert.Nil(t, server.GetDependency("mq"))
+
+	_ = guard.Do(func() error { return assert.AnError })
+
+	// 可选依赖降级时健康检查仍返回200，状态标记为degraded
+	w := httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var health struct {
+		Status       string                    `json:"status"`
+		Dependencies map[string]degrade.Status `json:"dependencies"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &health))
+	assert.Equal(t, "degraded", health.Status)
+	assert.True(t, health.Dependencies["search"].Degraded)
+
+	w = httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"search":{"error":"`)
+}
+
+func TestStatusPage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authManager := auth.New(&config.JWTConfig{Secret: "status-page-test-secret", ExpireHours: 1})
+	guard := degrade.NewDependencyGuard(degrade.GuardConfig{Name: "search"})
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{
+			Host: "localhost", Port: 8080, Mode: gin.TestMode,
+			StatusPage: true, StatusPageUser: "ops", StatusPagePassword: "secret",
+		}},
+		Auth:         authManager,
+		Dependencies: []*degrade.DependencyGuard{guard},
+	})
+	require.NoError(t, err)
+	server.GET("/boom", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.GetEngine().ServeHTTP(w, req)
+		return w
+	}
+	serve(httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	// 未认证或密码错误
+	w := serve(httptest.NewRequest(http.MethodGet, "/status", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.SetBasicAuth("ops", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, serve(req).Code)
+
+	// Basic认证返回HTML页面
+	req = httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.SetBasicAuth("ops", "secret")
+	w = serve(req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), "search")
+	assert.Contains(t, w.Body.String(), Version)
+
+	// 非管理员JWT被拒绝，管理员JWT可获取JSON数据
+	token, err := authManager.GenerateToken(1, "user", "", "user")
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodGet, "/status?format=json", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	assert.Equal(t, http.StatusUnauthorized, serve(req).Code)
+
+	token, err = authManager.GenerateToken(1, "admin", "", "admin")
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodGet, "/status?format=json", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = serve(req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data StatusReport `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "ok", response.Data.Status)
+	require.Len(t, response.Data.Checks, 1)
+	assert.Equal(t, "search", response.Data.Checks[0].Name)
+	require.Len(t, response.Data.Errors, 2)
+	assert.Equal(t, int64(1), response.Data.Errors[0].ServerErrors)
+	assert.Equal(t, int64(3), response.Data.Errors[0].ClientErrors)
+	assert.Equal(t, int64(5), response.Data.Errors[0].Requests)
+
+	// 未配置任何认证方式时不注册状态页
+	server, err = New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode, StatusPage: true}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, serve(httptest.NewRequest(http.MethodGet, "/status", nil)).Code)
+}
+
+func TestFieldEncryption(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}},
+	})
+	require.NoError(t, err)
+	keys := utils.NewKeyRing()
+	keys.SetKeySize(1024)
+	_, err = keys.Rotate()
+	require.NoError(t, err)
+
+	encrypt, err := server.SetupFieldEncryption(keys, &middleware.FieldEncryptionConfig{
+		RequestFields:  []string{"card.number"},
+		ResponseFields: []string{"data.card_number"},
+		Required:       true,
+	})
+	require.NoError(t, err)
+
+	// 同一密钥环可重复调用，公钥接口只注册一次；不同密钥环返回错误
+	_, err = server.SetupFieldEncryption(keys, &middleware.FieldEncryptionConfig{RequestFields: []string{"iban"}})
+	require.NoError(t, err)
+	_, err = server.SetupFieldEncryption(utils.NewKeyRing(), nil)
+	assert.Error(t, err)
+	server.POST("/cards", encrypt, func(c *gin.Context) {
+		var req struct {
+			Name string `json:"name"`
+			Card struct {
+				Number string `json:"number"`
+			} `json:"card"`
+		}
+		if !server.BindRequest(c, &req) {
+			return
+		}
+		server.Success(c, gin.H{"name": req.Name, "card_number": req.Card.Number})
+	})
+
+	// 客户端获取公钥
+	w := httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/encryption-keys", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var published struct {
+		Keys []utils.PublicKeyInfo `json:"keys"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &published))
+	require.Len(t, published.Keys, 1)
+	publicKey, err := published.Keys[0].RSAPublicKey()
+	require.NoError(t, err)
+
+	fieldKey, err := utils.GenerateFieldKey()
+	require.NoError(t, err)
+	wrapped, err := utils.WrapKey(publicKey, published.Keys[0].KeyID, fieldKey)
+	require.NoError(t, err)
+	number, err := utils.EncryptField(fieldKey, "card.number", []byte(`"6222021234567890"`))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/cards", strings.NewReader(`{"name":"alice","card":{"number":"`+number+`"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.EncryptedKeyHeader, wrapped)
+	w = httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "6222021234567890")
+
+	var response struct {
+		Data struct {
+			Name       string `json:"name"`
+			CardNumber string `json:"card_number"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "alice", response.Data.Name)
+	plaintext, err := utils.DecryptField(fieldKey, "data.card_number", response.Data.CardNumber)
+	require.NoError(t, err)
+	assert.Equal(t, `"6222021234567890"`, string(plaintext))
+
+	// 为其他字段加密的密文不能放到 card.number 中
+	swapped, err := utils.EncryptField(fieldKey, "name", []byte(`"6222021234567890"`))
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodPost, "/cards", strings.NewReader(`{"name":"alice","card":{"number":"`+swapped+`"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.EncryptedKeyHeader, wrapped)
+	w = httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 缺少密钥头或字段未加密时拒绝
+	req = httptest.NewRequest(http.MethodPost, "/cards", strings.NewReader(`{"card":{"number":"6222021234567890"}}`))
+	w = httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	req.Header.Set(middleware.EncryptedKeyHeader, wrapped)
+	req.Body = io.NopCloser(strings.NewReader(`{"card":{"number":6222021234567890}}`))
+	w = httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSchemaReadinessCache(t *testing.T) {
+	var cache schemaReadinessCache
+	calls := 0
+	status := "not_ready"
+	check := func() gin.H {
+		calls++
+		return gin.H{"status": status}
+	}
+
+	// 未就绪时在重新检查间隔内复用结果
+	assert.Equal(t, "not_ready", cache.get(check)["status"])
+	assert.Equal(t, "not_ready", cache.get(check)["status"])
+	assert.Equal(t, 1, calls)
+
+	// 超过间隔后重新检查，就绪后不再检查
+	status = "ready"
+	cache.checkedAt = time.Now().Add(-schemaRecheckInterval)
+	assert.Equal(t, "ready", cache.get(check)["status"])
+	cache.checkedAt = time.Now().Add(-time.Hour)
+	assert.Equal(t, "ready", cache.get(check)["status"])
+	assert.Equal(t, 2, calls)
+
+	// 执行迁移后清除缓存
+	cache.invalidate()
+	cache.get(check)
+	assert.Equal(t, 3, calls)
+}
+
+func TestShutdownHooks(t *testing.T) {
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}},
+	})
+	require.NoError(t, err)
+
+	var calls []string
+	server.OnShutdown(func(ctx context.Context) error {
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline)
+		calls = append(calls, "pubsub")
+		return nil
+	})
+	server.OnShutdown(func(context.Context) error {
+		calls = append(calls, "queue")
+		return assert.AnError
+	})
+	require.NoError(t, server.Shutdown())
+	assert.Equal(t, []string{"pubsub", "queue"}, calls)
+}
+
+// rateLimitJanitors 统计正在运行的限流清理协程数
+func rateLimitJanitors() int {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	return strings.Count(string(buf), "middleware.(*rateLimiter).runJanitor")
+}
+
+func TestShutdownStopsOwnRateLimiters(t *testing.T) {
+	public := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(public, "app.js"), []byte("console.log(1)"), 0o644))
+	newServer := func() *Server {
+		server, err := New(&ServerConfig{
+			Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}},
+		})
+		require.NoError(t, err)
+		server.StaticWithOptions("/public", public, StaticOptions{RateLimit: 1})
+		return server
+	}
+
+	before := rateLimitJanitors()
+	first, second := newServer(), newServer()
+	require.Eventually(t, func() bool { return rateLimitJanitors() == before+2 }, time.Second, 10*time.Millisecond)
+
+	// 关闭一个服务器只停止它自己的限流器，另一个服务器的清理协程和限流继续工作
+	require.NoError(t, first.Shutdown())
+	assert.Eventually(t, func() bool { return rateLimitJanitors() == before+1 }, time.Second, 10*time.Millisecond)
+
+	do := func() int {
+		w := httptest.NewRecorder()
+		second.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public/app.js", nil))
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, do())
+	assert.Equal(t, http.StatusTooManyRequests, do())
+
+	require.NoError(t, second.Shutdown())
+	assert.Eventually(t, func() bool { return rateLimitJanitors() == before }, time.Second, 10*time.Millisecond)
+}
+
+func TestStaticSigned(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "report.txt"), []byte("quarterly report"), 0o644))
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{
+			Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode},
+			JWT:    config.JWTConfig{Secret: "signed-url-test-secret"},
+		},
+	})
+	require.NoError(t, err)
+	server.StaticSigned("/downloads", root)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	link, err := server.SignURL("/downloads/report.txt", time.Minute, "")
+	require.NoError(t, err)
+	w := get(link)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "quarterly report", w.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, get("/downloads/report.txt").Code)
+	assert.Equal(t, http.StatusForbidden, get(strings.Replace(link, "report.txt", "other.txt", 1)).Code)
+
+	expired, err := server.SignURL("/downloads/report.txt", -time.Second, "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusGone, get(expired).Code)
+}
+
+func TestStaticSignedUserBound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "invoice.pdf"), []byte("invoice"), 0o644))
+
+	jwtConfig := config.JWTConfig{Secret: "signed-url-test-secret", ExpireHours: 1, RefreshHours: 24, Issuer: "test"}
+	authManager := auth.New(&jwtConfig)
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}, JWT: jwtConfig},
+		Auth:   authManager,
+	})
+	require.NoError(t, err)
+	server.StaticSigned("/downloads", root)
+	server.GetEngine().GET("/stream/*path", server.SignedURL(), func(c *gin.Context) {
+		c.String(http.StatusOK, "stream")
+	})
+
+	alice, err := authManager.GenerateTokenPairForUser(&auth.User{ID: "42", Username: "alice", Roles: []string{"user"}}, "")
+	require.NoError(t, err)
+	mallory, err := authManager.GenerateTokenPairForUser(&auth.User{ID: "7", Username: "mallory", Roles: []string{"user"}}, "")
+	require.NoError(t, err)
+
+	get := func(target, token string) int {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		server.GetEngine().ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 绑定用户的链接只对该用户有效，转发给其他用户或未登录访问均被拒绝
+	link, err := server.SignURL("/downloads/invoice.pdf", time.Minute, "42")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, get(link, alice.AccessToken))
+	assert.Equal(t, http.StatusForbidden, get(link, mallory.AccessToken))
+	assert.Equal(t, http.StatusForbidden, get(link, ""))
+
+	// 自定义路由没有JWT中间件时，绑定用户的链接一律拒绝
+	stream, err := server.SignURL("/stream/video.mp4", time.Minute, "42")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, get(stream, alice.AccessToken))
+	open, err := server.SignURL("/stream/video.mp4", time.Minute, "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, get(open, ""))
+}
+
+func TestRunSelfTest(t *testing.T) {
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(config.Config{})
+	}))
+	defer remote.Close()
+
+	// 最简SMTP服务器：问候、EHLO、QUIT
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 localhost ESMTP\r\n")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if strings.HasPrefix(line, "QUIT") {
+				fmt.Fprint(conn, "221 bye\r\n")
+				return
+			}
+			fmt.Fprint(conn, "250 localhost\r\n")
+		}
+	}()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode},
+		Redis:  config.RedisConfig{Host: mr.Host(), Port: port},
+		JWT:    config.JWTConfig{Secret: strings.Repeat("s", 32), ExpireHours: 1, Issuer: "selftest"},
+	}
+	report := RunSelfTest(context.Background(), &SelfTestConfig{
+		Config:        cfg,
+		ConfigManager: config.NewWithSnapshot(remote.URL, filepath.Join(t.TempDir(), "config-snapshot.json")),
+		SMTPAddr:      listener.Addr().String(),
+		Skip:          []string{SelfTestCheckDatabase},
+	})
+	statuses := map[string]string{}
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	assert.True(t, report.Passed, "%+v", report.Checks)
+	assert.Equal(t, 0, report.ExitCode())
+	assert.Equal(t, map[string]string{
+		SelfTestCheckConfig:       SelfTestPassed,
+		SelfTestCheckJWT:          SelfTestPassed,
+		SelfTestCheckDatabase:     SelfTestSkipped,
+		SelfTestCheckMigrations:   SelfTestSkipped,
+		SelfTestCheckRedis:        SelfTestPassed,
+		SelfTestCheckRemoteConfig: SelfTestPassed,
+		SelfTestCheckSMTP:         SelfTestPassed,
+	}, statuses)
+	for _, check := range report.Checks {
+		if check.Name == SelfTestCheckMigrations {
+			assert.Equal(t, "SelfTestConfig.Migrations is not set", check.Detail)
+		}
+	}
+
+	// 默认JWT密钥和不可达的Redis导致自检失败
+	mr.Close()
+	cfg.JWT.Secret = config.DefaultJWTSecret
+	report = RunSelfTest(context.Background(), &SelfTestConfig{Config: cfg, Skip: []string{SelfTestCheckDatabase}, Timeout: 2 * time.Second})
+	assert.False(t, report.Passed)
+	assert.Equal(t, 1, report.ExitCode())
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf))
+	assert.Contains(t, buf.String(), `"name": "jwt",
+      "status": "failed"`)
+	assert.Contains(t, buf.String(), `"name": "redis",
+      "status": "failed"`)
+
+	assert.True(t, IsSelfTestMode([]string{"app", "selftest"}))
+	t.Setenv("SELFTEST", "true")
+	assert.True(t, IsSelfTestMode([]string{"app"}))
+}
+
+func TestSchedulerAdminRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sched := scheduler.New()
+	ran := make(chan struct{}, 1)
+	require.NoError(t, sched.Register("cleanup", "0 3 * * *", func(ctx context.Context) error {
+		ran <- struct{}{}
+		return nil
+	}))
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{
+			Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode},
+		},
+		Scheduler: sched,
+	})
+	require.NoError(t, err)
+	server.registerSchedulerAdminRoutes(server.Group("/admin"))
+
+	w := httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/scheduler/jobs/cleanup/run", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	<-ran
+
+	w = httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/scheduler/jobs/missing/run", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/scheduler/jobs", nil))
+		var body struct {
+			Data struct {
+				Jobs  []scheduler.JobStatus `json:"jobs"`
+				Count int                   `json:"count"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body.Data.Count == 1 && body.Data.Jobs[0].LastResult == scheduler.ResultSucceeded
+	}, time.Second, 10*time.Millisecond)
+
+	// 关闭服务器时停止调度器
+	require.NoError(t, server.Shutdown())
+	assert.ErrorIs(t, sched.Start(), scheduler.ErrSchedulerClosed)
+}
+
+func TestDevRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	require.NoError(t, err)
+	defer cacheManager.Close()
+
+	newServer := func(serverConfig config.ServerConfig) *Server {
+		serverConfig.Host, serverConfig.Port = "localhost", 8080
+		logManager, err := logger.New(&config.LogConfig{Level: "error", Format: "json", Output: "console"})
+		require.NoError(t, err)
+		server, err := New(&ServerConfig{
+			Config: &config.Config{Server: serverConfig, JWT: config.JWTConfig{Secret: "test-secret", ExpireHours: 1, RefreshHours: 24, Issuer: "test"}},
+			Logger: logManager,
+			Cache:  cacheManager,
+			Auth:   auth.New(&config.JWTConfig{Secret: "test-secret", ExpireHours: 1, RefreshHours: 24, Issuer: "test"}),
+		})
+		require.NoError(t, err)
+		return server
+	}
+
+	// 未开启时不注册
+	server := newServer(config.ServerConfig{Mode: gin.TestMode})
+	assert.False(t, server.EnableDevRoutes())
+	w := httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/demo/utils", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// release模式下禁止开启
+	_, err = New(&ServerConfig{Config: &config.Config{
+		Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.ReleaseMode, DevRoutes: true},
+		JWT:    config.JWTConfig{Secret: "test-secret"},
+	}})
+	assert.ErrorContains(t, err, "dev routes")
+
+	server = newServer(config.ServerConfig{Mode: gin.TestMode, DevRoutes: true})
+	require.True(t, server.EnableDevRoutes())
+
+	do := func(method, target, token string, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		server.GetEngine().ServeHTTP(w, req)
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp.Data
+	}
+
+	// 调用方指定的用户ID和角色被忽略
+	w, data := do(http.MethodPost, "/demo/auth/token", "", `{"user_id":"1","username":"alice","roles":["admin"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	token := data["tokens"].(map[string]interface{})["access_token"].(string)
+
+	w, data = do(http.MethodGet, "/demo/protected/profile", token, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice", data["username"])
+	assert.Equal(t, "123", data["id"])
+	assert.Equal(t, []interface{}{"user"}, data["roles"])
+
+	_, data = do(http.MethodGet, "/demo/rbac/check?resource=user&action=read", token, "")
+	assert.Equal(t, true, data["allowed"])
+	w, _ = do(http.MethodGet, "/demo/rbac/protected", token, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w, _ = do(http.MethodPut, "/demo/cache/greeting", "", `{"value":"hello","ttl_seconds":60}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, mr.Exists("app:demo:greeting"))
+	_, data = do(http.MethodGet, "/demo/cache/greeting", "", "")
+	assert.Equal(t, "hello", data["value"])
+	assert.Equal(t, "cache", data["from"])
+	_, data = do(http.MethodGet, "/demo/cache/missing", "", "")
+	assert.Equal(t, "generated", data["from"])
+	w, _ = do(http.MethodDelete, "/demo/cache/greeting", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, mr.Exists("app:demo:greeting"))
+}
+
+func TestServerTiming(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	require.NoError(t, err)
+	defer cacheManager.Close()
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode, ServerTiming: true}},
+		Cache:  cacheManager,
+	})
+	require.NoError(t, err)
+
+	server.GetEngine().GET("/timed", func(c *gin.Context) {
+		scoped := cacheManager.WithContext(c.Request.Context())
+		require.NoError(t, scoped.Set("timed", "value", time.Minute))
+		_, _ = scoped.Get("timed")
+		stop := middleware.TrackTiming(c, "render")
+		stop()
+		server.Success(c, gin.H{"ok": true})
+	})
+	server.GetEngine().DELETE("/timed", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/timed", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	timing := w.Header().Get("Server-Timing")
+	assert.Contains(t, timing, `cache;dur=`)
+	assert.Contains(t, timing, `desc="2 ops"`)
+	assert.Contains(t, timing, `render;dur=`)
+	assert.Contains(t, timing, `handler;dur=`)
+	assert.Regexp(t, `^\d+\.\d{2}ms$`, w.Header().Get("X-Response-Time"))
+
+	// 没有响应体时同样输出
+	w = httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/timed", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Server-Timing"), "handler;dur="))
+	assert.NotEmpty(t, w.Header().Get("X-Response-Time"))
+
+	// 未开启时不输出
+	plain, err := New(&ServerConfig{Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}}})
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	plain.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	assert.Empty(t, w.Header().Get("Server-Timing"))
+}
+
+func TestSessionLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	require.NoError(t, err)
+	defer cacheManager.Close()
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}},
+		Cache:  cacheManager,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, server.GetSessionManager())
+
+	engine := server.GetEngine()
+	engine.POST("/forms/login", middleware.BodyLimit(1<<20), middleware.SameOrigin(), server.handleLoginForm)
+	engine.POST("/forms/logout", server.handleLogoutForm)
+	engine.GET("/whoami", func(c *gin.Context) {
+		c.String(http.StatusOK, server.getUserFromSession(c))
+	})
+	engine.POST("/visits", func(c *gin.Context) {
+		session, err := middleware.EnsureSession(c)
+		require.NoError(t, err)
+		session.Set("visits", session.GetInt("visits", 0)+1)
+		c.String(http.StatusOK, strconv.Itoa(session.GetInt("visits", 0)))
+	})
+
+	do := func(method, target, sessionID string, form url.Values) *httptest.ResponseRecorder {
+		var body io.Reader
+		if form != nil {
+			body = strings.NewReader(form.Encode())
+		}
+		req := httptest.NewRequest(method, target, body)
+		if form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if sessionID != "" {
+			req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	sessionCookie := func(w *httptest.ResponseRecorder) *http.Cookie {
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == "session_id" {
+				return cookie
+			}
+		}
+		return nil
+	}
+
+	// 匿名会话按需创建，修改的数据在请求结束后保存
+	w := do(http.MethodPost, "/visits", "", nil)
+	require.Equal(t, "1", w.Body.String())
+	anonymous := sessionCookie(w)
+	require.NotNil(t, anonymous)
+	assert.True(t, anonymous.HttpOnly)
+	assert.Equal(t, sessionCookieMaxAge, anonymous.MaxAge)
+	assert.Equal(t, "2", do(http.MethodPost, "/visits", anonymous.Value, nil).Body.String())
+	assert.Empty(t, do(http.MethodGet, "/whoami", anonymous.Value, nil).Body.String())
+
+	// 登录失败不创建会话
+	w = do(http.MethodPost, "/forms/login", "", url.Values{"username": {"admin"}, "password": {"wrong"}})
+	assert.Nil(t, sessionCookie(w))
+
+	// 跨站提交的登录表单被拒绝（登录CSRF），超过大小上限的表单不会被解析
+	req := httptest.NewRequest(http.MethodPost, "/forms/login", strings.NewReader(url.Values{"username": {"admin"}, "password": {"admin123"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "https://evil.example")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Nil(t, sessionCookie(w))
+	w = do(http.MethodPost, "/forms/login", "", url.Values{"username": {"admin"}, "password": {"admin123"}, "padding": {strings.Repeat("x", 1<<20)}})
+	assert.Equal(t, "/login?error=invalid_credentials", w.Header().Get("Location"))
+	assert.Nil(t, sessionCookie(w))
+
+	// 登录后更换会话ID，保留会话数据，登录前的会话ID失效
+	w = do(http.MethodPost, "/forms/login", anonymous.Value, url.Values{"username": {"admin"}, "password": {"admin123"}})
+	require.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/dashboard", w.Header().Get("Location"))
+	loggedIn := sessionCookie(w)
+	require.NotNil(t, loggedIn)
+	assert.NotEqual(t, anonymous.Value, loggedIn.Value)
+	assert.Empty(t, do(http.MethodGet, "/whoami", anonymous.Value, nil).Body.String())
+	assert.Equal(t, "admin", do(http.MethodGet, "/whoami", loggedIn.Value, nil).Body.String())
+	assert.Equal(t, "3", do(http.MethodPost, "/visits", loggedIn.Value, nil).Body.String())
+
+	// 登出删除会话并清除Cookie
+	w = do(http.MethodPost, "/forms/logout", loggedIn.Value, nil)
+	require.Equal(t, http.StatusFound, w.Code)
+	require.NotNil(t, sessionCookie(w))
+	assert.Equal(t, -1, sessionCookie(w).MaxAge)
+	assert.False(t, server.GetSessionManager().IsValidSession(loggedIn.Value))
+	assert.Empty(t, do(http.MethodGet, "/whoami", loggedIn.Value, nil).Body.String())
+}
+
+func TestOIDCAuthorizationCodeFlow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	require.NoError(t, err)
+	defer cacheManager.Close()
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}},
+		Cache:  cacheManager,
+	})
+	require.NoError(t, err)
+
+	provider, err := auth.NewOIDCProvider(auth.OIDCProviderConfig{
+		Issuer: "https://id.example.com",
+		UserProvider: func(userID string) (*auth.User, error) {
+			if userID != "admin" {
+				return nil, errors.New("not found")
+			}
+			return &auth.User{ID: "admin", Username: "admin", Email: "admin@example.com"}, nil
+		},
+	})
+	require.NoError(t, err)
+	client, secret, err := provider.RegisterClient("wiki", []string{"https://wiki.example.com/callback"}, nil, nil)
+	require.NoError(t, err)
+
+	engine := server.GetEngine()
+	engine.SetHTMLTemplate(template.Must(template.New("oidc/consent.html").Parse(`{{.consent_token}}`)))
+	engine.POST("/forms/login", middleware.BodyLimit(1<<20), middleware.SameOrigin(), server.handleLoginForm)
+	server.SetupOIDCRoutes(provider)
+
+	do := func(method, target string, form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		var body io.Reader
+		if form != nil {
+			body = strings.NewReader(form.Encode())
+		}
+		req := httptest.NewRequest(method, target, body)
+		if form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	cookie := func(w *httptest.ResponseRecorder, name string) *http.Cookie {
+		for _, c := range w.Result().Cookies() {
+			if c.Name == name {
+				return c
+			}
+		}
+		return nil
+	}
+
+	authorizeParams := url.Values{
+		"client_id":             {client.ID},
+		"redirect_uri":          {"https://wiki.example.com/callback"},
+		"response_type":         {"code"},
+		"scope":                 {"openid profile email"},
+		"state":                 {"xyz"},
+		"code_challenge":        {"a-long-random-code-verifier-for-pkce-tests"},
+		"code_challenge_method": {"plain"},
+	}
+	authorizeURL := "/oauth/authorize?" + authorizeParams.Encode()
+
+	// 未登录时跳转到登录页，next 为授权地址
+	w := do(http.MethodGet, authorizeURL, nil)
+	require.Equal(t, http.StatusFound, w.Code)
+	loginURL, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "/login", loginURL.Path)
+	next := loginURL.Query().Get("next")
+	assert.Equal(t, authorizeURL, next)
+
+	// 登录失败时保留 next，成功后跳回授权地址
+	w = do(http.MethodPost, "/forms/login", url.Values{"username": {"admin"}, "password": {"wrong"}, "next": {next}})
+	assert.Equal(t, "/login?error=invalid_credentials&next="+url.QueryEscape(next), w.Header().Get("Location"))
+	w = do(http.MethodPost, "/forms/login", url.Values{"username": {"admin"}, "password": {"admin123"}, "next": {next}})
+	require.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, next, w.Header().Get("Location"))
+	session := cookie(w, "session_id")
+	require.NotNil(t, session)
+
+	// 站外地址不作为登录后的跳转地址
+	for _, target := range []string{"https://evil.example/", "//evil.example/", "/\\evil.example/"} {
+		w = do(http.MethodPost, "/forms/login", url.Values{"username": {"admin"}, "password": {"admin123"}, "next": {target}})
+		assert.Equal(t, "/dashboard", w.Header().Get("Location"), target)
+	}
+
+	// 授权确认页
+	w = do(http.MethodGet, next, nil, session)
+	require.Equal(t, http.StatusOK, w.Code)
+	consentToken := w.Body.String()
+	consentCookie := cookie(w, oidcConsentCookie)
+	require.NotNil(t, consentCookie)
+	assert.Equal(t, consentToken, consentCookie.Value)
+
+	form := url.Values{"consent_token": {consentToken}, "decision": {"approve"}}
+	for key, values := range authorizeParams {
+		form[key] = values
+	}
+	w = do(http.MethodPost, "/oauth/authorize", form, session, consentCookie)
+	require.Equal(t, http.StatusFound, w.Code)
+	callback, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "wiki.example.com", callback.Host)
+	assert.Equal(t, "xyz", callback.Query().Get("state"))
+	code := callback.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	// 换取令牌，访问令牌可用于用户信息端点，ID令牌不能
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(url.Values{
+		"grant_type":    {auth.GrantTypeAuthorizationCode},
+		"code":          {code},
+		"redirect_uri":  {"https://wiki.example.com/callback"},
+		"code_verifier": {"a-long-random-code-verifier-for-pkce-tests"},
+	}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(client.ID, secret)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var tokens auth.OIDCTokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	require.NotEmpty(t, tokens.AccessToken)
+	require.NotEmpty(t, tokens.IDToken)
+
+	userinfo := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/oauth/userinfo", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	w = userinfo(tokens.AccessToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"sub":"admin"`)
+	assert.Equal(t, http.StatusUnauthorized, userinfo(tokens.IDToken).Code)
+}
+
+func TestSAMLLoginBindsLocalUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	require.NoError(t, err)
+	defer cacheManager.Close()
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}},
+		Cache:  cacheManager,
+	})
+	require.NoError(t, err)
+
+	// 登录钩子是必需的
+	assert.Error(t, server.SetupSAMLRoutes(nil, nil))
+
+	// 已关联的外部身份映射到本地用户，IdP声明的用户名与本地账号相同也不会登录该账号
+	linked := map[string]string{auth.SAMLIdentityPrefix + "alice@corp": "17"}
+	var hookUser *auth.User
+	h := &samlHandler{server: server, onLogin: func(c *gin.Context, user *auth.User) (string, error) {
+		hookUser = user
+		return linked[user.ID], nil
+	}}
+	engine := server.GetEngine()
+	engine.POST("/test/saml/:subject", func(c *gin.Context) {
+		h.completeLogin(c, &auth.User{
+			ID:       auth.SAMLIdentityPrefix + c.Param("subject"),
+			Username: "admin",
+			Roles:    []string{"user"},
+		}, "/profile")
+	})
+	engine.GET("/whoami", func(c *gin.Context) {
+		c.String(http.StatusOK, server.getUserFromSession(c))
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/test/saml/alice@corp", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/profile", w.Header().Get("Location"))
+	require.NotNil(t, hookUser)
+	assert.Equal(t, []string{"user"}, hookUser.Roles)
+	var session *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "session_id" {
+			session = cookie
+		}
+	}
+	require.NotNil(t, session)
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.AddCookie(session)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, "17", w.Body.String())
+
+	// 钩子没有解析出本地用户时拒绝登录
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/test/saml/mallory@corp", nil))
+	assert.Equal(t, "/login?error=saml_denied", w.Header().Get("Location"))
+	for _, cookie := range w.Result().Cookies() {
+		assert.NotEqual(t, "session_id", cookie.Name)
+	}
+}
+
+func TestSessionDeviceBindingStrict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	require.NoError(t, err)
+	defer cacheManager.Close()
+	sessions := cache.NewSessionManager(cacheManager, "session", time.Hour)
+
+	for _, autoCreate := range []bool{false, true} {
+		engine := gin.New()
+		engine.Use(middleware.Session(&middleware.SessionConfig{
+			Manager:       sessions,
+			AutoCreate:    autoCreate,
+			DeviceBinding: auth.DeviceBindingStrict,
+		}))
+		engine.POST("/visits", func(c *gin.Context) {
+			session, err := middleware.EnsureSession(c)
+			require.NoError(t, err)
+			session.Set("visits", session.GetInt("visits", 0)+1)
+			c.String(http.StatusOK, strconv.Itoa(session.GetInt("visits", 0)))
+		})
+
+		do := func(sessionID, device string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/visits", nil)
+			req.Header.Set(auth.DeviceIDHeader, device)
+			if sessionID != "" {
+				req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			return w
+		}
+
+		// 匿名会话创建时即绑定设备，严格模式下同一设备可以继续使用
+		w := do("", "laptop")
+		require.Equal(t, "1", w.Body.String())
+		var sessionID string
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == "session_id" {
+				sessionID = cookie.Value
+			}
+		}
+		require.NotEmpty(t, sessionID)
+		assert.Equal(t, "2", do(sessionID, "laptop").Body.String(), "autoCreate=%v", autoCreate)
+
+		// 其他设备使用该会话ID时视为没有会话
+		assert.Equal(t, "1", do(sessionID, "phone").Body.String(), "autoCreate=%v", autoCreate)
+		assert.Equal(t, "3", do(sessionID, "laptop").Body.String(), "autoCreate=%v", autoCreate)
+	}
+}
+
+func TestCallCounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	require.NoError(t, err)
+	defer cacheManager.Close()
+
+	logManager, err := logger.New(&config.LogConfig{Level: "warn", Format: "json", Output: "console"})
+	require.NoError(t, err)
+	var logs bytes.Buffer
+	logManager.GetLogger().SetOutput(&logs)
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{
+			Host: "localhost", Port: 8080, Mode: gin.TestMode,
+			CallCounts: true, CacheOpWarnThreshold: 2,
+		}},
+		Logger: logManager,
+		Cache:  cacheManager,
+	})
+	require.NoError(t, err)
+
+	server.GetEngine().GET("/counted/:n", func(c *gin.Context) {
+		scoped := cacheManager.WithContext(c.Request.Context())
+		n, _ := strconv.Atoi(c.Param("n"))
+		for i := 0; i < n; i++ {
+			_, _ = scoped.Get(fmt.Sprintf("item:%d", i))
+		}
+		server.Success(c, gin.H{"ok": true})
+	})
+
+	cacheOps := metrics.Default.Histogram("hwhkit_http_request_cache_ops", "", nil, "method", "route")
+	before := cacheOps.Snapshot(http.MethodGet, "/counted/:n")
+
+	w := httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/counted/2", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, logs.String(), "call count threshold")
+	// 未开启 ServerTiming 时不输出响应头
+	assert.Empty(t, w.Header().Get("Server-Timing"))
+
+	w = httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/counted/3", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, logs.String(), "call count threshold")
+	assert.Contains(t, logs.String(), `"cache_ops":3`)
+
+	after := cacheOps.Snapshot(http.MethodGet, "/counted/:n")
+	assert.Equal(t, uint64(2), after.Count-before.Count)
+	assert.Equal(t, float64(5), after.Sum-before.Sum)
+}
+
+func TestStaticPaths(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	public, private := t.TempDir(), t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(public, "assets"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(public, "assets", "app.js"), []byte("console.log(1)"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(private, "report.txt"), []byte("quarterly report"), 0o644))
+
+	jwtConfig := config.JWTConfig{Secret: "static-test-secret", ExpireHours: 1, RefreshHours: 24, Issuer: "test"}
+	logManager, err := logger.New(&config.LogConfig{Level: "error", Format: "json", Output: "console"})
+	require.NoError(t, err)
+	authManager := auth.New(&jwtConfig)
+	server, err := New(&ServerConfig{
+		Config: &config.Config{
+			Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode, StaticPaths: []config.StaticPathConfig{
+				{Path: "/public", Root: public, CacheMaxAge: 3600, CORSOrigins: []string{"https://app.example.com"}},
+				{Path: "/files", Root: private, CacheMaxAge: 60, RequireAuth: true},
+			}},
+			JWT: jwtConfig,
+		},
+		Logger: logManager,
+		Auth:   authManager,
+	})
+	require.NoError(t, err)
+	server.StaticWithOptions("/browse", public, StaticOptions{Listing: true, CacheMaxAge: -1, RateLimit: 1})
+
+	do := func(method, target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		w := httptest.NewRecorder()
+		server.GetEngine().ServeHTTP(w, req)
+		return w
+	}
+
+	// 公开目录：缓存头和跨域，关闭目录列表
+	w := do(http.MethodGet, "/public/assets/app.js", http.Header{"Origin": {"https://app.example.com"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "console.log(1)", w.Body.String())
+	assert.Equal(t, "public, max-age=3600", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	w = do(http.MethodOptions, "/public/assets/app.js", http.Header{"Origin": {"https://app.example.com"}, "Access-Control-Request-Method": {"GET"}})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/public/assets/", nil).Code)
+
+	// 需要认证的目录
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/files/report.txt", nil).Code)
+	tokens, err := authManager.GenerateTokenPairForUser(&auth.User{ID: "1", Username: "alice"}, "")
+	require.NoError(t, err)
+	w = do(http.MethodGet, "/files/report.txt", http.Header{"Authorization": {"Bearer " + tokens.AccessToken}})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "quarterly report", w.Body.String())
+	assert.Equal(t, "private, max-age=60", w.Header().Get("Cache-Control"))
+
+	// 开启目录列表、禁止缓存、限流
+	w = do(http.MethodGet, "/browse/assets/", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "app.js")
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Equal(t, http.StatusTooManyRequests, do(http.MethodGet, "/browse/assets/app.js", nil).Code)
+
+	// 配置校验
+	_, err = New(&ServerConfig{Config: &config.Config{Server: config.ServerConfig{
+		Host: "localhost", Port: 8080, Mode: gin.TestMode,
+		StaticPaths: []config.StaticPathConfig{{Path: "files", Root: private}},
+	}}})
+	assert.ErrorContains(t, err, "must start with /")
+}
+
+// webAuthnTestUsers 测试用的通行密钥用户查询
+type webAuthnTestUsers map[string]*auth.User
+
+func (u webAuthnTestUsers) GetUserByID(id string) (*auth.User, error) {
+	for _, user := range u {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, auth.ErrUserNotFound
+}
+
+func (u webAuthnTestUsers) GetUserByUsername(username string) (*auth.User, error) {
+	if user, ok := u[username]; ok {
+		return user, nil
+	}
+	return nil, auth.ErrUserNotFound
+}
+
+// webAuthnTestStore 测试用的空凭证存储
+type webAuthnTestStore struct{}
+
+func (webAuthnTestStore) SaveCredential(*webauthn.Credential) error { return nil }
+func (webAuthnTestStore) GetCredential([]byte) (*webauthn.Credential, error) {
+	return nil, webauthn.ErrCredentialNotFound
+}
+func (webAuthnTestStore) ListCredentials(string) ([]*webauthn.Credential, error) { return nil, nil }
+func (webAuthnTestStore) DeleteCredential(string, []byte) error {
+	return webauthn.ErrCredentialNotFound
+}
+
+func TestWebAuthnRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	require.NoError(t, err)
+	defer cacheManager.Close()
+
+	wa, err := webauthn.New(webauthn.Config{RPID: "example.com", Origins: []string{"https://example.com"}},
+		webAuthnTestStore{}, webauthn.NewRedisChallengeStore(cacheManager, ""))
+	require.NoError(t, err)
+	users := webAuthnTestUsers{
+		"alice": {ID: "1", Username: "alice", IsActive: true},
+		"bob":   {ID: "2", Username: "bob", IsActive: false},
+	}
+
+	// 没有认证管理器时不注册
+	plain, err := New(&ServerConfig{Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}}})
+	require.NoError(t, err)
+	assert.Error(t, plain.SetupWebAuthnRoutes(wa, users))
+
+	jwtConfig := config.JWTConfig{Secret: "test-secret", ExpireHours: 1, RefreshHours: 24, Issuer: "test"}
+	logManager, err := logger.New(&config.LogConfig{Level: "error", Format: "json", Output: "console"})
+	require.NoError(t, err)
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}, JWT: jwtConfig},
+		Logger: logManager,
+		Cache:  cacheManager,
+		Auth:   auth.New(&jwtConfig),
+	})
+	require.NoError(t, err)
+	require.NoError(t, server.SetupWebAuthnRoutes(wa, users))
+
+	do := func(method, target, token, body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		server.GetEngine().ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		data, _ := resp["data"].(map[string]interface{})
+		return w.Code, data
+	}
+
+	// 用户不存在、已禁用或没有凭证时回退到密码登录，响应相同
+	for _, username := range []string{"alice", "bob", "nobody"} {
+		code, data := do(http.MethodPost, "/api/v1/auth/webauthn/login/begin", "", `{"username":"`+username+`"}`)
+		assert.Equal(t, http.StatusOK, code, username)
+		assert.Equal(t, true, data["password_fallback"], username)
+		assert.Nil(t, data["publicKey"], username)
+	}
+
+	// 无用户名登录返回挑战
+	code, data := do(http.MethodPost, "/api/v1/auth/webauthn/login/begin", "", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, data["password_fallback"])
+	options := data["publicKey"].(map[string]interface{})
+	assert.Equal(t, "example.com", options["rpId"])
+	assert.NotEmpty(t, options["challenge"])
+
+	code, _ = do(http.MethodPost, "/api/v1/auth/webauthn/login/finish", "", `{"id":"x","rawId":"eA","type":"public-key","response":{"clientDataJSON":"e30"}}`)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// 注册需要JWT认证
+	code, _ = do(http.MethodPost, "/api/v1/auth/webauthn/register/begin", "", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	tokens, err := server.GetAuthService().GetJWTManager().GenerateTokenPair(users["alice"])
+	require.NoError(t, err)
+	code, data = do(http.MethodPost, "/api/v1/auth/webauthn/register/begin", tokens.AccessToken, "")
+	require.Equal(t, http.StatusOK, code)
+	options = data["publicKey"].(map[string]interface{})
+	assert.Equal(t, "alice", options["user"].(map[string]interface{})["name"])
+	assert.Equal(t, "none", options["attestation"])
+
+	code, _ = do(http.MethodDelete, "/api/v1/auth/webauthn/credentials/eA", tokens.AccessToken, "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestTokenIntrospection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	require.NoError(t, err)
+	defer cacheManager.Close()
+
+	jwtConfig := config.JWTConfig{Secret: "test-secret", ExpireHours: 1, RefreshHours: 24, Issuer: "test"}
+	authManager := auth.New(&jwtConfig)
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}, JWT: jwtConfig},
+		Cache:  cacheManager,
+		Auth:   authManager,
+	})
+	require.NoError(t, err)
+
+	provider, err := auth.NewOIDCProvider(auth.OIDCProviderConfig{Issuer: "https://id.example.com"})
+	require.NoError(t, err)
+	gateway, gatewaySecret, err := provider.RegisterClient("gateway", nil, []string{auth.GrantTypeClientCredentials}, []string{"deploy"})
+	require.NoError(t, err)
+	other, otherSecret, err := provider.RegisterClient("other", nil, []string{auth.GrantTypeClientCredentials}, nil)
+	require.NoError(t, err)
+	require.NoError(t, server.SetupTokenIntrospectionRoutes(provider, gateway.ID))
+	assert.IsType(t, &auth.RedisRevocationStore{}, authManager.RevocationStore())
+
+	userTokens, err := authManager.GenerateTokenPairForUser(&auth.User{ID: "42", Username: "alice", Roles: []string{"user"}}, "")
+	require.NoError(t, err)
+	adminTokens, err := authManager.GenerateTokenPairForUser(&auth.User{ID: "1", Username: "root", Roles: []string{"admin"}}, "")
+	require.NoError(t, err)
+	clientToken, err := provider.Exchange(auth.TokenRequest{GrantType: auth.GrantTypeClientCredentials, ClientID: gateway.ID, ClientSecret: gatewaySecret})
+	require.NoError(t, err)
+
+	post := func(path string, form url.Values, authorize func(*http.Request)) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if authorize != nil {
+			authorize(req)
+		}
+		w := httptest.NewRecorder()
+		server.GetEngine().ServeHTTP(w, req)
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w, body
+	}
+	asGateway := func(req *http.Request) { req.SetBasicAuth(gateway.ID, gatewaySecret) }
+	asOther := func(req *http.Request) { req.SetBasicAuth(other.ID, otherSecret) }
+	bearer := func(token string) func(*http.Request) {
+		return func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+	}
+
+	// 调用方需要客户端凭证或管理员令牌
+	w, body := post("/oauth/introspect", url.Values{"token": {userTokens.AccessToken}}, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "invalid_client", body["error"])
+	w, _ = post("/oauth/introspect", url.Values{"token": {userTokens.AccessToken}}, bearer(userTokens.AccessToken))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w, _ = post("/oauth/introspect", url.Values{"token": {userTokens.AccessToken}}, func(req *http.Request) { req.SetBasicAuth(gateway.ID, "wrong") })
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 内省本服务和OIDC提供方签发的令牌
+	w, body = post("/oauth/introspect", url.Values{"token": {userTokens.AccessToken}}, asGateway)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Equal(t, true, body["active"])
+	assert.Equal(t, "42", body["sub"])
+	assert.Equal(t, "alice", body["username"])
+	_, body = post("/oauth/introspect", url.Values{"token": {clientToken.AccessToken}}, bearer(adminTokens.AccessToken))
+	assert.Equal(t, true, body["active"])
+	assert.Equal(t, gateway.ID, body["client_id"])
+	_, body = post("/oauth/introspect", url.Values{"token": {"garbage"}}, asGateway)
+	assert.Equal(t, map[string]interface{}{"active": false}, body)
+
+	// 不在资源服务器列表中的客户端不能内省和吊销本服务签发的令牌
+	_, body = post("/oauth/introspect", url.Values{"token": {userTokens.AccessToken}}, asOther)
+	assert.Equal(t, map[string]interface{}{"active": false}, body)
+	w, body = post("/oauth/revoke", url.Values{"token": {userTokens.RefreshToken}}, asOther)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "unauthorized_client", body["error"])
+	_, err = authManager.ValidateToken(userTokens.RefreshToken)
+	assert.NoError(t, err)
+
+	// 吊销：无效令牌同样返回200，客户端不能吊销其他客户端的OIDC令牌
+	w, _ = post("/oauth/revoke", url.Values{"token": {"garbage"}}, asGateway)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w, body = post("/oauth/revoke", url.Values{"token": {clientToken.AccessToken}}, asOther)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "unauthorized_client", body["error"])
+
+	w, _ = post("/oauth/revoke", url.Values{"token": {userTokens.AccessToken}, "token_type_hint": {"access_token"}}, asGateway)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w, _ = post("/oauth/revoke", url.Values{"token": {clientToken.AccessToken}}, asGateway)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	_, body = post("/oauth/introspect", url.Values{"token": {userTokens.AccessToken}}, asGateway)
+	assert.Equal(t, false, body["active"])
+	_, body = post("/oauth/introspect", url.Values{"token": {clientToken.AccessToken}}, asGateway)
+	assert.Equal(t, false, body["active"])
+	_, err = authManager.ValidateToken(userTokens.AccessToken)
+	assert.ErrorIs(t, err, auth.ErrTokenRevoked)
+	assert.Len(t, mr.Keys(), 2)
+}
+
+func TestOrgRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rbac := auth.NewRBAC()
+	require.NoError(t, org.RegisterDefaultRoles(rbac))
+	service := org.New(org.NewMemoryStore(), rbac)
+
+	jwtConfig := config.JWTConfig{Secret: "test-secret", ExpireHours: 1, RefreshHours: 24, Issuer: "test"}
+	authManager := auth.New(&jwtConfig)
+	logManager, err := logger.New(&config.LogConfig{Level: "error", Format: "json", Output: "console"})
+	require.NoError(t, err)
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}, JWT: jwtConfig},
+		Logger: logManager,
+		Auth:   authManager,
+	})
+	require.NoError(t, err)
+	require.NoError(t, server.SetupOrgRoutes(service))
+
+	tokens := map[string]string{}
+	for id, email := range map[string]string{"1": "alice@example.com", "2": "bob@example.com", "3": "carol@example.com"} {
+		pair, err := authManager.GenerateTokenPairForUser(&auth.User{ID: id, Username: "user" + id, Email: email}, "")
+		require.NoError(t, err)
+		tokens[id] = pair.AccessToken
+	}
+	alice, bob, carol := tokens["1"], tokens["2"], tokens["3"]
+
+	do := func(method, target, token, body string, header ...string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		server.GetEngine().ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := do(http.MethodPost, "/api/v1/orgs", alice, `{"name":"Acme Inc"}`)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "acme-inc", resp["data"].(map[string]interface{})["slug"])
+	code, _ = do(http.MethodPost, "/api/v1/orgs", alice, `{"name":"Acme","slug":"acme-inc"}`)
+	assert.Equal(t, http.StatusConflict, code)
+
+	// 按标识解析，非成员不能访问
+	code, resp = do(http.MethodGet, "/api/v1/orgs/acme-inc", alice, "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []interface{}{org.RoleOwner}, resp["data"].(map[string]interface{})["membership"].(map[string]interface{})["roles"])
+	code, _ = do(http.MethodGet, "/api/v1/orgs/acme-inc", bob, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = do(http.MethodGet, "/api/v1/orgs/missing", alice, "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// 邀请：未设置发送器时返回令牌，只有被邀请邮箱可以接受
+	code, resp = do(http.MethodPost, "/api/v1/orgs/acme-inc/invitations", alice, `{"email":"bob@example.com","roles":["org_admin"]}`)
+	require.Equal(t, http.StatusOK, code)
+	token := resp["data"].(map[string]interface{})["token"].(string)
+	code, _ = do(http.MethodPost, "/api/v1/orgs/invitations/accept", carol, `{"token":"`+token+`"}`)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = do(http.MethodPost, "/api/v1/orgs/invitations/accept", bob, `{"token":"`+token+`"}`)
+	require.Equal(t, http.StatusOK, code)
+	code, _ = do(http.MethodPost, "/api/v1/orgs/invitations/accept", bob, `{"token":"`+token+`"}`)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// 通过请求头指定组织的中间件
+	scoped := server.GetEngine().Group("/api/v1/projects", server.middleware.JWT(), middleware.OrgContext(service, ""))
+	scoped.GET("", middleware.RequireOrgPermission(service, org.ResourceMembers, "read"), func(c *gin.Context) {
+		current, _ := middleware.GetOrg(c)
+		c.JSON(http.StatusOK, gin.H{"org": current.Slug})
+	})
+	code, _ = do(http.MethodGet, "/api/v1/projects", bob, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, resp = do(http.MethodGet, "/api/v1/projects", bob, "", middleware.OrgHeader, "acme-inc")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "acme-inc", resp["org"])
+	code, _ = do(http.MethodGet, "/api/v1/projects", carol, "", middleware.OrgHeader, "1")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// 管理员可以管理成员，但不能授予所有者角色或移除所有者
+	code, resp = do(http.MethodPost, "/api/v1/orgs/acme-inc/invitations", bob, `{"email":"carol@example.com"}`)
+	require.Equal(t, http.StatusOK, code)
+	token = resp["data"].(map[string]interface{})["token"].(string)
+	code, _ = do(http.MethodPost, "/api/v1/orgs/invitations/accept", carol, `{"token":"`+token+`"}`)
+	require.Equal(t, http.StatusOK, code)
+	code, _ = do(http.MethodPost, "/api/v1/orgs/acme-inc/invitations", bob, `{"email":"dave@example.com","roles":["org_owner"]}`)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = do(http.MethodPut, "/api/v1/orgs/acme-inc/members/3", bob, `{"roles":["org_owner"]}`)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = do(http.MethodDelete, "/api/v1/orgs/acme-inc/members/1", bob, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = do(http.MethodPut, "/api/v1/orgs/acme-inc/members/3", bob, `{"roles":["unknown"]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = do(http.MethodDelete, "/api/v1/orgs/acme-inc", bob, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// 普通成员只能查看，可以退出组织
+	code, resp = do(http.MethodGet, "/api/v1/orgs/acme-inc/members", carol, "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Len(t, resp["data"], 3)
+	code, _ = do(http.MethodGet, "/api/v1/orgs/acme-inc/invitations", carol, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = do(http.MethodPut, "/api/v1/orgs/acme-inc", carol, `{"name":"Hijacked"}`)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = do(http.MethodDelete, "/api/v1/orgs/acme-inc/members/2", carol, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = do(http.MethodDelete, "/api/v1/orgs/acme-inc/members/3", carol, "")
+	assert.Equal(t, http.StatusOK, code)
+
+	// 最后一个所有者不能退出，所有者可以删除组织
+	code, _ = do(http.MethodDelete, "/api/v1/orgs/acme-inc/members/1", alice, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, resp = do(http.MethodGet, "/api/v1/orgs", bob, "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Len(t, resp["data"], 1)
+	code, _ = do(http.MethodDelete, "/api/v1/orgs/acme-inc", alice, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = do(http.MethodGet, "/api/v1/orgs/acme-inc", alice, "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+// unavailableRBACStore 读取用户角色失败的RBAC存储
+type unavailableRBACStore struct {
+	*auth.MemoryRBACStore
+}
+
+func (s *unavailableRBACStore) GetUserRoles(userID string) ([]string, error) {
+	return nil, errors.New("store unavailable")
+}
+
+func TestRequirePermissionStoreError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rbac := auth.NewRBACWithStore(&unavailableRBACStore{auth.NewMemoryRBACStore()})
+	require.NoError(t, auth.CreateDefaultRolesAndPermissions(rbac))
+	evaluator := auth.NewPolicyEvaluator(rbac)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", "u1") })
+	router.GET("/users", middleware.RequirePermission(rbac, "user", "read"), func(c *gin.Context) {})
+	router.GET("/policy", middleware.RequirePolicy(evaluator, &auth.Policy{Resource: "user", Actions: []string{"read"}}), func(c *gin.Context) {})
+
+	// 存储故障返回500而不是403，避免把故障误报为无权限
+	for _, path := range []string{"/users", "/policy"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusInternalServerError, w.Code, path)
+	}
+}
+
+func TestRequirePermissionPathParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rbac := auth.NewRBAC()
+	require.NoError(t, rbac.AddPermission(&auth.Permission{ID: "article.42.read", Name: "Read article 42", Resource: "article:42", Action: "read"}))
+	require.NoError(t, rbac.AddRole(&auth.Role{ID: "reader", Name: "Reader"}))
+	require.NoError(t, rbac.AddPermissionToRole("reader", "article.42.read"))
+	require.NoError(t, rbac.AssignRoleToUser("u1", "reader"))
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", "u1") })
+	router.GET("/articles/:id", middleware.RequirePermission(rbac, "article:{id}", "read"), func(c *gin.Context) {})
+
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, get("/articles/42"))
+	assert.Equal(t, http.StatusForbidden, get("/articles/43"))
+	// 路径参数中的分隔符不能扩大资源范围
+	assert.Equal(t, http.StatusBadRequest, get("/articles/42:comments"))
+	assert.Equal(t, http.StatusBadRequest, get("/articles/42%3A*"))
+	assert.Equal(t, http.StatusBadRequest, get("/articles/*"))
+}
+
+func TestVerifyMiddlewareChains(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	jwtConfig := config.JWTConfig{Secret: "test-secret", ExpireHours: 1, RefreshHours: 24, Issuer: "test"}
+	authManager := auth.New(&jwtConfig)
+	logManager, err := logger.New(&config.LogConfig{Level: "error", Format: "json", Output: "console"})
+	require.NoError(t, err)
+	newServer := func() *Server {
+		server, err := New(&ServerConfig{
+			Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}, JWT: jwtConfig},
+			Logger: logManager,
+			Auth:   authManager,
+		})
+		require.NoError(t, err)
+		return server
+	}
+	mw := newServer().GetMiddleware()
+	rbac := auth.NewRBAC()
+	limiter := middleware.NewRateLimiter(middleware.DefaultRateLimiterConfig())
+
+	// 正确的中间件顺序
+	server := newServer()
+	admin := server.Group("/admin", mw.JWT(), mw.RequireRole("admin"))
+	admin.GET("/users", middleware.Pagination(), server.handleListUsers)
+	admin.GET("/articles/:id", middleware.RequirePermission(rbac, "article:{id}", "read"), func(c *gin.Context) {})
+	server.POST("/signup", middleware.RateLimitByIP(10, 5), func(c *gin.Context) {})
+	server.POST("/contact", limiter.Middleware(), func(c *gin.Context) {})
+	server.POST("/webhooks/stripe", func(c *gin.Context) {})
+	chains, err := server.RouteChains()
+	require.NoError(t, err)
+	var users RouteChain
+	for _, chain := range chains {
+		if chain.Method == http.MethodGet && chain.Path == "/admin/users" {
+			users = chain
+		}
+	}
+	assert.Equal(t, []string{"middleware.JWT", "middleware.RequireRole", "middleware.Pagination", "server.(*Server).handleListUsers"}, users.Handlers[len(users.Handlers)-4:])
+
+	policy := DefaultChainPolicy()
+	policy.PublicWriteExemptPaths = []string{"/webhooks/"}
+	assert.NoError(t, server.VerifyMiddlewareChains(policy))
+
+	// 顺序错误、缺少分页中间件、公开POST未限流
+	server = newServer()
+	server.GET("/reports", mw.RequireRole("admin"), mw.JWT(), func(c *gin.Context) {})
+	server.GET("/users", mw.JWT(), server.handleListUsers)
+	server.GET("/orders", mw.JWT(), func(c *gin.Context) {})
+	server.SetRouteMeta(http.MethodGet, "/orders", RouteMeta{Paginated: true})
+	server.POST("/contact", func(c *gin.Context) {})
+	server.POST("/comments", mw.JWT(), func(c *gin.Context) {})
+	// 客户端证书不设置 claims，不能作为角色检查的认证中间件，但可以替代公开写接口限流
+	server.GET("/internal/jobs", middleware.RequireClientCert(nil), mw.RequireRole("admin"), func(c *gin.Context) {})
+	server.POST("/internal/events", middleware.RequireClientCert(nil), func(c *gin.Context) {})
+
+	err = server.VerifyMiddlewareChains(nil)
+	var verr *ChainVerificationError
+	require.ErrorAs(t, err, &verr)
+	messages := map[string]string{}
+	for _, violation := range verr.Violations {
+		messages[violation.Method+" "+violation.Path] = violation.Message
+	}
+	assert.Len(t, verr.Violations, 5, err.Error())
+	assert.Contains(t, messages["GET /internal/jobs"], "middleware.RequireRole runs before any auth middleware")
+	assert.Contains(t, messages["GET /reports"], "middleware.RequireRole runs before any auth middleware")
+	assert.Contains(t, messages["GET /users"], "server.(*Server).handleListUsers reads page/page_size")
+	assert.Contains(t, messages["GET /orders"], "add middleware.Pagination()")
+	assert.Contains(t, messages["POST /contact"], "public POST route has no rate limit middleware")
+	assert.Contains(t, err.Error(), "GET /reports: ")
+
+	// 全局限流满足公开POST规则，自定义规则
+	server = newServer()
+	server.Use(middleware.RateLimitGlobal(100, 10))
+	server.POST("/contact", func(c *gin.Context) {})
+	policy = DefaultChainPolicy()
+	policy.Rules = append(policy.Rules, func(route RouteChain) error {
+		if route.Index("middleware.Tracing") < 0 {
+			return fmt.Errorf("missing tracing middleware")
+		}
+		return nil
+	})
+	err = server.VerifyMiddlewareChains(policy)
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "missing tracing middleware", verr.Violations[0].Message)
+
+	// 启用配置时启动前校验
+	server = newServer()
+	server.config.Server.VerifyMiddleware = true
+	server.config.Server.Port = 0
+	server.POST("/contact", func(c *gin.Context) {})
+	assert.ErrorAs(t, server.Start(), &verr)
+}
+
+func TestTrustedHeaderAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	log, err := logger.New(&config.LogConfig{Level: "error", Format: "json", Output: "console"})
+	require.NoError(t, err)
+	jwtConfig := config.JWTConfig{Secret: "test-secret", ExpireHours: 1, RefreshHours: 24, Issuer: "test"}
+	jwtConfig.TrustedHeader = config.TrustedHeaderConfig{
+		Enabled:        true,
+		UserHeader:     "X-Auth-User",
+		EmailHeader:    "X-Auth-Email",
+		RolesHeader:    "X-Auth-Roles",
+		TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1"},
+	}
+	authManager := auth.New(&jwtConfig)
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}, JWT: jwtConfig},
+		Logger: log,
+		Auth:   authManager,
+	})
+	require.NoError(t, err)
+
+	protected := server.Group("/api", server.GetMiddleware().JWT())
+	protected.GET("/me", func(c *gin.Context) {
+		claims, _ := middleware.GetClaims(c)
+		role, _ := middleware.GetUserRole(c)
+		c.JSON(http.StatusOK, gin.H{"user_id": claims.UserID, "email": claims.Email, "roles": claims.Roles, "role": role})
+	})
+	protected.GET("/admin", server.GetMiddleware().RequireRole("admin"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	request := func(path, remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		server.GetEngine().ServeHTTP(w, req)
+		return w
+	}
+	identity := map[string]string{"X-Auth-User": "42", "X-Auth-Email": "alice@example.com", "X-Auth-Roles": "admin, editor"}
+
+	// 来自网关的身份请求头映射为与JWT相同的上下文
+	w := request("/api/me", "10.1.2.3:40000", identity)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "42", body["user_id"])
+	assert.Equal(t, "alice@example.com", body["email"])
+	assert.Equal(t, []interface{}{"admin", "editor"}, body["roles"])
+	assert.Equal(t, "admin", body["role"])
+	assert.Equal(t, http.StatusNoContent, request("/api/admin", "192.0.2.1:40000", identity).Code)
+
+	// 其他来源伪造的身份请求头被拒绝，X-Forwarded-For 不影响来源判断
+	spoofed := map[string]string{"X-Auth-User": "1", "X-Auth-Roles": "admin", "X-Forwarded-For": "10.1.2.3"}
+	assert.Equal(t, http.StatusUnauthorized, request("/api/admin", "203.0.113.7:40000", spoofed).Code)
+	assert.Equal(t, http.StatusUnauthorized, request("/api/me", "10.1.2.3:40000", map[string]string{"X-Auth-Roles": "admin"}).Code)
+
+	// 没有身份请求头时回退到JWT校验
+	tokens, err := authManager.GenerateTokenPairForUser(&auth.User{ID: "7", Username: "bob", Roles: []string{"user"}}, "")
+	require.NoError(t, err)
+	w = request("/api/me", "203.0.113.7:40000", map[string]string{"Authorization": "Bearer " + tokens.AccessToken})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"user_id":"7"`)
+	assert.Equal(t, http.StatusUnauthorized, request("/api/me", "10.1.2.3:40000", nil).Code)
+
+	// 按网关客户端证书校验
+	engine := gin.New()
+	engine.GET("/me", middleware.TrustedHeader(&middleware.TrustedHeaderConfig{ClientCertNames: []string{"gateway.internal"}}), func(c *gin.Context) {
+		userID, _ := middleware.GetUserID(c)
+		c.String(http.StatusOK, userID)
+	})
+	withCert := func(cert *x509.Certificate, verified bool) int {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("X-Auth-User", "42")
+		if cert != nil {
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+			if verified {
+				req.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+			}
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Code
+	}
+	gatewayCert := &x509.Certificate{Subject: pkix.Name{CommonName: "edge"}, DNSNames: []string{"gateway.internal"}}
+	assert.Equal(t, http.StatusOK, withCert(gatewayCert, true))
+	assert.Equal(t, http.StatusUnauthorized, withCert(gatewayCert, false))
+	assert.Equal(t, http.StatusUnauthorized, withCert(&x509.Certificate{Subject: pkix.Name{CommonName: "client"}}, true))
+	assert.Equal(t, http.StatusUnauthorized, withCert(nil, false))
+
+	// 未配置可信来源或地址无效时拒绝创建
+	jwtConfig.TrustedHeader.TrustedProxies = []string{"not-an-ip"}
+	_, err = New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}, JWT: jwtConfig},
+		Logger: log,
+		Auth:   authManager,
+	})
+	assert.Error(t, err)
+	assert.Panics(t, func() { middleware.TrustedHeader(&middleware.TrustedHeaderConfig{}) })
+}
+
+func TestRequestContextHelpers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	cacheManager, err := cache.New(&config.RedisConfig{Host: mr.Host(), Port: port, KeyPrefix: "app"})
+	require.NoError(t, err)
+	defer cacheManager.Close()
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}},
+		Cache:  cacheManager,
+	})
+	require.NoError(t, err)
+
+	server.GET("/context", func(c *gin.Context) {
+		assert.Nil(t, server.DB(c), "no database configured")
+		if err := server.Cache(c).Set("greeting", "hello", time.Minute); err != nil {
+			server.Error(c, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		server.Success(c, nil)
+	})
+
+	w := httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/context", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	value, err := cacheManager.Get("greeting")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", value)
+
+	// 请求已取消时缓存命令和健康检查随之取消
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/context", nil).WithContext(ctx))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "context canceled")
+
+	report := server.StatusReport(ctx)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, "error", report.Checks[0].Status)
+	assert.Equal(t, "ok", server.StatusReport(context.Background()).Checks[0].Status)
+}
+
+// testCertificate 测试证书及私钥
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pair tls.Certificate
+}
+
+// newTestCertificate 生成由 parent 签发的证书，parent 为 nil 时自签名
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *testCertificate) *testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCertificate{cert: cert, key: key, pair: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}}
+}
+
+// writePEM 将证书和私钥写入PEM文件
+func (c *testCertificate) writePEM(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	certFile, keyFile = filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestMutualTLS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+
+	ca := newTestCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	serverCert := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	billing := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "billing", OrganizationalUnit: []string{"payments"}},
+		DNSNames:     []string{"billing.internal"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+	stranger := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(4),
+		Subject:      pkix.Name{CommonName: "stranger", OrganizationalUnit: []string{"marketing"}},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+
+	certFile, keyFile := serverCert.writePEM(t, dir, "server")
+	caFile, _ := ca.writePEM(t, dir, "ca")
+	serverConfig := config.ServerConfig{Host: "localhost", Port: 8443, Mode: gin.TestMode}
+	serverConfig.TLS = config.ServerTLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ClientAuth: "verify_if_given"}
+	server, err := New(&ServerConfig{Config: &config.Config{Server: serverConfig}})
+	require.NoError(t, err)
+	assert.Equal(t, true, server.StartupSummary()["subsystems"].(map[string]bool)["tls"])
+
+	server.GET("/whoami", func(c *gin.Context) {
+		info, ok := middleware.GetClientCert(c)
+		if !ok {
+			c.String(http.StatusOK, "anonymous")
+			return
+		}
+		c.String(http.StatusOK, info.CommonName)
+	})
+	server.GET("/internal", middleware.RequireClientCert(&middleware.ClientCertConfig{OrganizationalUnits: []string{"payments"}}), func(c *gin.Context) {
+		info, _ := middleware.GetClientCert(c)
+		c.JSON(http.StatusOK, info)
+	})
+
+	ts := httptest.NewUnstartedServer(server.GetEngine())
+	ts.TLS = server.httpServer.TLSConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(path string, client *testCertificate) (int, string) {
+		tlsConfig := &tls.Config{RootCAs: roots}
+		if client != nil {
+			tlsConfig.Certificates = []tls.Certificate{client.pair}
+		}
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		resp, err := httpClient.Get(ts.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	// 证书主题写入请求上下文，没有证书的请求仍可访问普通路由
+	code, body := get("/whoami", billing)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "billing", body)
+	_, body = get("/whoami", nil)
+	assert.Equal(t, "anonymous", body)
+
+	code, body = get("/internal", billing)
+	require.Equal(t, http.StatusOK, code, body)
+	var info map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(body), &info))
+	assert.Equal(t, []interface{}{"payments"}, info["organizational_units"])
+	assert.Equal(t, []interface{}{"billing.internal"}, info["dns_names"])
+	assert.Len(t, info["fingerprint"], 64)
+
+	code, _ = get("/internal", stranger)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = get("/internal", nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// 不是服务器CA签发的证书不被接受，请求视为没有证书
+	rogueCA := newTestCertificate(t, &x509.Certificate{SerialNumber: big.NewInt(5), Subject: pkix.Name{CommonName: "Rogue CA"}, IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}, nil)
+	rogue := newTestCertificate(t, &x509.Certificate{SerialNumber: big.NewInt(6), Subject: pkix.Name{CommonName: "billing", OrganizationalUnit: []string{"payments"}}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}, rogueCA)
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{rogue.pair}}}}
+	resp, err := httpClient.Get(ts.URL + "/internal")
+	if err == nil {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	// 校验客户端证书必须配置CA
+	serverConfig.TLS.ClientCAFile = ""
+	_, err = New(&ServerConfig{Config: &config.Config{Server: serverConfig}})
+	assert.Error(t, err)
+}
+
+func TestRefreshTokenRejectedAsAccessToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	log, err := logger.New(&config.LogConfig{Level: "error", Format: "json", Output: "console"})
+	require.NoError(t, err)
+	jwtConfig := config.JWTConfig{Secret: "test-secret", ExpireHours: 1, RefreshHours: 24, Issuer: "test"}
+	authManager := auth.New(&jwtConfig)
+	server, err := New(&ServerConfig{
+		Config: &config.Config{Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode}, JWT: jwtConfig},
+		Logger: log,
+		Auth:   authManager,
+	})
+	require.NoError(t, err)
+
+	server.GET("/me", server.GetMiddleware().JWT(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	server.GET("/admin", server.GetMiddleware().JWT(), server.GetMiddleware().RequireRole("admin"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	server.GET("/optional", server.GetMiddleware().JWTOptional(), func(c *gin.Context) {
+		_, ok := middleware.GetClaims(c)
+		c.JSON(http.StatusOK, gin.H{"authenticated": ok})
+	})
+
+	pair, err := authManager.GenerateTokenPairForUser(&auth.User{ID: "1", Username: "admin", Roles: []string{"admin"}}, "")
+	require.NoError(t, err)
+	request := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.GetEngine().ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusNoContent, request("/me", pair.AccessToken).Code)
+	assert.Equal(t, http.StatusNoContent, request("/admin", pair.AccessToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, request("/me", pair.RefreshToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, request("/admin", pair.RefreshToken).Code)
+
+	w := request("/optional", pair.RefreshToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"authenticated":false}`, w.Body.String())
+	w = request("/optional", pair.AccessToken)
+	assert.JSONEq(t, `{"authenticated":true}`, w.Body.String())
+}
+
+func TestAnalyticsClientKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newContext := func(apiKey string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/items", nil)
+		if apiKey != "" {
+			c.Request.Header.Set(middleware.APIKeyHeader, apiKey)
+		}
+		return c
+	}
+
+	// 未经校验的API Key不产生新的客户端，统一计入 anonymous
+	assert.Equal(t, middleware.AnalyticsAnonymousClient, middleware.AnalyticsClientKey(newContext("")))
+	assert.Equal(t, middleware.AnalyticsAnonymousClient, middleware.AnalyticsClientKey(newContext("forged-1")))
+	assert.Equal(t, middleware.AnalyticsAnonymousClient, middleware.AnalyticsClientKey(newContext("forged-2")))
+
+	c := newContext("sk_live_secret")
+	middleware.SetAPIKeyID(c, "key_123")
+	client := middleware.AnalyticsClientKey(c)
+	assert.True(t, strings.HasPrefix(client, "key:"))
+	assert.NotContains(t, client, "key_123")
+
+	c.Set("user_id", "42")
+	assert.Equal(t, "user:42", middleware.AnalyticsClientKey(c))
+}
+
+func TestCoalesceMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls atomic.Int32
+	var release chan struct{}
+	engine := gin.New()
+	// 只设置 MaxBodySize 时仍按认证相关请求头区分用户
+	engine.Use(middleware.Coalesce(&middleware.CoalesceConfig{MaxBodySize: 1024}))
+	engine.GET("/me", func(c *gin.Context) {
+		calls.Add(1)
+		<-release
+		c.String(http.StatusOK, "user:"+c.GetHeader("Authorization"))
+	})
+
+	run := func(tokens ...string) []*httptest.ResponseRecorder {
+		calls.Store(0)
+		release = make(chan struct{})
+		recorders := make([]*httptest.ResponseRecorder, len(tokens))
+		var wg sync.WaitGroup
+		for i, token := range tokens {
+			recorders[i] = httptest.NewRecorder()
+			wg.Add(1)
+			go func(w *httptest.ResponseRecorder, token string) {
+				defer wg.Done()
+				req := httptest.NewRequest(http.MethodGet, "/me", nil)
+				req.Header.Set("Authorization", token)
+				engine.ServeHTTP(w, req)
+			}(recorders[i], token)
+		}
+		// 等待所有请求进入处理函数或合并等待
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		return recorders
+	}
+
+	// 不同用户的并发请求各自执行
+	recorders := run("Bearer alice", "Bearer bob")
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, "user:Bearer alice", recorders[0].Body.String())
+	assert.Equal(t, "user:Bearer bob", recorders[1].Body.String())
+	assert.Empty(t, recorders[0].Header().Get(middleware.CoalescedHeader))
+	assert.Empty(t, recorders[1].Header().Get(middleware.CoalescedHeader))
+
+	// 同一用户的并发请求合并为一次执行
+	recorders = run("Bearer alice", "Bearer alice")
+	assert.Equal(t, int32(1), calls.Load())
+	coalesced := 0
+	for _, w := range recorders {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "user:Bearer alice", w.Body.String())
+		if w.Header().Get(middleware.CoalescedHeader) == "true" {
+			coalesced++
+		}
+	}
+	assert.Equal(t, 1, coalesced)
+}
+
+func TestResponseSchemaValidator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	spec := []byte(`{
+		"openapi": "3.0.0",
+		"info": {"title": "test", "version": "1"},
+		"paths": {
+			"/items/{id}": {
+				"get": {
+					"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+					"responses": {
+						"200": {
+							"description": "ok",
+							"content": {"application/json": {"schema": {
+								"type": "object",
+								"required": ["id", "name"],
+								"properties": {"id": {"type": "integer"}, "name": {"type": "string"}}
+							}}}
+						}
+					}
+				}
+			}
+		}
+	}`)
+	var mismatches []string
+	validator, err := middleware.ResponseSchemaValidator(&middleware.ResponseSchemaConfig{
+		Spec: spec,
+		OnMismatch: func(c *gin.Context, err error) {
+			mismatches = append(mismatches, c.Request.URL.Path)
+		},
+	})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(validator)
+	router.GET("/items/:id", func(c *gin.Context) {
+		if c.Param("id") == "bad" {
+			c.JSON(http.StatusOK, gin.H{"id": "not-a-number"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": 1, "name": "widget"})
+	})
+	router.GET("/undocumented", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"anything": true})
+	})
+
+	// 只记录与文档不一致的响应，响应内容不变；文档中没有的路由不校验
+	for _, path := range []string{"/items/1", "/items/bad", "/undocumented"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	assert.Equal(t, []string{"/items/bad"}, mismatches)
+
+	// swag 生成的 Swagger 2.0 文档按 basePath 匹配路由
+	swagger := []byte(`{
+		"swagger": "2.0",
+		"info": {"title": "test", "version": "1"},
+		"basePath": "/api/v1",
+		"paths": {"/ping": {"get": {"produces": ["application/json"], "responses": {"200": {"description": "ok", "schema": {"type": "object", "required": ["pong"], "properties": {"pong": {"type": "boolean"}}}}}}}}
+	}`)
+	mismatches = nil
+	validator, err = middleware.ResponseSchemaValidator(&middleware.ResponseSchemaConfig{
+		Spec:       swagger,
+		OnMismatch: func(c *gin.Context, err error) { mismatches = append(mismatches, c.Request.URL.Path) },
+	})
+	require.NoError(t, err)
+	router = gin.New()
+	router.GET("/api/v1/ping", validator, func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/ping", nil))
+	assert.Equal(t, []string{"/api/v1/ping"}, mismatches)
+
+	_, err = middleware.ResponseSchemaValidator(&middleware.ResponseSchemaConfig{})
+	assert.Error(t, err)
+
+	// release 模式下不加载文档也不校验
+	gin.SetMode(gin.ReleaseMode)
+	defer gin.SetMode(gin.TestMode)
+	_, err = middleware.ResponseSchemaValidator(&middleware.ResponseSchemaConfig{})
+	assert.NoError(t, err)
+}
+
+func TestAccessLogPrivacy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	capture := func(cfg *config.LogConfig, handlers ...func(*logger.Manager) gin.HandlerFunc) []map[string]interface{} {
+		cfg.Level, cfg.Format, cfg.Output = "info", "json", "console"
+		logManager, err := logger.New(cfg)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		logManager.GetLogger().SetOutput(&buf)
+
+		router := gin.New()
+		for _, handler := range handlers {
+			router.Use(handler(logManager))
+		}
+		router.GET("/search", func(c *gin.Context) { c.Status(http.StatusOK) })
+		req := httptest.NewRequest(http.MethodGet, "/search?q=shoes&token=secret&email=a%40example.com", nil)
+		req.RemoteAddr = "203.0.113.77:1234"
+		req.Header.Set("User-Agent", "test-agent")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		var entries []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var entry map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(line), &entry))
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+
+	// 截断IP、不记录User-Agent、移除指定查询参数
+	entries := capture(&config.LogConfig{AnonymizeIP: middleware.IPAnonymizeTruncate, DropUserAgent: true, ExcludeQueryParams: []string{"token", "email"}},
+		middleware.LoggerWithManager, middleware.AccessLogger)
+	require.Len(t, entries, 2)
+	// 内层的 AccessLogger 先写日志
+	assert.Equal(t, "203.0.113.0", entries[0]["client_ip"])
+	assert.Equal(t, "/search?q=shoes", entries[0]["uri"])
+	assert.Equal(t, "203.0.113.0", entries[1]["ip"])
+	assert.Equal(t, "/search?q=shoes", entries[1]["path"])
+	assert.NotContains(t, entries[1], "user_agent")
+
+	// 哈希IP对同一盐值稳定，不同盐值结果不同，不包含原始IP
+	first := capture(&config.LogConfig{AnonymizeIP: middleware.IPAnonymizeHash, IPHashSalt: "salt-a"}, middleware.RequestLogger)
+	second := capture(&config.LogConfig{AnonymizeIP: middleware.IPAnonymizeHash, IPHashSalt: "salt-a"}, middleware.RequestLogger)
+	other := capture(&config.LogConfig{AnonymizeIP: middleware.IPAnonymizeHash, IPHashSalt: "salt-b"}, middleware.RequestLogger)
+	require.Len(t, first, 2)
+	assert.Len(t, first[0]["ip"], 16)
+	assert.Equal(t, first[0]["ip"], first[1]["ip"])
+	assert.Equal(t, first[0]["ip"], second[0]["ip"])
+	assert.NotEqual(t, first[0]["ip"], other[0]["ip"])
+	assert.Equal(t, "test-agent", first[0]["user_agent"])
+
+	// 未配置隐私设置时保持原样
+	entries = capture(&config.LogConfig{}, middleware.LoggerWithManager)
+	assert.Equal(t, "203.0.113.77", entries[0]["ip"])
+	assert.Equal(t, "/search?q=shoes&token=secret&email=a%40example.com", entries[0]["path"])
+}
+
+func TestChaosMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 固定随机数来源，0.1 小于所有配置的比例
+	random := func() float64 { return 0.1 }
+	newRouter := func(rules ...middleware.ChaosRule) *gin.Engine {
+		router := gin.New()
+		router.Use(middleware.Chaos(&middleware.ChaosConfig{Enabled: true, Rules: rules, SkipPaths: []string{"/health"}, Random: random}))
+		handler := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+		router.GET("/api/orders", handler)
+		router.GET("/health", handler)
+		router.GET("/static/app.js", handler)
+		return router
+	}
+	get := func(router *gin.Engine, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	// 注入错误，跳过的路径和不匹配规则的路径不受影响
+	router := newRouter(middleware.ChaosRule{Path: "/api/*", ErrorRate: 0.5, ErrorStatus: http.StatusBadGateway})
+	w := get(router, "/api/orders")
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Equal(t, "error", w.Header().Get(middleware.ChaosHeader))
+	assert.Equal(t, http.StatusOK, get(router, "/health").Code)
+	assert.Equal(t, http.StatusOK, get(router, "/static/app.js").Code)
+
+	// 比例低于随机数时不注入，默认错误状态码为503
+	router = newRouter(middleware.ChaosRule{Path: "/api/*", ErrorRate: 0.05}, middleware.ChaosRule{ErrorRate: 1})
+	assert.Equal(t, http.StatusOK, get(router, "/api/orders").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, get(router, "/static/app.js").Code)
+
+	// 注入延迟后继续处理请求
+	router = newRouter(middleware.ChaosRule{LatencyRate: 1, Latency: 30 * time.Millisecond})
+	start := time.Now()
+	w = get(router, "/api/orders")
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "latency", w.Header().Get(middleware.ChaosHeader))
+
+	// 断开连接时客户端收不到响应
+	ts := httptest.NewServer(newRouter(middleware.ChaosRule{DropRate: 1}))
+	defer ts.Close()
+	_, err := http.Get(ts.URL + "/api/orders")
+	assert.Error(t, err)
+	resp, err := http.Get(ts.URL + "/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 未启用时直接放行；release模式下服务器忽略故障注入配置
+	router = gin.New()
+	router.Use(middleware.Chaos(&middleware.ChaosConfig{Rules: []middleware.ChaosRule{{ErrorRate: 1}}}))
+	router.GET("/api/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+	assert.Equal(t, http.StatusOK, get(router, "/api/orders").Code)
+
+	chaos := config.ChaosConfig{Enabled: true, Rules: []config.ChaosRule{{Path: "/api/*", LatencyMs: 200, ErrorRate: 0.1}}}
+	server := &Server{config: &config.Config{Server: config.ServerConfig{Mode: gin.ReleaseMode, Chaos: chaos}}}
+	assert.Nil(t, server.chaosConfig())
+	server.config.Server.Mode = gin.DebugMode
+	cfg := server.chaosConfig()
+	require.NotNil(t, cfg)
+	assert.Equal(t, 200*time.Millisecond, cfg.Rules[0].Latency)
+	assert.Contains(t, cfg.SkipPaths, "/health")
+}
+
+func TestRateLimitHeadersAndStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := middleware.DefaultRateLimiterConfig()
+	cfg.Rate, cfg.Burst = 1, 2
+	limiter := middleware.NewRateLimiter(cfg)
+	defer limiter.Stop()
+	router := gin.New()
+	router.GET("/api", limiter.Middleware(), func(c *gin.Context) {
+		status, ok := middleware.GetRateLimitStatus(c)
+		require.True(t, ok)
+		c.JSON(http.StatusOK, status)
+	})
+	router.GET("/rate-limit", limiter.StatusHandler())
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "198.51.100.1:1234"
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 每次放行减少剩余配额，配额用尽后返回429和 Retry-After
+	w := get("/api")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get(middleware.RateLimitLimitHeader))
+	assert.Equal(t, "1", w.Header().Get(middleware.RateLimitRemainingHeader))
+	assert.Empty(t, w.Header().Get(middleware.RetryAfterHeader))
+	reset, err := strconv.ParseInt(w.Header().Get(middleware.RateLimitResetHeader), 10, 64)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, reset, time.Now().Unix())
+
+	assert.Equal(t, "0", get("/api").Header().Get(middleware.RateLimitRemainingHeader))
+	w = get("/api")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get(middleware.RetryAfterHeader))
+
+	// 查询状态不消耗配额，重置后恢复
+	w = get("/rate-limit")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var status middleware.RateLimitStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.False(t, status.Allowed)
+	assert.Equal(t, 0, status.Remaining)
+	assert.Equal(t, 0, limiter.Status("198.51.100.1").Remaining)
+	limiter.Reset("198.51.100.1")
+	assert.Equal(t, 2, limiter.Status("198.51.100.1").Remaining)
+	assert.Equal(t, http.StatusOK, get("/api").Code)
+
+	// 关闭响应头时仍然限流
+	cfg = middleware.DefaultRateLimiterConfig()
+	cfg.Rate, cfg.Burst, cfg.DisableHeaders = 1, 1, true
+	quiet := middleware.NewRateLimiter(cfg)
+	defer quiet.Stop()
+	router = gin.New()
+	router.GET("/api", quiet.Middleware(), func(c *gin.Context) {})
+	w = get("/api")
+	assert.Empty(t, w.Header().Get(middleware.RateLimitLimitHeader))
+	w = get("/api")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Empty(t, w.Header().Get(middleware.RetryAfterHeader))
+}
+
+func TestRateLimitStrategies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newLimiter := func(strategy string, rate, burst int, window time.Duration) *middleware.RateLimiter {
+		cfg := middleware.DefaultRateLimiterConfig()
+		cfg.Rate, cfg.Burst, cfg.Duration, cfg.Strategy = rate, burst, window, strategy
+		limiter := middleware.NewRateLimiter(cfg)
+		t.Cleanup(limiter.Stop)
+		return limiter
+	}
+	hit := func(limiter *middleware.RateLimiter, n int) []int {
+		router := gin.New()
+		router.GET("/api", limiter.Middleware(), func(c *gin.Context) {})
+		codes := make([]int, 0, n)
+		for i := 0; i < n; i++ {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api", nil))
+			codes = append(codes, w.Code)
+		}
+		return codes
+	}
+	ok, limited := http.StatusOK, http.StatusTooManyRequests
+
+	// 固定窗口和滑动窗口：每个窗口最多 Rate 个请求
+	fixed := newLimiter(middleware.StrategyFixedWindow, 3, 0, time.Minute)
+	assert.Equal(t, []int{ok, ok, ok, limited}, hit(fixed, 4))
+	status := fixed.Status("192.0.2.1")
+	assert.Equal(t, 3, status.Limit)
+	assert.Greater(t, status.RetryAfter, time.Duration(0))
+	assert.LessOrEqual(t, status.RetryAfter, time.Minute)
+
+	sliding := newLimiter(middleware.StrategySlidingWindow, 2, 0, time.Minute)
+	assert.Equal(t, []int{ok, ok, limited}, hit(sliding, 3))
+	assert.InDelta(t, time.Minute.Seconds(), sliding.Status("192.0.2.1").RetryAfter.Seconds(), 1)
+
+	// GCRA：突发 Burst 个后按平均间隔放行，RetryAfter 约为一个间隔
+	gcra := newLimiter(middleware.StrategyGCRA, 1, 2, 0)
+	assert.Equal(t, []int{ok, ok, limited}, hit(gcra, 3))
+	status = gcra.Status("192.0.2.1")
+	assert.Equal(t, 2, status.Limit)
+	assert.InDelta(t, 1, status.RetryAfter.Seconds(), 0.1)
+
+	// 默认令牌桶：Burst 为桶容量
+	bucket := newLimiter("", 1, 2, 0)
+	assert.Equal(t, []int{ok, ok, limited}, hit(bucket, 3))
+
+	// 不同键互不影响
+	bucket.Reset("192.0.2.1")
+	assert.Equal(t, 2, bucket.Status("192.0.2.1").Remaining)
+	assert.Equal(t, 2, bucket.Status("192.0.2.2").Remaining)
+
+	assert.Panics(t, func() { newLimiter("leaky", 1, 1, 0) })
+}
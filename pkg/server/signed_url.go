@@ -0,0 +1,52 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+	"github.com/hwh/hwhkit-go/pkg/utils"
+)
+
+// newURLSigner 创建签名URL生成器，未配置密钥时由JWT密钥派生，避免直接复用JWT签名密钥
+func newURLSigner(cfg *config.Config) *utils.URLSigner {
+	key := cfg.Server.URLSigningKey
+	if key == "" {
+		mac := hmac.New(sha256.New, []byte(cfg.JWT.Secret))
+		mac.Write([]byte("hwhkit-signed-url"))
+		key = hex.EncodeToString(mac.Sum(nil))
+	}
+	return utils.NewURLSigner(key)
+}
+
+// URLSigner 获取签名URL生成器
+func (s *Server) URLSigner() *utils.URLSigner {
+	return s.urlSigner
+}
+
+// SignURL 生成 ttl 后过期的签名URL，userID 非空时链接绑定该用户
+//
+//	link, _ := srv.SignURL("/downloads/reports/2024-01.pdf", 10*time.Minute, userID)
+func (s *Server) SignURL(path string, ttl time.Duration, userID string) (string, error) {
+	return s.urlSigner.Sign(path, ttl, userID)
+}
+
+// SignedURL 签名URL校验中间件，用于自定义的文件流路由
+// 绑定了用户的链接要求当前登录用户与之一致，需放在JWT中间件之后，否则绑定用户的链接一律拒绝
+func (s *Server) SignedURL() gin.HandlerFunc {
+	return middleware.SignedURL(&middleware.SignedURLConfig{Signer: s.urlSigner, MatchUser: true})
+}
+
+// StaticSigned 注册只能通过签名URL访问的静态文件目录，配置了认证时先解析可选的JWT以校验链接绑定的用户
+func (s *Server) StaticSigned(relativePath, root string) gin.IRoutes {
+	var handlers []gin.HandlerFunc
+	if s.auth != nil {
+		handlers = append(handlers, middleware.JWTOptional(middleware.DefaultJWTConfig(s.auth)))
+	}
+	handlers = append(handlers, s.SignedURL())
+	return s.engine.Group(relativePath, handlers...).Static("/", root)
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+)
+
+// StaticOptions 静态文件目录选项
+type StaticOptions struct {
+	// CacheMaxAge 大于0时输出 Cache-Control 的 max-age，需要认证的目录使用 private；小于0时输出 no-store
+	CacheMaxAge time.Duration
+	// RequireAuth 仅允许已登录用户访问：有效的JWT或已登录的页面会话，否则返回401
+	RequireAuth bool
+	// Listing 允许目录列表，关闭时没有 index.html 的目录返回404
+	Listing bool
+	// CORSOrigins 允许跨域访问的来源，为空时不输出CORS头
+	CORSOrigins []string
+	// RateLimit 每个IP每秒请求数，0为不限流
+	RateLimit int
+}
+
+// StaticWithOptions 注册带缓存头、访问控制、跨域和限流选项的静态文件目录
+//
+//	srv.StaticWithOptions("/downloads", "./data/downloads", server.StaticOptions{RequireAuth: true, CacheMaxAge: time.Hour})
+func (s *Server) StaticWithOptions(relativePath, root string, opts StaticOptions) gin.IRoutes {
+	var fs http.FileSystem = http.Dir(root)
+	if opts.Listing {
+		fs = gin.Dir(root, true)
+	}
+	return s.StaticFSWithOptions(relativePath, fs, opts)
+}
+
+// StaticFSWithOptions 注册带选项的文件系统，Listing 为 false 时屏蔽 fs 的目录列表
+func (s *Server) StaticFSWithOptions(relativePath string, fs http.FileSystem, opts StaticOptions) gin.IRoutes {
+	if !opts.Listing {
+		fs = noListingFS{fs: fs}
+	}
+
+	group := s.engine.Group(relativePath, s.staticHandlers(opts)...)
+	if len(opts.CORSOrigins) > 0 {
+		// 预检请求由CORS中间件直接响应
+		group.OPTIONS("/*filepath", func(c *gin.Context) {})
+	}
+	return group.StaticFS("/", fs)
+}
+
+// setupStaticPaths 按 Server.StaticPaths 配置注册静态文件目录
+func (s *Server) setupStaticPaths() {
+	for _, static := range s.config.Server.StaticPaths {
+		s.StaticWithOptions(static.Path, static.Root, staticOptionsFromConfig(static))
+	}
+}
+
+// staticOptionsFromConfig 将配置转换为静态文件目录选项
+func staticOptionsFromConfig(static config.StaticPathConfig) StaticOptions {
+	return StaticOptions{
+		CacheMaxAge: time.Duration(static.CacheMaxAge) * time.Second,
+		RequireAuth: static.RequireAuth,
+		Listing:     static.Listing,
+		CORSOrigins: static.CORSOrigins,
+		RateLimit:   static.RateLimit,
+	}
+}
+
+// staticHandlers 按选项组合静态文件目录的中间件：跨域、限流、认证、缓存头
+func (s *Server) staticHandlers(opts StaticOptions) []gin.HandlerFunc {
+	var handlers []gin.HandlerFunc
+	if len(opts.CORSOrigins) > 0 {
+		handlers = append(handlers, middleware.CORS(&middleware.CORSConfig{
+			AllowOrigins: opts.CORSOrigins,
+			AllowMethods: []string{http.MethodGet, http.MethodHead, http.MethodOptions},
+			AllowHeaders: []string{"Authorization", "Range"},
+			MaxAge:       12 * time.Hour,
+		}))
+	}
+	if opts.RateLimit > 0 {
+		limiter := middleware.NewIPRateLimiter(opts.RateLimit, opts.RateLimit)
+		s.rateLimiters = append(s.rateLimiters, limiter)
+		handlers = append(handlers, limiter.Middleware())
+	}
+	if opts.RequireAuth {
+		if s.middleware != nil {
+			handlers = append(handlers, s.middleware.JWTOptional())
+		}
+		handlers = append(handlers, s.requireStaticUser)
+	}
+
+	cacheControl := staticCacheControl(opts)
+	if cacheControl != "" {
+		handlers = append(handlers, func(c *gin.Context) {
+			c.Header("Cache-Control", cacheControl)
+			c.Next()
+		})
+	}
+	return handlers
+}
+
+// requireStaticUser 要求请求携带有效的JWT或已登录的页面会话
+func (s *Server) requireStaticUser(c *gin.Context) {
+	if _, ok := middleware.GetUserID(c); ok {
+		c.Next()
+		return
+	}
+	if s.getUserFromSession(c) != "" {
+		c.Next()
+		return
+	}
+	s.Error(c, http.StatusUnauthorized, "authentication required")
+	c.Abort()
+}
+
+// staticCacheControl 生成 Cache-Control 响应头，需要认证的文件不允许共享缓存
+func staticCacheControl(opts StaticOptions) string {
+	switch {
+	case opts.CacheMaxAge < 0:
+		return "no-store"
+	case opts.CacheMaxAge == 0:
+		return ""
+	case opts.RequireAuth:
+		return fmt.Sprintf("private, max-age=%d", int(opts.CacheMaxAge.Seconds()))
+	default:
+		return fmt.Sprintf("public, max-age=%d", int(opts.CacheMaxAge.Seconds()))
+	}
+}
+
+// noListingFS 屏蔽目录列表的文件系统，没有 index.html 的目录视为不存在
+type noListingFS struct {
+	fs http.FileSystem
+}
+
+// Open 实现 http.FileSystem 接口
+func (n noListingFS) Open(name string) (http.File, error) {
+	file, err := n.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		index, err := n.fs.Open(path.Join(name, "index.html"))
+		if err != nil {
+			file.Close()
+			return nil, os.ErrNotExist
+		}
+		index.Close()
+	}
+	return file, nil
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/metrics"
+)
+
+// Version 服务版本，可在构建时通过 -ldflags "-X github.com/hwh/hwhkit-go/pkg/server.Version=1.2.3" 设置
+var Version = "1.0.0"
+
+//go:embed templates/status.html
+var statusPageTemplate string
+
+// statusPage 状态页模板
+var statusPage = template.Must(template.New("status").Funcs(template.FuncMap{
+	"formatDateTime": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04:05")
+	},
+}).Parse(statusPageTemplate))
+
+// statusLatencyMetrics 状态页展示的依赖耗时指标
+var statusLatencyMetrics = []struct {
+	name   string
+	metric string
+}{
+	{"cache", "hwhkit_cache_command_duration_seconds"},
+	{"http_client", "hwhkit_http_client_request_duration_seconds"},
+}
+
+// StatusCheck 状态页中的单项健康检查
+type StatusCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"` // ok、degraded 或 error
+	Latency string `json:"latency,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// StatusErrors 时间窗口内的请求和错误数
+type StatusErrors struct {
+	Window       string `json:"window"`
+	Requests     int64  `json:"requests"`
+	ClientErrors int64  `json:"client_errors"` // 4xx
+	ServerErrors int64  `json:"server_errors"` // 5xx
+}
+
+// StatusLatency 依赖调用的平均耗时
+type StatusLatency struct {
+	Name   string `json:"name"`
+	Series string `json:"series"`
+	Count  uint64 `json:"count"`
+	Avg    string `json:"avg"`
+}
+
+// StatusReport 状态页数据
+type StatusReport struct {
+	Name        string          `json:"name"`
+	Status      string          `json:"status"`
+	Version     string          `json:"version"`
+	Environment string          `json:"environment"`
+	StartedAt   time.Time       `json:"started_at"`
+	Uptime      string          `json:"uptime"`
+	GeneratedAt time.Time       `json:"generated_at"`
+	Checks      []StatusCheck   `json:"checks"`
+	Errors      []StatusErrors  `json:"errors"`
+	Latencies   []StatusLatency `json:"latencies"`
+}
+
+// statusBucket 每分钟的请求计数
+type statusBucket struct {
+	minute       int64
+	requests     int64
+	clientErrors int64
+	serverErrors int64
+}
+
+// statusTracker 按分钟统计最近一小时的请求和错误数
+type statusTracker struct {
+	mu      sync.Mutex
+	buckets [60]statusBucket
+}
+
+// newStatusTracker 创建请求统计
+func newStatusTracker() *statusTracker {
+	return &statusTracker{}
+}
+
+// record 记录一次请求
+func (t *statusTracker) record(now time.Time, status int) {
+	minute := now.Unix() / 60
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	bucket := &t.buckets[minute%int64(len(t.buckets))]
+	if bucket.minute != minute {
+		*bucket = statusBucket{minute: minute}
+	}
+	bucket.requests++
+	switch {
+	case status >= 500:
+		bucket.serverErrors++
+	case status >= 400:
+		bucket.clientErrors++
+	}
+}
+
+// window 统计最近 minutes 分钟（含当前分钟）的请求和错误数
+func (t *statusTracker) window(now time.Time, minutes int) StatusErrors {
+	current := now.Unix() / 60
+	result := StatusErrors{Window: (time.Duration(minutes) * time.Minute).String()}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, bucket := range t.buckets {
+		if bucket.minute > current-int64(minutes) && bucket.minute <= current {
+			result.Requests += bucket.requests
+			result.ClientErrors += bucket.clientErrors
+			result.ServerErrors += bucket.serverErrors
+		}
+	}
+	return result
+}
+
+// middleware 在请求结束后按响应状态码计数
+func (t *statusTracker) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		t.record(time.Now(), c.Writer.Status())
+	}
+}
+
+// statusAuth 状态页认证：配置了账号时接受Basic认证，配置了认证管理器时接受admin角色的JWT
+func (s *Server) statusAuth() gin.HandlerFunc {
+	user, password := s.config.Server.StatusPageUser, s.config.Server.StatusPagePassword
+	return func(c *gin.Context) {
+		if user != "" {
+			if u, p, ok := c.Request.BasicAuth(); ok &&
+				subtle.ConstantTimeCompare([]byte(u), []byte(user)) == 1 &&
+				subtle.ConstantTimeCompare([]byte(p), []byte(password)) == 1 {
+				c.Next()
+				return
+			}
+		}
+		if s.auth != nil {
+			header := c.GetHeader("Authorization")
+			if token := strings.TrimPrefix(header, "Bearer "); token != header && token != "" {
+				if claims, err := s.auth.ValidateToken(token); err == nil && !claims.IsRefresh() && claims.HasAnyRole("admin") {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		if user != "" {
+			c.Header("WWW-Authenticate", `Basic realm="status", charset="UTF-8"`)
+		}
+		s.Error(c, http.StatusUnauthorized, "Unauthorized")
+		c.Abort()
+	}
+}
+
+// statusHandler 状态页，?format=json 时返回JSON
+func (s *Server) statusHandler(c *gin.Context) {
+	report := s.StatusReport(c.Request.Context())
+	if c.Query("format") == "json" {
+		s.Success(c, report)
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	if err := statusPage.Execute(c.Writer, report); err != nil && s.logger != nil {
+		s.logger.Errorf("Failed to render status page: %v", err)
+	}
+}
+
+// StatusReport 汇总状态页数据：组件健康检查及耗时、运行时长、最近的错误数和依赖平均耗时，健康检查使用 ctx
+func (s *Server) StatusReport(ctx context.Context) *StatusReport {
+	now := time.Now()
+	report := &StatusReport{
+		Name:        "hwhkit-go",
+		Status:      "ok",
+		Version:     Version,
+		Environment: s.config.Server.Mode,
+		StartedAt:   s.startedAt,
+		Uptime:      now.Sub(s.startedAt).Round(time.Second).String(),
+		GeneratedAt: now,
+		Checks:      []StatusCheck{},
+		Errors:      []StatusErrors{},
+		Latencies:   []StatusLatency{},
+	}
+
+	check := func(name string, health func() error) {
+		start := time.Now()
+		err := health()
+		result := StatusCheck{Name: name, Status: "ok", Latency: time.Since(start).Round(time.Microsecond).String()}
+		if err != nil {
+			result.Status = "error"
+			result.Error = err.Error()
+			report.Status = "error"
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	if s.db != nil {
+		check("database", s.db.WithContext(ctx).Health)
+	}
+	if s.cache != nil {
+		check("cache", s.cache.WithContext(ctx).Health)
+	}
+	for _, guard := range s.dependencies {
+		dependency := guard.Status()
+		result := StatusCheck{Name: dependency.Name, Status: "ok"}
+		if dependency.Degraded {
+			result.Status = "degraded"
+			result.Error = dependency.LastError
+			if report.Status == "ok" {
+				report.Status = "degraded"
+			}
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	if s.status != nil {
+		report.Errors = append(report.Errors, s.status.window(now, 5), s.status.window(now, 60))
+	}
+
+	snapshot := metrics.Default.Snapshot()
+	for _, m := range statusLatencyMetrics {
+		series, ok := snapshot[m.metric].(map[string]metrics.HistogramSnapshot)
+		if !ok {
+			continue
+		}
+		keys := make([]string, 0, len(series))
+		for key := range series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			histogram := series[key]
+			report.Latencies = append(report.Latencies, StatusLatency{
+				Name:   m.name,
+				Series: key,
+				Count:  histogram.Count,
+				Avg:    time.Duration(histogram.Avg * float64(time.Second)).Round(time.Microsecond).String(),
+			})
+		}
+	}
+	return report
+}
@@ -2,12 +2,21 @@ package server
 
 import (
 	"html/template"
+	"net/http"
+	"net/url"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/auth"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+	"github.com/hwh/hwhkit-go/pkg/utils"
 )
 
+// sessionCookieMaxAge 页面登录会话的有效期（秒）
+const sessionCookieMaxAge = 24 * 3600
+
 // TemplateManager 模板管理器
 type TemplateManager struct {
 	templateDir string
@@ -104,6 +113,15 @@ func (tm *TemplateManager) addDefaultFunctions() {
 		return strings.Title(s)
 	}
 	
+	tm.funcMap["slugify"] = func(s string) string {
+		return utils.Str.Slugify(s)
+	}
+	
+	tm.funcMap["markdown"] = func(s string) (template.HTML, error) {
+		html, err := utils.Markdown.ToHTML(s)
+		return template.HTML(html), err
+	}
+	
 	tm.funcMap["trim"] = func(s string) string {
 		return strings.TrimSpace(s)
 	}
@@ -193,8 +211,8 @@ func (s *Server) SetupTemplateRoutes() {
 		pages.GET("/profile", s.handleProfilePage)
 	}
 	
-	// 表单处理路由
-	forms := s.engine.Group("/forms")
+	// 表单处理路由，限制请求体大小并拒绝跨站提交（登录CSRF）
+	forms := s.engine.Group("/forms", middleware.BodyLimit(1<<20), middleware.SameOrigin(s.config.Server.CORSAllowOrigins...))
 	{
 		forms.POST("/login", s.handleLoginForm)
 		forms.POST("/register", s.handleRegisterForm)
@@ -220,6 +238,7 @@ func (s *Server) handleLoginPage(c *gin.Context) {
 	data := gin.H{
 		"title": "Login - HWHKit-Go",
 		"error": c.Query("error"),
+		"next":  c.Query("next"), // 登录表单以隐藏字段 next 提交
 	}
 	
 	c.HTML(http.StatusOK, "auth/login.html", data)
@@ -272,21 +291,32 @@ func (s *Server) handleProfilePage(c *gin.Context) {
 // 表单处理器
 
 // handleLoginForm 登录表单处理器
+// 登录成功后跳转到 next（如OIDC授权页），只接受站内相对路径，否则跳转到 /dashboard
 func (s *Server) handleLoginForm(c *gin.Context) {
 	username := c.PostForm("username")
 	password := c.PostForm("password")
+	next := c.PostForm("next")
+	if next == "" {
+		next = c.Query("next")
+	}
 	
 	// 验证用户
 	if s.authenticateUser(username, password) {
-		// 创建会话
-		sessionID := s.createUserSession(username)
-		c.SetCookie("session_id", sessionID, 3600*24, "/", "", false, true)
+		// 绑定会话并更换会话ID（作废登录前的会话ID）
+		if _, err := middleware.LoginSession(c, username); err != nil {
+			c.Redirect(http.StatusFound, "/login?error=session_unavailable")
+			return
+		}
 		
-		c.Redirect(http.StatusFound, "/dashboard")
+		c.Redirect(http.StatusFound, safeRelayState(next))
 		return
 	}
 	
-	c.Redirect(http.StatusFound, "/login?error=invalid_credentials")
+	failure := "/login?error=invalid_credentials"
+	if next != "" {
+		failure += "&next=" + url.QueryEscape(next)
+	}
+	c.Redirect(http.StatusFound, failure)
 }
 
 // handleRegisterForm 注册表单处理器
@@ -313,10 +343,8 @@ func (s *Server) handleRegisterForm(c *gin.Context) {
 
 // handleLogoutForm 登出表单处理器
 func (s *Server) handleLogoutForm(c *gin.Context) {
-	sessionID, _ := c.Cookie("session_id")
-	if sessionID != "" {
-		s.destroyUserSession(sessionID)
-		c.SetCookie("session_id", "", -1, "/", "", false, true)
+	if err := middleware.LogoutSession(c); err != nil && s.logger != nil {
+		s.logger.WithError(err).Warn("Failed to destroy session")
 	}
 	
 	c.Redirect(http.StatusFound, "/")
@@ -324,42 +352,22 @@ func (s *Server) handleLogoutForm(c *gin.Context) {
 
 // 辅助方法
 
-// getUserFromSession 从会话获取用户
+// getUserFromSession 从会话中间件加载的会话获取用户，未登录时返回空字符串
 func (s *Server) getUserFromSession(c *gin.Context) string {
-	sessionID, err := c.Cookie("session_id")
-	if err != nil {
-		return ""
+	if session, exists := middleware.GetSession(c); exists {
+		return session.UserID
 	}
-	
-	// 从缓存中获取会话信息
-	userID, err := s.cache.Get("session:" + sessionID)
-	if err != nil {
-		return ""
-	}
-	
-	return userID
-}
-
-// createUserSession 创建用户会话
-func (s *Server) createUserSession(username string) string {
-	sessionID := generateSessionID()
-	
-	// 在实际应用中，这里应该存储用户ID而不是用户名
-	s.cache.Set("session:"+sessionID, username, time.Hour*24)
-	
-	return sessionID
-}
-
-// destroyUserSession 销毁用户会话
-func (s *Server) destroyUserSession(sessionID string) {
-	s.cache.Delete("session:" + sessionID)
+	return ""
 }
 
 // authenticateUser 验证用户
 func (s *Server) authenticateUser(username, password string) bool {
 	// 这里应该从数据库验证用户
 	// 为了演示，使用硬编码
-	return username == "admin" && password == "admin123"
+	// 使用常量时间比较，避免通过耗时猜测凭证
+	usernameOK := auth.SecureCompare(username, "admin")
+	passwordOK := auth.SecureCompare(password, "admin123")
+	return usernameOK && passwordOK
 }
 
 // createUser 创建用户
@@ -388,14 +396,3 @@ func (s *Server) getDashboardStats() gin.H {
 		"response_time":  "25ms",
 	}
 }
-
-// generateSessionID 生成会话ID
-func generateSessionID() string {
-	return generateRequestID() // 复用请求ID生成逻辑
-}
-
-import (
-	"net/http"
-	"strings"
-	"time"
-)
\ No newline at end of file
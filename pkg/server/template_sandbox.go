@@ -0,0 +1,391 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/template/parse"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/utils"
+)
+
+// ErrTemplateTimeout 模板渲染超时
+var ErrTemplateTimeout = errors.New("template render timeout")
+
+// ErrTemplateOutputTooLarge 模板输出超出限制
+var ErrTemplateOutputTooLarge = errors.New("template output too large")
+
+// ErrTemplateFormatTooWide printf 的宽度或精度超出限制
+var ErrTemplateFormatTooWide = errors.New("template printf width or precision too large")
+
+// blockedTemplateIdentifiers 沙箱中禁止使用的内置函数
+var blockedTemplateIdentifiers = map[string]bool{
+	"call": true, // 可调用数据中的任意函数
+}
+
+const (
+	// maxPrintfWidth printf 允许的最大宽度/精度，避免 %999999999d 一次分配大量内存
+	maxPrintfWidth = 256
+	// maxFuncOutputBytes 单次函数调用允许产生的最大字符串长度
+	maxFuncOutputBytes = 1 << 20
+	// sandboxTickFunc 注入到 range 循环体中的中止检查函数
+	sandboxTickFunc = "sandboxTick"
+)
+
+// SandboxConfig 模板沙箱配置，用于渲染用户提交的模板（如管理员编辑的邮件模板）
+type SandboxConfig struct {
+	Timeout         time.Duration    // 单次渲染超时，默认1秒
+	MaxOutputBytes  int              // 最大输出字节数，默认1MB
+	MaxTemplateSize int              // 模板源码最大字节数，默认64KB
+	Funcs           template.FuncMap // 额外允许的函数，会覆盖同名的安全函数
+}
+
+// TemplateSandbox 受限模板引擎，仅允许白名单函数
+type TemplateSandbox struct {
+	config SandboxConfig
+	funcs  template.FuncMap
+}
+
+// SandboxTemplate 沙箱中解析完成的模板
+type SandboxTemplate struct {
+	sandbox *TemplateSandbox
+	tmpl    *template.Template
+}
+
+// NewTemplateSandbox 创建模板沙箱
+func NewTemplateSandbox(cfg SandboxConfig) *TemplateSandbox {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Second
+	}
+	if cfg.MaxOutputBytes <= 0 {
+		cfg.MaxOutputBytes = 1 << 20
+	}
+	if cfg.MaxTemplateSize <= 0 {
+		cfg.MaxTemplateSize = 64 << 10
+	}
+
+	funcs := SafeTemplateFuncs()
+	for name, fn := range cfg.Funcs {
+		funcs[name] = fn
+	}
+
+	return &TemplateSandbox{
+		config: cfg,
+		funcs:  funcs,
+	}
+}
+
+// SafeTemplateFuncs 沙箱默认允许的函数集合（无副作用、不会放大输出）
+func SafeTemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"upper":    strings.ToUpper,
+		"lower":    strings.ToLower,
+		"trim":     strings.TrimSpace,
+		"contains": strings.Contains,
+		"replace": func(s, old, new string) (string, error) {
+			if n := strings.Count(s, old); len(s)+n*(len(new)-len(old)) > maxFuncOutputBytes {
+				return "", ErrTemplateOutputTooLarge
+			}
+			return strings.ReplaceAll(s, old, new), nil
+		},
+		"printf": boundedPrintf,
+		"formatDate": func(t time.Time, format string) string {
+			return t.Format(format)
+		},
+		"formatDateTime": func(t time.Time) string {
+			return t.Format("2006-01-02 15:04:05")
+		},
+		"now": time.Now,
+		"default": func(defaultValue, value interface{}) interface{} {
+			if value == nil || value == "" {
+				return defaultValue
+			}
+			return value
+		},
+		"truncate": func(s string, length int) string {
+			runes := []rune(s)
+			if length < 0 || len(runes) <= length {
+				return s
+			}
+			return string(runes[:length]) + "..."
+		},
+		"markdown": func(s string) (template.HTML, error) {
+			html, err := utils.Markdown.ToHTML(s)
+			return template.HTML(html), err
+		},
+	}
+}
+
+// Parse 解析并校验模板
+// 禁止 call、template、define/block 等可能执行任意函数或递归的结构
+func (sb *TemplateSandbox) Parse(name, text string) (*SandboxTemplate, error) {
+	if len(text) > sb.config.MaxTemplateSize {
+		return nil, fmt.Errorf("template %s exceeds max size of %d bytes", name, sb.config.MaxTemplateSize)
+	}
+
+	tmpl, err := template.New(name).Funcs(sb.funcs).Funcs(template.FuncMap{
+		sandboxTickFunc: func() string { return "" },
+	}).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+
+	// 禁止定义子模板
+	if len(tmpl.Templates()) > 1 {
+		return nil, fmt.Errorf("template %s: define/block is not allowed in sandbox", name)
+	}
+
+	if tmpl.Tree != nil && tmpl.Tree.Root != nil {
+		if err := checkSandboxNode(tmpl.Tree.Root); err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+		injectSandboxTicks(tmpl.Tree.Root)
+	}
+
+	return &SandboxTemplate{sandbox: sb, tmpl: tmpl}, nil
+}
+
+// checkSandboxNode 递归检查模板语法树
+func checkSandboxNode(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkSandboxNode(child); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return checkSandboxNode(n.Pipe)
+	case *parse.IfNode:
+		return checkSandboxBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		return checkSandboxBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return checkSandboxBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		return fmt.Errorf("template inclusion %q is not allowed in sandbox", n.Name)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			if err := checkSandboxNode(cmd); err != nil {
+				return err
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if err := checkSandboxNode(arg); err != nil {
+				return err
+			}
+		}
+	case *parse.ChainNode:
+		return checkSandboxNode(n.Node)
+	case *parse.IdentifierNode:
+		if blockedTemplateIdentifiers[n.Ident] {
+			return fmt.Errorf("function %q is not allowed in sandbox", n.Ident)
+		}
+	}
+	return nil
+}
+
+// injectSandboxTicks 在每个 range 循环体开头插入中止检查
+// 不产生输出的循环不会触发写入器，需要靠该检查在超时后尽快退出
+func injectSandboxTicks(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			injectSandboxTicks(child)
+		}
+	case *parse.IfNode:
+		injectSandboxTicks(n.List)
+		injectSandboxTicks(n.ElseList)
+	case *parse.WithNode:
+		injectSandboxTicks(n.List)
+		injectSandboxTicks(n.ElseList)
+	case *parse.RangeNode:
+		injectSandboxTicks(n.List)
+		injectSandboxTicks(n.ElseList)
+		if n.List != nil {
+			tick := &parse.ActionNode{
+				NodeType: parse.NodeAction,
+				Pos:      n.Pos,
+				Line:     n.Line,
+				Pipe: &parse.PipeNode{
+					NodeType: parse.NodePipe,
+					Pos:      n.Pos,
+					Line:     n.Line,
+					Cmds: []*parse.CommandNode{{
+						NodeType: parse.NodeCommand,
+						Pos:      n.Pos,
+						Args:     []parse.Node{parse.NewIdentifier(sandboxTickFunc).SetPos(n.Pos)},
+					}},
+				},
+			}
+			n.List.Nodes = append([]parse.Node{tick}, n.List.Nodes...)
+		}
+	}
+}
+
+// checkSandboxBranch 检查 if/range/with 分支
+func checkSandboxBranch(n *parse.BranchNode) error {
+	if err := checkSandboxNode(n.Pipe); err != nil {
+		return err
+	}
+	if err := checkSandboxNode(n.List); err != nil {
+		return err
+	}
+	if n.ElseList != nil {
+		return checkSandboxNode(n.ElseList)
+	}
+	return nil
+}
+
+// Render 渲染模板
+// 数据会先转换为JSON兼容结构，模板中无法调用数据对象上的方法
+func (t *SandboxTemplate) Render(ctx context.Context, data interface{}) (string, error) {
+	safeData, err := sandboxData(data)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.sandbox.config.Timeout)
+	defer cancel()
+
+	writer := &limitedWriter{limit: t.sandbox.config.MaxOutputBytes}
+
+	// 每次渲染使用独立副本，将中止检查绑定到本次的写入器
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	tmpl.Funcs(template.FuncMap{
+		sandboxTickFunc: func() (string, error) {
+			if writer.aborted.Load() {
+				return "", ErrTemplateTimeout
+			}
+			return "", nil
+		},
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("template panic: %v", r)
+			}
+		}()
+		done <- tmpl.Execute(writer, safeData)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			if errors.Is(err, ErrTemplateOutputTooLarge) {
+				return "", ErrTemplateOutputTooLarge
+			}
+			return "", fmt.Errorf("failed to render template: %w", err)
+		}
+		return writer.buf.String(), nil
+	case <-ctx.Done():
+		// 通知写入器中止，正在执行的模板会在下一次输出或下一轮循环时退出
+		writer.abort()
+		return "", ErrTemplateTimeout
+	}
+}
+
+// boundedPrintf 替代内置 printf，拒绝过大的宽度/精度和 * 形式的动态宽度
+func boundedPrintf(format string, args ...interface{}) (string, error) {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		// 跳过标志位和显式参数索引
+		for i < len(format) && (strings.IndexByte("+-# 0", format[i]) >= 0 || format[i] == '[') {
+			if format[i] == '[' {
+				end := strings.IndexByte(format[i:], ']')
+				if end < 0 {
+					break
+				}
+				i += end
+			}
+			i++
+		}
+		for _, part := range []bool{false, true} {
+			if part {
+				if i >= len(format) || format[i] != '.' {
+					break
+				}
+				i++
+			}
+			start := i
+			for i < len(format) && (format[i] >= '0' && format[i] <= '9' || format[i] == '*') {
+				i++
+			}
+			digits := format[start:i]
+			if strings.Contains(digits, "*") {
+				return "", ErrTemplateFormatTooWide
+			}
+			if digits != "" {
+				n, err := strconv.Atoi(digits)
+				if err != nil || n > maxPrintfWidth {
+					return "", ErrTemplateFormatTooWide
+				}
+			}
+		}
+	}
+	return fmt.Sprintf(format, args...), nil
+}
+
+// sandboxData 将数据转换为JSON兼容结构（map/slice/基本类型）
+func sandboxData(data interface{}) (interface{}, error) {
+	if data == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert template data: %w", err)
+	}
+	var result interface{}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to convert template data: %w", err)
+	}
+	return result, nil
+}
+
+// limitedWriter 限制输出大小并支持中止的写入器
+type limitedWriter struct {
+	buf     bytes.Buffer
+	limit   int
+	aborted atomic.Bool
+}
+
+// Write 实现 io.Writer 接口
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.aborted.Load() {
+		return 0, ErrTemplateTimeout
+	}
+	if w.buf.Len()+len(p) > w.limit {
+		return 0, ErrTemplateOutputTooLarge
+	}
+	return w.buf.Write(p)
+}
+
+// abort 中止后续写入
+func (w *limitedWriter) abort() {
+	w.aborted.Store(true)
+}
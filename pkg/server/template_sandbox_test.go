@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateSandbox_Render(t *testing.T) {
+	sandbox := NewTemplateSandbox(SandboxConfig{})
+
+	tmpl, err := sandbox.Parse("welcome", `Hello {{upper .name}}, you have {{len .items}} items{{range .items}} [{{.}}]{{end}}`)
+	require.NoError(t, err)
+
+	output, err := tmpl.Render(context.Background(), map[string]interface{}{
+		"name":  "alice",
+		"items": []string{"a", "b"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello ALICE, you have 2 items [a] [b]", output)
+}
+
+func TestTemplateSandbox_BlockedConstructs(t *testing.T) {
+	sandbox := NewTemplateSandbox(SandboxConfig{})
+
+	_, err := sandbox.Parse("call", `{{call .fn}}`)
+	assert.Error(t, err)
+
+	_, err = sandbox.Parse("define", `{{define "x"}}x{{end}}{{template "x"}}`)
+	assert.Error(t, err)
+
+	// 未在白名单中的函数无法解析
+	_, err = sandbox.Parse("unknown", `{{exec "rm"}}`)
+	assert.Error(t, err)
+
+	_, err = NewTemplateSandbox(SandboxConfig{MaxTemplateSize: 8}).Parse("big", strings.Repeat("x", 16))
+	assert.Error(t, err)
+}
+
+func TestTemplateSandbox_Limits(t *testing.T) {
+	sandbox := NewTemplateSandbox(SandboxConfig{MaxOutputBytes: 10})
+	tmpl, err := sandbox.Parse("loop", `{{range .items}}0123456789{{end}}`)
+	require.NoError(t, err)
+
+	_, err = tmpl.Render(context.Background(), map[string]interface{}{"items": []int{1, 2, 3}})
+	assert.ErrorIs(t, err, ErrTemplateOutputTooLarge)
+
+	slow := NewTemplateSandbox(SandboxConfig{
+		Timeout: 20 * time.Millisecond,
+		Funcs: map[string]interface{}{
+			"sleep": func() string { time.Sleep(200 * time.Millisecond); return "" },
+		},
+	})
+	tmpl, err = slow.Parse("slow", `{{sleep}}`)
+	require.NoError(t, err)
+
+	_, err = tmpl.Render(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrTemplateTimeout)
+}
+
+func TestTemplateSandbox_BoundedPrintf(t *testing.T) {
+	sandbox := NewTemplateSandbox(SandboxConfig{})
+
+	tmpl, err := sandbox.Parse("ok", `{{printf "%05d|%.2f|%s" 42 3.14159 "x"}}`)
+	require.NoError(t, err)
+	output, err := tmpl.Render(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "00042|3.14|x", output)
+
+	for _, text := range []string{
+		`{{printf "%999999999d" 1}}`,
+		`{{printf "%.999999999f" 1.0}}`,
+		`{{printf "%*d" 999999999 1}}`,
+	} {
+		tmpl, err := sandbox.Parse("wide", text)
+		require.NoError(t, err)
+		_, err = tmpl.Render(context.Background(), nil)
+		assert.ErrorIs(t, err, ErrTemplateFormatTooWide, text)
+	}
+
+	tmpl, err = sandbox.Parse("replace", `{{replace .s "" .s}}`)
+	require.NoError(t, err)
+	_, err = tmpl.Render(context.Background(), map[string]interface{}{"s": strings.Repeat("x", 4096)})
+	assert.ErrorIs(t, err, ErrTemplateOutputTooLarge)
+}
+
+func TestTemplateSandbox_TimeoutStopsSilentLoop(t *testing.T) {
+	var started, finished atomic.Int32
+	sandbox := NewTemplateSandbox(SandboxConfig{
+		Timeout: 20 * time.Millisecond,
+		Funcs: map[string]interface{}{
+			"mark": func() string { started.Add(1); return "" },
+			"nap":  func() string { time.Sleep(time.Millisecond); return "" },
+			"done": func() string { finished.Add(1); return "" },
+		},
+	})
+	// 循环体不产生输出，只能依赖注入的中止检查退出
+	tmpl, err := sandbox.Parse("silent", `{{mark}}{{range .items}}{{range $.items}}{{nap}}{{end}}{{end}}{{done}}`)
+	require.NoError(t, err)
+
+	items := make([]int, 200)
+	_, err = tmpl.Render(context.Background(), map[string]interface{}{"items": items})
+	assert.ErrorIs(t, err, ErrTemplateTimeout)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), started.Load())
+	assert.Equal(t, int32(0), finished.Load())
+
+	// 同一模板仍可再次渲染
+	output, err := tmpl.Render(context.Background(), map[string]interface{}{"items": []int{}})
+	require.NoError(t, err)
+	assert.Equal(t, "", output)
+}
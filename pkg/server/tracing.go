@@ -0,0 +1,56 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/hwh/hwhkit-go/pkg/tracing"
+)
+
+// setupTracing 初始化追踪，未传入追踪管理器且配置启用时按 Config.Tracing 创建，并为数据库和缓存注册追踪钩子
+func (s *Server) setupTracing(manager *tracing.Manager) error {
+	if manager == nil {
+		if !s.config.Tracing.Enabled {
+			return nil
+		}
+		created, err := tracing.New(&s.config.Tracing)
+		if err != nil {
+			return fmt.Errorf("failed to initialize tracing: %w", err)
+		}
+		manager = created
+	}
+	if !manager.Enabled() {
+		return nil
+	}
+
+	s.tracing = manager
+	if s.db != nil {
+		if err := s.db.EnableTracing(manager.Tracer()); err != nil {
+			return err
+		}
+	}
+	if s.cache != nil {
+		s.cache.EnableTracing(manager.Tracer())
+	}
+	return nil
+}
+
+// GetTracing 获取追踪管理器，未启用追踪时返回 nil
+func (s *Server) GetTracing() *tracing.Manager {
+	return s.tracing
+}
+
+// setupTiming 启用响应耗时头或调用计数时为数据库和缓存注册耗时分段统计
+func (s *Server) setupTiming() error {
+	if !s.config.Server.ServerTiming && !s.config.Server.CallCounts {
+		return nil
+	}
+	if s.db != nil {
+		if err := s.db.EnableTiming(); err != nil {
+			return err
+		}
+	}
+	if s.cache != nil {
+		s.cache.EnableTiming()
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/hwh/hwhkit-go/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := tracetest.NewSpanRecorder()
+	tracingConfig := &config.TracingConfig{Enabled: true}
+	manager := tracing.NewWithProvider(tracingConfig, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	server, err := New(&ServerConfig{
+		Config: &config.Config{
+			Server:  config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode},
+			Tracing: *tracingConfig,
+		},
+		Tracing: manager,
+	})
+	require.NoError(t, err)
+	assert.Same(t, manager, server.GetTracing())
+
+	var handlerSpan trace.SpanContext
+	server.GET("/items/:id", func(c *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+		server.Success(c, nil)
+	})
+	server.GET("/fail", func(c *gin.Context) {
+		server.Error(c, http.StatusInternalServerError, "boom")
+	})
+
+	// 沿用上游 traceparent，关联ID与追踪ID一致
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, traceID, w.Header().Get("X-Trace-Id"))
+	assert.Equal(t, traceID, handlerSpan.TraceID().String())
+
+	w = httptest.NewRecorder()
+	server.GetEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "GET /items/:id", spans[0].Name())
+	assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	assert.Equal(t, handlerSpan.SpanID(), spans[0].SpanContext().SpanID())
+
+	attrs := map[string]interface{}{}
+	for _, attr := range spans[0].Attributes() {
+		attrs[string(attr.Key)] = attr.Value.AsInterface()
+	}
+	assert.Equal(t, "/items/:id", attrs["http.route"])
+	assert.Equal(t, int64(http.StatusOK), attrs["http.status_code"])
+
+	assert.Equal(t, "GET /fail", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, spans[1].SpanContext().TraceID().String(), w.Header().Get("X-Trace-Id"))
+}
+
+func TestTracingDisabled(t *testing.T) {
+	server, err := New(&ServerConfig{
+		Config: &config.Config{
+			Server: config.ServerConfig{Host: "localhost", Port: 8080, Mode: gin.TestMode},
+		},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, server.GetTracing())
+}
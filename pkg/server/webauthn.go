@@ -0,0 +1,224 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/auth"
+	"github.com/hwh/hwhkit-go/pkg/auth/webauthn"
+	"github.com/hwh/hwhkit-go/pkg/middleware"
+)
+
+// WebAuthnUserProvider 通行密钥登录使用的用户查询
+type WebAuthnUserProvider interface {
+	// GetUserByID 按用户ID查询，凭证的 UserID 即为令牌中的用户ID
+	GetUserByID(id string) (*auth.User, error)
+	// GetUserByUsername 按用户名查询，用于登录时列出用户的凭证
+	GetUserByUsername(username string) (*auth.User, error)
+}
+
+// webAuthnHandler 通行密钥路由处理器
+type webAuthnHandler struct {
+	server *Server
+	wa     *webauthn.WebAuthn
+	users  WebAuthnUserProvider
+}
+
+// webAuthnRegisterRequest 完成注册请求
+type webAuthnRegisterRequest struct {
+	Name       string                        `json:"name"`
+	Credential webauthn.RegistrationResponse `json:"credential"`
+}
+
+// webAuthnLoginRequest 开始登录请求，用户名为空时使用可发现凭证登录
+type webAuthnLoginRequest struct {
+	Username string `json:"username"`
+}
+
+// SetupWebAuthnRoutes 注册通行密钥路由，需要配置认证管理器
+// 注册和凭证管理需要JWT认证；登录成功后签发与密码登录相同的令牌对，启用会话时同时登录页面会话
+//
+//	POST   /api/v1/auth/webauthn/register/begin    开始注册，返回 navigator.credentials.create 选项
+//	POST   /api/v1/auth/webauthn/register/finish   完成注册，请求体 {"name": "...", "credential": {...}}
+//	GET    /api/v1/auth/webauthn/credentials       列出当前用户的凭证
+//	DELETE /api/v1/auth/webauthn/credentials/:id   删除当前用户的凭证
+//	POST   /api/v1/auth/webauthn/login/begin       开始登录，请求体 {"username": "..."} 可选
+//	POST   /api/v1/auth/webauthn/login/finish      完成登录，请求体为 navigator.credentials.get 的结果
+//
+// 用户不存在或没有注册凭证时 login/begin 返回 password_fallback: true，客户端应改用密码登录，
+// 两种情况的响应相同，不会暴露用户是否存在
+func (s *Server) SetupWebAuthnRoutes(wa *webauthn.WebAuthn, users WebAuthnUserProvider) error {
+	if s.middleware == nil || s.authService == nil {
+		return errors.New("webauthn routes require an auth manager")
+	}
+	h := &webAuthnHandler{server: s, wa: wa, users: users}
+
+	group := s.engine.Group("/api/v1/auth/webauthn")
+	{
+		group.POST("/login/begin", h.beginLogin)
+		group.POST("/login/finish", h.finishLogin)
+
+		authed := group.Group("/", s.middleware.JWT())
+		authed.POST("/register/begin", h.beginRegistration)
+		authed.POST("/register/finish", h.finishRegistration)
+		authed.GET("/credentials", h.listCredentials)
+		authed.DELETE("/credentials/:id", h.deleteCredential)
+	}
+	return nil
+}
+
+// beginRegistration 开始注册
+func (h *webAuthnHandler) beginRegistration(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		h.server.Error(c, http.StatusUnauthorized, "no user information found")
+		return
+	}
+	options, err := h.wa.BeginRegistration(webauthn.User{ID: claims.UserID, Name: claims.Username})
+	if err != nil {
+		h.server.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.server.Success(c, gin.H{"publicKey": options})
+}
+
+// finishRegistration 完成注册
+func (h *webAuthnHandler) finishRegistration(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	var req webAuthnRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.server.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	credential, err := h.wa.FinishRegistration(userID, &req.Credential, req.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, webauthn.ErrCredentialExists):
+			h.server.Error(c, http.StatusConflict, err.Error())
+		case errors.Is(err, webauthn.ErrVerification), errors.Is(err, webauthn.ErrChallengeNotFound):
+			h.server.Error(c, http.StatusBadRequest, err.Error())
+		default:
+			h.server.Error(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	h.server.Success(c, credential)
+}
+
+// listCredentials 列出当前用户的凭证
+func (h *webAuthnHandler) listCredentials(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	credentials, err := h.wa.Credentials().ListCredentials(userID)
+	if err != nil {
+		h.server.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.server.Success(c, credentials)
+}
+
+// deleteCredential 删除当前用户的凭证
+func (h *webAuthnHandler) deleteCredential(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, err := webauthn.DecodeBase64URL(c.Param("id"))
+	if err != nil {
+		h.server.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.wa.Credentials().DeleteCredential(userID, id); err != nil {
+		if errors.Is(err, webauthn.ErrCredentialNotFound) {
+			h.server.Error(c, http.StatusNotFound, err.Error())
+			return
+		}
+		h.server.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.server.Success(c, gin.H{"id": c.Param("id"), "deleted": true})
+}
+
+// beginLogin 开始登录，用户不存在、已禁用或没有凭证时提示回退到密码登录
+func (h *webAuthnHandler) beginLogin(c *gin.Context) {
+	var req webAuthnLoginRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.server.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	userID := ""
+	if req.Username != "" {
+		user, err := h.users.GetUserByUsername(req.Username)
+		if err != nil || !user.IsActive {
+			h.server.Success(c, gin.H{"password_fallback": true})
+			return
+		}
+		userID = user.ID
+	}
+
+	options, err := h.wa.BeginLogin(userID)
+	if err != nil {
+		if errors.Is(err, webauthn.ErrNoCredentials) {
+			h.server.Success(c, gin.H{"password_fallback": true})
+			return
+		}
+		h.server.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.server.Success(c, gin.H{"publicKey": options, "password_fallback": false})
+}
+
+// finishLogin 完成登录并签发令牌对
+func (h *webAuthnHandler) finishLogin(c *gin.Context) {
+	var req webauthn.AssertionResponse
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.server.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	credential, err := h.wa.FinishLogin(&req)
+	if err != nil {
+		if h.server.logger != nil && errors.Is(err, webauthn.ErrSignCount) {
+			h.server.logger.Warnf("WebAuthn凭证 %s 签名计数器异常: %v", req.ID, err)
+		}
+		if errors.Is(err, webauthn.ErrVerification) || errors.Is(err, webauthn.ErrChallengeNotFound) || errors.Is(err, webauthn.ErrSignCount) {
+			h.server.Error(c, http.StatusUnauthorized, "passkey authentication failed")
+			return
+		}
+		h.server.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, err := h.users.GetUserByID(credential.UserID)
+	if err != nil {
+		auth.RecordLogin(auth.LoginResultUserNotFound)
+		h.server.Error(c, http.StatusUnauthorized, "passkey authentication failed")
+		return
+	}
+	if !user.IsActive {
+		auth.RecordLogin(auth.LoginResultDisabled)
+		h.server.Error(c, http.StatusForbidden, "user account is disabled")
+		return
+	}
+
+	tokens, err := h.server.authService.GetJWTManager().GenerateTokenPair(user)
+	if err != nil {
+		auth.RecordLogin(auth.LoginResultError)
+		h.server.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	auth.RecordLogin(auth.LoginResultSuccess)
+
+	// 与页面表单登录一致，会话中保存用户名；未启用会话时只返回令牌
+	if h.server.sessions != nil {
+		if _, err := middleware.LoginSession(c, user.Username); err != nil && h.server.logger != nil {
+			h.server.logger.WithError(err).Warn("Failed to bind session after passkey login")
+		}
+	}
+	h.server.Success(c, gin.H{
+		"tokens":        tokens,
+		"user":          gin.H{"id": user.ID, "username": user.Username, "roles": user.Roles},
+		"credential_id": credential.ID,
+	})
+}
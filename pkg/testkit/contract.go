@@ -0,0 +1,192 @@
+// Package testkit 提供应用集成测试辅助工具
+package testkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hwh/hwhkit-go/pkg/server"
+)
+
+// 契约测试用例类型
+const (
+	CaseUnauthorized = "unauthorized"
+	CaseHappyPath    = "happy_path"
+	CaseValidation   = "validation"
+)
+
+// ContractCase 契约测试用例
+type ContractCase struct {
+	Name       string
+	Kind       string
+	Method     string
+	Path       string
+	Body       interface{}
+	Header     http.Header
+	WantStatus []int // 期望的状态码，为空时接受任意2xx
+}
+
+// ContractOptions 契约测试生成选项
+type ContractOptions struct {
+	Token     func(roles []string) string // 为需要认证的路由生成访问令牌
+	SkipPaths []string                    // 跳过的路径前缀
+}
+
+// routeParamPattern 匹配gin路由参数 :id 和 *path
+var routeParamPattern = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// GenerateContractCases 根据路由元数据生成契约测试用例，未声明元数据的路由会被忽略
+// 每个路由生成：需要认证时的未认证用例、使用示例请求的正常用例、声明了非法请求时的校验失败用例
+func GenerateContractCases(routes []server.RouteInfo, opts ContractOptions) []ContractCase {
+	var cases []ContractCase
+	for _, route := range routes {
+		if route.Meta == nil || skipPath(route.Path, opts.SkipPaths) {
+			continue
+		}
+		meta := route.Meta
+		path := fillPathParams(route.Path, meta.PathParams)
+		name := route.Method + " " + route.Path
+
+		header := http.Header{}
+		if meta.AuthRequired {
+			cases = append(cases, ContractCase{
+				Name:       name + "/" + CaseUnauthorized,
+				Kind:       CaseUnauthorized,
+				Method:     route.Method,
+				Path:       path,
+				Body:       meta.SampleRequest,
+				Header:     http.Header{},
+				WantStatus: []int{http.StatusUnauthorized},
+			})
+			if opts.Token != nil {
+				header.Set("Authorization", "Bearer "+opts.Token(meta.Roles))
+			}
+		}
+
+		var wantStatus []int
+		if meta.SuccessStatus != 0 {
+			wantStatus = []int{meta.SuccessStatus}
+		}
+		cases = append(cases, ContractCase{
+			Name:       name + "/" + CaseHappyPath,
+			Kind:       CaseHappyPath,
+			Method:     route.Method,
+			Path:       path,
+			Body:       meta.SampleRequest,
+			Header:     header,
+			WantStatus: wantStatus,
+		})
+
+		if meta.InvalidRequest != nil {
+			cases = append(cases, ContractCase{
+				Name:       name + "/" + CaseValidation,
+				Kind:       CaseValidation,
+				Method:     route.Method,
+				Path:       path,
+				Body:       meta.InvalidRequest,
+				Header:     header,
+				WantStatus: []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+			})
+		}
+	}
+	return cases
+}
+
+// NewRequest 构造用例请求，非字符串请求体按JSON编码
+func (tc ContractCase) NewRequest() (*http.Request, error) {
+	var body io.Reader
+	switch v := tc.Body.(type) {
+	case nil:
+	case string:
+		body = strings.NewReader(v)
+	case []byte:
+		body = bytes.NewReader(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(tc.Method, tc.Path, body)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, values := range tc.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	return req, nil
+}
+
+// CheckStatus 检查响应状态码是否符合预期
+func (tc ContractCase) CheckStatus(status int) error {
+	if len(tc.WantStatus) == 0 {
+		if status >= 200 && status < 300 {
+			return nil
+		}
+		return fmt.Errorf("expected 2xx status, got %d", status)
+	}
+	for _, want := range tc.WantStatus {
+		if status == want {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected status %v, got %d", tc.WantStatus, status)
+}
+
+// RunContractTests 对处理器执行生成的契约测试，每个用例作为一个子测试
+//
+//	testkit.RunContractTests(t, srv.GetEngine(), srv.Routes(), testkit.ContractOptions{Token: issueToken})
+func RunContractTests(t *testing.T, handler http.Handler, routes []server.RouteInfo, opts ContractOptions) {
+	t.Helper()
+
+	cases := GenerateContractCases(routes, opts)
+	if len(cases) == 0 {
+		t.Skip("no routes with metadata")
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			req, err := tc.NewRequest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if err := tc.CheckStatus(w.Code); err != nil {
+				t.Errorf("%s: %v, body: %s", tc.Name, err, w.Body.String())
+			}
+		})
+	}
+}
+
+// fillPathParams 使用示例值替换路径参数，未提供时使用 1
+func fillPathParams(path string, params map[string]string) string {
+	return routeParamPattern.ReplaceAllStringFunc(path, func(match string) string {
+		if value, exists := params[match[1:]]; exists {
+			return value
+		}
+		return "1"
+	})
+}
+
+// skipPath 判断路径是否需要跳过
+func skipPath(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
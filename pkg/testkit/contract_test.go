@@ -0,0 +1,84 @@
+package testkit
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwh/hwhkit-go/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newContractEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+
+	engine.GET("/public/ping", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"pong": true})
+	})
+	engine.POST("/users/:id/notes", func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer token-user" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		var req struct {
+			Text string `json:"text" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"user": c.Param("id"), "text": req.Text})
+	})
+	return engine
+}
+
+func TestGenerateContractCases(t *testing.T) {
+	routes := []server.RouteInfo{
+		{Method: "GET", Path: "/public/ping", Meta: &server.RouteMeta{}},
+		{Method: "POST", Path: "/users/:id/notes", Meta: &server.RouteMeta{
+			AuthRequired:   true,
+			Roles:          []string{"user"},
+			PathParams:     map[string]string{"id": "42"},
+			SampleRequest:  map[string]string{"text": "hello"},
+			InvalidRequest: map[string]string{},
+			SuccessStatus:  http.StatusCreated,
+		}},
+		{Method: "GET", Path: "/undocumented"},
+	}
+
+	cases := GenerateContractCases(routes, ContractOptions{
+		Token: func(roles []string) string { return "token-" + roles[0] },
+	})
+	require.Len(t, cases, 4)
+
+	kinds := make([]string, 0, len(cases))
+	for _, tc := range cases {
+		kinds = append(kinds, tc.Kind)
+	}
+	assert.Equal(t, []string{CaseHappyPath, CaseUnauthorized, CaseHappyPath, CaseValidation}, kinds)
+	assert.Equal(t, "/users/42/notes", cases[1].Path)
+	assert.Empty(t, cases[1].Header.Get("Authorization"))
+	assert.Equal(t, "Bearer token-user", cases[2].Header.Get("Authorization"))
+
+	assert.NoError(t, cases[0].CheckStatus(http.StatusNoContent))
+	assert.Error(t, cases[2].CheckStatus(http.StatusOK))
+}
+
+func TestRunContractTests(t *testing.T) {
+	routes := []server.RouteInfo{
+		{Method: "GET", Path: "/public/ping", Meta: &server.RouteMeta{}},
+		{Method: "POST", Path: "/users/:id/notes", Meta: &server.RouteMeta{
+			AuthRequired:   true,
+			Roles:          []string{"user"},
+			SampleRequest:  map[string]string{"text": "hello"},
+			InvalidRequest: `{"text":`,
+			SuccessStatus:  http.StatusCreated,
+		}},
+	}
+
+	RunContractTests(t, newContractEngine(), routes, ContractOptions{
+		Token: func(roles []string) string { return "token-" + roles[0] },
+	})
+}
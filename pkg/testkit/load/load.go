@@ -0,0 +1,316 @@
+// Package load 提供进程内压测工具，用于在CI中检查中间件和接口的性能回归
+package load
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Target 压测目标
+type Target struct {
+	Method string
+	Path   string
+	Body   []byte
+	Header http.Header
+	Weight int // 请求混合权重，默认 1
+}
+
+// Config 压测配置
+type Config struct {
+	Targets     []Target
+	Rate        int           // 每秒请求数，为0时不限速
+	Duration    time.Duration // 压测时长，与 Requests 至少设置一个
+	Requests    int           // 请求总数，达到后停止
+	Concurrency int           // 并发数，默认 10
+	Timeout     time.Duration // 单个请求超时，默认 5s
+}
+
+// Report 压测报告
+type Report struct {
+	Requests    int
+	Errors      int
+	ErrorRate   float64
+	StatusCodes map[int]int
+	Duration    time.Duration
+	Throughput  float64 // 每秒完成请求数
+	Min         time.Duration
+	Mean        time.Duration
+	P50         time.Duration
+	P90         time.Duration
+	P95         time.Duration
+	P99         time.Duration
+	Max         time.Duration
+}
+
+// Thresholds 压测阈值，为0的项不检查
+type Thresholds struct {
+	P50          time.Duration
+	P95          time.Duration
+	P99          time.Duration
+	MaxErrorRate float64
+}
+
+// result 单次请求结果
+type result struct {
+	status  int
+	latency time.Duration
+	err     error
+}
+
+// Run 在进程内启动处理器并按配置发起请求
+// 状态码在 200-399 之外或请求失败计为错误
+func Run(ctx context.Context, handler http.Handler, cfg Config) (*Report, error) {
+	if len(cfg.Targets) == 0 {
+		return nil, errors.New("at least one target is required")
+	}
+	if cfg.Duration <= 0 && cfg.Requests <= 0 {
+		return nil, errors.New("duration or requests must be set")
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 10
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := server.Client()
+	client.Timeout = cfg.Timeout
+	transport := client.Transport.(*http.Transport)
+	transport.MaxIdleConnsPerHost = cfg.Concurrency
+
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	picker := newTargetPicker(cfg.Targets)
+	jobs := make(chan Target)
+	results := make(chan result, cfg.Concurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				results <- send(client, server.URL, target)
+			}
+		}()
+	}
+
+	start := time.Now()
+	go func() {
+		defer close(jobs)
+
+		var ticker *time.Ticker
+		if cfg.Rate > 0 {
+			ticker = time.NewTicker(time.Second / time.Duration(cfg.Rate))
+			defer ticker.Stop()
+		}
+		for sent := 0; cfg.Requests <= 0 || sent < cfg.Requests; sent++ {
+			if ticker != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- picker.next():
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var collected []result
+	for r := range results {
+		collected = append(collected, r)
+	}
+	return buildReport(collected, time.Since(start)), nil
+}
+
+// send 发送单个请求
+func send(client *http.Client, baseURL string, target Target) result {
+	method := target.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if target.Body != nil {
+		body = bytes.NewReader(target.Body)
+	}
+	req, err := http.NewRequest(method, baseURL+target.Path, body)
+	if err != nil {
+		return result{err: err}
+	}
+	for key, values := range target.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return result{latency: time.Since(start), err: err}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return result{status: resp.StatusCode, latency: time.Since(start)}
+}
+
+// targetPicker 按权重随机选择目标
+type targetPicker struct {
+	mu      sync.Mutex
+	rnd     *rand.Rand
+	targets []Target
+	weights []int
+	total   int
+}
+
+// newTargetPicker 创建目标选择器
+func newTargetPicker(targets []Target) *targetPicker {
+	p := &targetPicker{
+		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		targets: targets,
+	}
+	for _, target := range targets {
+		weight := target.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		p.total += weight
+		p.weights = append(p.weights, p.total)
+	}
+	return p
+}
+
+// next 选择下一个目标
+func (p *targetPicker) next() Target {
+	p.mu.Lock()
+	n := p.rnd.Intn(p.total)
+	p.mu.Unlock()
+
+	i := sort.SearchInts(p.weights, n+1)
+	return p.targets[i]
+}
+
+// buildReport 汇总请求结果
+func buildReport(results []result, elapsed time.Duration) *Report {
+	report := &Report{
+		Requests:    len(results),
+		StatusCodes: make(map[int]int),
+		Duration:    elapsed,
+	}
+	if len(results) == 0 {
+		return report
+	}
+
+	latencies := make([]time.Duration, 0, len(results))
+	var total time.Duration
+	for _, r := range results {
+		if r.err != nil || r.status < 200 || r.status >= 400 {
+			report.Errors++
+		}
+		if r.err == nil {
+			report.StatusCodes[r.status]++
+		}
+		latencies = append(latencies, r.latency)
+		total += r.latency
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	report.ErrorRate = float64(report.Errors) / float64(report.Requests)
+	if elapsed > 0 {
+		report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	}
+	report.Min = latencies[0]
+	report.Max = latencies[len(latencies)-1]
+	report.Mean = total / time.Duration(len(latencies))
+	report.P50 = percentile(latencies, 50)
+	report.P90 = percentile(latencies, 90)
+	report.P95 = percentile(latencies, 95)
+	report.P99 = percentile(latencies, 99)
+	return report
+}
+
+// percentile 计算已排序延迟的百分位（最近秩法）
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(float64(len(sorted))*p/100+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// Check 检查报告是否满足阈值
+func (r *Report) Check(thresholds Thresholds) error {
+	var violations []string
+	if thresholds.P50 > 0 && r.P50 > thresholds.P50 {
+		violations = append(violations, fmt.Sprintf("p50 %s > %s", r.P50, thresholds.P50))
+	}
+	if thresholds.P95 > 0 && r.P95 > thresholds.P95 {
+		violations = append(violations, fmt.Sprintf("p95 %s > %s", r.P95, thresholds.P95))
+	}
+	if thresholds.P99 > 0 && r.P99 > thresholds.P99 {
+		violations = append(violations, fmt.Sprintf("p99 %s > %s", r.P99, thresholds.P99))
+	}
+	if thresholds.MaxErrorRate > 0 && r.ErrorRate > thresholds.MaxErrorRate {
+		violations = append(violations, fmt.Sprintf("error rate %.4f > %.4f", r.ErrorRate, thresholds.MaxErrorRate))
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("load thresholds exceeded: %s", strings.Join(violations, ", "))
+	}
+	return nil
+}
+
+// String 格式化报告
+func (r *Report) String() string {
+	return fmt.Sprintf(
+		"requests=%d errors=%d (%.2f%%) throughput=%.1f/s latency min=%s mean=%s p50=%s p90=%s p95=%s p99=%s max=%s",
+		r.Requests, r.Errors, r.ErrorRate*100, r.Throughput,
+		r.Min, r.Mean, r.P50, r.P90, r.P95, r.P99, r.Max,
+	)
+}
+
+// RunT 在测试中执行压测，记录报告并在超过阈值时使测试失败
+//
+//	load.RunT(t, srv.GetEngine(), load.Config{Targets: targets, Requests: 1000}, load.Thresholds{P99: 20 * time.Millisecond})
+func RunT(t testing.TB, handler http.Handler, cfg Config, thresholds Thresholds) *Report {
+	t.Helper()
+
+	report, err := Run(context.Background(), handler, cfg)
+	if err != nil {
+		t.Fatalf("load test failed: %v", err)
+	}
+	t.Log(report)
+	if err := report.Check(thresholds); err != nil {
+		t.Error(err)
+	}
+	return report
+}
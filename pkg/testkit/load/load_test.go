@@ -0,0 +1,74 @@
+package load
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLoadHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	return mux
+}
+
+func TestRunRequestMix(t *testing.T) {
+	report, err := Run(context.Background(), newLoadHandler(), Config{
+		Targets: []Target{
+			{Method: http.MethodGet, Path: "/ok", Weight: 3},
+			{Method: http.MethodGet, Path: "/fail", Weight: 1},
+		},
+		Requests:    200,
+		Concurrency: 4,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 200, report.Requests)
+	assert.Equal(t, report.StatusCodes[http.StatusInternalServerError], report.Errors)
+	assert.Equal(t, 200, report.StatusCodes[http.StatusOK]+report.StatusCodes[http.StatusInternalServerError])
+	assert.InDelta(t, 0.25, report.ErrorRate, 0.15)
+	assert.True(t, report.Min <= report.P50 && report.P50 <= report.P99 && report.P99 <= report.Max)
+
+	assert.Error(t, report.Check(Thresholds{MaxErrorRate: 0.01}))
+	assert.NoError(t, report.Check(Thresholds{P99: time.Minute}))
+}
+
+func TestRunRateAndDuration(t *testing.T) {
+	report, err := Run(context.Background(), newLoadHandler(), Config{
+		Targets:  []Target{{Path: "/ok"}},
+		Rate:     100,
+		Duration: 200 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	assert.Greater(t, report.Requests, 5)
+	assert.LessOrEqual(t, report.Requests, 25)
+	assert.Zero(t, report.Errors)
+}
+
+func TestRunValidation(t *testing.T) {
+	_, err := Run(context.Background(), newLoadHandler(), Config{Requests: 1})
+	assert.Error(t, err)
+
+	_, err = Run(context.Background(), newLoadHandler(), Config{Targets: []Target{{Path: "/ok"}}})
+	assert.Error(t, err)
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for i := range latencies {
+		latencies[i] = time.Duration(i+1) * time.Millisecond
+	}
+	assert.Equal(t, 50*time.Millisecond, percentile(latencies, 50))
+	assert.Equal(t, 99*time.Millisecond, percentile(latencies, 99))
+	assert.Equal(t, 100*time.Millisecond, percentile(latencies, 100))
+}
@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// InstrumentationName HTTP、GORM、Redis 埋点使用的 Tracer 名称
+const InstrumentationName = "github.com/hwh/hwhkit-go"
+
+// Manager 追踪管理器，持有 TracerProvider 并负责关闭时导出剩余的span
+type Manager struct {
+	config   *config.TracingConfig
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+}
+
+// New 创建追踪管理器
+// 启用时设置全局 TracerProvider 和 W3C TraceContext/Baggage 传播器；配置了 Endpoint 时通过 OTLP/HTTP 批量导出span，
+// 未配置时仍生成追踪上下文，用于日志关联。未启用时返回使用 noop Tracer 的管理器
+func New(cfg *config.TracingConfig) (*Manager, error) {
+	if cfg == nil || !cfg.Enabled {
+		return &Manager{config: cfg, tracer: noop.NewTracerProvider().Tracer(InstrumentationName)}, nil
+	}
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		return nil, fmt.Errorf("tracing sample rate %v is out of range 0-1", cfg.SampleRate)
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "hwhkit-go"
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build tracing resource: %w", err)
+	}
+
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRate))),
+	}
+	if cfg.Endpoint != "" {
+		exporterOptions := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.URLPath != "" {
+			exporterOptions = append(exporterOptions, otlptracehttp.WithURLPath(cfg.URLPath))
+		}
+		if cfg.Insecure {
+			exporterOptions = append(exporterOptions, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(context.Background(), exporterOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+
+	return NewWithProvider(cfg, sdktrace.NewTracerProvider(options...)), nil
+}
+
+// NewWithProvider 使用已创建的 TracerProvider 创建追踪管理器，并设置为全局 TracerProvider
+// 适用于自定义导出器或测试中使用 tracetest.SpanRecorder 的场景
+func NewWithProvider(cfg *config.TracingConfig, provider *sdktrace.TracerProvider) *Manager {
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return &Manager{
+		config:   cfg,
+		provider: provider,
+		tracer:   provider.Tracer(InstrumentationName),
+	}
+}
+
+// Enabled 是否启用追踪
+func (m *Manager) Enabled() bool {
+	return m.provider != nil
+}
+
+// Tracer 获取 Tracer，未启用时返回 noop Tracer
+func (m *Manager) Tracer() trace.Tracer {
+	return m.tracer
+}
+
+// Shutdown 导出剩余的span并关闭 TracerProvider
+func (m *Manager) Shutdown(ctx context.Context) error {
+	if m.provider == nil {
+		return nil
+	}
+	if err := m.provider.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shutdown tracer provider: %w", err)
+	}
+	return nil
+}
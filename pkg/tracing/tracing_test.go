@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hwh/hwhkit-go/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewDisabled(t *testing.T) {
+	manager, err := New(&config.TracingConfig{})
+	require.NoError(t, err)
+	assert.False(t, manager.Enabled())
+
+	_, span := manager.Tracer().Start(context.Background(), "noop")
+	assert.False(t, span.SpanContext().IsValid())
+	span.End()
+	assert.NoError(t, manager.Shutdown(context.Background()))
+}
+
+func TestNewWithoutExporter(t *testing.T) {
+	manager, err := New(&config.TracingConfig{Enabled: true, ServiceName: "test", SampleRate: 1})
+	require.NoError(t, err)
+	defer manager.Shutdown(context.Background())
+	assert.True(t, manager.Enabled())
+
+	// 未配置导出地址时仍生成追踪上下文，用于日志关联
+	_, span := manager.Tracer().Start(context.Background(), "request")
+	assert.True(t, span.SpanContext().IsValid())
+	assert.True(t, span.SpanContext().IsSampled())
+	span.End()
+
+	_, err = New(&config.TracingConfig{Enabled: true, SampleRate: 1.5})
+	assert.Error(t, err)
+}
+
+func TestSampleRate(t *testing.T) {
+	manager, err := New(&config.TracingConfig{Enabled: true, SampleRate: 0})
+	require.NoError(t, err)
+	defer manager.Shutdown(context.Background())
+
+	_, root := manager.Tracer().Start(context.Background(), "root")
+	assert.False(t, root.SpanContext().IsSampled())
+	root.End()
+
+	// 上游已采样的请求沿用上游结果
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), parent)
+	_, child := manager.Tracer().Start(ctx, "child")
+	assert.True(t, child.SpanContext().IsSampled())
+	assert.Equal(t, parent.TraceID(), child.SpanContext().TraceID())
+	child.End()
+}
+
+func TestNewWithProvider(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	manager := NewWithProvider(&config.TracingConfig{Enabled: true}, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer manager.Shutdown(context.Background())
+
+	_, span := manager.Tracer().Start(context.Background(), "work")
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "work", spans[0].Name())
+	assert.Equal(t, InstrumentationName, spans[0].InstrumentationScope().Name)
+}
@@ -0,0 +1,308 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FieldEncryptionAlgorithm 字段加密算法：RSA-OAEP(SHA-256) 包装随机生成的 AES-256 密钥，字段使用 AES-256-GCM 加密
+const FieldEncryptionAlgorithm = "RSA-OAEP-256+A256GCM"
+
+var (
+	// ErrUnknownEncryptionKey 密钥ID不存在（可能已轮换淘汰）
+	ErrUnknownEncryptionKey = errors.New("unknown encryption key")
+	// ErrDecryptionFailed 密文格式错误或校验失败
+	ErrDecryptionFailed = errors.New("decryption failed")
+)
+
+// EncryptionKey 字段加密使用的RSA密钥对
+type EncryptionKey struct {
+	ID         string
+	PrivateKey *rsa.PrivateKey
+	CreatedAt  time.Time
+}
+
+// PublicKeyInfo 分发给客户端的公钥信息
+type PublicKeyInfo struct {
+	KeyID     string    `json:"kid"`
+	Algorithm string    `json:"alg"`
+	PublicKey string    `json:"public_key"` // PEM格式（PKIX）
+	CreatedAt time.Time `json:"created_at"`
+	Current   bool      `json:"current"` // 客户端应使用当前密钥加密
+}
+
+// RSAPublicKey 解析公钥，供客户端或服务间调用加密使用
+func (p PublicKeyInfo) RSAPublicKey() (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(p.PublicKey))
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+	return key, nil
+}
+
+// KeyRing 字段加密密钥环，最新加入的密钥为当前密钥，轮换后旧密钥在保留数量内仍可解密，保证客户端缓存的公钥平滑过渡
+// 多实例部署时各实例应通过 AddKey 加载相同的密钥，Rotate 生成的密钥只存在于当前进程
+type KeyRing struct {
+	mu        sync.RWMutex
+	keys      []*EncryptionKey // 按加入顺序，最后一个为当前密钥
+	retention int
+	keySize   int
+}
+
+// NewKeyRing 创建密钥环，默认保留2个密钥（当前和上一个），新生成的密钥为2048位
+func NewKeyRing() *KeyRing {
+	return &KeyRing{retention: 2, keySize: 2048}
+}
+
+// SetRetention 设置保留的密钥数量（含当前密钥），超出时淘汰最早的密钥
+func (k *KeyRing) SetRetention(retention int) {
+	if retention <= 0 {
+		return
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.retention = retention
+	k.pruneLocked()
+}
+
+// SetKeySize 设置 Rotate 生成的RSA密钥位数
+func (k *KeyRing) SetKeySize(bits int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keySize = bits
+}
+
+// AddKey 加载PEM格式（PKCS#1或PKCS#8）的RSA私钥并设为当前密钥，id 为空时使用公钥指纹
+func (k *KeyRing) AddKey(id, privateKeyPEM string) (*EncryptionKey, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		var ok bool
+		if privateKey, ok = parsed.(*rsa.PrivateKey); !ok {
+			return nil, errors.New("private key is not an RSA key")
+		}
+	}
+	return k.add(id, privateKey)
+}
+
+// Rotate 生成新的RSA密钥并设为当前密钥
+func (k *KeyRing) Rotate() (*EncryptionKey, error) {
+	k.mu.RLock()
+	bits := k.keySize
+	k.mu.RUnlock()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate encryption key: %w", err)
+	}
+	return k.add("", privateKey)
+}
+
+// Retire 立即淘汰指定密钥（如密钥泄露），不能淘汰当前密钥
+func (k *KeyRing) Retire(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for i, key := range k.keys {
+		if key.ID != id {
+			continue
+		}
+		if i == len(k.keys)-1 {
+			return errors.New("cannot retire the current encryption key")
+		}
+		k.keys = append(k.keys[:i], k.keys[i+1:]...)
+		return nil
+	}
+	return ErrUnknownEncryptionKey
+}
+
+// Current 获取当前密钥，密钥环为空时返回nil
+func (k *KeyRing) Current() *EncryptionKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if len(k.keys) == 0 {
+		return nil
+	}
+	return k.keys[len(k.keys)-1]
+}
+
+// Key 按ID获取密钥
+func (k *KeyRing) Key(id string) (*EncryptionKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownEncryptionKey
+}
+
+// PublicKeys 获取所有保留密钥的公钥，当前密钥在前
+func (k *KeyRing) PublicKeys() []PublicKeyInfo {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	infos := make([]PublicKeyInfo, 0, len(k.keys))
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		key := k.keys[i]
+		der, err := x509.MarshalPKIXPublicKey(&key.PrivateKey.PublicKey)
+		if err != nil {
+			continue
+		}
+		infos = append(infos, PublicKeyInfo{
+			KeyID:     key.ID,
+			Algorithm: FieldEncryptionAlgorithm,
+			PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+			CreatedAt: key.CreatedAt,
+			Current:   i == len(k.keys)-1,
+		})
+	}
+	return infos
+}
+
+// UnwrapKey 解析 WrapKey 生成的 <kid>.<Base64URL密文>，用对应私钥解出字段加密密钥
+func (k *KeyRing) UnwrapKey(wrapped string) ([]byte, error) {
+	id, encoded, ok := strings.Cut(wrapped, ".")
+	if !ok {
+		return nil, ErrDecryptionFailed
+	}
+	key, err := k.Key(id)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	fieldKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key.PrivateKey, ciphertext, nil)
+	if err != nil || len(fieldKey) != 32 {
+		return nil, ErrDecryptionFailed
+	}
+	return fieldKey, nil
+}
+
+// add 加入密钥并淘汰超出保留数量的旧密钥
+func (k *KeyRing) add(id string, privateKey *rsa.PrivateKey) (*EncryptionKey, error) {
+	if id == "" {
+		der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal public key: %w", err)
+		}
+		sum := sha256.Sum256(der)
+		id = hex.EncodeToString(sum[:8])
+	}
+	if strings.Contains(id, ".") {
+		return nil, errors.New("encryption key id must not contain '.'")
+	}
+
+	key := &EncryptionKey{ID: id, PrivateKey: privateKey, CreatedAt: time.Now()}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for i, existing := range k.keys {
+		if existing.ID == id {
+			k.keys = append(k.keys[:i], k.keys[i+1:]...)
+			break
+		}
+	}
+	k.keys = append(k.keys, key)
+	k.pruneLocked()
+	return key, nil
+}
+
+// pruneLocked 淘汰超出保留数量的旧密钥，调用方需持有锁
+func (k *KeyRing) pruneLocked() {
+	if excess := len(k.keys) - k.retention; excess > 0 {
+		k.keys = append([]*EncryptionKey(nil), k.keys[excess:]...)
+	}
+}
+
+// GenerateFieldKey 生成随机的 AES-256 字段加密密钥
+func GenerateFieldKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate field key: %w", err)
+	}
+	return key, nil
+}
+
+// WrapKey 用服务端公钥包装字段加密密钥，返回 <kid>.<Base64URL密文>，客户端放入 X-Encrypted-Key 请求头
+func WrapKey(publicKey *rsa.PublicKey, keyID string, fieldKey []byte) (string, error) {
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, fieldKey, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap field key: %w", err)
+	}
+	return keyID + "." + base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+// EncryptField 使用 AES-256-GCM 加密字段，返回 Base64URL(随机数+密文)
+// field 为字段名（如 card.number），作为附加认证数据绑定到密文，密文不能被挪到其他字段解密
+func EncryptField(fieldKey []byte, field string, plaintext []byte) (string, error) {
+	gcm, err := newFieldCipher(fieldKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, []byte(field))), nil
+}
+
+// DecryptField 解密 EncryptField 生成的字段密文，field 必须与加密时的字段名一致
+func DecryptField(fieldKey []byte, field, value string) ([]byte, error) {
+	gcm, err := newFieldCipher(fieldKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return nil, ErrDecryptionFailed
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(field))
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
+
+// newFieldCipher 创建 AES-256-GCM
+func newFieldCipher(fieldKey []byte) (cipher.AEAD, error) {
+	if len(fieldKey) != 32 {
+		return nil, errors.New("field key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(fieldKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
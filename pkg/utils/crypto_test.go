@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRing_FieldEncryption(t *testing.T) {
+	keys := NewKeyRing()
+	keys.SetKeySize(1024)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	first, err := keys.AddKey("2024-01", string(pemKey))
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01", keys.Current().ID)
+
+	// 客户端使用分发的公钥包装字段密钥并加密字段
+	infos := keys.PublicKeys()
+	require.Len(t, infos, 1)
+	assert.True(t, infos[0].Current)
+	assert.Equal(t, FieldEncryptionAlgorithm, infos[0].Algorithm)
+	publicKey, err := infos[0].RSAPublicKey()
+	require.NoError(t, err)
+
+	fieldKey, err := GenerateFieldKey()
+	require.NoError(t, err)
+	wrapped, err := WrapKey(publicKey, infos[0].KeyID, fieldKey)
+	require.NoError(t, err)
+	ciphertext, err := EncryptField(fieldKey, "card.number", []byte(`"6222021234567890"`))
+	require.NoError(t, err)
+
+	unwrapped, err := keys.UnwrapKey(wrapped)
+	require.NoError(t, err)
+	plaintext, err := DecryptField(unwrapped, "card.number", ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, `"6222021234567890"`, string(plaintext))
+
+	_, err = DecryptField(unwrapped, "card.number", ciphertext[:len(ciphertext)-2]+"AA")
+	assert.ErrorIs(t, err, ErrDecryptionFailed)
+	// 字段名作为附加认证数据，密文挪到其他字段后无法解密
+	_, err = DecryptField(unwrapped, "card.cvv", ciphertext)
+	assert.ErrorIs(t, err, ErrDecryptionFailed)
+
+	// 轮换后旧密钥在保留期内仍可解密，超出保留数量后淘汰
+	second, err := keys.Rotate()
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, keys.Current().ID)
+	assert.NotEqual(t, first.ID, second.ID)
+	infos = keys.PublicKeys()
+	require.Len(t, infos, 2)
+	assert.Equal(t, second.ID, infos[0].KeyID)
+	_, err = keys.UnwrapKey(wrapped)
+	assert.NoError(t, err)
+
+	assert.Error(t, keys.Retire(second.ID))
+	_, err = keys.Rotate()
+	require.NoError(t, err)
+	_, err = keys.UnwrapKey(wrapped)
+	assert.ErrorIs(t, err, ErrUnknownEncryptionKey)
+	assert.ErrorIs(t, keys.Retire(first.ID), ErrUnknownEncryptionKey)
+	assert.NoError(t, keys.Retire(second.ID))
+	assert.Len(t, keys.PublicKeys(), 1)
+}
@@ -13,7 +13,11 @@ import (
 
 // HTTPUtils HTTP工具集合
 type HTTPUtils struct {
-	client *http.Client
+	client  *http.Client
+	limiter *outboundLimiter
+	ssrf    *ssrfGuard    // 为空表示未开启SSRF防护
+	signer  RequestSigner // 为空表示不签名
+	pool    *poolTracker
 }
 
 // NewHTTPUtils 创建HTTP工具实例
@@ -22,6 +26,8 @@ func NewHTTPUtils() *HTTPUtils {
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		limiter: newOutboundLimiter(),
+		pool:    newPoolTracker(),
 	}
 }
 
@@ -31,6 +37,8 @@ func NewHTTPUtilsWithTimeout(timeout time.Duration) *HTTPUtils {
 		client: &http.Client{
 			Timeout: timeout,
 		},
+		limiter: newOutboundLimiter(),
+		pool:    newPoolTracker(),
 	}
 }
 
@@ -91,14 +99,14 @@ func (h *HTTPUtils) Post(url string, data interface{}, headers map[string]string
 }
 
 // PostForm 发送POST表单请求
-func (h *HTTPUtils) PostForm(url string, formData map[string]string, headers map[string]string) (*HTTPResponse, error) {
+func (h *HTTPUtils) PostForm(rawURL string, formData map[string]string, headers map[string]string) (*HTTPResponse, error) {
 	// 构建表单数据
 	values := make(url.Values)
 	for key, value := range formData {
 		values.Set(key, value)
 	}
 	
-	req, err := http.NewRequest("POST", url, strings.NewReader(values.Encode()))
+	req, err := http.NewRequest("POST", rawURL, strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create POST form request: %w", err)
 	}
@@ -192,13 +200,39 @@ func (h *HTTPUtils) Request(method, url string, body io.Reader, headers map[stri
 
 // doRequest 执行HTTP请求
 func (h *HTTPUtils) doRequest(req *http.Request) (*HTTPResponse, error) {
+	// SSRF防护：连接地址由传输层检查，这里提前拒绝不允许的协议和端口
+	if h.ssrf != nil {
+		if err := h.ssrf.checkURL(req.URL); err != nil {
+			return nil, err
+		}
+	}
+
+	if h.signer != nil {
+		if err := signRequest(req, h.signer); err != nil {
+			return nil, err
+		}
+	}
+
+	// 出站限流
+	if l := h.limiter.get(req.URL.Host); l != nil {
+		release, err := l.acquire(req.Context())
+		if err != nil {
+			return nil, fmt.Errorf("rate limit wait canceled: %w", err)
+		}
+		defer release()
+	}
+
+	// 连接池和各阶段耗时统计
+	req, finish := h.pool.track(req)
 	resp, err := h.client.Do(req)
 	if err != nil {
+		finish(0, err)
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 	
 	body, err := io.ReadAll(resp.Body)
+	finish(resp.StatusCode, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
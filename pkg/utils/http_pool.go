@@ -0,0 +1,285 @@
+package utils
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hwh/hwhkit-go/pkg/metrics"
+)
+
+var (
+	httpClientRequests = metrics.Default.Counter("hwhkit_http_client_requests_total",
+		"Total number of outbound HTTP requests", "host", "status")
+	httpClientDuration = metrics.Default.Histogram("hwhkit_http_client_request_duration_seconds",
+		"Outbound HTTP request latency in seconds, including reading the body", nil, "host")
+	httpClientDNS = metrics.Default.Histogram("hwhkit_http_client_dns_duration_seconds",
+		"Outbound HTTP DNS lookup latency in seconds", nil, "host")
+	httpClientConnect = metrics.Default.Histogram("hwhkit_http_client_connect_duration_seconds",
+		"Outbound HTTP TCP connect latency in seconds", nil, "host")
+	httpClientTLS = metrics.Default.Histogram("hwhkit_http_client_tls_duration_seconds",
+		"Outbound HTTP TLS handshake latency in seconds", nil, "host")
+	httpClientFirstByte = metrics.Default.Histogram("hwhkit_http_client_first_byte_seconds",
+		"Time from sending an outbound HTTP request to the first response byte in seconds", nil, "host")
+	httpClientConns = metrics.Default.Counter("hwhkit_http_client_conns_total",
+		"Total number of connections obtained from the pool", "host", "reused")
+	httpClientIdleConns = metrics.Default.Gauge("hwhkit_http_client_idle_conns",
+		"Estimated number of idle pooled connections", "host")
+	httpClientInFlight = metrics.Default.Gauge("hwhkit_http_client_in_flight",
+		"Number of outbound HTTP requests in flight", "host")
+)
+
+// maxTrackedHosts 按主机统计的最大主机数，超出后新主机归入 otherHost，避免请求用户提供的URL时指标标签无限增长
+const maxTrackedHosts = 200
+
+// otherHost 超出 maxTrackedHosts 的主机共用的统计键和指标标签
+const otherHost = "other"
+
+// trackedHosts 已分配指标标签的主机，所有 HTTPUtils 共用，与全局指标的标签集合一致
+var trackedHosts = struct {
+	mu    sync.Mutex
+	hosts map[string]struct{}
+}{hosts: make(map[string]struct{})}
+
+// hostLabel 返回主机的统计键和指标标签，主机数达到上限后未见过的主机返回 otherHost
+func hostLabel(host string) string {
+	trackedHosts.mu.Lock()
+	defer trackedHosts.mu.Unlock()
+
+	if _, exists := trackedHosts.hosts[host]; exists {
+		return host
+	}
+	if len(trackedHosts.hosts) >= maxTrackedHosts {
+		return otherHost
+	}
+	trackedHosts.hosts[host] = struct{}{}
+	return host
+}
+
+// HostPoolStats 单主机的连接池和请求耗时统计，平均值只统计发生过该阶段的请求
+type HostPoolStats struct {
+	Requests    int64         `json:"requests"`
+	Errors      int64         `json:"errors"`       // 连接失败或读取响应失败的请求数
+	InFlight    int64         `json:"in_flight"`    // 当前进行中的请求数
+	NewConns    int64         `json:"new_conns"`    // 新建连接数
+	ReusedConns int64         `json:"reused_conns"` // 复用连接数
+	IdleConns   int64         `json:"idle_conns"`   // 估算的空闲连接数，连接池超时关闭的连接不会扣减
+	AvgDNS      time.Duration `json:"avg_dns"`
+	AvgConnect  time.Duration `json:"avg_connect"`
+	AvgTLS      time.Duration `json:"avg_tls"`
+	AvgTTFB     time.Duration `json:"avg_ttfb"` // 发起请求到收到首字节
+	AvgLatency  time.Duration `json:"avg_latency"`
+	LastError   string        `json:"last_error,omitempty"`
+	LastErrorAt time.Time     `json:"last_error_at,omitempty"`
+}
+
+// ReuseRatio 连接复用率，复用率低通常说明连接池过小或对端频繁关闭连接
+func (s HostPoolStats) ReuseRatio() float64 {
+	total := s.NewConns + s.ReusedConns
+	if total == 0 {
+		return 0
+	}
+	return float64(s.ReusedConns) / float64(total)
+}
+
+// phaseTiming 单个阶段的累计耗时
+type phaseTiming struct {
+	total time.Duration
+	count int64
+}
+
+func (p *phaseTiming) add(d time.Duration) {
+	p.total += d
+	p.count++
+}
+
+func (p *phaseTiming) avg() time.Duration {
+	if p.count == 0 {
+		return 0
+	}
+	return p.total / time.Duration(p.count)
+}
+
+// hostPool 单主机统计
+type hostPool struct {
+	mu                               sync.Mutex
+	stats                            HostPoolStats
+	dns, connect, tls, ttfb, latency phaseTiming
+}
+
+// record 更新统计信息
+func (p *hostPool) record(fn func(p *hostPool)) {
+	p.mu.Lock()
+	fn(p)
+	p.mu.Unlock()
+}
+
+// snapshot 获取统计信息副本
+func (p *hostPool) snapshot() HostPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := p.stats
+	stats.AvgDNS = p.dns.avg()
+	stats.AvgConnect = p.connect.avg()
+	stats.AvgTLS = p.tls.avg()
+	stats.AvgTTFB = p.ttfb.avg()
+	stats.AvgLatency = p.latency.avg()
+	return stats
+}
+
+// poolTracker 按主机统计出站请求的连接池使用情况和各阶段耗时
+type poolTracker struct {
+	mu    sync.Mutex
+	hosts map[string]*hostPool
+}
+
+// newPoolTracker 创建连接池统计
+func newPoolTracker() *poolTracker {
+	return &poolTracker{hosts: make(map[string]*hostPool)}
+}
+
+// host 获取主机对应的统计
+func (t *poolTracker) host(host string) *hostPool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, exists := t.hosts[host]
+	if !exists {
+		p = &hostPool{}
+		t.hosts[host] = p
+	}
+	return p
+}
+
+// track 为请求挂载 httptrace，返回的函数在读取完响应体后调用
+func (t *poolTracker) track(req *http.Request) (*http.Request, func(statusCode int, err error)) {
+	host := hostLabel(req.URL.Host)
+	p := t.host(host)
+	start := time.Now()
+
+	p.record(func(p *hostPool) { p.stats.InFlight++ })
+	httpClientInFlight.Inc(host)
+
+	var mu sync.Mutex
+	var dnsStart, connectStart, tlsStart time.Time
+	observe := func(histogram *metrics.Histogram, phase func(p *hostPool) *phaseTiming, since *time.Time) {
+		mu.Lock()
+		begin := *since
+		mu.Unlock()
+		if begin.IsZero() {
+			return
+		}
+		d := time.Since(begin)
+		histogram.Observe(d.Seconds(), host)
+		p.record(func(p *hostPool) { phase(p).add(d) })
+	}
+	mark := func(at *time.Time) {
+		mu.Lock()
+		*at = time.Now()
+		mu.Unlock()
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { mark(&dnsStart) },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			observe(httpClientDNS, func(p *hostPool) *phaseTiming { return &p.dns }, &dnsStart)
+		},
+		ConnectStart: func(string, string) { mark(&connectStart) },
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				observe(httpClientConnect, func(p *hostPool) *phaseTiming { return &p.connect }, &connectStart)
+			}
+		},
+		TLSHandshakeStart: func() { mark(&tlsStart) },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				observe(httpClientTLS, func(p *hostPool) *phaseTiming { return &p.tls }, &tlsStart)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			httpClientConns.Inc(host, strconv.FormatBool(info.Reused))
+			if info.WasIdle {
+				httpClientIdleConns.Dec(host)
+			}
+			p.record(func(p *hostPool) {
+				if info.Reused {
+					p.stats.ReusedConns++
+				} else {
+					p.stats.NewConns++
+				}
+				if info.WasIdle && p.stats.IdleConns > 0 {
+					p.stats.IdleConns--
+				}
+			})
+		},
+		PutIdleConn: func(err error) {
+			if err != nil {
+				return
+			}
+			httpClientIdleConns.Inc(host)
+			p.record(func(p *hostPool) { p.stats.IdleConns++ })
+		},
+		GotFirstResponseByte: func() {
+			observe(httpClientFirstByte, func(p *hostPool) *phaseTiming { return &p.ttfb }, &start)
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	return req, func(statusCode int, err error) {
+		latency := time.Since(start)
+		status := strconv.Itoa(statusCode)
+		if err != nil {
+			status = "error"
+		}
+		httpClientRequests.Inc(host, status)
+		httpClientDuration.Observe(latency.Seconds(), host)
+		httpClientInFlight.Dec(host)
+
+		p.record(func(p *hostPool) {
+			p.stats.Requests++
+			p.stats.InFlight--
+			p.latency.add(latency)
+			if err != nil {
+				p.stats.Errors++
+				p.stats.LastError = err.Error()
+				p.stats.LastErrorAt = time.Now()
+			}
+		})
+	}
+}
+
+// GetPoolStats 获取各主机的连接池和耗时统计，键为请求URL的Host（含端口），超出主机数上限的请求合并在 "other" 中
+func (h *HTTPUtils) GetPoolStats() map[string]HostPoolStats {
+	h.pool.mu.Lock()
+	hosts := make(map[string]*hostPool, len(h.pool.hosts))
+	for host, p := range h.pool.hosts {
+		hosts[host] = p
+	}
+	h.pool.mu.Unlock()
+
+	stats := make(map[string]HostPoolStats, len(hosts))
+	for host, p := range hosts {
+		stats[host] = p.snapshot()
+	}
+	return stats
+}
+
+// SlowHosts 返回平均耗时超过 threshold 的主机，按平均耗时从高到低排序，用于定位拖慢服务的第三方依赖
+func (h *HTTPUtils) SlowHosts(threshold time.Duration) []string {
+	stats := h.GetPoolStats()
+	hosts := make([]string, 0, len(stats))
+	for host, s := range stats {
+		if s.AvgLatency > threshold {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return stats[hosts[i]].AvgLatency > stats[hosts[j]].AvgLatency
+	})
+	return hosts
+}
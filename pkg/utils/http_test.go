@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPUtils_RateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	h := NewHTTPUtils()
+	h.SetRateLimit(HostLimitConfig{Rate: 20, Burst: 2})
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		resp, err := h.Get(server.URL, nil)
+		assert.NoError(t, err)
+		assert.True(t, resp.IsSuccess())
+	}
+	// 突发2个之后，剩余2个请求各需等待约50ms
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+
+	u, _ := url.Parse(server.URL)
+	stats := h.GetRateLimitStats()[u.Host]
+	assert.Equal(t, int64(4), stats.Requests)
+	assert.Equal(t, int64(2), stats.Throttled)
+	assert.Equal(t, 0, stats.InFlight)
+}
+
+func TestHTTPUtils_ConcurrencyLimit(t *testing.T) {
+	var current, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&current, -1)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	h := NewHTTPUtils()
+	h.SetHostRateLimit(u.Host, HostLimitConfig{MaxConcurrency: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := h.Get(server.URL, nil)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(2))
+	assert.Equal(t, int64(6), h.GetRateLimitStats()[u.Host].Requests)
+}
+
+func TestDoJSON(t *testing.T) {
+	type user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/1":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":1,"name":"alice"}`))
+		case "/users":
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":2,"name":"bob"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	h := NewHTTPUtils()
+
+	u, err := DoJSON[user](h, "GET", server.URL+"/users/1", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, user{ID: 1, Name: "alice"}, u)
+
+	created, err := PostJSON[user](server.URL+"/users", user{Name: "bob"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, created.ID)
+
+	_, err = GetJSON[user](server.URL+"/missing", nil)
+	var httpErr *HTTPError
+	assert.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestHTTPUtils_PoolStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	h := NewHTTPUtils()
+	for i := 0; i < 3; i++ {
+		_, err := h.Get(server.URL, nil)
+		require.NoError(t, err)
+	}
+	_, err := h.Get("http://127.0.0.1:1/", nil)
+	assert.Error(t, err)
+
+	u, _ := url.Parse(server.URL)
+	stats := h.GetPoolStats()[u.Host]
+	assert.Equal(t, int64(3), stats.Requests)
+	assert.Equal(t, int64(0), stats.InFlight)
+	assert.Equal(t, int64(1), stats.NewConns)
+	assert.Equal(t, int64(2), stats.ReusedConns)
+	assert.Equal(t, int64(1), stats.IdleConns)
+	assert.InDelta(t, 2.0/3, stats.ReuseRatio(), 0.01)
+	assert.Greater(t, stats.AvgConnect, time.Duration(0))
+	assert.GreaterOrEqual(t, stats.AvgTTFB, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, stats.AvgLatency, stats.AvgTTFB)
+
+	failed := h.GetPoolStats()["127.0.0.1:1"]
+	assert.Equal(t, int64(1), failed.Errors)
+	assert.NotEmpty(t, failed.LastError)
+
+	assert.Equal(t, []string{u.Host}, h.SlowHosts(5*time.Millisecond))
+	assert.Empty(t, h.SlowHosts(time.Minute))
+	assert.Equal(t, float64(3), httpClientRequests.Value(u.Host, "200"))
+}
+
+func TestHTTPUtils_PoolStatsHostLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// 占满主机数上限，之后的新主机归入 other
+	trackedHosts.mu.Lock()
+	saved := trackedHosts.hosts
+	trackedHosts.hosts = make(map[string]struct{}, maxTrackedHosts)
+	for i := 0; len(trackedHosts.hosts) < maxTrackedHosts; i++ {
+		trackedHosts.hosts[fmt.Sprintf("host-%d.example.com", i)] = struct{}{}
+	}
+	trackedHosts.mu.Unlock()
+	defer func() {
+		trackedHosts.mu.Lock()
+		trackedHosts.hosts = saved
+		trackedHosts.mu.Unlock()
+	}()
+
+	before := httpClientRequests.Value(otherHost, "200")
+	h := NewHTTPUtils()
+	_, err := h.Get(server.URL, nil)
+	require.NoError(t, err)
+
+	u, _ := url.Parse(server.URL)
+	stats := h.GetPoolStats()
+	assert.NotContains(t, stats, u.Host)
+	assert.Equal(t, int64(1), stats[otherHost].Requests)
+	assert.Equal(t, before+1, httpClientRequests.Value(otherHost, "200"))
+	assert.Equal(t, otherHost, hostLabel("host-new.example.com"))
+	assert.Equal(t, "host-0.example.com", hostLabel("host-0.example.com"))
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// maxNDJSONLineSize NDJSON单行最大长度
+const maxNDJSONLineSize = 10 * 1024 * 1024
+
+// DecodeStream 从io.Reader流式解析单个JSON值，不会一次性读取全部内容
+func (j *JSONUtils) DecodeStream(r io.Reader, v interface{}) error {
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode JSON stream: %w", err)
+	}
+	return nil
+}
+
+// EncodeStream 将对象直接编码写入io.Writer
+func (j *JSONUtils) EncodeStream(w io.Writer, v interface{}) error {
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		return fmt.Errorf("failed to encode JSON stream: %w", err)
+	}
+	return nil
+}
+
+// DecodeArrayStream 逐个元素解析JSON数组，每个元素回调一次
+// fn 中调用 dec.Decode(&item) 读取当前元素，适用于超大数组的导入
+func (j *JSONUtils) DecodeArrayStream(r io.Reader, fn func(dec *json.Decoder) error) error {
+	dec := json.NewDecoder(r)
+
+	token, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("failed to read JSON array start: %w", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected JSON array, got %v", token)
+	}
+
+	for index := 0; dec.More(); index++ {
+		if err := fn(dec); err != nil {
+			return fmt.Errorf("failed to process array element %d: %w", index, err)
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("failed to read JSON array end: %w", err)
+	}
+	return nil
+}
+
+// EachNDJSON 逐行读取NDJSON（每行一个JSON值），空行会被跳过
+// fn 的 line 参数在下一次回调前有效，如需保留请复制
+func (j *JSONUtils) EachNDJSON(r io.Reader, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxNDJSONLineSize)
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("failed to process NDJSON line %d: %w", lineNo, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read NDJSON stream: %w", err)
+	}
+	return nil
+}
+
+// WriteNDJSON 将多个对象以NDJSON格式写入io.Writer
+func (j *JSONUtils) WriteNDJSON(w io.Writer, values ...interface{}) error {
+	encoder := json.NewEncoder(w)
+	for i, v := range values {
+		if err := encoder.Encode(v); err != nil {
+			return fmt.Errorf("failed to encode NDJSON item %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// JSONArrayWriter 流式写入JSON数组，适用于大批量数据导出
+type JSONArrayWriter struct {
+	w     io.Writer
+	count int
+	err   error
+}
+
+// NewArrayWriter 创建流式JSON数组写入器
+func (j *JSONUtils) NewArrayWriter(w io.Writer) *JSONArrayWriter {
+	return &JSONArrayWriter{w: w}
+}
+
+// Write 写入一个数组元素
+func (a *JSONArrayWriter) Write(v interface{}) error {
+	if a.err != nil {
+		return a.err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal array element %d: %w", a.count, err)
+	}
+
+	prefix := []byte(",")
+	if a.count == 0 {
+		prefix = []byte("[")
+	}
+	if _, a.err = a.w.Write(prefix); a.err != nil {
+		return a.err
+	}
+	if _, a.err = a.w.Write(data); a.err != nil {
+		return a.err
+	}
+	a.count++
+	return nil
+}
+
+// Count 获取已写入的元素数量
+func (a *JSONArrayWriter) Count() int {
+	return a.count
+}
+
+// Close 结束数组写入，未写入任何元素时输出空数组
+func (a *JSONArrayWriter) Close() error {
+	if a.err != nil {
+		return a.err
+	}
+
+	closing := "]"
+	if a.count == 0 {
+		closing = "[]"
+	}
+	_, a.err = io.WriteString(a.w, closing)
+	return a.err
+}
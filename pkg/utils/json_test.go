@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONUtils_DecodeArrayStream(t *testing.T) {
+	j := NewJSONUtils()
+	input := `[{"id":1},{"id":2},{"id":3}]`
+
+	var ids []int
+	err := j.DecodeArrayStream(strings.NewReader(input), func(dec *json.Decoder) error {
+		var item struct {
+			ID int `json:"id"`
+		}
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+		ids = append(ids, item.ID)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, ids)
+
+	err = j.DecodeArrayStream(strings.NewReader(`{"id":1}`), func(dec *json.Decoder) error { return nil })
+	assert.Error(t, err)
+}
+
+func TestJSONUtils_NDJSON(t *testing.T) {
+	j := NewJSONUtils()
+
+	var buf bytes.Buffer
+	assert.NoError(t, j.WriteNDJSON(&buf, map[string]int{"a": 1}, map[string]int{"a": 2}))
+	buf.WriteString("\n")
+
+	var sum int
+	err := j.EachNDJSON(&buf, func(line []byte) error {
+		var item map[string]int
+		if err := json.Unmarshal(line, &item); err != nil {
+			return err
+		}
+		sum += item["a"]
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, sum)
+}
+
+func TestJSONUtils_ArrayWriter(t *testing.T) {
+	j := NewJSONUtils()
+
+	var buf bytes.Buffer
+	writer := j.NewArrayWriter(&buf)
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, writer.Write(map[string]int{"n": i}))
+	}
+	assert.NoError(t, writer.Close())
+	assert.Equal(t, `[{"n":1},{"n":2},{"n":3}]`, buf.String())
+	assert.Equal(t, 3, writer.Count())
+
+	var empty bytes.Buffer
+	assert.NoError(t, j.NewArrayWriter(&empty).Close())
+	assert.Equal(t, "[]", empty.String())
+}
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// CodeHighlighter 代码高亮钩子，返回高亮后的HTML和是否处理
+// 返回 false 时使用默认的 <pre><code class="language-xxx"> 输出
+// 高亮结果同样会经过清洗，只允许使用 class 属性标注样式
+type CodeHighlighter func(lang, code string) (string, bool)
+
+// defaultMarkdownCacheSize 默认缓存条目数
+const defaultMarkdownCacheSize = 256
+
+// markdownClassPattern 允许的 class 属性值
+var markdownClassPattern = regexp.MustCompile(`^[\w\- ]+$`)
+
+// MarkdownUtils Markdown渲染工具，输出经过清洗的安全HTML
+type MarkdownUtils struct {
+	md          goldmark.Markdown
+	policy      *bluemonday.Policy
+	highlighter CodeHighlighter
+
+	mu        sync.Mutex
+	cache     map[[sha256.Size]byte]string
+	cacheSize int
+}
+
+// NewMarkdownUtils 创建Markdown工具实例，默认启用GFM扩展和UGC清洗策略
+func NewMarkdownUtils() *MarkdownUtils {
+	m := &MarkdownUtils{
+		policy:    DefaultMarkdownPolicy(),
+		cache:     make(map[[sha256.Size]byte]string),
+		cacheSize: defaultMarkdownCacheSize,
+	}
+	m.md = goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithRendererOptions(
+			renderer.WithNodeRenderers(util.Prioritized(&codeBlockRenderer{m: m}, 100)),
+		),
+	)
+	return m
+}
+
+// DefaultMarkdownPolicy 默认清洗策略，基于 bluemonday.UGCPolicy
+// 额外允许代码块及高亮片段上的 class 属性
+func DefaultMarkdownPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").Matching(markdownClassPattern).OnElements("pre", "code", "span")
+	return policy
+}
+
+// SetPolicy 设置清洗策略，应在初始化阶段调用
+func (m *MarkdownUtils) SetPolicy(policy *bluemonday.Policy) {
+	m.policy = policy
+	m.ClearCache()
+}
+
+// SetHighlighter 设置代码高亮钩子，应在初始化阶段调用
+func (m *MarkdownUtils) SetHighlighter(highlighter CodeHighlighter) {
+	m.highlighter = highlighter
+	m.ClearCache()
+}
+
+// SetCacheSize 设置缓存条目数，<=0 表示禁用缓存
+func (m *MarkdownUtils) SetCacheSize(size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cacheSize = size
+	m.cache = make(map[[sha256.Size]byte]string)
+}
+
+// ClearCache 清空渲染缓存
+func (m *MarkdownUtils) ClearCache() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cache = make(map[[sha256.Size]byte]string)
+}
+
+// ToHTML 将Markdown渲染为经过清洗的HTML，相同内容直接返回缓存结果
+func (m *MarkdownUtils) ToHTML(source string) (string, error) {
+	key := sha256.Sum256([]byte(source))
+
+	m.mu.Lock()
+	if html, exists := m.cache[key]; exists {
+		m.mu.Unlock()
+		return html, nil
+	}
+	m.mu.Unlock()
+
+	var buf bytes.Buffer
+	if err := m.md.Convert([]byte(source), &buf); err != nil {
+		return "", fmt.Errorf("failed to render markdown: %w", err)
+	}
+	html := m.policy.SanitizeBytes(buf.Bytes())
+
+	m.mu.Lock()
+	if m.cacheSize > 0 {
+		// 缓存满时整体清空，避免维护淘汰顺序
+		if len(m.cache) >= m.cacheSize {
+			m.cache = make(map[[sha256.Size]byte]string)
+		}
+		m.cache[key] = string(html)
+	}
+	m.mu.Unlock()
+
+	return string(html), nil
+}
+
+// codeBlockRenderer 围栏代码块渲染器，支持高亮钩子
+type codeBlockRenderer struct {
+	m *MarkdownUtils
+}
+
+// RegisterFuncs 实现 renderer.NodeRenderer 接口
+func (r *codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderFencedCodeBlock)
+}
+
+// renderFencedCodeBlock 渲染围栏代码块
+func (r *codeBlockRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*ast.FencedCodeBlock)
+	lang := string(n.Language(source))
+
+	var code bytes.Buffer
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code.Write(line.Value(source))
+	}
+
+	if r.m.highlighter != nil {
+		if html, ok := r.m.highlighter(lang, code.String()); ok {
+			_, _ = w.WriteString(html)
+			return ast.WalkSkipChildren, nil
+		}
+	}
+
+	_, _ = w.WriteString("<pre><code")
+	if lang != "" {
+		_, _ = w.WriteString(` class="language-`)
+		_, _ = w.Write(util.EscapeHTML([]byte(lang)))
+		_ = w.WriteByte('"')
+	}
+	_ = w.WriteByte('>')
+	_, _ = w.Write(util.EscapeHTML(code.Bytes()))
+	_, _ = w.WriteString("</code></pre>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+// 全局Markdown工具实例
+var Markdown = NewMarkdownUtils()
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownUtils_ToHTML(t *testing.T) {
+	m := NewMarkdownUtils()
+
+	html, err := m.ToHTML("# Title\n\n**bold** [link](https://example.com)")
+	assert.NoError(t, err)
+	assert.Contains(t, html, "<h1")
+	assert.Contains(t, html, "<strong>bold</strong>")
+	assert.Contains(t, html, `href="https://example.com"`)
+
+	// 危险内容被清洗
+	html, err = m.ToHTML("<script>alert(1)</script>\n\n[x](javascript:alert(1))\n\n<img src=x onerror=alert(1)>")
+	assert.NoError(t, err)
+	assert.NotContains(t, html, "<script")
+	assert.NotContains(t, html, "javascript:")
+	assert.NotContains(t, html, "onerror")
+}
+
+func TestMarkdownUtils_CodeBlock(t *testing.T) {
+	m := NewMarkdownUtils()
+
+	html, err := m.ToHTML("```go\nfmt.Println(\"<hi>\")\n```")
+	assert.NoError(t, err)
+	assert.Contains(t, html, `<code class="language-go">`)
+	assert.Contains(t, html, "&lt;hi&gt;")
+
+	m.SetHighlighter(func(lang, code string) (string, bool) {
+		if lang != "go" {
+			return "", false
+		}
+		return `<pre class="hl"><code><span class="kw" onclick="x()">` + strings.TrimSpace(code) + `</span></code></pre>`, true
+	})
+	html, err = m.ToHTML("```go\nfunc\n```")
+	assert.NoError(t, err)
+	assert.Contains(t, html, `<span class="kw">func</span>`)
+	assert.NotContains(t, html, "onclick")
+}
+
+func TestMarkdownUtils_Cache(t *testing.T) {
+	m := NewMarkdownUtils()
+	m.SetCacheSize(2)
+
+	for _, src := range []string{"a", "b", "c"} {
+		_, err := m.ToHTML(src)
+		assert.NoError(t, err)
+	}
+	assert.LessOrEqual(t, len(m.cache), 2)
+
+	m.SetCacheSize(0)
+	_, err := m.ToHTML("a")
+	assert.NoError(t, err)
+	assert.Empty(t, m.cache)
+}
@@ -0,0 +1,319 @@
+package utils
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// MaskUtils 脱敏工具集合
+type MaskUtils struct {
+	// MaskChar 脱敏替换字符
+	MaskChar rune
+}
+
+// NewMaskUtils 创建脱敏工具实例
+func NewMaskUtils() *MaskUtils {
+	return &MaskUtils{MaskChar: '*'}
+}
+
+// 文本脱敏规则：邮箱格式本身足够明确，直接匹配；数字类信息只在字段名之后匹配（如 phone=、"id_card":），
+// 避免把订单号、毫秒时间戳等普通数字当成手机号或银行卡号；第1个分组为字段名和分隔符，第2个分组为值
+var (
+	maskEmailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	maskIDCardPattern = regexp.MustCompile(`(?i)(\b(?:id_?card|id_?no|identity)["']?\s*[:=]\s*["']?)(\d{17}[\dXx])\b`)
+	maskPhonePattern  = regexp.MustCompile(`(?i)(\b(?:phone|mobile|tel)["']?\s*[:=]\s*["']?)(1[3-9]\d{9})\b`)
+	maskCardPattern   = regexp.MustCompile(`(?i)(\b(?:card|card_?no|card_?number|bank_?card)["']?\s*[:=]\s*["']?)(\d{13,19})\b`)
+)
+
+// Keep 保留前 first 个和后 last 个字符，其余替换为脱敏字符
+// 字符串过短时全部替换
+func (m *MaskUtils) Keep(str string, first, last int) string {
+	runes := []rune(str)
+	if len(runes) == 0 {
+		return ""
+	}
+	if first < 0 {
+		first = 0
+	}
+	if last < 0 {
+		last = 0
+	}
+	if first+last >= len(runes) {
+		return strings.Repeat(string(m.MaskChar), len(runes))
+	}
+
+	masked := make([]rune, len(runes))
+	for i, r := range runes {
+		if i < first || i >= len(runes)-last {
+			masked[i] = r
+		} else {
+			masked[i] = m.MaskChar
+		}
+	}
+	return string(masked)
+}
+
+// MaskAll 全部替换为脱敏字符
+func (m *MaskUtils) MaskAll(str string) string {
+	return m.Keep(str, 0, 0)
+}
+
+// MaskEmail 邮箱脱敏，如 zhangsan@example.com -> z******n@example.com
+func (m *MaskUtils) MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return m.Keep(email, 1, 0)
+	}
+	local, domain := email[:at], email[at:]
+	if len([]rune(local)) <= 2 {
+		return m.Keep(local, 1, 0) + domain
+	}
+	return m.Keep(local, 1, 1) + domain
+}
+
+// MaskPhone 手机号脱敏，如 13812345678 -> 138****5678
+func (m *MaskUtils) MaskPhone(phone string) string {
+	if len([]rune(phone)) < 7 {
+		return m.Keep(phone, 1, 1)
+	}
+	return m.Keep(phone, 3, 4)
+}
+
+// MaskIDCard 身份证号脱敏，如 110101199003071234 -> 110***********1234
+func (m *MaskUtils) MaskIDCard(idCard string) string {
+	return m.Keep(idCard, 3, 4)
+}
+
+// MaskCard 银行卡号脱敏，保留前6位和后4位
+func (m *MaskUtils) MaskCard(card string) string {
+	card = strings.ReplaceAll(card, " ", "")
+	if len(card) < 12 {
+		return m.Keep(card, 0, 4)
+	}
+	return m.Keep(card, 6, 4)
+}
+
+// MaskName 姓名脱敏，保留第一个字，如 张三丰 -> 张**
+func (m *MaskUtils) MaskName(name string) string {
+	return m.Keep(name, 1, 0)
+}
+
+// MaskByType 按类型脱敏，类型可选 email、phone、idcard、card、name、all
+func (m *MaskUtils) MaskByType(str, maskType string) string {
+	switch strings.ToLower(maskType) {
+	case "email":
+		return m.MaskEmail(str)
+	case "phone", "mobile":
+		return m.MaskPhone(str)
+	case "idcard", "id_card":
+		return m.MaskIDCard(str)
+	case "card", "bankcard":
+		return m.MaskCard(str)
+	case "name":
+		return m.MaskName(str)
+	default:
+		return m.MaskAll(str)
+	}
+}
+
+// MaskText 识别并脱敏文本中的邮箱，以及字段名之后的身份证号、手机号和银行卡号，如 phone=13812345678
+func (m *MaskUtils) MaskText(text string) string {
+	text = maskEmailPattern.ReplaceAllStringFunc(text, m.MaskEmail)
+	text = maskFieldValues(text, maskIDCardPattern, m.MaskIDCard)
+	text = maskFieldValues(text, maskPhonePattern, m.MaskPhone)
+	text = maskFieldValues(text, maskCardPattern, m.MaskCard)
+	return text
+}
+
+// maskFieldValues 保留字段名和分隔符，只脱敏值
+func maskFieldValues(text string, pattern *regexp.Regexp, mask func(string) string) string {
+	return pattern.ReplaceAllStringFunc(text, func(match string) string {
+		groups := pattern.FindStringSubmatch(match)
+		return groups[1] + mask(groups[2])
+	})
+}
+
+// maxMaskDepth 结构体脱敏的最大递归深度
+const maxMaskDepth = 32
+
+// Masked 返回对象的脱敏副本，不修改原对象；没有需要脱敏的字段时直接返回原对象，不做复制
+// 字符串字段通过 mask 标签声明脱敏类型，如 `mask:"phone"`，支持嵌套结构体、切片和map
+func (m *MaskUtils) Masked(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	value := reflect.ValueOf(v)
+	if !mayContainMask(value.Type()) {
+		return v
+	}
+	masked, changed := m.maskValue(value, 0)
+	if !changed {
+		return v
+	}
+	return masked.Interface()
+}
+
+// maskTypes 缓存类型是否可能包含 mask 标签
+var maskTypes sync.Map
+
+// mayContainMask 判断类型是否可能包含需要脱敏的字段，包含接口的类型需要按值检查
+func mayContainMask(t reflect.Type) bool {
+	if cached, ok := maskTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	result := typeMayContainMask(t, map[reflect.Type]bool{})
+	maskTypes.Store(t, result)
+	return result
+}
+
+// typeMayContainMask 递归检查类型，visiting 用于处理自引用类型
+func typeMayContainMask(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return typeMayContainMask(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if _, ok := field.Tag.Lookup("mask"); ok && field.Type.Kind() == reflect.String {
+				return true
+			}
+			if typeMayContainMask(field.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maskValue 递归脱敏，只在子节点发生变化时复制当前节点，changed 表示返回值与原值不同
+func (m *MaskUtils) maskValue(v reflect.Value, depth int) (reflect.Value, bool) {
+	if depth > maxMaskDepth || !mayContainMask(v.Type()) {
+		return v, false
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, changed := m.maskValue(v.Elem(), depth+1)
+		if !changed {
+			return v, false
+		}
+		copied := reflect.New(v.Type().Elem())
+		copied.Elem().Set(elem)
+		return copied, true
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, changed := m.maskValue(v.Elem(), depth+1)
+		if !changed {
+			return v, false
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(elem)
+		return copied, true
+
+	case reflect.Struct:
+		t := v.Type()
+		var copied reflect.Value
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			masked, changed := v.Field(i), false
+			if maskType, ok := field.Tag.Lookup("mask"); ok && masked.Kind() == reflect.String {
+				if value := m.MaskByType(masked.String(), maskType); value != masked.String() {
+					masked, changed = reflect.ValueOf(value).Convert(field.Type), true
+				}
+			} else {
+				masked, changed = m.maskValue(masked, depth+1)
+			}
+			if !changed {
+				continue
+			}
+			if !copied.IsValid() {
+				copied = reflect.New(t).Elem()
+				copied.Set(v)
+			}
+			copied.Field(i).Set(masked)
+		}
+		if !copied.IsValid() {
+			return v, false
+		}
+		return copied, true
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return v, false
+		}
+		var copied reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			masked, changed := m.maskValue(v.Index(i), depth+1)
+			if !changed {
+				continue
+			}
+			if !copied.IsValid() {
+				if v.Kind() == reflect.Slice {
+					copied = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+					reflect.Copy(copied, v)
+				} else {
+					copied = reflect.New(v.Type()).Elem()
+					copied.Set(v)
+				}
+			}
+			copied.Index(i).Set(masked)
+		}
+		if !copied.IsValid() {
+			return v, false
+		}
+		return copied, true
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v, false
+		}
+		var copied reflect.Value
+		iter := v.MapRange()
+		for iter.Next() {
+			masked, changed := m.maskValue(iter.Value(), depth+1)
+			if !changed {
+				continue
+			}
+			if !copied.IsValid() {
+				copied = reflect.MakeMapWithSize(v.Type(), v.Len())
+				all := v.MapRange()
+				for all.Next() {
+					copied.SetMapIndex(all.Key(), all.Value())
+				}
+			}
+			copied.SetMapIndex(iter.Key(), masked)
+		}
+		if !copied.IsValid() {
+			return v, false
+		}
+		return copied, true
+
+	default:
+		return v, false
+	}
+}
+
+// 全局脱敏工具实例
+var Mask = NewMaskUtils()
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskUtils_Basic(t *testing.T) {
+	m := NewMaskUtils()
+
+	assert.Equal(t, "z******n@example.com", m.MaskEmail("zhangsan@example.com"))
+	assert.Equal(t, "138****5678", m.MaskPhone("13812345678"))
+	assert.Equal(t, "110***********1234", m.MaskIDCard("110101199003071234"))
+	assert.Equal(t, "622202******1234", m.MaskCard("6222 0212 3456 1234"))
+	assert.Equal(t, "张**", m.MaskName("张三丰"))
+	assert.Equal(t, "***", m.Keep("abc", 2, 2))
+}
+
+func TestMaskUtils_MaskText(t *testing.T) {
+	m := NewMaskUtils()
+
+	masked := m.MaskText("contact john@example.com or phone=13812345678")
+	assert.NotContains(t, masked, "john@example.com")
+	assert.Contains(t, masked, "phone=138****5678")
+
+	masked = m.MaskText(`{"id_card":"110101199003071234","card_no": "6222021234561234"}`)
+	assert.Equal(t, `{"id_card":"110***********1234","card_no": "622202******1234"}`, masked)
+
+	// 没有字段名的数字（订单号、毫秒时间戳等）不脱敏
+	plain := "order 13812345678 created at 1700000000000 ref 6222021234561234"
+	assert.Equal(t, plain, m.MaskText(plain))
+}
+
+func TestMaskUtils_Masked(t *testing.T) {
+	type profile struct {
+		Phone string `mask:"phone"`
+	}
+	type user struct {
+		Name    string
+		Email   string `mask:"email"`
+		Profile *profile
+		Tags    []string
+	}
+
+	original := &user{
+		Name:    "alice",
+		Email:   "alice@example.com",
+		Profile: &profile{Phone: "13812345678"},
+		Tags:    []string{"vip"},
+	}
+
+	masked := Mask.Masked([]*user{original}).([]*user)
+
+	assert.Equal(t, "alice", masked[0].Name)
+	assert.Equal(t, "a***e@example.com", masked[0].Email)
+	assert.Equal(t, "138****5678", masked[0].Profile.Phone)
+
+	// 原对象不应被修改
+	assert.Equal(t, "alice@example.com", original.Email)
+	assert.Equal(t, "13812345678", original.Profile.Phone)
+}
+
+func TestMaskUtils_MaskedWithoutMatch(t *testing.T) {
+	type item struct {
+		Name string
+		Tags []string
+	}
+	type user struct {
+		Email string `mask:"email"`
+	}
+
+	// 类型中没有 mask 标签时直接返回原对象
+	items := []item{{Name: "widget", Tags: []string{"a"}}}
+	masked := Mask.Masked(items).([]item)
+	assert.Same(t, &items[0], &masked[0])
+
+	// 接口中的值没有需要脱敏的字段时不复制
+	data := map[string]interface{}{"items": items, "total": 1}
+	assert.Equal(t, reflect.ValueOf(data).Pointer(), reflect.ValueOf(Mask.Masked(data)).Pointer())
+
+	// 只复制包含脱敏字段的部分
+	data["user"] = &user{Email: "alice@example.com"}
+	copied := Mask.Masked(data).(map[string]interface{})
+	assert.NotEqual(t, reflect.ValueOf(data).Pointer(), reflect.ValueOf(copied).Pointer())
+	assert.Equal(t, "a***e@example.com", copied["user"].(*user).Email)
+	assert.Equal(t, "alice@example.com", data["user"].(*user).Email)
+	assert.Same(t, &items[0], &copied["items"].([]item)[0])
+}
@@ -0,0 +1,270 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Decimal 精确十进制数，避免浮点数运算误差
+// JSON序列化为字符串，实现了 sql.Scanner 和 driver.Valuer
+type Decimal = decimal.Decimal
+
+// RoundingMode 舍入模式
+type RoundingMode int
+
+const (
+	RoundHalfUp   RoundingMode = iota // 四舍五入
+	RoundHalfEven                     // 银行家舍入（四舍六入五成双）
+	RoundDown                         // 向零截断
+	RoundUp                           // 远离零进位
+	RoundCeil                         // 向正无穷取整
+	RoundFloor                        // 向负无穷取整
+)
+
+// CurrencyInfo 货币信息
+type CurrencyInfo struct {
+	Code   string // ISO 4217 货币代码
+	Symbol string // 货币符号
+	Scale  int32  // 最小单位的小数位数
+}
+
+// currencies 内置货币信息，未知货币默认保留2位小数
+var currencies = map[string]CurrencyInfo{
+	"CNY": {Code: "CNY", Symbol: "¥", Scale: 2},
+	"USD": {Code: "USD", Symbol: "$", Scale: 2},
+	"EUR": {Code: "EUR", Symbol: "€", Scale: 2},
+	"GBP": {Code: "GBP", Symbol: "£", Scale: 2},
+	"HKD": {Code: "HKD", Symbol: "HK$", Scale: 2},
+	"JPY": {Code: "JPY", Symbol: "¥", Scale: 0},
+	"KRW": {Code: "KRW", Symbol: "₩", Scale: 0},
+}
+
+// RegisterCurrency 注册或覆盖货币信息
+func RegisterCurrency(info CurrencyInfo) {
+	currencies[strings.ToUpper(info.Code)] = info
+}
+
+// GetCurrency 获取货币信息
+func GetCurrency(code string) CurrencyInfo {
+	code = strings.ToUpper(code)
+	if info, exists := currencies[code]; exists {
+		return info
+	}
+	return CurrencyInfo{Code: code, Symbol: code + " ", Scale: 2}
+}
+
+// ParseDecimal 从字符串解析精确十进制数
+func ParseDecimal(value string) (Decimal, error) {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("invalid decimal %q: %w", value, err)
+	}
+	return d, nil
+}
+
+// DecimalFromInt 从整数创建精确十进制数
+func DecimalFromInt(value int64) Decimal {
+	return decimal.NewFromInt(value)
+}
+
+// RoundDecimal 按指定模式保留 places 位小数
+func RoundDecimal(d Decimal, places int32, mode RoundingMode) Decimal {
+	switch mode {
+	case RoundHalfEven:
+		return d.RoundBank(places)
+	case RoundDown:
+		return d.Truncate(places)
+	case RoundUp:
+		return d.RoundUp(places)
+	case RoundCeil:
+		return d.RoundCeil(places)
+	case RoundFloor:
+		return d.RoundFloor(places)
+	default:
+		return d.Round(places)
+	}
+}
+
+// Money 金额，由精确金额和货币代码组成
+// 在GORM模型中可以使用 `gorm:"embedded;embeddedPrefix:price_"` 嵌入为两列
+type Money struct {
+	Amount   Decimal `json:"amount" gorm:"type:decimal(20,4)"`
+	Currency string  `json:"currency" gorm:"size:3"`
+}
+
+// NewMoney 从字符串金额创建Money，如 NewMoney("19.99", "CNY")
+func NewMoney(amount string, currency string) (Money, error) {
+	d, err := ParseDecimal(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: d, Currency: strings.ToUpper(currency)}, nil
+}
+
+// NewMoneyFromMinor 从最小货币单位创建Money，如分：NewMoneyFromMinor(1999, "CNY") 表示 19.99
+func NewMoneyFromMinor(minor int64, currency string) Money {
+	info := GetCurrency(currency)
+	return Money{Amount: decimal.New(minor, -info.Scale), Currency: info.Code}
+}
+
+// ZeroMoney 创建指定货币的零金额
+func ZeroMoney(currency string) Money {
+	return Money{Amount: decimal.Zero, Currency: strings.ToUpper(currency)}
+}
+
+// checkCurrency 检查两个金额的货币是否一致
+func (m Money) checkCurrency(other Money) error {
+	if m.Currency != other.Currency {
+		return fmt.Errorf("currency mismatch: %s vs %s", m.Currency, other.Currency)
+	}
+	return nil
+}
+
+// Add 金额相加，货币不同时返回错误
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount.Add(other.Amount), Currency: m.Currency}, nil
+}
+
+// Sub 金额相减，货币不同时返回错误
+func (m Money) Sub(other Money) (Money, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount.Sub(other.Amount), Currency: m.Currency}, nil
+}
+
+// Mul 金额乘以系数（如数量、税率），结果不做舍入
+func (m Money) Mul(factor Decimal) Money {
+	return Money{Amount: m.Amount.Mul(factor), Currency: m.Currency}
+}
+
+// Div 金额除以系数，并按货币精度和舍入模式舍入
+func (m Money) Div(divisor Decimal, mode RoundingMode) (Money, error) {
+	if divisor.IsZero() {
+		return Money{}, fmt.Errorf("division by zero")
+	}
+	// 多保留4位后再按模式舍入，避免 DivRound 内部的四舍五入干扰
+	scale := GetCurrency(m.Currency).Scale
+	quotient := m.Amount.DivRound(divisor, scale+4)
+	return Money{Amount: RoundDecimal(quotient, scale, mode), Currency: m.Currency}, nil
+}
+
+// Round 按货币精度和舍入模式舍入
+func (m Money) Round(mode RoundingMode) Money {
+	scale := GetCurrency(m.Currency).Scale
+	return Money{Amount: RoundDecimal(m.Amount, scale, mode), Currency: m.Currency}
+}
+
+// Allocate 按比例分摊金额，余数依次分配给前面的份额，保证总和不变
+// 例如 10.00 按 [1,1,1] 分摊为 3.34、3.33、3.33
+func (m Money) Allocate(ratios ...int) ([]Money, error) {
+	if len(ratios) == 0 {
+		return nil, fmt.Errorf("at least one ratio is required")
+	}
+
+	total := 0
+	for _, ratio := range ratios {
+		if ratio < 0 {
+			return nil, fmt.Errorf("ratio must not be negative: %d", ratio)
+		}
+		total += ratio
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("sum of ratios must be positive")
+	}
+
+	minor := m.MinorUnits()
+	results := make([]Money, len(ratios))
+	remainder := minor
+	for i, ratio := range ratios {
+		share := minor * int64(ratio) / int64(total)
+		results[i] = NewMoneyFromMinor(share, m.Currency)
+		remainder -= share
+	}
+
+	// 分配余数（每份最多一个最小单位）
+	step := int64(1)
+	if remainder < 0 {
+		step = -1
+	}
+	for i := 0; remainder != 0; i = (i + 1) % len(results) {
+		if ratios[i] == 0 {
+			continue
+		}
+		results[i].Amount = results[i].Amount.Add(decimal.New(step, -GetCurrency(m.Currency).Scale))
+		remainder -= step
+	}
+
+	return results, nil
+}
+
+// MinorUnits 转换为最小货币单位（如分），按四舍五入处理多余精度
+func (m Money) MinorUnits() int64 {
+	scale := GetCurrency(m.Currency).Scale
+	return m.Amount.Shift(scale).Round(0).IntPart()
+}
+
+// Cmp 比较金额大小，货币不同时返回错误
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return 0, err
+	}
+	return m.Amount.Cmp(other.Amount), nil
+}
+
+// Equal 判断金额和货币是否相同
+func (m Money) Equal(other Money) bool {
+	return m.Currency == other.Currency && m.Amount.Equal(other.Amount)
+}
+
+// IsZero 判断金额是否为零
+func (m Money) IsZero() bool {
+	return m.Amount.IsZero()
+}
+
+// IsNegative 判断金额是否为负数
+func (m Money) IsNegative() bool {
+	return m.Amount.IsNegative()
+}
+
+// Neg 取相反数
+func (m Money) Neg() Money {
+	return Money{Amount: m.Amount.Neg(), Currency: m.Currency}
+}
+
+// String 返回金额字符串，如 "19.99 CNY"
+func (m Money) String() string {
+	scale := GetCurrency(m.Currency).Scale
+	return m.Amount.StringFixed(scale) + " " + m.Currency
+}
+
+// Format 按货币格式化金额，带符号和千分位，如 "¥1,234.50"
+func (m Money) Format() string {
+	info := GetCurrency(m.Currency)
+	amount := m.Amount.Abs().StringFixed(info.Scale)
+
+	intPart, fracPart := amount, ""
+	if idx := strings.IndexByte(amount, '.'); idx >= 0 {
+		intPart, fracPart = amount[:idx], amount[idx:]
+	}
+
+	// 插入千分位分隔符
+	var builder strings.Builder
+	for i, ch := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteRune(ch)
+	}
+
+	sign := ""
+	if m.Amount.IsNegative() {
+		sign = "-"
+	}
+	return sign + info.Symbol + builder.String() + fracPart
+}
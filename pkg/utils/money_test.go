@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoney_Arithmetic(t *testing.T) {
+	a, err := NewMoney("0.1", "cny")
+	assert.NoError(t, err)
+	b, _ := NewMoney("0.2", "CNY")
+
+	sum, err := a.Add(b)
+	assert.NoError(t, err)
+	assert.Equal(t, "0.30 CNY", sum.String())
+
+	_, err = a.Add(ZeroMoney("USD"))
+	assert.Error(t, err)
+
+	price := NewMoneyFromMinor(1999, "CNY")
+	total := price.Mul(DecimalFromInt(3))
+	assert.Equal(t, int64(5997), total.MinorUnits())
+}
+
+func TestMoney_Rounding(t *testing.T) {
+	m, _ := NewMoney("2.345", "USD")
+
+	assert.Equal(t, "2.35 USD", m.Round(RoundHalfUp).String())
+	assert.Equal(t, "2.34 USD", m.Round(RoundHalfEven).String())
+	assert.Equal(t, "2.34 USD", m.Round(RoundDown).String())
+	assert.Equal(t, "2.35 USD", m.Round(RoundUp).String())
+
+	third, err := NewMoneyFromMinor(1000, "CNY").Div(DecimalFromInt(3), RoundDown)
+	assert.NoError(t, err)
+	assert.Equal(t, "3.33 CNY", third.String())
+}
+
+func TestMoney_Allocate(t *testing.T) {
+	parts, err := NewMoneyFromMinor(1000, "CNY").Allocate(1, 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(334), parts[0].MinorUnits())
+	assert.Equal(t, int64(333), parts[1].MinorUnits())
+	assert.Equal(t, int64(333), parts[2].MinorUnits())
+}
+
+func TestMoney_FormatAndJSON(t *testing.T) {
+	m, _ := NewMoney("-1234567.5", "CNY")
+	assert.Equal(t, "-¥1,234,567.50", m.Format())
+	assert.Equal(t, "¥1,000", NewMoneyFromMinor(1000, "JPY").Format())
+
+	data, err := json.Marshal(NewMoneyFromMinor(1999, "CNY"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"19.99","currency":"CNY"}`, string(data))
+
+	var decoded Money
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.True(t, decoded.Equal(NewMoneyFromMinor(1999, "CNY")))
+}
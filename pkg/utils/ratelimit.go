@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// HostLimitConfig 出站请求的单主机限流配置
+type HostLimitConfig struct {
+	Rate           float64 // 每秒允许的请求数，<=0 表示不限速
+	Burst          int     // 令牌桶容量（允许的突发请求数），<=0 时取1
+	MaxConcurrency int     // 最大并发请求数，<=0 表示不限制
+}
+
+// HostLimitStats 单主机限流统计
+type HostLimitStats struct {
+	Requests  int64         `json:"requests"`   // 已放行的请求数
+	Throttled int64         `json:"throttled"`  // 需要等待令牌或并发槽位的请求数
+	Rejected  int64         `json:"rejected"`   // 等待期间上下文取消的请求数
+	InFlight  int           `json:"in_flight"`  // 当前进行中的请求数
+	TotalWait time.Duration `json:"total_wait"` // 累计等待时长
+}
+
+// hostLimiter 单主机令牌桶 + 并发限制
+type hostLimiter struct {
+	config     HostLimitConfig
+	mu         sync.Mutex
+	tokens     float64
+	lastRefill time.Time
+	slots      chan struct{}
+	stats      HostLimitStats
+}
+
+// newHostLimiter 创建单主机限流器
+func newHostLimiter(cfg HostLimitConfig) *hostLimiter {
+	if cfg.Burst <= 0 {
+		cfg.Burst = 1
+	}
+	l := &hostLimiter{
+		config:     cfg,
+		tokens:     float64(cfg.Burst),
+		lastRefill: time.Now(),
+	}
+	if cfg.MaxConcurrency > 0 {
+		l.slots = make(chan struct{}, cfg.MaxConcurrency)
+	}
+	return l
+}
+
+// reserve 尝试获取令牌，返回还需等待的时间
+func (l *hostLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.config.Rate <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	l.tokens += now.Sub(l.lastRefill).Seconds() * l.config.Rate
+	if l.tokens > float64(l.config.Burst) {
+		l.tokens = float64(l.config.Burst)
+	}
+	l.lastRefill = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.config.Rate * float64(time.Second))
+}
+
+// acquire 等待令牌和并发槽位，返回释放函数
+func (l *hostLimiter) acquire(ctx context.Context) (func(), error) {
+	start := time.Now()
+	waited := false
+
+	// 等待令牌
+	for {
+		wait := l.reserve()
+		if wait == 0 {
+			break
+		}
+		waited = true
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			l.record(func(s *HostLimitStats) { s.Rejected++ })
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	// 等待并发槽位
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			waited = true
+			select {
+			case l.slots <- struct{}{}:
+			case <-ctx.Done():
+				l.record(func(s *HostLimitStats) { s.Rejected++ })
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	l.record(func(s *HostLimitStats) {
+		s.Requests++
+		s.InFlight++
+		if waited {
+			s.Throttled++
+			s.TotalWait += time.Since(start)
+		}
+	})
+
+	return func() {
+		if l.slots != nil {
+			<-l.slots
+		}
+		l.record(func(s *HostLimitStats) { s.InFlight-- })
+	}, nil
+}
+
+// record 更新统计信息
+func (l *hostLimiter) record(fn func(s *HostLimitStats)) {
+	l.mu.Lock()
+	fn(&l.stats)
+	l.mu.Unlock()
+}
+
+// snapshot 获取统计信息副本
+func (l *hostLimiter) snapshot() HostLimitStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+// outboundLimiter 按主机划分的出站限流器
+type outboundLimiter struct {
+	mu        sync.Mutex
+	defaults  *HostLimitConfig
+	overrides map[string]HostLimitConfig
+	limiters  map[string]*hostLimiter
+}
+
+// newOutboundLimiter 创建出站限流器
+func newOutboundLimiter() *outboundLimiter {
+	return &outboundLimiter{
+		overrides: make(map[string]HostLimitConfig),
+		limiters:  make(map[string]*hostLimiter),
+	}
+}
+
+// get 获取主机对应的限流器，未配置限流时返回nil
+func (o *outboundLimiter) get(host string) *hostLimiter {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if l, exists := o.limiters[host]; exists {
+		return l
+	}
+
+	cfg, exists := o.overrides[host]
+	if !exists {
+		if o.defaults == nil {
+			return nil
+		}
+		cfg = *o.defaults
+	}
+
+	l := newHostLimiter(cfg)
+	o.limiters[host] = l
+	return l
+}
+
+// SetRateLimit 设置所有主机的默认出站限流
+func (h *HTTPUtils) SetRateLimit(cfg HostLimitConfig) {
+	h.limiter.mu.Lock()
+	defer h.limiter.mu.Unlock()
+
+	h.limiter.defaults = &cfg
+	// 重新创建未单独配置的主机限流器
+	for host := range h.limiter.limiters {
+		if _, exists := h.limiter.overrides[host]; !exists {
+			delete(h.limiter.limiters, host)
+		}
+	}
+}
+
+// SetHostRateLimit 设置指定主机的出站限流，host 与请求URL的Host一致（含端口）
+func (h *HTTPUtils) SetHostRateLimit(host string, cfg HostLimitConfig) {
+	h.limiter.mu.Lock()
+	defer h.limiter.mu.Unlock()
+
+	h.limiter.overrides[host] = cfg
+	delete(h.limiter.limiters, host)
+}
+
+// GetRateLimitStats 获取各主机的出站限流统计
+func (h *HTTPUtils) GetRateLimitStats() map[string]HostLimitStats {
+	h.limiter.mu.Lock()
+	limiters := make(map[string]*hostLimiter, len(h.limiter.limiters))
+	for host, l := range h.limiter.limiters {
+		limiters[host] = l
+	}
+	h.limiter.mu.Unlock()
+
+	stats := make(map[string]HostLimitStats, len(limiters))
+	for host, l := range limiters {
+		stats[host] = l.snapshot()
+	}
+	return stats
+}
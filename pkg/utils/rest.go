@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Go不支持泛型方法，因此REST辅助函数以包级泛型函数提供，
+// 默认使用全局 HTTP 实例，也可以通过 DoJSON 指定 HTTPUtils。
+
+// maxErrorBodyLength 错误信息中保留的响应体最大长度
+const maxErrorBodyLength = 512
+
+// HTTPError 非2xx响应错误，包含状态码和响应体上下文
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+// Error 实现 error 接口
+func (e *HTTPError) Error() string {
+	body := e.Body
+	if len(body) > maxErrorBodyLength {
+		body = body[:maxErrorBodyLength] + "..."
+	}
+	return fmt.Sprintf("%s %s failed with status %d: %s", e.Method, e.URL, e.StatusCode, body)
+}
+
+// GetJSON 发送GET请求并将JSON响应解析为T
+func GetJSON[T any](url string, headers map[string]string) (T, error) {
+	return DoJSON[T](HTTP, "GET", url, nil, headers)
+}
+
+// PostJSON 发送JSON POST请求并将JSON响应解析为T
+func PostJSON[T any](url string, data interface{}, headers map[string]string) (T, error) {
+	return DoJSON[T](HTTP, "POST", url, data, headers)
+}
+
+// PutJSON 发送JSON PUT请求并将JSON响应解析为T
+func PutJSON[T any](url string, data interface{}, headers map[string]string) (T, error) {
+	return DoJSON[T](HTTP, "PUT", url, data, headers)
+}
+
+// PatchJSON 发送JSON PATCH请求并将JSON响应解析为T
+func PatchJSON[T any](url string, data interface{}, headers map[string]string) (T, error) {
+	return DoJSON[T](HTTP, "PATCH", url, data, headers)
+}
+
+// DeleteJSON 发送DELETE请求并将JSON响应解析为T
+func DeleteJSON[T any](url string, headers map[string]string) (T, error) {
+	return DoJSON[T](HTTP, "DELETE", url, nil, headers)
+}
+
+// DoJSON 使用指定的HTTPUtils发送请求，校验状态码并将JSON响应解析为T
+// data 不为nil时序列化为JSON请求体；非2xx响应返回 *HTTPError
+func DoJSON[T any](h *HTTPUtils, method, url string, data interface{}, headers map[string]string) (T, error) {
+	var result T
+
+	var body io.Reader
+	if data != nil {
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return result, fmt.Errorf("failed to marshal JSON data: %w", err)
+		}
+		body = bytes.NewReader(jsonData)
+	}
+
+	reqHeaders := map[string]string{"Accept": "application/json"}
+	if data != nil {
+		reqHeaders["Content-Type"] = "application/json"
+	}
+	for key, value := range headers {
+		reqHeaders[key] = value
+	}
+
+	resp, err := h.Request(method, url, body, reqHeaders)
+	if err != nil {
+		return result, err
+	}
+
+	if !resp.IsSuccess() {
+		return result, &HTTPError{
+			Method:     method,
+			URL:        url,
+			StatusCode: resp.StatusCode,
+			Body:       resp.Text,
+		}
+	}
+
+	// 204 等无响应体的情况返回零值
+	if len(bytes.TrimSpace(resp.Body)) == 0 {
+		return result, nil
+	}
+
+	if err := resp.JSON(&result); err != nil {
+		return result, fmt.Errorf("failed to decode %s %s response (status %d): %w", method, url, resp.StatusCode, err)
+	}
+	return result, nil
+}
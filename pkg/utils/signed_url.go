@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// 签名URL查询参数
+const (
+	SignedURLExpiresParam   = "expires"   // 过期时间的Unix时间戳
+	SignedURLUserParam      = "user"      // 可选，绑定的用户ID
+	SignedURLSignatureParam = "signature" // Base64URL编码的 HMAC-SHA256 签名
+)
+
+// ErrSignedURLExpired 签名URL已过期
+var ErrSignedURLExpired = errors.New("signed url expired")
+
+// URLSigner 签名URL生成与校验，签名覆盖路径、过期时间、绑定用户和其他查询参数，用于分享有时效的下载链接而不暴露长期凭证
+type URLSigner struct {
+	secret []byte
+	now    func() time.Time
+}
+
+// NewURLSigner 创建URL签名器
+func NewURLSigner(secret string) *URLSigner {
+	return &URLSigner{secret: []byte(secret), now: time.Now}
+}
+
+// Sign 为URL（路径或完整地址，可带查询参数）签名，ttl 后过期；userID 非空时链接只对该用户有效（由校验方比对）
+func (s *URLSigner) Sign(rawURL string, ttl time.Duration, userID string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+
+	query := u.Query()
+	query.Del(SignedURLSignatureParam)
+	query.Set(SignedURLExpiresParam, strconv.FormatInt(s.now().Add(ttl).Unix(), 10))
+	if userID != "" {
+		query.Set(SignedURLUserParam, userID)
+	} else {
+		query.Del(SignedURLUserParam)
+	}
+	query.Set(SignedURLSignatureParam, s.signature(u.EscapedPath(), query))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Verify 校验签名URL，返回绑定的用户ID（未绑定时为空）
+func (s *URLSigner) Verify(u *url.URL) (string, error) {
+	query := u.Query()
+	signature := query.Get(SignedURLSignatureParam)
+	expires, err := strconv.ParseInt(query.Get(SignedURLExpiresParam), 10, 64)
+	if signature == "" || err != nil {
+		return "", fmt.Errorf("%w: missing or malformed signature parameters", ErrInvalidSignature)
+	}
+
+	query.Del(SignedURLSignatureParam)
+	if !hmac.Equal([]byte(signature), []byte(s.signature(u.EscapedPath(), query))) {
+		return "", ErrInvalidSignature
+	}
+	if !s.now().Before(time.Unix(expires, 0)) {
+		return "", ErrSignedURLExpired
+	}
+	return query.Get(SignedURLUserParam), nil
+}
+
+// signature 计算签名，签名串为 路径?按键排序的查询参数（不含签名）
+func (s *URLSigner) signature(path string, query url.Values) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(path + "?" + query.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,357 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignature 请求签名无效
+var ErrInvalidSignature = errors.New("invalid request signature")
+
+// RequestSigner 出站请求签名器，body 为请求体（可能为空）
+type RequestSigner interface {
+	Sign(req *http.Request, body []byte) error
+}
+
+// SetSigner 设置请求签名器，之后通过该实例发送的请求都会在发送前签名，传入 nil 取消签名
+func (h *HTTPUtils) SetSigner(signer RequestSigner) {
+	h.signer = signer
+}
+
+// signRequest 读取请求体并签名
+func signRequest(req *http.Request, signer RequestSigner) error {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return errors.New("request body must be replayable to be signed")
+		}
+		reader, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		body, err = io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if err := signer.Sign(req, body); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	return nil
+}
+
+// HMAC签名请求头
+const (
+	HMACKeyIDHeader     = "X-Key-Id"
+	HMACTimestampHeader = "X-Timestamp"
+	HMACNonceHeader     = "X-Nonce"
+	HMACSignatureHeader = "X-Signature"
+)
+
+// HMACSigner 使用 HMAC-SHA256 签名请求，用于调用内部签名服务
+// 签名串为 方法\n路径和查询\n时间戳\n随机串\n请求体SHA256，签名以十六进制写入 X-Signature
+type HMACSigner struct {
+	KeyID  string // 密钥ID，写入 X-Key-Id，为空时不设置
+	Secret string
+	now    func() time.Time
+}
+
+// NewHMACSigner 创建HMAC签名器
+func NewHMACSigner(keyID, secret string) *HMACSigner {
+	return &HMACSigner{KeyID: keyID, Secret: secret, now: time.Now}
+}
+
+// Sign 签名请求，设置时间戳、随机串和签名请求头
+func (s *HMACSigner) Sign(req *http.Request, body []byte) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+	nonceStr := hex.EncodeToString(nonce)
+
+	if s.KeyID != "" {
+		req.Header.Set(HMACKeyIDHeader, s.KeyID)
+	}
+	req.Header.Set(HMACTimestampHeader, timestamp)
+	req.Header.Set(HMACNonceHeader, nonceStr)
+	req.Header.Set(HMACSignatureHeader, hmacSignature(s.Secret, req.Method, req.URL.RequestURI(), timestamp, nonceStr, body))
+	return nil
+}
+
+// hmacSignature 计算HMAC签名
+func hmacSignature(secret, method, uri, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.ToUpper(method) + "\n" + uri + "\n" + timestamp + "\n" + nonce + "\n" + hex.EncodeToString(bodyHash[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyHMACRequest 校验 HMACSigner 签名的请求，时间戳超出容差视为重放；读取后会恢复请求体
+// 随机串的去重（如写入Redis并设置与容差相同的过期时间）由调用方负责
+func VerifyHMACRequest(r *http.Request, secret string, tolerance time.Duration) error {
+	timestamp := r.Header.Get(HMACTimestampHeader)
+	nonce := r.Header.Get(HMACNonceHeader)
+	signature := r.Header.Get(HMACSignatureHeader)
+	if timestamp == "" || nonce == "" || signature == "" {
+		return fmt.Errorf("%w: missing signature headers", ErrInvalidSignature)
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	expected := hmacSignature(secret, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// AWS Signature V4 常量
+const (
+	awsV4Algorithm       = "AWS4-HMAC-SHA256"
+	awsV4DateFormat      = "20060102T150405Z"
+	awsV4UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// awsV4IgnoredHeaders 不参与签名的请求头，与AWS SDK一致
+var awsV4IgnoredHeaders = map[string]bool{
+	"authorization":   true,
+	"user-agent":      true,
+	"x-amzn-trace-id": true,
+}
+
+// AWSV4Signer AWS Signature Version 4 签名器，用于S3兼容存储（MinIO、OSS、COS等）和其他AWS风格的API
+type AWSV4Signer struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // 临时凭证的会话令牌，可选
+	Region          string // 如 us-east-1
+	Service         string // 如 s3、execute-api
+	now             func() time.Time
+}
+
+// NewAWSV4Signer 创建AWS SigV4签名器
+func NewAWSV4Signer(accessKeyID, secretAccessKey, region, service string) *AWSV4Signer {
+	return &AWSV4Signer{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		Region:          region,
+		Service:         service,
+		now:             time.Now,
+	}
+}
+
+// Sign 签名请求，设置 X-Amz-Date 和 Authorization 请求头；S3 请求同时设置 X-Amz-Content-Sha256
+// 请求中已有的请求头（Authorization、User-Agent 除外）都参与签名
+func (s *AWSV4Signer) Sign(req *http.Request, body []byte) error {
+	now := s.timestamp()
+	amzDate := now.Format(awsV4DateFormat)
+
+	bodyHash := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(bodyHash[:])
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	signedHeaders, canonicalHeaders := s.canonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s.canonicalURI(req.URL),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := s.scope(now)
+	signature := s.signature(now, scope, amzDate, canonicalRequest)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsV4Algorithm, s.AccessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+// Presign 生成预签名URL（查询参数签名），用于直接下载或上传S3对象，只对 host 签名且不签名请求体
+func (s *AWSV4Signer) Presign(method, rawURL string, expires time.Duration) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	now := s.timestamp()
+	amzDate := now.Format(awsV4DateFormat)
+	scope := s.scope(now)
+
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", awsV4Algorithm)
+	query.Set("X-Amz-Credential", s.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if s.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	canonicalRequest := strings.Join([]string{
+		strings.ToUpper(method),
+		s.canonicalURI(u),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		awsV4UnsignedPayload,
+	}, "\n")
+	query.Set("X-Amz-Signature", s.signature(now, scope, amzDate, canonicalRequest))
+	u.RawQuery = canonicalQuery(query)
+	return u.String(), nil
+}
+
+// timestamp 获取签名时间（UTC）
+func (s *AWSV4Signer) timestamp() time.Time {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	return now().UTC()
+}
+
+// scope 凭证范围 日期/区域/服务/aws4_request
+func (s *AWSV4Signer) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.Region + "/" + s.Service + "/aws4_request"
+}
+
+// signature 计算签名
+func (s *AWSV4Signer) signature(t time.Time, scope, amzDate, canonicalRequest string) string {
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := awsV4Algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalURI 规范化路径，S3 只编码一次，其他服务对已编码的路径再编码一次
+func (s *AWSV4Signer) canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if s.Service == "s3" {
+		path = u.Path
+	}
+	if path == "" {
+		return "/"
+	}
+	return awsURIEncode(path, false)
+}
+
+// canonicalHeaders 规范化请求头，返回签名头列表和规范化请求头
+func (s *AWSV4Signer) canonicalHeaders(req *http.Request) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if awsV4IgnoredHeaders[lower] {
+			continue
+		}
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[lower] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return strings.Join(names, ";"), b.String()
+}
+
+// canonicalQuery 规范化查询字符串，参数名和值按RFC 3986编码后排序
+func canonicalQuery(values url.Values) string {
+	encoded := make(map[string][]string, len(values))
+	keys := make([]string, 0, len(values))
+	for key, vals := range values {
+		encodedKey := awsURIEncode(key, true)
+		keys = append(keys, encodedKey)
+		for _, value := range vals {
+			encoded[encodedKey] = append(encoded[encodedKey], awsURIEncode(value, true))
+		}
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(values))
+	for _, key := range keys {
+		vals := encoded[key]
+		sort.Strings(vals)
+		for _, value := range vals {
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// awsURIEncode 按AWS规则编码：保留非保留字符，encodeSlash 为 false 时保留路径分隔符
+func awsURIEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// hmacSHA256 计算 HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gosimple/slug"
+	"github.com/gosimple/unidecode"
+)
+
+// DefaultReservedSlugs 默认保留的slug，避免与系统路由冲突
+var DefaultReservedSlugs = []string{
+	"admin", "api", "auth", "login", "logout", "register",
+	"static", "assets", "health", "metrics", "new", "edit", "delete",
+}
+
+// maxSlugAttempts 生成唯一slug的最大尝试次数
+const maxSlugAttempts = 100
+
+// SlugOptions slug生成选项
+type SlugOptions struct {
+	MaxLength int      // 最大长度，<=0 表示不限制，截断时保留完整单词
+	Separator string   // 单词分隔符，默认 "-"
+	Reserved  []string // 保留词，为nil时使用 DefaultReservedSlugs
+}
+
+// Transliterate 将Unicode文本音译为ASCII，中文转换为不带声调的拼音
+// 如 "你好 Café" -> "Ni Hao Cafe"
+func (s *StringUtils) Transliterate(str string) string {
+	return strings.Join(strings.Fields(unidecode.Unidecode(str)), " ")
+}
+
+// Slugify 生成URL安全的slug，如 "Hello 世界!" -> "hello-shi-jie"
+func (s *StringUtils) Slugify(str string) string {
+	return s.SlugifyWithOptions(str, SlugOptions{})
+}
+
+// SlugifyWithOptions 按选项生成slug
+func (s *StringUtils) SlugifyWithOptions(str string, opts SlugOptions) string {
+	result := slug.Make(str)
+
+	if opts.MaxLength > 0 && len(result) > opts.MaxLength {
+		result = result[:opts.MaxLength]
+		// 在单词边界截断
+		if idx := strings.LastIndex(result, "-"); idx > 0 {
+			result = result[:idx]
+		}
+		result = strings.Trim(result, "-")
+	}
+
+	if opts.Separator != "" && opts.Separator != "-" {
+		result = strings.ReplaceAll(result, "-", opts.Separator)
+	}
+
+	return result
+}
+
+// IsReservedSlug 检查slug是否为保留词
+func (s *StringUtils) IsReservedSlug(value string, reserved []string) bool {
+	if reserved == nil {
+		reserved = DefaultReservedSlugs
+	}
+	for _, word := range reserved {
+		if strings.EqualFold(value, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// UniqueSlug 生成唯一slug，已存在或为保留词时依次追加 -2、-3 等后缀
+// exists 用于检查slug是否已被占用（如查询数据库）
+func (s *StringUtils) UniqueSlug(str string, opts SlugOptions, exists func(slug string) (bool, error)) (string, error) {
+	base := s.SlugifyWithOptions(str, opts)
+	if base == "" {
+		return "", fmt.Errorf("cannot generate slug from %q", str)
+	}
+
+	separator := opts.Separator
+	if separator == "" {
+		separator = "-"
+	}
+
+	candidate := base
+	for attempt := 2; attempt <= maxSlugAttempts+1; attempt++ {
+		if !s.IsReservedSlug(candidate, opts.Reserved) {
+			taken := false
+			if exists != nil {
+				var err error
+				if taken, err = exists(candidate); err != nil {
+					return "", fmt.Errorf("failed to check slug %q: %w", candidate, err)
+				}
+			}
+			if !taken {
+				return candidate, nil
+			}
+		}
+		candidate = fmt.Sprintf("%s%s%d", base, separator, attempt)
+	}
+
+	return "", fmt.Errorf("failed to generate unique slug for %q after %d attempts", str, maxSlugAttempts)
+}
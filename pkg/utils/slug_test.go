@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringUtils_Slugify(t *testing.T) {
+	s := NewStringUtils()
+
+	assert.Equal(t, "hello-world", s.Slugify("Hello, World!"))
+	assert.Equal(t, "ni-hao-shi-jie", s.Slugify("你好世界"))
+	assert.Equal(t, "cafe-au-lait", s.Slugify("Café au lait"))
+	assert.Equal(t, "hello_big", s.SlugifyWithOptions("Hello big world", SlugOptions{MaxLength: 12, Separator: "_"}))
+}
+
+func TestStringUtils_UniqueSlug(t *testing.T) {
+	s := NewStringUtils()
+	taken := map[string]bool{"my-post": true, "my-post-2": true}
+
+	result, err := s.UniqueSlug("My Post", SlugOptions{}, func(slug string) (bool, error) {
+		return taken[slug], nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "my-post-3", result)
+
+	// 保留词自动追加后缀
+	result, err = s.UniqueSlug("Admin", SlugOptions{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin-2", result)
+}
@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrSSRFBlocked 请求目标被SSRF防护拒绝
+var ErrSSRFBlocked = errors.New("request blocked by SSRF guard")
+
+// SSRFGuardConfig SSRF防护配置，用于请求用户提供的URL（如Webhook回调地址、头像地址）
+type SSRFGuardConfig struct {
+	AllowedSchemes []string // 允许的协议，默认 http、https
+	AllowedPorts   []int    // 允许的端口，默认 80、443
+	AllowedCIDRs   []string // 允许访问的内网网段，如需要回调的内部服务
+	DeniedCIDRs    []string // 额外禁止访问的网段
+	MaxRedirects   int      // 最大重定向次数，默认5，<0 表示不跟随重定向；重定向目标同样会被检查
+}
+
+// ssrfGuard SSRF防护
+type ssrfGuard struct {
+	schemes      map[string]bool
+	ports        map[int]bool
+	allowed      []*net.IPNet
+	denied       []*net.IPNet
+	maxRedirects int
+}
+
+// reservedCIDRs 除私有、回环、链路本地地址外需要拒绝的保留网段
+var reservedCIDRs = []string{
+	"0.0.0.0/8",      // 本网络
+	"100.64.0.0/10",  // 运营商级NAT
+	"192.0.0.0/24",   // IETF协议分配
+	"198.18.0.0/15",  // 基准测试
+	"240.0.0.0/4",    // 保留
+	"64:ff9b::/96",   // NAT64，可映射到内网IPv4地址
+	"64:ff9b:1::/48", // 本地NAT64
+	"2001:db8::/32",  // 文档示例
+}
+
+// newSSRFGuard 创建SSRF防护
+func newSSRFGuard(cfg SSRFGuardConfig) (*ssrfGuard, error) {
+	if len(cfg.AllowedSchemes) == 0 {
+		cfg.AllowedSchemes = []string{"http", "https"}
+	}
+	if len(cfg.AllowedPorts) == 0 {
+		cfg.AllowedPorts = []int{80, 443}
+	}
+	if cfg.MaxRedirects == 0 {
+		cfg.MaxRedirects = 5
+	}
+
+	g := &ssrfGuard{
+		schemes:      make(map[string]bool),
+		ports:        make(map[int]bool),
+		maxRedirects: cfg.MaxRedirects,
+	}
+	for _, scheme := range cfg.AllowedSchemes {
+		g.schemes[strings.ToLower(scheme)] = true
+	}
+	for _, port := range cfg.AllowedPorts {
+		g.ports[port] = true
+	}
+	var err error
+	if g.allowed, err = parseCIDRs(cfg.AllowedCIDRs); err != nil {
+		return nil, err
+	}
+	if g.denied, err = parseCIDRs(append(append([]string{}, reservedCIDRs...), cfg.DeniedCIDRs...)); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// parseCIDRs 解析网段列表
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// checkURL 检查协议和端口
+func (g *ssrfGuard) checkURL(u *url.URL) error {
+	scheme := strings.ToLower(u.Scheme)
+	if !g.schemes[scheme] {
+		return fmt.Errorf("%w: scheme %q is not allowed", ErrSSRFBlocked, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%w: missing host", ErrSSRFBlocked)
+	}
+	port := u.Port()
+	if port == "" {
+		switch scheme {
+		case "https":
+			port = "443"
+		default:
+			port = "80"
+		}
+	}
+	return g.checkPort(port)
+}
+
+// checkPort 检查端口是否在允许列表中
+func (g *ssrfGuard) checkPort(port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil || !g.ports[n] {
+		return fmt.Errorf("%w: port %s is not allowed", ErrSSRFBlocked, port)
+	}
+	return nil
+}
+
+// checkIP 检查IP是否为可访问的公网地址
+func (g *ssrfGuard) checkIP(ip net.IP) error {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, network := range g.allowed {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+	if !IsPublicIP(ip) {
+		return fmt.Errorf("%w: %s is a private or reserved address", ErrSSRFBlocked, ip)
+	}
+	for _, network := range g.denied {
+		if network.Contains(ip) {
+			return fmt.Errorf("%w: %s is in denied range %s", ErrSSRFBlocked, ip, network)
+		}
+	}
+	return nil
+}
+
+// control 在建立连接前检查实际连接的地址，DNS解析结果在检查和连接之间不会变化，可防御DNS重绑定
+func (g *ssrfGuard) control(network, address string, _ syscall.RawConn) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: invalid address %s", ErrSSRFBlocked, address)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%w: unresolved address %s", ErrSSRFBlocked, address)
+	}
+	if err := g.checkPort(port); err != nil {
+		return err
+	}
+	return g.checkIP(ip)
+}
+
+// checkRedirect 限制重定向次数并检查重定向目标
+func (g *ssrfGuard) checkRedirect(req *http.Request, via []*http.Request) error {
+	if g.maxRedirects < 0 {
+		return http.ErrUseLastResponse
+	}
+	if len(via) > g.maxRedirects {
+		return fmt.Errorf("%w: stopped after %d redirects", ErrSSRFBlocked, g.maxRedirects)
+	}
+	return g.checkURL(req.URL)
+}
+
+// transport 创建带连接检查的传输层，不使用代理（代理会绕过连接地址检查）
+func (g *ssrfGuard) transport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   g.control,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// IsPublicIP 判断是否为公网地址（非回环、私有、链路本地、组播和未指定地址）
+func IsPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// EnableSSRFGuard 开启SSRF防护：拒绝解析到内网、链路本地等地址的请求，限制协议、端口和重定向次数
+// 防护作用于当前HTTP客户端，替换传输层和重定向策略，之后调用 SetClient 会失效
+func (h *HTTPUtils) EnableSSRFGuard(cfg SSRFGuardConfig) error {
+	guard, err := newSSRFGuard(cfg)
+	if err != nil {
+		return err
+	}
+	h.client.Transport = guard.transport()
+	h.client.CheckRedirect = guard.checkRedirect
+	h.ssrf = guard
+	return nil
+}
+
+// NewSafeHTTPUtils 创建开启SSRF防护的HTTP工具实例，用于请求用户提供的URL
+func NewSafeHTTPUtils(cfg SSRFGuardConfig) (*HTTPUtils, error) {
+	h := NewHTTPUtils()
+	if err := h.EnableSSRFGuard(cfg); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// ValidateURL 按SSRF防护规则提前校验URL（如保存用户配置的Webhook地址时），会解析域名并检查所有地址
+// 未开启防护时只检查URL格式；请求时仍会检查实际连接的地址
+func (h *HTTPUtils) ValidateURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid URL: %s", rawURL)
+	}
+	if h.ssrf == nil {
+		return nil
+	}
+	if err := h.ssrf.checkURL(u); err != nil {
+		return err
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if err := h.ssrf.checkIP(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serverPort(t *testing.T, server *httptest.Server) int {
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	return port
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "::1", "fe80::1", "fd00::1", "0.0.0.0"} {
+		assert.False(t, IsPublicIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"} {
+		assert.True(t, IsPublicIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestSSRFGuard_BlocksPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	port := serverPort(t, server)
+
+	h, err := NewSafeHTTPUtils(SSRFGuardConfig{AllowedPorts: []int{port}})
+	require.NoError(t, err)
+	_, err = h.Get(server.URL, nil)
+	assert.True(t, errors.Is(err, ErrSSRFBlocked), "expected SSRF error, got %v", err)
+
+	// 元数据服务地址同样被拒绝
+	_, err = h.Get("http://169.254.169.254/latest/meta-data/", nil)
+	assert.ErrorIs(t, err, ErrSSRFBlocked)
+
+	// 白名单网段允许访问
+	h, err = NewSafeHTTPUtils(SSRFGuardConfig{AllowedPorts: []int{port}, AllowedCIDRs: []string{"127.0.0.0/8"}})
+	require.NoError(t, err)
+	resp, err := h.Get(server.URL, nil)
+	require.NoError(t, err)
+	assert.True(t, resp.IsSuccess())
+}
+
+func TestSSRFGuard_SchemeAndPort(t *testing.T) {
+	h, err := NewSafeHTTPUtils(SSRFGuardConfig{})
+	require.NoError(t, err)
+
+	_, err = h.Get("ftp://example.com/file", nil)
+	assert.ErrorIs(t, err, ErrSSRFBlocked)
+	_, err = h.Get("http://example.com:6379/", nil)
+	assert.ErrorIs(t, err, ErrSSRFBlocked)
+
+	assert.ErrorIs(t, h.ValidateURL(context.Background(), "gopher://example.com"), ErrSSRFBlocked)
+	assert.ErrorIs(t, h.ValidateURL(context.Background(), "http://127.0.0.1/"), ErrSSRFBlocked)
+	assert.ErrorIs(t, h.ValidateURL(context.Background(), "http://[::1]/"), ErrSSRFBlocked)
+	assert.Error(t, h.ValidateURL(context.Background(), "not a url"))
+
+	_, err = NewSafeHTTPUtils(SSRFGuardConfig{DeniedCIDRs: []string{"invalid"}})
+	assert.Error(t, err)
+}
+
+func TestSSRFGuard_Redirects(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/loop":
+			http.Redirect(w, r, server.URL+"/loop", http.StatusFound)
+		case "/internal":
+			http.Redirect(w, r, "http://127.0.0.1:6379/", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+	port := serverPort(t, server)
+
+	h, err := NewSafeHTTPUtils(SSRFGuardConfig{AllowedPorts: []int{port}, AllowedCIDRs: []string{"127.0.0.0/8"}, MaxRedirects: 2})
+	require.NoError(t, err)
+
+	_, err = h.Get(server.URL+"/loop", nil)
+	assert.ErrorIs(t, err, ErrSSRFBlocked)
+
+	// 重定向到不允许的端口
+	_, err = h.Get(server.URL+"/internal", nil)
+	assert.ErrorIs(t, err, ErrSSRFBlocked)
+
+	// 不跟随重定向时返回重定向响应
+	h, err = NewSafeHTTPUtils(SSRFGuardConfig{AllowedPorts: []int{port}, AllowedCIDRs: []string{"127.0.0.0/8"}, MaxRedirects: -1})
+	require.NoError(t, err)
+	resp, err := h.Get(server.URL+"/loop", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"regexp"
@@ -51,8 +52,8 @@ func (s *StringUtils) CamelCase(str string) string {
 		return ""
 	}
 	
-	// 按分隔符分割
-	words := regexp.MustCompile(`[^a-zA-Z0-9]+`).Split(str, -1)
+	// 先转为蛇形命名，按分隔符和驼峰边界统一切分单词，全大写的单词也能正确处理
+	words := strings.Split(s.SnakeCase(str), "_")
 	var result strings.Builder
 	
 	for i, word := range words {
@@ -61,7 +62,7 @@ func (s *StringUtils) CamelCase(str string) string {
 		}
 		
 		if i == 0 {
-			result.WriteString(strings.ToLower(word))
+			result.WriteString(word)
 		} else {
 			result.WriteString(s.Capitalize(word))
 		}
@@ -207,31 +208,83 @@ func (s *StringUtils) CountWords(str string) int {
 	return len(words)
 }
 
-// RandomString 生成随机字符串
-func (s *StringUtils) RandomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, length)
-	_, err := rand.Read(b)
-	if err != nil {
-		// 如果加密随机失败，回退到简单方法
-		return s.randomStringFallback(length)
+// 常用随机字符集
+const (
+	CharsetAlphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	CharsetAlphabetic   = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	CharsetNumeric      = "0123456789"
+	CharsetLowerHex     = "0123456789abcdef"
+	// CharsetHumanFriendly 去除了易混淆字符（0/O、1/I/L、2/Z、5/S、8/B、U/V）的大写字符集
+	CharsetHumanFriendly = "34679ACDEFGHJKMNPQRTWXY"
+)
+
+// RandomString 生成随机字母数字字符串，随机源不可用时返回错误
+func (s *StringUtils) RandomString(length int) (string, error) {
+	return s.RandomStringWithCharset(length, CharsetAlphanumeric)
+}
+
+// RandomStringWithCharset 使用指定字符集生成加密安全的随机字符串
+// 使用拒绝采样避免取模带来的分布偏差
+func (s *StringUtils) RandomStringWithCharset(length int, charset string) (string, error) {
+	if length <= 0 {
+		return "", nil
 	}
-	
-	for i := range b {
-		b[i] = charset[b[i]%byte(len(charset))]
+	chars := []rune(charset)
+	if len(chars) == 0 || len(chars) > 256 {
+		return "", fmt.Errorf("charset size must be between 1 and 256, got %d", len(chars))
 	}
-	
-	return string(b)
+
+	// 拒绝大于等于 limit 的字节，保证每个字符概率相同
+	limit := 256 - 256%len(chars)
+	result := make([]rune, 0, length)
+	buf := make([]byte, length+length/4+1)
+	for len(result) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("failed to read random bytes: %w", err)
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			result = append(result, chars[int(b)%len(chars)])
+			if len(result) == length {
+				break
+			}
+		}
+	}
+
+	return string(result), nil
 }
 
-// randomStringFallback 随机字符串回退方法
-func (s *StringUtils) randomStringFallback(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	result := make([]byte, length)
-	for i := range result {
-		result[i] = charset[i%len(charset)]
+// RandomToken 生成URL安全的随机令牌（byteLength 个随机字节的 base64url 编码，无填充）
+// 如 RandomToken(32) 生成43个字符的令牌，适用于重置密码、邀请链接等
+func (s *StringUtils) RandomToken(byteLength int) (string, error) {
+	if byteLength <= 0 {
+		return "", fmt.Errorf("byte length must be positive, got %d", byteLength)
+	}
+	b := make([]byte, byteLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// RandomCode 生成不含易混淆字符的大写验证码/邀请码
+// groupSize 大于0时按组用连字符分隔，如 RandomCode(8, 4) -> "K7FQ-M3XD"
+func (s *StringUtils) RandomCode(length int, groupSize int) (string, error) {
+	code, err := s.RandomStringWithCharset(length, CharsetHumanFriendly)
+	if err != nil || groupSize <= 0 {
+		return code, err
+	}
+
+	var builder strings.Builder
+	for i, ch := range code {
+		if i > 0 && i%groupSize == 0 {
+			builder.WriteByte('-')
+		}
+		builder.WriteRune(ch)
 	}
-	return string(result)
+	return builder.String(), nil
 }
 
 // RandomAlphabetic 生成随机字母字符串
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -143,7 +144,7 @@ func TestStringUtils_Truncate(t *testing.T) {
 	assert.Equal(t, "hello", s.Truncate("hello", 10, "..."))
 	assert.Equal(t, "hel...", s.Truncate("hello world", 6, "..."))
 	assert.Equal(t, "hello", s.Truncate("hello", 5, "..."))
-	assert.Equal(t, "he---", s.Truncate("hello", 5, "---"))
+	assert.Equal(t, "he---", s.Truncate("hello world", 5, "---"))
 }
 
 func TestStringUtils_ContainsIgnoreCase(t *testing.T) {
@@ -211,19 +212,54 @@ func TestStringUtils_RandomString(t *testing.T) {
 	s := NewStringUtils()
 	
 	// 测试长度
-	result := s.RandomString(10)
+	result, err := s.RandomString(10)
+	assert.NoError(t, err)
 	assert.Len(t, result, 10)
 	
 	// 测试不同调用产生不同结果
-	result1 := s.RandomString(5)
-	result2 := s.RandomString(5)
+	result1, _ := s.RandomString(5)
+	result2, _ := s.RandomString(5)
 	assert.NotEqual(t, result1, result2) // 理论上应该不相等
 	
 	// 测试空字符串
-	result = s.RandomString(0)
+	result, err = s.RandomString(0)
+	assert.NoError(t, err)
 	assert.Equal(t, "", result)
 }
 
+func TestStringUtils_RandomStringWithCharset(t *testing.T) {
+	s := NewStringUtils()
+	
+	result, err := s.RandomStringWithCharset(20, "ab")
+	assert.NoError(t, err)
+	assert.Len(t, result, 20)
+	assert.Empty(t, strings.Trim(result, "ab"))
+	
+	_, err = s.RandomStringWithCharset(5, "")
+	assert.Error(t, err)
+}
+
+func TestStringUtils_RandomToken(t *testing.T) {
+	s := NewStringUtils()
+	
+	token, err := s.RandomToken(32)
+	assert.NoError(t, err)
+	assert.Len(t, token, 43)
+	assert.NotContains(t, token, "+")
+	assert.NotContains(t, token, "/")
+	assert.NotContains(t, token, "=")
+}
+
+func TestStringUtils_RandomCode(t *testing.T) {
+	s := NewStringUtils()
+	
+	code, err := s.RandomCode(8, 4)
+	assert.NoError(t, err)
+	assert.Len(t, code, 9)
+	assert.Equal(t, byte('-'), code[4])
+	assert.Empty(t, strings.Trim(strings.ReplaceAll(code, "-", ""), CharsetHumanFriendly))
+}
+
 func TestStringUtils_IsNumeric(t *testing.T) {
 	s := NewStringUtils()
 	
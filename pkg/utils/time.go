@@ -1,18 +1,67 @@
 package utils
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 )
 
 // TimeUtils 时间工具集合
-type TimeUtils struct{}
+type TimeUtils struct {
+	location *time.Location // 为空时使用全局默认时区
+}
 
 // NewTimeUtils 创建时间工具实例
 func NewTimeUtils() *TimeUtils {
 	return &TimeUtils{}
 }
 
+// defaultLocation 全局默认时区
+var defaultLocation atomic.Pointer[time.Location]
+
+// SetDefaultLocation 设置全局默认时区，影响未绑定时区的时间工具实例，服务器启动时按 SERVER_TIMEZONE 设置
+func SetDefaultLocation(location *time.Location) {
+	defaultLocation.Store(location)
+}
+
+// DefaultLocation 获取全局默认时区，未设置时为系统本地时区
+func DefaultLocation() *time.Location {
+	if location := defaultLocation.Load(); location != nil {
+		return location
+	}
+	return time.Local
+}
+
+// locationContextKey 请求时区的上下文键
+type locationContextKey struct{}
+
+// ContextWithLocation 将展示时区存入上下文
+func ContextWithLocation(ctx context.Context, location *time.Location) context.Context {
+	return context.WithValue(ctx, locationContextKey{}, location)
+}
+
+// LocationFromContext 获取上下文中的展示时区，未设置时返回全局默认时区
+func LocationFromContext(ctx context.Context) *time.Location {
+	if location, ok := ctx.Value(locationContextKey{}).(*time.Location); ok && location != nil {
+		return location
+	}
+	return DefaultLocation()
+}
+
+// WithLocation 创建绑定指定时区的时间工具实例，用于按用户时区展示时间
+func (t *TimeUtils) WithLocation(location *time.Location) *TimeUtils {
+	return &TimeUtils{location: location}
+}
+
+// Location 获取时间工具使用的时区
+func (t *TimeUtils) Location() *time.Location {
+	if t.location != nil {
+		return t.location
+	}
+	return DefaultLocation()
+}
+
 // 常用时间格式常量
 const (
 	DateTimeFormat     = "2006-01-02 15:04:05"
@@ -28,7 +77,7 @@ const (
 
 // Now 获取当前时间
 func (t *TimeUtils) Now() time.Time {
-	return time.Now()
+	return time.Now().In(t.Location())
 }
 
 // NowUnix 获取当前Unix时间戳（秒）
@@ -53,27 +102,32 @@ func (t *TimeUtils) NowUnixNano() int64 {
 
 // FormatNow 格式化当前时间
 func (t *TimeUtils) FormatNow(layout string) string {
-	return time.Now().Format(layout)
+	return t.Now().Format(layout)
 }
 
 // FormatNowDateTime 格式化当前时间为日期时间字符串
 func (t *TimeUtils) FormatNowDateTime() string {
-	return time.Now().Format(DateTimeFormat)
+	return t.Now().Format(DateTimeFormat)
 }
 
 // FormatNowDate 格式化当前时间为日期字符串
 func (t *TimeUtils) FormatNowDate() string {
-	return time.Now().Format(DateFormat)
+	return t.Now().Format(DateFormat)
 }
 
 // FormatNowTime 格式化当前时间为时间字符串
 func (t *TimeUtils) FormatNowTime() string {
-	return time.Now().Format(TimeFormat)
+	return t.Now().Format(TimeFormat)
 }
 
 // Format 格式化时间
-func (t *TimeUtils) Format(time time.Time, layout string) string {
-	return time.Format(layout)
+func (t *TimeUtils) Format(tm time.Time, layout string) string {
+	return tm.Format(layout)
+}
+
+// Display 转换到时间工具的时区后格式化，用于按用户时区展示时间
+func (t *TimeUtils) Display(value time.Time, layout string) string {
+	return value.In(t.Location()).Format(layout)
 }
 
 // Parse 解析时间字符串
@@ -81,14 +135,14 @@ func (t *TimeUtils) Parse(layout, value string) (time.Time, error) {
 	return time.Parse(layout, value)
 }
 
-// ParseDateTime 解析日期时间字符串
+// ParseDateTime 按时间工具的时区解析日期时间字符串
 func (t *TimeUtils) ParseDateTime(value string) (time.Time, error) {
-	return time.Parse(DateTimeFormat, value)
+	return time.ParseInLocation(DateTimeFormat, value, t.Location())
 }
 
-// ParseDate 解析日期字符串
+// ParseDate 按时间工具的时区解析日期字符串
 func (t *TimeUtils) ParseDate(value string) (time.Time, error) {
-	return time.Parse(DateFormat, value)
+	return time.ParseInLocation(DateFormat, value, t.Location())
 }
 
 // ParseTime 解析时间字符串
@@ -117,81 +171,81 @@ func (t *TimeUtils) ParseUnixNano(timestamp int64) time.Time {
 }
 
 // AddDays 添加天数
-func (t *TimeUtils) AddDays(time time.Time, days int) time.Time {
-	return time.AddDate(0, 0, days)
+func (t *TimeUtils) AddDays(tm time.Time, days int) time.Time {
+	return tm.AddDate(0, 0, days)
 }
 
 // AddHours 添加小时数
-func (t *TimeUtils) AddHours(time time.Time, hours int) time.Time {
-	return time.Add(time.Duration(hours) * time.Hour)
+func (t *TimeUtils) AddHours(tm time.Time, hours int) time.Time {
+	return tm.Add(time.Duration(hours) * time.Hour)
 }
 
 // AddMinutes 添加分钟数
-func (t *TimeUtils) AddMinutes(time time.Time, minutes int) time.Time {
-	return time.Add(time.Duration(minutes) * time.Minute)
+func (t *TimeUtils) AddMinutes(tm time.Time, minutes int) time.Time {
+	return tm.Add(time.Duration(minutes) * time.Minute)
 }
 
 // AddSeconds 添加秒数
-func (t *TimeUtils) AddSeconds(time time.Time, seconds int) time.Time {
-	return time.Add(time.Duration(seconds) * time.Second)
+func (t *TimeUtils) AddSeconds(tm time.Time, seconds int) time.Time {
+	return tm.Add(time.Duration(seconds) * time.Second)
 }
 
 // SubtractDays 减去天数
-func (t *TimeUtils) SubtractDays(time time.Time, days int) time.Time {
-	return time.AddDate(0, 0, -days)
+func (t *TimeUtils) SubtractDays(tm time.Time, days int) time.Time {
+	return tm.AddDate(0, 0, -days)
 }
 
 // SubtractHours 减去小时数
-func (t *TimeUtils) SubtractHours(time time.Time, hours int) time.Time {
-	return time.Add(time.Duration(-hours) * time.Hour)
+func (t *TimeUtils) SubtractHours(tm time.Time, hours int) time.Time {
+	return tm.Add(time.Duration(-hours) * time.Hour)
 }
 
 // SubtractMinutes 减去分钟数
-func (t *TimeUtils) SubtractMinutes(time time.Time, minutes int) time.Time {
-	return time.Add(time.Duration(-minutes) * time.Minute)
+func (t *TimeUtils) SubtractMinutes(tm time.Time, minutes int) time.Time {
+	return tm.Add(time.Duration(-minutes) * time.Minute)
 }
 
 // SubtractSeconds 减去秒数
-func (t *TimeUtils) SubtractSeconds(time time.Time, seconds int) time.Time {
-	return time.Add(time.Duration(-seconds) * time.Second)
+func (t *TimeUtils) SubtractSeconds(tm time.Time, seconds int) time.Time {
+	return tm.Add(time.Duration(-seconds) * time.Second)
 }
 
 // BeginOfDay 获取一天的开始时间
-func (t *TimeUtils) BeginOfDay(time time.Time) time.Time {
-	year, month, day := time.Date()
-	return time.Date(year, month, day, 0, 0, 0, 0, time.Location())
+func (t *TimeUtils) BeginOfDay(tm time.Time) time.Time {
+	year, month, day := tm.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, tm.Location())
 }
 
 // EndOfDay 获取一天的结束时间
-func (t *TimeUtils) EndOfDay(time time.Time) time.Time {
-	year, month, day := time.Date()
-	return time.Date(year, month, day, 23, 59, 59, 999999999, time.Location())
+func (t *TimeUtils) EndOfDay(tm time.Time) time.Time {
+	year, month, day := tm.Date()
+	return time.Date(year, month, day, 23, 59, 59, 999999999, tm.Location())
 }
 
 // BeginOfMonth 获取月份的开始时间
-func (t *TimeUtils) BeginOfMonth(time time.Time) time.Time {
-	year, month, _ := time.Date()
-	return time.Date(year, month, 1, 0, 0, 0, 0, time.Location())
+func (t *TimeUtils) BeginOfMonth(tm time.Time) time.Time {
+	year, month, _ := tm.Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, tm.Location())
 }
 
 // EndOfMonth 获取月份的结束时间
-func (t *TimeUtils) EndOfMonth(time time.Time) time.Time {
-	year, month, _ := time.Date()
+func (t *TimeUtils) EndOfMonth(tm time.Time) time.Time {
+	year, month, _ := tm.Date()
 	// 下个月的第一天减去1纳秒
-	nextMonth := time.Date(year, month+1, 1, 0, 0, 0, 0, time.Location())
+	nextMonth := time.Date(year, month+1, 1, 0, 0, 0, 0, tm.Location())
 	return nextMonth.Add(-time.Nanosecond)
 }
 
 // BeginOfYear 获取年份的开始时间
-func (t *TimeUtils) BeginOfYear(time time.Time) time.Time {
-	year, _, _ := time.Date()
-	return time.Date(year, 1, 1, 0, 0, 0, 0, time.Location())
+func (t *TimeUtils) BeginOfYear(tm time.Time) time.Time {
+	year, _, _ := tm.Date()
+	return time.Date(year, 1, 1, 0, 0, 0, 0, tm.Location())
 }
 
 // EndOfYear 获取年份的结束时间
-func (t *TimeUtils) EndOfYear(time time.Time) time.Time {
-	year, _, _ := time.Date()
-	return time.Date(year, 12, 31, 23, 59, 59, 999999999, time.Location())
+func (t *TimeUtils) EndOfYear(tm time.Time) time.Time {
+	year, _, _ := tm.Date()
+	return time.Date(year, 12, 31, 23, 59, 59, 999999999, tm.Location())
 }
 
 // DiffDays 计算两个时间相差的天数
@@ -225,21 +279,21 @@ func (t *TimeUtils) DaysInMonth(year int, month time.Month) int {
 }
 
 // IsToday 判断是否为今天
-func (t *TimeUtils) IsToday(time time.Time) bool {
+func (t *TimeUtils) IsToday(tm time.Time) bool {
 	now := time.Now()
-	return t.IsSameDay(time, now)
+	return t.IsSameDay(tm, now)
 }
 
 // IsYesterday 判断是否为昨天
-func (t *TimeUtils) IsYesterday(time time.Time) bool {
+func (t *TimeUtils) IsYesterday(tm time.Time) bool {
 	yesterday := time.Now().AddDate(0, 0, -1)
-	return t.IsSameDay(time, yesterday)
+	return t.IsSameDay(tm, yesterday)
 }
 
 // IsTomorrow 判断是否为明天
-func (t *TimeUtils) IsTomorrow(time time.Time) bool {
+func (t *TimeUtils) IsTomorrow(tm time.Time) bool {
 	tomorrow := time.Now().AddDate(0, 0, 1)
-	return t.IsSameDay(time, tomorrow)
+	return t.IsSameDay(tm, tomorrow)
 }
 
 // IsSameDay 判断两个时间是否为同一天
@@ -272,19 +326,19 @@ func (t *TimeUtils) IsAfter(time1, time2 time.Time) bool {
 }
 
 // IsBetween 判断时间是否在指定范围内
-func (t *TimeUtils) IsBetween(time, start, end time.Time) bool {
-	return time.After(start) && time.Before(end)
+func (t *TimeUtils) IsBetween(tm, start, end time.Time) bool {
+	return tm.After(start) && tm.Before(end)
 }
 
 // IsWorkday 判断是否为工作日（周一到周五）
-func (t *TimeUtils) IsWorkday(time time.Time) bool {
-	weekday := time.Weekday()
+func (t *TimeUtils) IsWorkday(tm time.Time) bool {
+	weekday := tm.Weekday()
 	return weekday >= time.Monday && weekday <= time.Friday
 }
 
 // IsWeekend 判断是否为周末
-func (t *TimeUtils) IsWeekend(time time.Time) bool {
-	weekday := time.Weekday()
+func (t *TimeUtils) IsWeekend(tm time.Time) bool {
+	weekday := tm.Weekday()
 	return weekday == time.Saturday || weekday == time.Sunday
 }
 
@@ -303,9 +357,9 @@ func (t *TimeUtils) Age(birthday time.Time) int {
 }
 
 // TimeAgo 返回时间距离现在的描述（如：2小时前）
-func (t *TimeUtils) TimeAgo(time time.Time) string {
+func (t *TimeUtils) TimeAgo(tm time.Time) string {
 	now := time.Now()
-	diff := now.Sub(time)
+	diff := now.Sub(tm)
 	
 	if diff < time.Minute {
 		return "刚刚"
@@ -351,18 +405,18 @@ func (t *TimeUtils) SleepMilliseconds(milliseconds int) {
 }
 
 // ToLocation 转换时区
-func (t *TimeUtils) ToLocation(time time.Time, location *time.Location) time.Time {
-	return time.In(location)
+func (t *TimeUtils) ToLocation(tm time.Time, location *time.Location) time.Time {
+	return tm.In(location)
 }
 
 // ToUTC 转换为UTC时间
-func (t *TimeUtils) ToUTC(time time.Time) time.Time {
-	return time.UTC()
+func (t *TimeUtils) ToUTC(tm time.Time) time.Time {
+	return tm.UTC()
 }
 
 // ToLocal 转换为本地时间
-func (t *TimeUtils) ToLocal(time time.Time) time.Time {
-	return time.Local()
+func (t *TimeUtils) ToLocal(tm time.Time) time.Time {
+	return tm.Local()
 }
 
 // GetLocation 获取时区
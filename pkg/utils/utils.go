@@ -4,19 +4,23 @@ package utils
 
 // Utils 工具集合
 type Utils struct {
-	String *StringUtils
-	JSON   *JSONUtils
-	Time   *TimeUtils
-	HTTP   *HTTPUtils
+	String   *StringUtils
+	JSON     *JSONUtils
+	Time     *TimeUtils
+	HTTP     *HTTPUtils
+	Mask     *MaskUtils
+	Markdown *MarkdownUtils
 }
 
 // New 创建工具集合实例
 func New() *Utils {
 	return &Utils{
-		String: NewStringUtils(),
-		JSON:   NewJSONUtils(),
-		Time:   NewTimeUtils(),
-		HTTP:   NewHTTPUtils(),
+		String:   NewStringUtils(),
+		JSON:     NewJSONUtils(),
+		Time:     NewTimeUtils(),
+		HTTP:     NewHTTPUtils(),
+		Mask:     NewMaskUtils(),
+		Markdown: NewMarkdownUtils(),
 	}
 }
 
@@ -31,4 +35,6 @@ var (
 // - Str (string.go)
 // - JSON (json.go) 
 // - Time (time.go)
-// - HTTP (http.go)
\ No newline at end of file
+// - HTTP (http.go)
+// - Mask (mask.go)
+// - Markdown (markdown.go)
\ No newline at end of file